@@ -0,0 +1,33 @@
+// breakglass 生成紧急访问凭证：随机密码交由保管人密封保存，哈希写入 BREAK_GLASS_PASSWORD_HASH。
+// 凭证使用过一次后需重新生成并替换配置（轮换）
+//
+// 用法：go run ./cmd/breakglass
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+
+	"new-openclaw/pkg/password"
+)
+
+func main() {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("生成随机密码失败: %v", err)
+	}
+	secret := base64.RawURLEncoding.EncodeToString(b)
+
+	hash, err := password.Hash(secret)
+	if err != nil {
+		log.Fatalf("生成密码哈希失败: %v", err)
+	}
+
+	fmt.Println("紧急访问密码（打印后密封保存，不要写入任何配置或代码仓库）:")
+	fmt.Println("  " + secret)
+	fmt.Println()
+	fmt.Println("配置:")
+	fmt.Println("  BREAK_GLASS_PASSWORD_HASH=" + hash)
+}
@@ -0,0 +1,436 @@
+// loadtest 压测运行中的实例：按固定速率轮流请求指定接口，按路径自动附加用户 JWT、管理后台 JWT 或 API 签名
+// （密钥取自与服务端相同的环境变量），结束后输出各接口的延迟分位数、状态码分布与错误率，
+// 错误率超过 -max-error-rate 时退出码为 1
+//
+// 用法：go run ./cmd/loadtest [-url http://localhost:8080] [-rps 50] [-d 30s] [-c 100] [-routes "GET /api/v1/users,POST /api/v1/signed/webhook"]
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	adminmiddleware "new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/pkg/config"
+)
+
+// 认证方式
+const (
+	authNone  = "none"
+	authUser  = "user"
+	authAdmin = "admin"
+	authSign  = "sign"
+)
+
+// route 压测的接口
+type route struct {
+	method string
+	path   string
+	auth   string
+}
+
+func (r route) String() string {
+	return r.method + " " + r.path
+}
+
+// stats 单个接口的结果
+type stats struct {
+	latencies []time.Duration
+	statuses  map[int]int
+	// 连接失败、超时等未拿到响应的请求
+	transportErrors int
+	// 第一个失败请求的说明，便于排查认证配置不一致等问题
+	sample string
+}
+
+// runner 压测执行器
+type runner struct {
+	baseURL string
+	body    []byte
+	client  *http.Client
+
+	userToken  string
+	adminToken string
+	appKey     string
+	signSecret string
+	signConfig middleware.SignatureConfig
+
+	mu      sync.Mutex
+	results map[route]*stats
+}
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:8080", "目标实例地址")
+	rps := flag.Int("rps", 50, "每秒请求数（各接口轮流分摊）")
+	duration := flag.Duration("d", 30*time.Second, "压测时长")
+	concurrency := flag.Int("c", 100, "最大并发请求数（已满时跳过本次请求并计入丢弃）")
+	routeList := flag.String("routes", "GET /health", "压测的接口，逗号分隔，格式为 \"方法 路径 [认证方式]\"；认证方式为 none/user/admin/sign，省略时按路径推断")
+	body := flag.String("body", `{"event":"loadtest"}`, "非 GET 请求的请求体（JSON）")
+	timeout := flag.Duration("timeout", 10*time.Second, "单个请求的超时")
+	role := flag.String("role", "admin", "用户 JWT 的角色（权限范围取该角色的默认值）")
+	adminID := flag.Uint("admin-id", 1, "管理后台 JWT 对应的管理员 ID")
+	adminRole := flag.String("admin-role", "super_admin", "管理后台 JWT 的角色")
+	appKey := flag.String("app-key", "", "签名接口使用的 AppKey（为空时使用全局签名密钥）")
+	signSecret := flag.String("sign-secret", "", "签名密钥（默认取 API_SIGNATURE_KEY，指定 AppKey 时应传入其密钥）")
+	maxErrorRate := flag.Float64("max-error-rate", 0.01, "允许的错误率（非 2xx/3xx 及请求失败），超过时退出码为 1")
+	flag.Parse()
+
+	routes, err := parseRoutes(*routeList)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+	if *rps <= 0 || *concurrency <= 0 || *duration <= 0 {
+		fmt.Fprintln(os.Stderr, "-rps、-c、-d 必须大于 0")
+		os.Exit(2)
+	}
+
+	cfg := config.LoadConfig()
+	r, err := newRunner(cfg, strings.TrimRight(*baseURL, "/"), []byte(*body), *timeout, *concurrency)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "初始化失败: %v\n", err)
+		os.Exit(2)
+	}
+	if err := r.issueTokens(cfg, routes, *role, *adminID, *adminRole); err != nil {
+		fmt.Fprintf(os.Stderr, "生成 Token 失败: %v\n", err)
+		os.Exit(2)
+	}
+	r.appKey = *appKey
+	if *signSecret != "" {
+		r.signSecret = *signSecret
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("压测 %s：%d 个接口，目标 %d 次/秒，时长 %s，最大并发 %d（Ctrl+C 提前结束）\n",
+		r.baseURL, len(routes), *rps, *duration, *concurrency)
+	start := time.Now()
+	dropped := r.run(ctx, routes, *rps, *duration, *concurrency)
+	elapsed := time.Since(start)
+
+	if r.report(routes, elapsed, dropped) > *maxErrorRate {
+		os.Exit(1)
+	}
+}
+
+// parseRoutes 解析接口列表
+func parseRoutes(list string) ([]route, error) {
+	var routes []route
+	for _, item := range strings.Split(list, ",") {
+		fields := strings.Fields(item)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 || !strings.HasPrefix(fields[1], "/") {
+			return nil, fmt.Errorf("无效的接口 %q，格式为 \"方法 路径 [认证方式]\"", strings.TrimSpace(item))
+		}
+		rt := route{method: strings.ToUpper(fields[0]), path: fields[1]}
+		if len(fields) == 3 {
+			rt.auth = fields[2]
+		} else {
+			rt.auth = inferAuth(rt.path)
+		}
+		switch rt.auth {
+		case authNone, authUser, authAdmin, authSign:
+		default:
+			return nil, fmt.Errorf("接口 %s 的认证方式 %q 无效（none/user/admin/sign）", rt, rt.auth)
+		}
+		routes = append(routes, rt)
+	}
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("未指定压测的接口")
+	}
+	return routes, nil
+}
+
+// inferAuth 按路由分组推断认证方式（与 handler、admin 包注册路由时的中间件一致）
+func inferAuth(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/api/v1/signed/"),
+		strings.HasPrefix(path, "/api/v1/security/"),
+		strings.HasPrefix(path, "/api/v1/auth/"):
+		return authSign
+	case strings.HasPrefix(path, "/api/v1/public/"):
+		return authNone
+	case strings.HasPrefix(path, "/api/v1/"):
+		return authUser
+	case path == "/admin/login" || strings.HasPrefix(path, "/admin/break-glass"):
+		return authNone
+	case strings.HasPrefix(path, "/admin/"):
+		return authAdmin
+	default:
+		return authNone
+	}
+}
+
+// newRunner 按服务端的环境变量准备签名配置与 HTTP 客户端
+func newRunner(cfg *config.Config, baseURL string, body []byte, timeout time.Duration, concurrency int) (*runner, error) {
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		return nil, fmt.Errorf("无效的目标地址: %s", baseURL)
+	}
+	signConfig := middleware.CurrentSignatureConfig()
+	signConfig.Algorithm = cfg.Security.APISignatureAlgorithm
+	signConfig.SignedHeaders = cfg.Security.APISignatureSignedHeaders
+
+	return &runner{
+		baseURL: baseURL,
+		body:    body,
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				MaxIdleConns:        concurrency,
+				MaxIdleConnsPerHost: concurrency,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+		signSecret: cfg.Security.APISignatureKey,
+		signConfig: signConfig,
+		results:    make(map[route]*stats),
+	}, nil
+}
+
+// issueTokens 用服务端的 JWT 密钥签发压测所需的 Token（只签发用得到的）
+func (r *runner) issueTokens(cfg *config.Config, routes []route, role string, adminID uint, adminRole string) error {
+	need := make(map[string]bool)
+	for _, rt := range routes {
+		need[rt.auth] = true
+	}
+
+	if need[authUser] {
+		jwtConfig := middleware.JWTConfig{
+			SecretKey:      cfg.Security.JWTSecretKey,
+			TokenExpiry:    cfg.Security.JWTExpiry,
+			Issuer:         cfg.Security.JWTIssuer,
+			SigningMethod:  cfg.Security.JWTSigningMethod,
+			PrivateKeyFile: cfg.Security.JWTPrivateKeyFile,
+			PublicKeyFile:  cfg.Security.JWTPublicKeyFile,
+			KeyID:          cfg.Security.JWTKeyID,
+		}
+		token, err := middleware.GenerateTokenWithScopes("loadtest", "loadtest", role, middleware.RoleScopes[role], jwtConfig)
+		if err != nil {
+			return fmt.Errorf("用户 Token: %w", err)
+		}
+		r.userToken = token
+	}
+
+	if need[authAdmin] {
+		adminmiddleware.DefaultConfig.SecretKey = cfg.Security.AdminJWTSecretKey
+		adminmiddleware.DefaultConfig.SigningMethod = cfg.Security.AdminJWTSigningMethod
+		adminmiddleware.DefaultConfig.PrivateKeyFile = cfg.Security.AdminJWTPrivateKeyFile
+		adminmiddleware.DefaultConfig.PublicKeyFile = cfg.Security.AdminJWTPublicKeyFile
+		adminmiddleware.DefaultConfig.KeyID = cfg.Security.AdminJWTKeyID
+		token, _, err := adminmiddleware.GenerateToken(adminID, "loadtest", adminRole)
+		if err != nil {
+			return fmt.Errorf("管理后台 Token: %w", err)
+		}
+		r.adminToken = token
+	}
+	return nil
+}
+
+// run 按固定速率发出请求直到时长结束或收到中断信号，返回因并发已满而丢弃的请求数
+func (r *runner) run(ctx context.Context, routes []route, rps int, duration time.Duration, concurrency int) int {
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	ticker := time.NewTicker(time.Second / time.Duration(rps))
+	defer ticker.Stop()
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	dropped := 0
+	for i := 0; ; i++ {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return dropped
+		case <-ticker.C:
+		}
+
+		rt := routes[i%len(routes)]
+		select {
+		case sem <- struct{}{}:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				r.do(rt)
+			}()
+		default:
+			dropped++
+		}
+	}
+}
+
+// do 发送一次请求并记录结果（签名在计时之前完成）
+func (r *runner) do(rt route) {
+	var body []byte
+	if rt.method != http.MethodGet && rt.method != http.MethodHead {
+		body = r.body
+	}
+	req, err := http.NewRequest(rt.method, r.baseURL+rt.path, bytes.NewReader(body))
+	if err != nil {
+		r.record(rt, 0, 0, err.Error())
+		return
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	switch rt.auth {
+	case authUser:
+		req.Header.Set("Authorization", "Bearer "+r.userToken)
+	case authAdmin:
+		req.Header.Set("Authorization", "Bearer "+r.adminToken)
+	case authSign:
+		middleware.SignRequest(req, body, r.appKey, r.signSecret, r.signConfig)
+	}
+
+	start := time.Now()
+	resp, err := r.client.Do(req)
+	if err != nil {
+		r.record(rt, 0, 0, err.Error())
+		return
+	}
+	var sample string
+	if resp.StatusCode >= 400 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		sample = fmt.Sprintf("HTTP %d %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	r.record(rt, resp.StatusCode, time.Since(start), sample)
+}
+
+// record 记录结果；status 为 0 表示未拿到响应
+func (r *runner) record(rt route, status int, latency time.Duration, sample string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.results[rt]
+	if s == nil {
+		s = &stats{statuses: make(map[int]int)}
+		r.results[rt] = s
+	}
+	if status == 0 {
+		s.transportErrors++
+	} else {
+		s.statuses[status]++
+		s.latencies = append(s.latencies, latency)
+	}
+	if sample != "" && s.sample == "" {
+		s.sample = sample
+	}
+}
+
+// report 输出结果，返回总体错误率
+func (r *runner) report(routes []route, elapsed time.Duration, dropped int) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\n接口\t认证\t请求数\t错误率\tp50\tp90\tp99\t最大\t状态码")
+	var total, failed int
+	var all []time.Duration
+	for _, rt := range routes {
+		s := r.results[rt]
+		if s == nil {
+			fmt.Fprintf(w, "%s\t%s\t0\t-\t-\t-\t-\t-\t-\n", rt, rt.auth)
+			continue
+		}
+		count, errs := s.transportErrors, s.transportErrors
+		for status, n := range s.statuses {
+			count += n
+			if status >= 400 {
+				errs += n
+			}
+		}
+		total += count
+		failed += errs
+		all = append(all, s.latencies...)
+
+		sortDurations(s.latencies)
+		fmt.Fprintf(w, "%s\t%s\t%d\t%.2f%%\t%s\t%s\t%s\t%s\t%s\n", rt, rt.auth, count, percent(errs, count),
+			fmtDuration(percentile(s.latencies, 0.50)), fmtDuration(percentile(s.latencies, 0.90)),
+			fmtDuration(percentile(s.latencies, 0.99)), fmtDuration(percentile(s.latencies, 1)),
+			statusSummary(s))
+	}
+	w.Flush()
+
+	for _, rt := range routes {
+		if s := r.results[rt]; s != nil && s.sample != "" {
+			fmt.Printf("  %s 首个失败: %s\n", rt, s.sample)
+		}
+	}
+
+	sortDurations(all)
+	fmt.Printf("\n共 %d 个请求，用时 %s，实际 %.1f 次/秒，错误率 %.2f%%，丢弃 %d 个（并发已满）\n",
+		total, elapsed.Round(time.Millisecond), float64(total)/elapsed.Seconds(), percent(failed, total), dropped)
+	fmt.Printf("延迟 p50 %s，p95 %s，p99 %s\n",
+		fmtDuration(percentile(all, 0.50)), fmtDuration(percentile(all, 0.95)), fmtDuration(percentile(all, 0.99)))
+
+	if total == 0 {
+		return 1
+	}
+	return float64(failed) / float64(total)
+}
+
+// percentile 已排序延迟的分位数（最近秩法）
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+func sortDurations(d []time.Duration) {
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+func fmtDuration(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.Round(10 * time.Microsecond).String()
+}
+
+// statusSummary 状态码分布，如 200×980 429×20 ERR×1
+func statusSummary(s *stats) string {
+	codes := make([]int, 0, len(s.statuses))
+	for status := range s.statuses {
+		codes = append(codes, status)
+	}
+	sort.Ints(codes)
+	parts := make([]string, 0, len(codes)+1)
+	for _, status := range codes {
+		parts = append(parts, fmt.Sprintf("%d×%d", status, s.statuses[status]))
+	}
+	if s.transportErrors > 0 {
+		parts = append(parts, fmt.Sprintf("ERR×%d", s.transportErrors))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,119 @@
+// reencrypt 使用当前 KEK 重新加密已登记的加密列（KEK 轮换后执行，也用于加密历史明文）
+//
+// 用法：go run ./cmd/reencrypt [-batch 500] [-dry-run]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"new-openclaw/internal/database"
+	_ "new-openclaw/internal/model" // 登记模型中的加密列
+	"new-openclaw/pkg/config"
+	"new-openclaw/pkg/secrets"
+)
+
+// row 待处理的记录
+type row struct {
+	ID    uint64
+	Value string
+}
+
+func main() {
+	batch := flag.Int("batch", 500, "每批处理的记录数")
+	dryRun := flag.Bool("dry-run", false, "只统计需要重新加密的记录，不写库")
+	flag.Parse()
+
+	cfg := config.LoadConfig()
+	if err := secrets.Configure(secretsConfig(cfg)); err != nil {
+		log.Fatalf("加载 KEK 失败: %v", err)
+	}
+	keyring := secrets.Default()
+	if !keyring.Enabled() {
+		log.Fatal("未配置 SECRETS_KEK / SECRETS_KEK_FILE")
+	}
+
+	if err := database.InitMySQL(&cfg.MySQL); err != nil {
+		log.Fatalf("MySQL 连接失败: %v", err)
+	}
+	defer database.CloseMySQL()
+
+	columns := secrets.Columns()
+	if len(columns) == 0 {
+		fmt.Println("没有登记的加密列")
+		return
+	}
+
+	failed := false
+	for _, col := range columns {
+		updated, err := reencrypt(keyring, col, *batch, *dryRun)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s.%s: %v\n", col.Table, col.Column, err)
+			failed = true
+			continue
+		}
+		action := "已重新加密"
+		if *dryRun {
+			action = "需要重新加密"
+		}
+		fmt.Printf("✅ %s.%s: %s %d 条（当前 KEK 版本 %s）\n", col.Table, col.Column, action, updated, keyring.Version())
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// reencrypt 按主键分批扫描，将明文或旧版本密文改写为当前版本密文
+func reencrypt(keyring *secrets.Keyring, col secrets.Column, batch int, dryRun bool) (int, error) {
+	db := database.GetMySQL()
+	query := fmt.Sprintf("SELECT id, `%s` AS value FROM `%s` WHERE id > ? ORDER BY id LIMIT ?", col.Column, col.Table)
+	update := fmt.Sprintf("UPDATE `%s` SET `%s` = ? WHERE id = ? AND `%s` = ?", col.Table, col.Column, col.Column)
+
+	var lastID uint64
+	count := 0
+	for {
+		var rows []row
+		if err := db.Raw(query, lastID, batch).Scan(&rows).Error; err != nil {
+			return count, err
+		}
+		if len(rows) == 0 {
+			return count, nil
+		}
+
+		for _, r := range rows {
+			lastID = r.ID
+			if !keyring.NeedsReencrypt(r.Value) {
+				continue
+			}
+			count++
+			if dryRun {
+				continue
+			}
+
+			plaintext, err := keyring.Decrypt(r.Value)
+			if err != nil {
+				return count, fmt.Errorf("id=%d: %w", r.ID, err)
+			}
+			ciphertext, err := keyring.Encrypt(plaintext)
+			if err != nil {
+				return count, fmt.Errorf("id=%d: %w", r.ID, err)
+			}
+			// 以旧值作为条件，避免覆盖并发写入的新值
+			if err := db.Exec(update, ciphertext, r.ID, r.Value).Error; err != nil {
+				return count, fmt.Errorf("id=%d: %w", r.ID, err)
+			}
+		}
+	}
+}
+
+func secretsConfig(cfg *config.Config) secrets.Config {
+	return secrets.Config{
+		KEK:     cfg.Secrets.KEK,
+		KEKFile: cfg.Secrets.KEKFile,
+		Version: cfg.Secrets.KEKVersion,
+		Retired: cfg.Secrets.RetiredKEKs,
+	}
+}
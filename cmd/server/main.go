@@ -6,13 +6,11 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
-	"new-openclaw/internal/admin"
-	"new-openclaw/internal/database"
-	"new-openclaw/internal/handler"
-	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/discovery"
+	"new-openclaw/pkg/auth/token"
 	"new-openclaw/pkg/config"
+	"new-openclaw/pkg/server"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,96 +22,21 @@ func main() {
 	// 设置运行模式
 	gin.SetMode(cfg.Server.Mode)
 
-	// 初始化数据库连接
-	if err := database.InitAll(cfg); err != nil {
-		log.Printf("数据库初始化警告: %v", err)
+	// 初始化依赖、后台任务与完整的中间件、路由（与嵌入方、端到端测试使用同一套组装）
+	r, shutdown, err := server.NewServer(cfg)
+	if err != nil {
+		log.Fatalf("服务初始化失败: %v", err)
 	}
 
-	// 优雅关闭
-	defer database.CloseAll()
-
-	// 创建路由
-	r := gin.New()
-
-	// ========== 安全中间件配置 ==========
-
-	// 1. 基础中间件
-	r.Use(gin.Recovery())
-	r.Use(middleware.RequestID())      // 请求 ID
-	r.Use(middleware.SecureHeaders())  // 安全响应头
-
-	// 2. CORS 跨域
-	r.Use(middleware.Cors())
-
-	// 3. IP 过滤（黑名单/白名单）
-	ipFilterConfig := middleware.IPFilterConfig{
-		WhitelistMode: cfg.Security.IPWhitelistMode,
-		Whitelist:     cfg.Security.IPWhitelist,
-		Blacklist:     cfg.Security.IPBlacklist,
-		AllowPrivate:  true,
-		TrustProxy:    true,
-		ProxyHeader:   "X-Real-IP",
-		BlockHandler:  middleware.DefaultIPFilterConfig.BlockHandler,
-	}
-	r.Use(middleware.IPFilterWithConfig(ipFilterConfig))
-
-	// 4. 全局频率限制
-	rateLimitConfig := middleware.RateLimitConfig{
-		Window:       cfg.Security.RateLimitWindow,
-		MaxRequests:  cfg.Security.RateLimitMaxRequests,
-		KeyFunc:      middleware.DefaultRateLimitConfig.KeyFunc,
-		LimitHandler: middleware.DefaultRateLimitConfig.LimitHandler,
-	}
-	r.Use(middleware.RateLimitWithConfig(rateLimitConfig))
-
-	// 5. 请求日志审计
-	auditConfig := middleware.AuditConfig{
-		Enabled:             cfg.Security.AuditEnabled,
-		Output:              cfg.Security.AuditOutput,
-		FilePath:            cfg.Security.AuditFilePath,
-		LogRequestBody:      true,
-		LogResponseBody:     true,
-		MaxRequestBodySize:  4096,
-		MaxResponseBodySize: 4096,
-		SensitiveFields:     []string{"password", "token", "secret", "key", "authorization"},
-		ExcludePaths:        []string{"/ping", "/health", "/metrics"},
-		Async:               true,
-		BufferSize:          1000,
-	}
-	r.Use(middleware.AuditWithConfig(auditConfig))
-
-	// 6. 安全审计（检测攻击行为）
-	r.Use(middleware.SecurityAudit())
-
-	// 7. 日志中间件
-	r.Use(middleware.Logger())
-
-	// 更新 JWT 配置
-	middleware.DefaultJWTConfig = middleware.JWTConfig{
-		SecretKey:     cfg.Security.JWTSecretKey,
-		TokenExpiry:   cfg.Security.JWTExpiry,
-		RefreshExpiry: cfg.Security.JWTRefreshExpiry,
-		Issuer:        cfg.Security.JWTIssuer,
-	}
-
-	// 更新 API 签名配置
-	middleware.DefaultSignatureConfig = middleware.SignatureConfig{
-		SecretKey:      cfg.Security.APISignatureKey,
-		Expiry:         cfg.Security.APISignatureExpiry,
-		Algorithm:      "hmac-sha256",
-		TimeTolerance:  time.Minute * 2,
-		SignatureParam: "sign",
-		TimestampParam: "timestamp",
-		NonceParam:     "nonce",
-		AppKeyParam:    "app_key",
-		ValidateBody:   true,
-	}
-
-	// ========== 注册路由 ==========
-	handler.RegisterRoutes(r)
-
-	// 注册管理后台路由
-	admin.RegisterRoutes(r)
+	// 收到 SIGHUP 时重新加载 JWT 密钥（密钥轮换无需重启）
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			token.ReloadKeys()
+			log.Println("🔑 JWT 密钥已重新加载")
+		}
+	}()
 
 	// 监听退出信号
 	quit := make(chan os.Signal, 1)
@@ -122,7 +45,11 @@ func main() {
 	go func() {
 		<-quit
 		log.Println("正在关闭服务...")
-		database.CloseAll()
+		discovery.Deregister()
+		if err := shutdown(); err != nil {
+			log.Printf("关闭服务时出错: %v", err)
+			os.Exit(1)
+		}
 		os.Exit(0)
 	}()
 
@@ -131,10 +58,22 @@ func main() {
 	log.Printf("🚀 服务启动在 http://localhost%s", addr)
 	log.Printf("📋 安全功能已启用:")
 	log.Printf("   - JWT Token 认证")
-	log.Printf("   - 请求频率限制 (%d 次/%v)", cfg.Security.RateLimitMaxRequests, cfg.Security.RateLimitWindow)
+	log.Printf("   - 请求频率限制 (%d 次/%v, 算法: %s)", cfg.Security.RateLimitMaxRequests, cfg.Security.RateLimitWindow, cfg.Security.RateLimitAlgorithm)
 	log.Printf("   - API 签名验证")
 	log.Printf("   - IP 过滤 (白名单模式: %v)", cfg.Security.IPWhitelistMode)
 	log.Printf("   - 请求日志审计 (输出: %s)", cfg.Security.AuditOutput)
+	log.Printf("   - WAF 规则 (内置规则: %v, 动作: %s)", cfg.WAF.BuiltinRules, cfg.WAF.BuiltinAction)
+	if cfg.LoginGuard.Enabled {
+		log.Printf("   - 登录暴力破解防护 (%d 次失败后锁定 %v)", cfg.LoginGuard.LockThreshold, cfg.LoginGuard.LockDuration)
+	}
+	if cfg.Standalone() {
+		log.Printf("🧪 单机模式 (SQLite: %s，状态存储均为内存，功能可用性见 /admin/system/info)", cfg.Server.SQLitePath)
+	}
+
+	// 注册到服务发现（失败不影响启动）
+	if err := discovery.Register(cfg.Discovery, cfg.Server.Port, cfg.Server.Version); err != nil {
+		log.Printf("服务发现注册警告: %v", err)
+	}
 
 	if err := r.Run(addr); err != nil {
 		log.Fatalf("服务启动失败: %v", err)
@@ -0,0 +1,258 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/jobs"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/notify"
+	"new-openclaw/internal/webhook"
+	"new-openclaw/pkg/config"
+)
+
+// 异常类型
+const (
+	AnomalyBulkDelete      = "bulk_delete"
+	AnomalyOffHours        = "off_hours"
+	AnomalyPermissionSpike = "permission_spike"
+)
+
+// permissionPaths 涉及权限变更的路径前缀
+var permissionPaths = []string{
+	"/admin/admins",
+	"/admin/roles",
+	"/admin/permissions",
+}
+
+// cfg 异常检测配置
+var cfg = config.AnalyticsConfig{
+	WorkHourStart:           9,
+	WorkHourEnd:             19,
+	BulkDeleteThreshold:     10,
+	BulkDeleteWindow:        10 * time.Minute,
+	PermissionEditThreshold: 5,
+	PermissionEditWindow:    time.Hour,
+}
+
+// Configure 设置异常检测配置
+func Configure(c config.AnalyticsConfig) {
+	cfg = c
+}
+
+// Heatmap 管理员活动热力图（按星期 × 小时统计）
+type Heatmap struct {
+	// Cells[weekday][hour]，weekday 0 为星期日
+	Cells   [7][24]int     `json:"cells"`
+	Total   int            `json:"total"`
+	ByAdmin map[string]int `json:"by_admin"`
+}
+
+// Anomaly 异常行为
+type Anomaly struct {
+	Type     string    `json:"type"`
+	AdminID  uint      `json:"admin_id"`
+	Username string    `json:"username"`
+	Count    int       `json:"count"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Detail   string    `json:"detail"`
+}
+
+// LoadLogs 加载指定时间之后的操作日志
+func LoadLogs(since time.Time) ([]model.OperationLog, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, fmt.Errorf("数据库未连接")
+	}
+
+	var logs []model.OperationLog
+	err := db.Where("created_at >= ?", since).Order("created_at ASC").Find(&logs).Error
+	return logs, err
+}
+
+// BuildHeatmap 构建活动热力图
+func BuildHeatmap(logs []model.OperationLog) *Heatmap {
+	h := &Heatmap{ByAdmin: make(map[string]int)}
+	for _, l := range logs {
+		t := l.CreatedAt.Local()
+		h.Cells[t.Weekday()][t.Hour()]++
+		h.ByAdmin[l.Username]++
+		h.Total++
+	}
+	return h
+}
+
+// Detect 检测异常行为（logs 需按时间升序）
+func Detect(logs []model.OperationLog) []Anomaly {
+	var anomalies []Anomaly
+
+	deletes := make(map[uint][]model.OperationLog)
+	permissionEdits := make(map[uint][]model.OperationLog)
+	offHours := make(map[uint][]model.OperationLog)
+
+	for _, l := range logs {
+		if l.Method == "DELETE" {
+			deletes[l.AdminID] = append(deletes[l.AdminID], l)
+		}
+		if isPermissionPath(l.Path) {
+			permissionEdits[l.AdminID] = append(permissionEdits[l.AdminID], l)
+		}
+		if isOffHours(l.CreatedAt) {
+			offHours[l.AdminID] = append(offHours[l.AdminID], l)
+		}
+	}
+
+	for _, group := range deletes {
+		if a, ok := detectBurst(group, cfg.BulkDeleteThreshold, cfg.BulkDeleteWindow); ok {
+			a.Type = AnomalyBulkDelete
+			a.Detail = fmt.Sprintf("%v 内删除 %d 次", cfg.BulkDeleteWindow, a.Count)
+			anomalies = append(anomalies, a)
+		}
+	}
+
+	for _, group := range permissionEdits {
+		if a, ok := detectBurst(group, cfg.PermissionEditThreshold, cfg.PermissionEditWindow); ok {
+			a.Type = AnomalyPermissionSpike
+			a.Detail = fmt.Sprintf("%v 内修改权限 %d 次", cfg.PermissionEditWindow, a.Count)
+			anomalies = append(anomalies, a)
+		}
+	}
+
+	for _, group := range offHours {
+		first, last := group[0], group[len(group)-1]
+		anomalies = append(anomalies, Anomaly{
+			Type:     AnomalyOffHours,
+			AdminID:  first.AdminID,
+			Username: first.Username,
+			Count:    len(group),
+			Start:    first.CreatedAt,
+			End:      last.CreatedAt,
+			Detail:   fmt.Sprintf("非工作时间（%02d:00-%02d:00 以外）操作 %d 次", cfg.WorkHourStart, cfg.WorkHourEnd, len(group)),
+		})
+	}
+
+	return anomalies
+}
+
+// detectBurst 滑动窗口检测突发操作，返回次数最多的窗口
+func detectBurst(logs []model.OperationLog, threshold int, window time.Duration) (Anomaly, bool) {
+	if threshold <= 0 || len(logs) < threshold {
+		return Anomaly{}, false
+	}
+
+	best, bestStart, start := 0, 0, 0
+	for end := range logs {
+		for logs[end].CreatedAt.Sub(logs[start].CreatedAt) > window {
+			start++
+		}
+		if n := end - start + 1; n > best {
+			best, bestStart = n, start
+		}
+	}
+
+	if best < threshold {
+		return Anomaly{}, false
+	}
+
+	first, last := logs[bestStart], logs[bestStart+best-1]
+	return Anomaly{
+		AdminID:  first.AdminID,
+		Username: first.Username,
+		Count:    best,
+		Start:    first.CreatedAt,
+		End:      last.CreatedAt,
+	}, true
+}
+
+// isPermissionPath 检查是否为权限相关路径
+func isPermissionPath(path string) bool {
+	for _, prefix := range permissionPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isOffHours 检查是否为非工作时间
+func isOffHours(t time.Time) bool {
+	hour := t.Local().Hour()
+	return hour < cfg.WorkHourStart || hour >= cfg.WorkHourEnd
+}
+
+// RegisterAlertJob 注册异常告警定时任务（定期检测并推送到 Webhook 与管理员安全通知，多副本部署时仅由 Leader 推送）
+func RegisterAlertJob() {
+	if cfg.AlertInterval <= 0 {
+		return
+	}
+
+	jobs.Register(jobs.Job{
+		Name:        "admin_anomaly_alerts",
+		Description: "检测管理员异常行为并推送告警",
+		Interval:    cfg.AlertInterval,
+		LeaderOnly:  true,
+		Run:         runAlerts,
+	})
+}
+
+// runAlerts 检测最近一个周期的异常行为并推送
+func runAlerts(ctx context.Context) error {
+	logs, err := LoadLogs(time.Now().Add(-cfg.AlertInterval))
+	if err != nil {
+		return err
+	}
+	anomalies := Detect(logs)
+	if len(anomalies) == 0 {
+		return nil
+	}
+	log.Printf("[SECURITY ALERT] 检测到 %d 条管理员异常行为", len(anomalies))
+	sendAlert(anomalies)
+	return nil
+}
+
+// sendAlert 推送告警
+func sendAlert(anomalies []Anomaly) {
+	lines := make([]string, len(anomalies))
+	for i, a := range anomalies {
+		lines[i] = fmt.Sprintf("%s（%s）：%s", a.Username, a.Type, a.Detail)
+	}
+	title := fmt.Sprintf("检测到 %d 条管理员异常行为", len(anomalies))
+	if err := notify.Notify(notify.CategorySecurity, title, strings.Join(lines, "\n")); err != nil {
+		log.Printf("发送异常告警通知失败: %v", err)
+	}
+	if _, err := webhook.Emit(webhook.EventAdminAnomaly, map[string]interface{}{
+		"anomalies": anomalies,
+		"count":     len(anomalies),
+	}); err != nil {
+		log.Printf("投递异常告警 Webhook 失败: %v", err)
+	}
+
+	if cfg.AlertWebhook == "" {
+		return
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"event":     "admin_anomaly",
+		"timestamp": time.Now(),
+		"anomalies": anomalies,
+	})
+	if err != nil {
+		return
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(cfg.AlertWebhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("推送异常告警失败: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,206 @@
+package dashboard
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/admin/analytics"
+	adminmiddleware "new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/auditstore"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/secevents"
+	"new-openclaw/internal/session"
+	"new-openclaw/pkg/config"
+)
+
+// 首页统计中列出的接口数与安全事件数
+const (
+	topEndpoints         = 10
+	recentSecurityEvents = 10
+)
+
+// statsTimeout 单次统计的超时时间（各数据源共用）
+const statsTimeout = 5 * time.Second
+
+// AccountStats 账号数
+type AccountStats struct {
+	Total    int64 `json:"total"`
+	Active   int64 `json:"active"`
+	NewToday int64 `json:"new_today"`
+}
+
+// RequestStats 今日请求统计（来自 MongoDB 中的审计日志）
+type RequestStats struct {
+	auditstore.Summary
+	// 4xx、5xx 占总请求数的比例
+	ClientErrorRate float64                    `json:"client_error_rate"`
+	ServerErrorRate float64                    `json:"server_error_rate"`
+	TopEndpoints    []auditstore.EndpointCount `json:"top_endpoints"`
+	Since           time.Time                  `json:"since"`
+}
+
+// SessionStats 有效会话（管理员与用户分别统计账号数与会话数）
+type SessionStats struct {
+	AdminAccounts int `json:"admin_accounts"`
+	AdminSessions int `json:"admin_sessions"`
+	UserAccounts  int `json:"user_accounts"`
+	UserSessions  int `json:"user_sessions"`
+}
+
+// Stats 管理后台首页统计；数据源不可用时对应字段为空，原因记录在 Unavailable 中
+type Stats struct {
+	Admins         *AccountStats         `json:"admins"`
+	Users          *AccountStats         `json:"users"`
+	Requests       *RequestStats         `json:"requests"`
+	Sessions       *SessionStats         `json:"sessions"`
+	SecurityEvents []model.SecurityEvent `json:"security_events"`
+	Unavailable    map[string]string     `json:"unavailable,omitempty"`
+	GeneratedAt    time.Time             `json:"generated_at"`
+}
+
+var (
+	statsCfg = config.DashboardConfig{StatsCacheTTL: time.Minute}
+
+	// statsCache 本实例缓存的统计结果；统计期间持有锁，并发请求等待同一次统计
+	statsCache struct {
+		mu    sync.Mutex
+		stats *Stats
+	}
+
+	// anomalyCache 本实例缓存的异常检测结果（按时间范围），避免每次加载首页都重新扫描操作日志
+	anomalyCache struct {
+		mu      sync.Mutex
+		entries map[time.Duration]anomalyEntry
+	}
+)
+
+// anomalyEntry 一个时间范围的异常检测结果
+type anomalyEntry struct {
+	generatedAt time.Time
+	anomalies   []analytics.Anomaly
+}
+
+// Configure 设置首页统计与异常检测结果的缓存时间
+func Configure(c config.DashboardConfig) {
+	statsCache.mu.Lock()
+	defer statsCache.mu.Unlock()
+	anomalyCache.mu.Lock()
+	defer anomalyCache.mu.Unlock()
+	statsCfg = c
+	statsCache.stats = nil
+	anomalyCache.entries = nil
+}
+
+// LoadStats 获取首页统计（缓存 DASHBOARD_STATS_CACHE_TTL，refresh 为 true 时重新统计）
+func LoadStats(refresh bool) *Stats {
+	statsCache.mu.Lock()
+	defer statsCache.mu.Unlock()
+
+	now := time.Now()
+	if cached := statsCache.stats; cached != nil && !refresh && now.Sub(cached.GeneratedAt) < statsCfg.StatsCacheTTL {
+		return cached
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), statsTimeout)
+	defer cancel()
+	stats := computeStats(ctx, now)
+	statsCache.stats = stats
+	return stats
+}
+
+// loadAnomalies 获取 [from, to) 内的管理员异常行为（同一时间范围的结果缓存 DASHBOARD_STATS_CACHE_TTL）
+func loadAnomalies(from, to time.Time) ([]analytics.Anomaly, error) {
+	anomalyCache.mu.Lock()
+	defer anomalyCache.mu.Unlock()
+
+	span := to.Sub(from)
+	if cached, ok := anomalyCache.entries[span]; ok && to.Sub(cached.generatedAt) < statsCfg.StatsCacheTTL {
+		return cached.anomalies, nil
+	}
+	logs, err := analytics.LoadLogs(from)
+	if err != nil {
+		return nil, err
+	}
+	anomalies := analytics.Detect(logs)
+	if anomalyCache.entries == nil {
+		anomalyCache.entries = make(map[time.Duration]anomalyEntry)
+	}
+	anomalyCache.entries[span] = anomalyEntry{generatedAt: to, anomalies: anomalies}
+	return anomalies, nil
+}
+
+// computeStats 从各数据源统计（单个数据源失败不影响其他统计）
+func computeStats(ctx context.Context, now time.Time) *Stats {
+	stats := &Stats{GeneratedAt: now, Unavailable: make(map[string]string)}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	if db := database.GetMySQL(); db != nil {
+		admins := &AccountStats{}
+		users := &AccountStats{}
+		db = db.WithContext(ctx)
+		err := db.Model(&model.Admin{}).Count(&admins.Total).Error
+		if err == nil {
+			err = db.Model(&model.Admin{}).Where("status = ?", 1).Count(&admins.Active).Error
+		}
+		if err == nil {
+			err = db.Model(&model.Admin{}).Where("created_at >= ?", today).Count(&admins.NewToday).Error
+		}
+		if err == nil {
+			err = db.Model(&model.User{}).Count(&users.Total).Error
+		}
+		if err == nil {
+			err = db.Model(&model.User{}).Where("status = ?", model.UserStatusActive).Count(&users.Active).Error
+		}
+		if err == nil {
+			err = db.Model(&model.User{}).Where("created_at >= ?", today).Count(&users.NewToday).Error
+		}
+		if err != nil {
+			stats.Unavailable["accounts"] = err.Error()
+		} else {
+			stats.Admins, stats.Users = admins, users
+		}
+	} else {
+		stats.Unavailable["accounts"] = "数据库未连接"
+	}
+
+	filter := auditstore.Filter{From: today}
+	if summary, err := auditstore.Summarize(ctx, filter); err != nil {
+		stats.Unavailable["requests"] = err.Error()
+	} else {
+		requests := &RequestStats{Summary: *summary, Since: today}
+		if summary.Total > 0 {
+			requests.ClientErrorRate = float64(summary.ClientErrors) / float64(summary.Total)
+			requests.ServerErrorRate = float64(summary.ServerErrors) / float64(summary.Total)
+		}
+		if requests.TopEndpoints, err = auditstore.TopEndpoints(ctx, filter, topEndpoints); err != nil {
+			stats.Unavailable["requests"] = err.Error()
+		} else {
+			stats.Requests = requests
+		}
+	}
+
+	sessions := &SessionStats{}
+	var err error
+	sessions.AdminAccounts, sessions.AdminSessions, err = session.Count(ctx, adminmiddleware.DefaultConfig.Issuer)
+	if err == nil {
+		sessions.UserAccounts, sessions.UserSessions, err = session.Count(ctx, middleware.CurrentJWTConfig().Issuer)
+	}
+	if err != nil {
+		stats.Unavailable["sessions"] = err.Error()
+	} else {
+		stats.Sessions = sessions
+	}
+
+	if events, _, err := secevents.List(ctx, secevents.Filter{}, 1, recentSecurityEvents); err != nil {
+		stats.Unavailable["security_events"] = err.Error()
+	} else {
+		stats.SecurityEvents = events
+	}
+
+	if len(stats.Unavailable) == 0 {
+		stats.Unavailable = nil
+	}
+	return stats
+}
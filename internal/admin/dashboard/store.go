@@ -0,0 +1,262 @@
+package dashboard
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"new-openclaw/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// collection 仪表盘集合
+const collection = "admin_dashboards"
+
+// 每个管理员的仪表盘数量及每个仪表盘的组件数量上限
+const (
+	MaxDashboards = 20
+	MaxWidgets    = 24
+)
+
+var (
+	// ErrUnavailable MongoDB 未连接
+	ErrUnavailable = errors.New("MongoDB 未连接，自定义仪表盘不可用")
+	// ErrNotFound 仪表盘不存在
+	ErrNotFound = errors.New("仪表盘不存在")
+	// ErrLimit 仪表盘数量已达上限
+	ErrLimit = fmt.Errorf("每个管理员最多 %d 个仪表盘", MaxDashboards)
+)
+
+// Layout 组件在网格中的位置与大小
+type Layout struct {
+	X int `bson:"x" json:"x"`
+	Y int `bson:"y" json:"y"`
+	W int `bson:"w" json:"w"`
+	H int `bson:"h" json:"h"`
+}
+
+// Widget 组件定义：指标、图表类型、时间范围
+type Widget struct {
+	ID     string  `bson:"id" json:"id"`
+	Title  string  `bson:"title" json:"title"`
+	Metric string  `bson:"metric" json:"metric" binding:"required"`
+	Chart  string  `bson:"chart" json:"chart" binding:"required"`
+	Range  string  `bson:"range" json:"range" binding:"required"`
+	Layout *Layout `bson:"layout,omitempty" json:"layout,omitempty"`
+}
+
+// Dashboard 管理员自定义仪表盘（按管理员存储在 MongoDB）
+type Dashboard struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	AdminID uint               `bson:"admin_id" json:"admin_id"`
+	Name    string             `bson:"name" json:"name"`
+	// 是否为首页（GET /admin/dashboard）展示的仪表盘
+	Default   bool      `bson:"default" json:"default"`
+	Widgets   []Widget  `bson:"widgets" json:"widgets"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// Builtin 内置仪表盘（管理员未设置首页仪表盘或 MongoDB 不可用时使用）
+func Builtin(role string) Dashboard {
+	d := Dashboard{Name: "默认仪表盘"}
+	if role == "super_admin" {
+		d.Widgets = []Widget{
+			{ID: "operations", Title: "管理员操作", Metric: "admin_operations", Chart: ChartLine, Range: "24h"},
+			{ID: "anomalies", Title: "异常行为", Metric: "admin_anomalies", Chart: ChartTable, Range: "24h"},
+			{ID: "dependencies", Title: "依赖状态变化", Metric: "dependency_status_changes", Chart: ChartBar, Range: "7d"},
+			{ID: "webhooks", Title: "Webhook 投递", Metric: "webhook_deliveries", Chart: ChartPie, Range: "24h"},
+		}
+		return d
+	}
+	d.Widgets = []Widget{
+		{ID: "my-operations", Title: "我的操作", Metric: "my_operations", Chart: ChartLine, Range: "7d"},
+		{ID: "my-actions", Title: "操作分布", Metric: "my_operations", Chart: ChartTable, Range: "7d"},
+	}
+	return d
+}
+
+// Validate 校验组件定义并为缺少 ID 的组件生成 ID
+func Validate(widgets []Widget, role string) error {
+	if len(widgets) > MaxWidgets {
+		return fmt.Errorf("每个仪表盘最多 %d 个组件", MaxWidgets)
+	}
+	seen := make(map[string]bool, len(widgets))
+	for i := range widgets {
+		if _, err := validateWidget(widgets[i], role); err != nil {
+			return fmt.Errorf("组件 %d: %v", i+1, err)
+		}
+		if widgets[i].ID == "" {
+			widgets[i].ID = newWidgetID()
+		}
+		if seen[widgets[i].ID] {
+			return fmt.Errorf("组件 ID 重复: %s", widgets[i].ID)
+		}
+		seen[widgets[i].ID] = true
+	}
+	return nil
+}
+
+// List 获取管理员的仪表盘
+func List(ctx context.Context, adminID uint) ([]Dashboard, error) {
+	coll, err := dashboards()
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := coll.Find(ctx, bson.M{"admin_id": adminID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	list := []Dashboard{}
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Get 获取管理员的仪表盘
+func Get(ctx context.Context, adminID uint, id string) (*Dashboard, error) {
+	coll, err := dashboards()
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	var d Dashboard
+	err = coll.FindOne(ctx, bson.M{"_id": oid, "admin_id": adminID}).Decode(&d)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Default 获取管理员的首页仪表盘（未设置时返回 nil）
+func Default(ctx context.Context, adminID uint) (*Dashboard, error) {
+	coll, err := dashboards()
+	if err != nil {
+		return nil, err
+	}
+
+	var d Dashboard
+	err = coll.FindOne(ctx, bson.M{"admin_id": adminID, "default": true}).Decode(&d)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Create 创建仪表盘（组件需先经过 Validate）
+func Create(ctx context.Context, d *Dashboard) error {
+	coll, err := dashboards()
+	if err != nil {
+		return err
+	}
+
+	count, err := coll.CountDocuments(ctx, bson.M{"admin_id": d.AdminID})
+	if err != nil {
+		return err
+	}
+	if count >= MaxDashboards {
+		return ErrLimit
+	}
+
+	now := time.Now()
+	d.ID = primitive.NewObjectID()
+	d.CreatedAt, d.UpdatedAt = now, now
+	if d.Widgets == nil {
+		d.Widgets = []Widget{}
+	}
+	if d.Default {
+		if err := clearDefault(ctx, coll, d.AdminID); err != nil {
+			return err
+		}
+	}
+	_, err = coll.InsertOne(ctx, d)
+	return err
+}
+
+// Update 更新仪表盘名称、组件及是否为首页
+func Update(ctx context.Context, d *Dashboard) error {
+	coll, err := dashboards()
+	if err != nil {
+		return err
+	}
+
+	if d.Default {
+		if err := clearDefault(ctx, coll, d.AdminID); err != nil {
+			return err
+		}
+	}
+	d.UpdatedAt = time.Now()
+	result, err := coll.UpdateOne(ctx, bson.M{"_id": d.ID, "admin_id": d.AdminID}, bson.M{"$set": bson.M{
+		"name":       d.Name,
+		"widgets":    d.Widgets,
+		"default":    d.Default,
+		"updated_at": d.UpdatedAt,
+	}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete 删除仪表盘
+func Delete(ctx context.Context, adminID uint, id string) error {
+	coll, err := dashboards()
+	if err != nil {
+		return err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrNotFound
+	}
+
+	result, err := coll.DeleteOne(ctx, bson.M{"_id": oid, "admin_id": adminID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// clearDefault 取消管理员其他仪表盘的首页标记
+func clearDefault(ctx context.Context, coll *mongo.Collection, adminID uint) error {
+	_, err := coll.UpdateMany(ctx, bson.M{"admin_id": adminID, "default": true}, bson.M{"$set": bson.M{"default": false}})
+	return err
+}
+
+// dashboards 仪表盘集合（MongoDB 未连接时返回 ErrUnavailable）
+func dashboards() (*mongo.Collection, error) {
+	if database.GetMongoDB() == nil {
+		return nil, ErrUnavailable
+	}
+	return database.GetMongoCollection(collection), nil
+}
+
+// newWidgetID 生成组件 ID
+func newWidgetID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,331 @@
+package dashboard
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+)
+
+// 图表类型
+const (
+	ChartLine   = "line"
+	ChartBar    = "bar"
+	ChartPie    = "pie"
+	ChartNumber = "number"
+	ChartTable  = "table"
+)
+
+// Ranges 可选的时间范围
+var Ranges = []string{"1h", "24h", "7d", "30d", "90d"}
+
+// row 指标的一条源数据：发生时间与分组维度
+type row struct {
+	At  time.Time
+	Key string
+}
+
+// Metric 可用于组件的指标（按时间分桶、按维度分组的汇总）
+type Metric struct {
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// 分组维度说明（pie、table 图表按该维度分组）
+	GroupBy string   `json:"group_by"`
+	Charts  []string `json:"charts"`
+	// 仅超级管理员可用
+	SuperAdminOnly bool `json:"super_admin_only"`
+
+	load func(from, to time.Time, adminID uint) ([]row, error)
+}
+
+// catalog 组件目录
+var catalog = []Metric{
+	{
+		Name:           "admin_operations",
+		Title:          "管理员操作",
+		Description:    "管理后台写操作次数",
+		GroupBy:        "管理员",
+		Charts:         []string{ChartLine, ChartBar, ChartPie, ChartNumber, ChartTable},
+		SuperAdminOnly: true,
+		load: func(from, to time.Time, _ uint) ([]row, error) {
+			return loadOperations(from, to, func(l model.OperationLog) string { return l.Username }, "")
+		},
+	},
+	{
+		Name:           "failed_operations",
+		Title:          "失败的操作",
+		Description:    "响应状态码 ≥ 400 的管理后台写操作",
+		GroupBy:        "操作",
+		Charts:         []string{ChartLine, ChartBar, ChartNumber, ChartTable},
+		SuperAdminOnly: true,
+		load: func(from, to time.Time, _ uint) ([]row, error) {
+			return loadOperations(from, to, func(l model.OperationLog) string { return l.Action }, "status >= ?", 400)
+		},
+	},
+	{
+		Name:        "my_operations",
+		Title:       "我的操作",
+		Description: "当前管理员的写操作次数",
+		GroupBy:     "操作",
+		Charts:      []string{ChartLine, ChartBar, ChartPie, ChartNumber, ChartTable},
+		load: func(from, to time.Time, adminID uint) ([]row, error) {
+			return loadOperations(from, to, func(l model.OperationLog) string { return l.Action }, "admin_id = ?", adminID)
+		},
+	},
+	{
+		Name:           "admin_anomalies",
+		Title:          "管理员异常行为",
+		Description:    "批量删除、非工作时间操作、权限修改激增",
+		GroupBy:        "异常类型",
+		Charts:         []string{ChartBar, ChartPie, ChartNumber, ChartTable},
+		SuperAdminOnly: true,
+		load: func(from, to time.Time, _ uint) ([]row, error) {
+			anomalies, err := loadAnomalies(from, to)
+			if err != nil {
+				return nil, err
+			}
+			var rows []row
+			for _, a := range anomalies {
+				rows = append(rows, row{At: a.Start, Key: a.Type})
+			}
+			return rows, nil
+		},
+	},
+	{
+		Name:           "dependency_status_changes",
+		Title:          "依赖状态变化",
+		Description:    "MySQL、Redis、MongoDB、审计日志输出的连接状态变化（health_events）",
+		GroupBy:        "组件",
+		Charts:         []string{ChartLine, ChartBar, ChartPie, ChartNumber, ChartTable},
+		SuperAdminOnly: true,
+		load: func(from, to time.Time, _ uint) ([]row, error) {
+			db := database.GetMySQL()
+			if db == nil {
+				return nil, fmt.Errorf("数据库未连接")
+			}
+			var events []model.HealthEvent
+			if err := db.Select("component", "occurred_at").
+				Where("occurred_at >= ? AND occurred_at < ?", from, to).Find(&events).Error; err != nil {
+				return nil, err
+			}
+			rows := make([]row, len(events))
+			for i, e := range events {
+				rows[i] = row{At: e.OccurredAt, Key: e.Component}
+			}
+			return rows, nil
+		},
+	},
+	{
+		Name:           "webhook_deliveries",
+		Title:          "Webhook 投递",
+		Description:    "Webhook 投递数量",
+		GroupBy:        "状态",
+		Charts:         []string{ChartLine, ChartBar, ChartPie, ChartNumber, ChartTable},
+		SuperAdminOnly: true,
+		load: func(from, to time.Time, _ uint) ([]row, error) {
+			db := database.GetMySQL()
+			if db == nil {
+				return nil, fmt.Errorf("数据库未连接")
+			}
+			var deliveries []model.WebhookDelivery
+			if err := db.Select("status", "created_at").
+				Where("created_at >= ? AND created_at < ?", from, to).Find(&deliveries).Error; err != nil {
+				return nil, err
+			}
+			rows := make([]row, len(deliveries))
+			for i, d := range deliveries {
+				rows[i] = row{At: d.CreatedAt, Key: d.Status}
+			}
+			return rows, nil
+		},
+	},
+}
+
+// Catalog 角色可用的组件目录
+func Catalog(role string) []Metric {
+	var list []Metric
+	for _, m := range catalog {
+		if !m.SuperAdminOnly || role == "super_admin" {
+			list = append(list, m)
+		}
+	}
+	return list
+}
+
+// lookupMetric 查找指标
+func lookupMetric(name string) (Metric, bool) {
+	for _, m := range catalog {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Metric{}, false
+}
+
+// loadOperations 加载时间范围内的操作日志（where 为额外条件），key 取分组维度
+func loadOperations(from, to time.Time, key func(model.OperationLog) string, where string, args ...interface{}) ([]row, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, fmt.Errorf("数据库未连接")
+	}
+	query := db.Select("username", "action", "created_at").Where("created_at >= ? AND created_at < ?", from, to)
+	if where != "" {
+		query = query.Where(where, args...)
+	}
+	var logs []model.OperationLog
+	if err := query.Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	rows := make([]row, len(logs))
+	for i, l := range logs {
+		rows[i] = row{At: l.CreatedAt, Key: key(l)}
+	}
+	return rows, nil
+}
+
+// Point 时间桶及其计数
+type Point struct {
+	At    time.Time `json:"at"`
+	Value int       `json:"value"`
+}
+
+// Group 分组及其计数
+type Group struct {
+	Key   string `json:"key"`
+	Value int    `json:"value"`
+}
+
+// Data 组件数据：number 只返回 total，line/bar 返回 points，pie/table 返回 groups
+type Data struct {
+	Total    int     `json:"total"`
+	Interval string  `json:"interval,omitempty"`
+	Points   []Point `json:"points,omitempty"`
+	Groups   []Group `json:"groups,omitempty"`
+}
+
+// Query 查询组件数据
+func Query(w Widget, role string, adminID uint, now time.Time) (*Data, error) {
+	m, err := validateWidget(w, role)
+	if err != nil {
+		return nil, err
+	}
+
+	span, _ := parseRange(w.Range)
+	from := now.Add(-span)
+	rows, err := m.load(from, now, adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &Data{Total: len(rows)}
+	switch w.Chart {
+	case ChartLine, ChartBar:
+		interval := bucketInterval(span)
+		data.Interval = interval.String()
+		start := from.Truncate(interval)
+		counts := make([]int, int(now.Sub(start)/interval)+1)
+		for _, r := range rows {
+			if i := int(r.At.Sub(start) / interval); i >= 0 && i < len(counts) {
+				counts[i]++
+			}
+		}
+		data.Points = make([]Point, len(counts))
+		for i, n := range counts {
+			data.Points[i] = Point{At: start.Add(time.Duration(i) * interval), Value: n}
+		}
+	case ChartPie, ChartTable:
+		counts := make(map[string]int)
+		for _, r := range rows {
+			counts[r.Key]++
+		}
+		for key, n := range counts {
+			data.Groups = append(data.Groups, Group{Key: key, Value: n})
+		}
+		sort.Slice(data.Groups, func(i, j int) bool {
+			if data.Groups[i].Value != data.Groups[j].Value {
+				return data.Groups[i].Value > data.Groups[j].Value
+			}
+			return data.Groups[i].Key < data.Groups[j].Key
+		})
+	}
+	return data, nil
+}
+
+// validateWidget 校验组件定义（指标存在且角色可用、图表类型受支持、时间范围有效）
+func validateWidget(w Widget, role string) (Metric, error) {
+	m, ok := lookupMetric(w.Metric)
+	if !ok {
+		return Metric{}, fmt.Errorf("未知的指标: %s", w.Metric)
+	}
+	if m.SuperAdminOnly && role != "super_admin" {
+		return Metric{}, fmt.Errorf("指标 %s 仅超级管理员可用", w.Metric)
+	}
+	supported := false
+	for _, chart := range m.Charts {
+		supported = supported || chart == w.Chart
+	}
+	if !supported {
+		return Metric{}, fmt.Errorf("指标 %s 不支持图表类型 %s（可选 %s）", w.Metric, w.Chart, strings.Join(m.Charts, ", "))
+	}
+	if _, err := parseRange(w.Range); err != nil {
+		return Metric{}, err
+	}
+	return m, nil
+}
+
+// parseRange 解析时间范围（如 1h、24h、7d）
+func parseRange(s string) (time.Duration, error) {
+	for _, r := range Ranges {
+		if r != s {
+			continue
+		}
+		if strings.HasSuffix(s, "d") {
+			days, _ := strconv.Atoi(strings.TrimSuffix(s, "d"))
+			return time.Duration(days) * 24 * time.Hour, nil
+		}
+		return time.ParseDuration(s)
+	}
+	return 0, fmt.Errorf("无效的时间范围: %s（可选 %s）", s, strings.Join(Ranges, ", "))
+}
+
+// bucketInterval 按时间范围选择分桶间隔
+func bucketInterval(span time.Duration) time.Duration {
+	switch {
+	case span <= time.Hour:
+		return 5 * time.Minute
+	case span <= 24*time.Hour:
+		return time.Hour
+	case span <= 7*24*time.Hour:
+		return 6 * time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+// Rendered 组件定义及其数据（查询失败时 Error 非空，不影响其他组件）
+type Rendered struct {
+	Widget
+	Data  *Data  `json:"data,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Render 查询仪表盘所有组件的数据（按当前角色校验，角色降级后不再返回受限指标）
+func Render(d Dashboard, role string, adminID uint) []Rendered {
+	now := time.Now()
+	list := make([]Rendered, len(d.Widgets))
+	for i, w := range d.Widgets {
+		list[i].Widget = w
+		data, err := Query(w, role, adminID, now)
+		if err != nil {
+			list[i].Error = err.Error()
+			continue
+		}
+		list[i].Data = data
+	}
+	return list
+}
@@ -2,10 +2,17 @@ package handler
 
 import (
 	"net/http"
+	"reflect"
 	"strconv"
 
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/admin/rbac"
+	"new-openclaw/internal/admin/scope"
+	"new-openclaw/internal/admin/views"
 	"new-openclaw/internal/database"
 	"new-openclaw/internal/model"
+	"new-openclaw/internal/session"
+	"new-openclaw/internal/tags"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,6 +21,18 @@ import (
 // @Summary 获取管理员列表
 // @Tags Admin
 // @Produce json
+// @Param view query int false "应用保存的视图"
+// @Param keyword query string false "关键字（用户名、昵称、邮箱模糊匹配）"
+// @Param username query string false "用户名（模糊）"
+// @Param nickname query string false "昵称（模糊）"
+// @Param email query string false "邮箱（模糊）"
+// @Param tag query string false "标签（逗号分隔，需同时带有）"
+// @Param role query string false "角色"
+// @Param status query int false "状态"
+// @Param created_from query string false "创建时间起（RFC3339 或 2006-01-02）"
+// @Param created_to query string false "创建时间止（RFC3339 或 2006-01-02）"
+// @Param sort query string false "排序字段，- 前缀表示降序"
+// @Param order query string false "排序方向 asc、desc（覆盖 sort 中的方向）"
 // @Param page query int false "页码"
 // @Param page_size query int false "每页数量"
 // @Success 200 {object} map[string]interface{}
@@ -38,11 +57,26 @@ func ListAdmins(c *gin.Context) {
 		return
 	}
 
+	query, view, ok := applyView(c, db.Model(&model.Admin{}), views.ListAdmins)
+	if !ok {
+		return
+	}
+
 	var admins []model.Admin
 	var total int64
 
-	db.Model(&model.Admin{}).Count(&total)
-	db.Offset((page - 1) * pageSize).Limit(pageSize).Find(&admins)
+	query.Count(&total)
+	query.Offset((page - 1) * pageSize).Limit(pageSize).Find(&admins)
+
+	ids := make([]uint, len(admins))
+	for i, a := range admins {
+		ids[i] = a.ID
+	}
+	if byID, err := tags.Map(c.Request.Context(), tags.ResourceAdmin, ids); err == nil {
+		for i := range admins {
+			admins[i].Tags = byID[admins[i].ID]
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
@@ -52,6 +86,7 @@ func ListAdmins(c *gin.Context) {
 			"total":     total,
 			"page":      page,
 			"page_size": pageSize,
+			"view":      view,
 		},
 	})
 }
@@ -71,6 +106,8 @@ func CreateAdmin(c *gin.Context) {
 		Nickname string `json:"nickname"`
 		Email    string `json:"email"`
 		Role     string `json:"role"`
+		// 数据范围（为空不限制）
+		Scope *model.AdminScope `json:"scope"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -81,6 +118,15 @@ func CreateAdmin(c *gin.Context) {
 		return
 	}
 
+	adminScope, err := scope.Normalize(req.Scope)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
 	db := database.GetMySQL()
 	if db == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -107,11 +153,16 @@ func CreateAdmin(c *gin.Context) {
 		Email:    req.Email,
 		Role:     req.Role,
 		Status:   1,
+		Scope:    adminScope,
 	}
 
 	if admin.Role == "" {
 		admin.Role = "admin"
 	}
+	if err := rbac.CheckRole(c.Request.Context(), admin.Role); err != nil {
+		rbacError(c, err)
+		return
+	}
 
 	if err := admin.SetPassword(req.Password); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -129,6 +180,8 @@ func CreateAdmin(c *gin.Context) {
 		return
 	}
 
+	middleware.RecordChange(c, nil, admin)
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "创建成功",
@@ -161,6 +214,8 @@ func UpdateAdmin(c *gin.Context) {
 		Role     string `json:"role"`
 		Status   *int   `json:"status"`
 		Password string `json:"password"`
+		// 数据范围（传 {} 清除限制；修改后该管理员需重新登录）
+		Scope *model.AdminScope `json:"scope"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -180,6 +235,17 @@ func UpdateAdmin(c *gin.Context) {
 		return
 	}
 
+	var adminScope *model.AdminScope
+	if req.Scope != nil {
+		if adminScope, err = scope.Normalize(req.Scope); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
 	var admin model.Admin
 	if err := db.First(&admin, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
@@ -189,6 +255,8 @@ func UpdateAdmin(c *gin.Context) {
 		return
 	}
 
+	before := admin
+
 	// 更新字段
 	updates := make(map[string]interface{})
 	if req.Nickname != "" {
@@ -198,6 +266,10 @@ func UpdateAdmin(c *gin.Context) {
 		updates["email"] = req.Email
 	}
 	if req.Role != "" {
+		if err := rbac.CheckRole(c.Request.Context(), req.Role); err != nil {
+			rbacError(c, err)
+			return
+		}
 		updates["role"] = req.Role
 	}
 	if req.Status != nil {
@@ -212,6 +284,7 @@ func UpdateAdmin(c *gin.Context) {
 			return
 		}
 		updates["password"] = admin.Password
+		middleware.RecordSecretChange(c, "password")
 	}
 
 	if err := db.Model(&admin).Updates(updates).Error; err != nil {
@@ -222,6 +295,40 @@ func UpdateAdmin(c *gin.Context) {
 		return
 	}
 
+	// 数据范围写在 Token 中，修改后吊销该管理员现有的 Token，使新范围立即生效
+	if req.Scope != nil && !reflect.DeepEqual(adminScope, admin.Scope) {
+		if err := db.Model(&admin).Select("scope").Updates(&model.Admin{Scope: adminScope}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "更新失败: " + err.Error(),
+			})
+			return
+		}
+		admin.Scope = adminScope
+		ttl := middleware.DefaultConfig.TokenExpiry
+		if err := session.RevokeAll(c.Request.Context(), middleware.DefaultConfig.Issuer, strconv.FormatUint(id, 10), ttl); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "数据范围已更新，但强制下线失败: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	// 角色同样写在 Token 中，修改后吊销现有的 Token
+	if req.Role != "" && req.Role != before.Role {
+		ttl := middleware.DefaultConfig.TokenExpiry
+		if err := session.RevokeAll(c.Request.Context(), middleware.DefaultConfig.Issuer, strconv.FormatUint(id, 10), ttl); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "角色已更新，但强制下线失败: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	middleware.RecordChange(c, before, admin)
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "更新成功",
@@ -255,6 +362,10 @@ func DeleteAdmin(c *gin.Context) {
 		return
 	}
 
+	// 删除前取出记录，写入操作日志的变更
+	var admin model.Admin
+	db.First(&admin, id)
+
 	result := db.Delete(&model.Admin{}, id)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -271,9 +382,61 @@ func DeleteAdmin(c *gin.Context) {
 		})
 		return
 	}
+	tags.Clear(c.Request.Context(), tags.ResourceAdmin, uint(id))
+	middleware.RecordChange(c, admin, nil)
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "删除成功",
 	})
 }
+
+// RevokeAdminSessions 吊销管理员当前所有 Token（强制下线）
+// @Summary 强制下线管理员
+// @Tags Admin
+// @Produce json
+// @Param id path int true "管理员ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/admins/{id}/revoke-sessions [post]
+func RevokeAdminSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的ID",
+		})
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	var admin model.Admin
+	if err := db.First(&admin, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "管理员不存在",
+		})
+		return
+	}
+
+	ttl := middleware.DefaultConfig.TokenExpiry
+	if err := session.RevokeAll(c.Request.Context(), middleware.DefaultConfig.Issuer, strconv.FormatUint(id, 10), ttl); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "强制下线失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "已强制下线",
+	})
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"new-openclaw/internal/admin/analytics"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActivityAnalytics 管理员活动热力图与异常行为
+// @Summary 管理员活动热力图与异常行为
+// @Tags Admin
+// @Produce json
+// @Param days query int false "统计天数（默认 7，最大 90）"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/analytics/activity [get]
+func ActivityAnalytics(c *gin.Context) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if days < 1 || days > 90 {
+		days = 7
+	}
+
+	logs, err := analytics.LoadLogs(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查询操作日志失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"days":      days,
+			"heatmap":   analytics.BuildHeatmap(logs),
+			"anomalies": analytics.Detect(logs),
+		},
+	})
+}
+
+// ListHealthEvents 依赖健康状态变化记录（最新在前），可按组件、天数筛选
+// @Summary 依赖健康状态变化记录
+// @Tags Admin
+// @Produce json
+// @Param component query string false "组件（mysql、redis、mongodb、audit 等）"
+// @Param days query int false "统计天数（默认 7，最大 90）"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/analytics/health-events [get]
+func ListHealthEvents(c *gin.Context) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if days < 1 || days > 90 {
+		days = 7
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	query := db.Model(&model.HealthEvent{}).Where("occurred_at >= ?", time.Now().AddDate(0, 0, -days))
+	if component := c.Query("component"); component != "" {
+		query = query.Where("component = ?", component)
+	}
+
+	var events []model.HealthEvent
+	var total int64
+
+	query.Count(&total)
+	query.Order("occurred_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&events)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"days":      days,
+			"list":      events,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
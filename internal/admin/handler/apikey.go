@@ -0,0 +1,372 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/apikey"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListAPIKeys 获取 API Key 列表（只返回 Key 前缀）
+// @Summary 获取 API Key 列表
+// @Tags Admin
+// @Produce json
+// @Param status query int false "状态（1 有效，0 已吊销）"
+// @Param name query string false "名称（模糊匹配）"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/api-keys [get]
+func ListAPIKeys(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	query := db.Model(&model.APIKey{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if name := c.Query("name"); name != "" {
+		query = query.Where("name LIKE ?", "%"+name+"%")
+	}
+
+	var keys []model.APIKey
+	var total int64
+
+	query.Count(&total)
+	query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&keys)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"list":      keys,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// GetAPIKey 获取 API Key 详情（含最近使用时间与 IP）
+// @Summary 获取 API Key 详情
+// @Tags Admin
+// @Produce json
+// @Param id path int true "API Key ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/api-keys/{id} [get]
+func GetAPIKey(c *gin.Context) {
+	key, ok := findAPIKey(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    key,
+	})
+}
+
+// CreateAPIKey 签发 API Key，完整 Key 只在签发时返回一次
+// @Summary 签发 API Key
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "如 {\"name\": \"billing\", \"scopes\": [\"users:read\"], \"expires_in\": \"2160h\"}"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/api-keys [post]
+func CreateAPIKey(c *gin.Context) {
+	var req struct {
+		Name   string   `json:"name" binding:"required,max=100"`
+		Scopes []string `json:"scopes" binding:"required,min=1"`
+		// 有效期（如 720h），与 expires_at 二选一，都为空时长期有效
+		ExpiresIn string     `json:"expires_in"`
+		ExpiresAt *time.Time `json:"expires_at"`
+		Remark    string     `json:"remark" binding:"max=255"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	scopes, err := normalizeAPIKeyScopes(req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	expiresAt := req.ExpiresAt
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "无效的有效期: " + req.ExpiresIn,
+			})
+			return
+		}
+		t := time.Now().Add(d)
+		expiresAt = &t
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "过期时间必须晚于当前时间",
+		})
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	plain, prefix, hash, err := apikey.Generate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "生成 API Key 失败",
+		})
+		return
+	}
+
+	key := model.APIKey{
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    scopes,
+		Status:    model.APIKeyStatusActive,
+		ExpiresAt: expiresAt,
+		Remark:    req.Remark,
+		CreatedBy: apiKeyOperator(c),
+	}
+	if err := db.Create(&key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "签发失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "签发成功，请妥善保存 API Key（之后无法再次查看）",
+		"data": gin.H{
+			"api_key": key,
+			"key":     plain,
+		},
+	})
+}
+
+// RotateAPIKey 轮换 API Key，可指定旧 Key 的宽限期以便调用方平滑切换
+// @Summary 轮换 API Key
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "API Key ID"
+// @Param body body map[string]interface{} false "如 {\"grace_period\": \"24h\"}"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/api-keys/{id}/rotate [post]
+func RotateAPIKey(c *gin.Context) {
+	var req struct {
+		// 旧 Key 继续有效的时间（为空或 0 立即失效）
+		GracePeriod string `json:"grace_period"`
+	}
+	// 请求体可选
+	_ = c.ShouldBindJSON(&req)
+
+	var grace time.Duration
+	if req.GracePeriod != "" {
+		var err error
+		if grace, err = time.ParseDuration(req.GracePeriod); err != nil || grace < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "无效的宽限期: " + req.GracePeriod,
+			})
+			return
+		}
+	}
+
+	key, ok := findAPIKey(c)
+	if !ok {
+		return
+	}
+	if key.Status != model.APIKeyStatusActive {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "API Key 已吊销，不能轮换",
+		})
+		return
+	}
+
+	plain, prefix, hash, err := apikey.Generate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "生成 API Key 失败",
+		})
+		return
+	}
+
+	// 宽限期内的旧 Key 与本次被替换的 Key 都需要清除缓存
+	stale := []string{key.KeyHash, key.PreviousKeyHash}
+	now := time.Now()
+	key.PreviousKeyHash = ""
+	key.PreviousExpiresAt = nil
+	if grace > 0 {
+		previousExpiresAt := now.Add(grace)
+		key.PreviousKeyHash = key.KeyHash
+		key.PreviousExpiresAt = &previousExpiresAt
+	}
+	key.Prefix = prefix
+	key.KeyHash = hash
+	key.RotatedAt = &now
+
+	saveAPIKey(c, key, stale, "已轮换，请妥善保存新的 API Key（之后无法再次查看）", gin.H{"key": plain})
+}
+
+// RevokeAPIKey 吊销 API Key（立即生效，含宽限期内的旧 Key；记录保留用于审计）
+// @Summary 吊销 API Key
+// @Tags Admin
+// @Produce json
+// @Param id path int true "API Key ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/api-keys/{id}/revoke [post]
+func RevokeAPIKey(c *gin.Context) {
+	key, ok := findAPIKey(c)
+	if !ok {
+		return
+	}
+	if key.Status != model.APIKeyStatusActive {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "API Key 已吊销",
+			"data":    key,
+		})
+		return
+	}
+
+	now := time.Now()
+	key.Status = model.APIKeyStatusRevoked
+	key.RevokedAt = &now
+	key.RevokedBy = apiKeyOperator(c)
+
+	saveAPIKey(c, key, []string{key.KeyHash, key.PreviousKeyHash}, "已吊销", nil)
+}
+
+// findAPIKey 按路径参数查找 API Key，失败时已写入响应
+func findAPIKey(c *gin.Context) (*model.APIKey, bool) {
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return nil, false
+	}
+
+	var key model.APIKey
+	err := db.First(&key, c.Param("id")).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "API Key 不存在",
+		})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查询失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	return &key, true
+}
+
+// saveAPIKey 保存 API Key 并清除相关 Key 的校验缓存
+func saveAPIKey(c *gin.Context, key *model.APIKey, stale []string, message string, extra gin.H) {
+	if err := database.GetMySQL().Save(key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "保存失败: " + err.Error(),
+		})
+		return
+	}
+	apikey.Invalidate(c.Request.Context(), append(stale, key.KeyHash)...)
+
+	data := gin.H{"api_key": key}
+	for k, v := range extra {
+		data[k] = v
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": message,
+		"data":    data,
+	})
+}
+
+// normalizeAPIKeyScopes 校验并去重权限范围（如 users:read、users:*），返回逗号分隔字符串
+func normalizeAPIKeyScopes(items []string) (string, error) {
+	seen := make(map[string]bool)
+	var scopes []string
+	for _, scope := range items {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || seen[scope] {
+			continue
+		}
+		if len(scope) > 64 || strings.ContainsAny(scope, ", \t") {
+			return "", errors.New("无效的权限范围: " + scope)
+		}
+		seen[scope] = true
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		return "", errors.New("至少需要一个权限范围")
+	}
+	return strings.Join(scopes, ","), nil
+}
+
+// apiKeyOperator 当前操作的管理员
+func apiKeyOperator(c *gin.Context) string {
+	if claims, ok := c.Get("admin_claims"); ok {
+		return claims.(*middleware.Claims).Username
+	}
+	return ""
+}
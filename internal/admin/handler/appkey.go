@@ -0,0 +1,501 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/admin/scope"
+	"new-openclaw/internal/appkey"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/tags"
+	"new-openclaw/pkg/secrets"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListAppKeys 获取 AppKey 列表（不返回密钥）
+// @Summary 获取 AppKey 列表
+// @Tags Admin
+// @Produce json
+// @Param status query int false "状态（1 启用，0 禁用）"
+// @Param tag query string false "标签（逗号分隔，需同时带有）"
+// @Param tenant query string false "租户"
+// @Param organization query string false "组织"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/app-keys [get]
+func ListAppKeys(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	query := db.Model(&model.AppKey{}).Scopes(scope.AppKeys(scope.Of(c)), tags.Filter(tags.ResourceAppKey, c.Query("tag")))
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if tenant := c.Query("tenant"); tenant != "" {
+		query = query.Where("tenant = ?", tenant)
+	}
+	if organization := c.Query("organization"); organization != "" {
+		query = query.Where("organization = ?", organization)
+	}
+
+	var keys []model.AppKey
+	var total int64
+
+	query.Count(&total)
+	query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&keys)
+
+	ids := make([]uint, len(keys))
+	for i, k := range keys {
+		ids[i] = k.ID
+	}
+	if byID, err := tags.Map(c.Request.Context(), tags.ResourceAppKey, ids); err == nil {
+		for i := range keys {
+			keys[i].Tags = byID[keys[i].ID]
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"list":      keys,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// CreateAppKey 创建 AppKey，密钥只在创建时返回一次
+// @Summary 创建 AppKey
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "AppKey 信息"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/app-keys [post]
+func CreateAppKey(c *gin.Context) {
+	var req struct {
+		// 为空时自动生成
+		AppKey       string     `json:"app_key" binding:"omitempty,max=64"`
+		Name         string     `json:"name" binding:"required,max=100"`
+		Tenant       string     `json:"tenant" binding:"max=64"`
+		Organization string     `json:"organization" binding:"max=100"`
+		AllowedIPs   []string   `json:"allowed_ips"`
+		ExpiresAt    *time.Time `json:"expires_at"`
+		Remark       string     `json:"remark"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 限定数据范围的管理员只能在自己的租户、组织下创建（新 AppKey 还没有标签）
+	if !scope.Contains(scope.Of(c), req.Tenant, req.Organization, nil) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "租户或组织不在数据范围内",
+		})
+		return
+	}
+
+	allowedIPs, err := appkey.ValidateAllowedIPs(req.AllowedIPs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	if req.AppKey == "" {
+		if req.AppKey, err = appkey.GenerateKey(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "生成 AppKey 失败",
+			})
+			return
+		}
+	}
+
+	var count int64
+	db.Model(&model.AppKey{}).Where("app_key = ?", req.AppKey).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "AppKey 已存在",
+		})
+		return
+	}
+
+	secret, err := appkey.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "生成密钥失败",
+		})
+		return
+	}
+
+	key := model.AppKey{
+		Key:          req.AppKey,
+		Name:         req.Name,
+		Tenant:       req.Tenant,
+		Organization: req.Organization,
+		Secret:       secrets.EncryptedString(secret),
+		Status:       model.AppKeyStatusActive,
+		AllowedIPs:   allowedIPs,
+		ExpiresAt:    req.ExpiresAt,
+		Remark:       req.Remark,
+	}
+	if err := db.Create(&key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "创建失败: " + err.Error(),
+		})
+		return
+	}
+	// 清除“未登记”的负缓存
+	appkey.Invalidate(c.Request.Context(), key.Key)
+	middleware.RecordChange(c, nil, key)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "创建成功，请妥善保存密钥（之后无法再次查看）",
+		"data": gin.H{
+			"app_key": key,
+			"secret":  secret,
+		},
+	})
+}
+
+// UpdateAppKey 更新 AppKey 名称、租户、组织、允许 IP、有效期
+// @Summary 更新 AppKey
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param app_key path string true "AppKey"
+// @Param body body map[string]interface{} true "AppKey 信息"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/app-keys/{app_key} [put]
+func UpdateAppKey(c *gin.Context) {
+	var req struct {
+		Name         *string    `json:"name" binding:"omitempty,max=100"`
+		Tenant       *string    `json:"tenant" binding:"omitempty,max=64"`
+		Organization *string    `json:"organization" binding:"omitempty,max=100"`
+		AllowedIPs   *[]string  `json:"allowed_ips"`
+		ExpiresAt    *time.Time `json:"expires_at"`
+		// 为 true 时清除有效期（永不过期）
+		NeverExpire bool    `json:"never_expire"`
+		Remark      *string `json:"remark"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	key, ok := findAppKey(c)
+	if !ok {
+		return
+	}
+	before := *key
+
+	if req.Name != nil {
+		key.Name = *req.Name
+	}
+	if req.Tenant != nil || req.Organization != nil {
+		if req.Tenant != nil {
+			key.Tenant = *req.Tenant
+		}
+		if req.Organization != nil {
+			key.Organization = *req.Organization
+		}
+		// 不能把 AppKey 移出自己的数据范围
+		if adminScope := scope.Of(c); adminScope != nil {
+			keyTags, _ := tags.Of(c.Request.Context(), tags.ResourceAppKey, key.ID)
+			if !scope.Contains(adminScope, key.Tenant, key.Organization, keyTags) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"code":    403,
+					"message": "租户或组织不在数据范围内",
+				})
+				return
+			}
+		}
+	}
+	if req.AllowedIPs != nil {
+		allowedIPs, err := appkey.ValidateAllowedIPs(*req.AllowedIPs)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return
+		}
+		key.AllowedIPs = allowedIPs
+	}
+	if req.ExpiresAt != nil {
+		key.ExpiresAt = req.ExpiresAt
+	}
+	if req.NeverExpire {
+		key.ExpiresAt = nil
+	}
+	if req.Remark != nil {
+		key.Remark = *req.Remark
+	}
+
+	middleware.RecordChange(c, before, key)
+	saveAppKey(c, key, "更新成功", nil)
+}
+
+// RotateAppKey 轮换 AppKey 密钥，旧密钥在宽限期内仍可验签，新密钥只返回一次
+// @Summary 轮换 AppKey 密钥
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param app_key path string true "AppKey"
+// @Param body body map[string]interface{} false "宽限期，如 {\"grace_period\": \"24h\"}"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/app-keys/{app_key}/rotate [post]
+func RotateAppKey(c *gin.Context) {
+	var req struct {
+		// 旧密钥继续有效的时间（为空或 0 立即失效）
+		GracePeriod string `json:"grace_period"`
+	}
+	// 请求体可选
+	_ = c.ShouldBindJSON(&req)
+
+	var grace time.Duration
+	if req.GracePeriod != "" {
+		var err error
+		if grace, err = time.ParseDuration(req.GracePeriod); err != nil || grace < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "无效的宽限期: " + req.GracePeriod,
+			})
+			return
+		}
+	}
+
+	key, ok := findAppKey(c)
+	if !ok {
+		return
+	}
+
+	secret, err := appkey.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "生成密钥失败",
+		})
+		return
+	}
+
+	now := time.Now()
+	key.PreviousSecret = ""
+	key.PreviousExpiresAt = nil
+	if grace > 0 {
+		previousExpiresAt := now.Add(grace)
+		key.PreviousSecret = key.Secret
+		key.PreviousExpiresAt = &previousExpiresAt
+	}
+	before := *key
+	key.Secret = secrets.EncryptedString(secret)
+	key.RotatedAt = &now
+	middleware.RecordChange(c, before, key)
+	middleware.RecordSecretChange(c, "secret")
+
+	saveAppKey(c, key, "密钥已轮换，请妥善保存新密钥（之后无法再次查看）", gin.H{"secret": secret})
+}
+
+// DisableAppKey 禁用 AppKey（立即生效）
+// @Summary 禁用 AppKey
+// @Tags Admin
+// @Produce json
+// @Param app_key path string true "AppKey"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/app-keys/{app_key}/disable [post]
+func DisableAppKey(c *gin.Context) {
+	setAppKeyStatus(c, model.AppKeyStatusDisabled, "已禁用")
+}
+
+// EnableAppKey 启用 AppKey
+// @Summary 启用 AppKey
+// @Tags Admin
+// @Produce json
+// @Param app_key path string true "AppKey"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/app-keys/{app_key}/enable [post]
+func EnableAppKey(c *gin.Context) {
+	setAppKeyStatus(c, model.AppKeyStatusActive, "已启用")
+}
+
+// DeleteAppKey 删除 AppKey（之后使用该 AppKey 的请求按未登记处理）
+// @Summary 删除 AppKey
+// @Tags Admin
+// @Produce json
+// @Param app_key path string true "AppKey"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/app-keys/{app_key} [delete]
+func DeleteAppKey(c *gin.Context) {
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	appKey := c.Param("app_key")
+	inScope := scope.AppKeys(scope.Of(c))
+	var key model.AppKey
+	db.Scopes(inScope).Where("app_key = ?", appKey).First(&key)
+	result := db.Scopes(inScope).Where("app_key = ?", appKey).Delete(&model.AppKey{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除失败: " + result.Error.Error(),
+		})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "AppKey 不存在",
+		})
+		return
+	}
+	appkey.Invalidate(c.Request.Context(), appKey)
+	tags.Clear(c.Request.Context(), tags.ResourceAppKey, key.ID)
+	middleware.RecordChange(c, key, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "删除成功",
+	})
+}
+
+// setAppKeyStatus 设置 AppKey 状态
+func setAppKeyStatus(c *gin.Context, status int, message string) {
+	key, ok := findAppKey(c)
+	if !ok {
+		return
+	}
+	before := *key
+	key.Status = status
+	middleware.RecordChange(c, before, key)
+	saveAppKey(c, key, message, nil)
+}
+
+// findAppKey 按路径参数查找 AppKey（数据范围外的按不存在处理），失败时已写入响应
+func findAppKey(c *gin.Context) (*model.AppKey, bool) {
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return nil, false
+	}
+
+	var key model.AppKey
+	err := db.Scopes(scope.AppKeys(scope.Of(c))).Where("app_key = ?", c.Param("app_key")).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "AppKey 不存在",
+		})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查询失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	return &key, true
+}
+
+// saveAppKey 保存 AppKey 并清除验签缓存
+func saveAppKey(c *gin.Context, key *model.AppKey, message string, extra gin.H) {
+	if err := database.GetMySQL().Save(key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "保存失败: " + err.Error(),
+		})
+		return
+	}
+	appkey.Invalidate(c.Request.Context(), key.Key)
+
+	data := gin.H{"app_key": key}
+	for k, v := range extra {
+		data[k] = v
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": message,
+		"data":    data,
+	})
+}
+
+// appKeyInScope 检查路径参数中的 AppKey 是否在当前管理员的数据范围内（范围外按不存在处理），失败时已写入响应
+func appKeyInScope(c *gin.Context) bool {
+	ok, err := scope.AllowsAppKey(c.Request.Context(), scope.Of(c), c.Param("app_key"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查询失败: " + err.Error(),
+		})
+		return false
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "AppKey 不存在",
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/auditstore"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListAuditLogs 查询 MongoDB 中的请求审计日志
+// @Summary 查询请求审计日志
+// @Tags Admin
+// @Produce json
+// @Param from query string false "时间起（RFC3339 或 2006-01-02）"
+// @Param to query string false "时间止（RFC3339 或 2006-01-02，按日期时包含当天）"
+// @Param user query string false "用户 ID 或用户名"
+// @Param path query string false "请求路径，末尾 * 按前缀匹配"
+// @Param status query string false "状态码，如 404 或 5xx"
+// @Param ip query string false "客户端 IP"
+// @Param method query string false "请求方法"
+// @Param request_id query string false "请求 ID"
+// @Param impersonator query string false "模拟登录的发起人（管理员 ID 或用户名），* 表示所有模拟登录期间的请求"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/audit-logs [get]
+func ListAuditLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := auditstore.Filter{
+		User:      c.Query("user"),
+		Path:      c.Query("path"),
+		ClientIP:  c.Query("ip"),
+		Method:    c.Query("method"),
+		RequestID: c.Query("request_id"),
+
+		Impersonator: c.Query("impersonator"),
+	}
+	var ok bool
+	if filter.From, ok = parseAuditTime(c, "from", false); !ok {
+		return
+	}
+	if filter.To, ok = parseAuditTime(c, "to", true); !ok {
+		return
+	}
+	if filter.StatusMin, filter.StatusMax, ok = parseStatusRange(c.Query("status")); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "status 应为状态码（如 404）或状态类别（如 5xx）",
+		})
+		return
+	}
+
+	records, total, err := auditstore.Find(c.Request.Context(), filter, page, pageSize)
+	if err != nil {
+		auditLogError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"list":      records,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// GetAuditLog 获取审计日志详情（含请求头与请求/响应体）
+// @Summary 获取请求审计日志详情
+// @Tags Admin
+// @Produce json
+// @Param id path string true "日志 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/audit-logs/{id} [get]
+func GetAuditLog(c *gin.Context) {
+	record, err := auditstore.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		auditLogError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    record,
+	})
+}
+
+// parseAuditTime 解析时间参数，失败时已写入响应；按日期查询截止时间时取当天结束
+func parseAuditTime(c *gin.Context, name string, endOfDay bool) (time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		return t, true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"code":    400,
+		"message": name + " 应为 RFC3339 时间或 2006-01-02 格式的日期",
+	})
+	return time.Time{}, false
+}
+
+// parseStatusRange 解析状态码（404）或状态类别（5xx）
+func parseStatusRange(value string) (int, int, bool) {
+	if value == "" {
+		return 0, 0, true
+	}
+	if class, ok := strings.CutSuffix(strings.ToLower(value), "xx"); ok {
+		n, err := strconv.Atoi(class)
+		if err != nil || n < 1 || n > 5 {
+			return 0, 0, false
+		}
+		return n * 100, n*100 + 99, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 100 || n > 599 {
+		return 0, 0, false
+	}
+	return n, n, true
+}
+
+// auditLogError 写入审计日志查询错误
+func auditLogError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	message := err.Error()
+	switch {
+	case errors.Is(err, auditstore.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, auditstore.ErrUnavailable):
+		status = http.StatusServiceUnavailable
+	default:
+		log.Printf("查询审计日志失败: %v", err)
+		message = "查询审计日志失败"
+	}
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": message,
+	})
+}
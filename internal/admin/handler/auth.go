@@ -1,14 +1,21 @@
 package handler
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/captcha"
 	"new-openclaw/internal/database"
+	"new-openclaw/internal/loginguard"
+	commonmiddleware "new-openclaw/internal/middleware"
 	"new-openclaw/internal/model"
-	"new-openclaw/pkg/jwt"
+	"new-openclaw/internal/session"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // Login 管理员登录
@@ -29,6 +36,26 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// 暴力破解防护：账号锁定或仍在失败后的等待间隔内
+	ctx, ip := c.Request.Context(), commonmiddleware.AbuseIP(c)
+	if d := loginguard.Check(ctx, loginguard.ScopeAdmin, req.Username, ip); !d.Allowed() {
+		recordLogin(c, req.Username, nil, middleware.AuthMethodPassword, model.LoginResultFailed, d.Reason())
+		loginguard.Reject(c, d)
+		return
+	}
+
+	// 人机验证：账号连续失败达到 CAPTCHA_LOGIN_AFTER 次后，先通过验证才校验密码
+	if captcha.Enabled() {
+		status, _ := loginguard.Get(ctx, loginguard.ScopeAdmin, req.Username)
+		if captcha.LoginRequired(status.Failures) {
+			solution := captcha.Solution{ID: req.CaptchaID, Answer: req.CaptchaAnswer, Token: req.CaptchaToken}
+			if !captcha.Check(c, solution) {
+				recordLogin(c, req.Username, nil, middleware.AuthMethodPassword, model.LoginResultFailed, "人机验证未通过")
+				return
+			}
+		}
+	}
+
 	// 查询管理员
 	var admin model.Admin
 	db := database.GetMySQL()
@@ -42,15 +69,16 @@ func Login(c *gin.Context) {
 
 	result := db.Where("username = ?", req.Username).First(&admin)
 	if result.Error != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"code":    401,
-			"message": "用户名或密码错误",
-		})
+		commonmiddleware.ReportAuthFailure(c, commonmiddleware.AuthFailureLogin, req.Username, "管理员不存在")
+		recordLogin(c, req.Username, nil, middleware.AuthMethodPassword, model.LoginResultFailed, "账号不存在")
+		d := loginguard.Fail(ctx, loginguard.ScopeAdmin, req.Username, ip)
+		loginFailed(c, d)
 		return
 	}
 
 	// 检查状态
 	if admin.Status != 1 {
+		recordLogin(c, req.Username, &admin, middleware.AuthMethodPassword, model.LoginResultFailed, "账号已禁用")
 		c.JSON(http.StatusForbidden, gin.H{
 			"code":    403,
 			"message": "账号已被禁用",
@@ -58,38 +86,119 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// 管理员记录上的锁定（登录计数存储数据丢失或重启后仍然有效）
+	if admin.LockedUntil != nil && time.Now().Before(*admin.LockedUntil) {
+		recordLogin(c, req.Username, &admin, middleware.AuthMethodPassword, model.LoginResultFailed, "账号已锁定")
+		loginguard.Reject(c, loginguard.Decision{Locked: true, RetryAfter: time.Until(*admin.LockedUntil)})
+		return
+	}
+
 	// 验证密码
 	if !admin.CheckPassword(req.Password) {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"code":    401,
-			"message": "用户名或密码错误",
+		commonmiddleware.ReportAuthFailure(c, commonmiddleware.AuthFailureLogin, req.Username, "密码错误")
+		recordLogin(c, req.Username, &admin, middleware.AuthMethodPassword, model.LoginResultFailed, "密码错误")
+		d := loginguard.Fail(ctx, loginguard.ScopeAdmin, req.Username, ip)
+		syncLoginFailure(db, &admin, d)
+		loginFailed(c, d)
+		return
+	}
+
+	loginguard.Succeed(ctx, loginguard.ScopeAdmin, req.Username)
+	if admin.FailedAttempts > 0 || admin.LockedUntil != nil {
+		db.Model(&admin).UpdateColumns(map[string]interface{}{"failed_attempts": 0, "locked_until": nil})
+	}
+
+	// 密码哈希算法已升级时，登录成功后透明地重新哈希
+	if admin.NeedsRehash() {
+		if err := admin.SetPassword(req.Password); err == nil {
+			db.Model(&admin).Update("password", admin.Password)
+		}
+	}
+
+	// 必须使用安全密钥的角色：注册了安全密钥后不能再用密码登录（尚未注册时允许登录，以便注册安全密钥）
+	if middleware.WebAuthnRequired(admin.Role) && hasWebAuthnCredential(db, admin.ID) {
+		recordLogin(c, req.Username, &admin, middleware.AuthMethodPassword, model.LoginResultFailed, "必须使用安全密钥登录")
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "该账号必须使用安全密钥登录",
+			"data":    gin.H{"webauthn_required": true},
 		})
 		return
 	}
 
+	completeLogin(c, db, &admin, middleware.AuthMethodPassword)
+}
+
+// completeLogin 身份校验通过后检查并发会话上限、签发 Token 并记录登录（密码登录与安全密钥登录共用）
+func completeLogin(c *gin.Context, db *gorm.DB, admin *model.Admin, method string) {
+	resp, ok := issueLoginToken(c, db, admin, method)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "登录成功",
+		"data":    resp,
+	})
+}
+
+// issueLoginToken 检查并发会话上限、签发 Token 并记录登录；失败时已写入响应
+func issueLoginToken(c *gin.Context, db *gorm.DB, admin *model.Admin, method string) (*model.AdminLoginResponse, bool) {
+	// 并发会话上限（拒绝新登录策略）
+	if !admitSession(c, admin.ID) {
+		recordLogin(c, admin.Username, admin, method, model.LoginResultFailed, "活跃会话数已达上限")
+		return nil, false
+	}
+
 	// 生成Token
-	token, expiresAt, err := jwt.GenerateToken(admin.ID, admin.Username, admin.Role)
+	token, expiresAt, err := middleware.GenerateLoginToken(admin.ID, admin.Username, admin.Role, admin.Scope, method)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "生成Token失败",
 		})
-		return
+		return nil, false
 	}
 
 	// 更新最后登录时间
 	now := time.Now()
-	db.Model(&admin).Update("last_login", now)
+	db.Model(admin).Update("last_login", now)
 
-	c.JSON(http.StatusOK, gin.H{
-		"code":    0,
-		"message": "登录成功",
-		"data": model.AdminLoginResponse{
-			Token:     token,
-			ExpiresAt: expiresAt,
-			Admin:     &admin,
-		},
-	})
+	// 浏览器客户端：Token 同时写入 HttpOnly Cookie
+	setTokenCookie(c, token, expiresAt)
+	trackSession(c, token)
+	recordLogin(c, admin.Username, admin, method, model.LoginResultSuccess, "")
+
+	return &model.AdminLoginResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		Admin:     admin,
+	}, true
+}
+
+// loginFailed 用户名或密码错误；下次登录需要人机验证时在 data.captcha_required 中提示前端
+func loginFailed(c *gin.Context, d loginguard.Decision) {
+	resp := gin.H{
+		"code":    401,
+		"message": "用户名或密码错误",
+	}
+	if captcha.LoginRequired(d.Failures) {
+		resp["data"] = gin.H{"captcha_required": true}
+	}
+	c.JSON(http.StatusUnauthorized, resp)
+}
+
+// syncLoginFailure 将本次失败后的连续失败次数与锁定截止时间写入管理员记录
+func syncLoginFailure(db *gorm.DB, admin *model.Admin, d loginguard.Decision) {
+	if !loginguard.Enabled() {
+		return
+	}
+	updates := map[string]interface{}{"failed_attempts": d.Failures}
+	if d.Locked {
+		updates["locked_until"] = time.Now().Add(d.RetryAfter)
+	}
+	db.Model(admin).UpdateColumns(updates)
 }
 
 // Logout 管理员登出
@@ -99,8 +208,26 @@ func Login(c *gin.Context) {
 // @Success 200 {object} map[string]interface{}
 // @Router /admin/logout [post]
 func Logout(c *gin.Context) {
-	// JWT是无状态的，客户端删除Token即可
-	// 如需实现Token黑名单，可以将Token存入Redis
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	// 当前 Token 立即失效（加入黑名单或删除服务端会话）
+	if err := revokeToken(c.Request.Context(), adminClaims.RegisteredClaims); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "登出失败: " + err.Error(),
+		})
+		return
+	}
+	session.Remove(c.Request.Context(), adminClaims.Issuer, adminClaims.Subject, adminClaims.ID)
+	if adminClaims.Impersonator != nil {
+		markImpersonationEnded(adminClaims.Impersonator.ImpersonationID, adminClaims.Impersonator.Username)
+	}
+
+	if middleware.DefaultConfig.Cookie.Enabled() {
+		http.SetCookie(c.Writer, middleware.DefaultConfig.Cookie.Expired())
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "登出成功",
@@ -123,7 +250,7 @@ func GetProfile(c *gin.Context) {
 		return
 	}
 
-	adminClaims := claims.(*jwt.Claims)
+	adminClaims := claims.(*middleware.Claims)
 
 	var admin model.Admin
 	db := database.GetMySQL()
@@ -167,10 +294,27 @@ func RefreshToken(c *gin.Context) {
 		return
 	}
 
-	adminClaims := claims.(*jwt.Claims)
+	adminClaims := claims.(*middleware.Claims)
 
-	// 生成新Token
-	token, expiresAt, err := jwt.GenerateToken(adminClaims.AdminID, adminClaims.Username, adminClaims.Role)
+	// 紧急访问会话到期后必须重新走紧急登录流程
+	if adminClaims.BreakGlass {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "紧急访问会话不能刷新",
+		})
+		return
+	}
+	// 模拟登录的有效期固定，到期后需由超级管理员重新发起
+	if adminClaims.Impersonator != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "模拟登录不能刷新",
+		})
+		return
+	}
+
+	// 生成新Token（沿用登录时间，刷新不能代替重新认证）
+	token, expiresAt, err := middleware.RefreshScopedToken(adminClaims)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -178,6 +322,15 @@ func RefreshToken(c *gin.Context) {
 		})
 		return
 	}
+	setTokenCookie(c, token, expiresAt)
+
+	// 启用并发会话上限或服务端会话时，刷新后的 Token 替换当前会话（旧 Token 吊销），不额外占用名额
+	if sessionLimit.Max > 0 || middleware.ServerSessions() {
+		if err := session.Revoke(c.Request.Context(), adminClaims.Issuer, adminClaims.Subject, adminClaims.ID); err != nil && !errors.Is(err, session.ErrNotFound) {
+			log.Printf("吊销刷新前的会话失败: admin=%d err=%v", adminClaims.AdminID, err)
+		}
+	}
+	trackSession(c, token)
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
@@ -188,3 +341,10 @@ func RefreshToken(c *gin.Context) {
 		},
 	})
 }
+
+// setTokenCookie 启用 Cookie 下发时将 Token 写入 HttpOnly Cookie
+func setTokenCookie(c *gin.Context, token string, expiresAt int64) {
+	if middleware.DefaultConfig.Cookie.Enabled() {
+		http.SetCookie(c.Writer, middleware.DefaultConfig.Cookie.New(token, time.Unix(expiresAt, 0)))
+	}
+}
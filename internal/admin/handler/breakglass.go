@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/breakglass"
+	commonmiddleware "new-openclaw/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BreakGlassLogin 紧急访问登录：Redis/数据库故障导致常规登录不可用时，使用密封的紧急凭证获取限时超级管理员权限。
+// 不访问数据库与 Redis；凭证使用一次后必须轮换，所有尝试均写入轨迹并告警所有超级管理员
+// @Summary 紧急访问登录
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "用户名、密码及使用原因"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/break-glass/login [post]
+func BreakGlassLogin(c *gin.Context) {
+	var req struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+		// 使用原因（写入轨迹与告警）
+		Reason string `json:"reason" binding:"required,max=500"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 凭证校验通过后先签发 Token，签发成功且写入轨迹后凭证才被消耗
+	var (
+		token     string
+		expiresAt int64
+	)
+	err := breakglass.Activate(breakglass.Attempt{
+		Username:  req.Username,
+		Password:  req.Password,
+		Reason:    req.Reason,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}, func(ttl time.Duration) error {
+		var err error
+		token, expiresAt, err = middleware.GenerateBreakGlassToken(req.Username, ttl)
+		return err
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, breakglass.ErrDisabled):
+			status = http.StatusNotFound
+		case errors.Is(err, breakglass.ErrInvalidCredential):
+			status = http.StatusUnauthorized
+			commonmiddleware.ReportAuthFailure(c, commonmiddleware.AuthFailureBreakGlass, req.Username, err.Error())
+		case errors.Is(err, breakglass.ErrRotationRequired):
+			status = http.StatusForbidden
+		case errors.Is(err, breakglass.ErrLocked):
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, gin.H{
+			"code":    status,
+			"message": err.Error(),
+		})
+		return
+	}
+	setTokenCookie(c, token, expiresAt)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "紧急访问已激活，所有操作均被记录并通知超级管理员；会话不可刷新，结束后请轮换紧急凭证",
+		"data": gin.H{
+			"token":      token,
+			"expires_at": expiresAt,
+		},
+	})
+}
+
+// GetBreakGlassStatus 获取紧急访问状态（是否启用、凭证是否需要轮换）
+// @Summary 获取紧急访问状态
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} breakglass.Status
+// @Router /admin/break-glass [get]
+func GetBreakGlassStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    breakglass.CurrentStatus(),
+	})
+}
+
+// ListBreakGlassTrail 获取紧急访问轨迹（最新在前）并校验哈希链是否完整
+// @Summary 获取紧急访问轨迹
+// @Tags Admin
+// @Produce json
+// @Param limit query int false "返回条数（默认 100）"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/break-glass/trail [get]
+func ListBreakGlassTrail(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit < 1 || limit > 1000 {
+		limit = 100
+	}
+
+	entries, err := breakglass.Entries()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "读取轨迹失败: " + err.Error(),
+		})
+		return
+	}
+	tamperedAt := breakglass.Verify(entries)
+
+	list := make([]breakglass.Entry, 0, limit)
+	for i := len(entries) - 1; i >= 0 && len(list) < limit; i-- {
+		list = append(list, entries[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"total":       len(entries),
+			"intact":      tamperedAt == 0,
+			"tampered_at": tamperedAt,
+			"list":        list,
+		},
+	})
+}
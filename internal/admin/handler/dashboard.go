@@ -1,14 +1,20 @@
 package handler
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"time"
 
-	"new-openclaw/pkg/jwt"
+	"new-openclaw/internal/admin/dashboard"
+	"new-openclaw/internal/admin/menu"
+	"new-openclaw/internal/admin/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Dashboard 管理后台首页
+// Dashboard 管理后台首页：返回按角色与权限过滤的菜单树，渲染管理员设置为首页的自定义仪表盘，未设置或 MongoDB 不可用时使用内置仪表盘；
+// 不限数据范围的超级管理员另外返回账号、今日请求、有效会话与最近安全事件的统计
 // @Summary 管理后台首页
 // @Tags Admin
 // @Produce json
@@ -16,30 +22,307 @@ import (
 // @Router /admin/dashboard [get]
 func Dashboard(c *gin.Context) {
 	claims, _ := c.Get("admin_claims")
-	adminClaims := claims.(*jwt.Claims)
+	adminClaims := claims.(*middleware.Claims)
+
+	board := dashboard.Builtin(adminClaims.GlobalRole())
+	custom, err := dashboard.Default(c.Request.Context(), adminClaims.AdminID)
+	if err != nil && !errors.Is(err, dashboard.ErrUnavailable) {
+		log.Printf("读取首页仪表盘失败: admin=%d err=%v", adminClaims.AdminID, err)
+	}
+	if custom != nil {
+		board = *custom
+	}
+	menus, err := menu.Tree(c.Request.Context(), adminClaims)
+	if err != nil {
+		log.Printf("读取菜单失败: admin=%d err=%v", adminClaims.AdminID, err)
+		menus = menu.Defaults(adminClaims)
+	}
+	var stats *dashboard.Stats
+	if adminClaims.GlobalRole() == "super_admin" {
+		stats = dashboard.LoadStats(false)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"welcome":   "欢迎来到 OpenClaw 管理后台",
+			"admin":     adminClaims.Username,
+			"role":      adminClaims.Role,
+			"menu":      menus,
+			"dashboard": board,
+			"widgets":   dashboard.Render(board, adminClaims.GlobalRole(), adminClaims.AdminID),
+			"stats":     stats,
+		},
+	})
+}
+
+// DashboardStats 首页统计：管理员与用户数、今日请求数与错误率、请求最多的接口、有效会话数、最近的安全事件
+// @Summary 管理后台首页统计
+// @Tags Admin
+// @Produce json
+// @Param refresh query bool false "忽略缓存重新统计"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/dashboard/stats [get]
+func DashboardStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    dashboard.LoadStats(c.Query("refresh") == "true"),
+	})
+}
+
+// ListDashboardWidgets 组件目录：当前角色可用的指标、图表类型及时间范围
+// @Summary 仪表盘组件目录
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/dashboard/widgets [get]
+func ListDashboardWidgets(c *gin.Context) {
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"metrics": dashboard.Catalog(adminClaims.GlobalRole()),
+			"ranges":  dashboard.Ranges,
+		},
+	})
+}
+
+// QueryDashboardWidget 按组件定义查询数据（保存前预览）
+// @Summary 预览仪表盘组件
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body dashboard.Widget true "组件定义"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/dashboard/widgets/query [post]
+func QueryDashboardWidget(c *gin.Context) {
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	var widget dashboard.Widget
+	if err := c.ShouldBindJSON(&widget); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	data, err := dashboard.Query(widget, adminClaims.GlobalRole(), adminClaims.AdminID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    data,
+	})
+}
+
+// ListDashboards 获取当前管理员的自定义仪表盘
+// @Summary 获取自定义仪表盘列表
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/dashboards [get]
+func ListDashboards(c *gin.Context) {
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	list, err := dashboard.List(c.Request.Context(), adminClaims.AdminID)
+	if err != nil {
+		dashboardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"list": list,
+		},
+	})
+}
+
+// GetDashboard 获取自定义仪表盘及各组件数据
+// @Summary 获取自定义仪表盘
+// @Tags Admin
+// @Produce json
+// @Param id path string true "仪表盘 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/dashboards/{id} [get]
+func GetDashboard(c *gin.Context) {
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	board, err := dashboard.Get(c.Request.Context(), adminClaims.AdminID, c.Param("id"))
+	if err != nil {
+		dashboardError(c, err)
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
 		"data": gin.H{
-			"welcome":  "欢迎来到 OpenClaw 管理后台",
-			"admin":    adminClaims.Username,
-			"role":     adminClaims.Role,
-			"menu": []gin.H{
-				{"name": "仪表盘", "path": "/admin/dashboard", "icon": "dashboard"},
-				{"name": "用户管理", "path": "/admin/users", "icon": "user"},
-				{"name": "系统设置", "path": "/admin/settings", "icon": "setting"},
-			},
-			"stats": gin.H{
-				"total_users":   0,
-				"today_visits":  0,
-				"total_orders":  0,
-				"total_revenue": 0,
-			},
+			"dashboard": board,
+			"widgets":   dashboard.Render(*board, adminClaims.GlobalRole(), adminClaims.AdminID),
 		},
 	})
 }
 
+// dashboardRequest 创建、更新仪表盘的请求体
+type dashboardRequest struct {
+	Name    string             `json:"name" binding:"required,max=100"`
+	Default bool               `json:"default"`
+	Widgets []dashboard.Widget `json:"widgets" binding:"dive"`
+}
+
+// CreateDashboard 创建自定义仪表盘
+// @Summary 创建自定义仪表盘
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "名称、是否为首页、组件定义"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/dashboards [post]
+func CreateDashboard(c *gin.Context) {
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	req, ok := bindDashboardRequest(c, adminClaims.GlobalRole())
+	if !ok {
+		return
+	}
+
+	board := dashboard.Dashboard{
+		AdminID: adminClaims.AdminID,
+		Name:    req.Name,
+		Default: req.Default,
+		Widgets: req.Widgets,
+	}
+	if err := dashboard.Create(c.Request.Context(), &board); err != nil {
+		dashboardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "创建成功",
+		"data":    board,
+	})
+}
+
+// UpdateDashboard 更新自定义仪表盘（整体替换名称与组件）
+// @Summary 更新自定义仪表盘
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path string true "仪表盘 ID"
+// @Param body body map[string]interface{} true "名称、是否为首页、组件定义"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/dashboards/{id} [put]
+func UpdateDashboard(c *gin.Context) {
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	req, ok := bindDashboardRequest(c, adminClaims.GlobalRole())
+	if !ok {
+		return
+	}
+
+	board, err := dashboard.Get(c.Request.Context(), adminClaims.AdminID, c.Param("id"))
+	if err != nil {
+		dashboardError(c, err)
+		return
+	}
+	board.Name = req.Name
+	board.Default = req.Default
+	board.Widgets = req.Widgets
+	if err := dashboard.Update(c.Request.Context(), board); err != nil {
+		dashboardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "更新成功",
+		"data":    board,
+	})
+}
+
+// DeleteDashboard 删除自定义仪表盘
+// @Summary 删除自定义仪表盘
+// @Tags Admin
+// @Produce json
+// @Param id path string true "仪表盘 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/dashboards/{id} [delete]
+func DeleteDashboard(c *gin.Context) {
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	if err := dashboard.Delete(c.Request.Context(), adminClaims.AdminID, c.Param("id")); err != nil {
+		dashboardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "删除成功",
+	})
+}
+
+// bindDashboardRequest 解析并校验仪表盘请求体，失败时已写入响应
+func bindDashboardRequest(c *gin.Context, role string) (*dashboardRequest, bool) {
+	var req dashboardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return nil, false
+	}
+	if err := dashboard.Validate(req.Widgets, role); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return nil, false
+	}
+	if req.Widgets == nil {
+		req.Widgets = []dashboard.Widget{}
+	}
+	return &req, true
+}
+
+// dashboardError 写入仪表盘存储错误
+func dashboardError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, dashboard.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, dashboard.ErrLimit):
+		status = http.StatusBadRequest
+	case errors.Is(err, dashboard.ErrUnavailable):
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": err.Error(),
+	})
+}
+
 // AdminIndex 管理后台HTML页面（可选）
 func AdminIndex(c *gin.Context) {
 	c.HTML(http.StatusOK, "admin/index.html", gin.H{
@@ -0,0 +1,396 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/database"
+	commonmiddleware "new-openclaw/internal/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/revocation"
+	"new-openclaw/pkg/auth/token"
+	"new-openclaw/pkg/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// impersonationTTL 模拟登录 Token 的有效期
+var impersonationTTL = 30 * time.Minute
+
+// ConfigureImpersonation 设置模拟登录配置
+func ConfigureImpersonation(cfg config.ImpersonationConfig) {
+	if cfg.TTL > 0 {
+		impersonationTTL = cfg.TTL
+	}
+}
+
+// ImpersonateRequest 模拟登录请求
+type ImpersonateRequest struct {
+	// 被模拟的账号类型：admin（默认）或 user
+	Realm string `json:"realm"`
+	// 模拟原因（如工单号），写入模拟记录
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+// Impersonate 以其他管理员或用户的身份签发短期 Token（排查"我这里看到的不一样"一类问题）。
+// Token 携带发起人（act 声明），模拟期间的请求在操作日志与审计日志中标记发起人；不能刷新，也不能用于再次模拟
+// @Summary 模拟其他管理员或用户登录
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "被模拟的管理员或用户 ID"
+// @Param request body ImpersonateRequest true "账号类型与模拟原因"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/impersonate/{id} [post]
+func Impersonate(c *gin.Context) {
+	caller := middleware.GetCurrentAdmin(c)
+	if caller.BreakGlass || caller.Impersonator != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "当前会话不能发起模拟登录",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的ID",
+		})
+		return
+	}
+
+	var req ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请填写模拟原因",
+		})
+		return
+	}
+	if req.Realm == "" {
+		req.Realm = model.ImpersonationRealmAdmin
+	}
+	if req.Realm != model.ImpersonationRealmAdmin && req.Realm != model.ImpersonationRealmUser {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "账号类型只能是 admin 或 user",
+		})
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	record := &model.Impersonation{
+		AdminID:       caller.AdminID,
+		AdminUsername: caller.Username,
+		Realm:         req.Realm,
+		TargetID:      uint(id),
+		Reason:        strings.TrimSpace(req.Reason),
+		IP:            c.ClientIP(),
+		UserAgent:     c.Request.UserAgent(),
+		ExpiresAt:     time.Now().Add(impersonationTTL),
+	}
+	if len(record.UserAgent) > 512 {
+		record.UserAgent = record.UserAgent[:512]
+	}
+
+	var admin model.Admin
+	var user model.User
+	if req.Realm == model.ImpersonationRealmAdmin {
+		if err := db.First(&admin, id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "管理员不存在",
+			})
+			return
+		}
+		switch {
+		case admin.ID == caller.AdminID:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "不能模拟自己",
+			})
+			return
+		case admin.Role == "super_admin":
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "不能模拟其他超级管理员",
+			})
+			return
+		case admin.Status != 1:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "账号已禁用",
+			})
+			return
+		}
+		record.TargetUsername = admin.Username
+	} else {
+		if err := db.First(&user, id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "用户不存在",
+			})
+			return
+		}
+		if user.Status != model.UserStatusActive {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "账号已禁用",
+			})
+			return
+		}
+		record.TargetUsername = user.Username
+	}
+
+	// 先写入记录取得 ID（Token 的 act 声明引用该记录），签发后补上 jti 与实际过期时间
+	if err := db.Create(record).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "创建模拟记录失败: " + err.Error(),
+		})
+		return
+	}
+	actor := &token.Actor{AdminID: caller.AdminID, Username: caller.Username, ImpersonationID: record.ID}
+
+	var tokenString string
+	var registered jwt.RegisteredClaims
+	if req.Realm == model.ImpersonationRealmAdmin {
+		tokenString, registered, err = middleware.GenerateImpersonationToken(&admin, actor, impersonationTTL)
+	} else {
+		scopes := commonmiddleware.RoleScopes[user.Role]
+		if !user.EmailVerified() {
+			scopes = commonmiddleware.UnverifiedScopes
+		}
+		userID := strconv.FormatUint(uint64(user.ID), 10)
+		tokenString, registered, err = commonmiddleware.GenerateImpersonationToken(userID, user.Username, user.Role, scopes, actor, impersonationTTL, commonmiddleware.CurrentJWTConfig())
+	}
+	if err != nil {
+		db.Delete(record)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "生成Token失败",
+		})
+		return
+	}
+	record.TokenID = registered.ID
+	record.ExpiresAt = registered.ExpiresAt.Time
+	db.Model(record).Updates(map[string]interface{}{"token_id": record.TokenID, "expires_at": record.ExpiresAt})
+
+	log.Printf("👤 管理员 %s 开始模拟 %s %s(#%d)，原因: %s", caller.Username, record.Realm, record.TargetUsername, record.TargetID, record.Reason)
+
+	// 不写入 Cookie：发起人的浏览器会话保持不变，模拟 Token 由前端在单独的标签页中使用
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"token":            tokenString,
+			"expires_at":       record.ExpiresAt.Unix(),
+			"impersonation_id": record.ID,
+			"realm":            record.Realm,
+			"target_id":        record.TargetID,
+			"target_username":  record.TargetUsername,
+		},
+	})
+}
+
+// StopImpersonation 结束当前的模拟登录（使用模拟 Token 调用），Token 立即失效
+// @Summary 结束模拟登录
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/impersonate/stop [post]
+func StopImpersonation(c *gin.Context) {
+	claims := middleware.GetCurrentAdmin(c)
+	if claims.Impersonator == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "当前不是模拟登录",
+		})
+		return
+	}
+
+	if err := revokeToken(c.Request.Context(), claims.RegisteredClaims); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "结束模拟登录失败: " + err.Error(),
+		})
+		return
+	}
+	markImpersonationEnded(claims.Impersonator.ImpersonationID, claims.Impersonator.Username)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "已结束模拟登录",
+	})
+}
+
+// ListImpersonations 查询模拟登录记录
+// @Summary 查询模拟登录记录
+// @Tags Admin
+// @Produce json
+// @Param admin_id query int false "发起模拟的管理员 ID"
+// @Param realm query string false "被模拟的账号类型（admin/user）"
+// @Param target_id query int false "被模拟的账号 ID"
+// @Param active query bool false "只看仍有效的模拟"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/impersonations [get]
+func ListImpersonations(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	query := db.Model(&model.Impersonation{})
+	if adminID := c.Query("admin_id"); adminID != "" {
+		query = query.Where("admin_id = ?", adminID)
+	}
+	if realm := c.Query("realm"); realm != "" {
+		query = query.Where("realm = ?", realm)
+	}
+	if targetID := c.Query("target_id"); targetID != "" {
+		query = query.Where("target_id = ?", targetID)
+	}
+	if active, _ := strconv.ParseBool(c.Query("active")); active {
+		query = query.Where("ended_at IS NULL AND expires_at > ?", time.Now())
+	}
+
+	var records []model.Impersonation
+	var total int64
+
+	query.Count(&total)
+	query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&records)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"list":      records,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// EndImpersonation 强制结束某次模拟登录（包括模拟用户签发的 Token）
+// @Summary 强制结束模拟登录
+// @Tags Admin
+// @Produce json
+// @Param id path int true "模拟记录 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/impersonations/{id} [delete]
+func EndImpersonation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的ID",
+		})
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	var record model.Impersonation
+	if err := db.First(&record, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "模拟记录不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查询失败: " + err.Error(),
+		})
+		return
+	}
+	if !record.Active() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "模拟登录已结束或已过期",
+		})
+		return
+	}
+
+	if record.TokenID != "" {
+		registered := jwt.RegisteredClaims{
+			ID:        record.TokenID,
+			ExpiresAt: jwt.NewNumericDate(record.ExpiresAt),
+		}
+		var err error
+		if record.Realm == model.ImpersonationRealmAdmin {
+			err = revokeToken(c.Request.Context(), registered)
+		} else {
+			err = revocation.Revoke(c.Request.Context(), registered)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "结束模拟登录失败: " + err.Error(),
+			})
+			return
+		}
+	}
+	markImpersonationEnded(record.ID, middleware.GetCurrentAdmin(c).Username)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "已结束模拟登录",
+	})
+}
+
+// markImpersonationEnded 记录模拟登录的结束时间及操作人（已结束的记录不变）
+func markImpersonationEnded(id uint, endedBy string) {
+	db := database.GetMySQL()
+	if db == nil || id == 0 {
+		return
+	}
+	err := db.Model(&model.Impersonation{}).
+		Where("id = ? AND ended_at IS NULL", id).
+		Updates(map[string]interface{}{"ended_at": time.Now(), "ended_by": endedBy}).Error
+	if err != nil {
+		log.Printf("记录模拟登录结束失败: id=%d err=%v", id, err)
+	}
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/iprules"
+	"new-openclaw/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListIPBans 获取自动封禁记录
+// @Summary 获取自动封禁记录
+// @Tags Admin
+// @Produce json
+// @Param ip query string false "IP"
+// @Param active query bool false "只返回生效中的封禁"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/ip-bans [get]
+func ListIPBans(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	query := db.Model(&model.IPBan{})
+	if ip := c.Query("ip"); ip != "" {
+		query = query.Where("ip = ?", ip)
+	}
+	if active, _ := strconv.ParseBool(c.Query("active")); active {
+		query = query.Where("lifted_at IS NULL AND expires_at > ?", time.Now())
+	}
+
+	var bans []model.IPBan
+	var total int64
+
+	query.Count(&total)
+	query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&bans)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"list":      bans,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// LiftIPBan 提前解除自动封禁（同时清除该 IP 的滥用评分）
+// @Summary 解除自动封禁
+// @Tags Admin
+// @Produce json
+// @Param id path int true "封禁记录 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/ip-bans/{id}/lift [post]
+func LiftIPBan(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的ID",
+		})
+		return
+	}
+
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	ban, err := iprules.LiftBan(c.Request.Context(), uint(id), adminClaims.Username)
+	if errors.Is(err, iprules.ErrBanNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "解除失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "已解除封禁",
+		"data":    ban,
+	})
+}
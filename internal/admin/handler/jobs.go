@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"new-openclaw/internal/jobs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListJobs 获取定时任务列表及运行状态
+// @Summary 获取定时任务列表
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/jobs [get]
+func ListJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    jobs.List(c.Request.Context()),
+	})
+}
+
+// GetJob 获取定时任务详情（上次/下次执行时间、最近失败记录）
+// @Summary 获取定时任务详情
+// @Tags Admin
+// @Produce json
+// @Param name path string true "任务名称"
+// @Success 200 {object} jobs.Status
+// @Router /admin/jobs/{name} [get]
+func GetJob(c *gin.Context) {
+	status, err := jobs.Get(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		jobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    status,
+	})
+}
+
+// TriggerJob 立即执行一次定时任务
+// @Summary 手动触发定时任务
+// @Tags Admin
+// @Produce json
+// @Param name path string true "任务名称"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/jobs/{name}/trigger [post]
+func TriggerJob(c *gin.Context) {
+	if err := jobs.Trigger(c.Param("name")); err != nil {
+		jobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "任务已触发",
+	})
+}
+
+// PauseJob 暂停定时任务调度
+// @Summary 暂停定时任务
+// @Tags Admin
+// @Produce json
+// @Param name path string true "任务名称"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/jobs/{name}/pause [post]
+func PauseJob(c *gin.Context) {
+	if err := jobs.Pause(c.Request.Context(), c.Param("name")); err != nil {
+		jobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "任务已暂停",
+	})
+}
+
+// ResumeJob 恢复定时任务调度
+// @Summary 恢复定时任务
+// @Tags Admin
+// @Produce json
+// @Param name path string true "任务名称"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/jobs/{name}/resume [post]
+func ResumeJob(c *gin.Context) {
+	if err := jobs.Resume(c.Request.Context(), c.Param("name")); err != nil {
+		jobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "任务已恢复",
+	})
+}
+
+// jobError 将任务错误转换为响应
+func jobError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": err.Error(),
+		})
+	case errors.Is(err, jobs.ErrRunning):
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "操作失败: " + err.Error(),
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/loginguard"
+	"new-openclaw/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetLoginLock 查询账号的登录失败次数与锁定状态
+// @Summary 查询账号登录锁定状态
+// @Tags Admin
+// @Produce json
+// @Param scope query string true "登录入口：admin（管理后台）、user（用户登录）"
+// @Param account query string true "账号"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/security/login-locks [get]
+func GetLoginLock(c *gin.Context) {
+	scope, account, ok := loginLockTarget(c)
+	if !ok {
+		return
+	}
+
+	status, err := loginguard.Get(c.Request.Context(), scope, account)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查询失败: " + err.Error(),
+		})
+		return
+	}
+	// 管理员记录上的锁定（计数存储中的锁定已丢失时仍然有效）
+	if admin := lockedAdmin(scope, account); admin != nil && !status.Locked {
+		status.Locked = true
+		status.LockRemaining = int64((time.Until(*admin.LockedUntil) + time.Second - 1) / time.Second)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    status,
+	})
+}
+
+// UnlockLogin 解除账号的登录锁定并清除失败计数
+// @Summary 解除账号登录锁定
+// @Tags Admin
+// @Produce json
+// @Param scope query string true "登录入口：admin（管理后台）、user（用户登录）"
+// @Param account query string true "账号"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/security/login-locks [delete]
+func UnlockLogin(c *gin.Context) {
+	scope, account, ok := loginLockTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := loginguard.Unlock(c.Request.Context(), scope, account); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "解除锁定失败: " + err.Error(),
+		})
+		return
+	}
+	if db := database.GetMySQL(); db != nil && scope == loginguard.ScopeAdmin {
+		db.Model(&model.Admin{}).Where("username = ?", account).
+			UpdateColumns(map[string]interface{}{"failed_attempts": 0, "locked_until": nil})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "已解除锁定",
+	})
+}
+
+// lockedAdmin 管理后台账号在管理员记录上仍处于锁定时返回该记录
+func lockedAdmin(scope, account string) *model.Admin {
+	db := database.GetMySQL()
+	if db == nil || scope != loginguard.ScopeAdmin {
+		return nil
+	}
+	var admin model.Admin
+	if err := db.Select("id", "locked_until").Where("username = ?", account).First(&admin).Error; err != nil {
+		return nil
+	}
+	if admin.LockedUntil == nil || !time.Now().Before(*admin.LockedUntil) {
+		return nil
+	}
+	return &admin
+}
+
+// loginLockTarget 解析登录入口与账号参数
+func loginLockTarget(c *gin.Context) (string, string, bool) {
+	scope, account := c.Query("scope"), c.Query("account")
+	if !loginguard.ValidScope(scope) || account == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "scope 应为 admin 或 user，且 account 不能为空",
+		})
+		return "", "", false
+	}
+	return scope, account, true
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/loginhistory"
+	"new-openclaw/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListLoginHistory 查询管理员与用户的登录记录
+// @Summary 查询登录记录
+// @Tags Admin
+// @Produce json
+// @Param realm query string false "账号类型（admin/user）"
+// @Param account_id query int false "账号 ID"
+// @Param username query string false "用户名"
+// @Param result query string false "结果（success/failed）"
+// @Param ip query string false "IP"
+// @Param unfamiliar query bool false "只看新设备或新位置的登录"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/security/login-history [get]
+func ListLoginHistory(c *gin.Context) {
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	query := db.Model(&model.LoginHistory{})
+	if realm := c.Query("realm"); realm != "" {
+		query = query.Where("realm = ?", realm)
+	}
+	if accountID := c.Query("account_id"); accountID != "" {
+		query = query.Where("account_id = ?", accountID)
+	}
+	if username := c.Query("username"); username != "" {
+		query = query.Where("username = ?", username)
+	}
+	if ip := c.Query("ip"); ip != "" {
+		query = query.Where("ip = ?", ip)
+	}
+	listLoginHistory(c, query)
+}
+
+// GetMyLoginHistory 当前管理员自己的登录记录
+// @Summary 我的登录记录
+// @Tags Admin
+// @Produce json
+// @Param result query string false "结果（success/failed）"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/profile/logins [get]
+func GetMyLoginHistory(c *gin.Context) {
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	listLoginHistory(c, db.Model(&model.LoginHistory{}).
+		Where("realm = ? AND account_id = ?", model.LoginRealmAdmin, adminClaims.AdminID))
+}
+
+// listLoginHistory 按结果、新设备筛选并分页返回登录记录（最新的在前）
+func listLoginHistory(c *gin.Context, query *gorm.DB) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	if result := c.Query("result"); result != "" {
+		query = query.Where("result = ?", result)
+	}
+	if unfamiliar, _ := strconv.ParseBool(c.Query("unfamiliar")); unfamiliar {
+		query = query.Where("new_device = ? OR new_location = ?", true, true)
+	}
+
+	var records []model.LoginHistory
+	var total int64
+
+	query.Count(&total)
+	query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&records)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"list":      records,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// recordLogin 记录管理员登录尝试（admin 为空表示账号不存在或尚未查询）
+func recordLogin(c *gin.Context, username string, admin *model.Admin, method, result, reason string) {
+	entry := loginhistory.Entry{
+		Realm:     model.LoginRealmAdmin,
+		Username:  username,
+		Method:    method,
+		Result:    result,
+		Reason:    reason,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	if admin != nil {
+		entry.AccountID = admin.ID
+		entry.Email = admin.Email
+	}
+	loginhistory.Record(c.Request.Context(), entry)
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"new-openclaw/internal/admin/middleware"
+	commonmiddleware "new-openclaw/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMaintenance 获取维护模式状态
+// @Summary 获取维护模式状态
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/maintenance [get]
+func GetMaintenance(c *gin.Context) {
+	state, err := commonmiddleware.CurrentMaintenance(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "读取维护模式状态失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    state,
+	})
+}
+
+// EnableMaintenance 开启维护模式，除管理后台与开放路径外的接口返回 503
+// @Summary 开启维护模式
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} false "如 {\"message\": \"升级中\", \"retry_after\": 600, \"duration\": \"30m\", \"allow_paths\": [\"/api/v1/public/*\"]}"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/maintenance [put]
+func EnableMaintenance(c *gin.Context) {
+	var req struct {
+		// 提示信息（为空使用 MAINTENANCE_MESSAGE）
+		Message string `json:"message"`
+		// Retry-After 秒数（为空使用 MAINTENANCE_RETRY_AFTER）
+		RetryAfter int `json:"retry_after"`
+		// 维护时长，到期自动关闭（为空需手动关闭）
+		Duration string `json:"duration"`
+		// 额外开放的路径（支持末尾 * 通配）
+		AllowPaths []string `json:"allow_paths"`
+	}
+	// 请求体可选
+	_ = c.ShouldBindJSON(&req)
+
+	if req.RetryAfter < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "retry_after 不能为负数",
+		})
+		return
+	}
+	state := commonmiddleware.MaintenanceState{
+		Message:    req.Message,
+		RetryAfter: req.RetryAfter,
+		AllowPaths: req.AllowPaths,
+	}
+	if req.Duration != "" {
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil || duration <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "无效的维护时长: " + req.Duration,
+			})
+			return
+		}
+		until := time.Now().Add(duration)
+		state.Until = &until
+	}
+	if claims, ok := c.Get("admin_claims"); ok {
+		state.StartedBy = claims.(*middleware.Claims).Username
+	}
+
+	if err := commonmiddleware.EnableMaintenance(c.Request.Context(), state); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "开启维护模式失败: " + err.Error(),
+		})
+		return
+	}
+
+	GetMaintenance(c)
+}
+
+// DisableMaintenance 关闭维护模式
+// @Summary 关闭维护模式
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/maintenance [delete]
+func DisableMaintenance(c *gin.Context) {
+	if err := commonmiddleware.DisableMaintenance(c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "关闭维护模式失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "已关闭维护模式",
+	})
+}
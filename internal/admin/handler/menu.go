@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"new-openclaw/internal/admin/menu"
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// menuRequest 添加、修改菜单的请求体
+type menuRequest struct {
+	ParentID   uint     `json:"parent_id"`
+	Name       string   `json:"name" binding:"required,max=64"`
+	Path       string   `json:"path" binding:"omitempty,max=255"`
+	Icon       string   `json:"icon" binding:"omitempty,max=64"`
+	Sort       int      `json:"sort"`
+	Roles      []string `json:"roles"`
+	Permission string   `json:"permission" binding:"omitempty,max=64"`
+}
+
+// ListMenus 获取全部菜单（树形，不按角色过滤）
+// @Summary 获取菜单列表
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/menus [get]
+func ListMenus(c *gin.Context) {
+	menus, err := menu.List(c.Request.Context())
+	if err != nil {
+		menuError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"list": menu.Build(menus, 0),
+		},
+	})
+}
+
+// GetMenu 获取菜单
+// @Summary 获取菜单
+// @Tags Admin
+// @Produce json
+// @Param id path int true "菜单 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/menus/{id} [get]
+func GetMenu(c *gin.Context) {
+	m, err := menu.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		menuError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    m,
+	})
+}
+
+// CreateMenu 添加菜单（roles 为空表示所有角色可见，permission 为空表示不要求权限）
+// @Summary 添加菜单
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "上级菜单、名称、路径、图标、排序值、可见角色、需要的权限"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/menus [post]
+func CreateMenu(c *gin.Context) {
+	var req menuRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	m := model.Menu{}
+	req.apply(&m)
+	if err := menu.Create(c.Request.Context(), &m); err != nil {
+		menuError(c, err)
+		return
+	}
+	middleware.RecordChange(c, nil, m)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "创建成功",
+		"data":    m,
+	})
+}
+
+// UpdateMenu 修改菜单（整体替换，上级菜单不能是自己或自己的下级）
+// @Summary 修改菜单
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "菜单 ID"
+// @Param body body map[string]interface{} true "上级菜单、名称、路径、图标、排序值、可见角色、需要的权限"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/menus/{id} [put]
+func UpdateMenu(c *gin.Context) {
+	var req menuRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	m, err := menu.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		menuError(c, err)
+		return
+	}
+	before := *m
+	req.apply(m)
+	if err := menu.Update(c.Request.Context(), m); err != nil {
+		menuError(c, err)
+		return
+	}
+	middleware.RecordChange(c, before, m)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "更新成功",
+		"data":    m,
+	})
+}
+
+// DeleteMenu 删除菜单（还有下级菜单时不能删除）
+// @Summary 删除菜单
+// @Tags Admin
+// @Produce json
+// @Param id path int true "菜单 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/menus/{id} [delete]
+func DeleteMenu(c *gin.Context) {
+	m, err := menu.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		menuError(c, err)
+		return
+	}
+	if err := menu.Delete(c.Request.Context(), m.ID); err != nil {
+		menuError(c, err)
+		return
+	}
+	middleware.RecordChange(c, m, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "删除成功",
+	})
+}
+
+// apply 将请求中的字段写入菜单
+func (r menuRequest) apply(m *model.Menu) {
+	m.ParentID = r.ParentID
+	m.Name = r.Name
+	m.Path = r.Path
+	m.Icon = r.Icon
+	m.Sort = r.Sort
+	m.Roles = r.Roles
+	m.Permission = r.Permission
+}
+
+// menuError 写入菜单管理错误
+func menuError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, menu.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	case errors.Is(err, menu.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": err.Error()})
+	case errors.Is(err, menu.ErrInUse):
+		c.JSON(http.StatusConflict, gin.H{"code": 409, "message": err.Error()})
+	case errors.Is(err, menu.ErrUnavailable):
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "操作失败: " + err.Error()})
+	}
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"net/http"
+
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/notify"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotificationPreferences 获取当前管理员各分类的通知偏好
+// @Summary 获取通知偏好
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/notifications/preferences [get]
+func GetNotificationPreferences(c *gin.Context) {
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	var prefs []model.NotificationPreference
+	db.Where("admin_id = ?", adminClaims.AdminID).Find(&prefs)
+
+	byCategory := make(map[string]model.NotificationPreference, len(prefs))
+	for _, p := range prefs {
+		byCategory[p.Category] = p
+	}
+
+	list := make([]model.NotificationPreference, 0, len(notify.Categories))
+	for _, category := range notify.Categories {
+		p, ok := byCategory[category]
+		if !ok {
+			p = notify.DefaultPreference(adminClaims.AdminID, category)
+		}
+		list = append(list, p)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    list,
+	})
+}
+
+// UpdateNotificationPreference 设置当前管理员某一分类的摘要频率与免打扰时段
+// @Summary 设置通知偏好
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param category path string true "分类：security, system, reports"
+// @Param body body map[string]interface{} true "通知偏好"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/notifications/preferences/{category} [put]
+func UpdateNotificationPreference(c *gin.Context) {
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	var req struct {
+		Frequency  string `json:"frequency" binding:"required"`
+		QuietStart string `json:"quiet_start"`
+		QuietEnd   string `json:"quiet_end"`
+		Timezone   string `json:"timezone"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	category := c.Param("category")
+	p := model.NotificationPreference{AdminID: adminClaims.AdminID, Category: category}
+	db.Where("admin_id = ? AND category = ?", adminClaims.AdminID, category).First(&p)
+
+	p.Frequency = req.Frequency
+	p.QuietStart = req.QuietStart
+	p.QuietEnd = req.QuietEnd
+	p.Timezone = req.Timezone
+
+	if err := notify.Validate(p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := db.Save(&p).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "保存失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "保存成功",
+		"data":    p,
+	})
+}
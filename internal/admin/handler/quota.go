@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"new-openclaw/internal/admin/scope"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/quota"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListQuotas 获取单独配置的 AppKey 配额及当前用量
+// @Summary 获取配额列表
+// @Tags Admin
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/quotas [get]
+func ListQuotas(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	var quotas []model.AppKeyQuota
+	var total int64
+
+	query := db.Model(&model.AppKeyQuota{}).Scopes(scope.ByAppKey(scope.Of(c)))
+	query.Count(&total)
+	query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&quotas)
+
+	list := make([]gin.H, 0, len(quotas))
+	for _, q := range quotas {
+		item := gin.H{"quota": q}
+		if usage, err := quota.GetUsage(c.Request.Context(), q.AppKey); err == nil {
+			item["usage"] = usage
+		}
+		list = append(list, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"list":      list,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+			"defaults":  quota.Defaults(),
+		},
+	})
+}
+
+// GetQuota 获取 AppKey 配额用量（未单独配置时使用默认配额）
+// @Summary 获取配额用量
+// @Tags Admin
+// @Produce json
+// @Param app_key path string true "AppKey"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/quotas/{app_key} [get]
+func GetQuota(c *gin.Context) {
+	if !appKeyInScope(c) {
+		return
+	}
+	usage, err := quota.GetUsage(c.Request.Context(), c.Param("app_key"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查询用量失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    usage,
+	})
+}
+
+// SetQuota 设置 AppKey 配额
+// @Summary 设置配额
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param app_key path string true "AppKey"
+// @Param body body map[string]interface{} true "配额"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/quotas/{app_key} [put]
+func SetQuota(c *gin.Context) {
+	var req struct {
+		DailyLimit   int64  `json:"daily_limit" binding:"min=0"`
+		MonthlyLimit int64  `json:"monthly_limit" binding:"min=0"`
+		Remark       string `json:"remark"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if !appKeyInScope(c) {
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	appKey := c.Param("app_key")
+	q := model.AppKeyQuota{AppKey: appKey}
+	db.Where("app_key = ?", appKey).First(&q)
+
+	q.DailyLimit = req.DailyLimit
+	q.MonthlyLimit = req.MonthlyLimit
+	q.Remark = req.Remark
+
+	if err := db.Save(&q).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "保存失败: " + err.Error(),
+		})
+		return
+	}
+	quota.Invalidate(appKey)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "保存成功",
+		"data":    q,
+	})
+}
+
+// DeleteQuota 删除 AppKey 的单独配额（恢复为默认配额）
+// @Summary 删除配额
+// @Tags Admin
+// @Produce json
+// @Param app_key path string true "AppKey"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/quotas/{app_key} [delete]
+func DeleteQuota(c *gin.Context) {
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+	if !appKeyInScope(c) {
+		return
+	}
+
+	appKey := c.Param("app_key")
+	result := db.Where("app_key = ?", appKey).Delete(&model.AppKeyQuota{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除失败: " + result.Error.Error(),
+		})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "配额不存在",
+		})
+		return
+	}
+	quota.Invalidate(appKey)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "删除成功",
+	})
+}
+
+// ResetQuotaUsage 清零 AppKey 当前周期用量
+// @Summary 清零配额用量
+// @Tags Admin
+// @Produce json
+// @Param app_key path string true "AppKey"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/quotas/{app_key}/reset [post]
+func ResetQuotaUsage(c *gin.Context) {
+	if !appKeyInScope(c) {
+		return
+	}
+	if err := quota.Reset(c.Request.Context(), c.Param("app_key")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "清零失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "清零成功",
+	})
+}
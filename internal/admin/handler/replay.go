@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"new-openclaw/internal/replay"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReplayRequest 将审计日志中的请求重放到目标环境并对比响应
+// @Summary 重放请求
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request_id path string true "请求ID"
+// @Param body body map[string]interface{} false "目标环境与 Authorization"
+// @Success 200 {object} replay.Result
+// @Router /admin/replay/{request_id} [post]
+func ReplayRequest(c *gin.Context) {
+	var req struct {
+		Target        string `json:"target"`
+		Authorization string `json:"authorization"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "参数错误: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	entry, err := replay.Find(c.Param("request_id"))
+	if errors.Is(err, replay.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "读取审计日志失败: " + err.Error(),
+		})
+		return
+	}
+
+	result, err := replay.Replay(c.Request.Context(), entry, replay.Options{
+		Target:        req.Target,
+		Authorization: req.Authorization,
+	})
+	if errors.Is(err, replay.ErrUnknownTarget) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    gin.H{"targets": replay.Targets()},
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"code":    502,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
@@ -0,0 +1,477 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/admin/rbac"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListRoles 获取管理员角色
+// @Summary 获取角色列表
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/roles [get]
+func ListRoles(c *gin.Context) {
+	list, err := rbac.ListRoles(c.Request.Context())
+	if err != nil {
+		rbacError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    list,
+	})
+}
+
+// GetRole 获取角色的权限与使用该角色的管理员数
+// @Summary 获取角色
+// @Tags Admin
+// @Produce json
+// @Param name path string true "角色名"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/roles/{name} [get]
+func GetRole(c *gin.Context) {
+	role, err := rbac.GetRole(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		rbacError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    role,
+	})
+}
+
+// CreateRole 添加角色（只能授予自己拥有的权限）
+// @Summary 添加角色
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "角色名、权限、说明"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/roles [post]
+func CreateRole(c *gin.Context) {
+	var req struct {
+		Name        string   `json:"name" binding:"required,max=32"`
+		Permissions []string `json:"permissions"`
+		Description string   `json:"description" binding:"omitempty,max=255"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if !canGrant(c, req.Permissions) {
+		return
+	}
+
+	role := model.AdminRole{
+		Name:        req.Name,
+		Permissions: req.Permissions,
+		Description: req.Description,
+	}
+	if err := rbac.CreateRole(c.Request.Context(), &role); err != nil {
+		rbacError(c, err)
+		return
+	}
+	middleware.RecordChange(c, nil, role)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "创建成功",
+		"data":    role,
+	})
+}
+
+// UpdateRole 修改角色的权限或说明（只能修改权限不超过自己的角色，不能修改自己的角色）
+// @Summary 修改角色
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param name path string true "角色名"
+// @Param body body map[string]interface{} true "权限、说明"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/roles/{name} [put]
+func UpdateRole(c *gin.Context) {
+	var req struct {
+		Permissions *[]string `json:"permissions"`
+		Description *string   `json:"description" binding:"omitempty,max=255"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	role, err := rbac.GetRole(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		rbacError(c, err)
+		return
+	}
+	if role.Name == middleware.GetCurrentAdmin(c).Role {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "不能修改自己的角色",
+		})
+		return
+	}
+	if !canGrant(c, middleware.PermissionsOf(role.Name)) {
+		return
+	}
+	before := *role
+
+	if req.Permissions != nil {
+		if !canGrant(c, *req.Permissions) {
+			return
+		}
+		role.Permissions = *req.Permissions
+	}
+	if req.Description != nil {
+		role.Description = *req.Description
+	}
+	if err := rbac.UpdateRole(c.Request.Context(), role); err != nil {
+		rbacError(c, err)
+		return
+	}
+	middleware.RecordChange(c, before, role)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "更新成功",
+		"data":    role,
+	})
+}
+
+// DeleteRole 删除自定义角色（仍有管理员使用时不能删除）
+// @Summary 删除角色
+// @Tags Admin
+// @Produce json
+// @Param name path string true "角色名"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/roles/{name} [delete]
+func DeleteRole(c *gin.Context) {
+	role, err := rbac.GetRole(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		rbacError(c, err)
+		return
+	}
+	if !canGrant(c, middleware.PermissionsOf(role.Name)) {
+		return
+	}
+	if err := rbac.DeleteRole(c.Request.Context(), role.Name); err != nil {
+		rbacError(c, err)
+		return
+	}
+	middleware.RecordChange(c, role, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "删除成功",
+	})
+}
+
+// AssignAdminRole 修改管理员的角色（吊销该管理员现有的 Token，使新角色立即生效）
+// @Summary 分配管理员角色
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "管理员ID"
+// @Param body body map[string]interface{} true "角色名"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/admins/{id}/role [put]
+func AssignAdminRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的ID",
+		})
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	var admin model.Admin
+	if err := db.First(&admin, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "管理员不存在",
+		})
+		return
+	}
+
+	current := middleware.GetCurrentAdmin(c)
+	if admin.ID == current.AdminID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "不能修改自己的角色",
+		})
+		return
+	}
+	// 只有超级管理员能任命或调整超级管理员
+	if (req.Role == rbac.SuperAdmin || admin.Role == rbac.SuperAdmin) && current.GlobalRole() != rbac.SuperAdmin {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "只有超级管理员能分配或调整超级管理员角色",
+		})
+		return
+	}
+
+	role, err := rbac.GetRole(c.Request.Context(), req.Role)
+	if err != nil {
+		rbacError(c, err)
+		return
+	}
+	// 新旧角色的权限（角色管理维护的与配置授予的，如 ADMIN_PII_READ_ROLES）都不能超出自己的权限：
+	// 不能借分配角色提权，也不能调整权限更高的管理员
+	if !canGrant(c, middleware.PermissionsOf(role.Name)) {
+		return
+	}
+	if admin.Role != role.Name && !canGrant(c, middleware.PermissionsOf(admin.Role)) {
+		return
+	}
+
+	before := admin
+	if err := db.Model(&admin).Update("role", role.Name).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "更新失败: " + err.Error(),
+		})
+		return
+	}
+	middleware.RecordChange(c, before, admin)
+
+	// 角色写在 Token 中，吊销该管理员现有的 Token
+	if before.Role != admin.Role {
+		ttl := middleware.DefaultConfig.TokenExpiry
+		if err := session.RevokeAll(c.Request.Context(), middleware.DefaultConfig.Issuer, strconv.FormatUint(id, 10), ttl); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "角色已更新，但强制下线失败: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "更新成功",
+		"data":    admin,
+	})
+}
+
+// ListPermissions 获取可分配的权限
+// @Summary 获取权限列表
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/permissions [get]
+func ListPermissions(c *gin.Context) {
+	list, err := rbac.ListPermissions(c.Request.Context())
+	if err != nil {
+		rbacError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    list,
+	})
+}
+
+// GetPermission 获取权限
+// @Summary 获取权限
+// @Tags Admin
+// @Produce json
+// @Param name path string true "权限名"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/permissions/{name} [get]
+func GetPermission(c *gin.Context) {
+	p, err := rbac.GetPermission(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		rbacError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    p,
+	})
+}
+
+// CreatePermission 登记自定义权限，之后可分配给角色
+// @Summary 添加权限
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "权限名、说明"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/permissions [post]
+func CreatePermission(c *gin.Context) {
+	var req struct {
+		Name        string `json:"name" binding:"required,max=64"`
+		Description string `json:"description" binding:"omitempty,max=255"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	p := model.AdminPermission{Name: req.Name, Description: req.Description}
+	if err := rbac.CreatePermission(c.Request.Context(), &p); err != nil {
+		rbacError(c, err)
+		return
+	}
+	middleware.RecordChange(c, nil, p)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "创建成功",
+		"data":    p,
+	})
+}
+
+// UpdatePermission 修改权限说明
+// @Summary 修改权限
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param name path string true "权限名"
+// @Param body body map[string]interface{} true "说明"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/permissions/{name} [put]
+func UpdatePermission(c *gin.Context) {
+	var req struct {
+		Description string `json:"description" binding:"max=255"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	p, err := rbac.GetPermission(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		rbacError(c, err)
+		return
+	}
+	before := *p
+	p.Description = req.Description
+	if err := rbac.UpdatePermission(c.Request.Context(), p); err != nil {
+		rbacError(c, err)
+		return
+	}
+	middleware.RecordChange(c, before, p)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "更新成功",
+		"data":    p,
+	})
+}
+
+// DeletePermission 删除自定义权限（仍被角色引用时不能删除）
+// @Summary 删除权限
+// @Tags Admin
+// @Produce json
+// @Param name path string true "权限名"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/permissions/{name} [delete]
+func DeletePermission(c *gin.Context) {
+	p, err := rbac.GetPermission(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		rbacError(c, err)
+		return
+	}
+	if err := rbac.DeletePermission(c.Request.Context(), p.Name); err != nil {
+		rbacError(c, err)
+		return
+	}
+	middleware.RecordChange(c, p, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "删除成功",
+	})
+}
+
+// canGrant 当前管理员是否拥有全部权限（不能授予或调整自己没有的权限），没有时写入 403
+func canGrant(c *gin.Context, permissions []string) bool {
+	current := middleware.GetCurrentAdmin(c)
+	for _, p := range permissions {
+		if !middleware.HasPermission(current, p) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "不能授予或调整自己没有的权限: " + p,
+			})
+			return false
+		}
+	}
+	return true
+}
+
+// rbacError 写入角色与权限管理的错误
+func rbacError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, rbac.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	case errors.Is(err, rbac.ErrBuiltin):
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": err.Error()})
+	case errors.Is(err, rbac.ErrRoleNotFound), errors.Is(err, rbac.ErrPermissionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": err.Error()})
+	case errors.Is(err, rbac.ErrExists), errors.Is(err, rbac.ErrInUse):
+		c.JSON(http.StatusConflict, gin.H{"code": 409, "message": err.Error()})
+	case errors.Is(err, rbac.ErrUnavailable):
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "操作失败: " + err.Error()})
+	}
+}
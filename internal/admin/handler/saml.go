@@ -0,0 +1,412 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/database"
+	commonmiddleware "new-openclaw/internal/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/oauth"
+	"new-openclaw/internal/store"
+	"new-openclaw/pkg/config"
+	"new-openclaw/pkg/saml"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	// samlSP 为空表示未启用 SAML 单点登录
+	samlSP  *saml.ServiceProvider
+	samlCfg config.SAMLConfig
+)
+
+// 单点登录账号不可用（返回 403）
+var (
+	errSAMLNoAccount = errors.New("管理员账号不存在")
+	errSAMLDisabled  = errors.New("账号已禁用")
+)
+
+// samlRolePriority 同时属于多个角色的分组时，按此顺序取权限最高的角色（其他角色排在其后，按名称排序）
+var samlRolePriority = []string{"super_admin", "admin", "editor"}
+
+// ConfigureSAML 设置 SAML 单点登录；未配置 IdP 单点登录地址时不启用。
+// SP 实体 ID 与 ACS 地址默认由 baseURL（APP_BASE_URL）生成
+func ConfigureSAML(cfg config.SAMLConfig, baseURL string) error {
+	samlSP = nil
+	if cfg.IdPSSOURL == "" {
+		return nil
+	}
+
+	certPEM := []byte(cfg.IdPCert)
+	if cfg.IdPCertFile != "" {
+		data, err := os.ReadFile(cfg.IdPCertFile)
+		if err != nil {
+			return fmt.Errorf("读取 SAML IdP 证书失败，单点登录未启用: %w", err)
+		}
+		certPEM = data
+	}
+	if len(certPEM) == 0 {
+		return errors.New("未配置 SAML_IDP_CERT_FILE 或 SAML_IDP_CERT，SAML 单点登录未启用")
+	}
+	certs, err := saml.ParseCertificates(certPEM)
+	if err != nil {
+		return fmt.Errorf("%v，SAML 单点登录未启用", err)
+	}
+	for _, cert := range certs {
+		if time.Now().After(cert.NotAfter) {
+			log.Printf("⚠️  SAML IdP 证书已于 %s 过期（仍用于校验签名，请尽快更新）", cert.NotAfter.Format(time.RFC3339))
+		}
+	}
+
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if cfg.EntityID == "" && baseURL != "" {
+		cfg.EntityID = baseURL + "/admin/saml/metadata"
+	}
+	if cfg.ACSURL == "" && baseURL != "" {
+		cfg.ACSURL = baseURL + "/admin/saml/acs"
+	}
+	if cfg.EntityID == "" || cfg.ACSURL == "" {
+		return errors.New("启用 SAML 单点登录需要配置 APP_BASE_URL（或 SAML_SP_ENTITY_ID 与 SAML_ACS_URL）")
+	}
+	if _, err := url.Parse(cfg.IdPSSOURL); err != nil {
+		return fmt.Errorf("SAML_IDP_SSO_URL 无效，单点登录未启用: %w", err)
+	}
+
+	samlCfg = cfg
+	samlSP = &saml.ServiceProvider{
+		EntityID:        cfg.EntityID,
+		ACSURL:          cfg.ACSURL,
+		IdPEntityID:     cfg.IdPEntityID,
+		IdPSSOURL:       cfg.IdPSSOURL,
+		IdPCertificates: certs,
+		ClockSkew:       cfg.ClockSkew,
+	}
+	return nil
+}
+
+// samlAvailable 未启用 SAML 单点登录时返回 404
+func samlAvailable(c *gin.Context) bool {
+	if samlSP == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "未启用 SAML 单点登录",
+		})
+		return false
+	}
+	return true
+}
+
+// samlRequest 发往 IdP 的认证请求（以请求 ID 为 Key 保存，只能使用一次）
+type samlRequest struct {
+	Redirect string `json:"redirect,omitempty"`
+}
+
+// SAMLMetadata SP 元数据（导入 IdP 完成配置）
+// @Summary SAML SP 元数据
+// @Tags Admin
+// @Produce xml
+// @Success 200 {string} string "SP 元数据"
+// @Router /admin/saml/metadata [get]
+func SAMLMetadata(c *gin.Context) {
+	if !samlAvailable(c) {
+		return
+	}
+	c.Data(http.StatusOK, "application/samlmetadata+xml", samlSP.Metadata())
+}
+
+// SAMLLogin 跳转到 IdP 登录；redirect 为登录完成后跳转的前端地址（须在 SAML_ALLOWED_REDIRECTS 中，为空时 ACS 返回 JSON）
+// @Summary SAML 单点登录
+// @Tags Admin
+// @Param redirect query string false "登录完成后跳转的前端地址"
+// @Success 302 {string} string "跳转到 IdP"
+// @Router /admin/saml/login [get]
+func SAMLLogin(c *gin.Context) {
+	if !samlAvailable(c) {
+		return
+	}
+	redirect := c.Query("redirect")
+	if redirect != "" && !oauth.AllowedRedirect(samlCfg.AllowedRedirects, redirect) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "不允许的跳转地址",
+		})
+		return
+	}
+
+	target, id, err := samlSP.AuthnRequest("")
+	if err != nil {
+		log.Printf("生成 SAML 认证请求失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "生成认证请求失败",
+		})
+		return
+	}
+	data, _ := json.Marshal(samlRequest{Redirect: redirect})
+	if err := store.For(store.ComponentNonce).Set(c.Request.Context(), samlRequestKey(id), string(data), samlCfg.RequestTTL); err != nil {
+		log.Printf("保存 SAML 认证请求失败: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": "单点登录暂不可用",
+		})
+		return
+	}
+	http.SetCookie(c.Writer, samlRequestCookie(id, samlCfg.RequestTTL))
+	c.Redirect(http.StatusFound, target)
+}
+
+// SAMLACS 断言消费服务：IdP 以 HTTP-POST 绑定提交 SAMLResponse，校验通过后按分组映射角色，
+// 查找或创建管理员并签发 Token。登录时指定了 redirect 的，跳转回该地址，Token 放在 URL 片段中
+// @Summary SAML 断言消费服务
+// @Tags Admin
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param SAMLResponse formData string true "Base64 编码的 SAML 响应"
+// @Success 200 {object} model.AdminLoginResponse
+// @Router /admin/saml/acs [post]
+func SAMLACS(c *gin.Context) {
+	if !samlAvailable(c) {
+		return
+	}
+	ctx := c.Request.Context()
+
+	assertion, err := samlSP.ParseResponse(c.PostForm("SAMLResponse"))
+	if err != nil {
+		samlLoginFailed(c, http.StatusUnauthorized, "", nil, err.Error())
+		return
+	}
+
+	// SP 发起的登录：认证请求只能使用一次，且须由发起登录的浏览器提交；IdP 发起的登录需显式开启
+	var request samlRequest
+	if assertion.InResponseTo != "" {
+		cookie, _ := c.Cookie(samlRequestCookieName)
+		http.SetCookie(c.Writer, samlRequestCookie("", -1))
+		if subtle.ConstantTimeCompare([]byte(cookie), []byte(assertion.InResponseTo)) != 1 {
+			samlLoginFailed(c, http.StatusUnauthorized, assertion.NameID, nil, "登录请求不是由当前浏览器发起的")
+			return
+		}
+		s := store.For(store.ComponentNonce)
+		key := samlRequestKey(assertion.InResponseTo)
+		value, err := s.Get(ctx, key)
+		if err == nil {
+			var ok bool
+			ok, err = s.SetNX(ctx, key+":used", "1", samlCfg.RequestTTL)
+			if err == nil && !ok {
+				err = store.ErrNotFound
+			}
+		}
+		if err != nil {
+			samlLoginFailed(c, http.StatusUnauthorized, assertion.NameID, nil, "登录请求已失效")
+			return
+		}
+		s.Del(ctx, key)
+		json.Unmarshal([]byte(value), &request)
+	} else if !samlCfg.AllowIdPInitiated {
+		samlLoginFailed(c, http.StatusUnauthorized, assertion.NameID, nil, "不允许 IdP 发起的登录")
+		return
+	}
+
+	// 同一断言只能使用一次（保留到断言失效）
+	ttl := time.Until(assertion.NotOnOrAfter) + samlCfg.ClockSkew
+	if ttl < time.Minute {
+		ttl = time.Minute
+	}
+	if ok, err := store.For(store.ComponentNonce).SetNX(ctx, "saml:assertion:"+assertion.ID, "1", ttl); err != nil || !ok {
+		samlLoginFailed(c, http.StatusUnauthorized, assertion.NameID, nil, "断言已使用")
+		return
+	}
+
+	username := assertion.NameID
+	if samlCfg.UsernameAttribute != "" {
+		username = assertion.Attribute(samlCfg.UsernameAttribute)
+	}
+	username = strings.TrimSpace(username)
+	if username == "" || len(username) > 50 {
+		samlLoginFailed(c, http.StatusUnauthorized, username, nil, "无法从断言中获取有效的用户名")
+		return
+	}
+	role := samlRole(assertion.Attributes[samlCfg.GroupsAttribute])
+	if role == "" {
+		samlLoginFailed(c, http.StatusForbidden, username, nil, "未分配管理后台角色")
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+	admin, err := samlProvision(db, username, assertion.Attribute(samlCfg.EmailAttribute), role)
+	if errors.Is(err, errSAMLNoAccount) || errors.Is(err, errSAMLDisabled) {
+		samlLoginFailed(c, http.StatusForbidden, username, admin, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("SAML 单点登录同步管理员失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "登录失败",
+		})
+		return
+	}
+
+	resp, ok := issueLoginToken(c, db, admin, middleware.AuthMethodSAML)
+	if !ok {
+		return
+	}
+	if request.Redirect != "" {
+		fragment := url.Values{
+			"token":      {resp.Token},
+			"expires_at": {strconv.FormatInt(resp.ExpiresAt, 10)},
+		}
+		c.Redirect(http.StatusFound, request.Redirect+"#"+fragment.Encode())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "登录成功",
+		"data":    resp,
+	})
+}
+
+// samlProvision 按用户名查找管理员（不存在且开启自动创建时创建），并将角色同步为分组映射的角色
+func samlProvision(db *gorm.DB, username, email, role string) (*model.Admin, error) {
+	var admin model.Admin
+	err := db.Where("username = ?", username).First(&admin).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if !samlCfg.AutoCreate {
+			return nil, errSAMLNoAccount
+		}
+		// 单点登录创建的管理员使用随机密码，只能通过 IdP 登录（超级管理员可以为其重置密码）
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		admin = model.Admin{Username: username, Email: email, Role: role, Status: 1}
+		if err := admin.SetPassword(hex.EncodeToString(secret)); err != nil {
+			return nil, err
+		}
+		if err := db.Create(&admin).Error; err != nil {
+			return nil, err
+		}
+		log.Printf("SAML 单点登录创建管理员: %s（角色 %s）", username, role)
+		return &admin, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if admin.Status != 1 {
+		return &admin, errSAMLDisabled
+	}
+	updates := map[string]interface{}{}
+	if admin.Role != role {
+		log.Printf("SAML 单点登录同步管理员角色: %s %s -> %s", username, admin.Role, role)
+		updates["role"] = role
+		admin.Role = role
+	}
+	if admin.Email == "" && email != "" {
+		updates["email"] = email
+		admin.Email = email
+	}
+	if len(updates) > 0 {
+		if err := db.Model(&admin).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &admin, nil
+}
+
+// samlRole 按 IdP 分组映射管理员角色：属于多个已映射分组时取权限最高的角色，都不属于时取默认角色
+func samlRole(groups []string) string {
+	member := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		member[g] = true
+	}
+
+	roles := make([]string, 0, len(samlCfg.RoleGroups))
+	for role := range samlCfg.RoleGroups {
+		roles = append(roles, role)
+	}
+	rank := func(role string) int {
+		for i, r := range samlRolePriority {
+			if r == role {
+				return i
+			}
+		}
+		return len(samlRolePriority)
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		if ri, rj := rank(roles[i]), rank(roles[j]); ri != rj {
+			return ri < rj
+		}
+		return roles[i] < roles[j]
+	})
+
+	for _, role := range roles {
+		for _, g := range samlCfg.RoleGroups[role] {
+			if member[g] {
+				return role
+			}
+		}
+	}
+	return samlCfg.DefaultRole
+}
+
+// samlLoginFailed 记录失败的单点登录并返回错误（admin 为空表示账号不存在或尚未查询）
+func samlLoginFailed(c *gin.Context, status int, username string, admin *model.Admin, reason string) {
+	commonmiddleware.ReportAuthFailure(c, commonmiddleware.AuthFailureSAML, username, reason)
+	recordLogin(c, username, admin, middleware.AuthMethodSAML, model.LoginResultFailed, reason)
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": "单点登录失败: " + reason,
+	})
+}
+
+// samlRequestCookieName 保存认证请求 ID 的 Cookie，ACS 校验断言的 InResponseTo 与其一致，防止他人发起的登录在当前浏览器完成
+const samlRequestCookieName = "saml_request"
+
+// samlRequestCookie 认证请求 Cookie（ttl < 0 时清除）。IdP 以跨站 POST 提交断言，HTTPS 的 ACS 使用 SameSite=None 才能携带
+func samlRequestCookie(id string, ttl time.Duration) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     samlRequestCookieName,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   int(ttl / time.Second),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if u, err := url.Parse(samlCfg.ACSURL); err == nil {
+		cookie.Path = u.Path
+		if u.Scheme == "https" {
+			cookie.Secure = true
+			cookie.SameSite = http.SameSiteNoneMode
+		}
+	}
+	if ttl < 0 {
+		cookie.MaxAge = -1
+	}
+	return cookie
+}
+
+func samlRequestKey(id string) string {
+	return "saml:" + id
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/secevents"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSecurityEvents 查询安全事件（攻击检测、认证失败、IP 封禁与解除）
+// @Summary 获取安全事件列表
+// @Tags Admin
+// @Produce json
+// @Param type query string false "事件类型，多个以逗号分隔：attack_detected、auth_failed、ip_banned、ban_lifted"
+// @Param severity query string false "最低严重级别：low、medium、high、critical"
+// @Param ip query string false "客户端 IP"
+// @Param from query string false "时间起（RFC3339 或 2006-01-02）"
+// @Param to query string false "时间止（RFC3339 或 2006-01-02，按日期时包含当天）"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/security/events [get]
+func ListSecurityEvents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := secevents.Filter{
+		MinSeverity: c.Query("severity"),
+		IP:          c.Query("ip"),
+	}
+	if filter.MinSeverity != "" && !secevents.ValidSeverity(filter.MinSeverity) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "severity 应为 " + strings.Join(model.Severities, "、") + " 之一",
+		})
+		return
+	}
+	if types := c.Query("type"); types != "" {
+		filter.Types = strings.Split(types, ",")
+	}
+	var ok bool
+	if filter.From, ok = parseAuditTime(c, "from", false); !ok {
+		return
+	}
+	if filter.To, ok = parseAuditTime(c, "to", true); !ok {
+		return
+	}
+
+	events, total, err := secevents.List(c.Request.Context(), filter, page, pageSize)
+	if err != nil {
+		securityEventError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"list":      events,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// TestSecurityAlert 向所有已配置的告警渠道发送一条测试告警（不经过去重与限流）
+// @Summary 发送测试安全告警
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/security/alerts/test [post]
+func TestSecurityAlert(c *gin.Context) {
+	channels := secevents.Channels()
+	if len(channels) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "未配置告警渠道",
+		})
+		return
+	}
+
+	results := secevents.Send(c.Request.Context(), secevents.Alert{Event: model.SecurityEvent{
+		Type:      "test",
+		Severity:  model.SeverityLow,
+		IP:        c.ClientIP(),
+		Detail:    "测试告警，请忽略",
+		CreatedAt: time.Now(),
+	}})
+
+	data := gin.H{}
+	for channel, err := range results {
+		if err != nil {
+			data[channel] = err.Error()
+			continue
+		}
+		data[channel] = "ok"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    data,
+	})
+}
+
+// securityEventError 写入安全事件查询错误
+func securityEventError(c *gin.Context, err error) {
+	if errors.Is(err, secevents.ErrUnavailable) {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "查询失败: " + err.Error()})
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"new-openclaw/internal/secstate"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSecurityState 查看安全状态：默认返回本实例当前生效的状态；
+// 指定 at 时返回当时各实例（或指定实例）最后一次快照中的状态
+// @Summary 查看当前或历史某一时刻的安全状态
+// @Tags Admin
+// @Produce json
+// @Param at query string false "时间点（RFC3339 或 2006-01-02），为空时返回当前状态"
+// @Param instance query string false "实例（仅 at 时有效）"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/security/state [get]
+func GetSecurityState(c *gin.Context) {
+	if c.Query("at") == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "success",
+			"data":    secstate.Capture(),
+		})
+		return
+	}
+
+	at, ok := parseAuditTime(c, "at", true)
+	if !ok {
+		return
+	}
+	snapshots, err := secstate.At(c.Request.Context(), at, c.Query("instance"))
+	if err != nil {
+		securityStateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"at":        at,
+			"snapshots": snapshots,
+		},
+	})
+}
+
+// ListSecuritySnapshots 安全状态快照列表（不含状态内容）
+// @Summary 安全状态快照列表
+// @Tags Admin
+// @Produce json
+// @Param from query string false "时间起（RFC3339 或 2006-01-02）"
+// @Param to query string false "时间止（RFC3339 或 2006-01-02，按日期时包含当天）"
+// @Param instance query string false "实例"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/security/snapshots [get]
+func ListSecuritySnapshots(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	from, ok := parseAuditTime(c, "from", false)
+	if !ok {
+		return
+	}
+	to, ok := parseAuditTime(c, "to", true)
+	if !ok {
+		return
+	}
+
+	snapshots, total, err := secstate.List(c.Request.Context(), from, to, c.Query("instance"), page, pageSize)
+	if err != nil {
+		securityStateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"list":      snapshots,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// CreateSecuritySnapshot 立即保存本实例的安全状态快照（如变更规则前后留档）
+// @Summary 保存安全状态快照
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/security/snapshots [post]
+func CreateSecuritySnapshot(c *gin.Context) {
+	snapshot, err := secstate.Take(c.Request.Context(), secstate.TriggerManual)
+	if err != nil {
+		securityStateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "快照已保存",
+		"data":    snapshot,
+	})
+}
+
+// GetSecuritySnapshot 获取安全状态快照详情
+// @Summary 获取安全状态快照详情
+// @Tags Admin
+// @Produce json
+// @Param id path string true "快照 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/security/snapshots/{id} [get]
+func GetSecuritySnapshot(c *gin.Context) {
+	snapshot, err := secstate.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		securityStateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    snapshot,
+	})
+}
+
+// securityStateError 快照不存在返回 404，MongoDB 未连接返回 503
+func securityStateError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	message := err.Error()
+	switch {
+	case errors.Is(err, secstate.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, secstate.ErrUnavailable):
+		status = http.StatusServiceUnavailable
+	default:
+		log.Printf("安全状态快照操作失败: %v", err)
+		message = "安全状态快照操作失败"
+	}
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": message,
+	})
+}
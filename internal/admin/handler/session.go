@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/revocation"
+	"new-openclaw/internal/session"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// sessionLimit 每个管理员的并发会话上限
+var sessionLimit session.Limit
+
+// ConfigureSessionLimit 设置管理员并发会话上限及超出时的策略
+func ConfigureSessionLimit(limit session.Limit) {
+	sessionLimit = limit
+}
+
+// ListSessions 获取当前管理员的活跃会话
+// @Summary 获取当前管理员的活跃会话
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} session.Session
+// @Router /admin/sessions [get]
+func ListSessions(c *gin.Context) {
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	sessions, err := session.List(c.Request.Context(), adminClaims.Issuer, adminClaims.Subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查询失败: " + err.Error(),
+		})
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == adminClaims.ID
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    sessions,
+	})
+}
+
+// RevokeSession 吊销当前管理员的某个会话
+// @Summary 吊销会话
+// @Tags Admin
+// @Produce json
+// @Param id path string true "会话ID（jti）"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/sessions/{id} [delete]
+func RevokeSession(c *gin.Context) {
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	if err := session.Revoke(c.Request.Context(), adminClaims.Issuer, adminClaims.Subject, c.Param("id")); err != nil {
+		sessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "会话已吊销",
+	})
+}
+
+// ListAdminSessions 获取指定管理员的活跃会话
+// @Summary 获取管理员的活跃会话
+// @Tags Admin
+// @Produce json
+// @Param id path int true "管理员ID"
+// @Success 200 {array} session.Session
+// @Router /admin/admins/{id}/sessions [get]
+func ListAdminSessions(c *gin.Context) {
+	id, ok := sessionAdminID(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := session.List(c.Request.Context(), middleware.DefaultConfig.Issuer, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查询失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    sessions,
+	})
+}
+
+// RevokeAdminSession 吊销指定管理员的某个会话
+// @Summary 吊销管理员的会话
+// @Tags Admin
+// @Produce json
+// @Param id path int true "管理员ID"
+// @Param sid path string true "会话ID（jti）"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/admins/{id}/sessions/{sid} [delete]
+func RevokeAdminSession(c *gin.Context) {
+	id, ok := sessionAdminID(c)
+	if !ok {
+		return
+	}
+
+	if err := session.Revoke(c.Request.Context(), middleware.DefaultConfig.Issuer, id, c.Param("sid")); err != nil {
+		sessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "会话已吊销",
+	})
+}
+
+// ListSessionEvents 获取当前管理员的会话事件（超出并发上限被拒绝、被吊销等）
+// @Summary 获取会话事件
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{} "events 为会话事件列表，limit 为并发会话上限"
+// @Router /admin/sessions/events [get]
+func ListSessionEvents(c *gin.Context) {
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	sessionEvents(c, adminClaims.Subject)
+}
+
+// ListAdminSessionEvents 获取指定管理员的会话事件
+// @Summary 获取管理员的会话事件
+// @Tags Admin
+// @Produce json
+// @Param id path int true "管理员ID"
+// @Success 200 {object} map[string]interface{} "events 为会话事件列表，limit 为并发会话上限"
+// @Router /admin/admins/{id}/sessions/events [get]
+func ListAdminSessionEvents(c *gin.Context) {
+	id, ok := sessionAdminID(c)
+	if !ok {
+		return
+	}
+
+	sessionEvents(c, id)
+}
+
+// sessionEvents 返回会话事件及当前的并发上限配置
+func sessionEvents(c *gin.Context, subject string) {
+	events, err := session.Events(c.Request.Context(), middleware.DefaultConfig.Issuer, subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查询失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"limit":  sessionLimit,
+			"events": events,
+		},
+	})
+}
+
+// admitSession 拒绝新登录策略下检查并发会话上限，超出时已写入响应
+func admitSession(c *gin.Context, adminID uint) bool {
+	subject := strconv.FormatUint(uint64(adminID), 10)
+	err := session.Admit(c.Request.Context(), middleware.DefaultConfig.Issuer, subject, sessionLimit, c.ClientIP(), c.Request.UserAgent())
+	if errors.Is(err, session.ErrLimitExceeded) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": fmt.Sprintf("同时登录的会话数已达上限（%d），请先在其他设备登出或吊销会话", sessionLimit.Max),
+		})
+		return false
+	}
+	if err != nil {
+		// 会话存储不可用时不阻断登录
+		log.Printf("检查会话上限失败: admin=%d err=%v", adminID, err)
+	}
+	return true
+}
+
+// trackSession 记录新签发的 Token，用于会话列表与单独吊销；超出并发上限时按策略吊销最早的会话
+func trackSession(c *gin.Context, token string) {
+	claims, err := middleware.ParseToken(token)
+	if err != nil {
+		return
+	}
+	ctx := c.Request.Context()
+	if err := session.Track(ctx, claims.RegisteredClaims, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		log.Printf("记录会话失败: admin=%d err=%v", claims.AdminID, err)
+		return
+	}
+
+	revoked, err := session.Enforce(ctx, claims.Issuer, claims.Subject, sessionLimit, claims.ID)
+	if err != nil {
+		log.Printf("吊销超出上限的会话失败: admin=%d err=%v", claims.AdminID, err)
+	}
+	for _, s := range revoked {
+		log.Printf("会话数超过上限，已吊销最早的会话: admin=%d session=%s device=%s", claims.AdminID, s.ID, s.Device)
+	}
+}
+
+// revokeToken 使管理后台的登录凭证立即失效：服务端会话直接删除，JWT（包括紧急访问 Token）加入黑名单
+func revokeToken(ctx context.Context, claims jwt.RegisteredClaims) error {
+	if middleware.ServerSessions() && !strings.HasPrefix(claims.Subject, middleware.BreakGlassSubjectPrefix) {
+		return session.Destroy(ctx, claims.ID)
+	}
+	return revocation.Revoke(ctx, claims)
+}
+
+// sessionAdminID 解析路径中的管理员ID并确认管理员存在
+func sessionAdminID(c *gin.Context) (string, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的ID",
+		})
+		return "", false
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return "", false
+	}
+
+	var admin model.Admin
+	if err := db.First(&admin, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "管理员不存在",
+		})
+		return "", false
+	}
+	return strconv.FormatUint(id, 10), true
+}
+
+// sessionError 将会话错误转换为响应
+func sessionError(c *gin.Context, err error) {
+	if errors.Is(err, session.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"code":    500,
+		"message": "吊销失败: " + err.Error(),
+	})
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/settings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSettings 获取系统设置
+// @Summary 获取系统设置列表
+// @Tags Admin
+// @Produce json
+// @Param group query string false "分组，如 rate_limit"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/settings [get]
+func ListSettings(c *gin.Context) {
+	list, err := settings.List(c.Request.Context(), c.Query("group"))
+	if err != nil {
+		settingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    list,
+	})
+}
+
+// GetSetting 获取单个系统设置
+// @Summary 获取系统设置
+// @Tags Admin
+// @Produce json
+// @Param key path string true "键"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/settings/{key} [get]
+func GetSetting(c *gin.Context) {
+	s, err := settings.Get(c.Request.Context(), c.Param("key"))
+	if err != nil {
+		settingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    s,
+	})
+}
+
+// CreateSetting 添加系统设置（立即生效并广播到所有实例）
+// @Summary 添加系统设置
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "键、值、类型、分组、说明"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/settings [post]
+func CreateSetting(c *gin.Context) {
+	var req struct {
+		Key string `json:"key" binding:"required,max=128"`
+		// 字符串或 JSON 值（如 true、120、"5m"、["/ping"]），按 type 校验
+		Value       interface{} `json:"value"`
+		Type        string      `json:"type"`
+		Group       string      `json:"group" binding:"omitempty,max=64"`
+		Description string      `json:"description" binding:"omitempty,max=255"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	s := model.Setting{
+		Key:         req.Key,
+		Value:       settingValue(req.Value),
+		Type:        req.Type,
+		Group:       req.Group,
+		Description: req.Description,
+		UpdatedBy:   middleware.GetCurrentAdmin(c).Username,
+	}
+	if err := settings.Create(c.Request.Context(), &s); err != nil {
+		settingError(c, err)
+		return
+	}
+	middleware.RecordChange(c, nil, s)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "创建成功",
+		"data":    s,
+	})
+}
+
+// UpdateSetting 修改系统设置的值、类型、分组或说明（立即生效并广播到所有实例）
+// @Summary 修改系统设置
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param key path string true "键"
+// @Param body body map[string]interface{} true "值、类型、分组、说明"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/settings/{key} [put]
+func UpdateSetting(c *gin.Context) {
+	var req struct {
+		Value       interface{} `json:"value"`
+		Type        *string     `json:"type"`
+		Group       *string     `json:"group" binding:"omitempty,max=64"`
+		Description *string     `json:"description" binding:"omitempty,max=255"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	s, err := settings.Get(c.Request.Context(), c.Param("key"))
+	if err != nil {
+		settingError(c, err)
+		return
+	}
+	before := *s
+
+	if req.Value != nil {
+		s.Value = settingValue(req.Value)
+	}
+	if req.Type != nil {
+		s.Type = *req.Type
+	}
+	if req.Group != nil {
+		s.Group = *req.Group
+	}
+	if req.Description != nil {
+		s.Description = *req.Description
+	}
+	s.UpdatedBy = middleware.GetCurrentAdmin(c).Username
+	if err := settings.Update(c.Request.Context(), s); err != nil {
+		settingError(c, err)
+		return
+	}
+	middleware.RecordChange(c, before, s)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "更新成功",
+		"data":    s,
+	})
+}
+
+// DeleteSetting 删除系统设置（恢复为环境变量或默认值）
+// @Summary 删除系统设置
+// @Tags Admin
+// @Produce json
+// @Param key path string true "键"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/settings/{key} [delete]
+func DeleteSetting(c *gin.Context) {
+	s, err := settings.Get(c.Request.Context(), c.Param("key"))
+	if err != nil {
+		settingError(c, err)
+		return
+	}
+	if err := settings.Delete(c.Request.Context(), s.Key); err != nil {
+		settingError(c, err)
+		return
+	}
+	middleware.RecordChange(c, s, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "删除成功",
+	})
+}
+
+// settingValue 请求中的值转为文本：字符串保持原样，其他 JSON 值（数字、布尔、数组、对象）按 JSON 编码
+func settingValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	if v == nil {
+		return ""
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// settingError 写入系统设置错误
+func settingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, settings.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	case errors.Is(err, settings.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": err.Error()})
+	case errors.Is(err, settings.ErrExists):
+		c.JSON(http.StatusConflict, gin.H{"code": 409, "message": err.Error()})
+	case errors.Is(err, settings.ErrUnavailable):
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "操作失败: " + err.Error()})
+	}
+}
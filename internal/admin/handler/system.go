@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"net/http"
+	"os"
+	"runtime"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/leader"
+	"new-openclaw/internal/store"
+	"new-openclaw/pkg/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// startTime 进程启动时间
+var startTime = time.Now()
+
+// systemConfig 启动配置（功能可用性报告）
+var systemConfig = &config.Config{}
+
+// ConfigureSystem 设置启动配置，用于在系统信息中报告运行模式及各功能可用性
+func ConfigureSystem(cfg *config.Config) {
+	systemConfig = cfg
+}
+
+// Feature 功能可用性
+type Feature struct {
+	Available bool   `json:"available"`
+	Backend   string `json:"backend,omitempty"`
+	Note      string `json:"note,omitempty"`
+}
+
+// SystemInfo 系统信息（运行时状态、选主状态、运行模式及功能可用性）
+// @Summary 系统信息
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/system/info [get]
+func SystemInfo(c *gin.Context) {
+	hostname, _ := os.Hostname()
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	mode := "default"
+	if systemConfig.Standalone() {
+		mode = config.AppModeStandalone
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"hostname":       hostname,
+			"pid":            os.Getpid(),
+			"go_version":     runtime.Version(),
+			"num_cpu":        runtime.NumCPU(),
+			"goroutines":     runtime.NumGoroutine(),
+			"heap_alloc":     mem.HeapAlloc,
+			"start_time":     startTime.Format(time.RFC3339),
+			"uptime_seconds": int64(time.Since(startTime).Seconds()),
+			"leader":         leader.Status(),
+			"mode":           mode,
+			"features":       features(),
+		},
+	})
+}
+
+// features 各功能当前可用性（按实际连接状态与生效配置）
+func features() map[string]Feature {
+	standalone := systemConfig.Standalone()
+	feature := func(available bool, backend, unconfigured string) Feature {
+		f := Feature{Available: available, Backend: backend}
+		if !available {
+			f.Note = unconfigured
+			if standalone {
+				f.Note = "单机模式下不可用"
+			}
+		}
+		return f
+	}
+
+	result := map[string]Feature{
+		"database": {Available: database.GetMySQL() != nil, Backend: database.Driver()},
+		"redis":    feature(database.GetRedis() != nil, "", "未连接"),
+		"mongodb":  feature(database.GetMongoDB() != nil, "", "未连接"),
+		"discovery": feature(systemConfig.Discovery.Provider != "",
+			systemConfig.Discovery.Provider, "未配置 DISCOVERY_PROVIDER"),
+		"config_center": feature(systemConfig.ConfigCenter.Provider != "",
+			systemConfig.ConfigCenter.Provider, "未配置 CONFIG_CENTER_PROVIDER，使用本地配置"),
+		"email_notify": feature(systemConfig.Mail.SMTPHost != "",
+			"smtp", "未配置 SMTP，通知只写日志"),
+		"anomaly_alert_webhook": feature(systemConfig.Analytics.AlertWebhook != "",
+			"", "未配置 ANOMALY_ALERT_WEBHOOK"),
+		"audit_log": {Available: systemConfig.Security.AuditEnabled, Backend: systemConfig.Security.AuditOutput},
+	}
+	if !result["database"].Available {
+		result["database"] = Feature{Note: "未连接，管理后台、配额、会话等依赖数据库的接口不可用"}
+	}
+
+	election := leader.Status()
+	result["leader_election"] = Feature{Available: true, Backend: election.Backend}
+	if !election.Enabled {
+		result["leader_election"] = Feature{Available: true, Backend: "single", Note: "未启用选主，视为单实例"}
+	}
+
+	// 状态存储按组件报告后端（memory 时多副本部署不共享状态）
+	for component, backend := range store.Backends() {
+		result["store."+component] = Feature{Available: true, Backend: backend}
+	}
+	return result
+}
@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/tags"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListTags 获取所有标签及各类资源的使用次数
+// @Summary 获取标签列表
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/tags [get]
+func ListTags(c *gin.Context) {
+	list, err := tags.List(c.Request.Context())
+	if err != nil {
+		tagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"list":           list,
+			"resource_types": tags.ResourceTypes,
+		},
+	})
+}
+
+// ListTagResources 获取带有标签的各类资源 ID（跨模块分组）
+// @Summary 按标签获取资源
+// @Tags Admin
+// @Produce json
+// @Param name path string true "标签名"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/tags/{name}/resources [get]
+func ListTagResources(c *gin.Context) {
+	resources, err := tags.Resources(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		tagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    resources,
+	})
+}
+
+// DeleteTag 删除标签（同时移除所有资源上的该标签）
+// @Summary 删除标签
+// @Tags Admin
+// @Produce json
+// @Param name path string true "标签名"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/tags/{name} [delete]
+func DeleteTag(c *gin.Context) {
+	if err := tags.Delete(c.Request.Context(), c.Param("name")); err != nil {
+		tagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "删除成功",
+	})
+}
+
+// GetResourceTags 获取资源的标签
+// @Summary 获取资源标签
+// @Tags Admin
+// @Produce json
+// @Param type path string true "资源类型（admin、app_key、ip_rule）"
+// @Param id path int true "资源 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/resource-tags/{type}/{id} [get]
+func GetResourceTags(c *gin.Context) {
+	resourceType, id, ok := tagResource(c)
+	if !ok {
+		return
+	}
+
+	list, err := tags.Of(c.Request.Context(), resourceType, id)
+	if err != nil {
+		tagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    list,
+	})
+}
+
+// AddResourceTags 给资源添加标签（标签不存在时自动创建）
+// @Summary 添加资源标签
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param type path string true "资源类型（admin、app_key、ip_rule）"
+// @Param id path int true "资源 ID"
+// @Param body body map[string]interface{} true "标签名列表"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/resource-tags/{type}/{id} [post]
+func AddResourceTags(c *gin.Context) {
+	resourceType, id, ok := tagResource(c)
+	if !ok {
+		return
+	}
+	names, ok := bindTagNames(c)
+	if !ok {
+		return
+	}
+
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	list, err := tags.Assign(c.Request.Context(), resourceType, id, names, adminClaims.Username)
+	if err != nil {
+		tagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "添加成功",
+		"data":    list,
+	})
+}
+
+// RemoveResourceTags 移除资源的标签
+// @Summary 移除资源标签
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param type path string true "资源类型（admin、app_key、ip_rule）"
+// @Param id path int true "资源 ID"
+// @Param body body map[string]interface{} true "标签名列表"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/resource-tags/{type}/{id} [delete]
+func RemoveResourceTags(c *gin.Context) {
+	resourceType, id, ok := tagResource(c)
+	if !ok {
+		return
+	}
+	names, ok := bindTagNames(c)
+	if !ok {
+		return
+	}
+
+	list, err := tags.Unassign(c.Request.Context(), resourceType, id, names)
+	if err != nil {
+		tagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "移除成功",
+		"data":    list,
+	})
+}
+
+// tagResource 解析路径中的资源类型与 ID，失败时已写入响应
+func tagResource(c *gin.Context) (string, uint, bool) {
+	resourceType := c.Param("type")
+	if err := tags.ValidResource(resourceType); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return "", 0, false
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的ID",
+		})
+		return "", 0, false
+	}
+	return resourceType, uint(id), true
+}
+
+// bindTagNames 解析请求体中的标签名列表，失败时已写入响应
+func bindTagNames(c *gin.Context) ([]string, bool) {
+	var req struct {
+		Tags []string `json:"tags" binding:"required,min=1,max=20"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return nil, false
+	}
+	return req.Tags, true
+}
+
+// tagError 写入标签错误
+func tagError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, tags.ErrInvalid):
+		status = http.StatusBadRequest
+	case errors.Is(err, tags.ErrNotFound), errors.Is(err, tags.ErrResourceNotFound):
+		status = http.StatusNotFound
+	}
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": err.Error(),
+	})
+}
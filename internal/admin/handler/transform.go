@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"new-openclaw/internal/admin/scope"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/transform"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListTransforms 获取合作方载荷转换模板列表
+// @Summary 获取载荷转换模板列表
+// @Tags Admin
+// @Produce json
+// @Param app_key query string false "AppKey"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/transforms [get]
+func ListTransforms(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	query := db.Model(&model.PartnerTransform{}).Scopes(scope.ByAppKey(scope.Of(c)))
+	if appKey := c.Query("app_key"); appKey != "" {
+		query = query.Where("app_key = ?", appKey)
+	}
+
+	var transforms []model.PartnerTransform
+	var total int64
+
+	query.Count(&total)
+	query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&transforms)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"list":      transforms,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// SetTransform 设置合作方载荷转换模板
+// @Summary 设置载荷转换模板
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param app_key path string true "AppKey"
+// @Param direction path string true "方向（outbound/inbound）"
+// @Param body body map[string]interface{} true "模板"
+// @Success 200 {object} model.PartnerTransform
+// @Router /admin/transforms/{app_key}/{direction} [put]
+func SetTransform(c *gin.Context) {
+	var req struct {
+		Template string `json:"template" binding:"required"`
+		Enabled  *bool  `json:"enabled"`
+		Remark   string `json:"remark"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	direction := c.Param("direction")
+	if !transform.ValidDirection(direction) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的转换方向: " + direction,
+		})
+		return
+	}
+	if _, err := transform.Compile(req.Template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "模板语法错误: " + err.Error(),
+		})
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+	if !appKeyInScope(c) {
+		return
+	}
+
+	appKey := c.Param("app_key")
+	t := model.PartnerTransform{AppKey: appKey, Direction: direction, Enabled: true}
+	db.Where("app_key = ? AND direction = ?", appKey, direction).First(&t)
+
+	t.Template = req.Template
+	t.Remark = req.Remark
+	if req.Enabled != nil {
+		t.Enabled = *req.Enabled
+	}
+
+	if err := db.Save(&t).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "保存失败: " + err.Error(),
+		})
+		return
+	}
+	transform.Invalidate(appKey, direction)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "保存成功",
+		"data":    t,
+	})
+}
+
+// DeleteTransform 删除合作方载荷转换模板（恢复为原样传递）
+// @Summary 删除载荷转换模板
+// @Tags Admin
+// @Produce json
+// @Param app_key path string true "AppKey"
+// @Param direction path string true "方向（outbound/inbound）"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/transforms/{app_key}/{direction} [delete]
+func DeleteTransform(c *gin.Context) {
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+	if !appKeyInScope(c) {
+		return
+	}
+
+	appKey, direction := c.Param("app_key"), c.Param("direction")
+	result := db.Where("app_key = ? AND direction = ?", appKey, direction).Delete(&model.PartnerTransform{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除失败: " + result.Error.Error(),
+		})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "转换模板不存在",
+		})
+		return
+	}
+	transform.Invalidate(appKey, direction)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "删除成功",
+	})
+}
+
+// PreviewTransform 使用示例载荷预览模板转换结果（不保存）
+// @Summary 预览载荷转换
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "模板与示例载荷"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/transforms/preview [post]
+func PreviewTransform(c *gin.Context) {
+	var req struct {
+		Template string          `json:"template" binding:"required"`
+		Payload  json.RawMessage `json:"payload"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	tmpl, err := transform.Compile(req.Template)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "模板语法错误: " + err.Error(),
+		})
+		return
+	}
+
+	output, err := transform.Render(tmpl, req.Payload)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    json.RawMessage(output),
+	})
+}
@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/database"
+	commonmiddleware "new-openclaw/internal/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/upload"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UploadFile 上传文件（multipart 字段 file），返回文件记录与签名下载链接
+// @Summary 上传文件
+// @Tags Admin
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "文件"
+// @Param purpose formData string false "用途，如 avatar"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/upload [post]
+func UploadFile(c *gin.Context) {
+	fh, err := c.FormFile("file")
+	if err != nil {
+		if commonmiddleware.AbortIfBodyTooLarge(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请选择要上传的文件（字段 file）",
+		})
+		return
+	}
+
+	admin := middleware.GetCurrentAdmin(c)
+	f, err := upload.Save(c.Request.Context(), fh, c.PostForm("purpose"), upload.Uploader{ID: admin.AdminID, Username: admin.Username})
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+	middleware.RecordChange(c, nil, f)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "上传成功",
+		"data":    fileWithURL(f),
+	})
+}
+
+// ListFiles 获取上传的文件（超级管理员查看全部，其他管理员只能查看自己上传的）
+// @Summary 获取文件列表
+// @Tags Admin
+// @Produce json
+// @Param purpose query string false "用途"
+// @Param uploader_id query int false "上传者 ID（仅超级管理员）"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/files [get]
+func ListFiles(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	query := db.Model(&model.File{})
+	admin := middleware.GetCurrentAdmin(c)
+	if admin.GlobalRole() != "super_admin" {
+		query = query.Where("uploader_id = ?", admin.AdminID)
+	} else if uploaderID := c.Query("uploader_id"); uploaderID != "" {
+		query = query.Where("uploader_id = ?", uploaderID)
+	}
+	if purpose := c.Query("purpose"); purpose != "" {
+		query = query.Where("purpose = ?", purpose)
+	}
+
+	var total int64
+	files := []model.File{}
+	query.Count(&total)
+	query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&files)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"list":      files,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// GetFile 获取文件记录与新的签名下载链接
+// @Summary 获取文件
+// @Tags Admin
+// @Produce json
+// @Param id path int true "文件 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/files/{id} [get]
+func GetFile(c *gin.Context) {
+	f, ok := ownFile(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    fileWithURL(f),
+	})
+}
+
+// DeleteFile 删除文件（存储中的内容与记录）
+// @Summary 删除文件
+// @Tags Admin
+// @Produce json
+// @Param id path int true "文件 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/files/{id} [delete]
+func DeleteFile(c *gin.Context) {
+	f, ok := ownFile(c)
+	if !ok {
+		return
+	}
+	if err := upload.Delete(c.Request.Context(), f); err != nil {
+		uploadError(c, err)
+		return
+	}
+	middleware.RecordChange(c, f, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "删除成功",
+	})
+}
+
+// DownloadFile 通过签名链接下载文件（无需登录）；对象存储签发直接下载链接并重定向
+// @Summary 下载文件
+// @Tags Admin
+// @Produce octet-stream
+// @Param id path int true "文件 ID"
+// @Param expires query int true "过期时间戳"
+// @Param signature query string true "签名"
+// @Success 200 {file} binary
+// @Router /admin/files/{id}/download [get]
+func DownloadFile(c *gin.Context) {
+	f, err := upload.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+	expires, err := upload.Verify(f, c.Query("expires"), c.Query("signature"), time.Now())
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+
+	r, link, err := upload.Open(c.Request.Context(), f, expires)
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+	if link != "" {
+		c.Redirect(http.StatusFound, link)
+		return
+	}
+	defer r.Close()
+
+	c.Header("Cache-Control", "private, max-age="+strconv.Itoa(int(time.Until(expires)/time.Second)))
+	c.DataFromReader(http.StatusOK, f.Size, f.ContentType, r, map[string]string{
+		"Content-Disposition": upload.Disposition(f),
+	})
+}
+
+// ownFile 读取路径中的文件，非超级管理员只能访问自己上传的文件
+func ownFile(c *gin.Context) (*model.File, bool) {
+	f, err := upload.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		uploadError(c, err)
+		return nil, false
+	}
+	admin := middleware.GetCurrentAdmin(c)
+	if admin.GlobalRole() != "super_admin" && f.UploaderID != admin.AdminID {
+		uploadError(c, upload.ErrNotFound)
+		return nil, false
+	}
+	return f, true
+}
+
+// fileWithURL 文件记录加上签名下载链接
+func fileWithURL(f *model.File) gin.H {
+	link, expires := upload.URL(f, time.Now())
+	return gin.H{
+		"file":       f,
+		"url":        link,
+		"expires_at": expires,
+	}
+}
+
+// uploadError 写入文件上传、下载错误
+func uploadError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, upload.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	case errors.Is(err, upload.ErrSignature), errors.Is(err, upload.ErrExpired):
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": err.Error()})
+	case errors.Is(err, upload.ErrNotFound), errors.Is(err, upload.ErrNotExist):
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": err.Error()})
+	case errors.Is(err, upload.ErrTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"code": 413, "message": err.Error()})
+	case errors.Is(err, upload.ErrType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"code": 415, "message": err.Error()})
+	case errors.Is(err, upload.ErrUnavailable):
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "操作失败: " + err.Error()})
+	}
+}
@@ -0,0 +1,329 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/admin/views"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// viewRequest 保存视图请求
+type viewRequest struct {
+	List    string            `json:"list" binding:"required"`
+	Name    string            `json:"name" binding:"required,max=100"`
+	Filters map[string]string `json:"filters"`
+	Sort    string            `json:"sort"`
+}
+
+// ListViews 获取当前管理员保存的视图及可用的列表定义
+// @Summary 获取保存的视图
+// @Tags Admin
+// @Produce json
+// @Param list query string false "列表（admins、operation_logs）"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/views [get]
+func ListViews(c *gin.Context) {
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	list, err := views.List(c.Request.Context(), adminClaims.AdminID, c.Query("list"))
+	if err != nil {
+		viewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"list":  list,
+			"lists": views.Specs(adminClaims.GlobalRole()),
+		},
+	})
+}
+
+// GetView 获取视图
+// @Summary 获取视图
+// @Tags Admin
+// @Produce json
+// @Param id path int true "视图 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/views/{id} [get]
+func GetView(c *gin.Context) {
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	v, err := views.Get(c.Request.Context(), adminClaims.AdminID, c.Param("id"))
+	if err != nil {
+		viewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    v,
+	})
+}
+
+// CreateView 保存视图（筛选条件与排序）
+// @Summary 保存视图
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "视图（list、name、filters、sort）"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/views [post]
+func CreateView(c *gin.Context) {
+	var req viewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	v := &model.SavedView{
+		AdminID: adminClaims.AdminID,
+		List:    req.List,
+		Name:    req.Name,
+		Filters: req.Filters,
+		Sort:    req.Sort,
+	}
+	if err := views.Validate(v, adminClaims.GlobalRole()); err != nil {
+		viewError(c, err)
+		return
+	}
+	if err := views.Save(c.Request.Context(), v); err != nil {
+		viewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "保存成功",
+		"data":    v,
+	})
+}
+
+// UpdateView 更新视图
+// @Summary 更新视图
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "视图 ID"
+// @Param body body map[string]interface{} true "视图（list、name、filters、sort）"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/views/{id} [put]
+func UpdateView(c *gin.Context) {
+	var req viewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	v, err := views.Get(c.Request.Context(), adminClaims.AdminID, c.Param("id"))
+	if err != nil {
+		viewError(c, err)
+		return
+	}
+	v.List = req.List
+	v.Name = req.Name
+	v.Filters = req.Filters
+	v.Sort = req.Sort
+	if err := views.Validate(v, adminClaims.GlobalRole()); err != nil {
+		viewError(c, err)
+		return
+	}
+	if err := views.Save(c.Request.Context(), v); err != nil {
+		viewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "更新成功",
+		"data":    v,
+	})
+}
+
+// DeleteView 删除视图
+// @Summary 删除视图
+// @Tags Admin
+// @Produce json
+// @Param id path int true "视图 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/views/{id} [delete]
+func DeleteView(c *gin.Context) {
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	if err := views.Delete(c.Request.Context(), adminClaims.AdminID, c.Param("id")); err != nil {
+		viewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "删除成功",
+	})
+}
+
+// ListOperationLogs 获取管理员操作审计日志
+// @Summary 获取操作审计日志
+// @Tags Admin
+// @Produce json
+// @Param view query int false "应用保存的视图"
+// @Param keyword query string false "关键字（用户名、路径模糊匹配）"
+// @Param admin_id query int false "管理员 ID"
+// @Param impersonator_id query int false "模拟登录的发起人 ID"
+// @Param action query string false "操作"
+// @Param resource query string false "资源，如 admins"
+// @Param target_id query string false "操作对象 ID"
+// @Param status_min query int false "响应状态码下限"
+// @Param from query string false "时间起"
+// @Param to query string false "时间止"
+// @Param sort query string false "排序字段，- 前缀表示降序"
+// @Param order query string false "排序方向 asc、desc"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/audit/operations [get]
+func ListOperationLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	query, view, ok := applyView(c, db.Model(&model.OperationLog{}), views.ListOperationLogs)
+	if !ok {
+		return
+	}
+
+	var logs []model.OperationLog
+	var total int64
+
+	query.Count(&total)
+	query.Offset((page - 1) * pageSize).Limit(pageSize).Find(&logs)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"list":      logs,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+			"view":      view,
+		},
+	})
+}
+
+// GetOperationLog 获取单条操作审计日志（含字段级变更）
+// @Summary 获取操作审计日志详情
+// @Tags Admin
+// @Produce json
+// @Param id path int true "日志 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/audit/operations/{id} [get]
+func GetOperationLog(c *gin.Context) {
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	var entry model.OperationLog
+	if err := db.First(&entry, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "日志不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    entry,
+	})
+}
+
+// applyView 按 ?view= 指定的视图及请求参数筛选、排序列表，失败时已写入响应
+func applyView(c *gin.Context, query *gorm.DB, list string) (*gorm.DB, *model.SavedView, bool) {
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	var view *model.SavedView
+	if id := c.Query("view"); id != "" {
+		v, err := views.Get(c.Request.Context(), adminClaims.AdminID, id)
+		if err != nil {
+			viewError(c, err)
+			return nil, nil, false
+		}
+		if v.List != list {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "视图不属于该列表: " + v.List,
+			})
+			return nil, nil, false
+		}
+		view = v
+	}
+
+	query, err := views.Apply(query, list, adminClaims.GlobalRole(), view, c.Request.URL.Query())
+	if err != nil {
+		viewError(c, err)
+		return nil, nil, false
+	}
+	return query, view, true
+}
+
+// viewError 写入视图错误
+func viewError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, views.ErrInvalid):
+		status = http.StatusBadRequest
+	case errors.Is(err, views.ErrNotFound):
+		status = http.StatusNotFound
+	}
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": err.Error(),
+	})
+}
@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/wafrules"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListWAFRules 获取数据库中的 WAF 规则
+// @Summary 获取 WAF 规则列表
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/waf/rules [get]
+func ListWAFRules(c *gin.Context) {
+	rules, err := wafrules.List(c.Request.Context())
+	if err != nil {
+		wafRuleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    rules,
+	})
+}
+
+// ListActiveWAFRules 本实例当前生效的 WAF 规则（内置、规则文件与数据库中启用的规则）
+// @Summary 获取生效中的 WAF 规则
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/waf/active [get]
+func ListActiveWAFRules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    wafrules.Active(),
+	})
+}
+
+// CreateWAFRule 添加 WAF 规则（立即生效并广播到所有实例）
+// @Summary 添加 WAF 规则
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "规则名、正则表达式、检查位置、动作"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/waf/rules [post]
+func CreateWAFRule(c *gin.Context) {
+	var req struct {
+		Name    string `json:"name" binding:"required,max=64"`
+		Pattern string `json:"pattern" binding:"required"`
+		// 检查位置：path、query、body、header、header:Name
+		Targets []string `json:"targets" binding:"required"`
+		// 动作：log、block、ban
+		Action  string `json:"action" binding:"required"`
+		Reason  string `json:"reason" binding:"omitempty,max=128"`
+		Enabled *bool  `json:"enabled"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	claims, _ := c.Get("admin_claims")
+	adminClaims := claims.(*middleware.Claims)
+
+	rule := model.WAFRule{
+		Name:      req.Name,
+		Pattern:   req.Pattern,
+		Targets:   strings.Join(req.Targets, ","),
+		Action:    req.Action,
+		Reason:    req.Reason,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+		CreatedBy: adminClaims.Username,
+	}
+	if err := wafrules.Create(c.Request.Context(), &rule); err != nil {
+		wafRuleError(c, err)
+		return
+	}
+	middleware.RecordChange(c, nil, rule)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "创建成功",
+		"data":    rule,
+	})
+}
+
+// UpdateWAFRule 更新 WAF 规则（立即生效并广播到所有实例）
+// @Summary 更新 WAF 规则
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "规则 ID"
+// @Param body body map[string]interface{} true "规则信息"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/waf/rules/{id} [put]
+func UpdateWAFRule(c *gin.Context) {
+	var req struct {
+		Name    *string  `json:"name" binding:"omitempty,max=64"`
+		Pattern *string  `json:"pattern"`
+		Targets []string `json:"targets"`
+		Action  *string  `json:"action"`
+		Reason  *string  `json:"reason" binding:"omitempty,max=128"`
+		Enabled *bool    `json:"enabled"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的ID",
+		})
+		return
+	}
+	rule, err := wafrules.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		wafRuleError(c, err)
+		return
+	}
+	before := *rule
+
+	if req.Name != nil {
+		rule.Name = *req.Name
+	}
+	if req.Pattern != nil {
+		rule.Pattern = *req.Pattern
+	}
+	if req.Targets != nil {
+		rule.Targets = strings.Join(req.Targets, ",")
+	}
+	if req.Action != nil {
+		rule.Action = *req.Action
+	}
+	if req.Reason != nil {
+		rule.Reason = *req.Reason
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if err := wafrules.Update(c.Request.Context(), rule); err != nil {
+		wafRuleError(c, err)
+		return
+	}
+	middleware.RecordChange(c, before, rule)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "更新成功",
+		"data":    rule,
+	})
+}
+
+// DeleteWAFRule 删除 WAF 规则
+// @Summary 删除 WAF 规则
+// @Tags Admin
+// @Produce json
+// @Param id path int true "规则 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/waf/rules/{id} [delete]
+func DeleteWAFRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的ID",
+		})
+		return
+	}
+	rule, err := wafrules.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		wafRuleError(c, err)
+		return
+	}
+	if err := wafrules.Delete(c.Request.Context(), uint(id)); err != nil {
+		wafRuleError(c, err)
+		return
+	}
+	middleware.RecordChange(c, rule, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "删除成功",
+	})
+}
+
+// ReloadWAFRules 重新读取规则文件与数据库中的规则（如修改 WAF_RULES_FILE 后），并通知其他实例
+// @Summary 重新加载 WAF 规则
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/waf/reload [post]
+func ReloadWAFRules(c *gin.Context) {
+	if err := wafrules.Reload(c.Request.Context()); err != nil {
+		wafRuleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "已重新加载",
+		"data":    wafrules.Active(),
+	})
+}
+
+// wafRuleError 写入 WAF 规则错误
+func wafRuleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, wafrules.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	case errors.Is(err, wafrules.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": err.Error()})
+	case errors.Is(err, wafrules.ErrUnavailable):
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "操作失败: " + err.Error()})
+	}
+}
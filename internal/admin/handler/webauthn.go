@@ -0,0 +1,704 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/loginguard"
+	commonmiddleware "new-openclaw/internal/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/store"
+	"new-openclaw/pkg/config"
+	"new-openclaw/pkg/webauthn"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxWebAuthnCredentials 每个管理员最多注册的安全密钥数量
+const maxWebAuthnCredentials = 10
+
+var (
+	// webAuthnRP 依赖方（为空表示未启用安全密钥登录）
+	webAuthnRP      *webauthn.RelyingParty
+	webAuthnTimeout = 2 * time.Minute
+)
+
+// errCeremonyExpired 注册或登录挑战不存在、已过期或已使用
+var errCeremonyExpired = errors.New("请求已过期，请重试")
+
+// ConfigureWebAuthn 设置安全密钥登录的依赖方及必须使用安全密钥的角色。
+// 依赖方 ID 与允许的来源未配置时取 baseURL（APP_BASE_URL），两者都为空时不启用
+func ConfigureWebAuthn(cfg config.WebAuthnConfig, baseURL string) error {
+	webAuthnRP = nil
+	middleware.RequireWebAuthnFor()
+
+	rpID, origins := cfg.RPID, cfg.Origins
+	if baseURL != "" {
+		u, err := url.Parse(baseURL)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("APP_BASE_URL 无效，安全密钥登录未启用: %s", baseURL)
+		}
+		if rpID == "" {
+			rpID = u.Hostname()
+		}
+		if len(origins) == 0 {
+			origins = []string{u.Scheme + "://" + u.Host}
+		}
+	}
+	if rpID == "" {
+		if len(cfg.RequiredRoles) > 0 {
+			return fmt.Errorf("未配置 WEBAUTHN_RP_ID 或 APP_BASE_URL，安全密钥登录未启用，WEBAUTHN_REQUIRED_ROLES 不生效")
+		}
+		return nil
+	}
+	if len(origins) == 0 {
+		origins = []string{"https://" + rpID}
+	}
+	for i, origin := range origins {
+		origins[i] = strings.TrimRight(origin, "/")
+	}
+
+	webAuthnRP = &webauthn.RelyingParty{
+		ID:                      rpID,
+		Name:                    cfg.RPName,
+		Origins:                 origins,
+		RequireUserVerification: cfg.RequireUserVerification,
+	}
+	if cfg.Timeout > 0 {
+		webAuthnTimeout = cfg.Timeout
+	}
+	middleware.RequireWebAuthnFor(cfg.RequiredRoles...)
+	return nil
+}
+
+// webAuthnCeremony 注册、登录挑战（保存在 webauthn 存储中，只能使用一次）
+type webAuthnCeremony struct {
+	Challenge []byte `json:"challenge"`
+	AdminID   uint   `json:"admin_id,omitempty"`
+	// 登录时输入的用户名（为空表示使用可发现凭证）
+	Username string `json:"username,omitempty"`
+}
+
+// BeginWebAuthnLogin 获取安全密钥登录选项
+// @Summary 获取安全密钥登录选项
+// @Description 填写用户名时只允许该管理员的安全密钥；不填时由浏览器列出本站点的通行密钥
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body object false "{\"username\": \"\"}"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/webauthn/login/options [post]
+func BeginWebAuthnLogin(c *gin.Context) {
+	if !webAuthnAvailable(c) {
+		return
+	}
+	var req struct {
+		Username string `json:"username" binding:"omitempty,max=50"`
+	}
+	// 请求体可以为空
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	// 用户名不存在或未注册安全密钥时同样返回选项（allowCredentials 为空），不暴露账号是否存在
+	var allow []webauthn.CredentialDescriptor
+	if req.Username != "" {
+		var admin model.Admin
+		if err := db.Where("username = ?", req.Username).First(&admin).Error; err == nil {
+			allow = credentialDescriptors(db, admin.ID)
+		}
+	}
+
+	challenge, id, err := startCeremony(c.Request.Context(), "login", webAuthnCeremony{Username: req.Username})
+	if err != nil {
+		log.Printf("保存安全密钥登录挑战失败: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": "安全密钥登录暂不可用",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"session_id": id,
+			"public_key": webAuthnRP.RequestOptions(challenge, allow, webAuthnTimeout),
+		},
+	})
+}
+
+// FinishWebAuthnLogin 提交安全密钥签名完成登录
+// @Summary 安全密钥登录
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body object true "{\"session_id\": \"\", \"credential\": {}}"
+// @Success 200 {object} model.AdminLoginResponse
+// @Router /admin/webauthn/login [post]
+func FinishWebAuthnLogin(c *gin.Context) {
+	if !webAuthnAvailable(c) {
+		return
+	}
+	var req struct {
+		SessionID  string                       `json:"session_id" binding:"required"`
+		Credential webauthn.AssertionCredential `json:"credential"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	ctx, ip := c.Request.Context(), commonmiddleware.AbuseIP(c)
+	ceremony, err := finishCeremony(ctx, "login", req.SessionID)
+	if err != nil {
+		webAuthnCeremonyError(c, err)
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	// 按凭证 ID 找到管理员；登录时填写了用户名的，凭证必须属于该管理员
+	var credential model.WebAuthnCredential
+	var admin model.Admin
+	credentialID, err := req.Credential.CredentialID()
+	if err == nil {
+		err = db.Where("credential_hash = ?", credentialHash(credentialID)).First(&credential).Error
+	}
+	if err == nil {
+		err = db.First(&admin, credential.AdminID).Error
+	}
+	if err != nil || (ceremony.Username != "" && ceremony.Username != admin.Username) {
+		commonmiddleware.ReportAuthFailure(c, commonmiddleware.AuthFailureLogin, ceremony.Username, "安全密钥未注册")
+		recordLogin(c, ceremony.Username, nil, middleware.AuthMethodWebAuthn, model.LoginResultFailed, "安全密钥未注册")
+		webAuthnLoginFailed(c)
+		return
+	}
+	// 可发现凭证返回的账号标识必须与凭证所属的管理员一致
+	if handle := req.Credential.UserHandle(); len(handle) > 0 && string(handle) != webAuthnUserID(admin.ID) {
+		commonmiddleware.ReportAuthFailure(c, commonmiddleware.AuthFailureLogin, admin.Username, "安全密钥账号标识不匹配")
+		recordLogin(c, admin.Username, &admin, middleware.AuthMethodWebAuthn, model.LoginResultFailed, "安全密钥账号标识不匹配")
+		webAuthnLoginFailed(c)
+		return
+	}
+
+	if d := loginguard.Check(ctx, loginguard.ScopeAdmin, admin.Username, ip); !d.Allowed() {
+		recordLogin(c, admin.Username, &admin, middleware.AuthMethodWebAuthn, model.LoginResultFailed, d.Reason())
+		loginguard.Reject(c, d)
+		return
+	}
+	if admin.Status != 1 {
+		recordLogin(c, admin.Username, &admin, middleware.AuthMethodWebAuthn, model.LoginResultFailed, "账号已禁用")
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "账号已被禁用",
+		})
+		return
+	}
+	if admin.LockedUntil != nil && time.Now().Before(*admin.LockedUntil) {
+		recordLogin(c, admin.Username, &admin, middleware.AuthMethodWebAuthn, model.LoginResultFailed, "账号已锁定")
+		loginguard.Reject(c, loginguard.Decision{Locked: true, RetryAfter: time.Until(*admin.LockedUntil)})
+		return
+	}
+
+	signCount, err := webAuthnRP.VerifyAssertion(ceremony.Challenge, &req.Credential, credential.PublicKey, credential.SignCount)
+	if err != nil {
+		commonmiddleware.ReportAuthFailure(c, commonmiddleware.AuthFailureLogin, admin.Username, err.Error())
+		recordLogin(c, admin.Username, &admin, middleware.AuthMethodWebAuthn, model.LoginResultFailed, err.Error())
+		webAuthnLoginFailed(c)
+		return
+	}
+
+	now := time.Now()
+	db.Model(&credential).UpdateColumns(map[string]interface{}{
+		"sign_count":   signCount,
+		"last_used_at": now,
+		"last_used_ip": c.ClientIP(),
+	})
+	loginguard.Succeed(ctx, loginguard.ScopeAdmin, admin.Username)
+	if admin.FailedAttempts > 0 || admin.LockedUntil != nil {
+		db.Model(&admin).UpdateColumns(map[string]interface{}{"failed_attempts": 0, "locked_until": nil})
+	}
+
+	completeLogin(c, db, &admin, middleware.AuthMethodWebAuthn)
+}
+
+// ListMyWebAuthnCredentials 当前管理员注册的安全密钥
+// @Summary 我的安全密钥
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{} "credentials 为安全密钥列表，enabled、required 为是否已启用、是否强制使用"
+// @Router /admin/profile/webauthn [get]
+func ListMyWebAuthnCredentials(c *gin.Context) {
+	adminClaims := middleware.GetCurrentAdmin(c)
+	listWebAuthnCredentials(c, adminClaims.AdminID)
+}
+
+// BeginWebAuthnRegistration 获取注册安全密钥的选项
+// @Summary 获取注册安全密钥的选项
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/profile/webauthn/options [post]
+func BeginWebAuthnRegistration(c *gin.Context) {
+	if !webAuthnAvailable(c) {
+		return
+	}
+	adminClaims := middleware.GetCurrentAdmin(c)
+	if adminClaims.BreakGlass {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "紧急访问会话不能注册安全密钥",
+		})
+		return
+	}
+	if adminClaims.Impersonator != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "模拟登录不能修改安全密钥",
+		})
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	var admin model.Admin
+	if err := db.First(&admin, adminClaims.AdminID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "管理员不存在",
+		})
+		return
+	}
+	exclude := credentialDescriptors(db, admin.ID)
+	if len(exclude) >= maxWebAuthnCredentials {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": fmt.Sprintf("最多注册 %d 个安全密钥，请先删除不再使用的安全密钥", maxWebAuthnCredentials),
+		})
+		return
+	}
+
+	challenge, id, err := startCeremony(c.Request.Context(), "register", webAuthnCeremony{AdminID: admin.ID})
+	if err != nil {
+		log.Printf("保存安全密钥注册挑战失败: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": "安全密钥注册暂不可用",
+		})
+		return
+	}
+
+	displayName := admin.Nickname
+	if displayName == "" {
+		displayName = admin.Username
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"session_id": id,
+			"public_key": webAuthnRP.CreationOptions(challenge, []byte(webAuthnUserID(admin.ID)), admin.Username, displayName, exclude, webAuthnTimeout),
+		},
+	})
+}
+
+// FinishWebAuthnRegistration 提交认证器的注册响应，保存安全密钥
+// @Summary 注册安全密钥
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body object true "{\"session_id\": \"\", \"name\": \"\", \"credential\": {}}"
+// @Success 200 {object} model.WebAuthnCredential
+// @Router /admin/profile/webauthn [post]
+func FinishWebAuthnRegistration(c *gin.Context) {
+	if !webAuthnAvailable(c) {
+		return
+	}
+	var req struct {
+		SessionID  string                          `json:"session_id" binding:"required"`
+		Name       string                          `json:"name" binding:"max=100"`
+		Credential webauthn.RegistrationCredential `json:"credential"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	adminClaims := middleware.GetCurrentAdmin(c)
+	ceremony, err := finishCeremony(c.Request.Context(), "register", req.SessionID)
+	if err == nil && ceremony.AdminID != adminClaims.AdminID {
+		err = errCeremonyExpired
+	}
+	if err != nil {
+		webAuthnCeremonyError(c, err)
+		return
+	}
+
+	registered, err := webAuthnRP.VerifyRegistration(ceremony.Challenge, &req.Credential)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "安全密钥注册失败: " + err.Error(),
+		})
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	hash := credentialHash(registered.ID)
+	var count int64
+	db.Model(&model.WebAuthnCredential{}).Where("credential_hash = ?", hash).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": "该安全密钥已注册",
+		})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || !utf8.ValidString(name) {
+		name = "安全密钥"
+	}
+	credential := model.WebAuthnCredential{
+		AdminID:        adminClaims.AdminID,
+		Name:           name,
+		CredentialID:   webauthn.Encode(registered.ID),
+		CredentialHash: hash,
+		PublicKey:      registered.PublicKey,
+		Algorithm:      registered.Algorithm,
+		SignCount:      registered.SignCount,
+		AAGUID:         formatAAGUID(registered.AAGUID),
+		Transports:     strings.Join(registered.Transports, ","),
+		BackupEligible: registered.BackupEligible,
+	}
+	if err := db.Create(&credential).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "保存安全密钥失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "安全密钥已注册",
+		"data":    credential,
+	})
+}
+
+// DeleteMyWebAuthnCredential 删除当前管理员的安全密钥
+// @Summary 删除我的安全密钥
+// @Tags Admin
+// @Produce json
+// @Param id path int true "安全密钥ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/profile/webauthn/{id} [delete]
+func DeleteMyWebAuthnCredential(c *gin.Context) {
+	if middleware.GetCurrentAdmin(c).Impersonator != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "模拟登录不能修改安全密钥",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的ID",
+		})
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	adminClaims := middleware.GetCurrentAdmin(c)
+	result := db.Where("id = ? AND admin_id = ?", id, adminClaims.AdminID).Delete(&model.WebAuthnCredential{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除失败: " + result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "安全密钥不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "删除成功",
+	})
+}
+
+// ListAdminWebAuthnCredentials 指定管理员注册的安全密钥
+// @Summary 获取管理员的安全密钥
+// @Tags Admin
+// @Produce json
+// @Param id path int true "管理员ID"
+// @Success 200 {object} map[string]interface{} "credentials 为安全密钥列表，enabled、required 为是否已启用、是否强制使用"
+// @Router /admin/admins/{id}/webauthn [get]
+func ListAdminWebAuthnCredentials(c *gin.Context) {
+	id, ok := sessionAdminID(c)
+	if !ok {
+		return
+	}
+	adminID, _ := strconv.ParseUint(id, 10, 64)
+	listWebAuthnCredentials(c, uint(adminID))
+}
+
+// ResetAdminWebAuthnCredentials 删除指定管理员的全部安全密钥（安全密钥丢失时由其他超级管理员重置，之后该管理员可以用密码登录并重新注册）
+// @Summary 重置管理员的安全密钥
+// @Tags Admin
+// @Produce json
+// @Param id path int true "管理员ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/admins/{id}/webauthn [delete]
+func ResetAdminWebAuthnCredentials(c *gin.Context) {
+	id, ok := sessionAdminID(c)
+	if !ok {
+		return
+	}
+
+	result := database.GetMySQL().Where("admin_id = ?", id).Delete(&model.WebAuthnCredential{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "重置失败: " + result.Error.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": fmt.Sprintf("已删除 %d 个安全密钥", result.RowsAffected),
+	})
+}
+
+// listWebAuthnCredentials 返回管理员的安全密钥及是否必须使用安全密钥登录
+func listWebAuthnCredentials(c *gin.Context, adminID uint) {
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	var admin model.Admin
+	db.Select("id", "role").First(&admin, adminID)
+	var credentials []model.WebAuthnCredential
+	db.Where("admin_id = ?", adminID).Order("id ASC").Find(&credentials)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"enabled":     webAuthnRP != nil,
+			"required":    middleware.WebAuthnRequired(admin.Role),
+			"credentials": credentials,
+		},
+	})
+}
+
+// webAuthnAvailable 未启用安全密钥登录时写入响应
+func webAuthnAvailable(c *gin.Context) bool {
+	if webAuthnRP == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "未启用安全密钥登录",
+		})
+		return false
+	}
+	return true
+}
+
+// webAuthnLoginFailed 安全密钥未注册或签名校验失败（不区分原因）
+func webAuthnLoginFailed(c *gin.Context) {
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"code":    401,
+		"message": "安全密钥验证失败",
+	})
+}
+
+// webAuthnCeremonyError 挑战已过期或存储不可用
+func webAuthnCeremonyError(c *gin.Context, err error) {
+	if errors.Is(err, errCeremonyExpired) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+	log.Printf("读取安全密钥挑战失败: %v", err)
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"code":    503,
+		"message": "安全密钥服务暂不可用",
+	})
+}
+
+// hasWebAuthnCredential 管理员是否注册了安全密钥
+func hasWebAuthnCredential(db *gorm.DB, adminID uint) bool {
+	var count int64
+	db.Model(&model.WebAuthnCredential{}).Where("admin_id = ?", adminID).Count(&count)
+	return count > 0
+}
+
+// credentialDescriptors 管理员已注册的安全密钥（注册时排除、登录时允许）
+func credentialDescriptors(db *gorm.DB, adminID uint) []webauthn.CredentialDescriptor {
+	var credentials []model.WebAuthnCredential
+	db.Select("credential_id", "transports").Where("admin_id = ?", adminID).Find(&credentials)
+
+	descriptors := make([]webauthn.CredentialDescriptor, 0, len(credentials))
+	for _, cred := range credentials {
+		id, err := webauthn.Decode(cred.CredentialID)
+		if err != nil {
+			continue
+		}
+		var transports []string
+		if cred.Transports != "" {
+			transports = strings.Split(cred.Transports, ",")
+		}
+		descriptors = append(descriptors, webauthn.NewDescriptor(id, transports))
+	}
+	return descriptors
+}
+
+// startCeremony 生成挑战并保存，返回挑战及仪式 ID
+func startCeremony(ctx context.Context, kind string, ceremony webAuthnCeremony) ([]byte, string, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, "", err
+	}
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", err
+	}
+	id := webauthn.Encode(raw)
+
+	ceremony.Challenge = challenge
+	data, _ := json.Marshal(ceremony)
+	if err := store.For(store.ComponentWebAuthn).Set(ctx, kind+":"+id, string(data), webAuthnTimeout); err != nil {
+		return nil, "", err
+	}
+	return challenge, id, nil
+}
+
+// finishCeremony 取出挑战；同一挑战只能提交一次（并发提交时只有一个请求能继续）
+func finishCeremony(ctx context.Context, kind, id string) (*webAuthnCeremony, error) {
+	s := store.For(store.ComponentWebAuthn)
+	key := kind + ":" + id
+
+	data, err := s.Get(ctx, key)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, errCeremonyExpired
+	}
+	if err != nil {
+		return nil, err
+	}
+	first, err := s.SetNX(ctx, "used:"+key, "1", webAuthnTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if !first {
+		return nil, errCeremonyExpired
+	}
+	s.Del(ctx, key)
+
+	var ceremony webAuthnCeremony
+	if err := json.Unmarshal([]byte(data), &ceremony); err != nil {
+		return nil, errCeremonyExpired
+	}
+	return &ceremony, nil
+}
+
+// webAuthnUserID 注册时提供给认证器的账号标识（管理员 ID，不包含用户名等个人信息）
+func webAuthnUserID(adminID uint) string {
+	return strconv.FormatUint(uint64(adminID), 10)
+}
+
+func credentialHash(id []byte) string {
+	sum := sha256.Sum256(id)
+	return hex.EncodeToString(sum[:])
+}
+
+// formatAAGUID 按 UUID 格式显示认证器型号标识
+func formatAAGUID(b []byte) string {
+	if len(b) != 16 {
+		return ""
+	}
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
@@ -0,0 +1,568 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/appkey"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/webhook"
+	"new-openclaw/pkg/secrets"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListWebhookEndpoints 获取 Webhook 投递目标列表（不返回签名密钥）及可订阅的事件类型
+// @Summary 获取 Webhook 投递目标列表
+// @Tags Admin
+// @Produce json
+// @Param event_type query string false "事件类型"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/webhooks/endpoints [get]
+func ListWebhookEndpoints(c *gin.Context) {
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	query := db.Model(&model.WebhookEndpoint{})
+	if eventType := c.Query("event_type"); eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+
+	var endpoints []model.WebhookEndpoint
+	query.Order("id DESC").Find(&endpoints)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"list":        endpoints,
+			"event_types": webhook.EventTypes,
+		},
+	})
+}
+
+// ListWebhookEvents 获取事件目录：事件类型、各版本的载荷结构（JSON Schema）及弃用、停止投递时间
+// @Summary 获取 Webhook 事件目录
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/webhooks/events [get]
+func ListWebhookEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"envelope": webhook.Envelope,
+			"events":   webhook.Catalog(),
+		},
+	})
+}
+
+// CreateWebhookEndpoint 创建 Webhook 投递目标，签名密钥只在创建时返回一次
+// @Summary 创建 Webhook 投递目标
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "事件类型、地址、合作方 AppKey、载荷版本"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/webhooks/endpoints [post]
+func CreateWebhookEndpoint(c *gin.Context) {
+	var req struct {
+		// 事件类型，* 表示全部
+		EventType string `json:"event_type" binding:"required,max=64"`
+		URL       string `json:"url" binding:"required,max=1024"`
+		// 合作方 AppKey（投递前按其 outbound 转换模板转换载荷）
+		AppKey string `json:"app_key" binding:"omitempty,max=64"`
+		// 固定接收的载荷版本（默认为事件类型的最新版本）
+		SchemaVersion int    `json:"schema_version"`
+		Remark        string `json:"remark" binding:"omitempty,max=255"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if req.SchemaVersion == 0 {
+		req.SchemaVersion = webhook.DefaultSchemaVersion(req.EventType)
+	}
+	if !validWebhookEndpoint(c, req.EventType, req.URL, req.SchemaVersion) {
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	secret, err := appkey.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "生成密钥失败",
+		})
+		return
+	}
+
+	endpoint := model.WebhookEndpoint{
+		EventType:     req.EventType,
+		URL:           req.URL,
+		Secret:        secrets.EncryptedString(secret),
+		AppKey:        req.AppKey,
+		SchemaVersion: req.SchemaVersion,
+		Enabled:       true,
+		Remark:        req.Remark,
+	}
+	if err := db.Create(&endpoint).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "创建失败: " + err.Error(),
+		})
+		return
+	}
+	middleware.RecordChange(c, nil, endpoint)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "创建成功，请妥善保存签名密钥（之后无法再次查看）",
+		"data": gin.H{
+			"endpoint": endpoint,
+			"secret":   secret,
+		},
+	})
+}
+
+// UpdateWebhookEndpoint 更新 Webhook 投递目标（事件类型、地址、合作方、载荷版本、启用状态）
+// @Summary 更新 Webhook 投递目标
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "投递目标 ID"
+// @Param body body map[string]interface{} true "投递目标信息"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/webhooks/endpoints/{id} [put]
+func UpdateWebhookEndpoint(c *gin.Context) {
+	var req struct {
+		EventType     *string `json:"event_type" binding:"omitempty,max=64"`
+		URL           *string `json:"url" binding:"omitempty,max=1024"`
+		AppKey        *string `json:"app_key" binding:"omitempty,max=64"`
+		SchemaVersion *int    `json:"schema_version"`
+		Enabled       *bool   `json:"enabled"`
+		Remark        *string `json:"remark" binding:"omitempty,max=255"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	endpoint, ok := findWebhookEndpoint(c)
+	if !ok {
+		return
+	}
+	before := *endpoint
+
+	if req.EventType != nil {
+		endpoint.EventType = *req.EventType
+	}
+	if req.URL != nil {
+		endpoint.URL = *req.URL
+	}
+	if req.SchemaVersion != nil {
+		endpoint.SchemaVersion = *req.SchemaVersion
+	}
+	if !validWebhookEndpoint(c, endpoint.EventType, endpoint.URL, endpoint.SchemaVersion) {
+		return
+	}
+	if req.AppKey != nil {
+		endpoint.AppKey = *req.AppKey
+	}
+	if req.Enabled != nil {
+		endpoint.Enabled = *req.Enabled
+	}
+	if req.Remark != nil {
+		endpoint.Remark = *req.Remark
+	}
+
+	middleware.RecordChange(c, before, endpoint)
+	saveWebhookEndpoint(c, endpoint, "更新成功", nil)
+}
+
+// RotateWebhookSecret 轮换 Webhook 签名密钥（立即生效，包括待重试的投递），新密钥只返回一次
+// @Summary 轮换 Webhook 签名密钥
+// @Tags Admin
+// @Produce json
+// @Param id path int true "投递目标 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/webhooks/endpoints/{id}/rotate [post]
+func RotateWebhookSecret(c *gin.Context) {
+	endpoint, ok := findWebhookEndpoint(c)
+	if !ok {
+		return
+	}
+
+	secret, err := appkey.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "生成密钥失败",
+		})
+		return
+	}
+	endpoint.Secret = secrets.EncryptedString(secret)
+	middleware.RecordSecretChange(c, "secret")
+
+	saveWebhookEndpoint(c, endpoint, "密钥已轮换，请妥善保存新密钥（之后无法再次查看）", gin.H{"secret": secret})
+}
+
+// DeleteWebhookEndpoint 删除 Webhook 投递目标（待投递的记录将标记为失败）
+// @Summary 删除 Webhook 投递目标
+// @Tags Admin
+// @Produce json
+// @Param id path int true "投递目标 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/webhooks/endpoints/{id} [delete]
+func DeleteWebhookEndpoint(c *gin.Context) {
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	// 删除前取出记录，写入操作日志的变更
+	var endpoint model.WebhookEndpoint
+	db.First(&endpoint, c.Param("id"))
+
+	result := db.Delete(&model.WebhookEndpoint{}, c.Param("id"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除失败: " + result.Error.Error(),
+		})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "投递目标不存在",
+		})
+		return
+	}
+	middleware.RecordChange(c, endpoint, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "删除成功",
+	})
+}
+
+// PingWebhookEndpoint 向投递目标发送测试事件（webhook.ping），结果见投递记录
+// @Summary 测试 Webhook 投递目标
+// @Tags Admin
+// @Produce json
+// @Param id path int true "投递目标 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/webhooks/endpoints/{id}/ping [post]
+func PingWebhookEndpoint(c *gin.Context) {
+	endpoint, ok := findWebhookEndpoint(c)
+	if !ok {
+		return
+	}
+
+	eventID, err := webhook.Ping(*endpoint)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "发送测试事件失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "测试事件已加入投递队列",
+		"data": gin.H{
+			"event_id": eventID,
+		},
+	})
+}
+
+// ListWebhookDeliveries 获取 Webhook 投递记录（最新在前）
+// @Summary 获取 Webhook 投递记录
+// @Tags Admin
+// @Produce json
+// @Param status query string false "状态：pending, succeeded, failed"
+// @Param endpoint_id query int false "投递目标 ID"
+// @Param event_type query string false "事件类型"
+// @Param event_id query string false "事件 ID"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/webhooks/deliveries [get]
+func ListWebhookDeliveries(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	query := db.Model(&model.WebhookDelivery{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if endpointID := c.Query("endpoint_id"); endpointID != "" {
+		query = query.Where("endpoint_id = ?", endpointID)
+	}
+	if eventType := c.Query("event_type"); eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+	if eventID := c.Query("event_id"); eventID != "" {
+		query = query.Where("event_id = ?", eventID)
+	}
+
+	var deliveries []model.WebhookDelivery
+	var total int64
+
+	query.Count(&total)
+	query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&deliveries)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"list":      deliveries,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// GetWebhookDelivery 获取 Webhook 投递详情及每次尝试的记录
+// @Summary 获取 Webhook 投递详情
+// @Tags Admin
+// @Produce json
+// @Param id path int true "投递 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/webhooks/deliveries/{id} [get]
+func GetWebhookDelivery(c *gin.Context) {
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	var delivery model.WebhookDelivery
+	if err := db.First(&delivery, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "投递记录不存在",
+		})
+		return
+	}
+
+	var attempts []model.WebhookAttempt
+	db.Where("delivery_id = ?", delivery.ID).Order("id ASC").Find(&attempts)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"delivery": delivery,
+			"attempts": attempts,
+		},
+	})
+}
+
+// ReplayWebhookDelivery 重放失败的 Webhook 投递（事件 ID 不变，按当前密钥重新签名）
+// @Summary 重放 Webhook 投递
+// @Tags Admin
+// @Produce json
+// @Param id path int true "投递 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/webhooks/deliveries/{id}/replay [post]
+func ReplayWebhookDelivery(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的投递 ID",
+		})
+		return
+	}
+
+	if err := webhook.Replay(c.Request.Context(), uint(id)); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, webhook.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, webhook.ErrNotFailed):
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"code":    status,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "已重新加入投递队列",
+	})
+}
+
+// ReplayFailedWebhookDeliveries 批量重放失败的 Webhook 投递（可按投递目标、起始时间筛选），由重试任务投递
+// @Summary 批量重放失败的 Webhook 投递
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} false "投递目标 ID、起始时间（默认 24 小时内）"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/webhooks/deliveries/replay [post]
+func ReplayFailedWebhookDeliveries(c *gin.Context) {
+	var req struct {
+		EndpointID uint       `json:"endpoint_id"`
+		Since      *time.Time `json:"since"`
+	}
+	// 请求体可选
+	_ = c.ShouldBindJSON(&req)
+
+	since := time.Now().Add(-24 * time.Hour)
+	if req.Since != nil {
+		since = *req.Since
+	}
+
+	count, err := webhook.ReplayFailed(c.Request.Context(), req.EndpointID, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "重放失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"replayed": count,
+		},
+	})
+}
+
+// validWebhookEndpoint 校验事件类型、投递地址与载荷版本，失败时已写入响应
+func validWebhookEndpoint(c *gin.Context, eventType, url string, schemaVersion int) bool {
+	if !webhook.ValidEventType(eventType) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的事件类型: " + eventType,
+		})
+		return false
+	}
+	if !webhook.ValidURL(url) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的投递地址（仅支持 http/https）",
+		})
+		return false
+	}
+	if !webhook.ValidSchemaVersion(eventType, schemaVersion) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的载荷版本：须为该事件类型仍在投递的版本，见 /admin/webhooks/events",
+		})
+		return false
+	}
+	return true
+}
+
+// findWebhookEndpoint 按路径参数查找投递目标，失败时已写入响应
+func findWebhookEndpoint(c *gin.Context) (*model.WebhookEndpoint, bool) {
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return nil, false
+	}
+
+	var endpoint model.WebhookEndpoint
+	err := db.First(&endpoint, c.Param("id")).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "投递目标不存在",
+		})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查询失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	return &endpoint, true
+}
+
+// saveWebhookEndpoint 保存投递目标
+func saveWebhookEndpoint(c *gin.Context, endpoint *model.WebhookEndpoint, message string, extra gin.H) {
+	if err := database.GetMySQL().Save(endpoint).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "保存失败: " + err.Error(),
+		})
+		return
+	}
+
+	data := gin.H{"endpoint": endpoint}
+	for k, v := range extra {
+		data[k] = v
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": message,
+		"data":    data,
+	})
+}
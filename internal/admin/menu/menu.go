@@ -0,0 +1,272 @@
+// Package menu 管理后台菜单：menus 表中的菜单项组成树，首页按当前管理员的角色与权限过滤后返回。
+// 菜单表为空时写入默认菜单；数据库未连接时使用默认菜单
+package menu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	adminmiddleware "new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/admin/rbac"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUnavailable 数据库未连接
+	ErrUnavailable = errors.New("数据库未连接")
+	// ErrNotFound 菜单不存在
+	ErrNotFound = errors.New("菜单不存在")
+	// ErrInvalid 名称、路径、上级菜单、角色或权限不合法
+	ErrInvalid = errors.New("参数错误")
+	// ErrInUse 还有下级菜单
+	ErrInUse = errors.New("请先删除下级菜单")
+)
+
+// defaults 默认菜单（菜单表为空时写入，数据库未连接时直接使用）
+var defaults = []model.Menu{
+	{Name: "仪表盘", Path: "/admin/dashboard", Icon: "dashboard", Sort: 10},
+	{Name: "用户管理", Path: "/admin/users", Icon: "user", Sort: 20},
+	{Name: "系统设置", Path: "/admin/settings", Icon: "setting", Sort: 30, Roles: []string{rbac.SuperAdmin}},
+}
+
+// Seed 菜单表为空时写入默认菜单
+func Seed(ctx context.Context) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+	var count int64
+	if err := db.WithContext(ctx).Model(&model.Menu{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	menus := make([]model.Menu, len(defaults))
+	for i, m := range defaults {
+		m.Roles = append([]string{}, m.Roles...)
+		menus[i] = m
+	}
+	return db.WithContext(ctx).Create(&menus).Error
+}
+
+// List 获取全部菜单（按上级、排序值、ID 排列）
+func List(ctx context.Context) ([]model.Menu, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	menus := []model.Menu{}
+	if err := db.WithContext(ctx).Order("parent_id ASC, sort ASC, id ASC").Find(&menus).Error; err != nil {
+		return nil, err
+	}
+	return menus, nil
+}
+
+// Get 获取菜单
+func Get(ctx context.Context, id string) (*model.Menu, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	menuID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	var m model.Menu
+	err = db.WithContext(ctx).First(&m, menuID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Create 添加菜单
+func Create(ctx context.Context, m *model.Menu) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+	if err := validate(ctx, db, m); err != nil {
+		return err
+	}
+	return db.WithContext(ctx).Create(m).Error
+}
+
+// Update 保存修改后的菜单
+func Update(ctx context.Context, m *model.Menu) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+	if err := validate(ctx, db, m); err != nil {
+		return err
+	}
+	return db.WithContext(ctx).Select("parent_id", "name", "path", "icon", "sort", "roles", "permission").Updates(m).Error
+}
+
+// Delete 删除菜单（还有下级菜单时不能删除）
+func Delete(ctx context.Context, id uint) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+	var children int64
+	if err := db.WithContext(ctx).Model(&model.Menu{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+		return err
+	}
+	if children > 0 {
+		return ErrInUse
+	}
+	result := db.WithContext(ctx).Delete(&model.Menu{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// validate 检查名称、路径、上级菜单（存在且不形成环）、可见角色与需要的权限
+func validate(ctx context.Context, db *gorm.DB, m *model.Menu) error {
+	m.Name = strings.TrimSpace(m.Name)
+	m.Path = strings.TrimSpace(m.Path)
+	m.Icon = strings.TrimSpace(m.Icon)
+	m.Permission = strings.TrimSpace(m.Permission)
+	if m.Name == "" || len(m.Name) > 64 {
+		return fmt.Errorf("%w: 名称不能为空，最长 64 个字符", ErrInvalid)
+	}
+	if m.Path != "" && !strings.HasPrefix(m.Path, "/") {
+		if u, err := url.Parse(m.Path); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: 路径应以 / 开头，或为 http(s) 外部链接", ErrInvalid)
+		}
+	}
+
+	// 沿上级链向上查找，不能经过自己
+	for parent, depth := m.ParentID, 0; parent != 0; depth++ {
+		if parent == m.ID || depth > 16 {
+			return fmt.Errorf("%w: 上级菜单不能是自己或自己的下级", ErrInvalid)
+		}
+		var p model.Menu
+		if err := db.WithContext(ctx).Select("id", "parent_id").First(&p, parent).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: 上级菜单 %d 不存在", ErrInvalid, parent)
+			}
+			return err
+		}
+		parent = p.ParentID
+	}
+
+	roles := make([]string, 0, len(m.Roles))
+	for _, role := range m.Roles {
+		if role = strings.TrimSpace(role); role == "" {
+			continue
+		}
+		if err := rbac.CheckRole(ctx, role); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		roles = append(roles, role)
+	}
+	m.Roles = roles
+	if m.Permission != "" {
+		if err := rbac.CheckPermission(ctx, m.Permission); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+	}
+	return nil
+}
+
+// Tree 当前管理员可见的菜单树：角色不在 Roles 中或缺少 Permission 的菜单及其下级不可见，
+// 没有路径且下级全部不可见的分组菜单同样不返回。数据库未连接时使用默认菜单
+func Tree(ctx context.Context, claims *adminmiddleware.Claims) ([]model.Menu, error) {
+	menus, err := List(ctx)
+	if errors.Is(err, ErrUnavailable) {
+		return Defaults(claims), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return filter(Build(menus, 0), claims), nil
+}
+
+// Defaults 当前管理员可见的默认菜单（读取菜单表失败时使用）
+func Defaults(claims *adminmiddleware.Claims) []model.Menu {
+	return filter(append([]model.Menu(nil), defaults...), claims)
+}
+
+// Build 将菜单列表组装为树（parent 为根的上级 ID），同级按排序值、ID 排列
+func Build(menus []model.Menu, parent uint) []model.Menu {
+	byParent := make(map[uint][]model.Menu)
+	for _, m := range menus {
+		byParent[m.ParentID] = append(byParent[m.ParentID], m)
+	}
+	var build func(parent uint, depth int) []model.Menu
+	build = func(parent uint, depth int) []model.Menu {
+		children := byParent[parent]
+		if len(children) == 0 || depth > 16 {
+			return nil
+		}
+		sort.SliceStable(children, func(i, j int) bool {
+			if children[i].Sort != children[j].Sort {
+				return children[i].Sort < children[j].Sort
+			}
+			return children[i].ID < children[j].ID
+		})
+		tree := make([]model.Menu, len(children))
+		for i, m := range children {
+			m.Children = build(m.ID, depth+1)
+			tree[i] = m
+		}
+		return tree
+	}
+	return build(parent, 0)
+}
+
+// filter 按角色与权限过滤菜单树
+func filter(menus []model.Menu, claims *adminmiddleware.Claims) []model.Menu {
+	visible := []model.Menu{}
+	for _, m := range menus {
+		if !allowed(m, claims) {
+			continue
+		}
+		hadChildren := len(m.Children) > 0
+		m.Children = filter(m.Children, claims)
+		if hadChildren && len(m.Children) == 0 && m.Path == "" {
+			continue
+		}
+		if len(m.Children) == 0 {
+			m.Children = nil
+		}
+		visible = append(visible, m)
+	}
+	return visible
+}
+
+// allowed 管理员是否可以看到菜单项（限定了数据范围的超级管理员按普通管理员判断角色）
+func allowed(m model.Menu, claims *adminmiddleware.Claims) bool {
+	if len(m.Roles) > 0 {
+		found := false
+		for _, role := range m.Roles {
+			if role == claims.GlobalRole() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return m.Permission == "" || adminmiddleware.HasPermission(claims, m.Permission)
+}
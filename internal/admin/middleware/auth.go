@@ -2,9 +2,8 @@ package middleware
 
 import (
 	"net/http"
-	"strings"
 
-	"new-openclaw/pkg/jwt"
+	"new-openclaw/pkg/auth/token"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,40 +11,70 @@ import (
 const (
 	// AdminContextKey 管理员信息在Context中的key
 	AdminContextKey = "admin_claims"
+	// CodeLoggedInElsewhere 账号已在其他设备登录（当前会话被新登录顶替）时的错误码
+	CodeLoggedInElsewhere = 40101
 )
 
+// OnTokenFailure 管理后台 Token 无效、过期或已吊销时的处理（如写入安全事件），为空不处理
+var OnTokenFailure func(c *gin.Context, err error)
+
+// webAuthnRoles 必须使用安全密钥登录的角色
+var webAuthnRoles = map[string]bool{}
+
+// RequireWebAuthnFor 设置必须使用安全密钥（WebAuthn）登录的角色（启动时按配置调用）
+func RequireWebAuthnFor(roles ...string) {
+	webAuthnRoles = make(map[string]bool, len(roles))
+	for _, role := range roles {
+		webAuthnRoles[role] = true
+	}
+}
+
+// WebAuthnRequired 该角色是否必须使用安全密钥登录
+func WebAuthnRequired(role string) bool {
+	return webAuthnRoles[role]
+}
+
 // JWTAuth JWT认证中间件
 func JWTAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 从Header获取Token
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "请先登录",
-			})
-			c.Abort()
-			return
-		}
-
-		// 检查Token格式
-		parts := strings.SplitN(authHeader, " ", 2)
-		if !(len(parts) == 2 && parts[0] == "Bearer") {
+		// 从Header获取Token，未携带时从Cookie读取（浏览器客户端）
+		tokenString, err := DefaultConfig.FromRequest(c.Request)
+		if err != nil {
+			message := "请先登录"
+			if err == token.ErrTokenFormat {
+				message = "Token格式错误"
+			}
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
-				"message": "Token格式错误",
+				"message": message,
 			})
 			c.Abort()
 			return
 		}
 
 		// 解析Token
-		claims, err := jwt.ParseToken(parts[1])
+		claims, err := ParseToken(tokenString)
 		if err != nil {
+			if OnTokenFailure != nil {
+				OnTokenFailure(c, err)
+			}
 			message := "Token无效"
-			if err == jwt.ErrTokenExpired {
+			if err == token.ErrTokenExpired {
 				message = "Token已过期，请重新登录"
 			}
+			if err == token.ErrTokenRevoked {
+				message = "Token已失效，请重新登录"
+			}
+			// 只允许一个活跃会话时，旧客户端以单独的错误码提示用户（而不是普通的登录失效）
+			if err == ErrLoggedInElsewhere {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"code":    CodeLoggedInElsewhere,
+					"message": "账号已在其他设备登录，请重新登录",
+					"data":    gin.H{"logged_in_elsewhere": true},
+				})
+				c.Abort()
+				return
+			}
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
 				"message": message,
@@ -56,6 +85,11 @@ func JWTAuth() gin.HandlerFunc {
 
 		// 将管理员信息存入Context
 		c.Set(AdminContextKey, claims)
+		if claims.Impersonator != nil {
+			c.Set(token.ActorContextKey, claims.Impersonator)
+		}
+		// 角色的细粒度权限，与 /api/v1 的权限范围共用同一个键（如响应脱敏判断 pii:read）
+		c.Set("scopes", PermissionsOf(claims.Role))
 		c.Next()
 	}
 }
@@ -73,7 +107,7 @@ func RequireRole(roles ...string) gin.HandlerFunc {
 			return
 		}
 
-		adminClaims := claims.(*jwt.Claims)
+		adminClaims := claims.(*Claims)
 		
 		// 检查角色权限
 		hasRole := false
@@ -93,15 +127,42 @@ func RequireRole(roles ...string) gin.HandlerFunc {
 			return
 		}
 
+		// 必须使用安全密钥的角色以密码登录（尚未注册安全密钥）时，只能访问个人资料、注册安全密钥等通用接口；
+		// 模拟登录由超级管理员发起，发起时已经过同样的检查
+		if webAuthnPending(adminClaims) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "请先注册安全密钥，并使用安全密钥重新登录",
+				"data":    gin.H{"webauthn_required": true},
+			})
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
 }
 
 // GetCurrentAdmin 从Context获取当前管理员信息
-func GetCurrentAdmin(c *gin.Context) *jwt.Claims {
+func GetCurrentAdmin(c *gin.Context) *Claims {
 	claims, exists := c.Get(AdminContextKey)
 	if !exists {
 		return nil
 	}
-	return claims.(*jwt.Claims)
+	return claims.(*Claims)
+}
+
+// RequireUnscoped 限定了数据范围的管理员不能访问的接口（全局配置、其他管理员等不区分客户的资源）
+func RequireUnscoped() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if admin := GetCurrentAdmin(c); admin != nil && !admin.Scope.Empty() {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "数据范围受限的管理员无权访问",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
 }
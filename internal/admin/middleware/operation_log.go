@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/breakglass"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OperationLog 操作日志中间件（记录管理员的写操作）
+func OperationLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		admin := GetCurrentAdmin(c)
+		if admin == nil {
+			return
+		}
+
+		// 紧急访问会话的所有请求写入紧急访问轨迹（不依赖数据库）
+		if admin.BreakGlass {
+			breakglass.Record(breakglass.Request{
+				Username:  admin.Username,
+				SessionID: admin.ID,
+				Method:    c.Request.Method,
+				Path:      c.Request.URL.Path,
+				Status:    c.Writer.Status(),
+				IP:        c.ClientIP(),
+			})
+		}
+
+		switch c.Request.Method {
+		case "POST", "PUT", "PATCH", "DELETE":
+		default:
+			return
+		}
+
+		db := database.GetMySQL()
+		if db == nil {
+			return
+		}
+
+		entry := &model.OperationLog{
+			AdminID:   admin.AdminID,
+			Username:  admin.Username,
+			Action:    c.Request.Method + " " + c.FullPath(),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			Resource:  operationResource(c.FullPath()),
+			TargetID:  operationTarget(c),
+			Status:    c.Writer.Status(),
+			IP:        c.ClientIP(),
+			RequestID: c.GetString("request_id"),
+			CreatedAt: time.Now(),
+		}
+		if change, ok := c.Get(operationChangeKey); ok && entry.Status < 400 {
+			entry.Changes = diffChanges(change.(operationChange))
+		}
+		if admin.Impersonator != nil {
+			entry.ImpersonatorID = admin.Impersonator.AdminID
+			entry.ImpersonatorName = admin.Impersonator.Username
+		}
+
+		// 异步写入，避免拖慢请求
+		go func() {
+			if err := db.Create(entry).Error; err != nil {
+				log.Printf("写入操作日志失败: %v", err)
+			}
+		}()
+	}
+}
+
+// operationChangeKey 处理器登记的变更前后数据在上下文中的键
+const operationChangeKey = "operation_change"
+
+// operationChange 处理器登记的资源变更前后的数据
+type operationChange struct {
+	before, after interface{}
+	// 不在 JSON 中输出但发生了变更的敏感字段（如密码）
+	secrets []string
+}
+
+// ignoredChangeFields 不计入变更的字段（每次写入都会变化）
+var ignoredChangeFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+}
+
+// RecordChange 登记本次写操作修改的资源在变更前后的数据（新建时 before 为 nil，删除时 after 为 nil），
+// 请求成功后由操作日志中间件按 JSON 字段比较，写入字段级变更
+func RecordChange(c *gin.Context, before, after interface{}) {
+	change := operationChange{before: before, after: after}
+	if existing, ok := c.Get(operationChangeKey); ok {
+		change.secrets = existing.(operationChange).secrets
+	}
+	c.Set(operationChangeKey, change)
+}
+
+// RecordSecretChange 登记不在接口中返回的敏感字段发生了变更（只记录字段名，不记录值）
+func RecordSecretChange(c *gin.Context, fields ...string) {
+	var change operationChange
+	if existing, ok := c.Get(operationChangeKey); ok {
+		change = existing.(operationChange)
+	}
+	change.secrets = append(change.secrets, fields...)
+	c.Set(operationChangeKey, change)
+}
+
+// diffChanges 比较变更前后的 JSON 字段；名称含 password、secret、token 的字段不记录原值
+func diffChanges(change operationChange) map[string]model.FieldChange {
+	before, after := changeFields(change.before), changeFields(change.after)
+	changes := make(map[string]model.FieldChange)
+	for _, fields := range []map[string]interface{}{before, after} {
+		for name := range fields {
+			if ignoredChangeFields[name] {
+				continue
+			}
+			if _, done := changes[name]; done {
+				continue
+			}
+			// 字段缺失（新建、删除）与 null 同等对待
+			old, cur := before[name], after[name]
+			if reflect.DeepEqual(old, cur) {
+				continue
+			}
+			if sensitiveField(name) {
+				old, cur = maskChange(old), maskChange(cur)
+			}
+			changes[name] = model.FieldChange{Before: old, After: cur}
+		}
+	}
+	for _, name := range change.secrets {
+		changes[name] = model.FieldChange{After: "******"}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// changeFields 将资源转换为 JSON 字段（与接口返回的字段一致，json:"-" 的字段不会出现）
+func changeFields(v interface{}) map[string]interface{} {
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	if json.Unmarshal(data, &fields) != nil {
+		return nil
+	}
+	return fields
+}
+
+func sensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	return strings.Contains(name, "password") || strings.Contains(name, "secret") || strings.Contains(name, "token")
+}
+
+func maskChange(v interface{}) interface{} {
+	if v == nil || v == "" {
+		return nil
+	}
+	return "******"
+}
+
+// operationTarget 操作对象的 ID：路径参数 id，没有时取第一个路径参数（如 :app_key）
+func operationTarget(c *gin.Context) string {
+	if id := c.Param("id"); id != "" {
+		return id
+	}
+	if len(c.Params) > 0 {
+		return c.Params[0].Value
+	}
+	return ""
+}
+
+// operationResource 由路由取资源名（/admin/ 之后的第一段，如 /admin/admins/:id 为 admins）
+func operationResource(fullPath string) string {
+	rest := strings.TrimPrefix(fullPath, "/admin/")
+	if rest == fullPath {
+		return ""
+	}
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		rest = rest[:i]
+	}
+	return rest
+}
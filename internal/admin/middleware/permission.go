@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 角色与权限、菜单管理接口使用的权限
+const (
+	PermissionRolesRead  = "roles:read"
+	PermissionRolesWrite = "roles:write"
+	PermissionMenusRead  = "menus:read"
+	PermissionMenusWrite = "menus:write"
+)
+
+var (
+	permMu sync.RWMutex
+	// configured 启动时按配置授予的权限（如 ADMIN_PII_READ_ROLES）
+	configured = map[string][]string{}
+	// managed 角色管理中维护的权限（随数据库热更新）
+	managed = map[string][]string{}
+)
+
+// Grant 给管理员角色授予权限（启动时按配置调用）
+func Grant(role string, permissions ...string) {
+	permMu.Lock()
+	defer permMu.Unlock()
+	configured[role] = append(configured[role], permissions...)
+}
+
+// SetRolePermissions 替换角色管理中维护的各角色权限，与配置授予的权限合并生效
+func SetRolePermissions(roles map[string][]string) {
+	next := make(map[string][]string, len(roles))
+	for role, permissions := range roles {
+		next[role] = append([]string(nil), permissions...)
+	}
+	permMu.Lock()
+	defer permMu.Unlock()
+	managed = next
+}
+
+// PermissionsOf 角色的细粒度权限（写法同 /api/v1 的权限范围，* 表示全部，pii:* 表示 pii: 开头的全部权限）
+func PermissionsOf(role string) []string {
+	permMu.RLock()
+	defer permMu.RUnlock()
+	permissions := make([]string, 0, len(configured[role])+len(managed[role]))
+	permissions = append(permissions, configured[role]...)
+	return append(permissions, managed[role]...)
+}
+
+// HasPermission 管理员是否拥有权限：不限数据范围的超级管理员拥有全部权限，其他管理员按角色的权限判断
+func HasPermission(claims *Claims, permission string) bool {
+	if claims == nil {
+		return false
+	}
+	if claims.GlobalRole() == "super_admin" {
+		return true
+	}
+	return matchPermission(PermissionsOf(claims.Role), permission)
+}
+
+// matchPermission 与 /api/v1 的权限范围匹配规则一致（管理后台中间件不能引用 internal/middleware）
+func matchPermission(granted []string, required string) bool {
+	for _, g := range granted {
+		if g == "*" || g == required {
+			return true
+		}
+		if strings.HasSuffix(g, ":*") && strings.HasPrefix(required, strings.TrimSuffix(g, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission 细粒度权限中间件：角色拥有该权限的管理员可以访问（不要求特定角色）
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := GetCurrentAdmin(c)
+		if claims == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "请先登录",
+			})
+			c.Abort()
+			return
+		}
+		if !HasPermission(claims, permission) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "权限不足",
+			})
+			c.Abort()
+			return
+		}
+		if webAuthnPending(claims) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "请先注册安全密钥，并使用安全密钥重新登录",
+				"data":    gin.H{"webauthn_required": true},
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// webAuthnPending 必须使用安全密钥的角色以密码登录（尚未注册安全密钥）：只能访问个人资料、注册安全密钥等通用接口。
+// 模拟登录由超级管理员发起，发起时已经过同样的检查
+func webAuthnPending(claims *Claims) bool {
+	return WebAuthnRequired(claims.Role) && claims.AuthMethod != AuthMethodWebAuthn && !claims.BreakGlass && claims.Impersonator == nil
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/session"
+	"new-openclaw/pkg/auth/token"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// serverSessions 服务端会话配置：启用后管理后台不再签发 JWT，登录凭证为保存在会话存储中的会话
+var serverSessions struct {
+	enabled bool
+	// 空闲超时（无请求）与绝对超时（从登录时间起算，刷新不会延长）
+	idle     time.Duration
+	absolute time.Duration
+}
+
+// UseServerSessions 管理后台改用服务端会话（启动时按配置调用）
+func UseServerSessions(idle, absolute time.Duration) {
+	serverSessions.enabled = true
+	serverSessions.idle = idle
+	serverSessions.absolute = absolute
+}
+
+// ServerSessions 管理后台是否使用服务端会话
+func ServerSessions() bool {
+	return serverSessions.enabled
+}
+
+// tokenTTL 登录凭证的有效期：服务端会话取绝对超时
+func tokenTTL() time.Duration {
+	if serverSessions.enabled {
+		return serverSessions.absolute
+	}
+	return DefaultConfig.TokenExpiry
+}
+
+// sign 签发登录凭证：默认为 JWT；启用服务端会话时创建会话，返回会话凭证
+func sign(claims *Claims) (string, error) {
+	if !serverSessions.enabled {
+		return DefaultConfig.Sign(claims)
+	}
+	if deadline := claims.AuthenticatedAt().Add(serverSessions.absolute); claims.ExpiresAt == nil || deadline.Before(claims.ExpiresAt.Time) {
+		claims.ExpiresAt = jwt.NewNumericDate(deadline)
+	}
+	return session.Create(context.Background(), claims.RegisteredClaims, claims, serverSessions.idle)
+}
+
+// parseSession 读取服务端会话中的管理员声明；会话已超时、被删除（登出、吊销）或账号被强制下线时返回 ErrTokenRevoked，
+// 被新登录顶替时返回 ErrLoggedInElsewhere
+func parseSession(credential string) (*Claims, error) {
+	// JWT 含两个 "."，会话凭证只有一个
+	if strings.Count(credential, ".") != 1 {
+		return nil, token.ErrTokenMalformed
+	}
+	id, _, _ := strings.Cut(credential, ".")
+	claims := &Claims{}
+	if err := session.Lookup(context.Background(), credential, claims); err != nil {
+		if errors.Is(err, session.ErrNotFound) {
+			if superseded(id) {
+				return nil, ErrLoggedInElsewhere
+			}
+			return nil, token.ErrTokenRevoked
+		}
+		return nil, err
+	}
+	if DefaultConfig.Blacklist != nil && DefaultConfig.Blacklist.IsRevoked(claims.RegisteredClaims) {
+		return nil, token.ErrTokenRevoked
+	}
+	return claims, nil
+}
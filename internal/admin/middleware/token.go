@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/revocation"
+	"new-openclaw/pkg/auth/token"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultConfig 管理后台 Token 配置（与用户 Token 共用 pkg/auth/token 的签发、校验逻辑）
+var DefaultConfig = &token.Config{
+	SecretKey:   "openclaw-admin-secret-key-2024",
+	TokenExpiry: 24 * time.Hour,
+	Issuer:      "openclaw-admin",
+}
+
+// ErrLoggedInElsewhere 账号已在其他设备登录，当前 Token 被新登录顶替
+var ErrLoggedInElsewhere = errors.New("账号已在其他设备登录")
+
+// Claims 管理员声明
+type Claims struct {
+	AdminID  uint   `json:"admin_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	// 紧急访问（break-glass）签发的 Token
+	BreakGlass bool `json:"break_glass,omitempty"`
+	// 数据范围（为空不限制；修改后吊销该管理员的 Token，下次登录生效）
+	Scope *model.AdminScope `json:"scope,omitempty"`
+	// 登录（输入密码）的时间，刷新 Token 时沿用，用于判断是否需要重新认证
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+	// 登录方式（password、webauthn、impersonation，为空视为 password），刷新 Token 时沿用
+	AuthMethod string `json:"auth_method,omitempty"`
+	// 模拟登录：发起模拟的超级管理员（为空表示本人登录）
+	Impersonator *token.Actor `json:"act,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Registered 返回标准声明
+func (c *Claims) Registered() jwt.RegisteredClaims {
+	return c.RegisteredClaims
+}
+
+// 管理员登录方式
+const (
+	AuthMethodPassword = "password"
+	AuthMethodWebAuthn = "webauthn"
+	// 超级管理员模拟登录签发的 Token
+	AuthMethodImpersonation = "impersonation"
+	// SAML 单点登录
+	AuthMethodSAML = "saml"
+)
+
+// GenerateToken 生成管理员 Token，返回 Token 及过期时间（Unix 秒）
+func GenerateToken(adminID uint, username, role string) (string, int64, error) {
+	return GenerateScopedToken(adminID, username, role, nil)
+}
+
+// AuthenticatedAt 最近一次登录的时间（早期签发、未携带 auth_time 的 Token 取签发时间）
+func (c *Claims) AuthenticatedAt() time.Time {
+	if c.AuthTime != nil {
+		return c.AuthTime.Time
+	}
+	if c.IssuedAt != nil {
+		return c.IssuedAt.Time
+	}
+	return time.Time{}
+}
+
+// GenerateScopedToken 生成限定数据范围的管理员 Token（密码登录）
+func GenerateScopedToken(adminID uint, username, role string, scope *model.AdminScope) (string, int64, error) {
+	return GenerateLoginToken(adminID, username, role, scope, AuthMethodPassword)
+}
+
+// GenerateLoginToken 按登录方式生成管理员 Token
+func GenerateLoginToken(adminID uint, username, role string, scope *model.AdminScope, method string) (string, int64, error) {
+	return signScopedToken(adminID, username, role, scope, time.Now(), method)
+}
+
+// RefreshScopedToken 为已登录的管理员换发 Token，保留原 Token 的身份、数据范围、登录时间与登录方式
+func RefreshScopedToken(claims *Claims) (string, int64, error) {
+	return signScopedToken(claims.AdminID, claims.Username, claims.Role, claims.Scope, claims.AuthenticatedAt(), claims.AuthMethod)
+}
+
+func signScopedToken(adminID uint, username, role string, scope *model.AdminScope, authTime time.Time, method string) (string, int64, error) {
+	if scope.Empty() {
+		scope = nil
+	}
+	claims := &Claims{
+		AdminID:          adminID,
+		Username:         username,
+		Role:             role,
+		Scope:            scope,
+		AuthTime:         jwt.NewNumericDate(authTime),
+		AuthMethod:       method,
+		RegisteredClaims: DefaultConfig.NewRegisteredClaims(strconv.FormatUint(uint64(adminID), 10), tokenTTL()),
+	}
+
+	tokenString, err := sign(claims)
+	if err != nil {
+		return "", 0, err
+	}
+	return tokenString, claims.ExpiresAt.Unix(), nil
+}
+
+// GenerateImpersonationToken 生成模拟登录 Token：以被模拟管理员的身份与数据范围签发，有效期为 ttl，携带发起模拟的管理员
+func GenerateImpersonationToken(target *model.Admin, actor *token.Actor, ttl time.Duration) (string, jwt.RegisteredClaims, error) {
+	scope := target.Scope
+	if scope.Empty() {
+		scope = nil
+	}
+	claims := &Claims{
+		AdminID:          target.ID,
+		Username:         target.Username,
+		Role:             target.Role,
+		Scope:            scope,
+		AuthTime:         jwt.NewNumericDate(time.Now()),
+		AuthMethod:       AuthMethodImpersonation,
+		Impersonator:     actor,
+		RegisteredClaims: DefaultConfig.NewRegisteredClaims(strconv.FormatUint(uint64(target.ID), 10), ttl),
+	}
+
+	tokenString, err := sign(claims)
+	if err != nil {
+		return "", jwt.RegisteredClaims{}, err
+	}
+	return tokenString, claims.RegisteredClaims, nil
+}
+
+// BreakGlassSubjectPrefix 紧急访问 Token 的 sub 前缀
+const BreakGlassSubjectPrefix = "break-glass:"
+
+// GenerateBreakGlassToken 生成紧急访问 Token（超级管理员权限，有效期为 ttl，不对应数据库中的管理员）。
+// 紧急访问用于 Redis、数据库故障时，启用服务端会话也始终签发无状态的 JWT
+func GenerateBreakGlassToken(username string, ttl time.Duration) (string, int64, error) {
+	claims := &Claims{
+		Username:         username,
+		Role:             "super_admin",
+		BreakGlass:       true,
+		RegisteredClaims: DefaultConfig.NewRegisteredClaims(BreakGlassSubjectPrefix+username, ttl),
+	}
+
+	tokenString, err := DefaultConfig.Sign(claims)
+	if err != nil {
+		return "", 0, err
+	}
+	return tokenString, claims.ExpiresAt.Unix(), nil
+}
+
+// ParseToken 解析管理员 Token（启用服务端会话时为会话凭证，紧急访问 Token 仍为 JWT）
+func ParseToken(tokenString string) (*Claims, error) {
+	if serverSessions.enabled {
+		if strings.Count(tokenString, ".") == 2 {
+			return parseBreakGlass(tokenString)
+		}
+		return parseSession(tokenString)
+	}
+	claims := &Claims{}
+	if err := DefaultConfig.Parse(tokenString, claims); err != nil {
+		// 签名有效、已被吊销的 Token 声明已解析，按 jti 查询吊销原因
+		if errors.Is(err, token.ErrTokenRevoked) && superseded(claims.ID) {
+			return nil, ErrLoggedInElsewhere
+		}
+		return nil, err
+	}
+	return claims, nil
+}
+
+// parseBreakGlass 启用服务端会话时只接受紧急访问的 JWT
+func parseBreakGlass(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	if err := DefaultConfig.Parse(tokenString, claims); err != nil {
+		return nil, err
+	}
+	if !claims.BreakGlass || !strings.HasPrefix(claims.Subject, BreakGlassSubjectPrefix) {
+		return nil, token.ErrTokenMalformed
+	}
+	return claims, nil
+}
+
+// superseded Token 是否因账号在其他设备登录而被吊销
+func superseded(id string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return revocation.Reason(ctx, id) == revocation.ReasonSuperseded
+}
+
+// GlobalRole 访问全局数据（仪表盘指标、审计等列表视图）时按此角色判断权限：
+// 限定了数据范围的超级管理员只能看到普通管理员可见的全局数据
+func (c *Claims) GlobalRole() string {
+	if c.Role == "super_admin" && !c.Scope.Empty() {
+		return "admin"
+	}
+	return c.Role
+}
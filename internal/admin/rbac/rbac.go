@@ -0,0 +1,472 @@
+// Package rbac 管理员角色与权限：admin_roles 表中维护各角色的细粒度权限，admin_permissions 表为可分配的权限目录。
+// 各实例加载后写入管理后台中间件（与按配置授予的权限合并），变更后通过 Redis 广播让其他实例重新加载
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	adminmiddleware "new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/jobs"
+	"new-openclaw/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// channel 角色权限变更广播频道（各实例收到后重新加载）
+const channel = "openclaw:admin-roles:reload"
+
+// SuperAdmin 超级管理员角色：拥有全部权限，不能修改
+const SuperAdmin = "super_admin"
+
+var (
+	// ErrUnavailable 数据库未连接
+	ErrUnavailable = errors.New("数据库未连接")
+	// ErrRoleNotFound 角色不存在
+	ErrRoleNotFound = errors.New("角色不存在")
+	// ErrPermissionNotFound 权限不存在
+	ErrPermissionNotFound = errors.New("权限不存在")
+	// ErrExists 角色或权限已存在
+	ErrExists = errors.New("已存在")
+	// ErrInvalid 名称或权限列表不合法
+	ErrInvalid = errors.New("参数不合法")
+	// ErrBuiltin 内置角色或权限不可删除
+	ErrBuiltin = errors.New("内置角色或权限不可删除")
+	// ErrInUse 角色仍有管理员使用，或权限仍被角色引用
+	ErrInUse = errors.New("仍在使用中")
+)
+
+// builtinRoles 内置角色（权限默认为空，按需在管理后台分配）
+var builtinRoles = []model.AdminRole{
+	{Name: SuperAdmin, Description: "超级管理员，拥有全部权限"},
+	{Name: "admin", Description: "管理员"},
+	{Name: "editor", Description: "编辑"},
+}
+
+// builtinPermissions 代码中使用的权限
+var builtinPermissions = []model.AdminPermission{
+	{Name: "pii:read", Description: "查看未脱敏的敏感字段"},
+	{Name: adminmiddleware.PermissionRolesRead, Description: "查看角色与权限"},
+	{Name: adminmiddleware.PermissionRolesWrite, Description: "管理角色与权限、分配管理员角色"},
+	{Name: adminmiddleware.PermissionMenusRead, Description: "查看全部菜单"},
+	{Name: adminmiddleware.PermissionMenusWrite, Description: "管理菜单"},
+}
+
+var (
+	// roleNamePattern 角色名只允许小写字母开头的小写字母、数字、下划线
+	roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,31}$`)
+	// permissionPattern 权限名以 : 分段，如 reports:export
+	permissionPattern = regexp.MustCompile(`^[a-z0-9_-]+(:[a-z0-9_-]+)+$`)
+	// wildcardPattern 通配权限，如 reports:*
+	wildcardPattern = regexp.MustCompile(`^([a-z0-9_-]+:)+\*$`)
+)
+
+var (
+	mu     sync.Mutex
+	cancel context.CancelFunc
+)
+
+// Seed 写入缺少的内置角色与内置权限（已存在的保持不变）
+func Seed(ctx context.Context) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+	db = db.WithContext(ctx)
+	for _, r := range builtinRoles {
+		var role model.AdminRole
+		attrs := model.AdminRole{Permissions: []string{}, Description: r.Description, Builtin: true}
+		if err := db.Where(model.AdminRole{Name: r.Name}).Attrs(attrs).FirstOrCreate(&role).Error; err != nil {
+			return err
+		}
+	}
+	for _, p := range builtinPermissions {
+		var permission model.AdminPermission
+		attrs := model.AdminPermission{Description: p.Description, Builtin: true}
+		if err := db.Where(model.AdminPermission{Name: p.Name}).Attrs(attrs).FirstOrCreate(&permission).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListRoles 获取全部角色及使用各角色的管理员数
+func ListRoles(ctx context.Context) ([]model.AdminRole, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	roles := []model.AdminRole{}
+	if err := db.WithContext(ctx).Order("builtin DESC, name ASC").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		Role  string
+		Total int64
+	}
+	if err := db.WithContext(ctx).Model(&model.Admin{}).Select("role, COUNT(*) AS total").Group("role").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	byRole := make(map[string]int64, len(counts))
+	for _, c := range counts {
+		byRole[c.Role] = c.Total
+	}
+	for i := range roles {
+		roles[i].Admins = byRole[roles[i].Name]
+	}
+	return roles, nil
+}
+
+// GetRole 获取角色
+func GetRole(ctx context.Context, name string) (*model.AdminRole, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	var role model.AdminRole
+	err := db.WithContext(ctx).Where("name = ?", name).First(&role).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrRoleNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := db.WithContext(ctx).Model(&model.Admin{}).Where("role = ?", role.Name).Count(&role.Admins).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// CreateRole 添加角色并通知所有实例重新加载
+func CreateRole(ctx context.Context, role *model.AdminRole) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+	role.Name = strings.TrimSpace(role.Name)
+	if !roleNamePattern.MatchString(role.Name) {
+		return fmt.Errorf("%w: 角色名只能包含小写字母、数字、_，以字母开头，长度 2-32", ErrInvalid)
+	}
+	if err := normalizePermissions(ctx, db, role); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := db.WithContext(ctx).Model(&model.AdminRole{}).Where("name = ?", role.Name).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: 角色 %s", ErrExists, role.Name)
+	}
+	role.Builtin = false
+	if err := db.WithContext(ctx).Create(role).Error; err != nil {
+		return err
+	}
+
+	changed(ctx)
+	return nil
+}
+
+// UpdateRole 保存修改后的角色权限与说明（名称不可修改）并通知所有实例重新加载
+func UpdateRole(ctx context.Context, role *model.AdminRole) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+	if role.Name == SuperAdmin {
+		return fmt.Errorf("%w: 超级管理员拥有全部权限，不能修改", ErrInvalid)
+	}
+	if err := normalizePermissions(ctx, db, role); err != nil {
+		return err
+	}
+	if err := db.WithContext(ctx).Model(role).Select("permissions", "description").Updates(role).Error; err != nil {
+		return err
+	}
+
+	changed(ctx)
+	return nil
+}
+
+// DeleteRole 删除自定义角色（仍有管理员使用时不能删除）并通知所有实例重新加载
+func DeleteRole(ctx context.Context, name string) error {
+	role, err := GetRole(ctx, name)
+	if err != nil {
+		return err
+	}
+	if role.Builtin {
+		return ErrBuiltin
+	}
+	if role.Admins > 0 {
+		return fmt.Errorf("%w: 还有 %d 个管理员使用角色 %s", ErrInUse, role.Admins, name)
+	}
+	if err := database.GetMySQL().WithContext(ctx).Delete(role).Error; err != nil {
+		return err
+	}
+
+	changed(ctx)
+	return nil
+}
+
+// CheckRole 检查角色是否存在（创建管理员、分配角色时使用）
+func CheckRole(ctx context.Context, name string) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+	var count int64
+	if err := db.WithContext(ctx).Model(&model.AdminRole{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: %s", ErrRoleNotFound, name)
+	}
+	return nil
+}
+
+// CheckPermission 检查权限是已登记的权限或通配权限（*、reports:*）
+func CheckPermission(ctx context.Context, name string) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+	if name == "*" || wildcardPattern.MatchString(name) {
+		return nil
+	}
+	var count int64
+	if err := db.WithContext(ctx).Model(&model.AdminPermission{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: %s", ErrPermissionNotFound, name)
+	}
+	return nil
+}
+
+// normalizePermissions 去重并排序，检查每个权限是已登记的权限或通配权限（*、reports:*）
+func normalizePermissions(ctx context.Context, db *gorm.DB, role *model.AdminRole) error {
+	seen := make(map[string]bool, len(role.Permissions))
+	permissions := make([]string, 0, len(role.Permissions))
+	var names []string
+	for _, p := range role.Permissions {
+		p = strings.TrimSpace(p)
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		permissions = append(permissions, p)
+		if p != "*" && !wildcardPattern.MatchString(p) {
+			names = append(names, p)
+		}
+	}
+
+	if len(names) > 0 {
+		var known []string
+		if err := db.WithContext(ctx).Model(&model.AdminPermission{}).Where("name IN ?", names).Pluck("name", &known).Error; err != nil {
+			return err
+		}
+		registered := make(map[string]bool, len(known))
+		for _, name := range known {
+			registered[name] = true
+		}
+		for _, name := range names {
+			if !registered[name] {
+				return fmt.Errorf("%w: 未登记的权限 %s", ErrInvalid, name)
+			}
+		}
+	}
+
+	sort.Strings(permissions)
+	role.Permissions = permissions
+	role.Description = strings.TrimSpace(role.Description)
+	return nil
+}
+
+// ListPermissions 获取权限目录
+func ListPermissions(ctx context.Context) ([]model.AdminPermission, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	list := []model.AdminPermission{}
+	if err := db.WithContext(ctx).Order("name ASC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetPermission 获取权限
+func GetPermission(ctx context.Context, name string) (*model.AdminPermission, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	var p model.AdminPermission
+	err := db.WithContext(ctx).Where("name = ?", name).First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrPermissionNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// CreatePermission 登记权限（供自定义的接口或前端按钮使用）
+func CreatePermission(ctx context.Context, p *model.AdminPermission) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+	p.Name = strings.TrimSpace(p.Name)
+	if len(p.Name) > 64 || !permissionPattern.MatchString(p.Name) {
+		return fmt.Errorf("%w: 权限名只能包含小写字母、数字、_、-，以 : 分段，如 reports:export", ErrInvalid)
+	}
+	p.Description = strings.TrimSpace(p.Description)
+
+	var count int64
+	if err := db.WithContext(ctx).Model(&model.AdminPermission{}).Where("name = ?", p.Name).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: 权限 %s", ErrExists, p.Name)
+	}
+	p.Builtin = false
+	return db.WithContext(ctx).Create(p).Error
+}
+
+// UpdatePermission 修改权限说明（名称不可修改）
+func UpdatePermission(ctx context.Context, p *model.AdminPermission) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+	p.Description = strings.TrimSpace(p.Description)
+	return db.WithContext(ctx).Model(p).Select("description").Updates(p).Error
+}
+
+// DeletePermission 删除自定义权限（仍被角色引用时不能删除）
+func DeletePermission(ctx context.Context, name string) error {
+	p, err := GetPermission(ctx, name)
+	if err != nil {
+		return err
+	}
+	if p.Builtin {
+		return ErrBuiltin
+	}
+
+	db := database.GetMySQL().WithContext(ctx)
+	var roles []model.AdminRole
+	if err := db.Find(&roles).Error; err != nil {
+		return err
+	}
+	for _, role := range roles {
+		for _, granted := range role.Permissions {
+			if granted == name {
+				return fmt.Errorf("%w: 角色 %s 引用了权限 %s", ErrInUse, role.Name, name)
+			}
+		}
+	}
+	return db.Delete(p).Error
+}
+
+// Load 重新读取各角色的权限写入管理后台中间件；数据库未连接时保持当前权限
+func Load(ctx context.Context) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil
+	}
+	var roles []model.AdminRole
+	if err := db.WithContext(ctx).Find(&roles).Error; err != nil {
+		return err
+	}
+	permissions := make(map[string][]string, len(roles))
+	for _, role := range roles {
+		permissions[role.Name] = role.Permissions
+	}
+	adminmiddleware.SetRolePermissions(permissions)
+	return nil
+}
+
+// changed 角色权限变更后立即在本实例生效，并广播给其他实例
+func changed(ctx context.Context) {
+	if err := Load(ctx); err != nil {
+		log.Printf("重新加载角色权限失败: %v", err)
+	}
+	if rdb := database.GetRedis(); rdb != nil {
+		if err := rdb.Publish(ctx, channel, time.Now().Format(time.RFC3339Nano)).Err(); err != nil {
+			log.Printf("广播角色权限变更失败（其他实例将在定时任务中重新加载）: %v", err)
+		}
+	}
+}
+
+// Start 写入内置角色与权限、加载角色权限，并订阅变更广播（未连接 Redis 时只依赖定时重新加载）
+func Start() {
+	ctx := context.Background()
+	if err := Seed(ctx); err != nil && !errors.Is(err, ErrUnavailable) {
+		log.Printf("⚠️  写入内置角色失败: %v", err)
+	}
+	if err := Load(ctx); err != nil {
+		log.Printf("⚠️  加载角色权限失败，只使用配置授予的权限: %v", err)
+	}
+
+	rdb := database.GetRedis()
+	if rdb == nil {
+		return
+	}
+	ctx, stop := context.WithCancel(ctx)
+	mu.Lock()
+	cancel = stop
+	mu.Unlock()
+
+	sub := rdb.Subscribe(ctx, channel)
+	messages := sub.Channel()
+	go func() {
+		defer sub.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-messages:
+				if !ok {
+					return
+				}
+				if err := Load(ctx); err != nil {
+					log.Printf("重新加载角色权限失败: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop 停止订阅变更广播
+func Stop() {
+	mu.Lock()
+	defer mu.Unlock()
+	if cancel != nil {
+		cancel()
+		cancel = nil
+	}
+}
+
+// RegisterReloadJob 注册定时重新加载角色权限的任务（每个实例都执行，补偿遗漏的广播）
+func RegisterReloadJob(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	jobs.Register(jobs.Job{
+		Name:        "admin_roles_reload",
+		Description: "重新加载管理员角色权限",
+		Interval:    interval,
+		Run:         Load,
+	})
+}
@@ -3,6 +3,7 @@ package admin
 import (
 	"new-openclaw/internal/admin/handler"
 	"new-openclaw/internal/admin/middleware"
+	commonmiddleware "new-openclaw/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,27 +14,310 @@ func RegisterRoutes(r *gin.Engine) {
 	{
 		// 公开接口（无需认证）
 		admin.POST("/login", handler.Login)
+		// 紧急访问（常规认证基础设施不可用时）
+		admin.POST("/break-glass/login", handler.BreakGlassLogin)
+		// 安全密钥（WebAuthn）登录
+		admin.POST("/webauthn/login/options", handler.BeginWebAuthnLogin)
+		admin.POST("/webauthn/login", handler.FinishWebAuthnLogin)
+		// SAML 单点登录
+		admin.GET("/saml/metadata", handler.SAMLMetadata)
+		admin.GET("/saml/login", handler.SAMLLogin)
+		admin.POST("/saml/acs", handler.SAMLACS)
+		// 文件下载（签名链接，无需登录）
+		admin.GET("/files/:id/download", handler.DownloadFile)
 
 		// 需要认证的接口
 		auth := admin.Group("")
 		auth.Use(middleware.JWTAuth())
+		auth.Use(middleware.OperationLog())
+		auth.Use(commonmiddleware.Redact())
 		{
 			// 认证相关
 			auth.POST("/logout", handler.Logout)
 			auth.GET("/profile", handler.GetProfile)
+			auth.GET("/profile/logins", handler.GetMyLoginHistory)
 			auth.POST("/refresh-token", handler.RefreshToken)
 
-			// 仪表盘
+			// 当前管理员的安全密钥（必须使用安全密钥的角色以密码登录后也可以访问，用于首次注册）
+			auth.GET("/profile/webauthn", handler.ListMyWebAuthnCredentials)
+			auth.POST("/profile/webauthn/options", handler.BeginWebAuthnRegistration)
+			auth.POST("/profile/webauthn", handler.FinishWebAuthnRegistration)
+			auth.DELETE("/profile/webauthn/:id", handler.DeleteMyWebAuthnCredential)
+
+			// 活跃会话
+			auth.GET("/sessions", handler.ListSessions)
+			auth.GET("/sessions/events", handler.ListSessionEvents)
+			auth.DELETE("/sessions/:id", handler.RevokeSession)
+
+			// 仪表盘（首页及当前管理员的自定义仪表盘）
 			auth.GET("/dashboard", handler.Dashboard)
+			auth.GET("/dashboard/stats", middleware.RequireRole("super_admin"), middleware.RequireUnscoped(), handler.DashboardStats)
+			auth.GET("/dashboard/widgets", handler.ListDashboardWidgets)
+			auth.POST("/dashboard/widgets/query", handler.QueryDashboardWidget)
+			auth.GET("/dashboards", handler.ListDashboards)
+			auth.POST("/dashboards", handler.CreateDashboard)
+			auth.GET("/dashboards/:id", handler.GetDashboard)
+			auth.PUT("/dashboards/:id", handler.UpdateDashboard)
+			auth.DELETE("/dashboards/:id", handler.DeleteDashboard)
+
+			// 通知偏好（摘要频率、免打扰时段）
+			auth.GET("/notifications/preferences", handler.GetNotificationPreferences)
+			auth.PUT("/notifications/preferences/:category", handler.UpdateNotificationPreference)
 
-			// 管理员管理（仅超级管理员）
+			// 保存的列表视图（按管理员隔离）
+			auth.GET("/views", handler.ListViews)
+			auth.POST("/views", handler.CreateView)
+			auth.GET("/views/:id", handler.GetView)
+			auth.PUT("/views/:id", handler.UpdateView)
+			auth.DELETE("/views/:id", handler.DeleteView)
+
+			// 管理员管理（仅不限数据范围的超级管理员）
 			admins := auth.Group("/admins")
-			admins.Use(middleware.RequireRole("super_admin"))
+			admins.Use(middleware.RequireRole("super_admin"), middleware.RequireUnscoped())
 			{
 				admins.GET("", handler.ListAdmins)
 				admins.POST("", handler.CreateAdmin)
 				admins.PUT("/:id", handler.UpdateAdmin)
 				admins.DELETE("/:id", handler.DeleteAdmin)
+				admins.GET("/:id/sessions", handler.ListAdminSessions)
+				admins.GET("/:id/sessions/events", handler.ListAdminSessionEvents)
+				admins.DELETE("/:id/sessions/:sid", handler.RevokeAdminSession)
+				admins.POST("/:id/revoke-sessions", handler.RevokeAdminSessions)
+				admins.GET("/:id/webauthn", handler.ListAdminWebAuthnCredentials)
+				admins.DELETE("/:id/webauthn", handler.ResetAdminWebAuthnCredentials)
+			}
+
+			// 文件上传（非超级管理员只能查看、删除自己上传的文件）
+			auth.POST("/upload", handler.UploadFile)
+			auth.GET("/files", handler.ListFiles)
+			auth.GET("/files/:id", handler.GetFile)
+			auth.DELETE("/files/:id", handler.DeleteFile)
+
+			// 角色与权限管理（拥有 roles:read / roles:write 权限的管理员；不能授予或调整自己没有的权限）
+			roles := auth.Group("")
+			roles.Use(middleware.RequireUnscoped())
+			{
+				roles.GET("/roles", middleware.RequirePermission(middleware.PermissionRolesRead), handler.ListRoles)
+				roles.GET("/roles/:name", middleware.RequirePermission(middleware.PermissionRolesRead), handler.GetRole)
+				roles.POST("/roles", middleware.RequirePermission(middleware.PermissionRolesWrite), handler.CreateRole)
+				roles.PUT("/roles/:name", middleware.RequirePermission(middleware.PermissionRolesWrite), handler.UpdateRole)
+				roles.DELETE("/roles/:name", middleware.RequirePermission(middleware.PermissionRolesWrite), handler.DeleteRole)
+				roles.GET("/permissions", middleware.RequirePermission(middleware.PermissionRolesRead), handler.ListPermissions)
+				roles.GET("/permissions/:name", middleware.RequirePermission(middleware.PermissionRolesRead), handler.GetPermission)
+				roles.POST("/permissions", middleware.RequirePermission(middleware.PermissionRolesWrite), handler.CreatePermission)
+				roles.PUT("/permissions/:name", middleware.RequirePermission(middleware.PermissionRolesWrite), handler.UpdatePermission)
+				roles.DELETE("/permissions/:name", middleware.RequirePermission(middleware.PermissionRolesWrite), handler.DeletePermission)
+				roles.PUT("/admins/:id/role", middleware.RequirePermission(middleware.PermissionRolesWrite), handler.AssignAdminRole)
+			}
+
+			// 菜单管理（拥有 menus:read / menus:write 权限的管理员；首页按角色与权限返回可见的菜单树）
+			menus := auth.Group("/menus")
+			menus.Use(middleware.RequireUnscoped())
+			{
+				menus.GET("", middleware.RequirePermission(middleware.PermissionMenusRead), handler.ListMenus)
+				menus.GET("/:id", middleware.RequirePermission(middleware.PermissionMenusRead), handler.GetMenu)
+				menus.POST("", middleware.RequirePermission(middleware.PermissionMenusWrite), handler.CreateMenu)
+				menus.PUT("/:id", middleware.RequirePermission(middleware.PermissionMenusWrite), handler.UpdateMenu)
+				menus.DELETE("/:id", middleware.RequirePermission(middleware.PermissionMenusWrite), handler.DeleteMenu)
+			}
+
+			// 模拟登录：超级管理员以其他管理员或用户的身份签发短期 Token，使用模拟 Token 调用 stop 结束
+			auth.POST("/impersonate/stop", handler.StopImpersonation)
+			impersonate := auth.Group("")
+			impersonate.Use(middleware.RequireRole("super_admin"), middleware.RequireUnscoped())
+			{
+				impersonate.POST("/impersonate/:id", handler.Impersonate)
+				impersonate.GET("/impersonations", handler.ListImpersonations)
+				impersonate.DELETE("/impersonations/:id", handler.EndImpersonation)
+			}
+
+			// 紧急访问状态与轨迹（仅超级管理员）
+			breakGlass := auth.Group("/break-glass")
+			breakGlass.Use(middleware.RequireRole("super_admin"), middleware.RequireUnscoped())
+			{
+				breakGlass.GET("", handler.GetBreakGlassStatus)
+				breakGlass.GET("/trail", handler.ListBreakGlassTrail)
+			}
+
+			// 行为分析（仅超级管理员）
+			analytics := auth.Group("/analytics")
+			analytics.Use(middleware.RequireRole("super_admin"), middleware.RequireUnscoped())
+			{
+				analytics.GET("/activity", handler.ActivityAnalytics)
+				analytics.GET("/health-events", handler.ListHealthEvents)
+			}
+
+			// 配额、AppKey、载荷转换模板按 AppKey 区分客户，限定数据范围的管理员只能访问范围内的 AppKey；
+			// 其他全局资源另加 RequireUnscoped，仅对不限数据范围的超级管理员开放
+
+			// AppKey 配额（仅超级管理员）
+			quotas := auth.Group("/quotas")
+			quotas.Use(middleware.RequireRole("super_admin"))
+			{
+				quotas.GET("", handler.ListQuotas)
+				quotas.GET("/:app_key", handler.GetQuota)
+				quotas.PUT("/:app_key", handler.SetQuota)
+				quotas.DELETE("/:app_key", handler.DeleteQuota)
+				quotas.POST("/:app_key/reset", handler.ResetQuotaUsage)
+			}
+
+			// AppKey 签名凭证（仅超级管理员）
+			appKeys := auth.Group("/app-keys")
+			appKeys.Use(middleware.RequireRole("super_admin"))
+			{
+				appKeys.GET("", handler.ListAppKeys)
+				appKeys.POST("", handler.CreateAppKey)
+				appKeys.PUT("/:app_key", handler.UpdateAppKey)
+				appKeys.DELETE("/:app_key", handler.DeleteAppKey)
+				appKeys.POST("/:app_key/rotate", handler.RotateAppKey)
+				appKeys.POST("/:app_key/disable", handler.DisableAppKey)
+				appKeys.POST("/:app_key/enable", handler.EnableAppKey)
+			}
+
+			// Webhook 投递目标与投递记录（仅超级管理员）
+			webhooks := auth.Group("/webhooks")
+			webhooks.Use(middleware.RequireRole("super_admin"), middleware.RequireUnscoped())
+			{
+				webhooks.GET("/events", handler.ListWebhookEvents)
+				webhooks.GET("/endpoints", handler.ListWebhookEndpoints)
+				webhooks.POST("/endpoints", handler.CreateWebhookEndpoint)
+				webhooks.PUT("/endpoints/:id", handler.UpdateWebhookEndpoint)
+				webhooks.DELETE("/endpoints/:id", handler.DeleteWebhookEndpoint)
+				webhooks.POST("/endpoints/:id/rotate", handler.RotateWebhookSecret)
+				webhooks.POST("/endpoints/:id/ping", handler.PingWebhookEndpoint)
+				webhooks.GET("/deliveries", handler.ListWebhookDeliveries)
+				webhooks.POST("/deliveries/replay", handler.ReplayFailedWebhookDeliveries)
+				webhooks.GET("/deliveries/:id", handler.GetWebhookDelivery)
+				webhooks.POST("/deliveries/:id/replay", handler.ReplayWebhookDelivery)
+			}
+
+			// 操作审计（仅超级管理员）
+			audit := auth.Group("/audit")
+			audit.Use(middleware.RequireRole("super_admin"), middleware.RequireUnscoped())
+			{
+				audit.GET("/operations", handler.ListOperationLogs)
+				audit.GET("/operations/:id", handler.GetOperationLog)
+			}
+
+			// 请求审计日志（存储在 MongoDB，仅超级管理员）
+			auditLogs := auth.Group("/audit-logs")
+			auditLogs.Use(middleware.RequireRole("super_admin"), middleware.RequireUnscoped())
+			{
+				auditLogs.GET("", handler.ListAuditLogs)
+				auditLogs.GET("/:id", handler.GetAuditLog)
+			}
+
+			// 安全状态（当前及历史快照、安全事件与告警，仅超级管理员）
+			security := auth.Group("/security")
+			security.Use(middleware.RequireRole("super_admin"), middleware.RequireUnscoped())
+			{
+				security.GET("/state", handler.GetSecurityState)
+				security.GET("/snapshots", handler.ListSecuritySnapshots)
+				security.POST("/snapshots", handler.CreateSecuritySnapshot)
+				security.GET("/snapshots/:id", handler.GetSecuritySnapshot)
+				security.GET("/events", handler.ListSecurityEvents)
+				security.POST("/alerts/test", handler.TestSecurityAlert)
+				security.GET("/login-locks", handler.GetLoginLock)
+				security.DELETE("/login-locks", handler.UnlockLogin)
+				security.GET("/login-history", handler.ListLoginHistory)
+			}
+
+			// WAF 规则（仅超级管理员）
+			waf := auth.Group("/waf")
+			waf.Use(middleware.RequireRole("super_admin"), middleware.RequireUnscoped())
+			{
+				waf.GET("/rules", handler.ListWAFRules)
+				waf.POST("/rules", handler.CreateWAFRule)
+				waf.PUT("/rules/:id", handler.UpdateWAFRule)
+				waf.DELETE("/rules/:id", handler.DeleteWAFRule)
+				waf.GET("/active", handler.ListActiveWAFRules)
+				waf.POST("/reload", handler.ReloadWAFRules)
+			}
+
+			// 系统设置（仅超级管理员）
+			systemSettings := auth.Group("/settings")
+			systemSettings.Use(middleware.RequireRole("super_admin"), middleware.RequireUnscoped())
+			{
+				systemSettings.GET("", handler.ListSettings)
+				systemSettings.POST("", handler.CreateSetting)
+				systemSettings.GET("/:key", handler.GetSetting)
+				systemSettings.PUT("/:key", handler.UpdateSetting)
+				systemSettings.DELETE("/:key", handler.DeleteSetting)
+			}
+
+			// 自动封禁记录复核（仅超级管理员）
+			ipBans := auth.Group("/ip-bans")
+			ipBans.Use(middleware.RequireRole("super_admin"), middleware.RequireUnscoped())
+			{
+				ipBans.GET("", handler.ListIPBans)
+				ipBans.POST("/:id/lift", handler.LiftIPBan)
+			}
+
+			// 资源标签：管理员、AppKey、IP 规则（仅超级管理员）
+			tagGroup := auth.Group("/tags")
+			tagGroup.Use(middleware.RequireRole("super_admin"), middleware.RequireUnscoped())
+			{
+				tagGroup.GET("", handler.ListTags)
+				tagGroup.DELETE("/:name", handler.DeleteTag)
+				tagGroup.GET("/:name/resources", handler.ListTagResources)
+			}
+			resourceTags := auth.Group("/resource-tags")
+			resourceTags.Use(middleware.RequireRole("super_admin"), middleware.RequireUnscoped())
+			{
+				resourceTags.GET("/:type/:id", handler.GetResourceTags)
+				resourceTags.POST("/:type/:id", handler.AddResourceTags)
+				resourceTags.DELETE("/:type/:id", handler.RemoveResourceTags)
+			}
+
+			// 合作方载荷转换模板（仅超级管理员）
+			transforms := auth.Group("/transforms")
+			transforms.Use(middleware.RequireRole("super_admin"))
+			{
+				transforms.GET("", handler.ListTransforms)
+				transforms.POST("/preview", handler.PreviewTransform)
+				transforms.PUT("/:app_key/:direction", handler.SetTransform)
+				transforms.DELETE("/:app_key/:direction", handler.DeleteTransform)
+			}
+
+			// 请求重放（仅超级管理员）
+			auth.POST("/replay/:request_id", middleware.RequireRole("super_admin"), middleware.RequireUnscoped(), handler.ReplayRequest)
+
+			// 定时任务（仅超级管理员）
+			jobs := auth.Group("/jobs")
+			jobs.Use(middleware.RequireRole("super_admin"), middleware.RequireUnscoped())
+			{
+				jobs.GET("", handler.ListJobs)
+				jobs.GET("/:name", handler.GetJob)
+				jobs.POST("/:name/trigger", handler.TriggerJob)
+				jobs.POST("/:name/pause", handler.PauseJob)
+				jobs.POST("/:name/resume", handler.ResumeJob)
+			}
+
+			// API Key 签发、轮换与吊销（仅超级管理员）
+			apiKeys := auth.Group("/api-keys")
+			apiKeys.Use(middleware.RequireRole("super_admin"), middleware.RequireUnscoped())
+			{
+				apiKeys.GET("", handler.ListAPIKeys)
+				apiKeys.POST("", handler.CreateAPIKey)
+				apiKeys.GET("/:id", handler.GetAPIKey)
+				apiKeys.POST("/:id/rotate", handler.RotateAPIKey)
+				apiKeys.POST("/:id/revoke", handler.RevokeAPIKey)
+			}
+
+			// 维护模式（仅超级管理员）
+			maintenance := auth.Group("/maintenance")
+			maintenance.Use(middleware.RequireRole("super_admin"), middleware.RequireUnscoped())
+			{
+				maintenance.GET("", handler.GetMaintenance)
+				maintenance.PUT("", handler.EnableMaintenance)
+				maintenance.DELETE("", handler.DisableMaintenance)
+			}
+
+			// 系统信息（仅超级管理员）
+			system := auth.Group("/system")
+			system.Use(middleware.RequireRole("super_admin"), middleware.RequireUnscoped())
+			{
+				system.GET("/info", handler.SystemInfo)
 			}
 		}
 	}
@@ -0,0 +1,169 @@
+// Package scope 管理员的数据范围：在角色权限之外，把可见、可改的 AppKey（客户）
+// 限定到指定租户、组织或标签（用户分群），由各仓储查询统一追加条件
+package scope
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/tags"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MaxEntries 每类范围的数量上限
+const MaxEntries = 50
+
+// ErrInvalid 数据范围格式错误
+var ErrInvalid = errors.New("无效的数据范围")
+
+// Of 当前管理员的数据范围（nil 表示不限制）
+func Of(c *gin.Context) *model.AdminScope {
+	admin := middleware.GetCurrentAdmin(c)
+	if admin == nil || admin.Scope.Empty() {
+		return nil
+	}
+	return admin.Scope
+}
+
+// Normalize 校验并规范化数据范围（去除空白、去重，标签名转小写），全部为空时返回 nil
+func Normalize(s *model.AdminScope) (*model.AdminScope, error) {
+	if s == nil {
+		return nil, nil
+	}
+	tenants, err := normalizeNames("tenants", s.Tenants, 64)
+	if err != nil {
+		return nil, err
+	}
+	organizations, err := normalizeNames("organizations", s.Organizations, 100)
+	if err != nil {
+		return nil, err
+	}
+	tagNames, err := tags.Normalize(s.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if len(tagNames) > MaxEntries {
+		return nil, fmt.Errorf("%w: tags 最多 %d 项", ErrInvalid, MaxEntries)
+	}
+	if len(tagNames) == 0 {
+		tagNames = nil
+	}
+
+	result := &model.AdminScope{Tenants: tenants, Organizations: organizations, Tags: tagNames}
+	if result.Empty() {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// Contains AppKey 是否在数据范围内（租户、组织或标签命中任意一项）
+func Contains(s *model.AdminScope, tenant, organization string, keyTags []string) bool {
+	if s.Empty() {
+		return true
+	}
+	if tenant != "" && contains(s.Tenants, tenant) {
+		return true
+	}
+	if organization != "" && contains(s.Organizations, organization) {
+		return true
+	}
+	for _, tag := range keyTags {
+		if contains(s.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// AppKeys app_keys 表的查询条件：只保留范围内的 AppKey
+func AppKeys(s *model.AdminScope) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if s.Empty() {
+			return db
+		}
+
+		var conds []string
+		var args []interface{}
+		if len(s.Tenants) > 0 {
+			conds = append(conds, "app_keys.tenant IN ?")
+			args = append(args, s.Tenants)
+		}
+		if len(s.Organizations) > 0 {
+			conds = append(conds, "app_keys.organization IN ?")
+			args = append(args, s.Organizations)
+		}
+		if len(s.Tags) > 0 {
+			tagged := db.Session(&gorm.Session{NewDB: true}).Table("taggings").
+				Select("taggings.resource_id").
+				Joins("JOIN tags ON tags.id = taggings.tag_id").
+				Where("taggings.resource_type = ? AND tags.name IN ?", tags.ResourceAppKey, s.Tags)
+			conds = append(conds, "app_keys.id IN (?)")
+			args = append(args, tagged)
+		}
+		return db.Where("("+strings.Join(conds, " OR ")+")", args...)
+	}
+}
+
+// ByAppKey 以 app_key 列关联 AppKey 的表（配额、载荷转换模板）的查询条件
+func ByAppKey(s *model.AdminScope) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if s.Empty() {
+			return db
+		}
+		keys := db.Session(&gorm.Session{NewDB: true}).Model(&model.AppKey{}).
+			Select("app_keys.app_key").Scopes(AppKeys(s))
+		return db.Where("app_key IN (?)", keys)
+	}
+}
+
+// AllowsAppKey AppKey 是否已登记且在数据范围内（不限制范围时总是返回 true）
+func AllowsAppKey(ctx context.Context, s *model.AdminScope, appKey string) (bool, error) {
+	if s.Empty() {
+		return true, nil
+	}
+	db := database.GetMySQL()
+	if db == nil {
+		return false, errors.New("数据库未连接")
+	}
+	var count int64
+	err := db.WithContext(ctx).Model(&model.AppKey{}).Scopes(AppKeys(s)).
+		Where("app_keys.app_key = ?", appKey).Count(&count).Error
+	return count > 0, err
+}
+
+// normalizeNames 去除空白、去重并校验长度
+func normalizeNames(field string, names []string, maxLen int) ([]string, error) {
+	seen := make(map[string]bool, len(names))
+	var result []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if len(name) > maxLen {
+			return nil, fmt.Errorf("%w: %s 中的 %q 超过 %d 个字符", ErrInvalid, field, name, maxLen)
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+	if len(result) > MaxEntries {
+		return nil, fmt.Errorf("%w: %s 最多 %d 项", ErrInvalid, field, MaxEntries)
+	}
+	return result, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,377 @@
+package views
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/tags"
+
+	"gorm.io/gorm"
+)
+
+// 支持保存视图的列表
+const (
+	ListAdmins        = "admins"
+	ListOperationLogs = "operation_logs"
+)
+
+// MaxViews 每个管理员每个列表的视图数量上限
+const MaxViews = 50
+
+var (
+	// ErrUnavailable 数据库未连接
+	ErrUnavailable = errors.New("数据库未连接")
+	// ErrNotFound 视图不存在
+	ErrNotFound = errors.New("视图不存在")
+	// ErrInvalid 列表、筛选字段或排序不合法
+	ErrInvalid = errors.New("参数错误")
+)
+
+// 筛选值类型
+const (
+	KindString = "string"
+	KindInt    = "int"
+	KindTime   = "time"
+)
+
+// Filter 列表支持的筛选字段
+type Filter struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	Kind  string `json:"kind"`
+
+	// column 与 op（=、LIKE、>=、<=）组成查询条件；columns 不为空时在多列中模糊匹配（任一列包含即可）；
+	// scope 不为空时优先使用
+	column  string
+	op      string
+	columns []string
+	scope   func(value string) func(*gorm.DB) *gorm.DB
+}
+
+// Spec 列表定义：可用的筛选字段与排序字段
+type Spec struct {
+	Name    string   `json:"name"`
+	Title   string   `json:"title"`
+	Filters []Filter `json:"filters"`
+	Sorts   []string `json:"sorts"`
+	// 未指定排序时使用
+	DefaultSort string `json:"default_sort"`
+	// 仅超级管理员可用（与列表接口的权限一致）
+	SuperAdminOnly bool `json:"super_admin_only"`
+}
+
+// specs 列表定义
+var specs = []Spec{
+	{
+		Name:  ListAdmins,
+		Title: "管理员",
+		Filters: []Filter{
+			{Name: "keyword", Title: "关键字（用户名、昵称、邮箱模糊）", Kind: KindString, columns: []string{"username", "nickname", "email"}},
+			{Name: "username", Title: "用户名（模糊）", Kind: KindString, column: "username", op: "LIKE"},
+			{Name: "nickname", Title: "昵称（模糊）", Kind: KindString, column: "nickname", op: "LIKE"},
+			{Name: "email", Title: "邮箱（模糊）", Kind: KindString, column: "email", op: "LIKE"},
+			{Name: "role", Title: "角色", Kind: KindString, column: "role", op: "="},
+			{Name: "status", Title: "状态（1 启用，0 禁用）", Kind: KindInt, column: "status", op: "="},
+			{Name: "tag", Title: "标签（逗号分隔，需同时带有）", Kind: KindString, scope: func(value string) func(*gorm.DB) *gorm.DB {
+				return tags.Filter(tags.ResourceAdmin, value)
+			}},
+			{Name: "created_from", Title: "创建时间起", Kind: KindTime, column: "created_at", op: ">="},
+			{Name: "created_to", Title: "创建时间止", Kind: KindTime, column: "created_at", op: "<="},
+		},
+		Sorts:          []string{"id", "username", "nickname", "email", "role", "status", "created_at", "updated_at", "last_login"},
+		DefaultSort:    "id",
+		SuperAdminOnly: true,
+	},
+	{
+		Name:  ListOperationLogs,
+		Title: "操作审计",
+		Filters: []Filter{
+			{Name: "keyword", Title: "关键字（用户名、路径模糊）", Kind: KindString, columns: []string{"username", "path"}},
+			{Name: "admin_id", Title: "管理员 ID", Kind: KindInt, column: "admin_id", op: "="},
+			{Name: "username", Title: "用户名", Kind: KindString, column: "username", op: "="},
+			{Name: "action", Title: "操作（如 DELETE /admin/admins/:id）", Kind: KindString, column: "action", op: "="},
+			{Name: "method", Title: "请求方法", Kind: KindString, column: "method", op: "="},
+			{Name: "resource", Title: "资源（如 admins、app-keys）", Kind: KindString, column: "resource", op: "="},
+			{Name: "target_id", Title: "操作对象 ID", Kind: KindString, column: "target_id", op: "="},
+			{Name: "path", Title: "路径（模糊）", Kind: KindString, column: "path", op: "LIKE"},
+			{Name: "status", Title: "响应状态码", Kind: KindInt, column: "status", op: "="},
+			{Name: "status_min", Title: "响应状态码下限（如 400 只看失败）", Kind: KindInt, column: "status", op: ">="},
+			{Name: "ip", Title: "IP", Kind: KindString, column: "ip", op: "="},
+			{Name: "impersonator_id", Title: "模拟登录的发起人 ID（只看模拟期间的操作）", Kind: KindInt, column: "impersonator_id", op: "="},
+			{Name: "from", Title: "时间起", Kind: KindTime, column: "created_at", op: ">="},
+			{Name: "to", Title: "时间止", Kind: KindTime, column: "created_at", op: "<="},
+		},
+		Sorts:          []string{"id", "created_at", "status", "username", "action"},
+		DefaultSort:    "-id",
+		SuperAdminOnly: true,
+	},
+}
+
+// Specs 角色可用的列表定义
+func Specs(role string) []Spec {
+	var list []Spec
+	for _, spec := range specs {
+		if !spec.SuperAdminOnly || role == "super_admin" {
+			list = append(list, spec)
+		}
+	}
+	return list
+}
+
+// lookup 查找角色可用的列表定义
+func lookup(list, role string) (Spec, error) {
+	for _, spec := range Specs(role) {
+		if spec.Name == list {
+			return spec, nil
+		}
+	}
+	return Spec{}, fmt.Errorf("%w: 未知的列表 %s", ErrInvalid, list)
+}
+
+// Validate 校验视图的列表、筛选条件与排序
+func Validate(v *model.SavedView, role string) error {
+	spec, err := lookup(v.List, role)
+	if err != nil {
+		return err
+	}
+	for name, value := range v.Filters {
+		f, ok := spec.filter(name)
+		if !ok {
+			return fmt.Errorf("%w: 列表 %s 不支持筛选字段 %s", ErrInvalid, v.List, name)
+		}
+		if _, err := f.parse(value); err != nil {
+			return err
+		}
+	}
+	return spec.validSort(v.Sort)
+}
+
+// Apply 将筛选条件与排序应用到列表查询（params 为请求参数，覆盖视图中的同名筛选；sort 为空时使用视图或列表默认排序，
+// order 为 asc、desc 时覆盖排序方向）
+func Apply(query *gorm.DB, list, role string, view *model.SavedView, params url.Values) (*gorm.DB, error) {
+	spec, err := lookup(list, role)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := make(map[string]string)
+	sortBy := spec.DefaultSort
+	if view != nil {
+		for name, value := range view.Filters {
+			filters[name] = value
+		}
+		if view.Sort != "" {
+			sortBy = view.Sort
+		}
+	}
+	for _, f := range spec.Filters {
+		if value := params.Get(f.Name); value != "" {
+			filters[f.Name] = value
+		}
+	}
+	if s := params.Get("sort"); s != "" {
+		sortBy = s
+	}
+	switch strings.ToLower(params.Get("order")) {
+	case "":
+	case "asc":
+		sortBy = strings.TrimPrefix(sortBy, "-")
+	case "desc":
+		sortBy = "-" + strings.TrimPrefix(sortBy, "-")
+	default:
+		return nil, fmt.Errorf("%w: order 只能为 asc 或 desc", ErrInvalid)
+	}
+
+	// 按字段名排序，保证生成的 SQL 稳定
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f, ok := spec.filter(name)
+		if !ok {
+			// 列表定义移除了字段，忽略旧视图中的条件
+			continue
+		}
+		value, err := f.parse(filters[name])
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case f.scope != nil:
+			query = query.Scopes(f.scope(filters[name]))
+		case len(f.columns) > 0:
+			conds := make([]string, len(f.columns))
+			args := make([]interface{}, len(f.columns))
+			for i, column := range f.columns {
+				conds[i] = column + " LIKE ? ESCAPE '!'"
+				args[i] = contains(filters[name])
+			}
+			query = query.Where("("+strings.Join(conds, " OR ")+")", args...)
+		case f.op == "LIKE":
+			query = query.Where(f.column+" LIKE ? ESCAPE '!'", contains(filters[name]))
+		default:
+			query = query.Where(f.column+" "+f.op+" ?", value)
+		}
+	}
+
+	if err := spec.validSort(sortBy); err != nil {
+		return nil, err
+	}
+	field, direction := sortBy, "ASC"
+	if strings.HasPrefix(sortBy, "-") {
+		field, direction = strings.TrimPrefix(sortBy, "-"), "DESC"
+	}
+	query = query.Order(field + " " + direction)
+	// 排序字段有重复值时按 id 排序，保证分页结果稳定
+	if field != "id" {
+		query = query.Order("id " + direction)
+	}
+	return query, nil
+}
+
+// likeEscaper 转义 LIKE 中的通配符（以 ! 为转义字符，MySQL 与 SQLite 写法一致）
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// contains 模糊匹配的 LIKE 参数：用户输入的 %、_ 按字面匹配
+func contains(value string) string {
+	return "%" + likeEscaper.Replace(value) + "%"
+}
+
+// filter 查找筛选字段
+func (s Spec) filter(name string) (Filter, bool) {
+	for _, f := range s.Filters {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Filter{}, false
+}
+
+// validSort 检查排序字段（为空表示使用默认排序）
+func (s Spec) validSort(value string) error {
+	if value == "" {
+		return nil
+	}
+	field := strings.TrimPrefix(value, "-")
+	for _, allowed := range s.Sorts {
+		if allowed == field {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: 列表 %s 不支持按 %s 排序（可选 %s）", ErrInvalid, s.Name, field, strings.Join(s.Sorts, ", "))
+}
+
+// parse 按类型解析筛选值（时间支持 RFC3339 或 2006-01-02）
+func (f Filter) parse(value string) (interface{}, error) {
+	switch f.Kind {
+	case KindInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: 筛选字段 %s 应为整数", ErrInvalid, f.Name)
+		}
+		return n, nil
+	case KindTime:
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, nil
+		}
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("%w: 筛选字段 %s 应为 RFC3339 时间或日期", ErrInvalid, f.Name)
+		}
+		return t, nil
+	default:
+		if len(value) > 255 {
+			return nil, fmt.Errorf("%w: 筛选字段 %s 过长", ErrInvalid, f.Name)
+		}
+		return value, nil
+	}
+}
+
+// List 获取管理员的视图（list 为空时返回全部）
+func List(ctx context.Context, adminID uint, list string) ([]model.SavedView, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	query := db.WithContext(ctx).Where("admin_id = ?", adminID)
+	if list != "" {
+		query = query.Where("list = ?", list)
+	}
+	result := []model.SavedView{}
+	if err := query.Order("list ASC, name ASC").Find(&result).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Get 获取管理员的视图
+func Get(ctx context.Context, adminID uint, id string) (*model.SavedView, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	viewID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	var v model.SavedView
+	err = db.WithContext(ctx).Where("id = ? AND admin_id = ?", viewID, adminID).First(&v).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Save 创建或更新视图（v.ID 为 0 时创建），同一列表下名称不可重复
+func Save(ctx context.Context, v *model.SavedView) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+
+	var count int64
+	if err := db.WithContext(ctx).Model(&model.SavedView{}).
+		Where("admin_id = ? AND list = ? AND name = ? AND id <> ?", v.AdminID, v.List, v.Name, v.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: 视图名称已存在: %s", ErrInvalid, v.Name)
+	}
+	if v.ID == 0 {
+		if err := db.WithContext(ctx).Model(&model.SavedView{}).
+			Where("admin_id = ? AND list = ?", v.AdminID, v.List).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= MaxViews {
+			return fmt.Errorf("%w: 每个列表最多保存 %d 个视图", ErrInvalid, MaxViews)
+		}
+	}
+	if v.Filters == nil {
+		v.Filters = map[string]string{}
+	}
+	return db.WithContext(ctx).Save(v).Error
+}
+
+// Delete 删除管理员的视图
+func Delete(ctx context.Context, adminID uint, id string) error {
+	v, err := Get(ctx, adminID, id)
+	if err != nil {
+		return err
+	}
+	return database.GetMySQL().WithContext(ctx).Delete(v).Error
+}
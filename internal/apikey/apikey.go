@@ -0,0 +1,215 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/store"
+	"new-openclaw/pkg/config"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound API Key 不存在
+	ErrNotFound = errors.New("无效的 API Key")
+	// ErrRevoked API Key 已吊销
+	ErrRevoked = errors.New("API Key 已吊销")
+	// ErrExpired API Key 已过期（含轮换宽限期已过的旧 Key）
+	ErrExpired = errors.New("API Key 已过期")
+	// ErrUnavailable 数据库不可用等原因无法完成校验
+	ErrUnavailable = errors.New("API Key 校验暂不可用")
+)
+
+// keyPrefix API Key 的固定前缀，便于在代码仓库、日志中扫描泄露的 Key
+const keyPrefix = "sk_"
+
+// keyLength Key 的总长度（前缀 + 20 字节随机数的十六进制）
+const keyLength = len(keyPrefix) + 40
+
+// displayLength 列表、日志中展示的 Key 前缀长度
+const displayLength = len(keyPrefix) + 8
+
+// missingTTL 不存在的 Key 的缓存时间（防止无效 Key 反复查库）
+const missingTTL = 30 * time.Second
+
+// cfg API Key 配置
+var cfg = config.APIKeyConfig{
+	CacheTTL:         5 * time.Minute,
+	LastUsedInterval: time.Minute,
+}
+
+// Configure 设置缓存时间及最近使用时间的写库间隔
+func Configure(c config.APIKeyConfig) {
+	cfg = c
+}
+
+// Identity 通过认证的 API Key
+type Identity struct {
+	ID     uint
+	Name   string
+	Prefix string
+	Scopes []string
+}
+
+// credential 缓存的校验结果（缓存键为 Key 的摘要，不含明文）
+type credential struct {
+	Missing   bool       `json:"missing,omitempty"`
+	ID        uint       `json:"id,omitempty"`
+	Name      string     `json:"name,omitempty"`
+	Prefix    string     `json:"prefix,omitempty"`
+	Scopes    []string   `json:"scopes,omitempty"`
+	Status    int        `json:"status"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// 以轮换前的旧 Key 命中时，旧 Key 的失效时间
+	GraceUntil *time.Time `json:"grace_until,omitempty"`
+}
+
+// Authenticate 校验 API Key 的状态与有效期，并按间隔记录最近使用时间与调用方 IP
+func Authenticate(ctx context.Context, key, clientIP string) (*Identity, error) {
+	if !strings.HasPrefix(key, keyPrefix) || len(key) != keyLength {
+		return nil, ErrNotFound
+	}
+
+	cred, err := lookup(ctx, Hash(key))
+	if err != nil {
+		log.Printf("API Key 校验失败: prefix=%s err=%v", key[:displayLength], err)
+		return nil, ErrUnavailable
+	}
+
+	now := time.Now()
+	switch {
+	case cred.Missing:
+		return nil, ErrNotFound
+	case cred.Status != model.APIKeyStatusActive:
+		return nil, ErrRevoked
+	case cred.ExpiresAt != nil && now.After(*cred.ExpiresAt):
+		return nil, ErrExpired
+	case cred.GraceUntil != nil && now.After(*cred.GraceUntil):
+		return nil, ErrExpired
+	}
+
+	touch(ctx, cred.ID, clientIP, now)
+	return &Identity{
+		ID:     cred.ID,
+		Name:   cred.Name,
+		Prefix: cred.Prefix,
+		Scopes: cred.Scopes,
+	}, nil
+}
+
+// Invalidate 清除 Key 摘要对应的缓存（管理端吊销、轮换后调用）
+func Invalidate(ctx context.Context, hashes ...string) error {
+	keys := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		if hash != "" {
+			keys = append(keys, "key:"+hash)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return store.For(store.ComponentAPIKey).Del(ctx, keys...)
+}
+
+// lookup 先查缓存，未命中时查库（当前 Key 或宽限期内的旧 Key）并写入缓存
+func lookup(ctx context.Context, hash string) (*credential, error) {
+	s := store.For(store.ComponentAPIKey)
+
+	if raw, err := s.Get(ctx, "key:"+hash); err == nil {
+		var cached credential
+		if json.Unmarshal([]byte(raw), &cached) == nil {
+			return &cached, nil
+		}
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, errors.New("数据库未连接")
+	}
+
+	var key model.APIKey
+	var graceUntil *time.Time
+	err := db.Where("key_hash = ?", hash).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = db.Where("previous_key_hash = ? AND previous_expires_at > ?", hash, time.Now()).First(&key).Error
+		graceUntil = key.PreviousExpiresAt
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		cred := &credential{Missing: true}
+		cache(ctx, s, hash, cred, missingTTL)
+		return cred, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cred := &credential{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		Status:     key.Status,
+		ExpiresAt:  key.ExpiresAt,
+		GraceUntil: graceUntil,
+	}
+	cache(ctx, s, hash, cred, cfg.CacheTTL)
+	return cred, nil
+}
+
+// cache 写入缓存（失败只影响性能，不影响认证）
+func cache(ctx context.Context, s store.Store, hash string, cred *credential, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return
+	}
+	s.Set(ctx, "key:"+hash, string(data), ttl)
+}
+
+// touch 更新最近使用时间与 IP；同一 Key 在 LastUsedInterval 内只写一次库
+func touch(ctx context.Context, id uint, clientIP string, now time.Time) {
+	if cfg.LastUsedInterval > 0 {
+		acquired, err := store.For(store.ComponentAPIKey).SetNX(ctx, "used:"+strconv.FormatUint(uint64(id), 10), "1", cfg.LastUsedInterval)
+		if err != nil || !acquired {
+			return
+		}
+	}
+	db := database.GetMySQL()
+	if db == nil {
+		return
+	}
+	// 不更新 updated_at（只反映管理端的修改）
+	db.Model(&model.APIKey{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"last_used_at": now,
+		"last_used_ip": clientIP,
+	})
+}
+
+// Generate 生成新的 API Key，返回明文、展示用前缀及摘要（只有摘要入库）
+func Generate() (key, prefix, hash string, err error) {
+	b := make([]byte, 20)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	key = keyPrefix + hex.EncodeToString(b)
+	return key, key[:displayLength], Hash(key), nil
+}
+
+// Hash Key 的 SHA-256 摘要（Key 为高熵随机数，无需加盐的慢哈希）
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
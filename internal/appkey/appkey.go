@@ -0,0 +1,234 @@
+package appkey
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/store"
+	"new-openclaw/pkg/config"
+	"new-openclaw/pkg/secrets"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound AppKey 不存在
+	ErrNotFound = errors.New("无效的 AppKey")
+	// ErrDisabled AppKey 已禁用
+	ErrDisabled = errors.New("AppKey 已禁用")
+	// ErrExpired AppKey 已过期
+	ErrExpired = errors.New("AppKey 已过期")
+	// ErrIPNotAllowed 调用方 IP 不在 AppKey 允许列表中
+	ErrIPNotAllowed = errors.New("当前 IP 不允许使用该 AppKey")
+)
+
+// missingTTL 不存在的 AppKey 的缓存时间（防止无效 Key 反复查库）
+const missingTTL = 30 * time.Second
+
+// cfg AppKey 配置
+var cfg = config.AppKeyConfig{
+	CacheTTL:       5 * time.Minute,
+	GlobalFallback: true,
+}
+
+// Configure 设置缓存时间及未登记 AppKey 是否回退到全局签名密钥
+func Configure(c config.AppKeyConfig) {
+	cfg = c
+}
+
+// credential 缓存的凭证（密钥以 KEK 加密后写入缓存）
+type credential struct {
+	Missing           bool       `json:"missing,omitempty"`
+	Secret            string     `json:"secret,omitempty"`
+	PreviousSecret    string     `json:"previous_secret,omitempty"`
+	PreviousExpiresAt *time.Time `json:"previous_expires_at,omitempty"`
+	Status            int        `json:"status"`
+	AllowedIPs        string     `json:"allowed_ips,omitempty"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+}
+
+// Resolve 查找 AppKey 当前可用的签名密钥（轮换宽限期内包含旧密钥），并校验状态、有效期及调用方 IP。
+// 未携带或未登记的 AppKey 在允许回退时返回空列表（由调用方使用全局密钥）
+func Resolve(ctx context.Context, appKey, clientIP string) ([]string, error) {
+	cred := &credential{Missing: true}
+	if appKey != "" {
+		var err error
+		if cred, err = lookup(ctx, appKey); err != nil {
+			return nil, err
+		}
+	}
+	if cred.Missing {
+		if cfg.GlobalFallback {
+			return nil, nil
+		}
+		return nil, ErrNotFound
+	}
+
+	now := time.Now()
+	switch {
+	case cred.Status != model.AppKeyStatusActive:
+		return nil, ErrDisabled
+	case cred.ExpiresAt != nil && now.After(*cred.ExpiresAt):
+		return nil, ErrExpired
+	case !AllowsIP(cred.AllowedIPs, clientIP):
+		return nil, ErrIPNotAllowed
+	}
+
+	result := []string{cred.Secret}
+	if cred.PreviousSecret != "" && cred.PreviousExpiresAt != nil && now.Before(*cred.PreviousExpiresAt) {
+		result = append(result, cred.PreviousSecret)
+	}
+	return result, nil
+}
+
+// Invalidate 清除 AppKey 缓存（管理端修改后调用）
+func Invalidate(ctx context.Context, appKey string) error {
+	return store.For(store.ComponentAppKey).Del(ctx, appKey)
+}
+
+// lookup 先查缓存，未命中时查库并写入缓存
+func lookup(ctx context.Context, appKey string) (*credential, error) {
+	s := store.For(store.ComponentAppKey)
+
+	if raw, err := s.Get(ctx, appKey); err == nil {
+		var cached credential
+		if json.Unmarshal([]byte(raw), &cached) == nil && decrypt(&cached) == nil {
+			return &cached, nil
+		}
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		// 数据库不可用时无法区分 AppKey 是否登记，按未登记处理
+		return &credential{Missing: true}, nil
+	}
+
+	var key model.AppKey
+	err := db.Where("app_key = ?", appKey).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		cred := &credential{Missing: true}
+		cache(ctx, s, appKey, cred, missingTTL)
+		return cred, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cred := &credential{
+		Secret:            key.Secret.String(),
+		PreviousSecret:    key.PreviousSecret.String(),
+		PreviousExpiresAt: key.PreviousExpiresAt,
+		Status:            key.Status,
+		AllowedIPs:        key.AllowedIPs,
+		ExpiresAt:         key.ExpiresAt,
+	}
+	cache(ctx, s, appKey, cred, cfg.CacheTTL)
+	return cred, nil
+}
+
+// cache 写入缓存（失败只影响性能，不影响验签）
+func cache(ctx context.Context, s store.Store, appKey string, cred *credential, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	encrypted := *cred
+	var err error
+	if encrypted.Secret, err = secrets.Encrypt(cred.Secret); err != nil {
+		return
+	}
+	if encrypted.PreviousSecret, err = secrets.Encrypt(cred.PreviousSecret); err != nil {
+		return
+	}
+	data, err := json.Marshal(encrypted)
+	if err != nil {
+		return
+	}
+	s.Set(ctx, appKey, string(data), ttl)
+}
+
+// decrypt 解密缓存中的密钥
+func decrypt(cred *credential) error {
+	var err error
+	if cred.Secret, err = secrets.Decrypt(cred.Secret); err != nil {
+		return err
+	}
+	cred.PreviousSecret, err = secrets.Decrypt(cred.PreviousSecret)
+	return err
+}
+
+// AllowsIP 检查 IP 是否在允许列表（逗号分隔的 IP/CIDR，为空不限制）中
+func AllowsIP(allowed, ip string) bool {
+	if strings.TrimSpace(allowed) == "" {
+		return true
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, item := range strings.Split(allowed, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.Contains(item, "/") {
+			if _, ipNet, err := net.ParseCIDR(item); err == nil && ipNet.Contains(parsed) {
+				return true
+			}
+			continue
+		}
+		if allowedIP := net.ParseIP(item); allowedIP != nil && allowedIP.Equal(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateAllowedIPs 校验允许列表格式，返回规范化后的逗号分隔字符串
+func ValidateAllowedIPs(items []string) (string, error) {
+	var result []string
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.Contains(item, "/") {
+			if _, _, err := net.ParseCIDR(item); err != nil {
+				return "", errors.New("无效的 CIDR: " + item)
+			}
+		} else if net.ParseIP(item) == nil {
+			return "", errors.New("无效的 IP: " + item)
+		}
+		result = append(result, item)
+	}
+	return strings.Join(result, ","), nil
+}
+
+// GenerateKey 生成 AppKey
+func GenerateKey() (string, error) {
+	key, err := randomHex(12)
+	if err != nil {
+		return "", err
+	}
+	return "ak_" + key, nil
+}
+
+// GenerateSecret 生成签名密钥
+func GenerateSecret() (string, error) {
+	return randomHex(32)
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,144 @@
+// Package auditsink 审计日志的外部输出：Elasticsearch 批量索引、Kafka（经 REST Proxy）、syslog 与 MongoDB，
+// 按配置组合后交给 middleware.AuditConfig.Sinks，由审计日志记录器批量发送
+package auditsink
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/middleware"
+	"new-openclaw/pkg/config"
+)
+
+// 输出名称（AUDIT_SINKS 的取值）
+const (
+	Elasticsearch = "elasticsearch"
+	Kafka         = "kafka"
+	Syslog        = "syslog"
+	MongoDB       = "mongodb"
+)
+
+// FromConfig 按配置创建启用的输出（名称重复时只创建一次），任一输出配置有误时返回错误
+func FromConfig(cfg config.AuditSinksConfig) ([]middleware.AuditSink, error) {
+	client := &http.Client{Timeout: cfg.Timeout}
+
+	var sinks []middleware.AuditSink
+	seen := make(map[string]bool)
+	for _, name := range cfg.Enabled {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		var (
+			sink middleware.AuditSink
+			err  error
+		)
+		switch name {
+		case Elasticsearch:
+			sink, err = NewElasticsearch(ElasticsearchConfig{
+				URL:      cfg.ElasticsearchURL,
+				Index:    cfg.ElasticsearchIndex,
+				Username: cfg.ElasticsearchUsername,
+				Password: cfg.ElasticsearchPassword,
+				APIKey:   cfg.ElasticsearchAPIKey,
+				Client:   client,
+			})
+		case Kafka:
+			sink, err = NewKafka(KafkaConfig{
+				RESTURL: cfg.KafkaRESTURL,
+				Topic:   cfg.KafkaTopic,
+				Client:  client,
+			})
+		case Syslog:
+			sink, err = NewSyslog(SyslogConfig{
+				Addr:     cfg.SyslogAddr,
+				Tag:      cfg.SyslogTag,
+				Facility: cfg.SyslogFacility,
+				Timeout:  cfg.Timeout,
+			})
+		case MongoDB:
+			sink = NewMongoDB()
+		default:
+			err = fmt.Errorf("未知的审计日志输出 %q（可选 elasticsearch、kafka、syslog、mongodb）", name)
+		}
+		if err != nil {
+			for _, created := range sinks {
+				created.Close()
+			}
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+// Options 批量写入参数
+func Options(cfg config.AuditSinksConfig) middleware.AuditSinkOptions {
+	options := middleware.DefaultAuditSinkOptions
+	if cfg.BatchSize > 0 {
+		options.BatchSize = cfg.BatchSize
+	}
+	if cfg.FlushInterval > 0 {
+		options.FlushInterval = cfg.FlushInterval
+	}
+	if cfg.BufferSize > 0 {
+		options.BufferSize = cfg.BufferSize
+	}
+	if cfg.Timeout > 0 {
+		options.Timeout = cfg.Timeout
+	}
+	return options
+}
+
+// partialRetries 部分条目被拒绝时的重发次数
+const partialRetries = 2
+
+// sendFunc 发送一批日志，返回被拒绝且可以重试的条目；整个请求失败时返回错误
+type sendFunc func(ctx context.Context, logs []*middleware.AuditLog) ([]*middleware.AuditLog, error)
+
+// sendPartial 发送一批日志，输出只拒绝了其中部分条目时（如 bulk 请求中单条限流）只重发这些条目。
+// 首次请求失败时返回错误，由调用方整批重试；之后的失败不再返回错误，避免已写入的条目被重复写入
+func sendPartial(ctx context.Context, name string, logs []*middleware.AuditLog, send sendFunc) error {
+	pending := logs
+	for attempt := 0; ; attempt++ {
+		failed, err := send(ctx, pending)
+		switch {
+		case err != nil && attempt == 0:
+			return err
+		case err != nil:
+			log.Printf("审计日志重发到 %s 失败，丢弃 %d 条: %v", name, len(pending), err)
+			return nil
+		case len(failed) == 0:
+			return nil
+		case attempt >= partialRetries:
+			log.Printf("审计日志写入 %s 时 %d 条多次被拒绝，已丢弃", name, len(failed))
+			return nil
+		}
+
+		pending = failed
+		select {
+		case <-time.After(time.Duration(attempt+1) * 500 * time.Millisecond):
+		case <-ctx.Done():
+			log.Printf("审计日志重发到 %s 超时，丢弃 %d 条", name, len(pending))
+			return nil
+		}
+	}
+}
+
+// retryableStatus 单条写入失败的状态码是否值得重发（限流或服务端错误）
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// responseError 读取错误响应的前一段内容
+func responseError(resp *http.Response) error {
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+}
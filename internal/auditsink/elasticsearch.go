@@ -0,0 +1,159 @@
+package auditsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/middleware"
+)
+
+// ElasticsearchConfig Elasticsearch 输出配置
+type ElasticsearchConfig struct {
+	// 集群地址，如 http://localhost:9200
+	URL string
+	// 索引名，{date} 替换为日志日期（2006.01.02），也可以是数据流名
+	Index string
+	// Basic 认证或 API Key（二选一，API Key 优先）
+	Username string
+	Password string
+	APIKey   string
+	Client   *http.Client
+}
+
+// elasticsearchSink 通过 _bulk 接口批量写入
+type elasticsearchSink struct {
+	endpoint string
+	config   ElasticsearchConfig
+}
+
+// esDocument 写入的文档：审计日志字段加上 Elasticsearch 惯用的 @timestamp（数据流必需）
+type esDocument struct {
+	Timestamp time.Time `json:"@timestamp"`
+	*middleware.AuditLog
+}
+
+// esBulkResponse _bulk 响应中判断单条结果所需的字段
+type esBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// NewElasticsearch 创建 Elasticsearch 输出
+func NewElasticsearch(cfg ElasticsearchConfig) (middleware.AuditSink, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("无效的 Elasticsearch 地址: %q", cfg.URL)
+	}
+	if cfg.Index == "" {
+		return nil, fmt.Errorf("未配置 Elasticsearch 索引")
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	return &elasticsearchSink{
+		endpoint: strings.TrimRight(cfg.URL, "/") + "/_bulk",
+		config:   cfg,
+	}, nil
+}
+
+func (s *elasticsearchSink) Name() string {
+	return Elasticsearch
+}
+
+func (s *elasticsearchSink) WriteBatch(ctx context.Context, logs []*middleware.AuditLog) error {
+	return sendPartial(ctx, Elasticsearch, logs, s.bulk)
+}
+
+func (s *elasticsearchSink) Close() error {
+	return nil
+}
+
+// bulk 发送一次 _bulk 请求，返回因限流等原因被拒绝、可以重发的条目
+func (s *elasticsearchSink) bulk(ctx context.Context, logs []*middleware.AuditLog) ([]*middleware.AuditLog, error) {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, entry := range logs {
+		// create 同时适用于普通索引与数据流
+		action := map[string]map[string]string{"create": {"_index": s.index(entry.Timestamp)}}
+		if err := encoder.Encode(action); err != nil {
+			return nil, err
+		}
+		if err := encoder.Encode(esDocument{Timestamp: entry.Timestamp, AuditLog: entry}); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	switch {
+	case s.config.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+s.config.APIKey)
+	case s.config.Username != "":
+		req.SetBasicAuth(s.config.Username, s.config.Password)
+	}
+
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+
+	var result esBulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析 _bulk 响应失败: %w", err)
+	}
+	if !result.Errors {
+		return nil, nil
+	}
+
+	// 单条失败：限流、分片不可用等可以重发，映射错误等重发也不会成功，直接丢弃
+	var retry []*middleware.AuditLog
+	rejected := 0
+	var reason string
+	for i, item := range result.Items {
+		if i >= len(logs) {
+			break
+		}
+		for _, r := range item {
+			if r.Error == nil {
+				continue
+			}
+			if retryableStatus(r.Status) {
+				retry = append(retry, logs[i])
+				continue
+			}
+			rejected++
+			if reason == "" {
+				reason = r.Error.Type + ": " + r.Error.Reason
+			}
+		}
+	}
+	if rejected > 0 {
+		log.Printf("Elasticsearch 拒绝了 %d 条审计日志（%s）", rejected, reason)
+	}
+	return retry, nil
+}
+
+// index 日志对应的索引名
+func (s *elasticsearchSink) index(t time.Time) string {
+	return strings.ReplaceAll(s.config.Index, "{date}", t.UTC().Format("2006.01.02"))
+}
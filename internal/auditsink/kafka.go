@@ -0,0 +1,127 @@
+package auditsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"new-openclaw/internal/middleware"
+)
+
+// KafkaConfig Kafka 输出配置。通过 Confluent REST Proxy（v2 API）发布，不需要在服务中引入 Kafka 客户端
+type KafkaConfig struct {
+	// REST Proxy 地址，如 http://localhost:8082
+	RESTURL string
+	Topic   string
+	Client  *http.Client
+}
+
+// kafkaSink 经 REST Proxy 批量发布到主题
+type kafkaSink struct {
+	endpoint string
+	client   *http.Client
+}
+
+// kafkaRecord 单条消息：以请求 ID 为键，同一请求的日志落在同一分区
+type kafkaRecord struct {
+	Key   string               `json:"key,omitempty"`
+	Value *middleware.AuditLog `json:"value"`
+}
+
+// kafkaProduceResponse 发布结果（与请求中的消息一一对应）
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+// NewKafka 创建 Kafka 输出
+func NewKafka(cfg KafkaConfig) (middleware.AuditSink, error) {
+	u, err := url.Parse(cfg.RESTURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("无效的 Kafka REST Proxy 地址: %q", cfg.RESTURL)
+	}
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("未配置 Kafka 主题")
+	}
+	client := cfg.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &kafkaSink{
+		endpoint: strings.TrimRight(cfg.RESTURL, "/") + "/topics/" + url.PathEscape(cfg.Topic),
+		client:   client,
+	}, nil
+}
+
+func (s *kafkaSink) Name() string {
+	return Kafka
+}
+
+func (s *kafkaSink) WriteBatch(ctx context.Context, logs []*middleware.AuditLog) error {
+	return sendPartial(ctx, Kafka, logs, s.produce)
+}
+
+func (s *kafkaSink) Close() error {
+	return nil
+}
+
+// produce 发布一批消息，返回发布失败的消息
+func (s *kafkaSink) produce(ctx context.Context, logs []*middleware.AuditLog) ([]*middleware.AuditLog, error) {
+	records := make([]kafkaRecord, len(logs))
+	for i, entry := range logs {
+		records[i] = kafkaRecord{Key: entry.RequestID, Value: entry}
+	}
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+
+	var result kafkaProduceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析 REST Proxy 响应失败: %w", err)
+	}
+	// error_code 为 2 表示可重试（如 Leader 切换），1 表示不可重试
+	var retry []*middleware.AuditLog
+	rejected := 0
+	var reason string
+	for i, offset := range result.Offsets {
+		if i >= len(logs) || offset.ErrorCode == nil {
+			continue
+		}
+		if *offset.ErrorCode == 2 {
+			retry = append(retry, logs[i])
+			continue
+		}
+		rejected++
+		if reason == "" {
+			reason = offset.Error
+		}
+	}
+	if rejected > 0 {
+		log.Printf("Kafka REST Proxy 拒绝了 %d 条审计日志（%s）", rejected, reason)
+	}
+	return retry, nil
+}
@@ -0,0 +1,29 @@
+package auditsink
+
+import (
+	"context"
+
+	"new-openclaw/internal/auditstore"
+	"new-openclaw/internal/middleware"
+)
+
+// mongoSink 写入 MongoDB（复用 MONGO_URI 的连接，集合与保留时长见 auditstore）
+type mongoSink struct{}
+
+// NewMongoDB 创建 MongoDB 输出
+func NewMongoDB() middleware.AuditSink {
+	return mongoSink{}
+}
+
+func (mongoSink) Name() string {
+	return MongoDB
+}
+
+func (mongoSink) WriteBatch(ctx context.Context, logs []*middleware.AuditLog) error {
+	return sendPartial(ctx, MongoDB, logs, auditstore.Insert)
+}
+
+// Close 连接由 database 包统一关闭
+func (mongoSink) Close() error {
+	return nil
+}
@@ -0,0 +1,193 @@
+package auditsink
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/middleware"
+)
+
+// maxDatagram UDP 单条消息的长度上限（超出部分截断）
+const maxDatagram = 8192
+
+// facilities syslog 设施编号
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslog 严重级别
+const (
+	severityWarning = 4
+	severityNotice  = 5
+	severityInfo    = 6
+)
+
+// SyslogConfig syslog 输出配置
+type SyslogConfig struct {
+	// udp://host:514、tcp://host:601 或 tls://host:6514
+	Addr string
+	// APP-NAME 字段
+	Tag string
+	// 设施名称，如 local0、auth
+	Facility string
+	// 连接与写入超时
+	Timeout time.Duration
+}
+
+// syslogSink 以 RFC 5424 格式发送，消息体为审计日志 JSON；TCP/TLS 按 RFC 6587 的长度前缀分帧
+type syslogSink struct {
+	network  string
+	address  string
+	tag      string
+	facility int
+	timeout  time.Duration
+	hostname string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslog 创建 syslog 输出（首次发送时才建立连接）
+func NewSyslog(cfg SyslogConfig) (middleware.AuditSink, error) {
+	u, err := url.Parse(cfg.Addr)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("无效的 syslog 地址: %q（格式如 udp://host:514）", cfg.Addr)
+	}
+	switch u.Scheme {
+	case "udp", "tcp", "tls":
+	default:
+		return nil, fmt.Errorf("不支持的 syslog 协议 %q（可选 udp、tcp、tls）", u.Scheme)
+	}
+	facility, ok := facilities[strings.ToLower(cfg.Facility)]
+	if !ok {
+		return nil, fmt.Errorf("未知的 syslog 设施 %q", cfg.Facility)
+	}
+
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	tag := cfg.Tag
+	if tag == "" {
+		tag = "-"
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &syslogSink{
+		network:  u.Scheme,
+		address:  u.Host,
+		tag:      tag,
+		facility: facility,
+		timeout:  timeout,
+		hostname: hostname,
+	}, nil
+}
+
+func (s *syslogSink) Name() string {
+	return Syslog
+}
+
+func (s *syslogSink) WriteBatch(ctx context.Context, logs []*middleware.AuditLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sendPartial(ctx, Syslog, logs, s.write)
+}
+
+// write 逐条发送。连接中途断开时关闭连接并返回尚未发送的条目（下次重发时重新连接，已发送的不重复）；
+// 第一条就失败时返回错误
+func (s *syslogSink) write(ctx context.Context, logs []*middleware.AuditLog) ([]*middleware.AuditLog, error) {
+	for i, entry := range logs {
+		message, err := s.format(entry)
+		if err != nil {
+			continue
+		}
+		if s.conn == nil {
+			s.conn, err = s.dial(ctx)
+		}
+		if err == nil {
+			s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+			if _, err = s.conn.Write(s.frame(message)); err != nil {
+				s.conn.Close()
+				s.conn = nil
+			}
+		}
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			return logs[i:], nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *syslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// dial 建立连接
+func (s *syslogSink) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: s.timeout}
+	if s.network == "tls" {
+		host, _, _ := net.SplitHostPort(s.address)
+		return (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", s.address)
+	}
+	return dialer.DialContext(ctx, s.network, s.address)
+}
+
+// format 生成 RFC 5424 消息：<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID - MSG
+func (s *syslogSink) format(entry *middleware.AuditLog) ([]byte, error) {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	header := fmt.Sprintf("<%d>1 %s %s %s %d audit - ",
+		s.facility*8+severity(entry), entry.Timestamp.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		s.hostname, s.tag, os.Getpid())
+	message := append([]byte(header), body...)
+	if s.network == "udp" && len(message) > maxDatagram {
+		message = message[:maxDatagram]
+	}
+	return message, nil
+}
+
+// frame TCP/TLS 使用长度前缀分帧（消息体中可能含换行），UDP 每个数据报一条
+func (s *syslogSink) frame(message []byte) []byte {
+	if s.network == "udp" {
+		return message
+	}
+	return append([]byte(strconv.Itoa(len(message))+" "), message...)
+}
+
+// severity 按响应状态确定严重级别：5xx 为 warning，4xx 与慢请求为 notice
+func severity(entry *middleware.AuditLog) int {
+	switch {
+	case entry.StatusCode >= 500:
+		return severityWarning
+	case entry.StatusCode >= 400 || entry.Slow:
+		return severityNotice
+	default:
+		return severityInfo
+	}
+}
@@ -0,0 +1,413 @@
+// Package auditstore 审计日志的 MongoDB 存储：经 auditsink 批量写入（按保留时长自动过期或写入固定集合），
+// 并按时间、用户、路径、状态码、IP 查询，排查事件时不需要登录服务器翻查日志文件
+package auditstore
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/pkg/auth/token"
+	"new-openclaw/pkg/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrUnavailable MongoDB 未连接
+	ErrUnavailable = errors.New("MongoDB 未连接，审计日志存储不可用")
+	// ErrNotFound 审计日志不存在
+	ErrNotFound = errors.New("审计日志不存在")
+)
+
+// MongoDB 错误码：索引已存在但选项不同
+const codeIndexOptionsConflict = 85
+
+// Record 存储的审计日志
+type Record struct {
+	ID           primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	RequestID    string                 `bson:"request_id" json:"request_id"`
+	Timestamp    time.Time              `bson:"timestamp" json:"timestamp"`
+	ClientIP     string                 `bson:"client_ip" json:"client_ip"`
+	UserID       string                 `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	Username     string                 `bson:"username,omitempty" json:"username,omitempty"`
+	Method       string                 `bson:"method" json:"method"`
+	Path         string                 `bson:"path" json:"path"`
+	Query        string                 `bson:"query,omitempty" json:"query,omitempty"`
+	Headers      map[string]string      `bson:"headers,omitempty" json:"headers,omitempty"`
+	RequestBody  string                 `bson:"request_body,omitempty" json:"request_body,omitempty"`
+	StatusCode   int                    `bson:"status_code" json:"status_code"`
+	ResponseBody string                 `bson:"response_body,omitempty" json:"response_body,omitempty"`
+	ResponseSize int                    `bson:"response_size" json:"response_size"`
+	Latency      int64                  `bson:"latency_ms" json:"latency_ms"`
+	Error        string                 `bson:"error,omitempty" json:"error,omitempty"`
+	UserAgent    string                 `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	Referer      string                 `bson:"referer,omitempty" json:"referer,omitempty"`
+	Slow         bool                   `bson:"slow,omitempty" json:"slow,omitempty"`
+	Extra        map[string]interface{} `bson:"extra,omitempty" json:"extra,omitempty"`
+
+	// 模拟登录期间的请求：发起模拟的超级管理员
+	ImpersonatedBy *token.Actor `bson:"impersonated_by,omitempty" json:"impersonated_by,omitempty"`
+}
+
+// Filter 查询条件（零值不限制）
+type Filter struct {
+	From time.Time
+	To   time.Time
+	// 用户 ID 或用户名
+	User string
+	// 路径，末尾 * 按前缀匹配
+	Path string
+	// 状态码范围（闭区间）
+	StatusMin int
+	StatusMax int
+	ClientIP  string
+	Method    string
+	RequestID string
+	// 模拟登录的发起人（管理员 ID 或用户名），* 表示所有模拟登录期间的请求
+	Impersonator string
+}
+
+var (
+	cfg = config.AuditSinksConfig{MongoCollection: "audit_logs", MongoRetention: 30 * 24 * time.Hour}
+
+	// 集合与索引只需成功准备一次，失败时下次写入或查询再试
+	ensured  bool
+	ensureMu sync.Mutex
+	mu       sync.RWMutex
+)
+
+// Configure 设置集合名、保留时长与固定集合大小
+func Configure(c config.AuditSinksConfig) {
+	mu.Lock()
+	defer mu.Unlock()
+	cfg = c
+	if cfg.MongoCollection == "" {
+		cfg.MongoCollection = "audit_logs"
+	}
+	ensureMu.Lock()
+	ensured = false
+	ensureMu.Unlock()
+}
+
+// Insert 批量写入，返回被拒绝的日志（只重发这些条目）；整个请求失败时返回错误
+func Insert(ctx context.Context, logs []*middleware.AuditLog) ([]*middleware.AuditLog, error) {
+	coll, err := collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]interface{}, len(logs))
+	for i, auditLog := range logs {
+		docs[i] = newRecord(auditLog)
+	}
+	_, err = coll.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	var bwe mongo.BulkWriteException
+	if err == nil || !errors.As(err, &bwe) || len(bwe.WriteErrors) == 0 {
+		return nil, err
+	}
+	failed := make([]*middleware.AuditLog, 0, len(bwe.WriteErrors))
+	for _, we := range bwe.WriteErrors {
+		if we.Index >= 0 && we.Index < len(logs) {
+			failed = append(failed, logs[we.Index])
+		}
+	}
+	return failed, nil
+}
+
+// Find 按条件分页查询（按时间倒序），列表不含请求头与请求/响应体
+func Find(ctx context.Context, filter Filter, page, pageSize int) ([]Record, int64, error) {
+	coll, err := collection(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := filter.bson()
+	total, err := coll.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}}).
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize)).
+		SetProjection(bson.M{"headers": 0, "request_body": 0, "response_body": 0})
+	cursor, err := coll.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	records := make([]Record, 0, pageSize)
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, 0, err
+	}
+	return records, total, nil
+}
+
+// Get 获取完整的审计日志
+func Get(ctx context.Context, id string) (*Record, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	coll, err := collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var record Record
+	if err := coll.FindOne(ctx, bson.M{"_id": objectID}).Decode(&record); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Summary 请求数统计
+type Summary struct {
+	Total int64 `json:"total"`
+	// 状态码 4xx、5xx 的请求数
+	ClientErrors int64 `json:"client_errors"`
+	ServerErrors int64 `json:"server_errors"`
+}
+
+// EndpointCount 接口的请求数
+type EndpointCount struct {
+	Method string `json:"method" bson:"method"`
+	Path   string `json:"path" bson:"path"`
+	Count  int64  `json:"count" bson:"count"`
+	// 其中状态码 ≥ 500 的请求数
+	ServerErrors int64 `json:"server_errors" bson:"server_errors"`
+}
+
+// Summarize 按条件统计请求总数及 4xx、5xx 数量（一次聚合）
+func Summarize(ctx context.Context, filter Filter) (*Summary, error) {
+	coll, err := collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: filter.bson()}},
+		{{Key: "$group", Value: bson.M{
+			"_id":           nil,
+			"total":         bson.M{"$sum": 1},
+			"client_errors": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$and": bson.A{bson.M{"$gte": bson.A{"$status_code", 400}}, bson.M{"$lt": bson.A{"$status_code", 500}}}}, 1, 0}}},
+			"server_errors": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gte": bson.A{"$status_code", 500}}, 1, 0}}},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		Total        int64 `bson:"total"`
+		ClientErrors int64 `bson:"client_errors"`
+		ServerErrors int64 `bson:"server_errors"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	summary := &Summary{}
+	if len(rows) > 0 {
+		summary.Total, summary.ClientErrors, summary.ServerErrors = rows[0].Total, rows[0].ClientErrors, rows[0].ServerErrors
+	}
+	return summary, nil
+}
+
+// TopEndpoints 按条件统计请求数最多的接口（按请求方法 + 路径分组，路径中的 ID 不做归并）
+func TopEndpoints(ctx context.Context, filter Filter, limit int) ([]EndpointCount, error) {
+	coll, err := collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: filter.bson()}},
+		{{Key: "$group", Value: bson.M{
+			"_id":           bson.M{"method": "$method", "path": "$path"},
+			"count":         bson.M{"$sum": 1},
+			"server_errors": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gte": bson.A{"$status_code", 500}}, 1, 0}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$project", Value: bson.M{"_id": 0, "method": "$_id.method", "path": "$_id.path", "count": 1, "server_errors": 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	list := []EndpointCount{}
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// bson 转换为查询条件
+func (f Filter) bson() bson.M {
+	query := bson.M{}
+	if !f.From.IsZero() || !f.To.IsZero() {
+		timeRange := bson.M{}
+		if !f.From.IsZero() {
+			timeRange["$gte"] = f.From
+		}
+		if !f.To.IsZero() {
+			timeRange["$lte"] = f.To
+		}
+		query["timestamp"] = timeRange
+	}
+	if f.User != "" {
+		query["$or"] = bson.A{bson.M{"user_id": f.User}, bson.M{"username": f.User}}
+	}
+	if f.Path != "" {
+		if prefix, ok := strings.CutSuffix(f.Path, "*"); ok {
+			query["path"] = bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)}
+		} else {
+			query["path"] = f.Path
+		}
+	}
+	if f.StatusMin > 0 || f.StatusMax > 0 {
+		status := bson.M{}
+		if f.StatusMin > 0 {
+			status["$gte"] = f.StatusMin
+		}
+		if f.StatusMax > 0 {
+			status["$lte"] = f.StatusMax
+		}
+		query["status_code"] = status
+	}
+	if f.ClientIP != "" {
+		query["client_ip"] = f.ClientIP
+	}
+	if f.Method != "" {
+		query["method"] = strings.ToUpper(f.Method)
+	}
+	if f.RequestID != "" {
+		query["request_id"] = f.RequestID
+	}
+	if f.Impersonator == "*" {
+		query["impersonated_by"] = bson.M{"$exists": true}
+	} else if f.Impersonator != "" {
+		or := bson.A{bson.M{"impersonated_by.username": f.Impersonator}}
+		if id, err := strconv.ParseUint(f.Impersonator, 10, 64); err == nil {
+			or = append(or, bson.M{"impersonated_by.admin_id": id})
+		}
+		// 与用户条件的 $or 同时存在时以 $and 组合
+		query["$and"] = bson.A{bson.M{"$or": or}}
+	}
+	return query
+}
+
+// collection 审计日志集合（首次使用时创建固定集合与索引）
+func collection(ctx context.Context) (*mongo.Collection, error) {
+	db := database.GetMongoDB()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	mu.RLock()
+	c := cfg
+	mu.RUnlock()
+
+	ensureMu.Lock()
+	defer ensureMu.Unlock()
+	if !ensured {
+		if err := ensure(ctx, db, c); err != nil {
+			return nil, err
+		}
+		ensured = true
+	}
+	return db.Collection(c.MongoCollection), nil
+}
+
+// ensure 创建固定集合（已存在时不修改）及查询用的索引；非固定集合按保留时长建立 TTL 索引
+func ensure(ctx context.Context, db *mongo.Database, c config.AuditSinksConfig) error {
+	capped := c.MongoCappedSizeMB > 0
+	if capped {
+		names, err := db.ListCollectionNames(ctx, bson.M{"name": c.MongoCollection})
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			opts := options.CreateCollection().SetCapped(true).SetSizeInBytes(int64(c.MongoCappedSizeMB) << 20)
+			if err := db.CreateCollection(ctx, c.MongoCollection, opts); err != nil {
+				return err
+			}
+		}
+	}
+
+	coll := db.Collection(c.MongoCollection)
+	_, err := coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "username", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "path", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "client_ip", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "status_code", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "request_id", Value: 1}}},
+		{Keys: bson.D{{Key: "impersonated_by.admin_id", Value: 1}, {Key: "timestamp", Value: -1}}, Options: options.Index().SetSparse(true)},
+	})
+	if err != nil {
+		return err
+	}
+
+	// 固定集合不支持 TTL 索引，按集合大小淘汰
+	timestampIndex := options.Index().SetName("timestamp")
+	ttl := !capped && c.MongoRetention > 0
+	if ttl {
+		timestampIndex.SetExpireAfterSeconds(int32(c.MongoRetention / time.Second))
+	}
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "timestamp", Value: 1}},
+		Options: timestampIndex,
+	})
+	var se mongo.ServerError
+	if err != nil && ttl && errors.As(err, &se) && se.HasErrorCode(codeIndexOptionsConflict) {
+		// 保留时长修改后更新已有的 TTL 索引
+		err = db.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: c.MongoCollection},
+			{Key: "index", Value: bson.M{"name": "timestamp", "expireAfterSeconds": int32(c.MongoRetention / time.Second)}},
+		}).Err()
+	}
+	if err != nil {
+		// 索引与配置不一致不影响写入与查询
+		log.Printf("⚠️  审计日志集合 %s 的时间索引未按配置更新: %v", c.MongoCollection, err)
+	}
+	return nil
+}
+
+// newRecord 由审计日志生成存储记录
+func newRecord(l *middleware.AuditLog) *Record {
+	return &Record{
+		RequestID:    l.RequestID,
+		Timestamp:    l.Timestamp,
+		ClientIP:     l.ClientIP,
+		UserID:       l.UserID,
+		Username:     l.Username,
+		Method:       l.Method,
+		Path:         l.Path,
+		Query:        l.Query,
+		Headers:      l.Headers,
+		RequestBody:  l.RequestBody,
+		StatusCode:   l.StatusCode,
+		ResponseBody: l.ResponseBody,
+		ResponseSize: l.ResponseSize,
+		Latency:      l.Latency,
+		Error:        l.Error,
+		UserAgent:    l.UserAgent,
+		Referer:      l.Referer,
+		Slow:         l.Slow,
+		Extra:        l.Extra,
+
+		ImpersonatedBy: l.ImpersonatedBy,
+	}
+}
@@ -0,0 +1,262 @@
+package breakglass
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/notify"
+	"new-openclaw/pkg/config"
+	"new-openclaw/pkg/password"
+)
+
+var (
+	// ErrDisabled 未配置紧急凭证
+	ErrDisabled = errors.New("未启用紧急访问")
+	// ErrInvalidCredential 用户名或密码错误
+	ErrInvalidCredential = errors.New("紧急凭证错误")
+	// ErrRotationRequired 当前凭证已使用过，需轮换后才能再次使用
+	ErrRotationRequired = errors.New("紧急凭证已使用过，请轮换 BREAK_GLASS_PASSWORD_HASH 后再使用")
+	// ErrLocked 连续失败次数过多，暂时锁定
+	ErrLocked = errors.New("紧急凭证连续验证失败次数过多，请稍后再试")
+)
+
+// 连续失败锁定（进程内计数，不依赖 Redis）
+const (
+	maxFailures  = 5
+	lockDuration = 15 * time.Minute
+)
+
+var (
+	cfg   config.BreakGlassConfig
+	trail = &Trail{}
+
+	// consumed 已激活过的凭证指纹（从轨迹文件加载）
+	consumed   = make(map[string]time.Time)
+	failures   int
+	lockedTill time.Time
+	mu         sync.Mutex
+)
+
+// Configure 设置紧急访问配置，并从轨迹文件加载已使用过的凭证
+func Configure(c config.BreakGlassConfig) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	cfg = c
+	trail = &Trail{path: c.TrailFile}
+	consumed = make(map[string]time.Time)
+
+	entries, err := trail.Entries()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Event == EventActivated && e.Fingerprint != "" {
+			consumed[e.Fingerprint] = e.At
+		}
+	}
+
+	if Enabled() {
+		if at, ok := consumed[fingerprint()]; ok {
+			log.Printf("⚠️  紧急凭证已于 %s 使用过，轮换前无法再次使用", at.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// Enabled 是否配置了紧急凭证
+func Enabled() bool {
+	return cfg.PasswordHash != ""
+}
+
+// Status 紧急访问状态
+type Status struct {
+	Enabled          bool       `json:"enabled"`
+	Username         string     `json:"username,omitempty"`
+	Fingerprint      string     `json:"fingerprint,omitempty"`
+	TTL              string     `json:"ttl"`
+	RotationRequired bool       `json:"rotation_required"`
+	LastActivatedAt  *time.Time `json:"last_activated_at,omitempty"`
+}
+
+// CurrentStatus 获取紧急访问状态
+func CurrentStatus() Status {
+	mu.Lock()
+	defer mu.Unlock()
+
+	status := Status{Enabled: Enabled(), TTL: cfg.TTL.String()}
+	if !status.Enabled {
+		return status
+	}
+	status.Username = cfg.Username
+	status.Fingerprint = fingerprint()
+	if at, ok := consumed[status.Fingerprint]; ok {
+		status.RotationRequired = true
+		status.LastActivatedAt = &at
+	}
+	return status
+}
+
+// Attempt 一次紧急登录请求
+type Attempt struct {
+	Username  string
+	Password  string
+	Reason    string
+	IP        string
+	UserAgent string
+}
+
+// Activate 校验紧急凭证，通过后以有效期调用 issue 签发会话：签发成功且写入轨迹后凭证才标记为已使用（之后必须轮换），
+// 签发失败时凭证仍可再次使用。所有结果均写入轨迹并告警
+func Activate(a Attempt, issue func(ttl time.Duration) error) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !Enabled() {
+		return ErrDisabled
+	}
+
+	entry := Entry{
+		Username:    a.Username,
+		IP:          a.IP,
+		UserAgent:   a.UserAgent,
+		Reason:      a.Reason,
+		Fingerprint: fingerprint(),
+	}
+
+	now := time.Now()
+	if now.Before(lockedTill) {
+		entry.Event = EventLocked
+		record(entry, "紧急访问被锁定期间再次尝试")
+		return ErrLocked
+	}
+
+	usernameOK := subtle.ConstantTimeCompare([]byte(a.Username), []byte(cfg.Username)) == 1
+	if !password.Verify(cfg.PasswordHash, a.Password) || !usernameOK {
+		failures++
+		entry.Event = EventFailed
+		if failures >= maxFailures {
+			lockedTill = now.Add(lockDuration)
+			failures = 0
+		}
+		record(entry, "紧急凭证验证失败")
+		return ErrInvalidCredential
+	}
+	failures = 0
+
+	if _, ok := consumed[entry.Fingerprint]; ok {
+		entry.Event = EventRejected
+		record(entry, "已使用过的紧急凭证再次尝试登录")
+		return ErrRotationRequired
+	}
+
+	// 先签发会话（签发的凭证在写入轨迹前不会返回给调用方），失败时不消耗凭证
+	if err := issue(cfg.TTL); err != nil {
+		return fmt.Errorf("签发紧急会话失败: %w", err)
+	}
+
+	entry.Event = EventActivated
+	// 轨迹写入失败时拒绝激活：紧急访问必须留下审计记录
+	if err := trail.Append(&entry); err != nil {
+		log.Printf("写入紧急访问轨迹失败: %v", err)
+		return fmt.Errorf("写入审计轨迹失败: %w", err)
+	}
+	consumed[entry.Fingerprint] = entry.At
+	alert(entry, "紧急访问已激活")
+	return nil
+}
+
+// Request 紧急会话发起的请求
+type Request struct {
+	Username  string
+	SessionID string
+	Method    string
+	Path      string
+	Status    int
+	IP        string
+}
+
+// Record 记录紧急会话发起的请求；写操作同时告警
+func Record(r Request) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	entry := Entry{
+		Event:     EventRequest,
+		Username:  r.Username,
+		SessionID: r.SessionID,
+		Method:    r.Method,
+		Path:      r.Path,
+		Status:    r.Status,
+		IP:        r.IP,
+	}
+	switch r.Method {
+	case "POST", "PUT", "PATCH", "DELETE":
+		record(entry, "紧急会话执行了写操作")
+	default:
+		if err := trail.Append(&entry); err != nil {
+			log.Printf("写入紧急访问轨迹失败: %v", err)
+		}
+	}
+}
+
+// Entries 读取全部轨迹记录
+func Entries() ([]Entry, error) {
+	mu.Lock()
+	t := trail
+	mu.Unlock()
+	return t.Entries()
+}
+
+// record 写入轨迹并告警（调用方持有 mu）
+func record(entry Entry, title string) {
+	if err := trail.Append(&entry); err != nil {
+		log.Printf("写入紧急访问轨迹失败: %v", err)
+	}
+	alert(entry, title)
+}
+
+// alert 异步向所有超级管理员及额外邮箱发送告警
+func alert(entry Entry, title string) {
+	at := entry.At
+	if at.IsZero() {
+		// 轨迹写入失败时仍需告警
+		at = time.Now()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "事件: %s\n时间: %s\n账号: %s\nIP: %s\n", entry.Event, at.Format(time.RFC3339), entry.Username, entry.IP)
+	if entry.Reason != "" {
+		fmt.Fprintf(&b, "原因: %s\n", entry.Reason)
+	}
+	if entry.UserAgent != "" {
+		fmt.Fprintf(&b, "设备: %s\n", entry.UserAgent)
+	}
+	if entry.Method != "" {
+		fmt.Fprintf(&b, "请求: %s %s（状态 %d）\n", entry.Method, entry.Path, entry.Status)
+	}
+	if entry.Event == EventActivated {
+		fmt.Fprintf(&b, "\n紧急会话有效期 %s，结束后请立即轮换 BREAK_GLASS_PASSWORD_HASH。\n", cfg.TTL)
+	}
+	body := b.String()
+	extra := cfg.AlertEmails
+
+	go func() {
+		if err := notify.Alert("[紧急访问] "+title, body, extra); err != nil {
+			log.Printf("查询超级管理员失败，告警仅发送到 BREAK_GLASS_ALERT_EMAILS: %v", err)
+		}
+	}()
+}
+
+// fingerprint 当前凭证的指纹（哈希变更即视为已轮换）
+func fingerprint() string {
+	sum := sha256.Sum256([]byte(cfg.PasswordHash))
+	return hex.EncodeToString(sum[:8])
+}
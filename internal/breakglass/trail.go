@@ -0,0 +1,157 @@
+package breakglass
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 轨迹事件
+const (
+	EventActivated = "activated"
+	EventFailed    = "failed"
+	EventLocked    = "locked"
+	EventRejected  = "rotation_required"
+	EventRequest   = "request"
+)
+
+// Entry 紧急访问轨迹记录，Hash 为 PrevHash 与记录内容的 SHA-256（哈希链，任一记录被修改或删除都会被发现）
+type Entry struct {
+	Seq         int64     `json:"seq"`
+	At          time.Time `json:"at"`
+	Event       string    `json:"event"`
+	Username    string    `json:"username,omitempty"`
+	IP          string    `json:"ip,omitempty"`
+	UserAgent   string    `json:"user_agent,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	SessionID   string    `json:"session_id,omitempty"`
+	Method      string    `json:"method,omitempty"`
+	Path        string    `json:"path,omitempty"`
+	Status      int       `json:"status,omitempty"`
+	PrevHash    string    `json:"prev_hash"`
+	Hash        string    `json:"hash"`
+}
+
+// Trail 只追加的轨迹文件（只写本地文件，不依赖数据库与 Redis）
+type Trail struct {
+	path string
+
+	mu       sync.Mutex
+	loaded   bool
+	seq      int64
+	lastHash string
+}
+
+// Append 追加一条记录并落盘
+func (t *Trail) Append(e *Entry) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.path == "" {
+		return errors.New("未配置 BREAK_GLASS_TRAIL_FILE")
+	}
+	if !t.loaded {
+		entries, err := t.read()
+		if err != nil {
+			return err
+		}
+		if n := len(entries); n > 0 {
+			t.seq = entries[n-1].Seq
+			t.lastHash = entries[n-1].Hash
+		}
+		t.loaded = true
+	}
+
+	e.Seq = t.seq + 1
+	e.At = time.Now()
+	e.PrevHash = t.lastHash
+	e.Hash = hashEntry(*e)
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(t.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	t.seq = e.Seq
+	t.lastHash = e.Hash
+	return nil
+}
+
+// Entries 读取全部记录
+func (t *Trail) Entries() ([]Entry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.read()
+}
+
+// read 读取轨迹文件（调用方持有 t.mu），文件不存在时返回空列表
+func (t *Trail) read() ([]Entry, error) {
+	if t.path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(t.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// 无法解析的行保留为空记录，校验哈希链时会被发现
+			e = Entry{Event: "corrupted"}
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Verify 校验哈希链，返回第一条被篡改记录的位置（从 1 开始，0 表示完整）
+func Verify(entries []Entry) int {
+	prev := ""
+	for i, e := range entries {
+		if e.PrevHash != prev || e.Seq != int64(i+1) || hashEntry(e) != e.Hash {
+			return i + 1
+		}
+		prev = e.Hash
+	}
+	return 0
+}
+
+// hashEntry 计算记录哈希（不含 Hash 字段本身）
+func hashEntry(e Entry) string {
+	e.Hash = ""
+	data, _ := json.Marshal(e)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,254 @@
+// Package captcha 登录与注册的人机验证：默认由服务端生成数字图片验证码（答案保存在 captcha 存储中，一次性使用），
+// 也可改用 hCaptcha、reCAPTCHA 或 Turnstile，由前端渲染验证组件、服务端经 siteverify 接口校验凭证
+package captcha
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/metrics"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/store"
+	"new-openclaw/pkg/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 验证方式
+const (
+	ProviderImage     = "image"
+	ProviderHCaptcha  = "hcaptcha"
+	ProviderReCaptcha = "recaptcha"
+	ProviderTurnstile = "turnstile"
+)
+
+// 各服务默认的 siteverify 地址
+var verifyURLs = map[string]string{
+	ProviderHCaptcha:  "https://hcaptcha.com/siteverify",
+	ProviderReCaptcha: "https://www.google.com/recaptcha/api/siteverify",
+	ProviderTurnstile: "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+}
+
+var (
+	// ErrRequired 未提交验证码或凭证
+	ErrRequired = errors.New("请完成人机验证")
+	// ErrInvalid 验证码错误、已过期或已使用
+	ErrInvalid = errors.New("人机验证未通过，请重新获取验证码")
+	// ErrUnavailable 验证服务不可用
+	ErrUnavailable = errors.New("人机验证服务暂不可用")
+)
+
+var cfg config.CaptchaConfig
+
+// verifier 第三方验证服务（图片验证码时为空）
+var verifier middleware.CaptchaVerifier
+
+var captchaTotal = metrics.NewCounterVec("captcha_verifications_total", "人机验证的校验次数", "result")
+
+// Configure 设置验证方式；使用第三方服务时必须配置密钥
+func Configure(c config.CaptchaConfig) error {
+	if !c.Enabled {
+		cfg = c
+		return nil
+	}
+	switch c.Provider {
+	case ProviderImage:
+		verifier = nil
+	case ProviderHCaptcha, ProviderReCaptcha, ProviderTurnstile:
+		if c.Secret == "" {
+			return fmt.Errorf("人机验证方式 %s 需要配置 CAPTCHA_SECRET", c.Provider)
+		}
+		if c.VerifyURL == "" {
+			c.VerifyURL = verifyURLs[c.Provider]
+		}
+		verifier = middleware.NewSiteVerifyCaptcha(c.VerifyURL, c.Secret, c.Timeout)
+	default:
+		return fmt.Errorf("未知的人机验证方式: %s", c.Provider)
+	}
+	if c.Length <= 0 {
+		c.Length = 5
+	}
+	if c.TTL <= 0 {
+		c.TTL = 2 * time.Minute
+	}
+	cfg = c
+	return nil
+}
+
+// Enabled 是否启用
+func Enabled() bool {
+	return cfg.Enabled
+}
+
+// LoginRequired 账号连续失败 failures 次后，下一次登录是否需要验证
+func LoginRequired(failures int64) bool {
+	return cfg.Enabled && failures >= int64(cfg.LoginAfter)
+}
+
+// RegisterRequired 注册是否需要验证
+func RegisterRequired() bool {
+	return cfg.Enabled && cfg.Register
+}
+
+// Challenge 下发给前端的验证信息
+type Challenge struct {
+	Provider string `json:"provider"`
+	// 图片验证码：提交时带上 captcha_id 与识别出的 captcha_answer
+	ID        string `json:"captcha_id,omitempty"`
+	Image     string `json:"image,omitempty"`
+	ExpiresIn int    `json:"expires_in,omitempty"`
+	// 第三方服务：前端用 site_key 渲染验证组件，提交时带上 captcha_token
+	SiteKey string `json:"site_key,omitempty"`
+}
+
+// Solution 客户端提交的验证结果
+type Solution struct {
+	ID     string `json:"captcha_id"`
+	Answer string `json:"captcha_answer"`
+	Token  string `json:"captcha_token"`
+}
+
+// New 生成验证信息；图片验证码的答案保存 TTL，只能校验一次
+func New(ctx context.Context) (*Challenge, error) {
+	if verifier != nil {
+		return &Challenge{Provider: cfg.Provider, SiteKey: cfg.SiteKey}, nil
+	}
+
+	code, err := randomDigits(cfg.Length)
+	if err != nil {
+		return nil, err
+	}
+	image, err := renderPNG(code)
+	if err != nil {
+		return nil, err
+	}
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	if err := store.For(store.ComponentCaptcha).Set(ctx, "answer:"+id, code, cfg.TTL); err != nil {
+		return nil, err
+	}
+	return &Challenge{
+		Provider:  ProviderImage,
+		ID:        id,
+		Image:     image,
+		ExpiresIn: int(cfg.TTL.Seconds()),
+	}, nil
+}
+
+// Verify 校验客户端提交的验证结果。图片验证码无论对错都立即作废，防止对同一张图片反复猜测
+func Verify(ctx context.Context, s Solution, ip string) error {
+	err := verify(ctx, s, ip)
+	switch {
+	case err == nil:
+		captchaTotal.Inc("passed")
+	case errors.Is(err, ErrRequired):
+		captchaTotal.Inc("missing")
+	case errors.Is(err, ErrUnavailable):
+		captchaTotal.Inc("error")
+	default:
+		captchaTotal.Inc("failed")
+	}
+	return err
+}
+
+func verify(ctx context.Context, s Solution, ip string) error {
+	if verifier != nil {
+		if s.Token == "" {
+			return ErrRequired
+		}
+		ok, err := verifier.Verify(ctx, s.Token, ip)
+		if err != nil {
+			log.Printf("人机验证服务调用失败: %v", err)
+			return ErrUnavailable
+		}
+		if !ok {
+			return ErrInvalid
+		}
+		return nil
+	}
+
+	if s.ID == "" || s.Answer == "" {
+		return ErrRequired
+	}
+	st := store.For(store.ComponentCaptcha)
+	// 并发提交同一验证码时只有一个请求能继续
+	first, err := st.SetNX(ctx, "used:"+s.ID, "1", cfg.TTL)
+	if err != nil {
+		return ErrUnavailable
+	}
+	if !first {
+		return ErrInvalid
+	}
+	answer, err := st.Get(ctx, "answer:"+s.ID)
+	st.Del(ctx, "answer:"+s.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrInvalid
+	}
+	if err != nil {
+		return ErrUnavailable
+	}
+	if strings.TrimSpace(s.Answer) != answer {
+		return ErrInvalid
+	}
+	return nil
+}
+
+// randomDigits 随机数字串
+func randomDigits(n int) (string, error) {
+	b := make([]byte, n)
+	for i := range b {
+		d, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		b[i] = byte('0' + d.Int64())
+	}
+	return string(b), nil
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Check 校验请求提交的验证结果（第三方凭证也可放在 X-Captcha-Token 请求头中），未通过时写入响应并返回 false
+func Check(c *gin.Context, s Solution) bool {
+	if s.Token == "" {
+		s.Token = c.GetHeader(middleware.CaptchaTokenHeader)
+	}
+	err := Verify(c.Request.Context(), s, middleware.AbuseIP(c))
+	if err == nil {
+		return true
+	}
+	Reject(c, err)
+	return false
+}
+
+// Reject 人机验证未通过：缺少或错误时 400（data.captcha_required 提示前端展示验证码），服务不可用时 503
+func Reject(c *gin.Context, err error) {
+	if errors.Is(err, ErrUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"code":    400,
+		"message": err.Error(),
+		"data":    gin.H{"captcha_required": true},
+	})
+}
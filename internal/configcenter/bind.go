@@ -0,0 +1,89 @@
+package configcenter
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"new-openclaw/internal/middleware"
+)
+
+// RateLimitSection 频率限制配置段（未填写的字段保持当前值）
+type RateLimitSection struct {
+	Window      string `json:"window"`
+	MaxRequests int    `json:"max_requests"`
+	Algorithm   string `json:"algorithm"`
+	Burst       *int   `json:"burst"`
+	// 预警阈值（已用比例）
+	WarnThreshold *float64 `json:"warn_threshold"`
+	// 观察期截止时间（RFC3339），之前只记录超限、不拦截；收紧限额时与 max_requests 一起下发
+	EnforceAfter *string `json:"enforce_after"`
+}
+
+// IPFilterSection IP 过滤配置段（整体替换）
+type IPFilterSection struct {
+	WhitelistMode bool     `json:"whitelist_mode"`
+	Whitelist     []string `json:"whitelist"`
+	Blacklist     []string `json:"blacklist"`
+}
+
+// BindRateLimiter 将 rate_limit 配置段绑定到动态频率限制器
+func BindRateLimiter(limiter *middleware.DynamicRateLimiter) {
+	Handle(SectionRateLimit, func(raw json.RawMessage) error {
+		var section RateLimitSection
+		if err := json.Unmarshal(raw, &section); err != nil {
+			return err
+		}
+
+		cfg := limiter.Config()
+		if section.Window != "" {
+			window, err := time.ParseDuration(section.Window)
+			if err != nil || window <= 0 {
+				return fmt.Errorf("无效的时间窗口: %s", section.Window)
+			}
+			cfg.Window = window
+		}
+		if section.MaxRequests > 0 {
+			cfg.MaxRequests = section.MaxRequests
+		}
+		if section.Algorithm != "" {
+			cfg.Algorithm = section.Algorithm
+		}
+		if section.Burst != nil {
+			cfg.Burst = *section.Burst
+		}
+		if section.WarnThreshold != nil {
+			cfg.WarnThreshold = *section.WarnThreshold
+		}
+		if section.EnforceAfter != nil {
+			cfg.EnforceAfter = time.Time{}
+			if *section.EnforceAfter != "" {
+				t, err := time.Parse(time.RFC3339, *section.EnforceAfter)
+				if err != nil {
+					return fmt.Errorf("无效的观察期截止时间: %s", *section.EnforceAfter)
+				}
+				cfg.EnforceAfter = t
+			}
+		}
+
+		return limiter.Update(cfg)
+	})
+}
+
+// BindIPFilter 将 ip_filter 配置段绑定到动态 IP 过滤器
+func BindIPFilter(filter *middleware.DynamicIPFilter) {
+	Handle(SectionIPFilter, func(raw json.RawMessage) error {
+		var section IPFilterSection
+		if err := json.Unmarshal(raw, &section); err != nil {
+			return err
+		}
+
+		cfg := filter.Config()
+		cfg.WhitelistMode = section.WhitelistMode
+		cfg.Whitelist = section.Whitelist
+		cfg.Blacklist = section.Blacklist
+
+		filter.Reload(cfg)
+		return nil
+	})
+}
@@ -0,0 +1,110 @@
+package configcenter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"new-openclaw/pkg/config"
+)
+
+// 配置中心类型
+const (
+	ProviderEtcd  = "etcd"
+	ProviderNacos = "nacos"
+)
+
+// 可热更新的配置段
+const (
+	SectionRateLimit = "rate_limit"
+	SectionIPFilter  = "ip_filter"
+	SectionFeatures  = "features"
+)
+
+// Source 远程配置源
+type Source interface {
+	// Watch 监听配置变化，每次获取到新内容时调用 onChange，直到 ctx 结束
+	Watch(ctx context.Context, onChange func(data []byte))
+}
+
+// HandlerFunc 配置段变更处理函数
+type HandlerFunc func(raw json.RawMessage) error
+
+var (
+	mu       sync.Mutex
+	handlers = make(map[string]HandlerFunc)
+	applied  = make(map[string]json.RawMessage)
+	cancel   context.CancelFunc
+)
+
+// Handle 注册配置段变更处理函数（需在 Start 之前调用）
+func Handle(section string, fn HandlerFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers[section] = fn
+}
+
+// Start 连接配置中心并开始监听，Provider 为空时不启用
+func Start(cfg config.ConfigCenterConfig) error {
+	if cfg.Provider == "" {
+		return nil
+	}
+
+	var source Source
+	switch cfg.Provider {
+	case ProviderEtcd:
+		source = newEtcdSource(cfg)
+	case ProviderNacos:
+		source = newNacosSource(cfg)
+	default:
+		return fmt.Errorf("不支持的配置中心类型: %s", cfg.Provider)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	mu.Lock()
+	cancel = stop
+	mu.Unlock()
+
+	go source.Watch(ctx, apply)
+
+	log.Printf("✅ 已连接配置中心 %s: %s", cfg.Provider, cfg.Key)
+	return nil
+}
+
+// Stop 停止监听
+func Stop() {
+	mu.Lock()
+	defer mu.Unlock()
+	if cancel != nil {
+		cancel()
+		cancel = nil
+	}
+}
+
+// apply 解析配置文档，仅对内容发生变化的配置段调用处理函数
+func apply(data []byte) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		log.Printf("配置中心内容解析失败: %v", err)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for section, fn := range handlers {
+		raw, ok := doc[section]
+		if !ok || bytes.Equal(raw, applied[section]) {
+			continue
+		}
+		if err := fn(raw); err != nil {
+			log.Printf("配置段 %s 应用失败: %v", section, err)
+			continue
+		}
+		applied[section] = raw
+		log.Printf("配置段 %s 已热更新", section)
+	}
+}
@@ -0,0 +1,152 @@
+package configcenter
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/pkg/config"
+)
+
+// etcdSource etcd 配置源（v3 gRPC-Gateway JSON API，使用 watch 流实时获取变更）
+type etcdSource struct {
+	endpoint string
+	key      string
+	client   *http.Client
+}
+
+func newEtcdSource(cfg config.ConfigCenterConfig) *etcdSource {
+	return &etcdSource{
+		endpoint: strings.TrimRight(cfg.Endpoint, "/"),
+		key:      base64.StdEncoding.EncodeToString([]byte(cfg.Key)),
+		// watch 为长连接，不设置整体超时
+		client: &http.Client{},
+	}
+}
+
+type etcdKV struct {
+	Value       string `json:"value"`
+	ModRevision string `json:"mod_revision"`
+}
+
+func (s *etcdSource) Watch(ctx context.Context, onChange func(data []byte)) {
+	for {
+		revision, err := s.load(ctx, onChange)
+		if err == nil {
+			err = s.watch(ctx, revision+1, onChange)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("etcd 配置监听中断，3 秒后重试: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(3 * time.Second):
+		}
+	}
+}
+
+// load 读取当前值，返回当前 revision
+func (s *etcdSource) load(ctx context.Context, onChange func(data []byte)) (int64, error) {
+	var resp struct {
+		Header struct {
+			Revision string `json:"revision"`
+		} `json:"header"`
+		Kvs []etcdKV `json:"kvs"`
+	}
+	if err := s.post(ctx, "/v3/kv/range", map[string]interface{}{"key": s.key}, &resp); err != nil {
+		return 0, err
+	}
+
+	if len(resp.Kvs) > 0 {
+		if data, err := base64.StdEncoding.DecodeString(resp.Kvs[0].Value); err == nil {
+			onChange(data)
+		}
+	}
+
+	revision, _ := strconv.ParseInt(resp.Header.Revision, 10, 64)
+	return revision, nil
+}
+
+// watch 从指定 revision 开始监听变更
+func (s *etcdSource) watch(ctx context.Context, startRevision int64, onChange func(data []byte)) error {
+	body, _ := json.Marshal(map[string]interface{}{
+		"create_request": map[string]interface{}{
+			"key":            s.key,
+			"start_revision": startRevision,
+		},
+	})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+"/v3/watch", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("watch 返回 %d", resp.StatusCode)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Result struct {
+				Events []struct {
+					Type string `json:"type"`
+					KV   etcdKV `json:"kv"`
+				} `json:"events"`
+			} `json:"result"`
+		}
+		if err := decoder.Decode(&msg); err != nil {
+			return err
+		}
+
+		for _, event := range msg.Result.Events {
+			// 删除事件保持当前配置
+			if event.Type == "DELETE" {
+				continue
+			}
+			if data, err := base64.StdEncoding.DecodeString(event.KV.Value); err == nil {
+				onChange(data)
+			}
+		}
+	}
+}
+
+func (s *etcdSource) post(ctx context.Context, path string, body interface{}, out interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	data, _ := json.Marshal(body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s 返回 %d", path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,44 @@
+package configcenter
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+var (
+	featuresMu sync.RWMutex
+	features   = make(map[string]bool)
+)
+
+func init() {
+	Handle(SectionFeatures, func(raw json.RawMessage) error {
+		var flags map[string]bool
+		if err := json.Unmarshal(raw, &flags); err != nil {
+			return err
+		}
+
+		featuresMu.Lock()
+		features = flags
+		featuresMu.Unlock()
+		return nil
+	})
+}
+
+// Feature 查询功能开关（未配置时返回 false）
+func Feature(name string) bool {
+	featuresMu.RLock()
+	defer featuresMu.RUnlock()
+	return features[name]
+}
+
+// Features 获取所有功能开关
+func Features() map[string]bool {
+	featuresMu.RLock()
+	defer featuresMu.RUnlock()
+
+	result := make(map[string]bool, len(features))
+	for k, v := range features {
+		result[k] = v
+	}
+	return result
+}
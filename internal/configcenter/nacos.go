@@ -0,0 +1,127 @@
+package configcenter
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"new-openclaw/pkg/config"
+)
+
+// nacosSource Nacos 配置源（长轮询监听，变更后拉取最新内容）
+type nacosSource struct {
+	endpoint  string
+	dataID    string
+	group     string
+	namespace string
+	client    *http.Client
+}
+
+func newNacosSource(cfg config.ConfigCenterConfig) *nacosSource {
+	group := cfg.Group
+	if group == "" {
+		group = "DEFAULT_GROUP"
+	}
+	return &nacosSource{
+		endpoint:  strings.TrimRight(cfg.Endpoint, "/"),
+		dataID:    cfg.Key,
+		group:     group,
+		namespace: cfg.Namespace,
+		// 长轮询最长挂起 30 秒
+		client: &http.Client{Timeout: 40 * time.Second},
+	}
+}
+
+func (s *nacosSource) Watch(ctx context.Context, onChange func(data []byte)) {
+	contentMD5 := ""
+	for {
+		data, err := s.fetch(ctx)
+		if err == nil {
+			if sum := md5sum(data); sum != contentMD5 {
+				contentMD5 = sum
+				onChange(data)
+			}
+			err = s.listen(ctx, contentMD5)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("Nacos 配置监听失败，3 秒后重试: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(3 * time.Second):
+			}
+		}
+	}
+}
+
+// fetch 拉取配置内容
+func (s *nacosSource) fetch(ctx context.Context) ([]byte, error) {
+	params := url.Values{}
+	params.Set("dataId", s.dataID)
+	params.Set("group", s.group)
+	if s.namespace != "" {
+		params.Set("tenant", s.namespace)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint+"/nacos/v1/cs/configs?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取配置返回 %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// listen 长轮询等待配置变更（返回即表示可能已变更或超时）
+func (s *nacosSource) listen(ctx context.Context, contentMD5 string) error {
+	listening := s.dataID + "\x02" + s.group + "\x02" + contentMD5
+	if s.namespace != "" {
+		listening += "\x02" + s.namespace
+	}
+	listening += "\x01"
+
+	form := url.Values{}
+	form.Set("Listening-Configs", listening)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+"/nacos/v1/cs/configs/listener", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Long-Pulling-Timeout", "30000")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("监听配置返回 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func md5sum(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
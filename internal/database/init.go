@@ -1,6 +1,7 @@
 package database
 
 import (
+	"errors"
 	"log"
 
 	"new-openclaw/internal/model"
@@ -9,6 +10,18 @@ import (
 
 // InitAll 初始化所有数据库连接
 func InitAll(cfg *config.Config) error {
+	// 单机模式：SQLite 替代 MySQL，不连接 Redis、MongoDB
+	if cfg.Standalone() {
+		if err := InitSQLite(cfg.Server.SQLitePath); err != nil {
+			return err
+		}
+		if err := AutoMigrate(); err != nil {
+			log.Printf("⚠️  数据库迁移失败: %v", err)
+		}
+		log.Println("🧪 单机模式：使用 SQLite 与内存存储，跳过 Redis、MongoDB")
+		return nil
+	}
+
 	// 初始化 MySQL（可选，连接失败只打印警告）
 	if err := InitMySQL(&cfg.MySQL); err != nil {
 		log.Printf("⚠️  MySQL 初始化失败（可选）: %v", err)
@@ -32,18 +45,26 @@ func InitAll(cfg *config.Config) error {
 	return nil
 }
 
-// CloseAll 关闭所有数据库连接
-func CloseAll() {
+// CloseAll 关闭所有数据库连接，返回各连接关闭时的错误（合并）
+func CloseAll() error {
+	var errs []error
 	if err := CloseMySQL(); err != nil {
 		log.Printf("关闭 MySQL 失败: %v", err)
+		errs = append(errs, err)
 	}
 	if err := CloseRedis(); err != nil {
 		log.Printf("关闭 Redis 失败: %v", err)
+		errs = append(errs, err)
 	}
 	if err := CloseMongoDB(); err != nil {
 		log.Printf("关闭 MongoDB 失败: %v", err)
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
 	}
 	log.Println("✅ 所有数据库连接已关闭")
+	return nil
 }
 
 // AutoMigrate 自动迁移数据库表
@@ -57,6 +78,35 @@ func AutoMigrate() error {
 	// 迁移所有模型
 	err := MySQL.AutoMigrate(
 		&model.Admin{},
+		&model.OperationLog{},
+		&model.AppKeyQuota{},
+		&model.NotificationPreference{},
+		&model.PendingNotification{},
+		&model.PartnerTransform{},
+		&model.AppKey{},
+		&model.HealthEvent{},
+		&model.WebhookEndpoint{},
+		&model.WebhookDelivery{},
+		&model.WebhookAttempt{},
+		&model.IPRule{},
+		&model.IPBan{},
+		&model.Tag{},
+		&model.Tagging{},
+		&model.SavedView{},
+		&model.SecurityEvent{},
+		&model.WAFRule{},
+		&model.User{},
+		&model.UserIdentity{},
+		&model.APIKey{},
+		&model.LoginHistory{},
+		&model.WebAuthnCredential{},
+		&model.Impersonation{},
+		&model.PersonalAccessToken{},
+		&model.Setting{},
+		&model.AdminRole{},
+		&model.AdminPermission{},
+		&model.File{},
+		&model.Menu{},
 	)
 
 	if err != nil {
@@ -26,7 +26,7 @@ func InitMySQL(cfg *config.MySQLConfig) error {
 
 	var err error
 	MySQL, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: recordingLogger{Interface: logger.Default.LogMode(logger.Info)},
 	})
 	if err != nil {
 		return fmt.Errorf("连接 MySQL 失败: %w", err)
@@ -43,6 +43,8 @@ func InitMySQL(cfg *config.MySQLConfig) error {
 	sqlDB.SetMaxOpenConns(100)          // 最大打开连接数
 	sqlDB.SetConnMaxLifetime(time.Hour) // 连接最大生命周期
 
+	driver = "mysql"
+
 	log.Println("✅ MySQL 连接成功")
 	return nil
 }
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/logger"
+)
+
+// queryRecorderKey 查询记录器在 context 中的 key
+type queryRecorderKey struct{}
+
+// QueryRecord 单条 SQL 记录
+type QueryRecord struct {
+	SQL        string `json:"sql"`
+	Rows       int64  `json:"rows"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// QueryRecorder 请求级 SQL 记录器
+type QueryRecorder struct {
+	queries []QueryRecord
+	mu      sync.Mutex
+}
+
+// maxRecordedQueries 单个请求最多记录的 SQL 条数
+const maxRecordedQueries = 100
+
+// WithQueryRecorder 返回携带 SQL 记录器的 context（需通过 db.WithContext(ctx) 执行查询）
+func WithQueryRecorder(ctx context.Context) (context.Context, *QueryRecorder) {
+	recorder := &QueryRecorder{}
+	return context.WithValue(ctx, queryRecorderKey{}, recorder), recorder
+}
+
+// Queries 获取已记录的 SQL
+func (r *QueryRecorder) Queries() []QueryRecord {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]QueryRecord(nil), r.queries...)
+}
+
+// add 追加记录
+func (r *QueryRecorder) add(q QueryRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.queries) < maxRecordedQueries {
+		r.queries = append(r.queries, q)
+	}
+}
+
+// recordingLogger 在原有 GORM 日志基础上记录请求级 SQL
+type recordingLogger struct {
+	logger.Interface
+}
+
+// LogMode 设置日志级别
+func (l recordingLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return recordingLogger{Interface: l.Interface.LogMode(level)}
+}
+
+// Trace 记录 SQL 执行
+func (l recordingLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	l.Interface.Trace(ctx, begin, fc, err)
+
+	recorder, ok := ctx.Value(queryRecorderKey{}).(*QueryRecorder)
+	if !ok {
+		return
+	}
+
+	sql, rows := fc()
+	record := QueryRecord{
+		SQL:        sql,
+		Rows:       rows,
+		DurationMs: time.Since(begin).Milliseconds(),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	recorder.add(record)
+}
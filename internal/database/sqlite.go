@@ -0,0 +1,56 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// driver 当前关系数据库驱动（mysql / sqlite）
+var driver string
+
+// InitSQLite 初始化 SQLite 连接（单机模式，替代 MySQL；path 为 :memory: 时不落盘）
+func InitSQLite(path string) error {
+	if path != ":memory:" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("创建 SQLite 目录失败: %w", err)
+		}
+	}
+
+	dsn := path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: recordingLogger{Interface: logger.Default.LogMode(logger.Info)},
+	})
+	if err != nil {
+		return fmt.Errorf("打开 SQLite 失败: %w", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("获取 SQLite 连接池失败: %w", err)
+	}
+
+	// SQLite 单写者，串行化连接避免 database is locked（内存库也只能共享同一连接）
+	sqlDB.SetMaxOpenConns(1)
+
+	// 与 MySQL 共用同一实例，业务代码通过 GetMySQL 获取
+	MySQL = db
+	driver = "sqlite"
+
+	log.Printf("✅ SQLite 已打开: %s", path)
+	return nil
+}
+
+// Driver 获取当前关系数据库驱动（未连接时为空）
+func Driver() string {
+	if MySQL == nil {
+		return ""
+	}
+	return driver
+}
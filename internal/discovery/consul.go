@@ -0,0 +1,53 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"new-openclaw/pkg/config"
+)
+
+// consulRegistrar Consul 注册（使用 Agent HTTP API，由 Consul 主动探测健康检查 URL）
+type consulRegistrar struct {
+	endpoint string
+	ttl      time.Duration
+}
+
+func newConsulRegistrar(cfg config.DiscoveryConfig) *consulRegistrar {
+	return &consulRegistrar{endpoint: strings.TrimRight(cfg.Endpoint, "/"), ttl: cfg.TTL}
+}
+
+func (r *consulRegistrar) Register(ctx context.Context, inst *Instance) error {
+	// Consul 要求自动注销时间不少于 1 分钟
+	deregisterAfter := r.ttl * 3
+	if deregisterAfter < time.Minute {
+		deregisterAfter = time.Minute
+	}
+
+	body := map[string]interface{}{
+		"ID":      inst.ID,
+		"Name":    inst.Name,
+		"Address": inst.Address,
+		"Port":    inst.Port,
+		"Meta":    inst.Metadata,
+		"Check": map[string]interface{}{
+			"HTTP":                           inst.HealthURL,
+			"Interval":                       r.ttl.String(),
+			"Timeout":                        "5s",
+			"DeregisterCriticalServiceAfter": deregisterAfter.String(),
+		},
+	}
+	return doJSON(ctx, http.MethodPut, r.endpoint+"/v1/agent/service/register", body, nil)
+}
+
+// Heartbeat Consul 通过 HTTP 检查判断存活，这里仅确认实例仍在注册表中
+func (r *consulRegistrar) Heartbeat(ctx context.Context, inst *Instance) error {
+	return doJSON(ctx, http.MethodGet, fmt.Sprintf("%s/v1/agent/service/%s", r.endpoint, inst.ID), nil, nil)
+}
+
+func (r *consulRegistrar) Deregister(ctx context.Context, inst *Instance) error {
+	return doJSON(ctx, http.MethodPut, fmt.Sprintf("%s/v1/agent/service/deregister/%s", r.endpoint, inst.ID), nil, nil)
+}
@@ -0,0 +1,188 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"new-openclaw/pkg/config"
+)
+
+// 注册中心类型
+const (
+	ProviderConsul = "consul"
+	ProviderEtcd   = "etcd"
+	ProviderNacos  = "nacos"
+)
+
+// Instance 服务实例
+type Instance struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Address   string            `json:"address"`
+	Port      int               `json:"port"`
+	HealthURL string            `json:"health_url"`
+	Metadata  map[string]string `json:"metadata"`
+}
+
+// Registrar 注册中心客户端
+type Registrar interface {
+	// Register 注册实例
+	Register(ctx context.Context, inst *Instance) error
+	// Heartbeat 续约（TTL 内需至少调用一次）
+	Heartbeat(ctx context.Context, inst *Instance) error
+	// Deregister 注销实例
+	Deregister(ctx context.Context, inst *Instance) error
+}
+
+// errInstanceNotFound 注册中心中已不存在本实例
+var errInstanceNotFound = errors.New("实例未注册")
+
+var (
+	mu        sync.Mutex
+	registrar Registrar
+	instance  *Instance
+	stopCh    chan struct{}
+)
+
+// Register 启动时向注册中心注册本实例，并在后台定期续约
+func Register(cfg config.DiscoveryConfig, port string, version string) error {
+	if cfg.Provider == "" {
+		return nil
+	}
+
+	r, err := newRegistrar(cfg)
+	if err != nil {
+		return err
+	}
+
+	inst, err := buildInstance(cfg, port, version)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.Register(ctx, inst); err != nil {
+		return fmt.Errorf("服务注册失败: %w", err)
+	}
+
+	mu.Lock()
+	registrar, instance, stopCh = r, inst, make(chan struct{})
+	mu.Unlock()
+
+	go heartbeat(r, inst, cfg.TTL, stopCh)
+
+	log.Printf("✅ 已注册到 %s: %s (%s:%d)", cfg.Provider, inst.ID, inst.Address, inst.Port)
+	return nil
+}
+
+// Deregister 关闭时注销本实例
+func Deregister() {
+	mu.Lock()
+	r, inst, stop := registrar, instance, stopCh
+	registrar, instance, stopCh = nil, nil, nil
+	mu.Unlock()
+
+	if r == nil {
+		return
+	}
+	close(stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.Deregister(ctx, inst); err != nil {
+		log.Printf("服务注销失败: %v", err)
+		return
+	}
+	log.Printf("已从注册中心注销: %s", inst.ID)
+}
+
+// heartbeat 按 TTL 的 1/3 间隔续约，续约失败时尝试重新注册
+func heartbeat(r Registrar, inst *Instance, ttl time.Duration, stop chan struct{}) {
+	interval := ttl / 3
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			if err := r.Heartbeat(ctx, inst); err != nil {
+				log.Printf("服务续约失败，尝试重新注册: %v", err)
+				if err := r.Register(ctx, inst); err != nil {
+					log.Printf("服务重新注册失败: %v", err)
+				}
+			}
+			cancel()
+		}
+	}
+}
+
+// newRegistrar 按类型创建注册中心客户端
+func newRegistrar(cfg config.DiscoveryConfig) (Registrar, error) {
+	switch cfg.Provider {
+	case ProviderConsul:
+		return newConsulRegistrar(cfg), nil
+	case ProviderEtcd:
+		return newEtcdRegistrar(cfg), nil
+	case ProviderNacos:
+		return newNacosRegistrar(cfg), nil
+	default:
+		return nil, fmt.Errorf("不支持的注册中心类型: %s", cfg.Provider)
+	}
+}
+
+// buildInstance 生成本实例信息
+func buildInstance(cfg config.DiscoveryConfig, port string, version string) (*Instance, error) {
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, fmt.Errorf("无效的端口: %s", port)
+	}
+
+	address := cfg.ServiceAddress
+	if address == "" {
+		address = localIP()
+	}
+
+	metadata := map[string]string{"version": version}
+	for k, v := range cfg.Metadata {
+		metadata[k] = v
+	}
+
+	hostname, _ := os.Hostname()
+	return &Instance{
+		ID:        fmt.Sprintf("%s-%s-%d", cfg.ServiceName, hostname, p),
+		Name:      cfg.ServiceName,
+		Address:   address,
+		Port:      p,
+		HealthURL: fmt.Sprintf("http://%s%s", net.JoinHostPort(address, port), cfg.HealthCheckPath),
+		Metadata:  metadata,
+	}, nil
+}
+
+// localIP 获取本机第一个非回环 IPv4 地址
+func localIP() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "127.0.0.1"
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+	}
+	return "127.0.0.1"
+}
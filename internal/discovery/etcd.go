@@ -0,0 +1,101 @@
+package discovery
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"new-openclaw/pkg/config"
+)
+
+// etcdRegistrar etcd 注册（使用 v3 gRPC-Gateway JSON API，实例信息绑定租约，租约过期自动删除）
+type etcdRegistrar struct {
+	endpoint string
+	prefix   string
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	leaseID string
+}
+
+func newEtcdRegistrar(cfg config.DiscoveryConfig) *etcdRegistrar {
+	prefix := cfg.Namespace
+	if prefix == "" {
+		prefix = "/services"
+	}
+	return &etcdRegistrar{
+		endpoint: strings.TrimRight(cfg.Endpoint, "/"),
+		prefix:   strings.TrimRight(prefix, "/"),
+		ttl:      cfg.TTL,
+	}
+}
+
+func (r *etcdRegistrar) key(inst *Instance) string {
+	return fmt.Sprintf("%s/%s/%s", r.prefix, inst.Name, inst.ID)
+}
+
+func (r *etcdRegistrar) Register(ctx context.Context, inst *Instance) error {
+	ttl := int64(r.ttl / time.Second)
+	if ttl < 1 {
+		ttl = 1
+	}
+
+	var grant struct {
+		ID string `json:"ID"`
+	}
+	if err := doJSON(ctx, http.MethodPost, r.endpoint+"/v3/lease/grant", map[string]interface{}{"TTL": ttl}, &grant); err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(inst)
+	if err != nil {
+		return err
+	}
+
+	body := map[string]interface{}{
+		"key":   base64.StdEncoding.EncodeToString([]byte(r.key(inst))),
+		"value": base64.StdEncoding.EncodeToString(value),
+		"lease": grant.ID,
+	}
+	if err := doJSON(ctx, http.MethodPost, r.endpoint+"/v3/kv/put", body, nil); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.leaseID = grant.ID
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *etcdRegistrar) Heartbeat(ctx context.Context, inst *Instance) error {
+	r.mu.Lock()
+	leaseID := r.leaseID
+	r.mu.Unlock()
+
+	var resp struct {
+		Result struct {
+			TTL string `json:"TTL"`
+		} `json:"result"`
+	}
+	if err := doJSON(ctx, http.MethodPost, r.endpoint+"/v3/lease/keepalive", map[string]interface{}{"ID": leaseID}, &resp); err != nil {
+		return err
+	}
+	// 租约已过期时 TTL 为空或 0
+	if resp.Result.TTL == "" || resp.Result.TTL == "0" {
+		return fmt.Errorf("租约 %s 已过期", leaseID)
+	}
+	return nil
+}
+
+func (r *etcdRegistrar) Deregister(ctx context.Context, inst *Instance) error {
+	r.mu.Lock()
+	leaseID := r.leaseID
+	r.mu.Unlock()
+
+	return doJSON(ctx, http.MethodPost, r.endpoint+"/v3/lease/revoke", map[string]interface{}{"ID": leaseID}, nil)
+}
@@ -0,0 +1,89 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/pkg/config"
+)
+
+// nacosRegistrar Nacos 注册（使用 Open API 注册临时实例，通过心跳续约）
+type nacosRegistrar struct {
+	endpoint  string
+	namespace string
+	ttl       time.Duration
+}
+
+func newNacosRegistrar(cfg config.DiscoveryConfig) *nacosRegistrar {
+	return &nacosRegistrar{
+		endpoint:  strings.TrimRight(cfg.Endpoint, "/"),
+		namespace: cfg.Namespace,
+		ttl:       cfg.TTL,
+	}
+}
+
+func (r *nacosRegistrar) params(inst *Instance) url.Values {
+	params := url.Values{}
+	params.Set("serviceName", inst.Name)
+	params.Set("ip", inst.Address)
+	params.Set("port", strconv.Itoa(inst.Port))
+	params.Set("ephemeral", "true")
+	if r.namespace != "" {
+		params.Set("namespaceId", r.namespace)
+	}
+	return params
+}
+
+func (r *nacosRegistrar) Register(ctx context.Context, inst *Instance) error {
+	metadata, _ := json.Marshal(r.metadata(inst))
+
+	params := r.params(inst)
+	params.Set("metadata", string(metadata))
+	return doJSON(ctx, http.MethodPost, r.endpoint+"/nacos/v1/ns/instance?"+params.Encode(), nil, nil)
+}
+
+func (r *nacosRegistrar) Heartbeat(ctx context.Context, inst *Instance) error {
+	beat, _ := json.Marshal(map[string]interface{}{
+		"serviceName": inst.Name,
+		"ip":          inst.Address,
+		"port":        inst.Port,
+		"metadata":    r.metadata(inst),
+	})
+
+	params := r.params(inst)
+	params.Set("beat", string(beat))
+
+	var resp struct {
+		Code int `json:"code"`
+	}
+	if err := doJSON(ctx, http.MethodPut, r.endpoint+"/nacos/v1/ns/instance/beat?"+params.Encode(), nil, &resp); err != nil {
+		return err
+	}
+	// 20404 表示实例不存在，需要重新注册
+	if resp.Code == 20404 {
+		return errInstanceNotFound
+	}
+	return nil
+}
+
+func (r *nacosRegistrar) Deregister(ctx context.Context, inst *Instance) error {
+	return doJSON(ctx, http.MethodDelete, r.endpoint+"/nacos/v1/ns/instance?"+r.params(inst).Encode(), nil, nil)
+}
+
+// metadata 附加健康检查地址及心跳超时（Nacos 按 preserved.* 元数据判定实例过期）
+func (r *nacosRegistrar) metadata(inst *Instance) map[string]string {
+	metadata := map[string]string{"health_url": inst.HealthURL}
+	for k, v := range inst.Metadata {
+		metadata[k] = v
+	}
+	ttl := r.ttl.Milliseconds()
+	metadata["preserved.heart.beat.interval"] = strconv.FormatInt(ttl/3, 10)
+	metadata["preserved.heart.beat.timeout"] = strconv.FormatInt(ttl, 10)
+	metadata["preserved.ip.delete.timeout"] = strconv.FormatInt(ttl*2, 10)
+	return metadata
+}
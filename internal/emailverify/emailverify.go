@@ -0,0 +1,160 @@
+// Package emailverify 注册邮箱验证：签发带 HMAC 签名的验证链接并通过邮件发送，用户打开链接后标记邮箱已验证。
+// 签名覆盖用户 ID、过期时间与当前邮箱，邮箱修改后旧链接自动失效；链接中不包含邮箱本身
+package emailverify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/mail"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/store"
+	"new-openclaw/pkg/config"
+	"new-openclaw/pkg/secrets"
+
+	"gorm.io/gorm"
+)
+
+// Path 验证链接的接口路径
+const Path = "/api/v1/public/verify-email"
+
+var (
+	// ErrInvalidToken 链接格式错误、签名不匹配或邮箱已修改
+	ErrInvalidToken = errors.New("验证链接无效")
+	// ErrExpired 链接已过期
+	ErrExpired = errors.New("验证链接已过期，请重新发送验证邮件")
+	// ErrTooFrequent 重新发送过于频繁
+	ErrTooFrequent = errors.New("验证邮件发送过于频繁，请稍后再试")
+	// ErrNoBaseURL 未配置 APP_BASE_URL，无法生成验证链接
+	ErrNoBaseURL = errors.New("未配置 APP_BASE_URL，无法生成验证链接")
+)
+
+var cfg = config.EmailVerifyConfig{
+	TTL:            24 * time.Hour,
+	ResendInterval: time.Minute,
+}
+
+var (
+	// secret 签名密钥
+	secret []byte
+	// baseURL 验证链接的站点地址（APP_BASE_URL；不使用请求的 Host，避免伪造 Host 头把带有效令牌的链接指向其他域名）
+	baseURL string
+)
+
+// keyPurpose 由 JWT 密钥派生签名密钥时使用的用途标识
+const keyPurpose = "email-verify-v1"
+
+// Configure 设置签名密钥、有效期与验证链接的站点地址；未配置 EMAIL_VERIFY_SECRET 时由 masterSecret（JWT 密钥）派生专用密钥。
+// 配置了 SMTP（真正向用户发信）却未配置站点地址时返回错误
+func Configure(c config.EmailVerifyConfig, masterSecret, siteURL string) error {
+	siteURL = strings.TrimRight(strings.TrimSpace(siteURL), "/")
+	if siteURL == "" && mail.Enabled() {
+		return ErrNoBaseURL
+	}
+	if c.TTL <= 0 {
+		c.TTL = 24 * time.Hour
+	}
+	cfg = c
+	baseURL = siteURL
+	if c.Secret != "" {
+		secret = []byte(c.Secret)
+	} else {
+		secret = secrets.DeriveKey(masterSecret, keyPurpose)
+	}
+	return nil
+}
+
+// RedirectURL 验证完成后跳转的前端地址（为空时验证接口返回 JSON）
+func RedirectURL() string {
+	return cfg.RedirectURL
+}
+
+// Token 为用户签发验证令牌：{用户 ID}.{过期时间戳}.{签名}
+func Token(u *model.User, now time.Time) string {
+	id := strconv.FormatUint(uint64(u.ID), 10)
+	exp := strconv.FormatInt(now.Add(cfg.TTL).Unix(), 10)
+	return id + "." + exp + "." + sign(id, exp, u.Email)
+}
+
+func sign(id, exp, email string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(id + "\n" + exp + "\n" + strings.ToLower(email)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Send 发送验证邮件；同一用户在 ResendInterval 内只发送一次，未配置站点地址时不发送
+func Send(ctx context.Context, u *model.User) error {
+	if baseURL == "" {
+		return ErrNoBaseURL
+	}
+	if cfg.ResendInterval > 0 {
+		key := "verify-mail:" + strconv.FormatUint(uint64(u.ID), 10)
+		first, err := store.For(store.ComponentLogin).SetNX(ctx, key, "1", cfg.ResendInterval)
+		if err != nil {
+			return err
+		}
+		if !first {
+			return ErrTooFrequent
+		}
+	}
+
+	link := baseURL + Path + "?" + url.Values{"token": {Token(u, time.Now())}}.Encode()
+	body := fmt.Sprintf("%s，您好：\n\n请在 %s 内打开以下链接完成邮箱验证：\n\n%s\n\n如果这不是您本人的操作，请忽略本邮件。",
+		u.Username, cfg.TTL, link)
+	return mail.Send(u.Email, "请验证您的邮箱", body)
+}
+
+// Verify 校验令牌并标记邮箱已验证；已验证过的用户再次打开链接同样返回成功
+func Verify(ctx context.Context, token string) (*model.User, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	id, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, errors.New("数据库未连接")
+	}
+	var user model.User
+	if err := db.WithContext(ctx).First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	// 先校验签名再判断过期，伪造的令牌一律只得到“无效”
+	if !hmac.Equal([]byte(parts[2]), []byte(sign(parts[0], parts[1], user.Email))) {
+		return nil, ErrInvalidToken
+	}
+	if user.EmailVerified() {
+		return &user, nil
+	}
+	if time.Now().Unix() > exp {
+		return nil, ErrExpired
+	}
+
+	now := time.Now()
+	if err := db.WithContext(ctx).Model(&user).Update("email_verified_at", now).Error; err != nil {
+		return nil, err
+	}
+	user.EmailVerifiedAt = &now
+	return &user, nil
+}
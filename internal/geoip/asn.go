@@ -0,0 +1,93 @@
+package geoip
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"new-openclaw/internal/jobs"
+	"new-openclaw/internal/metrics"
+)
+
+// ASN 自治系统信息
+type ASN struct {
+	Number       uint32 `json:"number"`
+	Organization string `json:"organization"`
+}
+
+var (
+	asnDB = &database{name: "ASN"}
+
+	lookupsTotal = metrics.NewCounterVec("geoip_asn_lookups_total", "ASN 查询次数", "result")
+)
+
+// Configure 设置 ASN 数据库路径并加载（路径为空时不启用 ASN 查询）
+func Configure(dbPath string) error {
+	return asnDB.configure(dbPath)
+}
+
+// Enabled 是否已加载 ASN 数据库
+func Enabled() bool {
+	return asnDB.enabled()
+}
+
+// Reload 文件有更新时重新加载（如每周更新的 GeoLite2-ASN），返回是否重新加载；加载失败时保留旧数据
+func Reload() (bool, error) {
+	return asnDB.reload()
+}
+
+// Lookup 查询 IP 所属的 ASN（未加载数据库或不在库中时返回 false）
+func Lookup(ip net.IP) (ASN, bool) {
+	fields, loaded, err := asnDB.lookup(ip)
+	if !loaded {
+		return ASN{}, false
+	}
+	if err != nil {
+		lookupsTotal.Inc("error")
+		return ASN{}, false
+	}
+	number, _ := fields["autonomous_system_number"].(uint64)
+	if number == 0 {
+		lookupsTotal.Inc("miss")
+		return ASN{}, false
+	}
+	org, _ := fields["autonomous_system_organization"].(string)
+	lookupsTotal.Inc("hit")
+	return ASN{Number: uint32(number), Organization: org}, true
+}
+
+// LookupNumber 查询 IP 所属的 ASN 编号（middleware.IPFilterConfig.ASNLookup）
+func LookupNumber(ip net.IP) (uint32, bool) {
+	asn, ok := Lookup(ip)
+	return asn.Number, ok
+}
+
+// RegisterReloadJob 注册定时检查数据库文件更新的任务（每个实例都执行）
+func RegisterReloadJob(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	if asnDB.configured() {
+		jobs.Register(jobs.Job{
+			Name:        "geoip_asn_reload",
+			Description: "ASN 数据库文件更新后重新加载",
+			Interval:    interval,
+			Run: func(context.Context) error {
+				_, err := Reload()
+				return err
+			},
+		})
+	}
+	if countryDB.configured() {
+		jobs.Register(jobs.Job{
+			Name:        "geoip_country_reload",
+			Description: "国家/地区数据库文件更新后重新加载",
+			Interval:    interval,
+			Run: func(context.Context) error {
+				_, err := countryDB.reload()
+				return err
+			},
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package geoip
+
+import (
+	"net"
+
+	"new-openclaw/internal/metrics"
+)
+
+// Country 国家/地区信息
+type Country struct {
+	// ISO 3166-1 代码，如 CN、US
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+var (
+	countryDB = &database{name: "国家/地区"}
+
+	countryLookupsTotal = metrics.NewCounterVec("geoip_country_lookups_total", "国家/地区查询次数", "result")
+)
+
+// ConfigureCountry 设置国家/地区数据库（GeoLite2-Country 或 GeoLite2-City）路径并加载，路径为空时不启用
+func ConfigureCountry(dbPath string) error {
+	return countryDB.configure(dbPath)
+}
+
+// CountryEnabled 是否已加载国家/地区数据库
+func CountryEnabled() bool {
+	return countryDB.enabled()
+}
+
+// LookupCountry 查询 IP 所在的国家/地区（未加载数据库或不在库中时返回 false）
+func LookupCountry(ip net.IP) (Country, bool) {
+	fields, loaded, err := countryDB.lookup(ip)
+	if !loaded {
+		return Country{}, false
+	}
+	if err != nil {
+		countryLookupsTotal.Inc("error")
+		return Country{}, false
+	}
+	// 优先使用 country，部分 IP（如卫星、匿名代理）只有 registered_country
+	country, ok := fields["country"].(map[string]interface{})
+	if !ok {
+		country, ok = fields["registered_country"].(map[string]interface{})
+	}
+	code, _ := country["iso_code"].(string)
+	if !ok || code == "" {
+		countryLookupsTotal.Inc("miss")
+		return Country{}, false
+	}
+
+	var name string
+	if names, ok := country["names"].(map[string]interface{}); ok {
+		if name, _ = names["zh-CN"].(string); name == "" {
+			name, _ = names["en"].(string)
+		}
+	}
+	countryLookupsTotal.Inc("hit")
+	return Country{Code: code, Name: name}, true
+}
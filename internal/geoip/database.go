@@ -0,0 +1,92 @@
+package geoip
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"new-openclaw/pkg/mmdb"
+)
+
+// database 按文件修改时间热更新的 mmdb 数据库
+type database struct {
+	name    string
+	mu      sync.RWMutex
+	path    string
+	reader  *mmdb.Reader
+	modTime time.Time
+}
+
+// configure 设置数据库路径并加载（路径为空时不启用）
+func (d *database) configure(dbPath string) error {
+	d.mu.Lock()
+	d.path = dbPath
+	d.mu.Unlock()
+	if dbPath == "" {
+		return nil
+	}
+	_, err := d.reload()
+	return err
+}
+
+func (d *database) enabled() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.reader != nil
+}
+
+func (d *database) configured() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.path != ""
+}
+
+// reload 文件有更新时重新加载，返回是否重新加载；加载失败时保留旧数据
+func (d *database) reload() (bool, error) {
+	d.mu.RLock()
+	p, loaded := d.path, d.modTime
+	d.mu.RUnlock()
+	if p == "" {
+		return false, nil
+	}
+
+	info, err := os.Stat(p)
+	if err != nil {
+		return false, err
+	}
+	if info.ModTime().Equal(loaded) {
+		return false, nil
+	}
+
+	r, err := mmdb.Open(p)
+	if err != nil {
+		return false, fmt.Errorf("加载 %s 数据库失败: %w", d.name, err)
+	}
+	md := r.Metadata()
+	d.mu.Lock()
+	d.reader, d.modTime = r, info.ModTime()
+	d.mu.Unlock()
+	log.Printf("✅ %s 数据库已加载: %s（%s，构建于 %s）", d.name, p, md.DatabaseType,
+		time.Unix(int64(md.BuildEpoch), 0).Format("2006-01-02"))
+	return true, nil
+}
+
+// lookup 查询 IP 的记录（未加载数据库时 loaded 为 false）
+func (d *database) lookup(ip net.IP) (fields map[string]interface{}, loaded bool, err error) {
+	d.mu.RLock()
+	r := d.reader
+	d.mu.RUnlock()
+	if r == nil || ip == nil {
+		return nil, false, nil
+	}
+
+	record, found, err := r.Lookup(ip)
+	if err != nil || !found {
+		return nil, true, err
+	}
+	fields, _ = record.(map[string]interface{})
+	return fields, true, nil
+}
@@ -0,0 +1,357 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/emailverify"
+	"new-openclaw/internal/loginguard"
+	"new-openclaw/internal/loginhistory"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/passwordreset"
+	"new-openclaw/internal/refreshtoken"
+	"new-openclaw/internal/session"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// loginUser 注册用户密码校验通过后签发 Token；邮箱未验证的用户只获得受限的权限范围
+func loginUser(c *gin.Context, db *gorm.DB, user *model.User) {
+	if user.Status != model.UserStatusActive {
+		recordLogin(c, user.Username, user, "password", model.LoginResultFailed, "账号已禁用")
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "账号已禁用",
+		})
+		return
+	}
+
+	scopes := middleware.RoleScopes[user.Role]
+	if !user.EmailVerified() {
+		scopes = middleware.UnverifiedScopes
+	}
+
+	jwtConfig := middleware.CurrentJWTConfig()
+	userID := strconv.FormatUint(uint64(user.ID), 10)
+	token, err := middleware.GenerateTokenWithScopes(userID, user.Username, user.Role, scopes, jwtConfig)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "生成令牌失败",
+		})
+		return
+	}
+	refreshToken, _ := refreshtoken.Issue(c.Request.Context(), userID, user.Username, jwtConfig)
+
+	now := time.Now()
+	db.Model(user).Update("last_login_at", now)
+
+	middleware.SetTokenCookie(c, token, now.Add(jwtConfig.TokenExpiry), jwtConfig)
+	trackSession(c, token)
+	recordLogin(c, user.Username, user, "password", model.LoginResultSuccess, "")
+
+	message := "登录成功"
+	if !user.EmailVerified() {
+		message = "登录成功，邮箱尚未验证，验证前只能查看个人信息"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": message,
+		"data": gin.H{
+			"token":          token,
+			"refresh_token":  refreshToken,
+			"expires_in":     int(jwtConfig.TokenExpiry.Seconds()),
+			"email_verified": user.EmailVerified(),
+			"scopes":         scopes,
+		},
+	})
+}
+
+// VerifyEmail 打开验证邮件中的链接完成邮箱验证；配置了 EMAIL_VERIFY_REDIRECT_URL 时跳转到前端页面
+// @Summary 验证邮箱
+// @Tags Public
+// @Produce json
+// @Param token query string true "验证邮件中的令牌"
+// @Success 200 {object} map[string]interface{}
+// @Success 302 {string} string "跳转到前端页面"
+// @Router /api/v1/public/verify-email [get]
+func VerifyEmail(c *gin.Context) {
+	user, err := emailverify.Verify(c.Request.Context(), c.Query("token"))
+
+	if redirect := emailverify.RedirectURL(); redirect != "" {
+		result := url.Values{"email_verified": {"1"}}
+		switch {
+		case errors.Is(err, emailverify.ErrExpired):
+			result = url.Values{"error": {"expired"}}
+		case errors.Is(err, emailverify.ErrInvalidToken):
+			result = url.Values{"error": {"invalid_token"}}
+		case err != nil:
+			log.Printf("邮箱验证失败: %v", err)
+			result = url.Values{"error": {"server_error"}}
+		}
+		sep := "?"
+		if strings.Contains(redirect, "?") {
+			sep = "&"
+		}
+		c.Redirect(http.StatusFound, redirect+sep+result.Encode())
+		return
+	}
+
+	switch {
+	case errors.Is(err, emailverify.ErrExpired), errors.Is(err, emailverify.ErrInvalidToken):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	case err != nil:
+		log.Printf("邮箱验证失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "邮箱验证失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "邮箱验证成功，请重新登录",
+		"data": gin.H{
+			"user_id":           user.ID,
+			"username":          user.Username,
+			"email_verified_at": user.EmailVerifiedAt,
+		},
+	})
+}
+
+// ResendVerification 重新发送验证邮件。无论邮箱是否注册都返回相同结果，避免被用于探测已注册的邮箱
+// @Summary 重新发送验证邮件
+// @Tags Public
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "邮箱"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/public/resend-verification [post]
+func ResendVerification(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	var user model.User
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	err := db.Where("email = ? AND password <> '' AND email_verified_at IS NULL AND status = ?", email, model.UserStatusActive).
+		First(&user).Error
+	if err == nil {
+		if err := emailverify.Send(c.Request.Context(), &user); err != nil && !errors.Is(err, emailverify.ErrTooFrequent) {
+			log.Printf("发送验证邮件失败: user=%s err=%v", user.Username, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "如果该邮箱已注册且尚未验证，验证邮件已发送",
+	})
+}
+
+// ForgotPassword 忘记密码：向注册邮箱发送一次性重置令牌。无论邮箱是否注册都返回相同结果
+// @Summary 忘记密码
+// @Tags Public
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "邮箱"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/public/forgot-password [post]
+func ForgotPassword(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	// 只有设置了密码的用户可以重置（第三方登录创建的用户没有密码）
+	var user model.User
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	err := db.Where("email = ? AND password <> '' AND status = ?", email, model.UserStatusActive).First(&user).Error
+	if err == nil {
+		if err := passwordreset.Request(c.Request.Context(), &user); err != nil && !errors.Is(err, passwordreset.ErrTooFrequent) {
+			log.Printf("发送重置密码邮件失败: user=%s err=%v", user.Username, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "如果该邮箱已注册，重置密码邮件已发送",
+	})
+}
+
+// ResetPassword 凭邮件中的令牌设置新密码，成功后吊销该用户的全部会话
+// @Summary 重置密码
+// @Tags Public
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "重置令牌、新密码"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/public/reset-password [post]
+func ResetPassword(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := passwordreset.Reset(ctx, req.Token, req.Password)
+	switch {
+	case errors.Is(err, passwordreset.ErrUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": err.Error(),
+		})
+		return
+	case err != nil:
+		// 令牌无效或新密码不符合密码策略
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 已登录的设备全部下线；刷新 Token 有效期更长，吊销时长取两者较大值
+	jwtConfig := middleware.CurrentJWTConfig()
+	ttl := jwtConfig.TokenExpiry
+	if jwtConfig.RefreshExpiry > ttl {
+		ttl = jwtConfig.RefreshExpiry
+	}
+	userID := strconv.FormatUint(uint64(user.ID), 10)
+	if err := session.RevokeAll(ctx, jwtConfig.Issuer, userID, ttl); err != nil {
+		log.Printf("重置密码后吊销会话失败: user=%s err=%v", user.Username, err)
+	}
+	// 清除登录失败计数与锁定
+	loginguard.Succeed(ctx, loginguard.ScopeUser, user.Username)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "密码已重置，请使用新密码登录",
+	})
+}
+
+// GetLoginHistory 当前用户最近的登录记录
+// @Summary 当前用户的登录记录
+// @Tags User
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param result query string false "登录结果"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/profile/logins [get]
+func GetLoginHistory(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	userID, _ := strconv.ParseUint(c.GetString("user_id"), 10, 64)
+	query := db.Model(&model.LoginHistory{}).Where("realm = ? AND account_id = ?", model.LoginRealmUser, userID)
+	if result := c.Query("result"); result != "" {
+		query = query.Where("result = ?", result)
+	}
+
+	var records []model.LoginHistory
+	var total int64
+
+	query.Count(&total)
+	query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&records)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data": gin.H{
+			"list":      records,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// recordLogin 记录用户登录尝试（user 为空表示账号不存在或尚未查询）；只向已验证的邮箱发送新设备提醒
+func recordLogin(c *gin.Context, username string, user *model.User, method, result, reason string) {
+	entry := loginhistory.Entry{
+		Realm:     model.LoginRealmUser,
+		Username:  username,
+		Method:    method,
+		Result:    result,
+		Reason:    reason,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	if user != nil {
+		entry.AccountID = user.ID
+		if user.EmailVerified() {
+			entry.Email = user.Email
+		}
+	}
+	loginhistory.Record(c.Request.Context(), entry)
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"new-openclaw/internal/captcha"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCaptcha 获取人机验证：图片验证码返回 captcha_id 与 PNG（data URL），第三方服务返回 site_key
+// @Summary 获取人机验证
+// @Tags Public
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/public/captcha [get]
+func GetCaptcha(c *gin.Context) {
+	if !captcha.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "未启用人机验证",
+		})
+		return
+	}
+
+	challenge, err := captcha.New(c.Request.Context())
+	if err != nil {
+		log.Printf("生成验证码失败: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": captcha.ErrUnavailable.Error(),
+		})
+		return
+	}
+
+	// 每次获取都是新的验证码，不能被缓存
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    challenge,
+	})
+}
@@ -1,16 +1,21 @@
 package handler
 
 import (
-	"context"
 	"net/http"
 	"time"
 
-	"new-openclaw/internal/database"
+	"new-openclaw/internal/health"
+	"new-openclaw/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 // Ping 简单的 ping 接口
+// @Summary 存活检查
+// @Tags Health
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /ping [get]
 func Ping(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "pong",
@@ -18,6 +23,11 @@ func Ping(c *gin.Context) {
 }
 
 // HealthCheck 健康检查接口
+// @Summary 健康检查（数据库、Redis、MongoDB、审计日志）
+// @Tags Health
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /health [get]
 func HealthCheck(c *gin.Context) {
 	status := gin.H{
 		"status":    "ok",
@@ -26,42 +36,19 @@ func HealthCheck(c *gin.Context) {
 		"version":   "1.0.0",
 	}
 
-	// 检查 MySQL
-	if db := database.GetMySQL(); db != nil {
-		sqlDB, err := db.DB()
-		if err == nil && sqlDB.Ping() == nil {
-			status["mysql"] = "connected"
-		} else {
-			status["mysql"] = "disconnected"
-		}
-	} else {
-		status["mysql"] = "not configured"
-	}
-
-	// 检查 Redis
-	if rdb := database.GetRedis(); rdb != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-		if rdb.Ping(ctx).Err() == nil {
-			status["redis"] = "connected"
-		} else {
-			status["redis"] = "disconnected"
-		}
-	} else {
-		status["redis"] = "not configured"
+	// 检查 MySQL（单机模式为 SQLite）、Redis、MongoDB
+	for _, dep := range health.Probe(c.Request.Context()) {
+		status[dep.Name] = dep.Status
 	}
 
-	// 检查 MongoDB
-	if mdb := database.GetMongoDB(); mdb != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-		if database.MongoClient.Ping(ctx, nil) == nil {
-			status["mongodb"] = "connected"
-		} else {
-			status["mongodb"] = "disconnected"
+	// 检查审计日志写入（主文件不可写时已降级到备用输出）
+	if audit, ok := middleware.AuditHealthStatus(); ok {
+		status["audit"] = audit
+		if audit.Status != "ok" {
+			status["status"] = "degraded"
 		}
 	} else {
-		status["mongodb"] = "not configured"
+		status["audit"] = "not configured"
 	}
 
 	c.JSON(http.StatusOK, status)
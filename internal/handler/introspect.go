@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	adminmiddleware "new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/refreshtoken"
+	authtoken "new-openclaw/pkg/auth/token"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// introspection Token 内省响应（RFC 7662）
+type introspection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Nbf       int64  `json:"nbf,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+	// 扩展字段
+	Role        string `json:"role,omitempty"`
+	SubjectType string `json:"subject_type,omitempty"`
+	// 模拟登录签发的 Token：发起模拟的管理员（RFC 8693）
+	Act *authtoken.Actor `json:"act,omitempty"`
+}
+
+// Introspect Token 内省（RFC 7662）：内部服务通过 API 签名认证后校验 Token 并获取声明，无需共享 JWT 密钥。
+// 支持用户 Token 与管理后台 Token；无效、过期或已吊销的 Token 返回 {"active": false}
+// @Summary Token 内省
+// @Tags Service
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "待校验的 Token"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/auth/introspect [post]
+func Introspect(c *gin.Context) {
+	var req struct {
+		Token string `form:"token" json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_request",
+			"error_description": "缺少 token 参数",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, introspect(c.Request.Context(), req.Token))
+}
+
+// introspect 依次按用户 Token、用户刷新 Token、管理后台 Token 解析
+func introspect(ctx context.Context, token string) introspection {
+	if claims, err := middleware.ParseTokenWithConfig(token, middleware.CurrentJWTConfig()); err == nil {
+		result := introspection{
+			Active:      true,
+			Scope:       strings.Join(claims.GrantedScopes(), " "),
+			Username:    claims.Username,
+			TokenType:   "access_token",
+			Role:        claims.Role,
+			SubjectType: "user",
+			Act:         claims.Impersonator,
+		}
+		fillRegistered(&result, claims.RegisteredClaims)
+		return result
+	}
+
+	// 刷新 Token 只有标准声明，已被轮换（使用过）的视为无效
+	if claims, err := middleware.ParseRefreshToken(token, middleware.CurrentJWTConfig()); err == nil {
+		if !refreshtoken.Active(ctx, claims) {
+			return introspection{Active: false}
+		}
+		result := introspection{
+			Active:      true,
+			TokenType:   "refresh_token",
+			SubjectType: "user",
+		}
+		fillRegistered(&result, claims.RegisteredClaims)
+		return result
+	}
+
+	if claims, err := adminmiddleware.ParseToken(token); err == nil {
+		result := introspection{
+			Active:      true,
+			Username:    claims.Username,
+			TokenType:   "access_token",
+			Role:        claims.Role,
+			SubjectType: "admin",
+			Act:         claims.Impersonator,
+		}
+		fillRegistered(&result, claims.RegisteredClaims)
+		return result
+	}
+
+	return introspection{Active: false}
+}
+
+// fillRegistered 填充标准声明
+func fillRegistered(result *introspection, claims jwt.RegisteredClaims) {
+	result.Sub = claims.Subject
+	result.Iss = claims.Issuer
+	result.Jti = claims.ID
+	if claims.ExpiresAt != nil {
+		result.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		result.Iat = claims.IssuedAt.Unix()
+	}
+	if claims.NotBefore != nil {
+		result.Nbf = claims.NotBefore.Unix()
+	}
+}
@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"new-openclaw/internal/geoip"
+	"new-openclaw/internal/iprules"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/reputation"
+	"new-openclaw/internal/tags"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ipRuleRequest 添加、移除 IP 规则的请求体
+type ipRuleRequest struct {
+	// 单个 IP、CIDR 网段或 ASN（如 AS16509）
+	IP     string `json:"ip" binding:"required"`
+	Remark string `json:"remark" binding:"max=255"`
+}
+
+// ListIPRules 获取持久化的 IP 规则（type=whitelist/blacklist、tag=标签 过滤）
+// @Summary 获取 IP 规则
+// @Tags Admin
+// @Produce json
+// @Param type query string false "whitelist/blacklist"
+// @Param tag query string false "标签"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/ip/rules [get]
+func ListIPRules(c *gin.Context) {
+	ruleType := c.Query("type")
+	if ruleType != "" {
+		if err := iprules.ValidType(ruleType); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	rules, err := iprules.List(c.Request.Context(), ruleType, c.Query("tag"))
+	if err != nil {
+		ipRuleError(c, err)
+		return
+	}
+	ids := make([]uint, len(rules))
+	for i, rule := range rules {
+		ids[i] = rule.ID
+	}
+	if byID, err := tags.Map(c.Request.Context(), tags.ResourceIPRule, ids); err == nil {
+		for i := range rules {
+			rules[i].Tags = byID[rules[i].ID]
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    rules,
+	})
+}
+
+// AddIPBlacklist 添加 IP 黑名单（持久化并广播到所有实例）
+// @Summary 添加 IP 黑名单
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "IP 或 CIDR、备注"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/ip/blacklist [post]
+func AddIPBlacklist(c *gin.Context) {
+	addIPRule(c, model.IPRuleBlacklist, "已添加到黑名单")
+}
+
+// RemoveIPBlacklist 移除 IP 黑名单
+// @Summary 移除 IP 黑名单
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "IP 或 CIDR"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/ip/blacklist [delete]
+func RemoveIPBlacklist(c *gin.Context) {
+	removeIPRule(c, model.IPRuleBlacklist, "已从黑名单移除")
+}
+
+// AddIPWhitelist 添加 IP 白名单（白名单模式下生效）
+// @Summary 添加 IP 白名单
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "IP 或 CIDR、备注"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/ip/whitelist [post]
+func AddIPWhitelist(c *gin.Context) {
+	addIPRule(c, model.IPRuleWhitelist, "已添加到白名单")
+}
+
+// RemoveIPWhitelist 移除 IP 白名单
+// @Summary 移除 IP 白名单
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "IP 或 CIDR"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/ip/whitelist [delete]
+func RemoveIPWhitelist(c *gin.Context) {
+	removeIPRule(c, model.IPRuleWhitelist, "已从白名单移除")
+}
+
+// addIPRule 添加规则
+func addIPRule(c *gin.Context, ruleType, message string) {
+	var req ipRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	rule, err := iprules.Add(c.Request.Context(), ruleType, req.IP, req.Remark, c.GetString("username"))
+	if err != nil {
+		ipRuleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": rule.CIDR + " " + message,
+		"data":    rule,
+	})
+}
+
+// removeIPRule 移除规则
+func removeIPRule(c *gin.Context, ruleType, message string) {
+	var req ipRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := iprules.Remove(c.Request.Context(), ruleType, req.IP); err != nil {
+		ipRuleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": req.IP + " " + message,
+	})
+}
+
+// LookupIPASN 查询 IP 所属的 ASN（确认要封禁或放行的自治系统）
+// @Summary 查询 IP 所属 ASN
+// @Tags Admin
+// @Produce json
+// @Param ip query string true "IP 地址" example(8.8.8.8)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/ip/asn [get]
+func LookupIPASN(c *gin.Context) {
+	ip := net.ParseIP(middleware.NormalizeIP(c.Query("ip")))
+	if ip == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的 IP",
+		})
+		return
+	}
+	if !geoip.Enabled() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": "未配置 ASN 数据库（GEOIP_ASN_DB）",
+		})
+		return
+	}
+
+	asn, ok := geoip.Lookup(ip)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "ASN 数据库中没有该 IP",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"ip":           ip.String(),
+			"asn":          asn.Number,
+			"organization": asn.Organization,
+			"rule":         fmt.Sprintf("AS%d", asn.Number),
+		},
+	})
+}
+
+// GetIPReputation IP 信誉名单（威胁情报黑名单）的下载状态
+// @Summary IP 信誉名单状态
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/ip/reputation [get]
+func GetIPReputation(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"active": reputation.Active(),
+			"feeds":  reputation.Status(),
+		},
+	})
+}
+
+// ipRuleError 写入 IP 规则错误
+func ipRuleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, iprules.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	case errors.Is(err, iprules.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "操作失败: " + err.Error()})
+	}
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"net/http"
+
+	adminmiddleware "new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/pkg/auth/token"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS 公开 JWT 验证公钥（RS256/ES256，包含当前密钥和历史密钥）
+// @Summary JWT 验证公钥
+// @Tags Health
+// @Produce json
+// @Success 200 {object} token.JWKSet
+// @Router /.well-known/jwks.json [get]
+func JWKS(c *gin.Context) {
+	userKeys, _ := middleware.CurrentJWTConfig().Keys()
+	adminKeys, _ := adminmiddleware.DefaultConfig.Keys()
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, token.MergeJWKS(userKeys, adminKeys))
+}
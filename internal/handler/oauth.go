@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/oauth"
+	"new-openclaw/internal/refreshtoken"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OAuthProviders 已启用的第三方登录方式
+// @Summary 第三方登录方式
+// @Tags Public
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/public/oauth/providers [get]
+func OAuthProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    gin.H{"providers": oauth.Providers()},
+	})
+}
+
+// OAuthLogin 跳转到第三方授权页；redirect 为登录完成后跳转的前端地址（为空时回调返回 JSON）
+// @Summary 第三方登录
+// @Tags Public
+// @Param provider path string true "登录方式" example(github)
+// @Param redirect query string false "登录完成后跳转的前端地址"
+// @Success 302 {string} string "跳转到第三方授权页"
+// @Router /api/v1/public/oauth/{provider}/login [get]
+func OAuthLogin(c *gin.Context) {
+	target, state, err := oauth.Begin(c.Request.Context(), c.Param("provider"), c.Query("redirect"))
+	if err != nil {
+		oauthError(c, err)
+		return
+	}
+	http.SetCookie(c.Writer, oauth.StateCookie(c.Param("provider"), state))
+	c.Redirect(http.StatusFound, target)
+}
+
+// OAuthCallback 第三方授权回调：完成登录并签发本服务的 Token。
+// 登录时指定了 redirect 的，跳转回该地址，Token 放在 URL 片段中（不会发送到服务器、不进入访问日志）
+// @Summary 第三方授权回调
+// @Tags Public
+// @Produce json
+// @Param provider path string true "登录方式" example(github)
+// @Param code query string false "授权码"
+// @Param state query string true "登录时生成的 state"
+// @Param error query string false "第三方返回的错误"
+// @Success 200 {object} map[string]interface{}
+// @Success 302 {string} string "跳转回登录时指定的地址"
+// @Failure 502 {object} map[string]interface{}
+// @Router /api/v1/public/oauth/{provider}/callback [get]
+func OAuthCallback(c *gin.Context) {
+	ctx, name, state := c.Request.Context(), c.Param("provider"), c.Query("state")
+
+	// state 须由当前浏览器发起的登录生成（Cookie 中的摘要一致），否则不消费 state、不换取授权码
+	cookie, _ := c.Cookie(oauth.StateCookieName)
+	if !oauth.CheckStateCookie(cookie, state) {
+		middleware.ReportAuthFailure(c, middleware.AuthFailureOAuth, name, "state 与浏览器不匹配")
+		oauthError(c, oauth.ErrInvalidState)
+		return
+	}
+	http.SetCookie(c.Writer, oauth.StateCookie(name, ""))
+
+	// 用户在第三方页面拒绝授权
+	if reason := c.Query("error"); reason != "" {
+		redirect, err := oauth.Redirect(ctx, name, state)
+		if err == nil && redirect != "" {
+			c.Redirect(http.StatusFound, redirect+"#"+url.Values{"error": {reason}}.Encode())
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "第三方登录未完成: " + reason,
+		})
+		return
+	}
+
+	result, err := oauth.Complete(ctx, name, c.Query("code"), state)
+	if err != nil {
+		if !errors.Is(err, oauth.ErrUnknownProvider) {
+			middleware.ReportAuthFailure(c, middleware.AuthFailureOAuth, name, err.Error())
+			recordLogin(c, "", nil, "oauth:"+name, model.LoginResultFailed, err.Error())
+		}
+		if result != nil && result.Redirect != "" {
+			c.Redirect(http.StatusFound, result.Redirect+"#"+url.Values{"error": {oauthErrorCode(err)}}.Encode())
+			return
+		}
+		oauthError(c, err)
+		return
+	}
+
+	user := result.User
+	jwtConfig := middleware.CurrentJWTConfig()
+	userID := strconv.FormatUint(uint64(user.ID), 10)
+	scopes := middleware.RoleScopes[user.Role]
+	if !user.EmailVerified() {
+		scopes = middleware.UnverifiedScopes
+	}
+	token, err := middleware.GenerateTokenWithScopes(userID, user.Username, user.Role, scopes, jwtConfig)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "生成令牌失败",
+		})
+		return
+	}
+	refreshToken, _ := refreshtoken.Issue(c.Request.Context(), userID, user.Username, jwtConfig)
+	expiresIn := int(jwtConfig.TokenExpiry.Seconds())
+
+	middleware.SetTokenCookie(c, token, time.Now().Add(jwtConfig.TokenExpiry), jwtConfig)
+	trackSession(c, token)
+	recordLogin(c, user.Username, user, "oauth:"+name, model.LoginResultSuccess, "")
+	if result.Created {
+		log.Printf("第三方登录创建用户: provider=%s user=%s", name, user.Username)
+	}
+
+	if result.Redirect != "" {
+		fragment := url.Values{
+			"token":         {token},
+			"refresh_token": {refreshToken},
+			"expires_in":    {strconv.Itoa(expiresIn)},
+		}
+		c.Redirect(http.StatusFound, result.Redirect+"#"+fragment.Encode())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "登录成功",
+		"data": gin.H{
+			"token":         token,
+			"refresh_token": refreshToken,
+			"expires_in":    expiresIn,
+			"created":       result.Created,
+			"user":          oauthUser(user),
+		},
+	})
+}
+
+func oauthUser(u *model.User) gin.H {
+	return gin.H{
+		"id":       u.ID,
+		"username": u.Username,
+		"email":    u.Email,
+		"nickname": u.Nickname,
+		"avatar":   u.Avatar,
+	}
+}
+
+// oauthErrorCode 跳转回前端时的错误码（不暴露第三方接口的错误详情）
+func oauthErrorCode(err error) string {
+	switch {
+	case errors.Is(err, oauth.ErrInvalidState):
+		return "invalid_state"
+	case errors.Is(err, oauth.ErrRegistrationDisabled):
+		return "registration_disabled"
+	case errors.Is(err, oauth.ErrUserDisabled):
+		return "user_disabled"
+	}
+	log.Printf("第三方登录失败: %v", err)
+	return "server_error"
+}
+
+func oauthError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, oauth.ErrUnknownProvider):
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": err.Error()})
+	case errors.Is(err, oauth.ErrInvalidRedirect), errors.Is(err, oauth.ErrInvalidState):
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	case errors.Is(err, oauth.ErrRegistrationDisabled), errors.Is(err, oauth.ErrUserDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": err.Error()})
+	case errors.Is(err, oauth.ErrUnavailable):
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+	default:
+		log.Printf("第三方登录失败: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"code": 502, "message": "第三方登录失败"})
+	}
+}
@@ -0,0 +1,262 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/pat"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListPersonalTokens 获取当前用户的个人访问令牌（不含明文，已吊销的也会列出）
+// @Summary 获取个人访问令牌
+// @Tags User
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/tokens [get]
+func ListPersonalTokens(c *gin.Context) {
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	var tokens []model.PersonalAccessToken
+	db.Where("user_id = ?", c.GetString("user_id")).Order("id DESC").Find(&tokens)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    tokens,
+	})
+}
+
+// CreatePersonalToken 创建个人访问令牌，完整令牌只在创建时返回一次。
+// 权限范围不能超出当前登录拥有的范围；个人访问令牌与模拟登录的 Token 不能用来创建新令牌
+// @Summary 创建个人访问令牌
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "名称、权限范围、有效期"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/tokens [post]
+func CreatePersonalToken(c *gin.Context) {
+	var req struct {
+		Name   string   `json:"name" binding:"required,max=100"`
+		Scopes []string `json:"scopes" binding:"required,min=1"`
+		// 有效期（如 720h），与 expires_at 二选一，都为空时取 PAT_MAX_TTL
+		ExpiresIn string     `json:"expires_in"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	claims := c.MustGet("claims").(*middleware.Claims)
+	if middleware.ViaPersonalToken(c) || claims.Impersonator != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "请使用本人登录的 Token 创建个人访问令牌",
+		})
+		return
+	}
+
+	scopes, err := personalTokenScopes(req.Scopes, claims.GrantedScopes())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	now := time.Now()
+	cfg := pat.Config()
+	expiresAt := req.ExpiresAt
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "无效的有效期: " + req.ExpiresIn,
+			})
+			return
+		}
+		t := now.Add(d)
+		expiresAt = &t
+	}
+	if expiresAt == nil && cfg.MaxTTL > 0 {
+		t := now.Add(cfg.MaxTTL)
+		expiresAt = &t
+	}
+	if expiresAt != nil && !expiresAt.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "过期时间必须晚于当前时间",
+		})
+		return
+	}
+	if cfg.MaxTTL > 0 && expiresAt.After(now.Add(cfg.MaxTTL)) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "有效期不能超过 " + cfg.MaxTTL.String(),
+		})
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	userID, err := strconv.ParseUint(claims.UserID, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "当前账号不能创建个人访问令牌",
+		})
+		return
+	}
+
+	if cfg.MaxPerUser > 0 {
+		var count int64
+		db.Model(&model.PersonalAccessToken{}).
+			Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+			Count(&count)
+		if count >= int64(cfg.MaxPerUser) {
+			c.JSON(http.StatusConflict, gin.H{
+				"code":    409,
+				"message": "有效的个人访问令牌数已达上限 " + strconv.Itoa(cfg.MaxPerUser) + "，请先吊销不再使用的令牌",
+			})
+			return
+		}
+	}
+
+	plain, prefix, hash, err := pat.Generate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "生成令牌失败",
+		})
+		return
+	}
+
+	token := model.PersonalAccessToken{
+		UserID:    uint(userID),
+		Name:      req.Name,
+		Prefix:    prefix,
+		TokenHash: hash,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := db.Create(&token).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "创建失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "创建成功，请妥善保存令牌（之后无法再次查看）",
+		"data": gin.H{
+			"personal_token": token,
+			"token":          plain,
+		},
+	})
+}
+
+// RevokePersonalToken 吊销当前用户的个人访问令牌，立即生效（记录保留）
+// @Summary 吊销个人访问令牌
+// @Tags User
+// @Produce json
+// @Param id path int true "令牌 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/tokens/{id} [delete]
+func RevokePersonalToken(c *gin.Context) {
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	var token model.PersonalAccessToken
+	err := db.Where("id = ? AND user_id = ?", c.Param("id"), c.GetString("user_id")).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "个人访问令牌不存在",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查询失败",
+		})
+		return
+	}
+
+	if token.RevokedAt == nil {
+		if err := db.Model(&token).Update("revoked_at", time.Now()).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "吊销失败: " + err.Error(),
+			})
+			return
+		}
+	}
+	pat.Invalidate(c.Request.Context(), token.TokenHash)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "已吊销",
+	})
+}
+
+// personalTokenScopes 校验并去重权限范围（每一项都必须在当前拥有的范围内），返回逗号分隔的字符串
+func personalTokenScopes(items, granted []string) (string, error) {
+	seen := make(map[string]bool)
+	var scopes []string
+	for _, scope := range items {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || seen[scope] {
+			continue
+		}
+		if len(scope) > 64 || strings.ContainsAny(scope, ", \t") {
+			return "", errors.New("无效的权限范围: " + scope)
+		}
+		if !middleware.HasScope(granted, scope) {
+			return "", errors.New("超出当前权限范围: " + scope)
+		}
+		seen[scope] = true
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		return "", errors.New("至少需要一个权限范围")
+	}
+	return strings.Join(scopes, ","), nil
+}
@@ -1,9 +1,26 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/captcha"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/emailverify"
+	"new-openclaw/internal/loginguard"
+	"new-openclaw/internal/metrics"
 	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/refreshtoken"
+	"new-openclaw/internal/transform"
+	"new-openclaw/pkg/password"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // RegisterRoutes 注册所有路由
@@ -11,6 +28,8 @@ func RegisterRoutes(r *gin.Engine) {
 	// 健康检查（无需认证）
 	r.GET("/ping", Ping)
 	r.GET("/health", HealthCheck)
+	r.GET("/metrics", metrics.Handler)
+	r.GET("/.well-known/jwks.json", JWKS)
 
 	// API v1 分组
 	v1 := r.Group("/api/v1")
@@ -21,38 +40,94 @@ func RegisterRoutes(r *gin.Engine) {
 			public.POST("/login", Login)
 			public.POST("/register", Register)
 			public.POST("/refresh-token", RefreshToken)
+			public.GET("/captcha", GetCaptcha)
+
+			// 注册邮箱验证
+			public.GET("/verify-email", VerifyEmail)
+			public.POST("/resend-verification", ResendVerification)
+
+			// 忘记密码
+			public.POST("/forgot-password", ForgotPassword)
+			public.POST("/reset-password", ResetPassword)
+
+			// 第三方登录（OAuth2 / OIDC）
+			public.GET("/oauth/providers", OAuthProviders)
+			public.GET("/oauth/:provider/login", OAuthLogin)
+			public.GET("/oauth/:provider/callback", OAuthCallback)
 		}
 
 		// 需要 JWT 认证的接口
 		auth := v1.Group("/")
 		auth.Use(middleware.JWTAuth())
+		auth.Use(middleware.Redact())
 		{
 			// 用户相关
-			auth.GET("/users", GetUsers)
-			auth.GET("/users/:id", GetUserByID)
-			auth.POST("/users", CreateUser)
-			auth.PUT("/users/:id", UpdateUser)
-			auth.DELETE("/users/:id", DeleteUser)
+			auth.GET("/users", middleware.RequireScope("users:read"), GetUsers)
+			auth.GET("/users/:id", middleware.RequireScope("users:read"), GetUserByID)
+			auth.POST("/users", middleware.RequireScope("users:write"), CreateUser)
+			auth.PUT("/users/:id", middleware.RequireScope("users:write"), UpdateUser)
+			auth.DELETE("/users/:id", middleware.RequireScope("users:write"), DeleteUser)
 
 			// 用户信息
-			auth.GET("/profile", GetProfile)
-			auth.PUT("/profile", UpdateProfile)
+			auth.GET("/profile", middleware.RequireScope("profile:read"), GetProfile)
+			auth.GET("/profile/logins", middleware.RequireScope("profile:read"), GetLoginHistory)
+			auth.PUT("/profile", middleware.RequireScope("profile:write"), UpdateProfile)
+
+			// 活跃会话
+			auth.GET("/sessions", middleware.RequireScope("profile:read"), ListSessions)
+			auth.DELETE("/sessions", middleware.RequireScope("profile:write"), RevokeAllSessions)
+			auth.DELETE("/sessions/:id", middleware.RequireScope("profile:write"), RevokeSession)
+
+			// 个人访问令牌
+			auth.GET("/tokens", middleware.RequireScope("profile:read"), ListPersonalTokens)
+			auth.POST("/tokens", middleware.RequireScope("profile:write"), CreatePersonalToken)
+			auth.DELETE("/tokens/:id", middleware.RequireScope("profile:write"), RevokePersonalToken)
 		}
 
 		// 需要管理员权限的接口
 		admin := v1.Group("/admin")
 		admin.Use(middleware.JWTAuth())
 		admin.Use(middleware.RequireRole("admin"))
+		admin.Use(middleware.Redact())
 		{
 			admin.GET("/users", GetAllUsers)
 			admin.DELETE("/users/:id", AdminDeleteUser)
+			admin.GET("/ip/rules", ListIPRules)
+			admin.GET("/ip/asn", LookupIPASN)
+			admin.GET("/ip/reputation", GetIPReputation)
 			admin.POST("/ip/blacklist", AddIPBlacklist)
 			admin.DELETE("/ip/blacklist", RemoveIPBlacklist)
+			admin.POST("/ip/whitelist", AddIPWhitelist)
+			admin.DELETE("/ip/whitelist", RemoveIPWhitelist)
+		}
+
+		// Token 内省（供内部服务校验 Token，需 API 签名）
+		introspect := v1.Group("/auth")
+		introspect.Use(middleware.APISignature())
+		{
+			introspect.POST("/introspect", Introspect)
+		}
+
+		// 安全事件订阅（供威胁情报汇聚系统拉取，需 API 签名且 AppKey 已授权）
+		feed := v1.Group("/security")
+		feed.Use(middleware.APISignature())
+		{
+			feed.GET("/feed", SecurityFeed)
+		}
+
+		// 服务间调用（X-API-Key 认证，按 Key 的权限范围授权）
+		service := v1.Group("/service")
+		service.Use(middleware.APIKeyAuth())
+		service.Use(middleware.Redact())
+		{
+			service.GET("/users", middleware.RequireScope("users:read"), GetUsers)
+			service.GET("/users/:id", middleware.RequireScope("users:read"), GetUserByID)
 		}
 
 		// 需要 API 签名验证的接口（用于第三方调用）
 		signed := v1.Group("/signed")
 		signed.Use(middleware.APISignature())
+		signed.Use(middleware.Quota())
 		{
 			signed.POST("/webhook", HandleWebhook)
 			signed.POST("/callback", HandleCallback)
@@ -61,6 +136,14 @@ func RegisterRoutes(r *gin.Engine) {
 }
 
 // Login 用户登录
+// @Summary 用户登录
+// @Tags Public
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "用户名、密码"
+// @Success 200 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Router /api/v1/public/login [post]
 func Login(c *gin.Context) {
 	var req struct {
 		Username string `json:"username" binding:"required"`
@@ -75,44 +158,74 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// TODO: 验证用户名密码
-	// 这里仅作示例，实际应查询数据库验证
-	if req.Username == "admin" && req.Password == "admin123" {
-		token, err := middleware.GenerateToken("1", req.Username, "admin", middleware.DefaultJWTConfig)
-		if err != nil {
-			c.JSON(500, gin.H{
-				"code":    500,
-				"message": "生成令牌失败",
-			})
-			return
-		}
+	// 暴力破解防护：账号锁定或仍在失败后的等待间隔内
+	ctx, ip := c.Request.Context(), middleware.AbuseIP(c)
+	if d := loginguard.Check(ctx, loginguard.ScopeUser, req.Username, ip); !d.Allowed() {
+		recordLogin(c, req.Username, nil, "password", model.LoginResultFailed, d.Reason())
+		loginguard.Reject(c, d)
+		return
+	}
 
-		refreshToken, _ := middleware.GenerateRefreshToken("1", middleware.DefaultJWTConfig)
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
 
-		c.JSON(200, gin.H{
-			"code":    200,
-			"message": "登录成功",
-			"data": gin.H{
-				"token":         token,
-				"refresh_token": refreshToken,
-				"expires_in":    86400,
-			},
+	// 校验数据库中的密码哈希；账号不存在与密码错误返回相同的提示
+	var user model.User
+	err := db.Where("username = ?", req.Username).First(&user).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("登录查询用户失败: user=%s err=%v", req.Username, err)
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "查询用户失败",
+		})
+		return
+	}
+	if err != nil || !user.CheckPassword(req.Password) {
+		reason, account := "账号不存在", (*model.User)(nil)
+		if err == nil {
+			reason, account = "密码错误", &user
+		}
+		middleware.ReportAuthFailure(c, middleware.AuthFailureLogin, req.Username, reason)
+		recordLogin(c, req.Username, account, "password", model.LoginResultFailed, reason)
+		loginguard.Fail(ctx, loginguard.ScopeUser, req.Username, ip)
+		c.JSON(401, gin.H{
+			"code":    401,
+			"message": "用户名或密码错误",
 		})
 		return
 	}
+	loginguard.Succeed(ctx, loginguard.ScopeUser, req.Username)
 
-	c.JSON(401, gin.H{
-		"code":    401,
-		"message": "用户名或密码错误",
-	})
+	// 密码哈希算法已升级时，登录成功后透明地重新哈希
+	if user.NeedsRehash() {
+		if err := user.SetPassword(req.Password); err == nil {
+			db.Model(&user).Update("password", user.Password)
+		}
+	}
+
+	loginUser(c, db, &user)
 }
 
 // Register 用户注册
+// @Summary 用户注册
+// @Tags Public
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "用户名、密码、邮箱、人机验证"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/public/register [post]
 func Register(c *gin.Context) {
 	var req struct {
-		Username string `json:"username" binding:"required"`
-		Password string `json:"password" binding:"required,min=6"`
-		Email    string `json:"email" binding:"required,email"`
+		Username string `json:"username" binding:"required,min=3,max=64"`
+		Password string `json:"password" binding:"required"`
+		Email    string `json:"email" binding:"required,email,max=255"`
+		captcha.Solution
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -123,14 +236,97 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// TODO: 实际注册逻辑
+	// 人机验证（防止批量注册）
+	if captcha.RegisterRequired() && !captcha.Check(c, req.Solution) {
+		return
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	username := strings.TrimSpace(req.Username)
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if err := password.Validate(req.Password, username, email); err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var count int64
+	db.Model(&model.User{}).Where("username = ?", username).Count(&count)
+	if count > 0 {
+		c.JSON(409, gin.H{
+			"code":    409,
+			"message": "用户名已存在",
+		})
+		return
+	}
+	db.Model(&model.User{}).Where("email = ?", email).Count(&count)
+	if count > 0 {
+		c.JSON(409, gin.H{
+			"code":    409,
+			"message": "邮箱已被注册",
+		})
+		return
+	}
+
+	user := model.User{
+		Username: username,
+		Email:    email,
+		Nickname: username,
+		Role:     "user",
+		Status:   model.UserStatusActive,
+	}
+	if err := user.SetPassword(req.Password); err != nil {
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "密码加密失败",
+		})
+		return
+	}
+	if err := db.Create(&user).Error; err != nil {
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "注册失败: " + err.Error(),
+		})
+		return
+	}
+
+	// 验证邮件发送失败不影响注册，用户可通过重新发送接口再次获取
+	message := "注册成功，请查收验证邮件完成邮箱验证"
+	if err := emailverify.Send(c.Request.Context(), &user); err != nil {
+		log.Printf("发送验证邮件失败: user=%s err=%v", user.Username, err)
+		message = "注册成功，但验证邮件发送失败，请稍后重新发送"
+	}
+
 	c.JSON(200, gin.H{
 		"code":    200,
-		"message": "注册成功",
+		"message": message,
+		"data": gin.H{
+			"user_id":        user.ID,
+			"username":       user.Username,
+			"email":          user.Email,
+			"email_verified": false,
+		},
 	})
 }
 
 // RefreshToken 刷新令牌
+// @Summary 刷新令牌
+// @Tags Public
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "刷新 Token"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/public/refresh-token [post]
 func RefreshToken(c *gin.Context) {
 	var req struct {
 		RefreshToken string `json:"refresh_token" binding:"required"`
@@ -144,14 +340,105 @@ func RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// TODO: 验证 refresh token 并生成新 token
+	// 校验并轮换刷新 Token：旧的刷新 Token 随即失效，再次使用会作废整个令牌族
+	ctx := c.Request.Context()
+	jwtConfig := middleware.CurrentJWTConfig()
+	rotation, err := refreshtoken.Rotate(ctx, req.RefreshToken, jwtConfig)
+	switch {
+	case errors.Is(err, refreshtoken.ErrReused):
+		claims := rotation.Claims
+		middleware.ReportAuthFailure(c, middleware.AuthFailureToken, claims.Subject, err.Error())
+		log.Printf("刷新令牌重复使用，已作废令牌族: user=%s family=%s ip=%s", claims.Subject, claims.Family, c.ClientIP())
+		c.JSON(401, gin.H{
+			"code":    401,
+			"message": err.Error(),
+		})
+		return
+	case errors.Is(err, refreshtoken.ErrInvalid):
+		c.JSON(401, gin.H{
+			"code":    401,
+			"message": err.Error(),
+		})
+		return
+	case err != nil:
+		log.Printf("刷新令牌失败: %v", err)
+		c.JSON(503, gin.H{
+			"code":    503,
+			"message": "服务暂不可用，请稍后重试",
+		})
+		return
+	}
+	claims := rotation.Claims
+
+	// 按用户当前的状态与角色签发新的访问 Token（禁用、删除、改名的用户不能继续刷新）
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+	var user model.User
+	err = db.Where("id = ? AND username = ?", claims.Subject, rotation.Username).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		refreshtoken.Revoke(ctx, claims.Family)
+		c.JSON(401, gin.H{
+			"code":    401,
+			"message": refreshtoken.ErrInvalid.Error(),
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("刷新令牌查询用户失败: user=%s err=%v", claims.Subject, err)
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "查询用户失败",
+		})
+		return
+	}
+	if user.Status != model.UserStatusActive {
+		refreshtoken.Revoke(ctx, claims.Family)
+		c.JSON(403, gin.H{
+			"code":    403,
+			"message": "账号已禁用",
+		})
+		return
+	}
+	scopes := middleware.RoleScopes[user.Role]
+	if !user.EmailVerified() {
+		scopes = middleware.UnverifiedScopes
+	}
+
+	token, err := middleware.GenerateTokenWithScopes(claims.Subject, user.Username, user.Role, scopes, jwtConfig)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "生成令牌失败",
+		})
+		return
+	}
+
+	middleware.SetTokenCookie(c, token, time.Now().Add(jwtConfig.TokenExpiry), jwtConfig)
+	trackSession(c, token)
+
 	c.JSON(200, gin.H{
 		"code":    200,
 		"message": "刷新成功",
+		"data": gin.H{
+			"token":         token,
+			"refresh_token": rotation.RefreshToken,
+			"expires_in":    int(jwtConfig.TokenExpiry.Seconds()),
+		},
 	})
 }
 
 // GetProfile 获取当前用户信息
+// @Summary 获取当前用户信息
+// @Tags User
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/profile [get]
 func GetProfile(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	username, _ := c.Get("username")
@@ -169,6 +456,12 @@ func GetProfile(c *gin.Context) {
 }
 
 // UpdateProfile 更新当前用户信息
+// @Summary 更新当前用户信息
+// @Tags User
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/profile [put]
 func UpdateProfile(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"code":    200,
@@ -177,6 +470,11 @@ func UpdateProfile(c *gin.Context) {
 }
 
 // GetAllUsers 管理员获取所有用户
+// @Summary 管理员获取所有用户
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/users [get]
 func GetAllUsers(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"code":    200,
@@ -186,6 +484,12 @@ func GetAllUsers(c *gin.Context) {
 }
 
 // AdminDeleteUser 管理员删除用户
+// @Summary 管理员删除用户
+// @Tags Admin
+// @Produce json
+// @Param id path int true "用户 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/users/{id} [delete]
 func AdminDeleteUser(c *gin.Context) {
 	id := c.Param("id")
 	c.JSON(200, gin.H{
@@ -194,60 +498,57 @@ func AdminDeleteUser(c *gin.Context) {
 	})
 }
 
-// AddIPBlacklist 添加 IP 黑名单
-func AddIPBlacklist(c *gin.Context) {
-	var req struct {
-		IP string `json:"ip" binding:"required"`
-	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{
-			"code":    400,
-			"message": "参数错误",
-		})
-		return
-	}
-
-	// TODO: 添加到黑名单
+// HandleWebhook 处理 Webhook
+// @Summary 处理 Webhook
+// @Tags Signed
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/signed/webhook [post]
+func HandleWebhook(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"code":    200,
-		"message": "IP " + req.IP + " 已添加到黑名单",
+		"message": "Webhook 处理成功",
 	})
 }
 
-// RemoveIPBlacklist 移除 IP 黑名单
-func RemoveIPBlacklist(c *gin.Context) {
-	var req struct {
-		IP string `json:"ip" binding:"required"`
+// HandleCallback 处理回调（按合作方配置的 inbound 模板将载荷转换为统一格式）
+// @Summary 处理回调
+// @Tags Signed
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "合作方回调载荷"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/signed/callback [post]
+func HandleCallback(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		if middleware.AbortIfBodyTooLarge(c, err) {
+			return
+		}
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "读取请求体失败",
+		})
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	payload, err := transform.Apply(c.GetString("app_key"), transform.Inbound, body)
+	if err != nil {
 		c.JSON(400, gin.H{
 			"code":    400,
-			"message": "参数错误",
+			"message": "回调载荷转换失败: " + err.Error(),
 		})
 		return
 	}
 
-	// TODO: 从黑名单移除
-	c.JSON(200, gin.H{
-		"code":    200,
-		"message": "IP " + req.IP + " 已从黑名单移除",
-	})
-}
+	// TODO: 实际回调处理逻辑
+	var data interface{}
+	json.Unmarshal(payload, &data)
 
-// HandleWebhook 处理 Webhook
-func HandleWebhook(c *gin.Context) {
-	c.JSON(200, gin.H{
-		"code":    200,
-		"message": "Webhook 处理成功",
-	})
-}
-
-// HandleCallback 处理回调
-func HandleCallback(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"code":    200,
 		"message": "Callback 处理成功",
+		"data":    data,
 	})
 }
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSessions 获取当前用户的活跃会话
+// @Summary 获取当前用户的活跃会话
+// @Tags User
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/sessions [get]
+func ListSessions(c *gin.Context) {
+	claims := c.MustGet("claims").(*middleware.Claims)
+
+	sessions, err := session.List(c.Request.Context(), claims.Issuer, claims.Subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查询失败: " + err.Error(),
+		})
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == claims.ID
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    sessions,
+	})
+}
+
+// RevokeSession 吊销当前用户的某个会话
+// @Summary 吊销会话
+// @Tags User
+// @Produce json
+// @Param id path string true "会话 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/sessions/{id} [delete]
+func RevokeSession(c *gin.Context) {
+	claims := c.MustGet("claims").(*middleware.Claims)
+
+	err := session.Revoke(c.Request.Context(), claims.Issuer, claims.Subject, c.Param("id"))
+	if errors.Is(err, session.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "吊销失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "会话已吊销",
+	})
+}
+
+// RevokeAllSessions 吊销当前用户的全部会话（所有设备下线）
+// @Summary 吊销全部会话
+// @Tags User
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/sessions [delete]
+func RevokeAllSessions(c *gin.Context) {
+	claims := c.MustGet("claims").(*middleware.Claims)
+
+	ttl := userRevocationTTL(middleware.CurrentJWTConfig())
+	if err := session.RevokeAll(c.Request.Context(), claims.Issuer, claims.Subject, ttl); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "吊销失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "已吊销全部会话",
+	})
+}
+
+// userRevocationTTL 吊销用户全部会话的时长：刷新 Token 有效期更长，取两者较大值
+func userRevocationTTL(jwtConfig middleware.JWTConfig) time.Duration {
+	ttl := jwtConfig.TokenExpiry
+	if refresh := jwtConfig.RefreshExpiry; refresh > ttl {
+		ttl = refresh
+	}
+	return ttl
+}
+
+// trackSession 记录新签发的 Token，用于会话列表与单独吊销
+func trackSession(c *gin.Context, token string) {
+	claims, err := middleware.ParseTokenWithConfig(token, middleware.CurrentJWTConfig())
+	if err != nil {
+		return
+	}
+	if err := session.Track(c.Request.Context(), claims.RegisteredClaims, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		log.Printf("记录会话失败: user=%s err=%v", claims.UserID, err)
+	}
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/threatfeed"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SecurityFeed 安全事件订阅：按游标返回攻击检测与自动封禁（STIX 2.1 风格 bundle）。
+// 需 API 签名，且 AppKey 在 THREAT_FEED_APP_KEYS 中；消费方保存返回的 next，下次以 cursor 传入
+// @Summary 安全事件订阅
+// @Tags Service
+// @Produce json
+// @Param cursor query string false "上次返回的 next"
+// @Param limit query int false "每页数量"
+// @Param since query string false "起始时间（RFC 3339）"
+// @Param types query string false "事件类型（逗号分隔）"
+// @Success 200 {object} threatfeed.Bundle
+// @Router /api/v1/security/feed [get]
+func SecurityFeed(c *gin.Context) {
+	if !threatfeed.Allowed(c.GetString("app_key")) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "该 AppKey 无权拉取安全事件订阅",
+		})
+		return
+	}
+
+	q := threatfeed.Query{Cursor: c.Query("cursor")}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "无效的 limit",
+			})
+			return
+		}
+		q.Limit = n
+	}
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "since 应为 RFC3339 时间",
+			})
+			return
+		}
+		q.Since = t
+	}
+	if types := c.Query("types"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			if t = strings.TrimSpace(t); !validFeedType(t) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"code":    400,
+					"message": "无效的事件类型: " + t + "（可选 " + strings.Join(threatfeed.Types, ", ") + "）",
+				})
+				return
+			}
+			q.Types = append(q.Types, t)
+		}
+	}
+
+	bundle, err := threatfeed.Feed(c.Request.Context(), q)
+	switch {
+	case errors.Is(err, threatfeed.ErrInvalidCursor):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": "读取安全事件失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, bundle)
+}
+
+// validFeedType 检查订阅的事件类型
+func validFeedType(t string) bool {
+	for _, allowed := range threatfeed.Types {
+		if allowed == t {
+			return true
+		}
+	}
+	return false
+}
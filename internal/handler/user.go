@@ -1,65 +1,105 @@
 package handler
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
-	"sync"
+	"strings"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/pat"
+	"new-openclaw/internal/session"
+	"new-openclaw/pkg/password"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-// User 用户结构体
-type User struct {
-	ID    int    `json:"id"`
-	Name  string `json:"name" binding:"required"`
-	Email string `json:"email" binding:"required,email"`
-	Age   int    `json:"age"`
-}
+// GetUsers 分页获取用户列表（支持按用户名/邮箱关键字、角色、状态筛选）
+// @Summary 获取用户列表
+// @Tags User
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param keyword query string false "用户名/邮箱关键字"
+// @Param role query string false "角色"
+// @Param status query int false "状态"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/users [get]
+// @Router /api/v1/service/users [get]
+func GetUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
 
-// 模拟数据库（内存存储）
-var (
-	users  = make(map[int]*User)
-	nextID = 1
-	mu     sync.RWMutex
-)
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
 
-// GetUsers 获取所有用户
-func GetUsers(c *gin.Context) {
-	mu.RLock()
-	defer mu.RUnlock()
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
 
-	userList := make([]*User, 0, len(users))
-	for _, u := range users {
-		userList = append(userList, u)
+	query := db.Model(&model.User{})
+	if keyword := strings.TrimSpace(c.Query("keyword")); keyword != "" {
+		like := "%" + keyword + "%"
+		query = query.Where("username LIKE ? OR email LIKE ?", like, like)
+	}
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
 	}
 
+	var users []model.User
+	var total int64
+
+	query.Count(&total)
+	query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&users)
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
-		"data":    userList,
+		"data": gin.H{
+			"list":      users,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
 	})
 }
 
 // GetUserByID 根据 ID 获取用户
+// @Summary 获取用户
+// @Tags User
+// @Produce json
+// @Param id path int true "用户 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/users/{id} [get]
+// @Router /api/v1/service/users/{id} [get]
 func GetUserByID(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的用户 ID",
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
 		})
 		return
 	}
 
-	mu.RLock()
-	user, exists := users[id]
-	mu.RUnlock()
-
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{
-			"code":    404,
-			"message": "用户不存在",
-		})
+	user, ok := findUser(c, db)
+	if !ok {
 		return
 	}
 
@@ -70,10 +110,23 @@ func GetUserByID(c *gin.Context) {
 	})
 }
 
-// CreateUser 创建用户
+// CreateUser 创建用户（密码按注册接口的规则校验；邮箱未验证，登录后只有受限的权限范围）
+// @Summary 创建用户
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param body body map[string]interface{} true "用户名、邮箱、密码、昵称"
+// @Success 201 {object} map[string]interface{}
+// @Router /api/v1/users [post]
 func CreateUser(c *gin.Context) {
-	var user User
-	if err := c.ShouldBindJSON(&user); err != nil {
+	var req struct {
+		Username string `json:"username" binding:"required,min=3,max=64"`
+		Email    string `json:"email" binding:"required,email,max=255"`
+		Password string `json:"password" binding:"required"`
+		Nickname string `json:"nickname" binding:"max=100"`
+		Role     string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
 			"message": "参数错误: " + err.Error(),
@@ -81,11 +134,53 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
-	mu.Lock()
-	user.ID = nextID
-	nextID++
-	users[user.ID] = &user
-	mu.Unlock()
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	user := model.User{
+		Username: strings.TrimSpace(req.Username),
+		Email:    strings.ToLower(strings.TrimSpace(req.Email)),
+		Nickname: req.Nickname,
+		Role:     "user",
+		Status:   model.UserStatusActive,
+	}
+	if req.Role != "" {
+		if !assignRole(c, req.Role) {
+			return
+		}
+		user.Role = req.Role
+	}
+	if err := password.Validate(req.Password, user.Username, user.Email); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+	if !uniqueUser(c, db, 0, user.Username, user.Email) {
+		return
+	}
+	if err := user.SetPassword(req.Password); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "密码加密失败",
+		})
+		return
+	}
+
+	if err := db.Create(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "创建失败: " + err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusCreated, gin.H{
 		"code":    0,
@@ -94,40 +189,118 @@ func CreateUser(c *gin.Context) {
 	})
 }
 
-// UpdateUser 更新用户
+// UpdateUser 更新用户（只修改请求中出现的字段）；修改邮箱后需要重新验证，禁用账号或修改密码、角色后吊销其全部会话，
+// 个人访问令牌按新的角色与状态校验
+// @Summary 更新用户
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param id path int true "用户 ID"
+// @Param body body map[string]interface{} true "用户名、邮箱、昵称、头像、密码、角色、状态（均可选）"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/users/{id} [put]
 func UpdateUser(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
+	var req struct {
+		Username *string `json:"username" binding:"omitempty,min=3,max=64"`
+		Email    *string `json:"email" binding:"omitempty,email,max=255"`
+		Nickname *string `json:"nickname" binding:"omitempty,max=100"`
+		Avatar   *string `json:"avatar" binding:"omitempty,max=512"`
+		Password *string `json:"password"`
+		Role     *string `json:"role"`
+		Status   *int    `json:"status" binding:"omitempty,oneof=0 1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
-			"message": "无效的用户 ID",
+			"message": "参数错误: " + err.Error(),
 		})
 		return
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
-
-	if _, exists := users[id]; !exists {
-		c.JSON(http.StatusNotFound, gin.H{
-			"code":    404,
-			"message": "用户不存在",
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
 		})
 		return
 	}
 
-	var user User
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "参数错误: " + err.Error(),
-		})
+	user, ok := findUser(c, db)
+	if !ok {
 		return
 	}
 
-	user.ID = id
-	users[id] = &user
+	updates := map[string]interface{}{}
+	username, email := user.Username, user.Email
+	if req.Username != nil {
+		username = strings.TrimSpace(*req.Username)
+		updates["username"] = username
+	}
+	if req.Email != nil {
+		email = strings.ToLower(strings.TrimSpace(*req.Email))
+		if email != user.Email {
+			updates["email"] = email
+			updates["email_verified_at"] = nil
+		}
+	}
+	if req.Nickname != nil {
+		updates["nickname"] = *req.Nickname
+	}
+	if req.Avatar != nil {
+		updates["avatar"] = *req.Avatar
+	}
+	if req.Role != nil && *req.Role != user.Role {
+		if !assignRole(c, *req.Role) {
+			return
+		}
+		updates["role"] = *req.Role
+	}
+	if req.Status != nil {
+		updates["status"] = *req.Status
+	}
+	if req.Password != nil {
+		if err := password.Validate(*req.Password, username, email); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return
+		}
+		if err := user.SetPassword(*req.Password); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "密码加密失败",
+			})
+			return
+		}
+		updates["password"] = user.Password
+	}
+	if !uniqueUser(c, db, user.ID, username, email) {
+		return
+	}
 
+	if len(updates) > 0 {
+		if err := db.Model(user).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "更新失败: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	// 已签发的 Token 携带旧的角色与权限范围，需要重新登录
+	_, rehashed := updates["password"]
+	_, roleChanged := updates["role"]
+	if rehashed || roleChanged || (req.Status != nil && *req.Status != model.UserStatusActive) {
+		revokeUserSessions(c, user.ID)
+	}
+	if roleChanged || req.Status != nil {
+		pat.InvalidateUser(c.Request.Context(), db, user.ID)
+	}
+
+	db.First(user, user.ID)
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "更新成功",
@@ -135,32 +308,133 @@ func UpdateUser(c *gin.Context) {
 	})
 }
 
-// DeleteUser 删除用户
+// DeleteUser 删除用户及其第三方身份绑定、个人访问令牌，并吊销其全部会话
+// @Summary 删除用户
+// @Tags User
+// @Produce json
+// @Param id path int true "用户 ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/users/{id} [delete]
 func DeleteUser(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
+	db := database.GetMySQL()
+	if db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "数据库未连接",
+		})
+		return
+	}
+
+	user, ok := findUser(c, db)
+	if !ok {
+		return
+	}
+
+	// 删除后无法再按用户查到令牌摘要，先取出用于清除缓存
+	var tokenHashes []string
+	db.Model(&model.PersonalAccessToken{}).Where("user_id = ?", user.ID).Pluck("token_hash", &tokenHashes)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&model.UserIdentity{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&model.PersonalAccessToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(user).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除失败: " + err.Error(),
+		})
+		return
+	}
+	revokeUserSessions(c, user.ID)
+	pat.Invalidate(c.Request.Context(), tokenHashes...)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "删除成功",
+	})
+}
+
+// findUser 按路径参数 id 查询用户，参数无效或用户不存在时写入响应并返回 false
+func findUser(c *gin.Context, db *gorm.DB) (*model.User, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
 			"message": "无效的用户 ID",
 		})
-		return
+		return nil, false
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
-
-	if _, exists := users[id]; !exists {
+	var user model.User
+	err = db.First(&user, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{
 			"code":    404,
 			"message": "用户不存在",
 		})
-		return
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查询用户失败",
+		})
+		return nil, false
 	}
+	return &user, true
+}
 
-	delete(users, id)
+// uniqueUser 检查用户名、邮箱是否已被其他用户（excludeID 以外）使用，冲突时写入 409 响应并返回 false
+func uniqueUser(c *gin.Context, db *gorm.DB, excludeID uint, username, email string) bool {
+	var count int64
+	db.Model(&model.User{}).Where("username = ? AND id <> ?", username, excludeID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": "用户名已存在",
+		})
+		return false
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"code":    0,
-		"message": "删除成功",
-	})
+	db.Model(&model.User{}).Where("email = ? AND id <> ?", email, excludeID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": "邮箱已被使用",
+		})
+		return false
+	}
+	return true
+}
+
+// assignRole 校验要设置的角色：必须是已定义的角色，且只有 admin 角色可以设置非 user 角色；不允许时写入响应并返回 false
+func assignRole(c *gin.Context, role string) bool {
+	if _, ok := middleware.RoleScopes[role]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的角色: " + role,
+		})
+		return false
+	}
+	if role != "user" && c.GetString("role") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "无权设置该角色",
+		})
+		return false
+	}
+	return true
+}
+
+// revokeUserSessions 吊销用户的全部会话（失败只记录日志）
+func revokeUserSessions(c *gin.Context, userID uint) {
+	jwtConfig := middleware.CurrentJWTConfig()
+	subject := strconv.FormatUint(uint64(userID), 10)
+	if err := session.RevokeAll(c.Request.Context(), jwtConfig.Issuer, subject, userRevocationTTL(jwtConfig)); err != nil {
+		log.Printf("吊销用户会话失败: user=%s err=%v", subject, err)
+	}
 }
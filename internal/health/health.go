@@ -0,0 +1,256 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/jobs"
+	"new-openclaw/internal/metrics"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/notify"
+	"new-openclaw/pkg/config"
+)
+
+// 依赖状态
+const (
+	StatusConnected     = "connected"
+	StatusDisconnected  = "disconnected"
+	StatusNotConfigured = "not configured"
+)
+
+// 审计日志输出组件名（状态为 ok / degraded）
+const ComponentAudit = "audit"
+
+// flapWindow 统计状态变化次数的窗口
+const flapWindow = time.Hour
+
+// maxPending 数据库不可用期间最多缓存的状态变化记录数
+const maxPending = 1000
+
+var statusChangesTotal = metrics.NewCounterVec(
+	"dependency_status_changes_total", "依赖健康状态变化次数", "component", "status")
+
+// Event 依赖健康状态变化事件
+type Event struct {
+	Component string    `json:"component"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Instance  string    `json:"instance"`
+	At        time.Time `json:"at"`
+	// 最近一小时内该组件的状态变化次数（含本次）
+	Flaps int `json:"flaps"`
+}
+
+// Healthy 变化后是否为正常状态
+func (e Event) Healthy() bool {
+	return e.To == StatusConnected || e.To == "ok"
+}
+
+// Dependency 依赖及其当前状态
+type Dependency struct {
+	Name   string
+	Status string
+}
+
+var (
+	cfg = config.HealthConfig{CheckInterval: 30 * time.Second}
+
+	instance = func() string {
+		hostname, _ := os.Hostname()
+		return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}()
+
+	mu        sync.Mutex
+	last      = make(map[string]string)
+	changes   = make(map[string][]time.Time)
+	pending   []model.HealthEvent
+	listeners []func(Event)
+)
+
+// Configure 设置健康监测配置
+func Configure(c config.HealthConfig) {
+	cfg = c
+}
+
+// OnChange 订阅依赖状态变化事件（需在 jobs.Start 之前调用，回调在检测任务中同步执行）
+func OnChange(fn func(Event)) {
+	mu.Lock()
+	listeners = append(listeners, fn)
+	mu.Unlock()
+}
+
+// Probe 检测 MySQL（单机模式为 SQLite）、Redis、MongoDB 的连接状态
+func Probe(ctx context.Context) []Dependency {
+	deps := make([]Dependency, 0, 3)
+
+	status := StatusNotConfigured
+	if db := database.GetMySQL(); db != nil {
+		pingCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		status = StatusDisconnected
+		if sqlDB, err := db.DB(); err == nil && sqlDB.PingContext(pingCtx) == nil {
+			status = StatusConnected
+		}
+		cancel()
+	}
+	deps = append(deps, Dependency{dbComponent(), status})
+
+	status = StatusNotConfigured
+	if rdb := database.GetRedis(); rdb != nil {
+		pingCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		status = StatusDisconnected
+		if rdb.Ping(pingCtx).Err() == nil {
+			status = StatusConnected
+		}
+		cancel()
+	}
+	deps = append(deps, Dependency{"redis", status})
+
+	status = StatusNotConfigured
+	if database.GetMongoDB() != nil {
+		pingCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		status = StatusDisconnected
+		if database.MongoClient.Ping(pingCtx, nil) == nil {
+			status = StatusConnected
+		}
+		cancel()
+	}
+	deps = append(deps, Dependency{"mongodb", status})
+
+	return deps
+}
+
+// RegisterJob 注册依赖健康监测任务（每个实例各自检测本实例的连接）
+func RegisterJob() {
+	if cfg.CheckInterval <= 0 {
+		return
+	}
+
+	jobs.Register(jobs.Job{
+		Name:        "dependency_health",
+		Description: "检测依赖连接状态，变化时通知并记录",
+		Interval:    cfg.CheckInterval,
+		Run:         Check,
+	})
+}
+
+// Check 检测一次依赖状态：与上次结果比较，状态变化时发布事件、通知管理员并写入 health_events。
+// 首次检测只记录基线；未配置的依赖不产生事件
+func Check(ctx context.Context) error {
+	deps := Probe(ctx)
+	if audit, ok := middleware.AuditHealthStatus(); ok {
+		deps = append(deps, Dependency{ComponentAudit, audit.Status})
+	}
+
+	now := time.Now()
+	var events []Event
+	mu.Lock()
+	for _, dep := range deps {
+		prev, seen := last[dep.Name]
+		last[dep.Name] = dep.Status
+		if !seen || prev == dep.Status || prev == StatusNotConfigured || dep.Status == StatusNotConfigured {
+			continue
+		}
+
+		recent := changes[dep.Name][:0]
+		for _, at := range changes[dep.Name] {
+			if now.Sub(at) < flapWindow {
+				recent = append(recent, at)
+			}
+		}
+		changes[dep.Name] = append(recent, now)
+
+		events = append(events, Event{
+			Component: dep.Name,
+			From:      prev,
+			To:        dep.Status,
+			Instance:  instance,
+			At:        now,
+			Flaps:     len(changes[dep.Name]),
+		})
+	}
+	subscribers := append([]func(Event){}, listeners...)
+	mu.Unlock()
+
+	for _, e := range events {
+		statusChangesTotal.Inc(e.Component, e.To)
+		if e.Healthy() {
+			log.Printf("✅ 依赖已恢复: %s %s -> %s（1 小时内变化 %d 次）", e.Component, e.From, e.To, e.Flaps)
+		} else {
+			log.Printf("⚠️  依赖异常: %s %s -> %s（1 小时内变化 %d 次）", e.Component, e.From, e.To, e.Flaps)
+		}
+		for _, fn := range subscribers {
+			fn(e)
+		}
+		sendNotification(e)
+	}
+
+	return record(events)
+}
+
+// sendNotification 以系统通知告知管理员；数据库不可用导致通知失败时改为立即告警（仅额外邮箱可收到）
+func sendNotification(e Event) {
+	state := "异常"
+	if e.Healthy() {
+		state = "恢复"
+	}
+	title := fmt.Sprintf("依赖%s: %s", state, e.Component)
+	content := fmt.Sprintf("组件: %s\n状态: %s -> %s\n实例: %s\n时间: %s\n最近 1 小时内变化次数: %d",
+		e.Component, e.From, e.To, e.Instance, e.At.Format(time.RFC3339), e.Flaps)
+
+	if err := notify.Notify(notify.CategorySystem, title, content); err != nil {
+		if len(cfg.AlertEmails) == 0 {
+			log.Printf("发送依赖状态通知失败: %v", err)
+			return
+		}
+		notify.Alert(title, content, cfg.AlertEmails)
+	}
+}
+
+// record 写入状态变化记录；数据库不可用时缓存，恢复后补写
+func record(events []Event) error {
+	mu.Lock()
+	for _, e := range events {
+		pending = append(pending, model.HealthEvent{
+			Component:  e.Component,
+			FromStatus: e.From,
+			ToStatus:   e.To,
+			Instance:   e.Instance,
+			Flaps:      e.Flaps,
+			OccurredAt: e.At,
+		})
+	}
+	if n := len(pending); n > maxPending {
+		log.Printf("依赖状态变化记录缓存已满，丢弃最早的 %d 条", n-maxPending)
+		pending = append([]model.HealthEvent{}, pending[n-maxPending:]...)
+	}
+	batch := pending
+	connected := last[dbComponent()] == StatusConnected
+	mu.Unlock()
+
+	db := database.GetMySQL()
+	if len(batch) == 0 || db == nil || !connected {
+		return nil
+	}
+	if err := db.Create(&batch).Error; err != nil {
+		return fmt.Errorf("写入依赖状态变化记录失败: %v", err)
+	}
+
+	mu.Lock()
+	pending = pending[len(batch):]
+	mu.Unlock()
+	return nil
+}
+
+// dbComponent 关系数据库的组件名
+func dbComponent() string {
+	if database.Driver() == "sqlite" {
+		return "sqlite"
+	}
+	return "mysql"
+}
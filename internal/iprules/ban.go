@@ -0,0 +1,146 @@
+package iprules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/metrics"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/notify"
+	"new-openclaw/internal/secevents"
+
+	"gorm.io/gorm"
+)
+
+// ErrBanNotFound 封禁记录不存在或已失效
+var ErrBanNotFound = errors.New("封禁记录不存在或已失效")
+
+var (
+	// banTTL 自动封禁时长
+	banTTL = time.Hour
+
+	instance = func() string {
+		hostname, _ := os.Hostname()
+		return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}()
+
+	bansTotal = metrics.NewCounterVec("ip_bans_total", "自动临时封禁的 IP 数", "trigger")
+)
+
+// ConfigureBans 设置自动封禁时长
+func ConfigureBans(ttl time.Duration) {
+	banTTL = ttl
+}
+
+// AutoBan 滥用评分达到阈值时临时封禁 IP（middleware.AbuseConfig.OnThreshold）：
+// 立即在本实例生效，写入 ip_bans 供管理员复核，并广播给其他实例。白名单中的 IP 不封禁
+func AutoBan(ctx context.Context, ip string, score int64, trigger string) {
+	mu.RLock()
+	f := filter
+	mu.RUnlock()
+	if f == nil || f.Banned(ip) || f.Whitelisted(ip) {
+		return
+	}
+
+	now := time.Now()
+	until := now.Add(banTTL)
+	f.Ban(ip, until)
+	bansTotal.Inc(trigger)
+
+	events := middleware.AbuseBreakdown(ctx, ip)
+	raw, _ := json.Marshal(events)
+	ban := model.IPBan{
+		IP:        ip,
+		Trigger:   trigger,
+		Score:     score,
+		Events:    string(raw),
+		Reason:    banReason(score, events),
+		Instance:  instance,
+		ExpiresAt: until,
+	}
+	log.Printf("[SECURITY ALERT] 自动封禁 IP %s 至 %s: %s", ip, until.Format(time.RFC3339), ban.Reason)
+
+	db := database.GetMySQL()
+	if db == nil {
+		return
+	}
+	if err := db.WithContext(ctx).Create(&ban).Error; err != nil {
+		log.Printf("写入封禁记录失败: ip=%s err=%v", ip, err)
+		return
+	}
+	secevents.RecordBan(ban)
+	notify.Notify(notify.CategorySecurity, "IP 已被自动封禁: "+ip,
+		fmt.Sprintf("%s\n封禁至 %s（记录 ID %d），可在 /admin/ip-bans 复核或提前解除。", ban.Reason, until.Format(time.RFC3339), ban.ID))
+	changed(ctx)
+}
+
+// LiftBan 提前解除封禁并清除该 IP 的滥用评分
+func LiftBan(ctx context.Context, id uint, liftedBy string) (*model.IPBan, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+
+	var ban model.IPBan
+	err := db.WithContext(ctx).First(&ban, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBanNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !ban.Active(now) {
+		return nil, ErrBanNotFound
+	}
+
+	ban.LiftedAt = &now
+	ban.LiftedBy = liftedBy
+	if err := db.WithContext(ctx).Save(&ban).Error; err != nil {
+		return nil, err
+	}
+	middleware.ResetAbuse(ctx, ban.IP)
+	secevents.RecordBanLifted(ban)
+	changed(ctx)
+	return &ban, nil
+}
+
+// loadBans 从数据库加载生效中的封禁到过滤器
+func loadBans(ctx context.Context, f *middleware.DynamicIPFilter) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+
+	var active []model.IPBan
+	if err := db.WithContext(ctx).Where("lifted_at IS NULL AND expires_at > ?", time.Now()).Find(&active).Error; err != nil {
+		return err
+	}
+	bans := make(map[string]time.Time, len(active))
+	for _, ban := range active {
+		if ban.ExpiresAt.After(bans[ban.IP]) {
+			bans[ban.IP] = ban.ExpiresAt
+		}
+	}
+	f.SetBans(bans)
+	return nil
+}
+
+// banReason 封禁原因：评分及各类事件次数
+func banReason(score int64, events map[string]int64) string {
+	parts := make([]string, 0, len(events))
+	for reason, n := range events {
+		parts = append(parts, fmt.Sprintf("%s=%d", reason, n))
+	}
+	sort.Strings(parts)
+	return fmt.Sprintf("窗口内滥用评分 %d（%s）", score, strings.Join(parts, ", "))
+}
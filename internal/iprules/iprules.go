@@ -0,0 +1,246 @@
+package iprules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/jobs"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/tags"
+
+	"gorm.io/gorm"
+)
+
+// channel 规则、封禁变更广播频道（各实例收到后从数据库重新加载）
+const channel = "openclaw:ip_rules:reload"
+
+var (
+	// ErrUnavailable 数据库未连接
+	ErrUnavailable = errors.New("数据库未连接")
+	// ErrNotFound 规则不存在
+	ErrNotFound = errors.New("IP 规则不存在")
+	// ErrInvalid IP、CIDR 或 ASN 格式错误
+	ErrInvalid = errors.New("无效的 IP、CIDR 或 ASN（如 AS16509）")
+)
+
+var (
+	filter *middleware.DynamicIPFilter
+	mu     sync.RWMutex
+
+	cancel context.CancelFunc
+)
+
+// Bind 绑定生效的动态 IP 过滤器
+func Bind(f *middleware.DynamicIPFilter) {
+	mu.Lock()
+	defer mu.Unlock()
+	filter = f
+}
+
+// Normalize 校验并规范化 IP、CIDR 或 ASN（CIDR 取网络地址，如 10.1.2.3/8 → 10.0.0.0/8；as16509 → AS16509）
+func Normalize(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if asn, ok := middleware.ParseASN(value); ok {
+		return fmt.Sprintf("AS%d", asn), nil
+	}
+	if strings.Contains(value, "/") {
+		_, ipNet, err := net.ParseCIDR(value)
+		if err != nil {
+			return "", ErrInvalid
+		}
+		return ipNet.String(), nil
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return "", ErrInvalid
+	}
+	return ip.String(), nil
+}
+
+// ValidType 检查规则类型
+func ValidType(ruleType string) error {
+	if ruleType != model.IPRuleWhitelist && ruleType != model.IPRuleBlacklist {
+		return fmt.Errorf("无效的规则类型: %s（可选 %s, %s）", ruleType, model.IPRuleWhitelist, model.IPRuleBlacklist)
+	}
+	return nil
+}
+
+// List 获取规则（ruleType 为空时返回全部，tag 为逗号分隔的标签，需同时带有）
+func List(ctx context.Context, ruleType, tag string) ([]model.IPRule, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	query := db.WithContext(ctx).Scopes(tags.Filter(tags.ResourceIPRule, tag)).Order("id ASC")
+	if ruleType != "" {
+		query = query.Where("type = ?", ruleType)
+	}
+	rules := []model.IPRule{}
+	if err := query.Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Add 添加规则并通知所有实例重新加载（已存在时更新备注）
+func Add(ctx context.Context, ruleType, value, remark, createdBy string) (*model.IPRule, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	cidr, err := Normalize(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var rule model.IPRule
+	err = db.WithContext(ctx).Where("type = ? AND cidr = ?", ruleType, cidr).First(&rule).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		rule = model.IPRule{Type: ruleType, CIDR: cidr, Remark: remark, CreatedBy: createdBy}
+		err = db.WithContext(ctx).Create(&rule).Error
+	case err == nil:
+		rule.Remark = remark
+		err = db.WithContext(ctx).Save(&rule).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	changed(ctx)
+	return &rule, nil
+}
+
+// Remove 删除规则并通知所有实例重新加载
+func Remove(ctx context.Context, ruleType, value string) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+	cidr, err := Normalize(value)
+	if err != nil {
+		return err
+	}
+
+	var rule model.IPRule
+	err = db.WithContext(ctx).Where("type = ? AND cidr = ?", ruleType, cidr).First(&rule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := db.WithContext(ctx).Delete(&rule).Error; err != nil {
+		return err
+	}
+	tags.Clear(ctx, tags.ResourceIPRule, rule.ID)
+
+	changed(ctx)
+	return nil
+}
+
+// Load 从数据库加载规则及生效中的封禁到过滤器（数据库未连接时保持当前规则）
+func Load(ctx context.Context) error {
+	mu.RLock()
+	f := filter
+	mu.RUnlock()
+	if f == nil {
+		return nil
+	}
+
+	rules, err := List(ctx, "", "")
+	if err != nil {
+		return err
+	}
+	var whitelist, blacklist []string
+	for _, rule := range rules {
+		switch rule.Type {
+		case model.IPRuleWhitelist:
+			whitelist = append(whitelist, rule.CIDR)
+		case model.IPRuleBlacklist:
+			blacklist = append(blacklist, rule.CIDR)
+		}
+	}
+	f.SetRules(whitelist, blacklist)
+	return loadBans(ctx, f)
+}
+
+// changed 规则变更后立即在本实例生效，并广播给其他实例
+func changed(ctx context.Context) {
+	if err := Load(ctx); err != nil {
+		log.Printf("重新加载 IP 规则失败: %v", err)
+	}
+	if rdb := database.GetRedis(); rdb != nil {
+		if err := rdb.Publish(ctx, channel, time.Now().Format(time.RFC3339Nano)).Err(); err != nil {
+			log.Printf("广播 IP 规则变更失败（其他实例将在定时任务中重新加载）: %v", err)
+		}
+	}
+}
+
+// Start 启动时加载规则，并订阅变更广播（未连接 Redis 时只依赖定时重新加载）
+func Start() {
+	if err := Load(context.Background()); err != nil {
+		log.Printf("⚠️  加载 IP 规则失败，仅使用配置中的名单: %v", err)
+	}
+
+	rdb := database.GetRedis()
+	if rdb == nil {
+		return
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	mu.Lock()
+	cancel = stop
+	mu.Unlock()
+
+	// 订阅断开时客户端自动重连，期间遗漏的广播由定时任务兜底
+	sub := rdb.Subscribe(ctx, channel)
+	messages := sub.Channel()
+	go func() {
+		defer sub.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-messages:
+				if !ok {
+					return
+				}
+				if err := Load(ctx); err != nil {
+					log.Printf("重新加载 IP 规则失败: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop 停止订阅变更广播
+func Stop() {
+	mu.Lock()
+	defer mu.Unlock()
+	if cancel != nil {
+		cancel()
+		cancel = nil
+	}
+}
+
+// RegisterReloadJob 注册定时重新加载规则的任务（每个实例都执行，补偿遗漏的广播）
+func RegisterReloadJob(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	jobs.Register(jobs.Job{
+		Name:        "ip_rules_reload",
+		Description: "从数据库重新加载 IP 黑白名单",
+		Interval:    interval,
+		Run:         Load,
+	})
+}
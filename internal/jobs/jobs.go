@@ -0,0 +1,302 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/leader"
+	"new-openclaw/internal/store"
+)
+
+// recentFailures 每个任务保留的最近失败记录数
+const recentFailures = 10
+
+var (
+	// ErrNotFound 任务不存在
+	ErrNotFound = errors.New("任务不存在")
+	// ErrRunning 任务正在执行
+	ErrRunning = errors.New("任务正在执行")
+)
+
+// Job 定时任务
+type Job struct {
+	// 任务名称（唯一）
+	Name        string
+	Description string
+	// 执行间隔
+	Interval time.Duration
+	// 仅在 Leader 上运行（多副本部署的单例任务）
+	LeaderOnly bool
+	// 执行函数
+	Run func(ctx context.Context) error
+}
+
+// Failure 失败记录
+type Failure struct {
+	At    time.Time `json:"at"`
+	Error string    `json:"error"`
+}
+
+// Status 任务状态（执行记录为本实例的统计）
+type Status struct {
+	Name           string     `json:"name"`
+	Description    string     `json:"description"`
+	Interval       string     `json:"interval"`
+	LeaderOnly     bool       `json:"leader_only"`
+	Paused         bool       `json:"paused"`
+	Running        bool       `json:"running"`
+	LastRun        *time.Time `json:"last_run"`
+	LastDuration   string     `json:"last_duration"`
+	LastError      string     `json:"last_error"`
+	NextRun        *time.Time `json:"next_run"`
+	Runs           int64      `json:"runs"`
+	Failures       int64      `json:"failures"`
+	RecentFailures []Failure  `json:"recent_failures"`
+}
+
+// entry 已注册任务及其运行状态
+type entry struct {
+	job Job
+
+	mu       sync.Mutex
+	running  bool
+	lastRun  time.Time
+	lastDur  time.Duration
+	lastErr  string
+	nextRun  time.Time
+	runs     int64
+	failures int64
+	recent   []Failure
+}
+
+var (
+	mu      sync.RWMutex
+	entries = make(map[string]*entry)
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+)
+
+// Register 注册定时任务（需在 Start 之前调用），同名任务会被替换
+func Register(job Job) {
+	if job.Name == "" || job.Interval <= 0 || job.Run == nil {
+		return
+	}
+	mu.Lock()
+	entries[job.Name] = &entry{job: job}
+	mu.Unlock()
+}
+
+// Start 启动所有已注册任务的调度
+func Start() {
+	mu.Lock()
+	defer mu.Unlock()
+	if stopCh != nil {
+		return
+	}
+	stopCh = make(chan struct{})
+
+	for _, e := range entries {
+		wg.Add(1)
+		go schedule(e, stopCh)
+	}
+	if len(entries) > 0 {
+		log.Printf("✅ 已启动 %d 个定时任务", len(entries))
+	}
+}
+
+// Stop 停止调度并等待执行中的任务结束
+func Stop() {
+	mu.Lock()
+	ch := stopCh
+	stopCh = nil
+	mu.Unlock()
+
+	if ch != nil {
+		close(ch)
+		wg.Wait()
+	}
+}
+
+// List 获取所有任务状态
+func List(ctx context.Context) []Status {
+	mu.RLock()
+	list := make([]Status, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, e.status(ctx))
+	}
+	mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get 获取单个任务状态
+func Get(ctx context.Context, name string) (Status, error) {
+	e, err := lookup(name)
+	if err != nil {
+		return Status{}, err
+	}
+	return e.status(ctx), nil
+}
+
+// Trigger 立即在本实例异步执行一次任务（忽略暂停状态与 Leader 限制）
+func Trigger(name string) error {
+	e, err := lookup(name)
+	if err != nil {
+		return err
+	}
+	if !e.begin() {
+		return ErrRunning
+	}
+	go e.execute()
+	return nil
+}
+
+// Pause 暂停任务调度（所有实例生效，手动触发不受影响）
+func Pause(ctx context.Context, name string) error {
+	if _, err := lookup(name); err != nil {
+		return err
+	}
+	return store.For(store.ComponentJobs).Set(ctx, pausedKey(name), "1", 0)
+}
+
+// Resume 恢复任务调度
+func Resume(ctx context.Context, name string) error {
+	if _, err := lookup(name); err != nil {
+		return err
+	}
+	return store.For(store.ComponentJobs).Del(ctx, pausedKey(name))
+}
+
+// Paused 任务是否已暂停
+func Paused(ctx context.Context, name string) bool {
+	_, err := store.For(store.ComponentJobs).Get(ctx, pausedKey(name))
+	return err == nil
+}
+
+// schedule 按间隔调度任务
+func schedule(e *entry, stop <-chan struct{}) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(e.job.Interval)
+	defer ticker.Stop()
+	e.setNext(time.Now().Add(e.job.Interval))
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			e.setNext(now.Add(e.job.Interval))
+			if e.job.LeaderOnly && !leader.Leader() {
+				continue
+			}
+			if Paused(context.Background(), e.job.Name) {
+				continue
+			}
+			if e.begin() {
+				e.execute()
+			}
+		}
+	}
+}
+
+// begin 标记任务开始执行，已在执行中时返回 false
+func (e *entry) begin() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.running {
+		return false
+	}
+	e.running = true
+	return true
+}
+
+// execute 执行任务并记录结果（需先调用 begin）
+func (e *entry) execute() {
+	start := time.Now()
+	err := runSafely(e.job)
+	duration := time.Since(start)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.running = false
+	e.lastRun = start
+	e.lastDur = duration
+	e.runs++
+	e.lastErr = ""
+	if err != nil {
+		e.lastErr = err.Error()
+		e.failures++
+		e.recent = append(e.recent, Failure{At: start, Error: err.Error()})
+		if len(e.recent) > recentFailures {
+			e.recent = e.recent[len(e.recent)-recentFailures:]
+		}
+		log.Printf("定时任务 %s 执行失败: %v", e.job.Name, err)
+	}
+}
+
+// runSafely 执行任务，panic 视为失败
+func runSafely(job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run(context.Background())
+}
+
+func (e *entry) setNext(t time.Time) {
+	e.mu.Lock()
+	e.nextRun = t
+	e.mu.Unlock()
+}
+
+func (e *entry) status(ctx context.Context) Status {
+	paused := Paused(ctx, e.job.Name)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	s := Status{
+		Name:           e.job.Name,
+		Description:    e.job.Description,
+		Interval:       e.job.Interval.String(),
+		LeaderOnly:     e.job.LeaderOnly,
+		Paused:         paused,
+		Running:        e.running,
+		LastError:      e.lastErr,
+		Runs:           e.runs,
+		Failures:       e.failures,
+		RecentFailures: append([]Failure{}, e.recent...),
+	}
+	if !e.lastRun.IsZero() {
+		lastRun := e.lastRun
+		s.LastRun = &lastRun
+		s.LastDuration = e.lastDur.String()
+	}
+	if !e.nextRun.IsZero() && !paused {
+		nextRun := e.nextRun
+		s.NextRun = &nextRun
+	}
+	return s
+}
+
+func lookup(name string) (*entry, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	e, ok := entries[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return e, nil
+}
+
+func pausedKey(name string) string {
+	return "paused:" + name
+}
@@ -0,0 +1,142 @@
+package leader
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// etcdLock 基于租约 + 事务的锁（v3 gRPC-Gateway JSON API），持有者宕机后租约到期自动释放
+type etcdLock struct {
+	endpoint string
+	key      string
+	id       string
+	ttl      time.Duration
+	client   *http.Client
+
+	mu      sync.Mutex
+	leaseID string
+}
+
+func newEtcdLock(endpoint, key, id string, ttl time.Duration) *etcdLock {
+	return &etcdLock{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		key:      base64.StdEncoding.EncodeToString([]byte(key)),
+		id:       id,
+		ttl:      ttl,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (l *etcdLock) acquire(ctx context.Context) (bool, error) {
+	ttl := int64(l.ttl / time.Second)
+	if ttl < 1 {
+		ttl = 1
+	}
+
+	var grant struct {
+		ID string `json:"ID"`
+	}
+	if err := l.post(ctx, "/v3/lease/grant", map[string]interface{}{"TTL": ttl}, &grant); err != nil {
+		return false, err
+	}
+
+	// Key 不存在（create_revision 为 0）时写入并绑定租约
+	var txn struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	body := map[string]interface{}{
+		"compare": []map[string]interface{}{
+			{"key": l.key, "target": "CREATE", "create_revision": "0"},
+		},
+		"success": []map[string]interface{}{
+			{"request_put": map[string]interface{}{
+				"key":   l.key,
+				"value": base64.StdEncoding.EncodeToString([]byte(l.id)),
+				"lease": grant.ID,
+			}},
+		},
+	}
+	if err := l.post(ctx, "/v3/kv/txn", body, &txn); err != nil {
+		return false, err
+	}
+
+	if !txn.Succeeded {
+		l.post(ctx, "/v3/lease/revoke", map[string]interface{}{"ID": grant.ID}, nil)
+		return false, nil
+	}
+
+	l.mu.Lock()
+	l.leaseID = grant.ID
+	l.mu.Unlock()
+	return true, nil
+}
+
+func (l *etcdLock) renew(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	leaseID := l.leaseID
+	l.mu.Unlock()
+
+	var resp struct {
+		Result struct {
+			TTL string `json:"TTL"`
+		} `json:"result"`
+	}
+	if err := l.post(ctx, "/v3/lease/keepalive", map[string]interface{}{"ID": leaseID}, &resp); err != nil {
+		return false, err
+	}
+	return resp.Result.TTL != "" && resp.Result.TTL != "0", nil
+}
+
+func (l *etcdLock) release(ctx context.Context) error {
+	l.mu.Lock()
+	leaseID := l.leaseID
+	l.mu.Unlock()
+
+	return l.post(ctx, "/v3/lease/revoke", map[string]interface{}{"ID": leaseID}, nil)
+}
+
+func (l *etcdLock) holder(ctx context.Context) (string, error) {
+	var resp struct {
+		Kvs []struct {
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := l.post(ctx, "/v3/kv/range", map[string]interface{}{"key": l.key}, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Kvs) == 0 {
+		return "", nil
+	}
+	value, err := base64.StdEncoding.DecodeString(resp.Kvs[0].Value)
+	return string(value), err
+}
+
+func (l *etcdLock) post(ctx context.Context, path string, body interface{}, out interface{}) error {
+	data, _ := json.Marshal(body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s 返回 %d", path, resp.StatusCode)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/pkg/config"
+)
+
+// 选主后端
+const (
+	BackendRedis = "redis"
+	BackendEtcd  = "etcd"
+)
+
+// lock 分布式锁（持有锁的实例即为 Leader）
+type lock interface {
+	// acquire 尝试获取锁，返回是否成功
+	acquire(ctx context.Context) (bool, error)
+	// renew 续约，返回是否仍持有锁
+	renew(ctx context.Context) (bool, error)
+	// release 释放锁
+	release(ctx context.Context) error
+	// holder 查询当前持有者
+	holder(ctx context.Context) (string, error)
+}
+
+// Info 选主状态
+type Info struct {
+	Enabled  bool      `json:"enabled"`
+	Backend  string    `json:"backend"`
+	ID       string    `json:"id"`
+	IsLeader bool      `json:"is_leader"`
+	LeaderID string    `json:"leader_id"`
+	Since    time.Time `json:"since,omitempty"`
+}
+
+var (
+	mu       sync.RWMutex
+	enabled  bool
+	backend  string
+	id       string
+	isLeader = true
+	since    time.Time
+	current  lock
+	stopCh   chan struct{}
+	stopped  chan struct{}
+)
+
+// Start 启动选主，Backend 为空时视为单实例部署，本实例始终为 Leader
+func Start(cfg config.LeaderConfig) error {
+	if cfg.Backend == "" {
+		return nil
+	}
+
+	hostname, _ := os.Hostname()
+	instanceID := fmt.Sprintf("%s-%d", hostname, os.Getpid())
+
+	var l lock
+	switch cfg.Backend {
+	case BackendRedis:
+		rdb := database.GetRedis()
+		if rdb == nil {
+			return fmt.Errorf("Redis 未初始化")
+		}
+		l = newRedisLock(rdb, cfg.Key, instanceID, cfg.TTL)
+	case BackendEtcd:
+		l = newEtcdLock(cfg.Endpoint, cfg.Key, instanceID, cfg.TTL)
+	default:
+		return fmt.Errorf("不支持的选主后端: %s", cfg.Backend)
+	}
+
+	mu.Lock()
+	enabled, backend, id, isLeader, current = true, cfg.Backend, instanceID, false, l
+	stopCh, stopped = make(chan struct{}), make(chan struct{})
+	mu.Unlock()
+
+	go run(l, cfg.TTL, stopCh, stopped)
+	return nil
+}
+
+// Stop 停止选主，如果是 Leader 则主动释放锁以便其他副本尽快接管
+func Stop() {
+	mu.Lock()
+	stop, done, l, leader := stopCh, stopped, current, isLeader
+	stopCh = nil
+	mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+
+	if leader {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		if err := l.release(ctx); err != nil {
+			log.Printf("释放 Leader 锁失败: %v", err)
+		}
+	}
+	setLeader(false)
+}
+
+// Leader 当前实例是否为 Leader（单例后台任务执行前调用）
+func Leader() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return isLeader
+}
+
+// Status 获取选主状态
+func Status() Info {
+	mu.RLock()
+	info := Info{Enabled: enabled, Backend: backend, ID: id, IsLeader: isLeader, Since: since}
+	l := current
+	mu.RUnlock()
+
+	if !info.Enabled {
+		return info
+	}
+	if info.IsLeader {
+		info.LeaderID = info.ID
+		return info
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if holder, err := l.holder(ctx); err == nil {
+		info.LeaderID = holder
+	}
+	return info
+}
+
+// run 按 TTL 的 1/3 周期续约或竞选，续约失败即放弃 Leader 身份
+func run(l lock, ttl time.Duration, stop, done chan struct{}) {
+	defer close(done)
+
+	interval := ttl / 3
+	if interval < 500*time.Millisecond {
+		interval = 500 * time.Millisecond
+	}
+
+	tick := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		defer cancel()
+
+		if Leader() {
+			ok, err := l.renew(ctx)
+			if err != nil || !ok {
+				log.Printf("⚠️  Leader 续约失败，放弃 Leader 身份: %v", err)
+				setLeader(false)
+			}
+			return
+		}
+
+		ok, err := l.acquire(ctx)
+		if err != nil {
+			log.Printf("Leader 竞选失败: %v", err)
+			return
+		}
+		if ok {
+			log.Printf("👑 当前实例成为 Leader")
+			setLeader(true)
+		}
+	}
+
+	tick()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			tick()
+		}
+	}
+}
+
+func setLeader(leader bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	if leader && !isLeader {
+		since = time.Now()
+	}
+	if !leader {
+		since = time.Time{}
+	}
+	isLeader = leader
+}
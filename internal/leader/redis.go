@@ -0,0 +1,58 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// renewScript 仅当锁仍由本实例持有时续约
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript 仅当锁仍由本实例持有时删除
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// redisLock 基于 SET NX PX 的锁
+type redisLock struct {
+	client *redis.Client
+	key    string
+	id     string
+	ttl    time.Duration
+}
+
+func newRedisLock(client *redis.Client, key, id string, ttl time.Duration) *redisLock {
+	return &redisLock{client: client, key: key, id: id, ttl: ttl}
+}
+
+func (l *redisLock) acquire(ctx context.Context) (bool, error) {
+	return l.client.SetNX(ctx, l.key, l.id, l.ttl).Result()
+}
+
+func (l *redisLock) renew(ctx context.Context) (bool, error) {
+	n, err := renewScript.Run(ctx, l.client, []string{l.key}, l.id, l.ttl.Milliseconds()).Int()
+	return n == 1, err
+}
+
+func (l *redisLock) release(ctx context.Context) error {
+	return releaseScript.Run(ctx, l.client, []string{l.key}, l.id).Err()
+}
+
+func (l *redisLock) holder(ctx context.Context) (string, error) {
+	value, err := l.client.Get(ctx, l.key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return value, err
+}
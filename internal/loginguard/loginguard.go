@@ -0,0 +1,281 @@
+// Package loginguard 登录暴力破解防护：按账号与 IP 统计登录失败次数，
+// 超过门槛后要求两次尝试之间按指数增长的间隔，账号失败次数过多时临时锁定（多次锁定时锁定时长逐次加倍）
+package loginguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/metrics"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/secevents"
+	"new-openclaw/internal/store"
+	"new-openclaw/pkg/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 登录入口
+const (
+	ScopeAdmin = "admin"
+	ScopeUser  = "user"
+)
+
+// lockHistoryTTL 锁定次数的保留时长（期间再次锁定时时长加倍）
+const lockHistoryTTL = 24 * time.Hour
+
+var cfg config.LoginGuardConfig
+
+var guardTotal = metrics.NewCounterVec("login_guard_total", "登录暴力破解防护的判定次数", "scope", "result")
+
+// Configure 设置防护参数
+func Configure(c config.LoginGuardConfig) {
+	cfg = c
+}
+
+// Enabled 是否启用
+func Enabled() bool {
+	return cfg.Enabled
+}
+
+// Decision 登录前的检查结果
+type Decision struct {
+	// 账号已锁定
+	Locked bool
+	// 需要等待的时长（0 表示允许尝试）
+	RetryAfter time.Duration
+	// 账号窗口内的连续失败次数（仅 Fail 返回）
+	Failures int64
+}
+
+// Allowed 是否允许本次尝试
+func (d Decision) Allowed() bool {
+	return d.RetryAfter <= 0
+}
+
+// Message 拒绝时返回给客户端的提示
+func (d Decision) Message() string {
+	seconds := int64((d.RetryAfter + time.Second - 1) / time.Second)
+	if d.Locked {
+		return fmt.Sprintf("登录失败次数过多，账号已临时锁定，请 %d 秒后重试", seconds)
+	}
+	return fmt.Sprintf("登录尝试过于频繁，请 %d 秒后重试", seconds)
+}
+
+// Reason 拒绝原因（不含等待时间，用于登录记录）
+func (d Decision) Reason() string {
+	if d.Locked {
+		return "账号已锁定"
+	}
+	return "登录尝试过于频繁"
+}
+
+// RetryAfterSeconds Retry-After 响应头的值（向上取整且至少为 1）
+func (d Decision) RetryAfterSeconds() string {
+	seconds := int64((d.RetryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
+}
+
+// Reject 拒绝本次登录尝试（429 与 Retry-After）
+func Reject(c *gin.Context, d Decision) {
+	c.Header("Retry-After", d.RetryAfterSeconds())
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"code":    429,
+		"message": d.Message(),
+	})
+}
+
+// Check 登录前检查账号是否锁定、账号或 IP 是否仍在等待间隔内。存储不可用时放行
+func Check(ctx context.Context, scope, account, ip string) Decision {
+	if !cfg.Enabled {
+		return Decision{}
+	}
+	s := store.For(store.ComponentLogin)
+
+	if ttl, err := s.TTL(ctx, lockKey(scope, account)); err == nil && ttl > 0 {
+		guardTotal.Inc(scope, "locked")
+		return Decision{Locked: true, RetryAfter: ttl}
+	}
+	var wait time.Duration
+	for _, key := range []string{delayKey(scope, "account", account), delayKey(scope, "ip", ip)} {
+		if ttl, err := s.TTL(ctx, key); err == nil && ttl > wait {
+			wait = ttl
+		}
+	}
+	if wait > 0 {
+		guardTotal.Inc(scope, "delayed")
+	}
+	return Decision{RetryAfter: wait}
+}
+
+// Fail 记录一次登录失败：失败次数超过门槛后设置下次尝试前的等待间隔，账号失败次数达到锁定门槛时锁定账号。
+// 返回本次失败后的检查结果
+func Fail(ctx context.Context, scope, account, ip string) Decision {
+	if !cfg.Enabled {
+		return Decision{}
+	}
+	guardTotal.Inc(scope, "failed")
+	s := store.For(store.ComponentLogin)
+
+	accountFailures := incr(ctx, s, failuresKey(scope, "account", account), cfg.Window)
+	ipFailures := incr(ctx, s, failuresKey(scope, "ip", ip), cfg.Window)
+
+	if cfg.LockThreshold > 0 && accountFailures >= int64(cfg.LockThreshold) {
+		return lock(ctx, s, scope, account, ip, accountFailures)
+	}
+
+	decision := Decision{Failures: accountFailures}
+	if d := delay(accountFailures, cfg.DelayAfter); d > 0 {
+		s.Set(ctx, delayKey(scope, "account", account), "1", d)
+		decision.RetryAfter = d
+	}
+	if d := delay(ipFailures, cfg.IPDelayAfter); d > 0 {
+		s.Set(ctx, delayKey(scope, "ip", ip), "1", d)
+		if d > decision.RetryAfter {
+			decision.RetryAfter = d
+		}
+	}
+	return decision
+}
+
+// Succeed 登录成功后清除账号的失败计数与等待间隔（IP 的计数保留，避免用一个有效账号重置对其他账号的尝试）
+func Succeed(ctx context.Context, scope, account string) {
+	if !cfg.Enabled {
+		return
+	}
+	store.For(store.ComponentLogin).Del(ctx,
+		failuresKey(scope, "account", account), delayKey(scope, "account", account))
+}
+
+// Status 账号当前的失败次数与锁定剩余时长
+type Status struct {
+	Scope    string `json:"scope"`
+	Account  string `json:"account"`
+	Failures int64  `json:"failures"`
+	Locks    int64  `json:"locks"`
+	Locked   bool   `json:"locked"`
+	// 锁定剩余秒数
+	LockRemaining int64 `json:"lock_remaining"`
+}
+
+// Get 查询账号的防护状态
+func Get(ctx context.Context, scope, account string) (Status, error) {
+	s := store.For(store.ComponentLogin)
+	status := Status{Scope: scope, Account: account}
+	var err error
+	if status.Failures, err = getInt(ctx, s, failuresKey(scope, "account", account)); err != nil {
+		return status, err
+	}
+	if status.Locks, err = getInt(ctx, s, locksKey(scope, account)); err != nil {
+		return status, err
+	}
+	if ttl, err := s.TTL(ctx, lockKey(scope, account)); err == nil && ttl > 0 {
+		status.Locked = true
+		status.LockRemaining = int64((ttl + time.Second - 1) / time.Second)
+	}
+	return status, nil
+}
+
+// Unlock 解除账号锁定并清除失败计数（锁定次数一并清除，下次锁定从初始时长开始）
+func Unlock(ctx context.Context, scope, account string) error {
+	return store.For(store.ComponentLogin).Del(ctx,
+		lockKey(scope, account), locksKey(scope, account),
+		failuresKey(scope, "account", account), delayKey(scope, "account", account))
+}
+
+// ValidScope 是否为有效的登录入口
+func ValidScope(scope string) bool {
+	return scope == ScopeAdmin || scope == ScopeUser
+}
+
+// lock 锁定账号并记录安全事件，锁定时长为 LockDuration × 2^(近期锁定次数-1)，不超过 MaxLockDuration
+func lock(ctx context.Context, s store.Store, scope, account, ip string, failures int64) Decision {
+	locks := incr(ctx, s, locksKey(scope, account), lockHistoryTTL)
+	duration := backoff(cfg.LockDuration, locks-1, cfg.MaxLockDuration)
+	s.Set(ctx, lockKey(scope, account), strconv.FormatInt(time.Now().Unix(), 10), duration)
+	s.Del(ctx, failuresKey(scope, "account", account), delayKey(scope, "account", account))
+	guardTotal.Inc(scope, "lockout")
+
+	until := time.Now().Add(duration)
+	secevents.Record(model.SecurityEvent{
+		Type:       model.SecurityEventAccountLocked,
+		IP:         ip,
+		Subject:    truncate(account, 64),
+		Labels:     scope,
+		Score:      failures,
+		Detail:     fmt.Sprintf("连续 %d 次登录失败，第 %d 次锁定，锁定 %s", failures, locks, duration),
+		ValidUntil: &until,
+	})
+	return Decision{Locked: true, RetryAfter: duration, Failures: failures}
+}
+
+// delay 失败次数超过 after 后的等待间隔：BaseDelay × 2^(failures-after-1)，不超过 MaxDelay；after 为 0 时不限制
+func delay(failures int64, after int) time.Duration {
+	if after <= 0 || failures <= int64(after) {
+		return 0
+	}
+	return backoff(cfg.BaseDelay, failures-int64(after)-1, cfg.MaxDelay)
+}
+
+func backoff(base time.Duration, exponent int64, max time.Duration) time.Duration {
+	d := base
+	for i := int64(0); i < exponent && (max <= 0 || d < max); i++ {
+		d *= 2
+	}
+	if max > 0 && d > max {
+		d = max
+	}
+	return d
+}
+
+// incr 计数加一，过期时间从第一次计数开始；存储不可用时返回 0（不限制）
+func incr(ctx context.Context, s store.Store, key string, window time.Duration) int64 {
+	n, err := s.IncrWithTTL(ctx, key, window)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func getInt(ctx context.Context, s store.Store, key string) (int64, error) {
+	value, err := s.Get(ctx, key)
+	if errors.Is(err, store.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func failuresKey(scope, kind, value string) string {
+	return "failures:" + scope + ":" + kind + ":" + value
+}
+
+func delayKey(scope, kind, value string) string {
+	return "delay:" + scope + ":" + kind + ":" + value
+}
+
+func lockKey(scope, account string) string {
+	return "lock:" + scope + ":" + account
+}
+
+func locksKey(scope, account string) string {
+	return "locks:" + scope + ":" + account
+}
+
+// truncate 截断超出列宽的账号名
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
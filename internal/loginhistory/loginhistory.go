@@ -0,0 +1,182 @@
+// Package loginhistory 登录记录：保存管理员与用户每次登录的结果、IP、设备与位置，
+// 成功登录的设备或位置此前未出现过时向账号邮箱发送提醒
+package loginhistory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/geoip"
+	"new-openclaw/internal/jobs"
+	"new-openclaw/internal/mail"
+	"new-openclaw/internal/metrics"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/session"
+	"new-openclaw/pkg/config"
+
+	"gorm.io/gorm"
+)
+
+var cfg = config.LoginHistoryConfig{
+	Enabled:         true,
+	Retention:       90 * 24 * time.Hour,
+	NotifyNewDevice: true,
+}
+
+var loginsTotal = metrics.NewCounterVec("login_history_total", "登录记录数", "realm", "result")
+
+// Configure 设置是否记录、保留时长及新设备提醒
+func Configure(c config.LoginHistoryConfig) {
+	cfg = c
+}
+
+// Entry 一次登录尝试
+type Entry struct {
+	Realm string
+	// 账号 ID（账号不存在时为 0）
+	AccountID uint
+	Username  string
+	// 接收新设备提醒的邮箱（为空不提醒）
+	Email     string
+	Method    string
+	Result    string
+	Reason    string
+	IP        string
+	UserAgent string
+}
+
+// Record 保存登录记录（写入失败只记日志，不影响登录）；成功登录来自新设备或新位置时异步发送提醒邮件
+func Record(ctx context.Context, e Entry) {
+	if !cfg.Enabled {
+		return
+	}
+	loginsTotal.Inc(e.Realm, e.Result)
+
+	db := database.GetMySQL()
+	if db == nil {
+		return
+	}
+
+	h := model.LoginHistory{
+		Realm:     e.Realm,
+		AccountID: e.AccountID,
+		Username:  truncate(e.Username, 64),
+		Method:    e.Method,
+		Result:    e.Result,
+		Reason:    truncate(e.Reason, 255),
+		IP:        e.IP,
+		UserAgent: truncate(e.UserAgent, 512),
+		Device:    session.Device(e.UserAgent),
+	}
+	if ip := net.ParseIP(e.IP); ip != nil {
+		if country, ok := geoip.LookupCountry(ip); ok {
+			h.Country, h.CountryName = country.Code, country.Name
+		}
+		if asn, ok := geoip.Lookup(ip); ok {
+			h.ASN, h.ASNOrg = asn.Number, truncate(asn.Organization, 255)
+		}
+	}
+
+	if e.Result == model.LoginResultSuccess && e.AccountID != 0 {
+		h.NewDevice, h.NewLocation = novelty(ctx, &h)
+	}
+
+	if err := db.WithContext(ctx).Create(&h).Error; err != nil {
+		log.Printf("保存登录记录失败: realm=%s user=%s err=%v", e.Realm, e.Username, err)
+		return
+	}
+
+	if (h.NewDevice || h.NewLocation) && cfg.NotifyNewDevice && e.Email != "" {
+		go alert(e.Email, &h)
+	}
+}
+
+// novelty 与该账号此前的成功登录比较，判断设备与位置是否为首次出现（首次登录不算）。
+// 位置优先按国家/地区比较，未配置国家/地区数据库时按 ASN 比较，两者都无法识别时不判断
+func novelty(ctx context.Context, h *model.LoginHistory) (newDevice, newLocation bool) {
+	base := database.GetMySQL().WithContext(ctx).Model(&model.LoginHistory{}).
+		Where("realm = ? AND account_id = ? AND result = ?", h.Realm, h.AccountID, model.LoginResultSuccess)
+
+	var total int64
+	if base.Session(&gorm.Session{}).Count(&total).Error != nil || total == 0 {
+		return false, false
+	}
+
+	var count int64
+	base.Session(&gorm.Session{}).Where("device = ?", h.Device).Count(&count)
+	newDevice = count == 0
+
+	switch {
+	case h.Country != "":
+		base.Session(&gorm.Session{}).Where("country = ?", h.Country).Count(&count)
+		newLocation = count == 0
+	case h.ASN != 0:
+		base.Session(&gorm.Session{}).Where("asn = ?", h.ASN).Count(&count)
+		newLocation = count == 0
+	}
+	return newDevice, newLocation
+}
+
+// alert 新设备 / 新位置登录提醒
+func alert(to string, h *model.LoginHistory) {
+	location := "未知"
+	switch {
+	case h.CountryName != "":
+		location = h.CountryName
+	case h.Country != "":
+		location = h.Country
+	}
+	if h.ASNOrg != "" {
+		location += fmt.Sprintf("（AS%d %s）", h.ASN, h.ASNOrg)
+	}
+
+	what := "新设备"
+	switch {
+	case h.NewDevice && h.NewLocation:
+		what = "新设备、新位置"
+	case h.NewLocation:
+		what = "新位置"
+	}
+
+	body := fmt.Sprintf("%s，您好：\n\n您的账号刚刚在%s登录：\n\n时间：%s\nIP：%s\n设备：%s\n位置：%s\n\n"+
+		"如果这是您本人的操作，请忽略本邮件；否则请立即修改密码并在会话管理中吊销可疑的会话。",
+		h.Username, what, h.CreatedAt.Format("2006-01-02 15:04:05"), h.IP, h.Device, location)
+	if err := mail.Send(to, "账号在"+what+"登录", body); err != nil {
+		log.Printf("发送登录提醒失败: user=%s err=%v", h.Username, err)
+	}
+}
+
+// RegisterCleanupJob 注册清理超过保留时长的登录记录的任务（仅 Leader 执行）
+func RegisterCleanupJob() {
+	if !cfg.Enabled || cfg.Retention <= 0 {
+		return
+	}
+
+	jobs.Register(jobs.Job{
+		Name:        "login_history_cleanup",
+		Description: "清理超过保留时长的登录记录",
+		Interval:    time.Hour,
+		LeaderOnly:  true,
+		Run: func(ctx context.Context) error {
+			db := database.GetMySQL()
+			if db == nil {
+				return fmt.Errorf("数据库未连接")
+			}
+			return db.WithContext(ctx).Where("created_at < ?", time.Now().Add(-cfg.Retention)).
+				Delete(&model.LoginHistory{}).Error
+		},
+	})
+}
+
+// truncate 截断超出列宽的字符串（不保留被截断的半个字符）
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,58 @@
+// Package mail 邮件发送服务：管理员通知、注册邮箱验证等统一经此发送，未配置 SMTP 时只写日志（便于开发环境查看邮件内容）
+package mail
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"new-openclaw/pkg/config"
+)
+
+// cfg SMTP 配置
+var cfg = config.MailConfig{SMTPPort: 587}
+
+// Configure 设置 SMTP 服务器与发件人
+func Configure(c config.MailConfig) {
+	cfg = c
+}
+
+// Enabled 是否配置了 SMTP（未配置时邮件只写日志）
+func Enabled() bool {
+	return cfg.SMTPHost != ""
+}
+
+// Send 发送纯文本邮件；未配置 SMTP 时仅记录日志
+func Send(to, subject, body string) error {
+	if cfg.SMTPHost == "" {
+		log.Printf("[MAIL] to=%s subject=%s\n%s", to, subject, body)
+		return nil
+	}
+
+	from := cfg.From
+	if from == "" {
+		from = cfg.SMTPUsername
+	}
+
+	var auth smtp.Auth
+	if cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + from,
+		"To: " + to,
+		"Subject: " + mime.QEncoding.Encode("UTF-8", subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	addr := fmt.Sprintf("%s:%d", cfg.SMTPHost, cfg.SMTPPort)
+	return smtp.SendMail(addr, auth, from, []string{to}, []byte(msg))
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"new-openclaw/internal/metrics"
+	"new-openclaw/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 计入滥用评分的事件类型
+const (
+	AbuseNotFound         = "not_found"
+	AbuseRateLimited      = "rate_limited"
+	AbuseSignatureFailure = "signature_failure"
+	AbuseSecurityAlert    = "security_alert"
+	AbuseHoneypot         = "honeypot"
+	AbuseAPIKeyFailure    = "api_key_failure"
+)
+
+// AbuseReasons 所有事件类型
+var AbuseReasons = []string{AbuseNotFound, AbuseRateLimited, AbuseSignatureFailure, AbuseSecurityAlert, AbuseHoneypot, AbuseAPIKeyFailure}
+
+// AbuseConfig 滥用评分配置（评分为窗口内累计的可疑事件数）
+type AbuseConfig struct {
+	// 统计窗口（从窗口内第一次事件开始计时）
+	Window time.Duration
+	// 状态存储（为空时使用 abuse 组件配置的存储）
+	Store store.Store
+	// 评分达到该值后每次记录事件都调用 OnThreshold（0 不触发）
+	Threshold int64
+	// 评分达到阈值时的处理（如自动封禁），reason 为本次触发的事件类型
+	OnThreshold func(ctx context.Context, ip string, score int64, reason string)
+}
+
+// DefaultAbuseConfig 默认滥用评分配置
+var DefaultAbuseConfig = AbuseConfig{
+	Window: 10 * time.Minute,
+}
+
+var abuseEventsTotal = metrics.NewCounterVec(
+	"abuse_events_total", "计入滥用评分的可疑事件数", "reason")
+
+// RecordAbuse 记录一次可疑事件，返回该 IP 当前的滥用评分
+func RecordAbuse(ctx context.Context, ip, reason string) int64 {
+	abuseEventsTotal.Inc(reason)
+
+	s := abuseStore()
+	key := abuseKey(ip)
+	score, err := s.IncrWithTTL(ctx, key, DefaultAbuseConfig.Window)
+	if err != nil {
+		return 0
+	}
+	// 按事件类型分别计数，供封禁记录说明原因
+	s.IncrWithTTL(ctx, key+":"+reason, DefaultAbuseConfig.Window)
+
+	config := DefaultAbuseConfig
+	if config.Threshold > 0 && score >= config.Threshold && config.OnThreshold != nil {
+		config.OnThreshold(ctx, ip, score, reason)
+	}
+	return score
+}
+
+// AbuseBreakdown 获取 IP 窗口内各类事件的次数
+func AbuseBreakdown(ctx context.Context, ip string) map[string]int64 {
+	s := abuseStore()
+	breakdown := make(map[string]int64)
+	for _, reason := range AbuseReasons {
+		value, err := s.Get(ctx, abuseKey(ip)+":"+reason)
+		if err != nil {
+			continue
+		}
+		if n, _ := strconv.ParseInt(value, 10, 64); n > 0 {
+			breakdown[reason] = n
+		}
+	}
+	return breakdown
+}
+
+// ResetAbuse 清除 IP 的滥用评分（管理员解除封禁后调用，避免立即再次触发）
+func ResetAbuse(ctx context.Context, ip string) {
+	keys := []string{abuseKey(ip)}
+	for _, reason := range AbuseReasons {
+		keys = append(keys, abuseKey(ip)+":"+reason)
+	}
+	abuseStore().Del(ctx, keys...)
+}
+
+// AbuseIP 计入滥用评分的 IP（与 IP 过滤使用同一来源，未经过 IP 过滤时使用 gin 解析的客户端 IP）
+func AbuseIP(c *gin.Context) string {
+	if ip := c.GetString("client_ip"); ip != "" {
+		return ip
+	}
+	return c.ClientIP()
+}
+
+// AbuseScore 获取 IP 当前的滥用评分
+func AbuseScore(ctx context.Context, ip string) int64 {
+	value, err := abuseStore().Get(ctx, abuseKey(ip))
+	if err != nil {
+		return 0
+	}
+	score, _ := strconv.ParseInt(value, 10, 64)
+	return score
+}
+
+func abuseStore() store.Store {
+	if DefaultAbuseConfig.Store != nil {
+		return DefaultAbuseConfig.Store
+	}
+	return store.For(store.ComponentAbuse)
+}
+
+func abuseKey(ip string) string {
+	return "abuse:" + ip
+}
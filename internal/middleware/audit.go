@@ -7,10 +7,12 @@ import (
 	"io"
 	"log"
 	"os"
-	"path/filepath"
 	"sync"
 	"time"
 
+	"new-openclaw/pkg/auth/token"
+	"new-openclaw/pkg/config"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -22,6 +24,10 @@ type AuditConfig struct {
 	Output string
 	// 日志文件路径
 	FilePath string
+	// 备用日志文件路径（主文件不可写时使用，为空则输出到标准错误）
+	FallbackPath string
+	// 日志文件轮转与保留
+	Rotation AuditRotation
 	// 是否记录请求体
 	LogRequestBody bool
 	// 是否记录响应体
@@ -32,14 +38,28 @@ type AuditConfig struct {
 	MaxResponseBodySize int
 	// 敏感字段（会被脱敏）
 	SensitiveFields []string
+	// 正则、JSONPath、查询参数与请求头的脱敏规则
+	MaskRules AuditMaskRules
 	// 排除的路径
 	ExcludePaths []string
+	// 记录请求的比例（0~1，为 0 时按 1 处理）
+	SampleRate float64
+	// 记录的请求中采集请求/响应体的比例（0~1，为 0 时按 1 处理；请求体的读取也随之跳过）
+	BodySampleRate float64
+	// 按路径的采样规则（按顺序匹配第一条，如 /admin/* 全部记录、上传接口只记录请求头）
+	PathRules []config.AuditPathRule
+	// 未被采样的请求出现 5xx 或慢请求时仍记录（不含请求/响应体）
+	KeepErrors bool
 	// 自定义日志处理函数
 	CustomHandler func(log *AuditLog)
 	// 异步写入
 	Async bool
 	// 异步写入缓冲区大小
 	BufferSize int
+	// 外部输出（可组合，与 Output 同时生效；Output 设为 none 时只写外部输出）
+	Sinks []AuditSink
+	// 外部输出的批量写入参数（零值字段取 DefaultAuditSinkOptions）
+	SinkOptions AuditSinkOptions
 }
 
 // DefaultAuditConfig 默认审计配置
@@ -53,6 +73,9 @@ var DefaultAuditConfig = AuditConfig{
 	MaxResponseBodySize: 4096,
 	SensitiveFields:     []string{"password", "token", "secret", "key", "authorization"},
 	ExcludePaths:        []string{"/ping", "/health", "/metrics"},
+	SampleRate:          1,
+	BodySampleRate:      1,
+	KeepErrors:          true,
 	Async:               true,
 	BufferSize:          1000,
 }
@@ -69,6 +92,8 @@ type AuditLog struct {
 	UserID string `json:"user_id,omitempty"`
 	// 用户名（如果已认证）
 	Username string `json:"username,omitempty"`
+	// 模拟登录期间的请求：发起模拟的超级管理员
+	ImpersonatedBy *token.Actor `json:"impersonated_by,omitempty"`
 	// 请求方法
 	Method string `json:"method"`
 	// 请求路径
@@ -93,6 +118,8 @@ type AuditLog struct {
 	UserAgent string `json:"user_agent,omitempty"`
 	// Referer
 	Referer string `json:"referer,omitempty"`
+	// 是否为慢请求
+	Slow bool `json:"slow,omitempty"`
 	// 额外信息
 	Extra map[string]interface{} `json:"extra,omitempty"`
 }
@@ -111,30 +138,55 @@ func (w *responseWriter) Write(b []byte) (int, error) {
 // AuditLogger 审计日志记录器
 type AuditLogger struct {
 	config   AuditConfig
+	masker   *auditMasker
 	file     *os.File
+	fallback *os.File
 	logChan  chan *AuditLog
+	sinks    []*sinkWorker
 	mu       sync.Mutex
 	wg       sync.WaitGroup
+
+	// 写入失败状态
+	degraded        bool
+	failures        uint64
+	lastError       string
+	lastErrorAt     time.Time
+	lastReopen      time.Time
+	lastRotateCheck time.Time
+
+	// 当前文件的大小与起始时间（轮转判断）
+	fileSize  int64
+	fileStart time.Time
 }
 
 // NewAuditLogger 创建审计日志记录器
 func NewAuditLogger(config AuditConfig) (*AuditLogger, error) {
+	masker, err := newAuditMasker(config.SensitiveFields, config.MaskRules)
+	if err != nil {
+		return nil, err
+	}
 	logger := &AuditLogger{
 		config: config,
+		masker: masker,
 	}
 
 	// 创建日志文件
 	if config.Output == "file" || config.Output == "both" {
-		dir := filepath.Dir(config.FilePath)
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return nil, fmt.Errorf("创建日志目录失败: %v", err)
+		if err := logger.openPrimary(); err != nil {
+			return nil, err
 		}
-
-		file, err := os.OpenFile(config.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-		if err != nil {
-			return nil, fmt.Errorf("打开日志文件失败: %v", err)
+		if config.Rotation.Enabled() {
+			// 压缩上次退出前未处理完的轮转文件，清理过期文件
+			go maintainAuditBackups(config.FilePath, config.Rotation)
 		}
-		logger.file = file
+
+		activeAuditMu.Lock()
+		activeAudit = logger
+		activeAuditMu.Unlock()
+	}
+
+	for _, sink := range config.Sinks {
+		logger.sinks = append(logger.sinks, newSinkWorker(sink, config.SinkOptions))
 	}
 
 	// 异步模式
@@ -175,8 +227,13 @@ func (l *AuditLogger) writeLog(auditLog *AuditLog) {
 	}
 
 	// 输出到文件
-	if l.file != nil && (l.config.Output == "file" || l.config.Output == "both") {
-		l.file.WriteString(logLine)
+	if l.config.Output == "file" || l.config.Output == "both" {
+		l.writeFile(logLine)
+	}
+
+	// 外部输出（各自排队批量发送）
+	for _, sink := range l.sinks {
+		sink.enqueue(auditLog)
 	}
 
 	// 自定义处理
@@ -205,9 +262,15 @@ func (l *AuditLogger) Close() {
 		close(l.logChan)
 		l.wg.Wait()
 	}
+	for _, sink := range l.sinks {
+		sink.close()
+	}
 	if l.file != nil {
 		l.file.Close()
 	}
+	if l.fallback != nil {
+		l.fallback.Close()
+	}
 }
 
 // Audit 审计中间件
@@ -258,26 +321,35 @@ func AuditWithLogger(logger *AuditLogger) gin.HandlerFunc {
 		c.Set("request_id", requestID)
 		c.Header("X-Request-ID", requestID)
 
+		// 采样：未采样的请求不读取请求体、不捕获响应体
+		sample := logger.sampling(c.Request.Method, c.Request.URL.Path)
+		recorded := sampled(requestID, "", sample.rate)
+		if !recorded && !logger.config.KeepErrors {
+			c.Next()
+			return
+		}
+		captureBody := recorded && sampled(requestID, "body:", sample.bodyRate)
+
 		// 读取请求体
 		var requestBody string
-		if logger.config.LogRequestBody && c.Request.Body != nil {
+		if captureBody && logger.config.LogRequestBody && c.Request.Body != nil {
 			bodyBytes, err := io.ReadAll(c.Request.Body)
 			if err == nil {
 				if len(bodyBytes) > logger.config.MaxRequestBodySize {
-					requestBody = string(bodyBytes[:logger.config.MaxRequestBodySize]) + "...(truncated)"
+					requestBody = string(bodyBytes[:logger.config.MaxRequestBodySize]) + truncatedSuffix
 				} else {
 					requestBody = string(bodyBytes)
 				}
 				// 脱敏处理
-				requestBody = maskSensitiveData(requestBody, logger.config.SensitiveFields)
-				// 重新设置 Body
-				c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+				requestBody = logger.masker.body(requestBody, c.GetHeader("Content-Type"))
 			}
+			// 重新设置 Body（读取失败时后续处理得到同样的错误，如请求体超过大小限制）
+			restoreBody(c, bodyBytes, err)
 		}
 
 		// 包装响应写入器
 		var responseBody string
-		if logger.config.LogResponseBody {
+		if captureBody && logger.config.LogResponseBody {
 			rw := &responseWriter{
 				ResponseWriter: c.Writer,
 				body:           bytes.NewBuffer(nil),
@@ -288,15 +360,20 @@ func AuditWithLogger(logger *AuditLogger) gin.HandlerFunc {
 
 			// 获取响应体
 			if rw.body.Len() > logger.config.MaxResponseBodySize {
-				responseBody = rw.body.String()[:logger.config.MaxResponseBodySize] + "...(truncated)"
+				responseBody = rw.body.String()[:logger.config.MaxResponseBodySize] + truncatedSuffix
 			} else {
 				responseBody = rw.body.String()
 			}
-			responseBody = maskSensitiveData(responseBody, logger.config.SensitiveFields)
+			responseBody = logger.masker.body(responseBody, c.Writer.Header().Get("Content-Type"))
 		} else {
 			c.Next()
 		}
 
+		slow := isSlowRequest(c, time.Since(startTime))
+		if !recorded && c.Writer.Status() < 500 && !slow {
+			return
+		}
+
 		// 构建审计日志
 		auditLog := &AuditLog{
 			RequestID:    requestID,
@@ -304,7 +381,7 @@ func AuditWithLogger(logger *AuditLogger) gin.HandlerFunc {
 			ClientIP:     c.ClientIP(),
 			Method:       c.Request.Method,
 			Path:         c.Request.URL.Path,
-			Query:        c.Request.URL.RawQuery,
+			Query:        logger.masker.query(c.Request.URL.RawQuery),
 			RequestBody:  requestBody,
 			StatusCode:   c.Writer.Status(),
 			ResponseBody: responseBody,
@@ -321,17 +398,63 @@ func AuditWithLogger(logger *AuditLogger) gin.HandlerFunc {
 		if username, exists := c.Get("username"); exists {
 			auditLog.Username = username.(string)
 		}
+		if actor, exists := c.Get(token.ActorContextKey); exists {
+			auditLog.ImpersonatedBy = actor.(*token.Actor)
+		}
+		// API Key 调用方（只记录 Key 前缀）
+		if apiKey := c.GetString("api_key"); apiKey != "" {
+			auditLog.Extra = map[string]interface{}{"api_key": apiKey, "app_name": c.GetString("app_name")}
+		}
+		// 个人访问令牌调用（只记录令牌前缀）
+		if prefix := c.GetString("personal_token"); prefix != "" {
+			auditLog.Extra = map[string]interface{}{"personal_token": prefix}
+		}
 
 		// 获取错误信息
 		if len(c.Errors) > 0 {
 			auditLog.Error = c.Errors.String()
 		}
 
-		// 获取重要请求头
+		// 慢请求标记
+		if slow {
+			auditLog.Slow = true
+			if detail := slowRequestDetail(c); detail != nil {
+				if auditLog.Extra == nil {
+					auditLog.Extra = map[string]interface{}{}
+				}
+				auditLog.Extra["slow_detail"] = detail
+			}
+		}
+
+		// 采样信息：统计时按 sample_rate 还原总量；未被采样、因错误或慢请求记录的标记 sampled_out
+		if sample.rate < 1 {
+			if auditLog.Extra == nil {
+				auditLog.Extra = map[string]interface{}{}
+			}
+			auditLog.Extra["sample_rate"] = sample.rate
+			if !recorded {
+				auditLog.Extra["sampled_out"] = true
+			}
+		}
+
+		// 其他中间件添加的备注（如频率限制预警）
+		if notes := c.GetStringSlice(auditNotesKey); len(notes) > 0 {
+			if auditLog.Extra == nil {
+				auditLog.Extra = map[string]interface{}{}
+			}
+			auditLog.Extra["notes"] = notes
+		}
+
+		// 获取重要请求头（及脱敏规则中列出的请求头）
 		auditLog.Headers = map[string]string{
-			"Content-Type":  c.GetHeader("Content-Type"),
+			"Content-Type":  logger.masker.header("Content-Type", c.GetHeader("Content-Type")),
 			"Authorization": maskString(c.GetHeader("Authorization")),
-			"X-App-Key":     c.GetHeader("X-App-Key"),
+			"X-App-Key":     logger.masker.header("X-App-Key", c.GetHeader("X-App-Key")),
+		}
+		for _, name := range logger.masker.headers {
+			if value := c.GetHeader(name); value != "" && name != "Authorization" {
+				auditLog.Headers[name] = logger.masker.header(name, value)
+			}
 		}
 
 		// 记录日志
@@ -339,46 +462,23 @@ func AuditWithLogger(logger *AuditLogger) gin.HandlerFunc {
 	}
 }
 
+// auditNotesKey 审计备注的上下文 Key
+const auditNotesKey = "audit_notes"
+
+// AddAuditNote 为当前请求的审计日志添加备注
+func AddAuditNote(c *gin.Context, note string) {
+	c.Set(auditNotesKey, append(c.GetStringSlice(auditNotesKey), note))
+}
+
 // generateRequestID 生成请求 ID
 func generateRequestID() string {
 	return fmt.Sprintf("%d-%d", time.Now().UnixNano(), time.Now().Nanosecond()%10000)
 }
 
-// maskSensitiveData 脱敏敏感数据
+// maskSensitiveData 按字段名脱敏 JSON（任意层级，包括数组中的对象）
 func maskSensitiveData(data string, sensitiveFields []string) string {
-	for _, field := range sensitiveFields {
-		// 简单的 JSON 字段脱敏
-		data = maskJSONField(data, field)
-	}
-	return data
-}
-
-// maskJSONField 脱敏 JSON 字段
-func maskJSONField(data, field string) string {
-	// 这是一个简化的实现，实际使用可能需要更复杂的处理
-	var result map[string]interface{}
-	if err := json.Unmarshal([]byte(data), &result); err != nil {
-		return data
-	}
-
-	maskMapField(result, field)
-
-	masked, err := json.Marshal(result)
-	if err != nil {
-		return data
-	}
-	return string(masked)
-}
-
-// maskMapField 递归脱敏 map 字段
-func maskMapField(data map[string]interface{}, field string) {
-	for key, value := range data {
-		if key == field {
-			data[key] = "***MASKED***"
-		} else if nested, ok := value.(map[string]interface{}); ok {
-			maskMapField(nested, field)
-		}
-	}
+	masker, _ := newAuditMasker(sensitiveFields, AuditMaskRules{})
+	return masker.body(data, "")
 }
 
 // maskString 脱敏字符串
@@ -388,89 +488,3 @@ func maskString(s string) string {
 	}
 	return s[:4] + "***" + s[len(s)-4:]
 }
-
-// SecurityAudit 安全审计中间件（记录安全相关事件）
-func SecurityAudit() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 记录安全相关信息
-		securityLog := map[string]interface{}{
-			"timestamp":  time.Now(),
-			"client_ip":  c.ClientIP(),
-			"method":     c.Request.Method,
-			"path":       c.Request.URL.Path,
-			"user_agent": c.Request.UserAgent(),
-		}
-
-		// 检测可疑行为
-		suspicious := false
-		var reasons []string
-
-		// 检查 SQL 注入特征
-		query := c.Request.URL.RawQuery
-		if containsSQLInjection(query) {
-			suspicious = true
-			reasons = append(reasons, "可能的 SQL 注入")
-		}
-
-		// 检查 XSS 特征
-		if containsXSS(query) {
-			suspicious = true
-			reasons = append(reasons, "可能的 XSS 攻击")
-		}
-
-		// 检查路径遍历
-		if containsPathTraversal(c.Request.URL.Path) {
-			suspicious = true
-			reasons = append(reasons, "可能的路径遍历")
-		}
-
-		if suspicious {
-			securityLog["suspicious"] = true
-			securityLog["reasons"] = reasons
-			log.Printf("[SECURITY ALERT] %v", securityLog)
-		}
-
-		c.Next()
-	}
-}
-
-// containsSQLInjection 检查是否包含 SQL 注入特征
-func containsSQLInjection(s string) bool {
-	patterns := []string{
-		"'--", "' OR ", "' AND ", "UNION SELECT", "DROP TABLE",
-		"INSERT INTO", "DELETE FROM", "UPDATE SET", "1=1", "1'='1",
-	}
-	for _, p := range patterns {
-		if bytes.Contains(bytes.ToUpper([]byte(s)), []byte(p)) {
-			return true
-		}
-	}
-	return false
-}
-
-// containsXSS 检查是否包含 XSS 特征
-func containsXSS(s string) bool {
-	patterns := []string{
-		"<script", "javascript:", "onerror=", "onload=", "onclick=",
-		"<iframe", "<object", "<embed", "expression(",
-	}
-	for _, p := range patterns {
-		if bytes.Contains(bytes.ToLower([]byte(s)), []byte(p)) {
-			return true
-		}
-	}
-	return false
-}
-
-// containsPathTraversal 检查是否包含路径遍历特征
-func containsPathTraversal(s string) bool {
-	patterns := []string{
-		"../", "..\\", "%2e%2e", "%252e%252e",
-	}
-	for _, p := range patterns {
-		if bytes.Contains(bytes.ToLower([]byte(s)), []byte(p)) {
-			return true
-		}
-	}
-	return false
-}
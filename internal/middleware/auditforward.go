@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/metrics"
+)
+
+// AuditSink 审计日志的外部输出（如 Elasticsearch、Kafka、syslog，实现见 internal/auditsink）。
+// 每个输出在独立的协程中批量写入，慢或不可用的输出不会阻塞请求与文件写入
+type AuditSink interface {
+	// Name 输出名称（用于指标标签与日志）
+	Name() string
+	// WriteBatch 写入一批审计日志，返回错误时整批重试
+	WriteBatch(ctx context.Context, logs []*AuditLog) error
+	// Close 释放连接等资源
+	Close() error
+}
+
+// AuditSinkOptions 外部输出的批量写入参数
+type AuditSinkOptions struct {
+	// 每批最多条数
+	BatchSize int
+	// 未攒满一批时的最长等待时间
+	FlushInterval time.Duration
+	// 待发送队列长度，已满时丢弃新日志
+	BufferSize int
+	// 单批写入的超时
+	Timeout time.Duration
+	// 失败后的重试次数（间隔 1s、2s、4s…）
+	Retries int
+}
+
+// DefaultAuditSinkOptions 默认批量写入参数
+var DefaultAuditSinkOptions = AuditSinkOptions{
+	BatchSize:     100,
+	FlushInterval: 2 * time.Second,
+	BufferSize:    10000,
+	Timeout:       10 * time.Second,
+	Retries:       2,
+}
+
+var (
+	auditSinkSentTotal    = metrics.NewCounterVec("audit_sink_sent_total", "写入外部输出的审计日志条数", "sink")
+	auditSinkDroppedTotal = metrics.NewCounterVec("audit_sink_dropped_total", "因队列已满或重试耗尽而丢弃的审计日志条数", "sink")
+)
+
+// sinkWorker 单个外部输出的发送队列
+type sinkWorker struct {
+	sink    AuditSink
+	options AuditSinkOptions
+	queue   chan *AuditLog
+	done    chan struct{}
+	// 重试等待期间收到 Close 时立即放弃
+	closing chan struct{}
+	once    sync.Once
+}
+
+func newSinkWorker(sink AuditSink, options AuditSinkOptions) *sinkWorker {
+	defaults := DefaultAuditSinkOptions
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaults.BatchSize
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = defaults.FlushInterval
+	}
+	if options.BufferSize <= 0 {
+		options.BufferSize = defaults.BufferSize
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaults.Timeout
+	}
+	if options.Retries < 0 {
+		options.Retries = 0
+	}
+
+	w := &sinkWorker{
+		sink:    sink,
+		options: options,
+		queue:   make(chan *AuditLog, options.BufferSize),
+		done:    make(chan struct{}),
+		closing: make(chan struct{}),
+	}
+	go w.loop()
+	return w
+}
+
+// enqueue 加入发送队列（不阻塞）
+func (w *sinkWorker) enqueue(auditLog *AuditLog) {
+	select {
+	case w.queue <- auditLog:
+	default:
+		auditSinkDroppedTotal.Inc(w.sink.Name())
+	}
+}
+
+// loop 攒批发送，队列关闭后发送剩余日志再退出
+func (w *sinkWorker) loop() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.options.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*AuditLog, 0, w.options.BatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			w.send(batch)
+			batch = make([]*AuditLog, 0, w.options.BatchSize)
+		}
+	}
+	for {
+		select {
+		case auditLog, ok := <-w.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, auditLog)
+			if len(batch) >= w.options.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// send 写入一批日志，失败时按退避间隔重试，重试耗尽后丢弃
+func (w *sinkWorker) send(batch []*AuditLog) {
+	name := w.sink.Name()
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), w.options.Timeout)
+		err := w.sink.WriteBatch(ctx, batch)
+		cancel()
+		if err == nil {
+			auditSinkSentTotal.Add(float64(len(batch)), name)
+			return
+		}
+
+		auditWriteFailuresTotal.Inc(name)
+		if attempt >= w.options.Retries {
+			log.Printf("审计日志写入 %s 失败，丢弃 %d 条: %v", name, len(batch), err)
+			auditSinkDroppedTotal.Add(float64(len(batch)), name)
+			return
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-w.closing:
+			log.Printf("审计日志写入 %s 失败（正在关闭），丢弃 %d 条: %v", name, len(batch), err)
+			auditSinkDroppedTotal.Add(float64(len(batch)), name)
+			return
+		}
+	}
+}
+
+// close 发送剩余日志并关闭输出
+func (w *sinkWorker) close() {
+	w.once.Do(func() {
+		// 关闭阶段不再退避重试，避免拖慢退出
+		close(w.closing)
+		close(w.queue)
+		<-w.done
+		if err := w.sink.Close(); err != nil {
+			log.Printf("关闭审计日志输出 %s 失败: %v", w.sink.Name(), err)
+		}
+	})
+}
@@ -0,0 +1,404 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maskedValue 审计日志中脱敏后的值
+const maskedValue = "***MASKED***"
+
+// truncatedSuffix 超过最大记录长度的请求/响应体截断后的标记
+const truncatedSuffix = "...(truncated)"
+
+// AuditMaskRules 审计日志的脱敏规则（在 SensitiveFields 按字段名脱敏之外）
+type AuditMaskRules struct {
+	// 正则表达式：匹配的内容（有捕获组时只替换捕获组）替换为 ***MASKED***，
+	// 作用于请求/响应体（JSON 只作用于字符串值，不破坏结构）、查询字符串与记录的请求头
+	Patterns []string
+	// JSONPath（$.card.number、$.items[*].token、$..cvv），作用于 JSON 请求/响应体，命中的值整体替换
+	JSONPaths []string
+	// 查询参数名（不区分大小写），同样作用于表单（application/x-www-form-urlencoded）请求体
+	QueryParams []string
+	// 请求头名：除固定记录的 Content-Type、Authorization、X-App-Key 外，列出的请求头也会记录，值部分遮盖
+	Headers []string
+}
+
+// Validate 检查正则表达式与 JSONPath 是否有效
+func (r AuditMaskRules) Validate() error {
+	_, err := newAuditMasker(nil, r)
+	return err
+}
+
+// auditMasker 编译后的脱敏规则
+type auditMasker struct {
+	// 按字段名脱敏（JSON 任意层级的键、查询参数与表单字段）
+	fields map[string]bool
+	// 不完整的 JSON（如被截断）中按字段名匹配 "name": value
+	fieldPattern *regexp.Regexp
+	patterns     []*regexp.Regexp
+	paths        [][]jsonPathSegment
+	// 小写的查询参数名
+	params map[string]bool
+	// 规范化的请求头名
+	headers []string
+}
+
+func newAuditMasker(sensitiveFields []string, rules AuditMaskRules) (*auditMasker, error) {
+	m := &auditMasker{
+		fields: make(map[string]bool),
+		params: make(map[string]bool),
+	}
+	var names []string
+	for _, field := range sensitiveFields {
+		m.fields[field] = true
+		names = append(names, regexp.QuoteMeta(field))
+	}
+	if len(names) > 0 {
+		m.fieldPattern = regexp.MustCompile(`("(?:` + strings.Join(names, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+	}
+	for _, pattern := range rules.Patterns {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("脱敏正则表达式 %q 无效: %v", pattern, err)
+		}
+		m.patterns = append(m.patterns, re)
+	}
+	for _, expr := range rules.JSONPaths {
+		if expr = strings.TrimSpace(expr); expr == "" {
+			continue
+		}
+		path, err := parseJSONPath(expr)
+		if err != nil {
+			return nil, fmt.Errorf("脱敏 JSONPath %q 无效: %v", expr, err)
+		}
+		m.paths = append(m.paths, path)
+	}
+	for _, param := range rules.QueryParams {
+		if param = strings.TrimSpace(param); param != "" {
+			m.params[strings.ToLower(param)] = true
+		}
+	}
+	for _, header := range rules.Headers {
+		if header = strings.TrimSpace(header); header != "" {
+			m.headers = append(m.headers, http.CanonicalHeaderKey(header))
+		}
+	}
+	return m, nil
+}
+
+// body 脱敏请求/响应体：JSON 按字段名、JSONPath 与正则（字符串值）处理；
+// 无法解析的内容（如截断的 JSON）按 "字段名": 值 匹配，表单按字段名处理，最后应用正则
+func (m *auditMasker) body(data, contentType string) string {
+	if data == "" {
+		return data
+	}
+	if masked, ok := m.jsonBody(data); ok {
+		return masked
+	}
+	data, truncated := strings.CutSuffix(data, truncatedSuffix)
+	if m.fieldPattern != nil {
+		data = m.fieldPattern.ReplaceAllString(data, `${1}"`+maskedValue+`"`)
+	}
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		data = m.pairs(data)
+	}
+	data = m.text(data)
+	if truncated {
+		data += truncatedSuffix
+	}
+	return data
+}
+
+// jsonBody 脱敏 JSON，不是合法 JSON（包括被截断的）时返回 false；没有内容被替换时保留原文
+func (m *auditMasker) jsonBody(data string) (string, bool) {
+	trimmed := strings.TrimSpace(data)
+	if trimmed == "" || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return "", false
+	}
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return "", false
+	}
+
+	value, changed := m.maskFields(value)
+	for _, path := range m.paths {
+		var hit bool
+		if value, hit = maskJSONPath(value, path); hit {
+			changed = true
+		}
+	}
+	if len(m.patterns) > 0 {
+		var hit bool
+		if value, hit = m.maskStrings(value); hit {
+			changed = true
+		}
+	}
+	if !changed {
+		return data, true
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return data, true
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), true
+}
+
+// maskFields 按字段名递归脱敏（包括数组中的对象）
+func (m *auditMasker) maskFields(value interface{}) (interface{}, bool) {
+	changed := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if m.fields[key] {
+				v[key] = maskedValue
+				changed = true
+				continue
+			}
+			var hit bool
+			if v[key], hit = m.maskFields(item); hit {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			var hit bool
+			if v[i], hit = m.maskFields(item); hit {
+				changed = true
+			}
+		}
+	}
+	return value, changed
+}
+
+// maskStrings 对 JSON 中的字符串值应用正则
+func (m *auditMasker) maskStrings(value interface{}) (interface{}, bool) {
+	changed := false
+	switch v := value.(type) {
+	case string:
+		if masked := m.text(v); masked != v {
+			return masked, true
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			var hit bool
+			if v[key], hit = m.maskStrings(item); hit {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			var hit bool
+			if v[i], hit = m.maskStrings(item); hit {
+				changed = true
+			}
+		}
+	}
+	return value, changed
+}
+
+// query 脱敏查询字符串
+func (m *auditMasker) query(raw string) string {
+	if raw == "" {
+		return raw
+	}
+	return m.text(m.pairs(raw))
+}
+
+// pairs 按参数名脱敏 a=1&b=2 格式的内容，参数顺序与未命中参数的原始编码保持不变
+func (m *auditMasker) pairs(raw string) string {
+	if len(m.fields) == 0 && len(m.params) == 0 {
+		return raw
+	}
+	parts := strings.Split(raw, "&")
+	for i, part := range parts {
+		key, _, hasValue := strings.Cut(part, "=")
+		if !hasValue {
+			continue
+		}
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if m.fields[name] || m.params[strings.ToLower(name)] {
+			parts[i] = key + "=" + maskedValue
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// header 记录的请求头值：列出的请求头部分遮盖，再应用正则
+func (m *auditMasker) header(name, value string) string {
+	for _, h := range m.headers {
+		if h == name {
+			return m.text(maskString(value))
+		}
+	}
+	return m.text(value)
+}
+
+// text 应用正则：没有捕获组时替换整个匹配，有捕获组时只替换捕获组（保留如 card= 之类的上下文）
+func (m *auditMasker) text(s string) string {
+	for _, re := range m.patterns {
+		if re.NumSubexp() == 0 {
+			s = re.ReplaceAllLiteralString(s, maskedValue)
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, match := range re.FindAllStringSubmatchIndex(s, -1) {
+			for g := 1; g <= re.NumSubexp(); g++ {
+				start, end := match[2*g], match[2*g+1]
+				if start < last {
+					continue
+				}
+				b.WriteString(s[last:start])
+				b.WriteString(maskedValue)
+				last = end
+			}
+		}
+		b.WriteString(s[last:])
+		s = b.String()
+	}
+	return s
+}
+
+// jsonPathSegment JSONPath 的一级：.name、['name']、[n]、.* / [*]，recursive 表示 ..（任意层级）
+type jsonPathSegment struct {
+	recursive bool
+	wildcard  bool
+	name      string
+	index     int
+}
+
+// parseJSONPath 解析 JSONPath 子集：$ 开头，支持 .name、['name']、[n]、[*]、.* 与 ..name
+func parseJSONPath(expr string) ([]jsonPathSegment, error) {
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("应以 $ 开头")
+	}
+	var segments []jsonPathSegment
+	rest := expr[1:]
+	for rest != "" {
+		segment := jsonPathSegment{index: -1}
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			segment.recursive = true
+			rest = rest[2:]
+			if strings.HasPrefix(rest, "[") {
+				break
+			}
+			fallthrough
+		case strings.HasPrefix(rest, "."):
+			rest = strings.TrimPrefix(rest, ".")
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			if name == "" {
+				return nil, fmt.Errorf("缺少字段名")
+			}
+			if name == "*" {
+				segment.wildcard = true
+			} else {
+				segment.name = name
+			}
+			rest = rest[end:]
+			segments = append(segments, segment)
+			continue
+		case !strings.HasPrefix(rest, "["):
+			return nil, fmt.Errorf("无法解析 %q", rest)
+		}
+
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return nil, fmt.Errorf("缺少 ]")
+		}
+		inner := strings.TrimSpace(rest[1:end])
+		rest = rest[end+1:]
+		switch {
+		case inner == "*":
+			segment.wildcard = true
+		case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+			segment.name = inner[1 : len(inner)-1]
+		default:
+			n, err := strconv.Atoi(inner)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("不支持的下标 [%s]", inner)
+			}
+			segment.index = n
+		}
+		segments = append(segments, segment)
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("不能脱敏整个请求体")
+	}
+	return segments, nil
+}
+
+// maskJSONPath 替换 JSONPath 命中的值，返回替换后的值及是否命中
+func maskJSONPath(value interface{}, path []jsonPathSegment) (interface{}, bool) {
+	if len(path) == 0 {
+		return maskedValue, true
+	}
+	segment, rest := path[0], path[1:]
+	hit := false
+
+	// ..name：先在各层子节点中继续查找，再匹配当前层
+	if segment.recursive {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for key, item := range v {
+				var h bool
+				if v[key], h = maskJSONPath(item, path); h {
+					hit = true
+				}
+			}
+		case []interface{}:
+			for i, item := range v {
+				var h bool
+				if v[i], h = maskJSONPath(item, path); h {
+					hit = true
+				}
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if !segment.wildcard && (segment.name == "" || key != segment.name) {
+				continue
+			}
+			var h bool
+			if v[key], h = maskJSONPath(item, rest); h {
+				hit = true
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if !segment.wildcard && i != segment.index {
+				continue
+			}
+			var h bool
+			if v[i], h = maskJSONPath(item, rest); h {
+				hit = true
+			}
+		}
+	}
+	return value, hit
+}
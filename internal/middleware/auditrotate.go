@@ -0,0 +1,227 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/metrics"
+)
+
+// auditBackupTimeFormat 轮转后文件名中的时间（UTC），如 audit-2026-10-15T05-27-39.000.log.gz
+const auditBackupTimeFormat = "2006-01-02T15-04-05.000"
+
+// AuditRotation 审计日志文件轮转配置（MaxSize 与 Interval 均为 0 时不轮转，可交给外部 logrotate）
+type AuditRotation struct {
+	// 文件超过该大小（字节）时轮转
+	MaxSize int64
+	// 按固定周期轮转（按 UTC 对齐，如 24h 为每天零点）
+	Interval time.Duration
+	// 轮转后的文件保留时长（0 不按时间清理）
+	MaxAge time.Duration
+	// 最多保留的轮转文件数（0 不限制）
+	MaxBackups int
+	// 轮转后的文件以 gzip 压缩
+	Compress bool
+}
+
+// Enabled 是否启用轮转
+func (r AuditRotation) Enabled() bool {
+	return r.MaxSize > 0 || r.Interval > 0
+}
+
+var auditRotationsTotal = metrics.NewCounterVec("audit_rotations_total", "审计日志文件轮转次数", "result")
+
+// auditMaintenanceMu 同一时间只执行一次压缩与清理
+var auditMaintenanceMu sync.Mutex
+
+// trackFile 记录当前文件的大小与起始时间（调用方持有 l.mu）。
+// 重启后沿用已有文件时，以其最后修改时间判断是否已跨过轮转周期
+func (l *AuditLogger) trackFile() {
+	l.fileSize = 0
+	l.fileStart = time.Now()
+	if l.file == nil {
+		return
+	}
+	if info, err := l.file.Stat(); err == nil && info.Size() > 0 {
+		l.fileSize = info.Size()
+		l.fileStart = info.ModTime()
+	}
+}
+
+// shouldRotate 写入 n 字节前是否需要轮转
+func (l *AuditLogger) shouldRotate(n int) bool {
+	rotation := l.config.Rotation
+	if l.file == nil || !rotation.Enabled() || l.fileSize == 0 {
+		return false
+	}
+	if rotation.MaxSize > 0 && l.fileSize+int64(n) > rotation.MaxSize {
+		return true
+	}
+	if rotation.Interval > 0 {
+		next := l.fileStart.Truncate(rotation.Interval).Add(rotation.Interval)
+		return !time.Now().Before(next)
+	}
+	return false
+}
+
+// rotate 将当前文件改名为带时间的备份并重新打开主文件（调用方持有 l.mu），压缩与清理在后台执行
+func (l *AuditLogger) rotate() {
+	l.file.Close()
+	l.file = nil
+
+	backup := auditBackupName(l.config.FilePath, time.Now())
+	if err := os.Rename(l.config.FilePath, backup); err != nil {
+		auditRotationsTotal.Inc("error")
+		log.Printf("审计日志轮转失败: %v", err)
+		backup = ""
+	} else {
+		auditRotationsTotal.Inc("ok")
+	}
+
+	if err := l.openPrimary(); err != nil {
+		// 交给 writeFile 的重开与降级逻辑处理
+		log.Printf("审计日志轮转后重新打开失败: %v", err)
+	}
+	if backup == "" {
+		// 改名失败时继续写原文件，推迟到下一个周期或下一次超限再试
+		l.fileStart = time.Now()
+		return
+	}
+
+	path, rotation := l.config.FilePath, l.config.Rotation
+	go maintainAuditBackups(path, rotation)
+}
+
+// auditBackupName 轮转后的文件名：audit.log → audit-<时间>.log
+func auditBackupName(path string, t time.Time) string {
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(path, ext)
+	name := fmt.Sprintf("%s-%s%s", prefix, t.UTC().Format(auditBackupTimeFormat), ext)
+	if _, err := os.Stat(name); err == nil {
+		name = fmt.Sprintf("%s-%s.%d%s", prefix, t.UTC().Format(auditBackupTimeFormat), t.UnixNano(), ext)
+	}
+	return name
+}
+
+// auditBackup 轮转后的文件
+type auditBackup struct {
+	path       string
+	rotatedAt  time.Time
+	compressed bool
+}
+
+// listAuditBackups 列出主文件所在目录中的轮转文件（按轮转时间从新到旧）
+func listAuditBackups(path string) ([]auditBackup, error) {
+	dir := filepath.Dir(path)
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(filepath.Base(path), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []auditBackup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(name, prefix)
+		compressed := strings.HasSuffix(rest, ext+".gz")
+		if compressed {
+			rest = strings.TrimSuffix(rest, ext+".gz")
+		} else if strings.HasSuffix(rest, ext) {
+			rest = strings.TrimSuffix(rest, ext)
+		} else {
+			continue
+		}
+		if len(rest) < len(auditBackupTimeFormat) {
+			continue
+		}
+		rotatedAt, err := time.Parse(auditBackupTimeFormat, rest[:len(auditBackupTimeFormat)])
+		if err != nil {
+			continue
+		}
+		backups = append(backups, auditBackup{
+			path:       filepath.Join(dir, name),
+			rotatedAt:  rotatedAt,
+			compressed: compressed,
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].rotatedAt.After(backups[j].rotatedAt)
+	})
+	return backups, nil
+}
+
+// maintainAuditBackups 压缩未压缩的轮转文件（包括上次进程退出前未处理完的），并按保留策略删除旧文件
+func maintainAuditBackups(path string, rotation AuditRotation) {
+	auditMaintenanceMu.Lock()
+	defer auditMaintenanceMu.Unlock()
+
+	backups, err := listAuditBackups(path)
+	if err != nil {
+		log.Printf("读取审计日志目录失败: %v", err)
+		return
+	}
+
+	for i, backup := range backups {
+		expired := rotation.MaxAge > 0 && time.Since(backup.rotatedAt) > rotation.MaxAge
+		if expired || (rotation.MaxBackups > 0 && i >= rotation.MaxBackups) {
+			if err := os.Remove(backup.path); err != nil && !os.IsNotExist(err) {
+				log.Printf("删除过期的审计日志失败: %v", err)
+			}
+			continue
+		}
+		if rotation.Compress && !backup.compressed {
+			if err := gzipAuditFile(backup.path); err != nil {
+				log.Printf("压缩审计日志失败: %v", err)
+			}
+		}
+	}
+}
+
+// gzipAuditFile 压缩为 <path>.gz 后删除原文件（先写临时文件，避免中断时留下不完整的压缩包）
+func gzipAuditFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
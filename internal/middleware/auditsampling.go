@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// auditSampling 一次请求的审计采样比例
+type auditSampling struct {
+	rate     float64
+	bodyRate float64
+}
+
+// sampling 按路径规则（按顺序匹配第一条）确定采样比例，规则未设置的比例沿用全局配置
+func (l *AuditLogger) sampling(method, path string) auditSampling {
+	s := auditSampling{
+		rate:     normalizeRate(l.config.SampleRate),
+		bodyRate: normalizeRate(l.config.BodySampleRate),
+	}
+	for _, rule := range l.config.PathRules {
+		if !matchRoute(rule.Route, method, path) {
+			continue
+		}
+		if rule.SampleRate >= 0 {
+			s.rate = rule.SampleRate
+		}
+		if rule.BodySampleRate >= 0 {
+			s.bodyRate = rule.BodySampleRate
+		}
+		break
+	}
+	return s
+}
+
+// normalizeRate 全局比例未配置（0）时全部记录；不记录某些路径用路径规则 off
+func normalizeRate(rate float64) float64 {
+	if rate <= 0 || rate > 1 {
+		return 1
+	}
+	return rate
+}
+
+// sampled 按请求 ID 的哈希决定是否采样：同一请求 ID 在各实例、各服务的结果一致，便于串联完整链路
+func sampled(requestID, salt string, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	h := fnv.New64a()
+	h.Write([]byte(salt))
+	h.Write([]byte(requestID))
+	return float64(h.Sum64()) < rate*math.MaxUint64
+}
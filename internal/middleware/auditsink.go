@@ -0,0 +1,202 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/metrics"
+)
+
+// 审计日志文件检测与重开间隔
+const (
+	auditRotateCheckInterval = time.Second
+	auditReopenInterval      = 5 * time.Second
+)
+
+var (
+	auditWriteFailuresTotal = metrics.NewCounterVec(
+		"audit_write_failures_total", "审计日志写入失败次数", "sink")
+
+	// activeAudit 当前写文件的审计日志记录器，用于健康检查
+	activeAudit   *AuditLogger
+	activeAuditMu sync.RWMutex
+)
+
+func init() {
+	metrics.NewGaugeFunc("audit_sink_degraded", "审计日志是否已切换到备用输出（1 表示降级）", func() float64 {
+		if health, ok := AuditHealthStatus(); ok && health.Status != "ok" {
+			return 1
+		}
+		return 0
+	})
+}
+
+// AuditHealth 审计日志写入健康状态
+type AuditHealth struct {
+	// ok：正常写入主文件；degraded：主文件不可写，已写入备用输出
+	Status string `json:"status"`
+	// 当前输出：primary, fallback
+	Sink string `json:"sink"`
+	// 累计写入失败次数
+	Failures uint64 `json:"failures"`
+	// 最近一次错误
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// AuditHealthStatus 获取审计日志写入健康状态（未启用文件输出时返回 false）
+func AuditHealthStatus() (AuditHealth, bool) {
+	activeAuditMu.RLock()
+	l := activeAudit
+	activeAuditMu.RUnlock()
+	if l == nil {
+		return AuditHealth{}, false
+	}
+	return l.Health(), true
+}
+
+// Health 审计日志写入健康状态
+func (l *AuditLogger) Health() AuditHealth {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	health := AuditHealth{
+		Status:    "ok",
+		Sink:      "primary",
+		Failures:  l.failures,
+		LastError: l.lastError,
+	}
+	if l.degraded {
+		health.Status = "degraded"
+		health.Sink = "fallback"
+	}
+	if !l.lastErrorAt.IsZero() {
+		at := l.lastErrorAt
+		health.LastErrorAt = &at
+	}
+	return health
+}
+
+// writeFile 写入审计日志文件（调用方持有 l.mu）：
+// 达到轮转条件时先轮转；文件被外部轮转时重新打开；写入失败时重开一次并重试，仍失败则写入备用输出
+func (l *AuditLogger) writeFile(line string) {
+	l.checkRotated()
+	if l.shouldRotate(len(line)) {
+		l.rotate()
+	}
+
+	err := l.writePrimary(line)
+	if err != nil && l.reopen() == nil {
+		err = l.writePrimary(line)
+	}
+	if err == nil {
+		if l.degraded {
+			l.degraded = false
+			log.Printf("审计日志已恢复写入: %s", l.config.FilePath)
+		}
+		return
+	}
+
+	auditWriteFailuresTotal.Inc("primary")
+	l.failures++
+	l.lastError = err.Error()
+	l.lastErrorAt = time.Now()
+	if !l.degraded {
+		l.degraded = true
+		log.Printf("审计日志写入失败，切换到备用输出: %v", err)
+	}
+	l.writeFallback(line)
+}
+
+// writePrimary 写入主日志文件
+func (l *AuditLogger) writePrimary(line string) error {
+	if l.file == nil {
+		return errors.New("审计日志文件未打开")
+	}
+	n, err := l.file.WriteString(line)
+	l.fileSize += int64(n)
+	return err
+}
+
+// writeFallback 写入备用文件，备用文件不可用时写入标准错误
+func (l *AuditLogger) writeFallback(line string) {
+	if l.config.FallbackPath != "" {
+		if l.fallback == nil {
+			file, err := openAuditFile(l.config.FallbackPath)
+			if err == nil {
+				l.fallback = file
+			}
+		}
+		if l.fallback != nil {
+			if _, err := l.fallback.WriteString(line); err == nil {
+				return
+			}
+			l.fallback.Close()
+			l.fallback = nil
+		}
+		auditWriteFailuresTotal.Inc("fallback")
+	}
+
+	fmt.Fprint(os.Stderr, "[AUDIT] "+line)
+}
+
+// reopen 重新打开主日志文件（限制重试频率，避免磁盘写满时每条日志都重试）
+func (l *AuditLogger) reopen() error {
+	if time.Since(l.lastReopen) < auditReopenInterval {
+		return errors.New("重开过于频繁")
+	}
+	l.lastReopen = time.Now()
+	return l.openPrimary()
+}
+
+// openPrimary 关闭并重新打开主日志文件
+func (l *AuditLogger) openPrimary() error {
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+	file, err := openAuditFile(l.config.FilePath)
+	if err != nil {
+		return err
+	}
+	l.file = file
+	l.trackFile()
+	return nil
+}
+
+// checkRotated 检测日志文件是否被外部移走或删除（如 logrotate），是则重新打开
+func (l *AuditLogger) checkRotated() {
+	if l.file == nil || time.Since(l.lastRotateCheck) < auditRotateCheckInterval {
+		return
+	}
+	l.lastRotateCheck = time.Now()
+
+	current, err := l.file.Stat()
+	if err != nil {
+		return
+	}
+	onDisk, err := os.Stat(l.config.FilePath)
+	if err == nil && os.SameFile(current, onDisk) {
+		return
+	}
+	if err := l.openPrimary(); err != nil {
+		log.Printf("审计日志文件重新打开失败: %v", err)
+	}
+}
+
+// openAuditFile 以追加模式打开日志文件（自动创建目录）
+func openAuditFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建日志目录失败: %v", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开日志文件失败: %v", err)
+	}
+	return file, nil
+}
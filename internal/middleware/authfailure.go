@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 认证失败类型
+const (
+	// AuthFailureToken 无效、过期或已吊销的 Token
+	AuthFailureToken = "invalid_token"
+	// AuthFailureSignature API 签名校验失败（含无效的 AppKey）
+	AuthFailureSignature = "signature"
+	// AuthFailureLogin 用户名或密码错误
+	AuthFailureLogin = "login"
+	// AuthFailureBreakGlass 紧急访问凭证错误
+	AuthFailureBreakGlass = "break_glass"
+	// AuthFailureOAuth 第三方登录失败（state 无效、授权码换取失败等）
+	AuthFailureOAuth = "oauth"
+	// AuthFailureAPIKey 无效、已吊销或已过期的 API Key
+	AuthFailureAPIKey = "api_key"
+	// AuthFailurePersonalToken 无效、已吊销或已过期的个人访问令牌
+	AuthFailurePersonalToken = "personal_token"
+	// AuthFailureSAML 管理后台 SAML 单点登录失败（签名无效、断言过期、未分配角色等）
+	AuthFailureSAML = "saml"
+)
+
+// AuthFailure 一次认证失败
+type AuthFailure struct {
+	Kind      string
+	IP        string
+	Method    string
+	Path      string
+	UserAgent string
+	RequestID string
+	// 尝试的用户名或 AppKey（可能为空）
+	Subject string
+	Detail  string
+}
+
+// OnAuthFailure 认证失败时的处理（如写入安全事件），为空不处理
+var OnAuthFailure func(ctx context.Context, failure AuthFailure)
+
+// ReportAuthFailure 报告当前请求的认证失败
+func ReportAuthFailure(c *gin.Context, kind, subject, detail string) {
+	if OnAuthFailure == nil {
+		return
+	}
+	OnAuthFailure(c.Request.Context(), AuthFailure{
+		Kind:      kind,
+		IP:        AbuseIP(c),
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		UserAgent: c.Request.UserAgent(),
+		RequestID: c.GetString("request_id"),
+		Subject:   subject,
+		Detail:    detail,
+	})
+}
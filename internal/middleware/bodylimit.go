@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"new-openclaw/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+var bodyTooLargeTotal = metrics.NewCounterVec(
+	"http_body_too_large_total", "请求体超过大小限制被拒绝的请求数", "method", "route")
+
+// BodyLimitConfig 请求体大小限制配置
+type BodyLimitConfig struct {
+	// 默认上限（字节，<= 0 表示不限制未单独配置的路由）
+	MaxBytes int64
+	// 按路由配置的上限，key 为 "METHOD /path" 或 "/path"，路径支持 * 后缀通配（如 "/api/v1/signed/*"）
+	RouteLimits map[string]int64
+}
+
+// BodyLimit 限制请求体大小，超出时返回 413。
+// 可用于路由组，但嵌套的限制取较小值：路由组只能收紧全局限制，放宽需在全局配置的 RouteLimits 中设置
+func BodyLimit(maxBytes int64) gin.HandlerFunc {
+	return BodyLimitWithConfig(BodyLimitConfig{MaxBytes: maxBytes})
+}
+
+// BodyLimitWithConfig 按路由限制请求体大小（应在审计、签名验证等读取请求体的中间件之前注册）。
+// 声明了 Content-Length 的请求超出时直接返回 413；分块传输的请求在读取超过上限时出错，
+// 读取方通过 AbortIfBodyTooLarge 返回 413
+func BodyLimitWithConfig(config BodyLimitConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := bodyLimit(config, c.Request.Method, c.FullPath())
+		if limit <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			rejectBodyTooLarge(c)
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// AbortIfBodyTooLarge 读取请求体的错误为超过大小限制时返回 413 并中止请求
+func AbortIfBodyTooLarge(c *gin.Context, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+	rejectBodyTooLarge(c)
+	return true
+}
+
+func rejectBodyTooLarge(c *gin.Context) {
+	bodyTooLargeTotal.Inc(c.Request.Method, routeLabel(c))
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"code":    413,
+		"message": "请求体过大",
+	})
+	c.Abort()
+}
+
+// bodyLimit 获取路由对应的请求体上限
+func bodyLimit(config BodyLimitConfig, method, route string) int64 {
+	if limit, ok := lookupRoute(config.RouteLimits, method, route); ok {
+		return limit
+	}
+	return config.MaxBytes
+}
+
+// lookupRoute 按 "METHOD /path" 或 "/path" 查找路由配置：精确匹配优先，其次取最长的 * 后缀通配
+func lookupRoute[T any](routes map[string]T, method, route string) (T, bool) {
+	if value, ok := routes[method+" "+route]; ok {
+		return value, true
+	}
+	if value, ok := routes[route]; ok {
+		return value, true
+	}
+
+	var best T
+	found, bestLen := false, -1
+	for pattern, value := range routes {
+		p := pattern
+		if i := strings.Index(p, " "); i > 0 {
+			if p[:i] != method {
+				continue
+			}
+			p = p[i+1:]
+		}
+		if matchPath(p, route) && len(p) > bestLen {
+			best, found, bestLen = value, true, len(p)
+		}
+	}
+	return best, found
+}
+
+// restoreBody 读取请求体后重新设置，供后续处理再次读取；读取出错时后续读取返回同样的错误，
+// 避免后续处理读到被截断的请求体
+func restoreBody(c *gin.Context, read []byte, err error) {
+	if err == nil {
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(read))
+		return
+	}
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(read), errReader{err}))
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConcurrencyConfig 并发限制配置
+type ConcurrencyConfig struct {
+	// 单实例最大并发请求数（0 表示不限制）
+	MaxInFlight int
+	// 单个 Key 最大并发请求数（0 表示不限制）
+	MaxInFlightPerKey int
+	// Key 生成函数（默认使用 IP）
+	KeyFunc func(c *gin.Context) string
+	// 排队等待超时（0 表示不排队，立即拒绝）
+	QueueTimeout time.Duration
+	// 饱和时建议客户端的重试间隔
+	RetryAfter time.Duration
+}
+
+var (
+	concurrencyInFlight int64
+	concurrencyMu       sync.Mutex
+
+	concurrencyRejectedTotal = metrics.NewCounterVec(
+		"concurrency_rejected_total", "因并发饱和被拒绝的请求数", "scope")
+)
+
+func init() {
+	metrics.NewGaugeFunc("http_requests_in_flight", "当前处理中的请求数", func() float64 {
+		concurrencyMu.Lock()
+		defer concurrencyMu.Unlock()
+		return float64(concurrencyInFlight)
+	})
+}
+
+// keySemaphore 按 Key 的信号量（引用计数归零时回收）
+type keySemaphore struct {
+	slots chan struct{}
+	refs  int
+}
+
+// ConcurrencyLimit 并发限制中间件
+func ConcurrencyLimit(maxInFlight int, queueTimeout time.Duration) gin.HandlerFunc {
+	return ConcurrencyLimitWithConfig(ConcurrencyConfig{
+		MaxInFlight:  maxInFlight,
+		QueueTimeout: queueTimeout,
+		RetryAfter:   time.Second,
+	})
+}
+
+// ConcurrencyLimitWithConfig 带配置的并发限制中间件（防止慢请求堆积拖垮实例）
+func ConcurrencyLimitWithConfig(config ConcurrencyConfig) gin.HandlerFunc {
+	if config.KeyFunc == nil {
+		config.KeyFunc = DefaultRateLimitConfig.KeyFunc
+	}
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = time.Second
+	}
+
+	var global chan struct{}
+	if config.MaxInFlight > 0 {
+		global = make(chan struct{}, config.MaxInFlight)
+	}
+
+	var mu sync.Mutex
+	keys := make(map[string]*keySemaphore)
+
+	reject := func(c *gin.Context, scope string) {
+		concurrencyRejectedTotal.Inc(scope)
+		c.Header("Retry-After", strconv.Itoa(int((config.RetryAfter+time.Second-1)/time.Second)))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": "服务繁忙，请稍后再试",
+		})
+		c.Abort()
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		if global != nil {
+			if !acquireSlot(ctx, global, config.QueueTimeout) {
+				reject(c, "instance")
+				return
+			}
+			defer func() { <-global }()
+		}
+
+		if config.MaxInFlightPerKey > 0 {
+			key := config.KeyFunc(c)
+
+			mu.Lock()
+			sem, exists := keys[key]
+			if !exists {
+				sem = &keySemaphore{slots: make(chan struct{}, config.MaxInFlightPerKey)}
+				keys[key] = sem
+			}
+			sem.refs++
+			mu.Unlock()
+
+			release := func() {
+				mu.Lock()
+				sem.refs--
+				if sem.refs == 0 {
+					delete(keys, key)
+				}
+				mu.Unlock()
+			}
+
+			if !acquireSlot(ctx, sem.slots, config.QueueTimeout) {
+				release()
+				reject(c, "key")
+				return
+			}
+			defer func() {
+				<-sem.slots
+				release()
+			}()
+		}
+
+		concurrencyMu.Lock()
+		concurrencyInFlight++
+		concurrencyMu.Unlock()
+		defer func() {
+			concurrencyMu.Lock()
+			concurrencyInFlight--
+			concurrencyMu.Unlock()
+		}()
+
+		c.Next()
+	}
+}
+
+// acquireSlot 获取信号量，最多等待 timeout
+func acquireSlot(ctx context.Context, slots chan struct{}, timeout time.Duration) bool {
+	select {
+	case slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if timeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"new-openclaw/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+var notModifiedTotal = metrics.NewCounterVec(
+	"http_not_modified_total", "命中 If-None-Match 返回 304 的请求数", "route")
+
+// ETagConfig 条件请求配置
+type ETagConfig struct {
+	// 计算 ETag 的最大响应体（字节），超过时直接写出、不设置 ETag
+	MaxSize int
+	// 不处理的路径（支持末尾 * 通配）
+	ExcludePaths []string
+}
+
+// DefaultETagConfig 默认条件请求配置
+var DefaultETagConfig = ETagConfig{
+	MaxSize: 1 << 20,
+}
+
+// ETag 条件请求中间件（使用默认配置）
+func ETag() gin.HandlerFunc {
+	return ETagWithConfig(DefaultETagConfig)
+}
+
+// ETagWithConfig 为 GET 请求的 200 JSON 响应计算 ETag（响应体的 SHA-256），
+// 请求的 If-None-Match 命中时返回 304 且不带响应体，轮询列表接口的客户端不必重复下载未变化的数据。
+// 须放在脱敏等改写响应体的中间件之前（外层），ETag 按最终写出的内容计算
+func ETagWithConfig(config ETagConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet || etagExcluded(config, c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		w := &etagWriter{ResponseWriter: original, max: config.MaxSize}
+		c.Writer = w
+		c.Next()
+		c.Writer = original
+
+		if !w.buffering {
+			return
+		}
+		body := w.body.Bytes()
+		etag := `"` + etagOf(body) + `"`
+		header := original.Header()
+		header.Set("ETag", etag)
+		if header.Get("Cache-Control") == "" {
+			// 允许客户端缓存，但每次使用前须携带 If-None-Match 重新验证
+			header.Set("Cache-Control", "private, no-cache")
+		}
+
+		if etagMatch(c.GetHeader("If-None-Match"), etag) {
+			notModifiedTotal.Inc(routeLabel(c))
+			header.Del("Content-Length")
+			original.WriteHeader(http.StatusNotModified)
+			original.WriteHeaderNow()
+			return
+		}
+		original.Write(body)
+	}
+}
+
+// etagWriter 第一次写出时判断是否需要计算 ETag（200 且为 JSON），需要时暂存响应体，其他响应直接写出
+type etagWriter struct {
+	gin.ResponseWriter
+	max       int
+	body      bytes.Buffer
+	decided   bool
+	buffering bool
+}
+
+func (w *etagWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.decided = true
+		w.buffering = w.Status() == http.StatusOK &&
+			strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+	}
+	if !w.buffering {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.max > 0 && w.body.Len()+len(b) > w.max {
+		// 响应体过大，放弃计算 ETag，写出已暂存的内容
+		w.buffering = false
+		if _, err := w.ResponseWriter.Write(w.body.Bytes()); err != nil {
+			return 0, err
+		}
+		w.body.Reset()
+		return w.ResponseWriter.Write(b)
+	}
+	return w.body.Write(b)
+}
+
+func (w *etagWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func etagOf(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:16])
+}
+
+// etagMatch If-None-Match 是否命中（逗号分隔的列表或 *，按弱比较忽略 W/ 前缀）
+func etagMatch(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func etagExcluded(config ETagConfig, path string) bool {
+	for _, pattern := range config.ExcludePaths {
+		if matchPath(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
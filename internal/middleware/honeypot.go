@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"log"
+
+	"new-openclaw/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HoneypotHit 访问诱饵路径的请求
+type HoneypotHit struct {
+	IP        string
+	Method    string
+	Path      string
+	UserAgent string
+	RequestID string
+	// 命中的诱饵路径配置
+	Pattern string
+	// 记录本次访问后该 IP 的滥用评分
+	Score int64
+}
+
+// HoneypotConfig 诱饵路径配置
+type HoneypotConfig struct {
+	// 正常客户端不会访问的路径（支持末尾 * 通配），如 /wp-login.php、/.env*、/phpmyadmin*
+	Paths []string
+	// 命中后的处理（如写入安全事件），为空不处理
+	Record func(ctx context.Context, hit HoneypotHit)
+	// 立即临时封禁来源 IP，为空只计入滥用评分
+	Ban func(ctx context.Context, ip string, score int64)
+}
+
+var honeypotHitsTotal = metrics.NewCounterVec(
+	"honeypot_hits_total", "访问诱饵路径的请求数", "path")
+
+// Honeypot 诱饵路径中间件：扫描器常探测的路径（WordPress 登录页、.env、phpMyAdmin 等）正常客户端从不访问，
+// 命中即可认定为恶意扫描，记录事件并立即封禁来源 IP，不必等滥用评分累计到阈值。
+// 响应与未知路由相同的 404，不暴露诱饵的存在；应在 IP 过滤之后注册，已封禁的 IP 不会重复处理
+func Honeypot(config HoneypotConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pattern, ok := honeypotPattern(config.Paths, c.Request.URL.Path)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		ip := AbuseIP(c)
+		honeypotHitsTotal.Inc(pattern)
+		score := RecordAbuse(ctx, ip, AbuseHoneypot)
+		log.Printf("[SECURITY ALERT] 诱饵路径命中: ip=%s method=%s path=%s", ip, c.Request.Method, c.Request.URL.Path)
+
+		if config.Record != nil {
+			config.Record(ctx, HoneypotHit{
+				IP:        ip,
+				Method:    c.Request.Method,
+				Path:      c.Request.URL.Path,
+				UserAgent: c.Request.UserAgent(),
+				RequestID: c.GetString("request_id"),
+				Pattern:   pattern,
+				Score:     score,
+			})
+		}
+		if config.Ban != nil {
+			config.Ban(ctx, ip, score)
+		}
+
+		NotFoundWithConfig(NotFoundConfig{})(c)
+		c.Abort()
+	}
+}
+
+// HoneypotConflicts 返回与诱饵路径冲突的已注册路由（诱饵会拦截这些路由上的正常请求）
+func HoneypotConflicts(paths []string, routes gin.RoutesInfo) []string {
+	var conflicts []string
+	for _, route := range routes {
+		if _, ok := honeypotPattern(paths, route.Path); ok {
+			conflicts = append(conflicts, route.Method+" "+route.Path)
+		}
+	}
+	return conflicts
+}
+
+func honeypotPattern(paths []string, path string) (string, bool) {
+	for _, pattern := range paths {
+		if matchPath(pattern, path) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
@@ -3,8 +3,11 @@ package middleware
 import (
 	"net"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,20 +24,25 @@ type IPFilterConfig struct {
 	AllowPrivate bool
 	// 是否信任代理头
 	TrustProxy bool
-	// 代理头名称
+	// 受信任的代理（IP 或 CIDR）：只有直连地址在其中时才采信代理头，为空时不采信任何代理头
+	TrustedProxies []string
+	// 代理头名称（X-Forwarded-For 缺失时使用）
 	ProxyHeader string
+	// ASN 查询：名单中 AS 开头的条目（如 AS16509）按 IP 所属的自治系统匹配，为空时这些条目不生效
+	ASNLookup func(ip net.IP) (uint32, bool)
 	// 被阻止时的响应
 	BlockHandler gin.HandlerFunc
 }
 
 // DefaultIPFilterConfig 默认 IP 过滤配置
 var DefaultIPFilterConfig = IPFilterConfig{
-	WhitelistMode: false,
-	Whitelist:     []string{},
-	Blacklist:     []string{},
-	AllowPrivate:  true,
-	TrustProxy:    true,
-	ProxyHeader:   "X-Real-IP",
+	WhitelistMode:  false,
+	Whitelist:      []string{},
+	Blacklist:      []string{},
+	AllowPrivate:   true,
+	TrustProxy:     true,
+	TrustedProxies: []string{"127.0.0.1", "::1"},
+	ProxyHeader:    "X-Real-IP",
 	BlockHandler: func(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{
 			"code":    403,
@@ -46,12 +54,20 @@ var DefaultIPFilterConfig = IPFilterConfig{
 
 // IPFilter IP 过滤器
 type IPFilter struct {
-	config     IPFilterConfig
-	whitelist  map[string]bool
-	blacklist  map[string]bool
-	whiteNets  []*net.IPNet
-	blackNets  []*net.IPNet
-	mu         sync.RWMutex
+	config    IPFilterConfig
+	whitelist map[string]bool
+	blacklist map[string]bool
+	whiteNets []*net.IPNet
+	blackNets []*net.IPNet
+	proxyNets []*net.IPNet
+	whiteASNs map[uint32]bool
+	blackASNs map[uint32]bool
+	// 威胁情报名单（只读，由 DynamicIPFilter.SetReputation 整体替换）
+	feedIPs  map[string]bool
+	feedNets []*net.IPNet
+	// 名单只用于标记客户端信誉，不拦截
+	feedAdvisory bool
+	mu           sync.RWMutex
 }
 
 // NewIPFilter 创建 IP 过滤器
@@ -60,29 +76,36 @@ func NewIPFilter(config IPFilterConfig) *IPFilter {
 		config:    config,
 		whitelist: make(map[string]bool),
 		blacklist: make(map[string]bool),
+		proxyNets: parseNets(config.TrustedProxies),
+		whiteASNs: make(map[uint32]bool),
+		blackASNs: make(map[uint32]bool),
 	}
 
 	// 解析白名单
 	for _, ip := range config.Whitelist {
-		if strings.Contains(ip, "/") {
+		if asn, ok := ParseASN(ip); ok {
+			filter.whiteASNs[asn] = true
+		} else if strings.Contains(ip, "/") {
 			_, ipNet, err := net.ParseCIDR(ip)
 			if err == nil {
 				filter.whiteNets = append(filter.whiteNets, ipNet)
 			}
-		} else {
-			filter.whitelist[ip] = true
+		} else if normalized := NormalizeIP(ip); normalized != "" {
+			filter.whitelist[normalized] = true
 		}
 	}
 
 	// 解析黑名单
 	for _, ip := range config.Blacklist {
-		if strings.Contains(ip, "/") {
+		if asn, ok := ParseASN(ip); ok {
+			filter.blackASNs[asn] = true
+		} else if strings.Contains(ip, "/") {
 			_, ipNet, err := net.ParseCIDR(ip)
 			if err == nil {
 				filter.blackNets = append(filter.blackNets, ipNet)
 			}
-		} else {
-			filter.blacklist[ip] = true
+		} else if normalized := NormalizeIP(ip); normalized != "" {
+			filter.blacklist[normalized] = true
 		}
 	}
 
@@ -94,6 +117,7 @@ func (f *IPFilter) IsAllowed(ip string) bool {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
+	ip = NormalizeIP(ip)
 	parsedIP := net.ParseIP(ip)
 	if parsedIP == nil {
 		return false
@@ -114,7 +138,7 @@ func (f *IPFilter) IsAllowed(ip string) bool {
 		if f.config.AllowPrivate && isPrivateIP(parsedIP) {
 			return true
 		}
-		return false
+		return f.matchASN(parsedIP, f.whiteASNs)
 	}
 
 	// 黑名单模式
@@ -126,22 +150,68 @@ func (f *IPFilter) IsAllowed(ip string) bool {
 			return false
 		}
 	}
+	// 按 ASN 封禁整个服务商时，白名单中的 IP/网段作为例外放行
+	if f.matchASN(parsedIP, f.blackASNs) && !f.whitelist[ip] && !containsNet(f.whiteNets, parsedIP) {
+		return false
+	}
+	if !f.feedAdvisory && f.feedListed(ip, parsedIP) {
+		return false
+	}
 
 	return true
 }
 
+// feedListed 检查 IP 是否命中威胁情报名单（调用方持有锁）。
+// 名单来自第三方，可能误报或包含保留网段：私有地址与白名单（含 ASN）不算命中
+func (f *IPFilter) feedListed(ip string, parsedIP net.IP) bool {
+	return (f.feedIPs[ip] || containsNet(f.feedNets, parsedIP)) && !isPrivateIP(parsedIP) &&
+		!f.whitelist[ip] && !containsNet(f.whiteNets, parsedIP) && !f.matchASN(parsedIP, f.whiteASNs)
+}
+
+// matchASN 检查 IP 所属的 ASN 是否在集合中（调用方持有锁）
+func (f *IPFilter) matchASN(ip net.IP, asns map[uint32]bool) bool {
+	if len(asns) == 0 || f.config.ASNLookup == nil {
+		return false
+	}
+	asn, ok := f.config.ASNLookup(ip)
+	return ok && asns[asn]
+}
+
+// inWhitelist 检查 IP 是否在白名单中
+func (f *IPFilter) inWhitelist(ip string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	ip = NormalizeIP(ip)
+	if f.whitelist[ip] {
+		return true
+	}
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return false
+	}
+	for _, ipNet := range f.whiteNets {
+		if ipNet.Contains(parsedIP) {
+			return true
+		}
+	}
+	return f.matchASN(parsedIP, f.whiteASNs)
+}
+
 // AddToWhitelist 添加到白名单
 func (f *IPFilter) AddToWhitelist(ip string) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	if strings.Contains(ip, "/") {
+	if asn, ok := ParseASN(ip); ok {
+		f.whiteASNs[asn] = true
+	} else if strings.Contains(ip, "/") {
 		_, ipNet, err := net.ParseCIDR(ip)
 		if err == nil {
 			f.whiteNets = append(f.whiteNets, ipNet)
 		}
-	} else {
-		f.whitelist[ip] = true
+	} else if normalized := NormalizeIP(ip); normalized != "" {
+		f.whitelist[normalized] = true
 	}
 }
 
@@ -150,13 +220,15 @@ func (f *IPFilter) AddToBlacklist(ip string) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	if strings.Contains(ip, "/") {
+	if asn, ok := ParseASN(ip); ok {
+		f.blackASNs[asn] = true
+	} else if strings.Contains(ip, "/") {
 		_, ipNet, err := net.ParseCIDR(ip)
 		if err == nil {
 			f.blackNets = append(f.blackNets, ipNet)
 		}
-	} else {
-		f.blacklist[ip] = true
+	} else if normalized := NormalizeIP(ip); normalized != "" {
+		f.blacklist[normalized] = true
 	}
 }
 
@@ -164,14 +236,22 @@ func (f *IPFilter) AddToBlacklist(ip string) {
 func (f *IPFilter) RemoveFromWhitelist(ip string) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	delete(f.whitelist, ip)
+	if asn, ok := ParseASN(ip); ok {
+		delete(f.whiteASNs, asn)
+		return
+	}
+	delete(f.whitelist, NormalizeIP(ip))
 }
 
 // RemoveFromBlacklist 从黑名单移除
 func (f *IPFilter) RemoveFromBlacklist(ip string) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	delete(f.blacklist, ip)
+	if asn, ok := ParseASN(ip); ok {
+		delete(f.blackASNs, asn)
+		return
+	}
+	delete(f.blacklist, NormalizeIP(ip))
 }
 
 // isPrivateIP 检查是否为私有 IP
@@ -195,18 +275,83 @@ func isPrivateIP(ip net.IP) bool {
 	return false
 }
 
-// getClientIP 获取客户端真实 IP
-func getClientIP(c *gin.Context, config IPFilterConfig) string {
-	if config.TrustProxy {
-		// 尝试从代理头获取
-		if ip := c.GetHeader(config.ProxyHeader); ip != "" {
-			return strings.TrimSpace(strings.Split(ip, ",")[0])
+// NormalizeIP 规范化 IP 地址：去除端口、方括号、IPv6 区域标识（如 %eth0），
+// IPv4 映射的 IPv6 地址转为 IPv4，IPv6 使用压缩格式；无效时返回空字符串
+func NormalizeIP(raw string) string {
+	s := strings.Trim(strings.TrimSpace(raw), `"`)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	if i := strings.IndexByte(s, '%'); i >= 0 {
+		s = s[:i]
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+// getClientIP 获取客户端真实 IP：直连地址不是受信任的代理时直接使用直连地址（忽略可伪造的代理头）；
+// 否则从右向左遍历 X-Forwarded-For，跳过受信任的代理，第一个不受信任的地址即为客户端
+func getClientIP(c *gin.Context, f *IPFilter) string {
+	remote := NormalizeIP(c.Request.RemoteAddr)
+	if !f.config.TrustProxy || remote == "" || !containsIP(f.proxyNets, remote) {
+		return remote
+	}
+
+	if values := c.Request.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		hops := strings.Split(strings.Join(values, ","), ",")
+		client := remote
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := NormalizeIP(hops[i])
+			if ip == "" {
+				// 格式错误的条目及其左侧的内容不可信，以最后一个受信任代理看到的地址为准
+				break
+			}
+			client = ip
+			if !containsIP(f.proxyNets, ip) {
+				break
+			}
+		}
+		return client
+	}
+	if f.config.ProxyHeader != "" {
+		if ip := NormalizeIP(c.GetHeader(f.config.ProxyHeader)); ip != "" {
+			return ip
 		}
-		if ip := c.GetHeader("X-Forwarded-For"); ip != "" {
-			return strings.TrimSpace(strings.Split(ip, ",")[0])
+	}
+	return remote
+}
+
+// containsIP 检查 IP 是否在网段列表中
+func containsIP(nets []*net.IPNet, ip string) bool {
+	parsed := net.ParseIP(ip)
+	return parsed != nil && containsNet(nets, parsed)
+}
+
+// containsNet 检查已解析的 IP 是否在网段列表中
+func containsNet(nets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
 		}
 	}
-	return c.ClientIP()
+	return false
+}
+
+// ParseASN 解析名单中的 ASN 条目（AS16509、as16509，不区分大小写）
+func ParseASN(entry string) (uint32, bool) {
+	entry = strings.TrimSpace(entry)
+	if len(entry) < 3 || !strings.EqualFold(entry[:2], "AS") {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(entry[2:], 10, 32)
+	if err != nil || n == 0 {
+		return 0, false
+	}
+	return uint32(n), true
 }
 
 // IPFilterMiddleware IP 过滤中间件
@@ -219,7 +364,7 @@ func IPFilterWithConfig(config IPFilterConfig) gin.HandlerFunc {
 	filter := NewIPFilter(config)
 
 	return func(c *gin.Context) {
-		ip := getClientIP(c, config)
+		ip := getClientIP(c, filter)
 
 		if !filter.IsAllowed(ip) {
 			config.BlockHandler(c)
@@ -235,12 +380,13 @@ func IPFilterWithConfig(config IPFilterConfig) gin.HandlerFunc {
 // IPWhitelist 白名单中间件
 func IPWhitelist(ips ...string) gin.HandlerFunc {
 	config := IPFilterConfig{
-		WhitelistMode: true,
-		Whitelist:     ips,
-		AllowPrivate:  true,
-		TrustProxy:    true,
-		ProxyHeader:   "X-Real-IP",
-		BlockHandler:  DefaultIPFilterConfig.BlockHandler,
+		WhitelistMode:  true,
+		Whitelist:      ips,
+		AllowPrivate:   true,
+		TrustProxy:     true,
+		TrustedProxies: DefaultIPFilterConfig.TrustedProxies,
+		ProxyHeader:    "X-Real-IP",
+		BlockHandler:   DefaultIPFilterConfig.BlockHandler,
 	}
 
 	return IPFilterWithConfig(config)
@@ -249,12 +395,13 @@ func IPWhitelist(ips ...string) gin.HandlerFunc {
 // IPBlacklist 黑名单中间件
 func IPBlacklist(ips ...string) gin.HandlerFunc {
 	config := IPFilterConfig{
-		WhitelistMode: false,
-		Blacklist:     ips,
-		AllowPrivate:  true,
-		TrustProxy:    true,
-		ProxyHeader:   "X-Real-IP",
-		BlockHandler:  DefaultIPFilterConfig.BlockHandler,
+		WhitelistMode:  false,
+		Blacklist:      ips,
+		AllowPrivate:   true,
+		TrustProxy:     true,
+		TrustedProxies: DefaultIPFilterConfig.TrustedProxies,
+		ProxyHeader:    "X-Real-IP",
+		BlockHandler:   DefaultIPFilterConfig.BlockHandler,
 	}
 
 	return IPFilterWithConfig(config)
@@ -263,22 +410,193 @@ func IPBlacklist(ips ...string) gin.HandlerFunc {
 // DynamicIPFilter 动态 IP 过滤器（支持运行时修改）
 type DynamicIPFilter struct {
 	filter *IPFilter
+	config IPFilterConfig
+	// 持久化的规则（与配置中的名单合并生效，Reload 时保留）
+	whitelist []string
+	blacklist []string
+	// 临时封禁的 IP 及解封时间（白名单模式下同样生效）
+	bans map[string]time.Time
+	// 威胁情报名单（黑名单模式下生效）
+	feedIPs      map[string]bool
+	feedNets     []*net.IPNet
+	feedAdvisory bool
+	mu           sync.RWMutex
 }
 
 // NewDynamicIPFilter 创建动态 IP 过滤器
 func NewDynamicIPFilter(config IPFilterConfig) *DynamicIPFilter {
 	return &DynamicIPFilter{
 		filter: NewIPFilter(config),
+		config: config,
 	}
 }
 
+// current 获取当前过滤器
+func (d *DynamicIPFilter) current() *IPFilter {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.filter
+}
+
+// Config 获取当前配置（不含持久化的规则）
+func (d *DynamicIPFilter) Config() IPFilterConfig {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.config
+}
+
+// Reload 整体替换配置中的过滤规则（运行时通过 Add/Remove 修改的条目会被覆盖，持久化的规则保留）
+func (d *DynamicIPFilter) Reload(config IPFilterConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.config = config
+	d.rebuild()
+}
+
+// SetRules 整体替换持久化的规则（与配置中的名单合并生效）
+func (d *DynamicIPFilter) SetRules(whitelist, blacklist []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.whitelist = whitelist
+	d.blacklist = blacklist
+	d.rebuild()
+}
+
+// SetReputation 整体替换威胁情报名单（IP 或 CIDR，无效条目忽略），返回生效的条目数；
+// 解析在加锁前完成，替换时只重建过滤器，不影响正在处理的请求
+func (d *DynamicIPFilter) SetReputation(entries []string) int {
+	ips := make(map[string]bool, len(entries))
+	var nets []*net.IPNet
+	for _, entry := range entries {
+		if strings.Contains(entry, "/") {
+			if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+				nets = append(nets, ipNet)
+			}
+		} else if normalized := NormalizeIP(entry); normalized != "" {
+			ips[normalized] = true
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.feedIPs = ips
+	d.feedNets = nets
+	d.rebuild()
+	return len(ips) + len(nets)
+}
+
+// SetReputationAdvisory 设置威胁情报名单是否只作为信誉标记（不拦截，由 ReputationListed 供处置策略判断）
+func (d *DynamicIPFilter) SetReputationAdvisory(advisory bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.feedAdvisory = advisory
+	d.rebuild()
+}
+
+// ReputationListed 检查 IP 是否命中威胁情报名单（私有地址与白名单除外）
+func (d *DynamicIPFilter) ReputationListed(ip string) bool {
+	filter := d.current()
+	filter.mu.RLock()
+	defer filter.mu.RUnlock()
+
+	ip = NormalizeIP(ip)
+	parsedIP := net.ParseIP(ip)
+	return parsedIP != nil && filter.feedListed(ip, parsedIP)
+}
+
+// SetBans 整体替换临时封禁的 IP
+func (d *DynamicIPFilter) SetBans(bans map[string]time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.bans = bans
+}
+
+// Ban 临时封禁 IP 到指定时间
+func (d *DynamicIPFilter) Ban(ip string, until time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.bans == nil {
+		d.bans = make(map[string]time.Time)
+	}
+	d.bans[NormalizeIP(ip)] = until
+}
+
+// Banned 检查 IP 是否处于临时封禁中
+func (d *DynamicIPFilter) Banned(ip string) bool {
+	d.mu.RLock()
+	until, ok := d.bans[NormalizeIP(ip)]
+	d.mu.RUnlock()
+	return ok && time.Now().Before(until)
+}
+
+// Whitelisted 检查 IP 是否在白名单中（配置或持久化的规则）
+func (d *DynamicIPFilter) Whitelisted(ip string) bool {
+	return d.current().inWhitelist(ip)
+}
+
+// IPFilterState IP 过滤器当前生效的状态
+type IPFilterState struct {
+	WhitelistMode bool `json:"whitelist_mode"`
+	// 配置与持久化的规则合并后的名单
+	Whitelist []string `json:"whitelist"`
+	Blacklist []string `json:"blacklist"`
+	// 生效中的临时封禁（按 IP 排序）
+	Bans []IPBanState `json:"bans"`
+	// 威胁情报名单的条目数及是否只作为信誉标记
+	ReputationEntries  int  `json:"reputation_entries"`
+	ReputationAdvisory bool `json:"reputation_advisory"`
+}
+
+// IPBanState 生效中的临时封禁
+type IPBanState struct {
+	IP    string    `json:"ip"`
+	Until time.Time `json:"until"`
+}
+
+// State 获取当前生效的名单与临时封禁（已过期的封禁不返回）
+func (d *DynamicIPFilter) State() IPFilterState {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	state := IPFilterState{
+		WhitelistMode:      d.config.WhitelistMode,
+		Whitelist:          append(append([]string{}, d.config.Whitelist...), d.whitelist...),
+		Blacklist:          append(append([]string{}, d.config.Blacklist...), d.blacklist...),
+		Bans:               []IPBanState{},
+		ReputationEntries:  len(d.feedIPs) + len(d.feedNets),
+		ReputationAdvisory: d.feedAdvisory,
+	}
+	now := time.Now()
+	for ip, until := range d.bans {
+		if now.Before(until) {
+			state.Bans = append(state.Bans, IPBanState{IP: ip, Until: until})
+		}
+	}
+	sort.Slice(state.Bans, func(i, j int) bool { return state.Bans[i].IP < state.Bans[j].IP })
+	return state
+}
+
+// rebuild 合并配置与持久化的规则，重建过滤器（调用方持有锁）
+func (d *DynamicIPFilter) rebuild() {
+	merged := d.config
+	merged.Whitelist = append(append([]string{}, d.config.Whitelist...), d.whitelist...)
+	merged.Blacklist = append(append([]string{}, d.config.Blacklist...), d.blacklist...)
+	filter := NewIPFilter(merged)
+	filter.config = d.config
+	filter.feedIPs = d.feedIPs
+	filter.feedNets = d.feedNets
+	filter.feedAdvisory = d.feedAdvisory
+	d.filter = filter
+}
+
 // Middleware 返回中间件
 func (d *DynamicIPFilter) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ip := getClientIP(c, d.filter.config)
+		filter := d.current()
+		ip := getClientIP(c, filter)
 
-		if !d.filter.IsAllowed(ip) {
-			d.filter.config.BlockHandler(c)
+		if d.Banned(ip) || !filter.IsAllowed(ip) {
+			filter.config.BlockHandler(c)
 			return
 		}
 
@@ -289,22 +607,22 @@ func (d *DynamicIPFilter) Middleware() gin.HandlerFunc {
 
 // AddWhitelist 添加白名单
 func (d *DynamicIPFilter) AddWhitelist(ip string) {
-	d.filter.AddToWhitelist(ip)
+	d.current().AddToWhitelist(ip)
 }
 
 // AddBlacklist 添加黑名单
 func (d *DynamicIPFilter) AddBlacklist(ip string) {
-	d.filter.AddToBlacklist(ip)
+	d.current().AddToBlacklist(ip)
 }
 
 // RemoveWhitelist 移除白名单
 func (d *DynamicIPFilter) RemoveWhitelist(ip string) {
-	d.filter.RemoveFromWhitelist(ip)
+	d.current().RemoveFromWhitelist(ip)
 }
 
 // RemoveBlacklist 移除黑名单
 func (d *DynamicIPFilter) RemoveBlacklist(ip string) {
-	d.filter.RemoveFromBlacklist(ip)
+	d.current().RemoveFromBlacklist(ip)
 }
 
 // CountryFilter 国家/地区过滤（需要 GeoIP 数据库支持）
@@ -0,0 +1,266 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// JSONSchema JSON Schema 的常用子集：type、properties、required、additionalProperties、items、enum、
+// minLength/maxLength、minimum/maximum、minItems/maxItems、pattern、format（email、date-time、ip）
+type JSONSchema struct {
+	Type                 string                 `json:"type,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// SchemaError 校验失败的字段
+type SchemaError struct {
+	// 字段路径，如 items[0].name（根为空）
+	Field string `json:"field"`
+	// 未通过的规则，如 required、type、maxLength
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+var schemaTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "integer": true, "number": true, "boolean": true, "null": true,
+}
+
+var schemaFormats = map[string]bool{"email": true, "date-time": true, "ip": true}
+
+// Compile 检查类型与格式名称并编译 pattern（递归处理子 Schema），加载 Schema 后调用一次
+func (s *JSONSchema) Compile() error {
+	if s.Type != "" && !schemaTypes[s.Type] {
+		return fmt.Errorf("未知的类型 %q", s.Type)
+	}
+	if s.Format != "" && !schemaFormats[s.Format] {
+		return fmt.Errorf("未知的格式 %q", s.Format)
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("无效的 pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	for name, property := range s.Properties {
+		if property == nil {
+			return fmt.Errorf("%s: Schema 为空", name)
+		}
+		if err := property.Compile(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	if s.Items != nil {
+		if err := s.Items.Compile(); err != nil {
+			return fmt.Errorf("items: %w", err)
+		}
+	}
+	return nil
+}
+
+// Validate 校验已解码的 JSON 值（数字须以 json.Number 解码），最多返回 max 个错误（<= 0 不限制）
+func (s *JSONSchema) Validate(value interface{}, max int) []SchemaError {
+	v := &schemaValidator{max: max}
+	v.validate(s, value, "")
+	return v.errors
+}
+
+type schemaValidator struct {
+	max    int
+	errors []SchemaError
+}
+
+func (v *schemaValidator) full() bool {
+	return v.max > 0 && len(v.errors) >= v.max
+}
+
+func (v *schemaValidator) fail(field, rule, format string, args ...interface{}) {
+	if v.full() {
+		return
+	}
+	v.errors = append(v.errors, SchemaError{Field: field, Rule: rule, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *schemaValidator) validate(s *JSONSchema, value interface{}, field string) {
+	if v.full() {
+		return
+	}
+	if s.Type != "" && !schemaTypeMatches(s.Type, value) {
+		v.fail(field, "type", "应为 %s 类型", s.Type)
+		return
+	}
+	if len(s.Enum) > 0 && !schemaEnumContains(s.Enum, value) {
+		v.fail(field, "enum", "取值应为 %s 之一", schemaEnumList(s.Enum))
+	}
+
+	switch x := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := x[name]; !ok {
+				v.fail(schemaField(field, name), "required", "缺少必填字段")
+			}
+		}
+		names := make([]string, 0, len(x))
+		for name := range x {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			item := x[name]
+			if property, ok := s.Properties[name]; ok {
+				v.validate(property, item, schemaField(field, name))
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				v.fail(schemaField(field, name), "additionalProperties", "不允许的字段")
+			}
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(x) < *s.MinItems {
+			v.fail(field, "minItems", "元素数不能少于 %d", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(x) > *s.MaxItems {
+			v.fail(field, "maxItems", "元素数不能超过 %d", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range x {
+				v.validate(s.Items, item, field+"["+strconv.Itoa(i)+"]")
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(x)
+		if s.MinLength != nil && length < *s.MinLength {
+			v.fail(field, "minLength", "长度不能少于 %d", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			v.fail(field, "maxLength", "长度不能超过 %d", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(x) {
+			v.fail(field, "pattern", "格式不符合 %s", s.Pattern)
+		}
+		if s.Format != "" && !schemaFormatMatches(s.Format, x) {
+			v.fail(field, "format", "应为 %s 格式", s.Format)
+		}
+	case json.Number:
+		n, _ := x.Float64()
+		if s.Minimum != nil && n < *s.Minimum {
+			v.fail(field, "minimum", "不能小于 %v", *s.Minimum)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			v.fail(field, "maximum", "不能大于 %v", *s.Maximum)
+		}
+	}
+}
+
+func schemaTypeMatches(typ string, value interface{}) bool {
+	switch x := value.(type) {
+	case map[string]interface{}:
+		return typ == "object"
+	case []interface{}:
+		return typ == "array"
+	case string:
+		return typ == "string"
+	case bool:
+		return typ == "boolean"
+	case nil:
+		return typ == "null"
+	case json.Number:
+		if typ == "number" {
+			return true
+		}
+		if typ != "integer" {
+			return false
+		}
+		if _, err := x.Int64(); err == nil {
+			return true
+		}
+		f, err := x.Float64()
+		return err == nil && f == math.Trunc(f)
+	}
+	return false
+}
+
+func schemaEnumContains(enum []interface{}, value interface{}) bool {
+	for _, candidate := range enum {
+		if schemaEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaEqual 比较枚举值（数字按数值比较）
+func schemaEqual(a, b interface{}) bool {
+	an, aNum := schemaNumber(a)
+	bn, bNum := schemaNumber(b)
+	if aNum || bNum {
+		return aNum && bNum && an == bn
+	}
+	switch a.(type) {
+	case string, bool, nil:
+		return a == b
+	}
+	return false
+}
+
+func schemaNumber(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case json.Number:
+		f, err := x.Float64()
+		return f, err == nil
+	case float64:
+		return x, true
+	}
+	return 0, false
+}
+
+func schemaEnumList(enum []interface{}) string {
+	values := make([]string, len(enum))
+	for i, value := range enum {
+		b, _ := json.Marshal(value)
+		values[i] = string(b)
+	}
+	return strings.Join(values, ", ")
+}
+
+func schemaFormatMatches(format, s string) bool {
+	switch format {
+	case "email":
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "ip":
+		return net.ParseIP(s) != nil
+	}
+	return true
+}
+
+func schemaField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
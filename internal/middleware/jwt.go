@@ -4,21 +4,22 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"new-openclaw/pkg/auth/token"
+
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// JWTConfig JWT 配置
-type JWTConfig struct {
-	SecretKey     string
-	TokenExpiry   time.Duration
-	RefreshExpiry time.Duration
-	Issuer        string
-}
+// JWTConfig JWT 配置（与管理后台共用 pkg/auth/token 的签发、校验逻辑）
+type JWTConfig = token.Config
 
-// DefaultJWTConfig 默认 JWT 配置
+// DefaultJWTConfig 默认 JWT 配置（未调用 ConfigureJWT 时使用）
+//
+// Deprecated: 不要在启动后直接修改该变量——请求处理与后台任务可能正在读取它（数据竞争），
+// 且已注册的中间件不会看到修改。使用 ConfigureJWT 设置全局配置，或以 NewJWTAuth 为路由单独指定配置
 var DefaultJWTConfig = JWTConfig{
 	SecretKey:     "your-secret-key-change-in-production",
 	TokenExpiry:   time.Hour * 24,
@@ -26,49 +27,115 @@ var DefaultJWTConfig = JWTConfig{
 	Issuer:        "new-openclaw",
 }
 
+// jwtConfig ConfigureJWT 设置的配置快照
+var jwtConfig atomic.Pointer[JWTConfig]
+
+// ConfigureJWT 设置全局 JWT 配置（保存副本，可与正在处理的请求并发调用）。
+// 之后创建的 JWTAuth、OptionalJWTAuth 及签发、解析 Token 的接口使用新配置，已创建的中间件不变
+func ConfigureJWT(config JWTConfig) {
+	jwtConfig.Store(&config)
+}
+
+// CurrentJWTConfig 当前生效的全局 JWT 配置（返回副本）
+func CurrentJWTConfig() JWTConfig {
+	if config := jwtConfig.Load(); config != nil {
+		return *config
+	}
+	return DefaultJWTConfig
+}
+
 // Claims 自定义 JWT Claims
 type Claims struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	// 细粒度权限范围，如 users:read、users:write（为空时按角色取 RoleScopes）
+	Scopes []string `json:"scopes,omitempty"`
+	// 模拟登录：发起模拟的超级管理员（为空表示用户本人登录）
+	Impersonator *token.Actor `json:"act,omitempty"`
+	// Token 类型（访问 Token 为空，刷新 Token 为 refresh）
+	Type string `json:"typ,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// JWTAuth JWT 认证中间件
+// Registered 返回标准声明
+func (c *Claims) Registered() jwt.RegisteredClaims {
+	return c.RegisteredClaims
+}
+
+// TokenTypeRefresh 刷新 Token 的 typ 声明
+const TokenTypeRefresh = "refresh"
+
+// ErrRefreshTokenAsAccess 以刷新 Token 访问接口
+var ErrRefreshTokenAsAccess = errors.New("刷新令牌不能用于访问接口")
+
+// RefreshClaims 刷新 Token 声明：标准声明、类型与令牌族（同一次登录轮换出的刷新 Token 属于同一族）
+type RefreshClaims struct {
+	Type   string `json:"typ"`
+	Family string `json:"fam,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Registered 返回标准声明
+func (c *RefreshClaims) Registered() jwt.RegisteredClaims {
+	return c.RegisteredClaims
+}
+
+// RoleScopes 各角色默认的权限范围（Token 未携带 scopes 时使用）
+var RoleScopes = map[string][]string{
+	"admin": {"*"},
+	"user":  {"users:read", "profile:read", "profile:write"},
+}
+
+// UnverifiedScopes 邮箱未验证的注册用户登录后的权限范围（只能查看自己的信息，验证邮箱后重新登录获得完整权限）
+var UnverifiedScopes = []string{"profile:read"}
+
+// JWTAuth JWT 认证中间件（使用创建时的全局配置）
 func JWTAuth() gin.HandlerFunc {
-	return JWTAuthWithConfig(DefaultJWTConfig)
+	return NewJWTAuth(CurrentJWTConfig())
+}
+
+// NewJWTAuth 创建 JWT 认证中间件，绑定传入配置的快照，不再读取包级变量
+func NewJWTAuth(config JWTConfig) gin.HandlerFunc {
+	return JWTAuthWithConfig(config)
 }
 
 // JWTAuthWithConfig 带配置的 JWT 认证中间件
 func JWTAuthWithConfig(config JWTConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 从 Header 获取 Token
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		// 从 Header 获取 Token，未携带时从 Cookie 读取
+		tokenString, err := tokenFromRequest(c, config)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
-				"message": "缺少认证令牌",
+				"message": err.Error(),
 			})
 			c.Abort()
 			return
 		}
 
-		// 检查 Bearer 前缀
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "认证令牌格式错误",
-			})
-			c.Abort()
+		// 个人访问令牌（pat_ 前缀）按摘要查库校验
+		if isPersonalToken(tokenString) {
+			identity, claims, err := parsePersonalToken(c, tokenString, config)
+			if err != nil {
+				ReportAuthFailure(c, AuthFailurePersonalToken, personalTokenDisplay(tokenString), err.Error())
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"code":    401,
+					"message": err.Error(),
+				})
+				c.Abort()
+				return
+			}
+			setClaims(c, claims)
+			setPersonalToken(c, identity)
+			c.Next()
 			return
 		}
 
-		tokenString := parts[1]
-
 		// 解析 Token
-		claims, err := ParseToken(tokenString, config.SecretKey)
+		claims, err := ParseTokenWithConfig(tokenString, config)
 		if err != nil {
+			ReportAuthFailure(c, AuthFailureToken, "", err.Error())
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
 				"message": "无效的认证令牌: " + err.Error(),
@@ -78,95 +145,162 @@ func JWTAuthWithConfig(config JWTConfig) gin.HandlerFunc {
 		}
 
 		// 将用户信息存入上下文
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Set("role", claims.Role)
-		c.Set("claims", claims)
+		setClaims(c, claims)
 
 		c.Next()
 	}
 }
 
-// GenerateToken 生成 JWT Token
-func GenerateToken(userID, username, role string, config JWTConfig) (string, error) {
-	now := time.Now()
-	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(config.TokenExpiry)),
-			IssuedAt:  jwt.NewNumericDate(now),
-			NotBefore: jwt.NewNumericDate(now),
-			Issuer:    config.Issuer,
-		},
+// setClaims 将 Token 中的用户信息存入上下文
+func setClaims(c *gin.Context, claims *Claims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Set("role", claims.Role)
+	c.Set("scopes", claims.GrantedScopes())
+	c.Set("claims", claims)
+	if claims.Impersonator != nil {
+		c.Set(token.ActorContextKey, claims.Impersonator)
 	}
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.SecretKey))
+// tokenFromRequest 从 Authorization 头（Bearer）或 Token Cookie 中获取 Token
+func tokenFromRequest(c *gin.Context, config JWTConfig) (string, error) {
+	return config.FromRequest(c.Request)
 }
 
-// GenerateRefreshToken 生成刷新 Token
-func GenerateRefreshToken(userID string, config JWTConfig) (string, error) {
-	now := time.Now()
-	claims := jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(now.Add(config.RefreshExpiry)),
-		IssuedAt:  jwt.NewNumericDate(now),
-		Subject:   userID,
-		Issuer:    config.Issuer,
+// SetTokenCookie 将 Token 写入 HttpOnly Cookie（未启用 Cookie 下发时不处理）
+func SetTokenCookie(c *gin.Context, token string, expiresAt time.Time, config JWTConfig) {
+	if config.Cookie.Enabled() {
+		http.SetCookie(c.Writer, config.Cookie.New(token, expiresAt))
 	}
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.SecretKey))
+// GrantedScopes 返回 Token 授予的权限范围（未携带时按角色取默认值）
+func (c *Claims) GrantedScopes() []string {
+	if len(c.Scopes) > 0 {
+		return c.Scopes
+	}
+	return RoleScopes[c.Role]
 }
 
-// ParseToken 解析 JWT Token
-func ParseToken(tokenString, secretKey string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("无效的签名方法")
-		}
-		return []byte(secretKey), nil
+// GenerateToken 生成 JWT Token（权限范围取角色默认值）
+func GenerateToken(userID, username, role string, config JWTConfig) (string, error) {
+	return GenerateTokenWithScopes(userID, username, role, nil, config)
+}
+
+// GenerateTokenWithScopes 生成携带权限范围的 JWT Token
+func GenerateTokenWithScopes(userID, username, role string, scopes []string, config JWTConfig) (string, error) {
+	return config.Sign(Claims{
+		UserID:           userID,
+		Username:         username,
+		Role:             role,
+		Scopes:           scopes,
+		RegisteredClaims: config.NewRegisteredClaims(userID, config.TokenExpiry),
 	})
+}
 
+// GenerateImpersonationToken 生成模拟登录 Token：以用户身份签发，有效期为 ttl，携带发起模拟的管理员（不签发刷新 Token）
+func GenerateImpersonationToken(userID, username, role string, scopes []string, actor *token.Actor, ttl time.Duration, config JWTConfig) (string, jwt.RegisteredClaims, error) {
+	claims := Claims{
+		UserID:           userID,
+		Username:         username,
+		Role:             role,
+		Scopes:           scopes,
+		Impersonator:     actor,
+		RegisteredClaims: config.NewRegisteredClaims(userID, ttl),
+	}
+	tokenString, err := config.Sign(claims)
 	if err != nil {
-		return nil, err
+		return "", jwt.RegisteredClaims{}, err
 	}
+	return tokenString, claims.RegisteredClaims, nil
+}
+
+// GenerateRefreshToken 生成刷新 Token（开启新的令牌族）。
+// 只签发不登记，登录、刷新接口应使用 internal/refreshtoken 签发，以便服务端校验与轮换
+func GenerateRefreshToken(userID string, config JWTConfig) (string, error) {
+	tokenString, _, err := GenerateRefreshTokenInFamily(userID, "", config)
+	return tokenString, err
+}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+// GenerateRefreshTokenInFamily 生成指定令牌族的刷新 Token（family 为空时开启新的令牌族），返回 Token 及其声明
+func GenerateRefreshTokenInFamily(userID, family string, config JWTConfig) (string, RefreshClaims, error) {
+	if family == "" {
+		family = token.NewTokenID()
 	}
+	claims := RefreshClaims{
+		Type:             TokenTypeRefresh,
+		Family:           family,
+		RegisteredClaims: config.NewRegisteredClaims(userID, config.RefreshExpiry),
+	}
+	tokenString, err := config.Sign(claims)
+	if err != nil {
+		return "", RefreshClaims{}, err
+	}
+	return tokenString, claims, nil
+}
 
-	return nil, errors.New("无效的令牌")
+// ParseRefreshToken 解析刷新 Token：校验签名、有效期、黑名单及 typ 声明
+func ParseRefreshToken(tokenString string, config JWTConfig) (*RefreshClaims, error) {
+	claims := &RefreshClaims{}
+	if err := config.Parse(tokenString, claims); err != nil {
+		return nil, err
+	}
+	if claims.Type != TokenTypeRefresh {
+		return nil, token.ErrTokenInvalid
+	}
+	return claims, nil
+}
+
+// ParseToken 解析 HS256 签名的 JWT Token
+func ParseToken(tokenString, secretKey string) (*Claims, error) {
+	return ParseTokenWithConfig(tokenString, JWTConfig{SecretKey: secretKey})
+}
+
+// ParseTokenWithConfig 按配置的签名算法解析 JWT 访问 Token（RS256/ES256 只需公钥）。
+// 刷新 Token（包括早期签发、没有 typ 与 user_id 声明的刷新 Token）不能当作访问 Token 使用
+func ParseTokenWithConfig(tokenString string, config JWTConfig) (*Claims, error) {
+	claims := &Claims{}
+	if err := config.Parse(tokenString, claims); err != nil {
+		return nil, err
+	}
+	if claims.Type == TokenTypeRefresh || claims.UserID == "" {
+		return nil, ErrRefreshTokenAsAccess
+	}
+	return claims, nil
 }
 
 // OptionalJWTAuth 可选的 JWT 认证（不强制要求）
 func OptionalJWTAuth() gin.HandlerFunc {
-	return OptionalJWTAuthWithConfig(DefaultJWTConfig)
+	return NewOptionalJWTAuth(CurrentJWTConfig())
+}
+
+// NewOptionalJWTAuth 创建可选的 JWT 认证中间件，绑定传入配置的快照
+func NewOptionalJWTAuth(config JWTConfig) gin.HandlerFunc {
+	return OptionalJWTAuthWithConfig(config)
 }
 
 // OptionalJWTAuthWithConfig 带配置的可选 JWT 认证
 func OptionalJWTAuthWithConfig(config JWTConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		tokenString, err := tokenFromRequest(c, config)
+		if err != nil {
 			c.Next()
 			return
 		}
 
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
+		if isPersonalToken(tokenString) {
+			if identity, claims, err := parsePersonalToken(c, tokenString, config); err == nil {
+				setClaims(c, claims)
+				setPersonalToken(c, identity)
+			}
 			c.Next()
 			return
 		}
 
-		tokenString := parts[1]
-		claims, err := ParseToken(tokenString, config.SecretKey)
+		claims, err := ParseTokenWithConfig(tokenString, config)
 		if err == nil {
-			c.Set("user_id", claims.UserID)
-			c.Set("username", claims.Username)
-			c.Set("role", claims.Role)
-			c.Set("claims", claims)
+			setClaims(c, claims)
 		}
 
 		c.Next()
@@ -186,6 +320,16 @@ func RequireRole(roles ...string) gin.HandlerFunc {
 			return
 		}
 
+		// 个人访问令牌只有授予了全部权限（*）时才能访问按角色授权的接口
+		if ViaPersonalToken(c) && !HasScope(c.GetStringSlice("scopes"), "*") {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "个人访问令牌的权限范围不足",
+			})
+			c.Abort()
+			return
+		}
+
 		userRole := role.(string)
 		for _, r := range roles {
 			if userRole == r {
@@ -201,3 +345,46 @@ func RequireRole(roles ...string) gin.HandlerFunc {
 		c.Abort()
 	}
 }
+
+// RequireScope 权限范围验证中间件（需同时拥有所有指定的范围）
+// 授予的范围支持通配：* 匹配全部，users:* 匹配 users:read、users:write 等
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("scopes")
+		if !exists {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "未授权访问",
+			})
+			c.Abort()
+			return
+		}
+
+		granted, _ := value.([]string)
+		for _, scope := range scopes {
+			if !HasScope(granted, scope) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"code":    403,
+					"message": "权限不足，缺少 " + scope,
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// HasScope 检查授予的范围是否包含所需范围
+func HasScope(granted []string, required string) bool {
+	for _, g := range granted {
+		if g == "*" || g == required {
+			return true
+		}
+		if strings.HasSuffix(g, ":*") && strings.HasPrefix(required, strings.TrimSuffix(g, "*")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,239 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/metrics"
+	"new-openclaw/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maintenanceKey 维护模式状态在存储中的 Key
+const maintenanceKey = "state"
+
+// MaintenanceConfig 维护模式配置（开关状态保存在存储中，由管理接口设置）
+type MaintenanceConfig struct {
+	// 未单独设置时的提示信息与 Retry-After
+	Message    string
+	RetryAfter time.Duration
+	// 维护期间仍然开放的路径（支持末尾 * 通配），/admin 下的接口始终开放
+	AllowPaths []string
+	// 从存储刷新开关状态的间隔，其他实例的修改最迟在该间隔后生效
+	RefreshInterval time.Duration
+	// 状态存储（为空时使用 maintenance 组件配置的存储）
+	Store store.Store
+}
+
+// DefaultMaintenanceConfig 默认维护模式配置
+var DefaultMaintenanceConfig = MaintenanceConfig{
+	Message:         "系统维护中，请稍后再试",
+	RetryAfter:      5 * time.Minute,
+	AllowPaths:      []string{"/ping", "/health", "/metrics"},
+	RefreshInterval: 5 * time.Second,
+}
+
+// MaintenanceOverride 在运行时调整默认提示信息与 Retry-After（由系统设置提供），为空时直接使用 DefaultMaintenanceConfig
+var MaintenanceOverride func(config MaintenanceConfig) MaintenanceConfig
+
+// maintenanceConfig 当前生效的维护模式配置
+func maintenanceConfig() MaintenanceConfig {
+	if MaintenanceOverride != nil {
+		return MaintenanceOverride(DefaultMaintenanceConfig)
+	}
+	return DefaultMaintenanceConfig
+}
+
+// MaintenanceState 维护模式状态
+type MaintenanceState struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message,omitempty"`
+	// Retry-After（秒）
+	RetryAfter int `json:"retry_after,omitempty"`
+	// 本次维护额外开放的路径（与配置的 AllowPaths 合并）
+	AllowPaths []string `json:"allow_paths,omitempty"`
+	// 自动结束时间（为空时需手动关闭）
+	Until     *time.Time `json:"until,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	StartedBy string     `json:"started_by,omitempty"`
+}
+
+// active 维护是否生效中（到达结束时间后视为关闭，存储中的记录随过期时间删除）
+func (s MaintenanceState) active(now time.Time) bool {
+	return s.Enabled && (s.Until == nil || now.Before(*s.Until))
+}
+
+var maintenanceRejectedTotal = metrics.NewCounterVec(
+	"http_maintenance_rejected_total", "维护模式期间返回 503 的请求数", "route")
+
+// maintenanceCache 本实例缓存的开关状态，每个刷新间隔最多读取一次存储
+var maintenanceCache struct {
+	mu         sync.Mutex
+	state      MaintenanceState
+	fetchedAt  time.Time
+	refreshing bool
+}
+
+// EnableMaintenance 开启维护模式（state.Until 不为空时到期自动关闭），本实例立即生效
+func EnableMaintenance(ctx context.Context, state MaintenanceState) error {
+	now := time.Now()
+	state.Enabled = true
+	state.StartedAt = &now
+
+	var ttl time.Duration
+	if state.Until != nil {
+		if ttl = time.Until(*state.Until); ttl <= 0 {
+			return errors.New("结束时间已过")
+		}
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := maintenanceStore().Set(ctx, maintenanceKey, string(data), ttl); err != nil {
+		return err
+	}
+	cacheMaintenance(state)
+	return nil
+}
+
+// DisableMaintenance 关闭维护模式，本实例立即生效
+func DisableMaintenance(ctx context.Context) error {
+	if err := maintenanceStore().Del(ctx, maintenanceKey); err != nil {
+		return err
+	}
+	cacheMaintenance(MaintenanceState{})
+	return nil
+}
+
+// CurrentMaintenance 从存储读取当前的维护模式状态（未设置的提示信息与 Retry-After 填充为默认值）
+func CurrentMaintenance(ctx context.Context) (MaintenanceState, error) {
+	state, err := loadMaintenance(ctx)
+	if err != nil {
+		return MaintenanceState{}, err
+	}
+	cacheMaintenance(state)
+	if !state.active(time.Now()) {
+		return MaintenanceState{}, nil
+	}
+	config := maintenanceConfig()
+	if state.Message == "" {
+		state.Message = config.Message
+	}
+	if state.RetryAfter <= 0 {
+		state.RetryAfter = int(config.RetryAfter.Seconds())
+	}
+	return state, nil
+}
+
+// Maintenance 维护模式中间件：开启后除 /admin 与开放路径外的请求均返回 503 与 Retry-After，
+// 应在频率限制、认证等中间件之前注册
+func Maintenance() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := cachedMaintenance()
+		if !state.active(time.Now()) || maintenanceAllowed(state, c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		config := maintenanceConfig()
+		message, retryAfter := state.Message, state.RetryAfter
+		if message == "" {
+			message = config.Message
+		}
+		if retryAfter <= 0 {
+			retryAfter = int(config.RetryAfter.Seconds())
+		}
+
+		maintenanceRejectedTotal.Inc(routeLabel(c))
+		if retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": message,
+		})
+		c.Abort()
+	}
+}
+
+func maintenanceAllowed(state MaintenanceState, path string) bool {
+	if path == "/admin" || strings.HasPrefix(path, "/admin/") {
+		return true
+	}
+	for _, patterns := range [][]string{maintenanceConfig().AllowPaths, state.AllowPaths} {
+		for _, pattern := range patterns {
+			if matchPath(pattern, path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cachedMaintenance 获取缓存的状态；缓存过期时由一个请求读取存储，其他请求继续使用旧状态，
+// 存储不可用时保持最后一次读取到的状态
+func cachedMaintenance() MaintenanceState {
+	cache := &maintenanceCache
+	cache.mu.Lock()
+	state := cache.state
+	stale := time.Since(cache.fetchedAt) >= DefaultMaintenanceConfig.RefreshInterval
+	if !stale || cache.refreshing {
+		cache.mu.Unlock()
+		return state
+	}
+	cache.refreshing = true
+	cache.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	fresh, err := loadMaintenance(ctx)
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.refreshing = false
+	if err != nil {
+		log.Printf("读取维护模式状态失败: %v", err)
+		// 失败后同样等待一个刷新间隔再重试，避免存储故障时每个请求都去读取
+		cache.fetchedAt = time.Now()
+		return state
+	}
+	cache.state, cache.fetchedAt = fresh, time.Now()
+	return fresh
+}
+
+func cacheMaintenance(state MaintenanceState) {
+	maintenanceCache.mu.Lock()
+	defer maintenanceCache.mu.Unlock()
+	maintenanceCache.state, maintenanceCache.fetchedAt = state, time.Now()
+}
+
+func loadMaintenance(ctx context.Context) (MaintenanceState, error) {
+	value, err := maintenanceStore().Get(ctx, maintenanceKey)
+	if errors.Is(err, store.ErrNotFound) {
+		return MaintenanceState{}, nil
+	}
+	if err != nil {
+		return MaintenanceState{}, err
+	}
+	var state MaintenanceState
+	if err := json.Unmarshal([]byte(value), &state); err != nil {
+		return MaintenanceState{}, err
+	}
+	return state, nil
+}
+
+func maintenanceStore() store.Store {
+	if DefaultMaintenanceConfig.Store != nil {
+		return DefaultMaintenanceConfig.Store
+	}
+	return store.For(store.ComponentMaintenance)
+}
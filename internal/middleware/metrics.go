@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"new-openclaw/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	httpRequestsTotal = metrics.NewCounterVec(
+		"http_requests_total", "HTTP 请求总数", "method", "route", "status")
+	httpRequestDuration = metrics.NewHistogramVec(
+		"http_request_duration_seconds", "HTTP 请求耗时（秒）", metrics.DefBuckets, "method", "route")
+	httpRequestSize = metrics.NewHistogramVec(
+		"http_request_size_bytes", "HTTP 请求体大小（字节）", metrics.SizeBuckets, "route", "consumer")
+	httpResponseSize = metrics.NewHistogramVec(
+		"http_response_size_bytes", "HTTP 响应体大小（字节）", metrics.SizeBuckets, "route", "consumer")
+)
+
+// countingReader 统计实际读取的请求体字节数（分块传输时 ContentLength 未知）
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// consumerLabel 获取调用方标签：签名验证通过的 AppKey，其余按是否认证归类（避免按用户产生过多标签）
+func consumerLabel(c *gin.Context) string {
+	if appKey := c.GetString("app_key"); appKey != "" {
+		return appKey
+	}
+	if _, exists := c.Get("user_id"); exists {
+		return "user"
+	}
+	if _, exists := c.Get("admin_claims"); exists {
+		return "admin"
+	}
+	return "anonymous"
+}
+
+// routeLabel 获取路由标签（未匹配路由统一归类，避免标签基数爆炸）
+func routeLabel(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return route
+	}
+	return "unmatched"
+}
+
+// Metrics 请求指标中间件
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		var body *countingReader
+		if c.Request.Body != nil {
+			body = &countingReader{ReadCloser: c.Request.Body}
+			c.Request.Body = body
+		}
+
+		c.Next()
+
+		route := routeLabel(c)
+		httpRequestsTotal.Inc(c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+		httpRequestDuration.Observe(time.Since(start).Seconds(), c.Request.Method, route)
+
+		// 请求体未被读取时以 Content-Length 为准
+		requestSize := c.Request.ContentLength
+		if body != nil && body.n > requestSize {
+			requestSize = body.n
+		}
+		if requestSize < 0 {
+			requestSize = 0
+		}
+		responseSize := c.Writer.Size()
+		if responseSize < 0 {
+			responseSize = 0
+		}
+
+		consumer := consumerLabel(c)
+		httpRequestSize.Observe(float64(requestSize), route, consumer)
+		httpResponseSize.Observe(float64(responseSize), route, consumer)
+	}
+}
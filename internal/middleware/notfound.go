@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotFoundConfig 未知路由处理配置
+type NotFoundConfig struct {
+	// 按路径前缀自定义响应（最长前缀优先），如 "/admin" 返回管理后台专用的 404
+	Handlers map[string]gin.HandlerFunc
+	// 是否将未知路径计入请求 IP 的滥用评分（扫描器会探测大量路径）
+	CountAbuse bool
+}
+
+// NotFound 未知路由处理（统一响应格式，并计入滥用评分）
+func NotFound() gin.HandlerFunc {
+	return NotFoundWithConfig(NotFoundConfig{CountAbuse: true})
+}
+
+// NotFoundWithConfig 带配置的未知路由处理
+func NotFoundWithConfig(config NotFoundConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+
+		if config.CountAbuse {
+			RecordAbuse(c.Request.Context(), AbuseIP(c), AbuseNotFound)
+		}
+
+		if h := prefixHandler(config.Handlers, path); h != nil {
+			h(c)
+			return
+		}
+
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "接口不存在",
+			"path":    path,
+		})
+	}
+}
+
+// MethodNotAllowed 请求方法不支持时的处理（设置 Allow 头并提示该路径支持的方法）
+// 需同时设置 r.HandleMethodNotAllowed = true
+func MethodNotAllowed(r *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowed := allowedMethods(r.Routes(), c.Request.URL.Path)
+
+		message := "不支持的请求方法 " + c.Request.Method
+		if len(allowed) > 0 {
+			c.Header("Allow", strings.Join(allowed, ", "))
+			message += "，请使用 " + strings.Join(allowed, " / ")
+		}
+
+		c.JSON(http.StatusMethodNotAllowed, gin.H{
+			"code":    405,
+			"message": message,
+			"data": gin.H{
+				"allowed_methods": allowed,
+			},
+		})
+	}
+}
+
+// prefixHandler 查找最长匹配前缀的自定义处理函数
+func prefixHandler(handlers map[string]gin.HandlerFunc, path string) gin.HandlerFunc {
+	var best gin.HandlerFunc
+	bestLen := -1
+	for prefix, h := range handlers {
+		if strings.HasPrefix(path, prefix) && len(prefix) > bestLen {
+			best, bestLen = h, len(prefix)
+		}
+	}
+	return best
+}
+
+// allowedMethods 返回注册了该路径的所有请求方法
+func allowedMethods(routes gin.RoutesInfo, path string) []string {
+	seen := make(map[string]bool)
+	var methods []string
+	for _, route := range routes {
+		if !seen[route.Method] && matchRoutePattern(route.Path, path) {
+			seen[route.Method] = true
+			methods = append(methods, route.Method)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// matchRoutePattern 按 gin 路由规则匹配路径（:param 匹配一段，*param 匹配剩余部分）
+func matchRoutePattern(pattern, path string) bool {
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, "*") {
+			return true
+		}
+		if i >= len(pathParts) {
+			return false
+		}
+		if strings.HasPrefix(part, ":") {
+			if pathParts[i] == "" {
+				return false
+			}
+			continue
+		}
+		if part != pathParts[i] {
+			return false
+		}
+	}
+	return len(patternParts) == len(pathParts)
+}
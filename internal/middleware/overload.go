@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"new-openclaw/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 过载等级
+const (
+	// OverloadNone 正常
+	OverloadNone int32 = iota
+	// OverloadModerate 轻度过载：丢弃批量请求和未认证的公开请求
+	OverloadModerate
+	// OverloadSevere 严重过载（超过阈值 1.5 倍）：丢弃除关键请求和豁免路径外的所有请求
+	OverloadSevere
+)
+
+// OverloadConfig 过载保护配置
+type OverloadConfig struct {
+	// 最大 goroutine 数（0 表示不检测）
+	MaxGoroutines int
+	// 最大堆内存（字节，0 表示不检测）
+	MaxHeapBytes uint64
+	// 最大调度延迟（0 表示不检测）
+	MaxSchedLatency time.Duration
+	// 采样间隔
+	SampleInterval time.Duration
+	// 始终放行的路径前缀（管理后台、健康检查）
+	ExemptPaths []string
+}
+
+// DefaultOverloadConfig 默认过载保护配置
+var DefaultOverloadConfig = OverloadConfig{
+	MaxGoroutines:   10000,
+	MaxHeapBytes:    1 << 30,
+	MaxSchedLatency: 100 * time.Millisecond,
+	SampleInterval:  time.Second,
+	ExemptPaths:     []string{"/admin", "/health", "/ping", "/metrics"},
+}
+
+var shedRequestsTotal = metrics.NewCounterVec(
+	"http_shed_requests_total", "过载保护丢弃的请求数", "level")
+
+// OverloadMonitor 运行时负载监控
+type OverloadMonitor struct {
+	config       OverloadConfig
+	level        int32
+	goroutines   int64
+	heapBytes    uint64
+	schedLatency int64
+}
+
+// NewOverloadMonitor 创建负载监控并启动采样协程
+func NewOverloadMonitor(config OverloadConfig) *OverloadMonitor {
+	if config.SampleInterval <= 0 {
+		config.SampleInterval = time.Second
+	}
+
+	m := &OverloadMonitor{config: config}
+
+	metrics.NewGaugeFunc("runtime_goroutines", "当前 goroutine 数", func() float64 {
+		return float64(atomic.LoadInt64(&m.goroutines))
+	})
+	metrics.NewGaugeFunc("runtime_heap_bytes", "当前堆内存（字节）", func() float64 {
+		return float64(atomic.LoadUint64(&m.heapBytes))
+	})
+	metrics.NewGaugeFunc("runtime_sched_latency_seconds", "调度延迟（秒）", func() float64 {
+		return time.Duration(atomic.LoadInt64(&m.schedLatency)).Seconds()
+	})
+	metrics.NewGaugeFunc("overload_level", "过载等级（0 正常，1 轻度，2 严重）", func() float64 {
+		return float64(m.Level())
+	})
+
+	go m.run()
+
+	return m
+}
+
+// run 定期采样
+func (m *OverloadMonitor) run() {
+	ticker := time.NewTicker(m.config.SampleInterval)
+	defer ticker.Stop()
+
+	expected := time.Now().Add(m.config.SampleInterval)
+	for now := range ticker.C {
+		// 调度延迟：定时器实际触发时间与预期时间的偏差
+		latency := now.Sub(expected)
+		if latency < 0 {
+			latency = 0
+		}
+		expected = now.Add(m.config.SampleInterval)
+
+		var memStats runtime.MemStats
+		runtime.ReadMemStats(&memStats)
+
+		m.sample(runtime.NumGoroutine(), memStats.HeapAlloc, latency)
+	}
+}
+
+// sample 记录采样结果并计算过载等级
+func (m *OverloadMonitor) sample(goroutines int, heapBytes uint64, latency time.Duration) {
+	atomic.StoreInt64(&m.goroutines, int64(goroutines))
+	atomic.StoreUint64(&m.heapBytes, heapBytes)
+	atomic.StoreInt64(&m.schedLatency, int64(latency))
+
+	// 取各项指标中最高的负载比例
+	ratio := 0.0
+	if m.config.MaxGoroutines > 0 {
+		ratio = maxFloat(ratio, float64(goroutines)/float64(m.config.MaxGoroutines))
+	}
+	if m.config.MaxHeapBytes > 0 {
+		ratio = maxFloat(ratio, float64(heapBytes)/float64(m.config.MaxHeapBytes))
+	}
+	if m.config.MaxSchedLatency > 0 {
+		ratio = maxFloat(ratio, float64(latency)/float64(m.config.MaxSchedLatency))
+	}
+
+	level := OverloadNone
+	switch {
+	case ratio >= 1.5:
+		level = OverloadSevere
+	case ratio >= 1:
+		level = OverloadModerate
+	}
+	atomic.StoreInt32(&m.level, level)
+}
+
+// Level 获取当前过载等级
+func (m *OverloadMonitor) Level() int32 {
+	return atomic.LoadInt32(&m.level)
+}
+
+// Stats 获取当前负载数据
+func (m *OverloadMonitor) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"level":            m.Level(),
+		"goroutines":       atomic.LoadInt64(&m.goroutines),
+		"heap_bytes":       atomic.LoadUint64(&m.heapBytes),
+		"sched_latency_ms": time.Duration(atomic.LoadInt64(&m.schedLatency)).Milliseconds(),
+	}
+}
+
+// maxFloat 取较大值
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// isExemptPath 检查路径是否豁免过载保护
+func (m *OverloadMonitor) isExemptPath(path string) bool {
+	for _, prefix := range m.config.ExemptPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadShedding 过载保护中间件（按优先级丢弃请求，返回 503）
+func LoadShedding(monitor *OverloadMonitor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		level := monitor.Level()
+		priority := RequestPriority(c)
+		if level == OverloadNone || priority == PriorityCritical || monitor.isExemptPath(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		// 轻度过载时优先丢弃批量请求和未认证的请求（Token 须验签通过，随意填写的 Authorization 头不能豁免；
+		// 优先使用上下文中已验证的声明与验签结果缓存，避免过载时重复验签）
+		if level == OverloadModerate && priority != PriorityBulk && requestRole(c) != "" {
+			c.Next()
+			return
+		}
+
+		shedRequestsTotal.Inc(strconv.Itoa(int(level)))
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": "服务繁忙，请稍后再试",
+		})
+		c.Abort()
+	}
+}
@@ -0,0 +1,355 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	adminmiddleware "new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/metrics"
+	"new-openclaw/internal/store"
+	"new-openclaw/pkg/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 处置动作
+const (
+	PolicyAllow    = "allow"
+	PolicyThrottle = "throttle"
+	PolicyCaptcha  = "captcha"
+	PolicyStepUp   = "step_up"
+	PolicyBlock    = "block"
+)
+
+// 客户端信誉标记
+const (
+	// ReputationListed 命中 IP 信誉名单
+	ReputationListed = "listed"
+	// ReputationClean 未命中任何名单
+	ReputationClean = "clean"
+)
+
+// CaptchaTokenHeader 客户端提交人机验证凭证的请求头
+const CaptchaTokenHeader = "X-Captcha-Token"
+
+// policyActionKey 命中的处置动作在 Context 中的 key
+const policyActionKey = "policy_action"
+
+// CaptchaVerifier 人机验证凭证的校验（实现见 SiteVerifyCaptcha）
+type CaptchaVerifier interface {
+	// Verify 校验前端提交的凭证，err 表示验证服务不可用
+	Verify(ctx context.Context, token, ip string) (bool, error)
+}
+
+// PolicyConfig 反滥用处置策略配置
+type PolicyConfig struct {
+	// 处置规则（按顺序匹配第一条）
+	Rules []config.PolicyRule
+	// 路由敏感级别（按顺序匹配，未列出的为 low）
+	Routes []config.PolicyRoute
+	// 客户端信誉标记（为空或返回空字符串时视为 clean）
+	Reputation func(c *gin.Context) string
+	// 人机验证（为空时 captcha 按 block 处理）
+	Captcha        CaptchaVerifier
+	CaptchaSiteKey string
+	// 验证通过后免验证的时长
+	CaptchaPassTTL time.Duration
+	// 重新认证的时限：Token 的登录时间早于此时长时要求重新登录
+	StepUpMaxAge time.Duration
+	// 验证通过状态的存储（为空时使用 abuse 组件配置的存储）
+	Store store.Store
+}
+
+// PolicyDecision 一次请求的评估结果
+type PolicyDecision struct {
+	// 命中的规则（未命中时为空）
+	Rule   string `json:"rule,omitempty"`
+	Action string `json:"action"`
+	// 参与判断的条件（未被任何规则用到的条件不计算，为零值）
+	Score       int64  `json:"score"`
+	Sensitivity string `json:"sensitivity"`
+	Reputation  string `json:"reputation,omitempty"`
+}
+
+var policyDecisionsTotal = metrics.NewCounterVec(
+	"policy_decisions_total", "反滥用处置策略命中次数（result: passed 放行, challenged 要求验证, rejected 拒绝）", "action", "result")
+
+// policyRule 解析后的规则
+type policyRule struct {
+	config.PolicyRule
+	// 敏感级别下限的序号（-1 不限制）
+	minLevel int
+	// throttle 的限流器
+	limiter *RateLimiter
+}
+
+// PolicyEngine 反滥用处置策略：按顺序评估规则，由一个中间件统一执行放行、限速、人机验证、重新认证或拒绝
+type PolicyEngine struct {
+	config PolicyConfig
+	rules  []policyRule
+}
+
+// NewPolicyEngine 创建处置策略，throttle 规则的时间窗口或限额无效时返回错误
+func NewPolicyEngine(cfg PolicyConfig) (*PolicyEngine, error) {
+	e := &PolicyEngine{config: cfg}
+	for i, rule := range cfg.Rules {
+		r := policyRule{PolicyRule: rule, minLevel: -1}
+		if rule.MinSensitivity != "" {
+			r.minLevel = sensitivityLevel(rule.MinSensitivity)
+		}
+		if rule.Action == PolicyThrottle {
+			limiter, err := NewRateLimiter(RateLimitConfig{
+				Window:      rule.Window,
+				MaxRequests: rule.MaxRequests,
+				Prefix:      "policy:" + strconv.Itoa(i),
+			})
+			if err != nil {
+				return nil, err
+			}
+			r.limiter = limiter
+		}
+		if rule.Action == PolicyCaptcha && cfg.Captcha == nil {
+			log.Printf("⚠️  处置规则 %q 需要人机验证，但未配置验证服务，命中时直接拒绝", rule.Name)
+		}
+		e.rules = append(e.rules, r)
+	}
+	return e, nil
+}
+
+// Sensitivity 获取路由的敏感级别
+func (e *PolicyEngine) Sensitivity(method, path string) string {
+	for _, route := range e.config.Routes {
+		if matchRoute(route.Route, method, path) {
+			return route.Level
+		}
+	}
+	return config.PolicySensitivityLevels[0]
+}
+
+// Evaluate 评估请求命中的规则（不执行处置）
+func (e *PolicyEngine) Evaluate(c *gin.Context) PolicyDecision {
+	decision, _ := e.evaluate(c)
+	return decision
+}
+
+// evaluate 按顺序匹配规则；评分与信誉只在规则用到时才查询
+func (e *PolicyEngine) evaluate(c *gin.Context) (PolicyDecision, *policyRule) {
+	decision := PolicyDecision{
+		Action:      PolicyAllow,
+		Sensitivity: e.Sensitivity(c.Request.Method, c.Request.URL.Path),
+	}
+	level := sensitivityLevel(decision.Sensitivity)
+	ip := AbuseIP(c)
+
+	scored, rated := false, false
+	for i := range e.rules {
+		rule := &e.rules[i]
+		if rule.minLevel >= 0 && level < rule.minLevel {
+			continue
+		}
+		if rule.MinScore > 0 {
+			if !scored {
+				decision.Score, scored = AbuseScore(c.Request.Context(), ip), true
+			}
+			if decision.Score < rule.MinScore {
+				continue
+			}
+		}
+		if rule.Reputation != "" {
+			if !rated {
+				decision.Reputation, rated = e.reputation(c), true
+			}
+			if decision.Reputation != rule.Reputation {
+				continue
+			}
+		}
+		decision.Rule, decision.Action = rule.Name, rule.Action
+		return decision, rule
+	}
+	return decision, nil
+}
+
+// Middleware 处置策略中间件（须放在 IP 过滤之后，依赖其解析的客户端 IP）
+func (e *PolicyEngine) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(e.rules) == 0 {
+			c.Next()
+			return
+		}
+		decision, rule := e.evaluate(c)
+		if rule == nil {
+			c.Next()
+			return
+		}
+		c.Set(policyActionKey, rule.Action)
+
+		passed := false
+		switch rule.Action {
+		case PolicyAllow:
+			passed = true
+		case PolicyThrottle:
+			passed = e.throttle(c, rule)
+		case PolicyCaptcha:
+			passed = e.captcha(c)
+		case PolicyStepUp:
+			passed = e.stepUp(c)
+		default:
+			policyDecisionsTotal.Inc(PolicyBlock, "rejected")
+			e.reject(c, http.StatusForbidden, PolicyBlock, "请求已被安全策略拒绝")
+		}
+		if !passed {
+			AddAuditNote(c, fmt.Sprintf("命中处置规则 %s（评分 %d，敏感级别 %s）", decision.Rule, decision.Score, decision.Sensitivity))
+			return
+		}
+		policyDecisionsTotal.Inc(rule.Action, "passed")
+		c.Next()
+	}
+}
+
+// PolicyAction 获取请求命中的处置动作（未命中任何规则时为空）
+func PolicyAction(c *gin.Context) string {
+	return c.GetString(policyActionKey)
+}
+
+// throttle 按规则的限额对 IP 限速，超限计入滥用评分（评分继续升高时可命中更严格的规则）
+func (e *PolicyEngine) throttle(c *gin.Context, rule *policyRule) bool {
+	ip := AbuseIP(c)
+	result, err := rule.limiter.TakeContext(c.Request.Context(), ip)
+	if err != nil {
+		// 请求已取消或超时
+		c.Abort()
+		return false
+	}
+	setRateLimitHeaders(c, result)
+	if result.Allowed {
+		return true
+	}
+	RecordAbuse(c.Request.Context(), ip, AbuseRateLimited)
+	policyDecisionsTotal.Inc(PolicyThrottle, "rejected")
+	e.reject(c, http.StatusTooManyRequests, PolicyThrottle, "请求过于频繁，请稍后再试")
+	return false
+}
+
+// captcha 要求人机验证：凭证校验通过后记住该 IP，有效期内不再要求
+func (e *PolicyEngine) captcha(c *gin.Context) bool {
+	if e.config.Captcha == nil {
+		policyDecisionsTotal.Inc(PolicyCaptcha, "rejected")
+		e.reject(c, http.StatusForbidden, PolicyBlock, "请求已被安全策略拒绝")
+		return false
+	}
+
+	ctx := c.Request.Context()
+	ip := AbuseIP(c)
+	key := "policy:captcha:" + ip
+	if _, err := e.store().Get(ctx, key); err == nil {
+		return true
+	}
+
+	message := "请完成人机验证后重试"
+	if token := c.GetHeader(CaptchaTokenHeader); token != "" {
+		ok, err := e.config.Captcha.Verify(ctx, token, ip)
+		switch {
+		case err != nil:
+			log.Printf("人机验证服务异常: %v", err)
+			message = "人机验证服务暂不可用，请稍后重试"
+		case ok:
+			if e.config.CaptchaPassTTL > 0 {
+				e.store().Set(ctx, key, "1", e.config.CaptchaPassTTL)
+			}
+			return true
+		default:
+			message = "人机验证未通过，请重试"
+		}
+	}
+
+	policyDecisionsTotal.Inc(PolicyCaptcha, "challenged")
+	c.Header("X-Policy-Action", PolicyCaptcha)
+	c.JSON(http.StatusForbidden, gin.H{
+		"code":     403,
+		"message":  message,
+		"action":   PolicyCaptcha,
+		"site_key": e.config.CaptchaSiteKey,
+	})
+	c.Abort()
+	return false
+}
+
+// stepUp 要求近期登录过：未携带或携带无效 Token 的请求交给后面的认证中间件处理
+func (e *PolicyEngine) stepUp(c *gin.Context) bool {
+	authTime, ok := requestAuthTime(c)
+	if !ok || time.Since(authTime) <= e.config.StepUpMaxAge {
+		return true
+	}
+
+	maxAge := int64(e.config.StepUpMaxAge / time.Second)
+	policyDecisionsTotal.Inc(PolicyStepUp, "challenged")
+	// RFC 9470：告知客户端需要在 max_age 秒内重新认证
+	c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_user_authentication", error_description="A more recent authentication is required", max_age="%d"`, maxAge))
+	c.Header("X-Policy-Action", PolicyStepUp)
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"code":    401,
+		"message": "该操作需要重新登录验证身份",
+		"action":  PolicyStepUp,
+		"max_age": maxAge,
+	})
+	c.Abort()
+	return false
+}
+
+// reject 拒绝请求
+func (e *PolicyEngine) reject(c *gin.Context, status int, action, message string) {
+	c.Header("X-Policy-Action", action)
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": message,
+		"action":  action,
+	})
+	c.Abort()
+}
+
+func (e *PolicyEngine) reputation(c *gin.Context) string {
+	if e.config.Reputation != nil {
+		if label := e.config.Reputation(c); label != "" {
+			return label
+		}
+	}
+	return ReputationClean
+}
+
+func (e *PolicyEngine) store() store.Store {
+	if e.config.Store != nil {
+		return e.config.Store
+	}
+	return store.For(store.ComponentAbuse)
+}
+
+// requestAuthTime 获取请求所携带 Token 的登录时间（用户 Token 不能刷新，取签发时间；管理员 Token 取 auth_time）
+func requestAuthTime(c *gin.Context) (time.Time, bool) {
+	if tokenString, err := CurrentJWTConfig().FromRequest(c.Request); err == nil {
+		if claims, err := ParseTokenWithConfig(tokenString, CurrentJWTConfig()); err == nil && claims.IssuedAt != nil {
+			return claims.IssuedAt.Time, true
+		}
+	}
+	if tokenString, err := adminmiddleware.DefaultConfig.FromRequest(c.Request); err == nil {
+		if claims, err := adminmiddleware.ParseToken(tokenString); err == nil {
+			if authTime := claims.AuthenticatedAt(); !authTime.IsZero() {
+				return authTime, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// sensitivityLevel 敏感级别的序号（未知级别按 low）
+func sensitivityLevel(level string) int {
+	for i, l := range config.PolicySensitivityLevels {
+		if l == level {
+			return i
+		}
+	}
+	return 0
+}
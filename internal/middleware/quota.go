@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"new-openclaw/internal/quota"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Quota AppKey 配额中间件（需放在签名验证之后，使用上下文中已验证的 app_key）
+func Quota() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		appKey := c.GetString("app_key")
+		if appKey == "" {
+			c.Next()
+			return
+		}
+
+		usage, err := quota.Consume(c.Request.Context(), appKey)
+		if err != nil {
+			// 存储不可用时放行，避免配额组件故障导致服务不可用
+			log.Printf("配额存储异常: %v", err)
+			c.Next()
+			return
+		}
+
+		setQuotaHeaders(c, usage)
+
+		if usage.Exceeded != "" {
+			message := "今日调用配额已用完"
+			if usage.Exceeded == quota.PeriodMonthly {
+				message = "本月调用配额已用完"
+			}
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"code":    quota.ErrCodeQuotaExceeded,
+				"message": message,
+				"data":    usage,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// setQuotaHeaders 写入配额响应头（仅对有上限的周期）
+func setQuotaHeaders(c *gin.Context, usage quota.Usage) {
+	if usage.Limits.Daily > 0 {
+		c.Header("X-Quota-Daily-Limit", strconv.FormatInt(usage.Limits.Daily, 10))
+		c.Header("X-Quota-Daily-Remaining", strconv.FormatInt(remaining(usage.Limits.Daily, usage.Daily), 10))
+	}
+	if usage.Limits.Monthly > 0 {
+		c.Header("X-Quota-Monthly-Limit", strconv.FormatInt(usage.Limits.Monthly, 10))
+		c.Header("X-Quota-Monthly-Remaining", strconv.FormatInt(remaining(usage.Limits.Monthly, usage.Monthly), 10))
+	}
+	if usage.Exceeded != "" {
+		reset := usage.DailyReset
+		if usage.Exceeded == quota.PeriodMonthly {
+			reset = usage.MonthlyReset
+		}
+		c.Header("Retry-After", strconv.FormatInt(int64((time.Until(reset)+time.Second-1)/time.Second), 10))
+	}
+}
+
+func remaining(limit, used int64) int64 {
+	if used >= limit {
+		return 0
+	}
+	return limit - used
+}
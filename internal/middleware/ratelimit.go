@@ -1,10 +1,18 @@
 package middleware
 
 import (
+	"context"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
+	"new-openclaw/internal/metrics"
+	"new-openclaw/internal/store"
+	"new-openclaw/pkg/config"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -18,12 +26,35 @@ type RateLimitConfig struct {
 	KeyFunc func(c *gin.Context) string
 	// 被限制时的响应
 	LimitHandler gin.HandlerFunc
+	// 状态存储（为空时使用 ratelimit 组件配置的存储）
+	Store store.Store
+	// 存储 Key 前缀（多个限流器共享存储时用于区分）
+	Prefix string
+	// 限流算法：fixed_window（默认）, sliding_log, token_bucket, leaky_bucket
+	Algorithm string
+	// 突发容量（令牌桶容量 / 漏桶队列长度），默认等于 MaxRequests
+	Burst int
+	// 豁免的 IP/CIDR
+	ExemptCIDRs []string
+	// 豁免的 AppKey（仅对已通过签名验证的请求生效）
+	ExemptAppKeys []string
+	// 豁免的 JWT 角色（如 super_admin）
+	ExemptRoles []string
+	// 预警阈值（已用额度占限额的比例，如 0.8），达到后响应 X-RateLimit-Warning 头并写入审计备注，0 表示不预警
+	WarnThreshold float64
+	// 在此时间之前只记录超限、不返回 429（新的更严格限额先观察一段时间）
+	EnforceAfter time.Time
 }
 
+// rateLimitWarningsTotal 软限制事件数（kind: warn 达到预警阈值, log_only 观察期内超限）
+var rateLimitWarningsTotal = metrics.NewCounterVec(
+	"rate_limit_warnings_total", "频率限制预警与观察期超限次数", "limiter", "kind")
+
 // DefaultRateLimitConfig 默认频率限制配置
 var DefaultRateLimitConfig = RateLimitConfig{
 	Window:      time.Minute,
 	MaxRequests: 60,
+	Prefix:      "global",
 	KeyFunc: func(c *gin.Context) string {
 		return c.ClientIP()
 	},
@@ -36,92 +67,155 @@ var DefaultRateLimitConfig = RateLimitConfig{
 	},
 }
 
-// rateLimitEntry 频率限制条目
-type rateLimitEntry struct {
-	count     int
-	startTime time.Time
+// RateLimiter 频率限制器（状态保存在 Store 中）
+type RateLimiter struct {
+	config    RateLimitConfig
+	store     store.Store
+	algorithm rateLimitAlgorithm
 }
 
-// RateLimiter 频率限制器
-type RateLimiter struct {
-	config  RateLimitConfig
-	entries map[string]*rateLimitEntry
-	mu      sync.RWMutex
+// validate 检查时间窗口与限额：时间窗口必须大于 0，且每个请求的平均间隔（Window/MaxRequests）不能为 0
+// （令牌桶、漏桶按该间隔计算）
+func (config RateLimitConfig) validate() error {
+	if config.Window <= 0 {
+		return fmt.Errorf("频率限制 %s 的时间窗口必须大于 0: %s", config.Prefix, config.Window)
+	}
+	if config.MaxRequests > 0 && config.Window < time.Duration(config.MaxRequests) {
+		return fmt.Errorf("频率限制 %s 的限额 %d 超出时间窗口 %s 的精度", config.Prefix, config.MaxRequests, config.Window)
+	}
+	return nil
 }
 
-// NewRateLimiter 创建频率限制器
-func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+// NewRateLimiter 创建频率限制器，时间窗口或限额无效时返回错误
+func NewRateLimiter(config RateLimitConfig) (*RateLimiter, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	s := config.Store
+	if s == nil {
+		s = store.For(store.ComponentRateLimit)
+	}
+
 	rl := &RateLimiter{
-		config:  config,
-		entries: make(map[string]*rateLimitEntry),
+		config: config,
+		store:  s,
 	}
+	rl.algorithm = newRateLimitAlgorithm(rl)
 
-	// 启动清理协程
-	go rl.cleanup()
+	return rl, nil
+}
 
-	return rl
+// storeKey 生成存储 Key
+func (rl *RateLimiter) storeKey(key string) string {
+	return rl.config.Prefix + ":" + key
 }
 
-// cleanup 定期清理过期条目
-func (rl *RateLimiter) cleanup() {
-	ticker := time.NewTicker(rl.config.Window)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		rl.mu.Lock()
-		now := time.Now()
-		for key, entry := range rl.entries {
-			if now.Sub(entry.startTime) > rl.config.Window {
-				delete(rl.entries, key)
-			}
+// RateLimitResult 频率限制状态
+type RateLimitResult struct {
+	// 是否允许
+	Allowed bool
+	// 限额
+	Limit int
+	// 剩余可用次数
+	Remaining int
+	// 限额完全恢复的时间
+	Reset time.Time
+	// 被拒绝时建议的重试等待时间
+	RetryAfter time.Duration
+}
+
+// Take 消耗一次请求额度并返回限流状态
+func (rl *RateLimiter) Take(key string) RateLimitResult {
+	result, _ := rl.TakeContext(context.Background(), key)
+	return result
+}
+
+// TakeContext 消耗一次请求额度并返回限流状态。请求取消或超时（包括漏桶排队期间）时不再等待，
+// 返回拒绝的结果与 ctx.Err()；存储异常时放行
+func (rl *RateLimiter) TakeContext(ctx context.Context, key string) (RateLimitResult, error) {
+	result, err := rl.algorithm.take(ctx, rl.storeKey(key), time.Now())
+	if err != nil {
+		if ctx.Err() != nil {
+			return RateLimitResult{Limit: rl.config.MaxRequests}, ctx.Err()
 		}
-		rl.mu.Unlock()
+		// 存储不可用时放行，避免限流组件故障导致服务不可用
+		log.Printf("频率限制存储异常: %v", err)
+		return RateLimitResult{Allowed: true, Limit: rl.config.MaxRequests, Remaining: rl.config.MaxRequests}, nil
+	}
+	if result.Remaining < 0 {
+		result.Remaining = 0
 	}
+	if !result.Allowed {
+		rateLimitHotKeys.record(rl.config.Prefix, key, time.Now())
+	}
+	return result, nil
 }
 
 // Allow 检查是否允许请求
 func (rl *RateLimiter) Allow(key string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	now := time.Now()
-	entry, exists := rl.entries[key]
+	return rl.Take(key).Allowed
+}
 
-	if !exists || now.Sub(entry.startTime) > rl.config.Window {
-		rl.entries[key] = &rateLimitEntry{
-			count:     1,
-			startTime: now,
-		}
-		return true
-	}
+// Peek 查询限流状态（不消耗额度）
+func (rl *RateLimiter) Peek(key string) RateLimitResult {
+	return rl.PeekContext(context.Background(), key)
+}
 
-	if entry.count >= rl.config.MaxRequests {
-		return false
+// PeekContext 查询限流状态（不消耗额度）
+func (rl *RateLimiter) PeekContext(ctx context.Context, key string) RateLimitResult {
+	result := rl.algorithm.peek(ctx, rl.storeKey(key), time.Now())
+	if result.Remaining < 0 {
+		result.Remaining = 0
 	}
-
-	entry.count++
-	return true
+	return result
 }
 
 // GetRemaining 获取剩余请求数
 func (rl *RateLimiter) GetRemaining(key string) int {
-	rl.mu.RLock()
-	defer rl.mu.RUnlock()
+	return rl.Peek(key).Remaining
+}
 
-	entry, exists := rl.entries[key]
-	if !exists {
-		return rl.config.MaxRequests
+// setRateLimitHeaders 写入限流响应头
+func setRateLimitHeaders(c *gin.Context, result RateLimitResult) {
+	c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	if !result.Reset.IsZero() {
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(result.Reset.Unix(), 10))
+	}
+	if !result.Allowed {
+		// Retry-After 以秒为单位，向上取整且至少为 1
+		seconds := int64((result.RetryAfter + time.Second - 1) / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+		c.Header("Retry-After", strconv.FormatInt(seconds, 10))
 	}
+}
 
-	if time.Now().Sub(entry.startTime) > rl.config.Window {
-		return rl.config.MaxRequests
+// softLimit 软限制：超过预警阈值时添加 X-RateLimit-Warning 头；
+// 处于观察期（EnforceAfter 之前）时超限请求只记录不拦截，返回的结果视为允许
+func softLimit(c *gin.Context, config RateLimitConfig, result RateLimitResult) RateLimitResult {
+	if !result.Allowed {
+		if time.Now().Before(config.EnforceAfter) {
+			enforceAt := config.EnforceAfter.Format(time.RFC3339)
+			c.Header("X-RateLimit-Warning", "rate limit exceeded, enforced from "+enforceAt)
+			AddAuditNote(c, fmt.Sprintf("超出频率限制 %s（观察期，%s 起拦截）", config.Prefix, enforceAt))
+			rateLimitWarningsTotal.Inc(config.Prefix, "log_only")
+			result.Allowed = true
+		}
+		return result
 	}
 
-	remaining := rl.config.MaxRequests - entry.count
-	if remaining < 0 {
-		return 0
+	if config.WarnThreshold > 0 && result.Limit > 0 {
+		used := result.Limit - result.Remaining
+		if float64(used) >= config.WarnThreshold*float64(result.Limit) {
+			c.Header("X-RateLimit-Warning", fmt.Sprintf("%d of %d requests used", used, result.Limit))
+			AddAuditNote(c, fmt.Sprintf("频率限制 %s 已用 %d/%d", config.Prefix, used, result.Limit))
+			rateLimitWarningsTotal.Inc(config.Prefix, "warn")
+		}
 	}
-	return remaining
+	return result
 }
 
 // RateLimit 频率限制中间件
@@ -129,22 +223,108 @@ func RateLimit() gin.HandlerFunc {
 	return RateLimitWithConfig(DefaultRateLimitConfig)
 }
 
-// RateLimitWithConfig 带配置的频率限制中间件
+// RateLimitWithConfig 带配置的频率限制中间件；配置无效时记录日志，所有请求返回 500（不静默放行）
 func RateLimitWithConfig(config RateLimitConfig) gin.HandlerFunc {
-	limiter := NewRateLimiter(config)
+	limiter, err := NewRateLimiter(config)
+	if err != nil {
+		log.Printf("⚠️  %v", err)
+		return func(c *gin.Context) {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "频率限制配置错误",
+			})
+		}
+	}
+	exemption := newRateLimitExemption(config)
 
 	return func(c *gin.Context) {
-		key := config.KeyFunc(c)
+		if exemption.match(c) {
+			c.Next()
+			return
+		}
+
+		result, err := limiter.TakeContext(c.Request.Context(), config.KeyFunc(c))
+		if err != nil {
+			// 请求已取消或超时
+			c.Abort()
+			return
+		}
+		result = softLimit(c, config, result)
+		setRateLimitHeaders(c, result)
 
-		if !limiter.Allow(key) {
+		if !result.Allowed {
+			RecordAbuse(c.Request.Context(), AbuseIP(c), AbuseRateLimited)
 			config.LimitHandler(c)
 			return
 		}
 
-		// 添加响应头
-		remaining := limiter.GetRemaining(key)
-		c.Header("X-RateLimit-Limit", string(rune(config.MaxRequests)))
-		c.Header("X-RateLimit-Remaining", string(rune(remaining)))
+		c.Next()
+	}
+}
+
+// DynamicRateLimiter 动态频率限制器（支持运行时修改限额，如配置中心下发）
+type DynamicRateLimiter struct {
+	mu        sync.RWMutex
+	config    RateLimitConfig
+	limiter   *RateLimiter
+	exemption *rateLimitExemption
+}
+
+// NewDynamicRateLimiter 创建动态频率限制器，配置无效时返回错误
+func NewDynamicRateLimiter(config RateLimitConfig) (*DynamicRateLimiter, error) {
+	d := &DynamicRateLimiter{}
+	if err := d.Update(config); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Config 获取当前配置
+func (d *DynamicRateLimiter) Config() RateLimitConfig {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.config
+}
+
+// Update 替换配置（计数状态保存在 Store 中，前缀不变时不会丢失）；配置无效时返回错误并保留当前配置
+func (d *DynamicRateLimiter) Update(config RateLimitConfig) error {
+	limiter, err := NewRateLimiter(config)
+	if err != nil {
+		return err
+	}
+	exemption := newRateLimitExemption(config)
+
+	d.mu.Lock()
+	d.config, d.limiter, d.exemption = config, limiter, exemption
+	d.mu.Unlock()
+	return nil
+}
+
+// Middleware 返回中间件
+func (d *DynamicRateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d.mu.RLock()
+		config, limiter, exemption := d.config, d.limiter, d.exemption
+		d.mu.RUnlock()
+
+		if exemption.match(c) {
+			c.Next()
+			return
+		}
+
+		result, err := limiter.TakeContext(c.Request.Context(), config.KeyFunc(c))
+		if err != nil {
+			c.Abort()
+			return
+		}
+		result = softLimit(c, config, result)
+		setRateLimitHeaders(c, result)
+
+		if !result.Allowed {
+			RecordAbuse(c.Request.Context(), AbuseIP(c), AbuseRateLimited)
+			config.LimitHandler(c)
+			return
+		}
 
 		c.Next()
 	}
@@ -163,6 +343,7 @@ func APIRateLimit(maxRequests int, window time.Duration) gin.HandlerFunc {
 			return "ip:" + c.ClientIP()
 		},
 		LimitHandler: DefaultRateLimitConfig.LimitHandler,
+		Prefix:       "api",
 	}
 
 	return RateLimitWithConfig(config)
@@ -178,81 +359,12 @@ func EndpointRateLimit(maxRequests int, window time.Duration) gin.HandlerFunc {
 			return c.ClientIP() + ":" + c.FullPath()
 		},
 		LimitHandler: DefaultRateLimitConfig.LimitHandler,
+		Prefix:       "endpoint",
 	}
 
 	return RateLimitWithConfig(config)
 }
 
-// SlidingWindowRateLimiter 滑动窗口频率限制器
-type SlidingWindowRateLimiter struct {
-	config    RateLimitConfig
-	requests  map[string][]time.Time
-	mu        sync.RWMutex
-}
-
-// NewSlidingWindowRateLimiter 创建滑动窗口频率限制器
-func NewSlidingWindowRateLimiter(config RateLimitConfig) *SlidingWindowRateLimiter {
-	rl := &SlidingWindowRateLimiter{
-		config:   config,
-		requests: make(map[string][]time.Time),
-	}
-
-	go rl.cleanup()
-
-	return rl
-}
-
-// cleanup 清理过期请求记录
-func (rl *SlidingWindowRateLimiter) cleanup() {
-	ticker := time.NewTicker(rl.config.Window / 2)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		rl.mu.Lock()
-		now := time.Now()
-		for key, times := range rl.requests {
-			var valid []time.Time
-			for _, t := range times {
-				if now.Sub(t) <= rl.config.Window {
-					valid = append(valid, t)
-				}
-			}
-			if len(valid) == 0 {
-				delete(rl.requests, key)
-			} else {
-				rl.requests[key] = valid
-			}
-		}
-		rl.mu.Unlock()
-	}
-}
-
-// Allow 检查是否允许请求（滑动窗口）
-func (rl *SlidingWindowRateLimiter) Allow(key string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	now := time.Now()
-	times := rl.requests[key]
-
-	// 过滤掉窗口外的请求
-	var valid []time.Time
-	for _, t := range times {
-		if now.Sub(t) <= rl.config.Window {
-			valid = append(valid, t)
-		}
-	}
-
-	if len(valid) >= rl.config.MaxRequests {
-		rl.requests[key] = valid
-		return false
-	}
-
-	valid = append(valid, now)
-	rl.requests[key] = valid
-	return true
-}
-
 // SlidingWindowRateLimit 滑动窗口频率限制中间件
 func SlidingWindowRateLimit(maxRequests int, window time.Duration) gin.HandlerFunc {
 	config := RateLimitConfig{
@@ -260,18 +372,68 @@ func SlidingWindowRateLimit(maxRequests int, window time.Duration) gin.HandlerFu
 		MaxRequests:  maxRequests,
 		KeyFunc:      DefaultRateLimitConfig.KeyFunc,
 		LimitHandler: DefaultRateLimitConfig.LimitHandler,
+		Prefix:       "sliding",
+		Algorithm:    AlgorithmSlidingLog,
 	}
 
-	limiter := NewSlidingWindowRateLimiter(config)
+	return RateLimitWithConfig(config)
+}
 
-	return func(c *gin.Context) {
-		key := config.KeyFunc(c)
+// routeRateLimiter 路由规则对应的限流器
+type routeRateLimiter struct {
+	rule    config.RateLimitRule
+	limiter *RateLimiter
+}
 
-		if !limiter.Allow(key) {
-			config.LimitHandler(c)
+// RouteRateLimit 按路由规则的频率限制中间件（按顺序匹配，命中第一条规则），规则的时间窗口或限额无效时返回错误。
+// base 提供 KeyFunc、LimitHandler、Store、Algorithm 等公共配置，Window/MaxRequests 由规则覆盖
+func RouteRateLimit(rules []config.RateLimitRule, base RateLimitConfig) (gin.HandlerFunc, error) {
+	limiters := make([]routeRateLimiter, 0, len(rules))
+	for _, rule := range rules {
+		ruleConfig := base
+		ruleConfig.Window = rule.Window
+		ruleConfig.MaxRequests = rule.MaxRequests
+		ruleConfig.Prefix = "route:" + rule.Method + ":" + rule.Path
+		ruleConfig.EnforceAfter = rule.EnforceAfter
+		limiter, err := NewRateLimiter(ruleConfig)
+		if err != nil {
+			return nil, err
+		}
+		limiters = append(limiters, routeRateLimiter{rule: rule, limiter: limiter})
+	}
+
+	exemption := newRateLimitExemption(base)
+
+	return func(c *gin.Context) {
+		if exemption.match(c) {
+			c.Next()
 			return
 		}
 
+		path := c.Request.URL.Path
+		for _, rl := range limiters {
+			if rl.rule.Method != "" && rl.rule.Method != c.Request.Method {
+				continue
+			}
+			if !matchPath(rl.rule.Path, path) {
+				continue
+			}
+
+			result, err := rl.limiter.TakeContext(c.Request.Context(), base.KeyFunc(c))
+			if err != nil {
+				c.Abort()
+				return
+			}
+			result = softLimit(c, rl.limiter.config, result)
+			if !result.Allowed {
+				setRateLimitHeaders(c, result)
+				RecordAbuse(c.Request.Context(), AbuseIP(c), AbuseRateLimited)
+				base.LimitHandler(c)
+				return
+			}
+			break
+		}
+
 		c.Next()
-	}
+	}, nil
 }
@@ -0,0 +1,398 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/store"
+)
+
+// 限流算法
+const (
+	// AlgorithmFixedWindow 固定窗口（窗口边界处可能出现两倍突发）
+	AlgorithmFixedWindow = "fixed_window"
+	// AlgorithmSlidingLog 滑动日志（精确，但每个 Key 需保存窗口内全部请求时间）
+	AlgorithmSlidingLog = "sliding_log"
+	// AlgorithmTokenBucket 令牌桶（允许 Burst 大小的突发，长期速率为 MaxRequests/Window）
+	AlgorithmTokenBucket = "token_bucket"
+	// AlgorithmLeakyBucket 漏桶（请求排队匀速放行，队列满时拒绝）
+	AlgorithmLeakyBucket = "leaky_bucket"
+)
+
+// rateLimitAlgorithm 限流算法接口
+type rateLimitAlgorithm interface {
+	// take 消耗一次额度
+	take(ctx context.Context, key string, now time.Time) (RateLimitResult, error)
+	// peek 查询当前状态
+	peek(ctx context.Context, key string, now time.Time) RateLimitResult
+}
+
+// newRateLimitAlgorithm 按配置创建限流算法
+func newRateLimitAlgorithm(rl *RateLimiter) rateLimitAlgorithm {
+	capacity := rl.config.Burst
+	if capacity <= 0 {
+		capacity = rl.config.MaxRequests
+	}
+
+	switch rl.config.Algorithm {
+	case AlgorithmFixedWindow, "":
+		return &fixedWindow{rl: rl}
+	case AlgorithmSlidingLog:
+		return &slidingLog{rl: rl}
+	case AlgorithmTokenBucket:
+		return &tokenBucket{rl: rl, capacity: capacity}
+	case AlgorithmLeakyBucket:
+		return &leakyBucket{rl: rl, capacity: capacity}
+	default:
+		log.Printf("⚠️  未知的限流算法 %q，使用固定窗口", rl.config.Algorithm)
+		return &fixedWindow{rl: rl}
+	}
+}
+
+// interval 每个请求对应的平均间隔（Window / MaxRequests）
+func (rl *RateLimiter) interval() time.Duration {
+	if rl.config.MaxRequests <= 0 {
+		return rl.config.Window
+	}
+	return rl.config.Window / time.Duration(rl.config.MaxRequests)
+}
+
+// fixedWindow 固定窗口算法
+type fixedWindow struct {
+	rl *RateLimiter
+}
+
+// reset 获取窗口结束时间
+func (a *fixedWindow) reset(ctx context.Context, key string, now time.Time) time.Time {
+	ttl, err := a.rl.store.TTL(ctx, key)
+	if err != nil || ttl < 0 {
+		return now.Add(a.rl.config.Window)
+	}
+	return now.Add(ttl)
+}
+
+func (a *fixedWindow) take(ctx context.Context, key string, now time.Time) (RateLimitResult, error) {
+	// 窗口内首次请求时同时设置过期时间
+	count, err := a.rl.store.IncrWithTTL(ctx, key, a.rl.config.Window)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	reset := now.Add(a.rl.config.Window)
+	if count > 1 {
+		reset = a.reset(ctx, key, now)
+	}
+
+	result := RateLimitResult{
+		Allowed:   count <= int64(a.rl.config.MaxRequests),
+		Limit:     a.rl.config.MaxRequests,
+		Remaining: a.rl.config.MaxRequests - int(count),
+		Reset:     reset,
+	}
+	if !result.Allowed {
+		result.RetryAfter = reset.Sub(now)
+	}
+	return result, nil
+}
+
+func (a *fixedWindow) peek(ctx context.Context, key string, now time.Time) RateLimitResult {
+	result := RateLimitResult{Allowed: true, Limit: a.rl.config.MaxRequests, Remaining: a.rl.config.MaxRequests}
+
+	value, err := a.rl.store.Get(ctx, key)
+	if err != nil {
+		return result
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil {
+		return result
+	}
+
+	result.Remaining = a.rl.config.MaxRequests - count
+	result.Allowed = result.Remaining > 0
+	result.Reset = a.reset(ctx, key, now)
+	return result
+}
+
+// 以下算法需要"读取-计算-写回"，通过进程内锁保证单实例内的原子性；
+// 多实例共享 Redis 时同一 Key 的并发请求可能出现少量误差。
+
+// lockShards 锁分片数
+const lockShards = 64
+
+// keyLocks 按 Key 哈希分片的锁，不同 Key 的请求大概率落在不同分片上，避免单把锁成为瓶颈
+type keyLocks [lockShards]sync.Mutex
+
+// of 获取 Key 对应的锁
+func (l *keyLocks) of(key string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &l[h.Sum32()%lockShards]
+}
+
+// slidingLog 滑动日志算法（状态：逗号分隔的请求时间戳）
+type slidingLog struct {
+	rl    *RateLimiter
+	locks keyLocks
+}
+
+// load 读取窗口内的请求时间（升序）
+func (a *slidingLog) load(ctx context.Context, key string, now time.Time) ([]int64, error) {
+	value, err := a.rl.store.Get(ctx, key)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := now.Add(-a.rl.config.Window).UnixNano()
+	var times []int64
+	for _, part := range strings.Split(value, ",") {
+		t, err := strconv.ParseInt(part, 10, 64)
+		if err == nil && t > cutoff {
+			times = append(times, t)
+		}
+	}
+	return times, nil
+}
+
+// result 根据窗口内的请求时间计算状态
+func (a *slidingLog) result(times []int64, now time.Time) RateLimitResult {
+	result := RateLimitResult{
+		Allowed:   len(times) < a.rl.config.MaxRequests,
+		Limit:     a.rl.config.MaxRequests,
+		Remaining: a.rl.config.MaxRequests - len(times),
+		Reset:     now,
+	}
+	if len(times) > 0 {
+		// 最新一条请求滑出窗口时额度完全恢复
+		result.Reset = time.Unix(0, times[len(times)-1]).Add(a.rl.config.Window)
+		if !result.Allowed {
+			// 最早一条请求滑出窗口时可以重试
+			result.RetryAfter = time.Unix(0, times[0]).Add(a.rl.config.Window).Sub(now)
+		}
+	}
+	return result
+}
+
+func (a *slidingLog) take(ctx context.Context, key string, now time.Time) (RateLimitResult, error) {
+	mu := a.locks.of(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	times, err := a.load(ctx, key, now)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	if len(times) >= a.rl.config.MaxRequests {
+		return a.result(times, now), nil
+	}
+
+	times = append(times, now.UnixNano())
+	parts := make([]string, len(times))
+	for i, t := range times {
+		parts[i] = strconv.FormatInt(t, 10)
+	}
+	if err := a.rl.store.Set(ctx, key, strings.Join(parts, ","), a.rl.config.Window); err != nil {
+		return RateLimitResult{}, err
+	}
+
+	result := a.result(times, now)
+	result.Allowed = true
+	return result, nil
+}
+
+func (a *slidingLog) peek(ctx context.Context, key string, now time.Time) RateLimitResult {
+	times, err := a.load(ctx, key, now)
+	if err != nil {
+		return RateLimitResult{Allowed: true, Limit: a.rl.config.MaxRequests, Remaining: a.rl.config.MaxRequests}
+	}
+	return a.result(times, now)
+}
+
+// tokenBucket 令牌桶算法（状态：剩余令牌|上次补充时间）
+type tokenBucket struct {
+	rl       *RateLimiter
+	capacity int
+	locks    keyLocks
+}
+
+// load 读取并补充令牌
+func (a *tokenBucket) load(ctx context.Context, key string, now time.Time) (float64, error) {
+	value, err := a.rl.store.Get(ctx, key)
+	if errors.Is(err, store.ErrNotFound) {
+		return float64(a.capacity), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var tokens float64
+	var last int64
+	if _, err := fmt.Sscanf(value, "%g|%d", &tokens, &last); err != nil {
+		return float64(a.capacity), nil
+	}
+
+	// 按 MaxRequests/Window 的速率补充令牌
+	elapsed := now.Sub(time.Unix(0, last))
+	tokens += elapsed.Seconds() / a.rl.interval().Seconds()
+	if tokens > float64(a.capacity) {
+		tokens = float64(a.capacity)
+	}
+	return tokens, nil
+}
+
+// result 根据剩余令牌计算状态
+func (a *tokenBucket) result(tokens float64, now time.Time) RateLimitResult {
+	interval := a.rl.interval()
+	result := RateLimitResult{
+		Allowed:   tokens >= 1,
+		Limit:     a.capacity,
+		Remaining: int(tokens),
+		// 桶被填满的时间
+		Reset: now.Add(time.Duration((float64(a.capacity) - tokens) * float64(interval))),
+	}
+	if !result.Allowed {
+		result.RetryAfter = time.Duration((1 - tokens) * float64(interval))
+	}
+	return result
+}
+
+func (a *tokenBucket) take(ctx context.Context, key string, now time.Time) (RateLimitResult, error) {
+	mu := a.locks.of(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	tokens, err := a.load(ctx, key, now)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+
+	// 桶满所需时间之后状态即可丢弃
+	ttl := time.Duration(a.capacity)*a.rl.interval() + time.Second
+	value := fmt.Sprintf("%g|%d", tokens, now.UnixNano())
+	if err := a.rl.store.Set(ctx, key, value, ttl); err != nil {
+		return RateLimitResult{}, err
+	}
+
+	result := a.result(tokens, now)
+	result.Allowed = allowed
+	if allowed {
+		result.RetryAfter = 0
+	}
+	return result, nil
+}
+
+func (a *tokenBucket) peek(ctx context.Context, key string, now time.Time) RateLimitResult {
+	tokens, err := a.load(ctx, key, now)
+	if err != nil {
+		tokens = float64(a.capacity)
+	}
+	return a.result(tokens, now)
+}
+
+// leakyBucket 漏桶算法（状态：队列中最后一个请求的放行时间）
+type leakyBucket struct {
+	rl       *RateLimiter
+	capacity int
+	locks    keyLocks
+}
+
+// load 读取下一个可放行时间
+func (a *leakyBucket) load(ctx context.Context, key string, now time.Time) (time.Time, error) {
+	value, err := a.rl.store.Get(ctx, key)
+	if errors.Is(err, store.ErrNotFound) {
+		return now, nil
+	}
+	if err != nil {
+		return now, err
+	}
+
+	next, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || time.Unix(0, next).Before(now) {
+		return now, nil
+	}
+	return time.Unix(0, next), nil
+}
+
+// result 根据队列状态计算限流状态
+func (a *leakyBucket) result(next, now time.Time) RateLimitResult {
+	interval := a.rl.interval()
+	queued := int(next.Sub(now) / interval)
+	result := RateLimitResult{
+		Allowed:   queued < a.capacity,
+		Limit:     a.capacity,
+		Remaining: a.capacity - queued,
+		// 队列排空的时间
+		Reset: next,
+	}
+	if !result.Allowed {
+		// 队列中腾出一个位置所需时间
+		result.RetryAfter = next.Sub(now) - time.Duration(a.capacity-1)*interval
+	}
+	return result
+}
+
+func (a *leakyBucket) take(ctx context.Context, key string, now time.Time) (RateLimitResult, error) {
+	mu := a.locks.of(key)
+	mu.Lock()
+	next, err := a.load(ctx, key, now)
+	if err != nil {
+		mu.Unlock()
+		return RateLimitResult{}, err
+	}
+
+	// 排队中的请求数超过队列长度则拒绝
+	interval := a.rl.interval()
+	wait := next.Sub(now)
+	if wait >= time.Duration(a.capacity)*interval {
+		mu.Unlock()
+		result := a.result(next, now)
+		result.Allowed = false
+		return result, nil
+	}
+
+	next = next.Add(interval)
+	err = a.rl.store.Set(ctx, key, strconv.FormatInt(next.UnixNano(), 10), next.Sub(now)+time.Second)
+	mu.Unlock()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	// 等待轮到该请求，实现匀速放行
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return RateLimitResult{}, ctx.Err()
+		}
+	}
+
+	result := a.result(next, time.Now())
+	result.Allowed = true
+	result.RetryAfter = 0
+	return result, nil
+}
+
+func (a *leakyBucket) peek(ctx context.Context, key string, now time.Time) RateLimitResult {
+	next, err := a.load(ctx, key, now)
+	if err != nil {
+		next = now
+	}
+	return a.result(next, now)
+}
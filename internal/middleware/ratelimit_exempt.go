@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	adminmiddleware "new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exemptAppKeyContextKey 本次请求按 AppKey 签名获得的豁免结果（全局与按路由的限流器共用，同一请求只判断一次）
+const exemptAppKeyContextKey = "rate_limit_exempt_app_key"
+
+// rateLimitExemption 频率限制豁免规则
+type rateLimitExemption struct {
+	nets    []*net.IPNet
+	appKeys map[string]bool
+	roles   map[string]bool
+}
+
+// newRateLimitExemption 根据配置构建豁免规则，未配置任何豁免时返回 nil
+func newRateLimitExemption(config RateLimitConfig) *rateLimitExemption {
+	if len(config.ExemptCIDRs) == 0 && len(config.ExemptAppKeys) == 0 && len(config.ExemptRoles) == 0 {
+		return nil
+	}
+
+	e := &rateLimitExemption{
+		nets:    parseNets(config.ExemptCIDRs),
+		appKeys: make(map[string]bool),
+		roles:   make(map[string]bool),
+	}
+	for _, appKey := range config.ExemptAppKeys {
+		e.appKeys[strings.TrimSpace(appKey)] = true
+	}
+	for _, role := range config.ExemptRoles {
+		e.roles[strings.TrimSpace(role)] = true
+	}
+	return e
+}
+
+// match 检查请求是否豁免频率限制
+func (e *rateLimitExemption) match(c *gin.Context) bool {
+	if e == nil {
+		return false
+	}
+
+	if len(e.nets) > 0 {
+		if ip := net.ParseIP(c.ClientIP()); ip != nil {
+			for _, ipNet := range e.nets {
+				if ipNet.Contains(ip) {
+					return true
+				}
+			}
+		}
+	}
+
+	if len(e.appKeys) > 0 && e.signedAppKey(c) {
+		return true
+	}
+
+	if len(e.roles) > 0 {
+		if role := requestRole(c); role != "" && e.roles[role] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// signedAppKey 请求是否由豁免名单中的 AppKey 签名。签名中间件验证后写入上下文的 AppKey 直接使用；
+// 全局限流在签名验证之前执行，此时按请求头或查询参数中的 AppKey 查找密钥，校验时间戳、nonce 与签名，
+// 只声明 AppKey 而签名无效的请求照常限流。同一 (AppKey, nonce) 只豁免一次：重放的签名请求
+// 在未挂载签名中间件的路由上同样计入限流（单独记录，不占用签名中间件的 nonce）
+func (e *rateLimitExemption) signedAppKey(c *gin.Context) bool {
+	if appKey := c.GetString("app_key"); appKey != "" {
+		return e.appKeys[appKey]
+	}
+	if exempt, exists := c.Get(exemptAppKeyContextKey); exists {
+		return exempt.(bool)
+	}
+	exempt := e.verifySignedAppKey(c)
+	c.Set(exemptAppKeyContextKey, exempt)
+	return exempt
+}
+
+// verifySignedAppKey 校验签名并登记 (AppKey, nonce)，首次出现时返回 true
+func (e *rateLimitExemption) verifySignedAppKey(c *gin.Context) bool {
+	config := CurrentSignatureConfig()
+	params := readSignatureParams(c, config)
+	if !e.appKeys[params.appKey] || params.signature == "" || params.nonce == "" ||
+		params.checkTimestamp(config, time.Now()) != "" {
+		return false
+	}
+	secretKeys, err := resolveSecretKeys(c, config, params.appKey)
+	if err != nil {
+		return false
+	}
+	sign, missing, err := requestSigner(c, config, params)
+	if missing != "" || err != nil || !signatureMatches(params.signature, sign, secretKeys) {
+		return false
+	}
+
+	nonceStore := config.NonceStore
+	if nonceStore == nil {
+		nonceStore = store.For(store.ComponentNonce)
+	}
+	first, err := nonceStore.SetNX(c.Request.Context(), "ratelimit-exempt:"+params.appKey+":"+params.nonce,
+		params.timestamp, config.Expiry+config.TimeTolerance)
+	return err == nil && first
+}
+
+// 验签结果缓存：全局限流与过载保护在认证中间件之前执行，每个请求都要判断角色，
+// 同一 Token 在缓存时间内不重复验签（RS256/ES256 验签开销较大，过载时尤其明显）
+const (
+	roleCacheTTL  = 30 * time.Second
+	roleCacheSize = 10000
+)
+
+// roleCacheEntry 验签通过的 Token 对应的角色
+type roleCacheEntry struct {
+	role     string
+	expireAt time.Time
+}
+
+// roleCache 按 Token 的 SHA-256 缓存验签通过的角色（验签失败不缓存）。缓存期内已吊销的 Token 仍会取得角色，
+// 只影响限流豁免与过载分级，接口鉴权仍由认证中间件完成
+var roleCache = struct {
+	sync.Mutex
+	entries map[[sha256.Size]byte]roleCacheEntry
+}{entries: make(map[[sha256.Size]byte]roleCacheEntry)}
+
+// requestRole 获取请求的角色（优先使用已认证的上下文，其次是验签结果缓存，最后校验 Bearer Token）
+func requestRole(c *gin.Context) string {
+	if role := c.GetString("role"); role != "" {
+		return role
+	}
+	if claims, exists := c.Get("admin_claims"); exists {
+		if adminClaims, ok := claims.(*adminmiddleware.Claims); ok {
+			return adminClaims.Role
+		}
+	}
+
+	parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return ""
+	}
+
+	now := time.Now()
+	key := sha256.Sum256([]byte(parts[1]))
+	roleCache.Lock()
+	entry, ok := roleCache.entries[key]
+	roleCache.Unlock()
+	if ok && now.Before(entry.expireAt) {
+		return entry.role
+	}
+
+	role, expireAt := "", now.Add(roleCacheTTL)
+	if claims, err := ParseTokenWithConfig(parts[1], CurrentJWTConfig()); err == nil {
+		role = claims.Role
+		if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(expireAt) {
+			expireAt = claims.ExpiresAt.Time
+		}
+	} else if claims, err := adminmiddleware.ParseToken(parts[1]); err == nil {
+		role = claims.Role
+		if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(expireAt) {
+			expireAt = claims.ExpiresAt.Time
+		}
+	}
+	if role == "" {
+		return ""
+	}
+
+	roleCache.Lock()
+	if len(roleCache.entries) >= roleCacheSize {
+		// 超出容量时整体清空（条目最多保留 roleCacheTTL，清空后重新验签即可）
+		roleCache.entries = make(map[[sha256.Size]byte]roleCacheEntry)
+	}
+	roleCache.entries[key] = roleCacheEntry{role: role, expireAt: expireAt}
+	roleCache.Unlock()
+	return role
+}
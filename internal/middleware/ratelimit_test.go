@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"new-openclaw/internal/store"
+)
+
+// TestNewRateLimiterInvalidWindow 时间窗口不为正或平均间隔为 0 的配置被拒绝（令牌桶、漏桶按该间隔做除法）
+func TestNewRateLimiterInvalidWindow(t *testing.T) {
+	cases := []struct {
+		name        string
+		window      time.Duration
+		maxRequests int
+	}{
+		{"zero window", 0, 10},
+		{"negative window", -time.Second, 10},
+		{"sub-nanosecond interval", 5 * time.Nanosecond, 10},
+	}
+	for _, tc := range cases {
+		for _, algorithm := range []string{AlgorithmFixedWindow, AlgorithmSlidingLog, AlgorithmTokenBucket, AlgorithmLeakyBucket} {
+			_, err := NewRateLimiter(RateLimitConfig{
+				Window:      tc.window,
+				MaxRequests: tc.maxRequests,
+				Algorithm:   algorithm,
+				Store:       store.NewMemoryStore(),
+			})
+			if err == nil {
+				t.Errorf("%s/%s: 期望返回错误", tc.name, algorithm)
+			}
+		}
+	}
+
+	if _, err := NewRateLimiter(RateLimitConfig{Window: 10 * time.Nanosecond, MaxRequests: 10, Store: store.NewMemoryStore()}); err != nil {
+		t.Errorf("间隔为 1ns 的配置应当有效: %v", err)
+	}
+}
+
+// TestLeakyBucketCanceled 漏桶排队期间请求取消时返回拒绝与 ctx.Err()，而不是放行
+func TestLeakyBucketCanceled(t *testing.T) {
+	rl, err := NewRateLimiter(RateLimitConfig{
+		Window:      time.Minute,
+		MaxRequests: 1,
+		Burst:       5,
+		Algorithm:   AlgorithmLeakyBucket,
+		Prefix:      "test",
+		Store:       store.NewMemoryStore(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一个请求立即放行，第二个请求需要排队一分钟
+	if result, err := rl.TakeContext(context.Background(), "k"); err != nil || !result.Allowed {
+		t.Fatalf("第一个请求: %+v, %v", result, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	result, err := rl.TakeContext(ctx, "k")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v，期望 context.DeadlineExceeded", err)
+	}
+	if result.Allowed {
+		t.Fatal("取消的请求不应放行")
+	}
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// 热点 Key 的统计周期与每个周期最多跟踪的 Key 数（超出后新 Key 不再计入，避免被大量随机 Key 撑爆内存）
+const (
+	hotKeyPeriod  = 5 * time.Minute
+	hotKeyMaxKeys = 10000
+)
+
+// RateLimitHotKey 近期被频率限制拒绝次数较多的 Key（本实例统计）
+type RateLimitHotKey struct {
+	// 限流器（RateLimitConfig.Prefix，如 global、route:POST:/api/v1/public/login）
+	Limiter  string    `json:"limiter"`
+	Key      string    `json:"key"`
+	Rejected int64     `json:"rejected"`
+	LastAt   time.Time `json:"last_at"`
+}
+
+type hotKeyID struct {
+	limiter string
+	key     string
+}
+
+type hotKeyCount struct {
+	rejected int64
+	lastAt   time.Time
+}
+
+// hotKeyTracker 按周期轮换的两组计数，查询时合并，覆盖最近一到两个周期
+type hotKeyTracker struct {
+	mu        sync.Mutex
+	current   map[hotKeyID]*hotKeyCount
+	previous  map[hotKeyID]*hotKeyCount
+	rotatedAt time.Time
+}
+
+var rateLimitHotKeys = &hotKeyTracker{current: make(map[hotKeyID]*hotKeyCount)}
+
+// record 记录一次拒绝
+func (t *hotKeyTracker) record(limiter, key string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rotate(now)
+
+	id := hotKeyID{limiter: limiter, key: key}
+	count, ok := t.current[id]
+	if !ok {
+		if len(t.current) >= hotKeyMaxKeys {
+			return
+		}
+		count = &hotKeyCount{}
+		t.current[id] = count
+	}
+	count.rejected++
+	count.lastAt = now
+}
+
+// rotate 跨过周期时轮换（调用方持有锁）
+func (t *hotKeyTracker) rotate(now time.Time) {
+	if t.rotatedAt.IsZero() {
+		t.rotatedAt = now
+		return
+	}
+	if now.Sub(t.rotatedAt) < hotKeyPeriod {
+		return
+	}
+	if now.Sub(t.rotatedAt) >= 2*hotKeyPeriod {
+		t.previous = nil
+	} else {
+		t.previous = t.current
+	}
+	t.current = make(map[hotKeyID]*hotKeyCount)
+	t.rotatedAt = now
+}
+
+// top 拒绝次数最多的 n 个 Key
+func (t *hotKeyTracker) top(n int, now time.Time) []RateLimitHotKey {
+	t.mu.Lock()
+	t.rotate(now)
+	merged := make(map[hotKeyID]RateLimitHotKey, len(t.current)+len(t.previous))
+	for _, counts := range []map[hotKeyID]*hotKeyCount{t.previous, t.current} {
+		for id, count := range counts {
+			hot := merged[id]
+			hot.Limiter, hot.Key = id.limiter, id.key
+			hot.Rejected += count.rejected
+			if count.lastAt.After(hot.LastAt) {
+				hot.LastAt = count.lastAt
+			}
+			merged[id] = hot
+		}
+	}
+	t.mu.Unlock()
+
+	keys := make([]RateLimitHotKey, 0, len(merged))
+	for _, hot := range merged {
+		keys = append(keys, hot)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Rejected != keys[j].Rejected {
+			return keys[i].Rejected > keys[j].Rejected
+		}
+		return keys[i].LastAt.After(keys[j].LastAt)
+	})
+	if n > 0 && len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+// RateLimitHotKeys 最近 5～10 分钟内被频率限制拒绝次数最多的 n 个 Key（n 为 0 时返回全部）
+func RateLimitHotKeys(n int) []RateLimitHotKey {
+	return rateLimitHotKeys.top(n, time.Now())
+}
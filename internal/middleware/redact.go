@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 脱敏方式
+const (
+	// RedactMask 部分遮盖（邮箱保留首字符与域名，IP 保留网段，其他保留首尾）
+	RedactMask = "mask"
+	// RedactHide 整体隐藏
+	RedactHide = "hide"
+)
+
+// PIIReadScope 查看未脱敏字段所需的权限范围
+const PIIReadScope = "pii:read"
+
+// hiddenValue 隐藏后的字段值
+const hiddenValue = "***"
+
+// RedactionConfig 响应脱敏配置
+type RedactionConfig struct {
+	// JSON 字段名 → 脱敏方式（mask/hide），在响应的任意层级匹配
+	Fields map[string]string
+	// 拥有该权限范围的调用方看到原值
+	Scope string
+}
+
+// DefaultRedactionConfig 默认脱敏配置
+var DefaultRedactionConfig = RedactionConfig{
+	Fields: map[string]string{
+		"email":         RedactMask,
+		"phone":         RedactMask,
+		"last_login_ip": RedactMask,
+	},
+	Scope: PIIReadScope,
+}
+
+// Redact 响应脱敏中间件（使用默认配置）
+func Redact() gin.HandlerFunc {
+	return RedactWithConfig(DefaultRedactionConfig)
+}
+
+// RedactWithConfig 响应脱敏中间件：调用方缺少 pii:read 时，统一在写出 JSON 响应前遮盖敏感字段，
+// 不需要各接口单独处理。须放在认证中间件之后（依赖其设置的权限范围）
+func RedactWithConfig(config RedactionConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(config.Fields) == 0 {
+			c.Next()
+			return
+		}
+		if granted, ok := c.Get("scopes"); ok {
+			if scopes, _ := granted.([]string); HasScope(scopes, config.Scope) {
+				c.Next()
+				return
+			}
+		}
+
+		original := c.Writer
+		buffered := &bufferedWriter{ResponseWriter: original}
+		c.Writer = buffered
+		c.Next()
+		c.Writer = original
+
+		body := buffered.body.Bytes()
+		if len(body) == 0 {
+			return
+		}
+		if strings.HasPrefix(original.Header().Get("Content-Type"), "application/json") {
+			if redacted, changed := redactJSON(body, config.Fields); changed {
+				body = redacted
+			}
+		}
+		original.Write(body)
+	}
+}
+
+// bufferedWriter 暂存响应体，脱敏后再写出
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// redactJSON 遮盖 JSON 中的敏感字段，没有命中的字段时返回原内容
+func redactJSON(body []byte, fields map[string]string) ([]byte, bool) {
+	// 响应中不含任何敏感字段名时跳过解析
+	found := false
+	for name := range fields {
+		if bytes.Contains(body, []byte(`"`+name+`"`)) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return body, false
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return body, false
+	}
+	if !redactValue(value, fields) {
+		return body, false
+	}
+	redacted, err := json.Marshal(value)
+	if err != nil {
+		return body, false
+	}
+	return redacted, true
+}
+
+// redactValue 递归处理对象与数组，返回是否有字段被修改
+func redactValue(value interface{}, fields map[string]string) bool {
+	changed := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			mode, sensitive := fields[key]
+			if !sensitive {
+				if redactValue(item, fields) {
+					changed = true
+				}
+				continue
+			}
+			if item == nil {
+				continue
+			}
+			s, isString := item.(string)
+			switch {
+			case s == "" && isString:
+				continue
+			case mode == RedactMask && isString:
+				v[key] = maskValue(s)
+			default:
+				v[key] = hiddenValue
+			}
+			changed = true
+		}
+	case []interface{}:
+		for _, item := range v {
+			if redactValue(item, fields) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// maskValue 按内容部分遮盖：邮箱 a***@example.com，IPv4 203.0.113.*，IPv6 保留 /48 网段，其他如手机号 138****5678
+func maskValue(s string) string {
+	if at := strings.LastIndex(s, "@"); at > 0 {
+		return string([]rune(s)[:1]) + hiddenValue + s[at:]
+	}
+	if ip := net.ParseIP(s); ip != nil {
+		if v4 := ip.To4(); v4 != nil {
+			addr := v4.String()
+			return addr[:strings.LastIndex(addr, ".")+1] + "*"
+		}
+		return ip.Mask(net.CIDRMask(48, 128)).String() + "*"
+	}
+
+	runes := []rune(s)
+	switch {
+	case len(runes) >= 8:
+		return string(runes[:3]) + strings.Repeat("*", len(runes)-7) + string(runes[len(runes)-4:])
+	case len(runes) > 2:
+		return string(runes[:1]) + strings.Repeat("*", len(runes)-2) + string(runes[len(runes)-1:])
+	default:
+		return hiddenValue
+	}
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+
+	"new-openclaw/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+var requestValidationFailures = metrics.NewCounterVec(
+	"request_validation_failures_total", "Content-Type 或 JSON Schema 校验未通过的请求数", "route", "reason")
+
+// RequestValidationConfig 请求格式校验配置
+type RequestValidationConfig struct {
+	// 带请求体的请求允许的 Content-Type（媒体类型，不含 charset 等参数），为空不检查
+	ContentTypes []string
+	// 按路由配置允许的 Content-Type（覆盖 ContentTypes），key 为 "METHOD /path" 或 "/path"，路径支持 * 后缀通配
+	RouteContentTypes map[string][]string
+	// 按路由配置的请求体 JSON Schema（key 同上，只校验 application/json 请求），须已调用 Compile
+	Schemas map[string]*JSONSchema
+	// 最多返回的校验错误数（0 不限制）
+	MaxErrors int
+}
+
+// RequestValidation 请求格式校验中间件：在处理函数之前拒绝 Content-Type 不符合路由要求的请求（415），
+// 并按路由的 JSON Schema 校验请求体，未通过时返回逐字段的错误（400），而不是参数绑定的原始错误信息。
+// 按匹配到的路由（c.FullPath()）查找配置，应在请求体大小限制之后注册
+func RequestValidation(config RequestValidationConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" || !hasBody(c.Request) {
+			c.Next()
+			return
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		allowed, ok := lookupRoute(config.RouteContentTypes, c.Request.Method, route)
+		if !ok {
+			allowed = config.ContentTypes
+		}
+		if len(allowed) > 0 && !containsFold(allowed, mediaType) {
+			requestValidationFailures.Inc(route, "content_type")
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{
+				"code":    415,
+				"message": "不支持的 Content-Type",
+				"data":    gin.H{"allowed": allowed},
+			})
+			c.Abort()
+			return
+		}
+
+		schema, ok := lookupRoute(config.Schemas, c.Request.Method, route)
+		if !ok || mediaType != "application/json" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		restoreBody(c, body, err)
+		if err != nil {
+			if AbortIfBodyTooLarge(c, err) {
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "读取请求体失败",
+			})
+			c.Abort()
+			return
+		}
+
+		value, err := decodeJSONBody(body)
+		if err != nil {
+			rejectInvalidRequest(c, route, "json", []SchemaError{{Rule: "json", Message: "请求体不是有效的 JSON"}})
+			return
+		}
+		if errs := schema.Validate(value, config.MaxErrors); len(errs) > 0 {
+			rejectInvalidRequest(c, route, "schema", errs)
+			return
+		}
+		c.Next()
+	}
+}
+
+// LoadRequestSchemas 读取 Schema 文件（JSON 对象，key 为 "METHOD /path"，value 为 JSON Schema）并编译
+func LoadRequestSchemas(path string) (map[string]*JSONSchema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取请求 Schema 文件失败: %w", err)
+	}
+	var schemas map[string]*JSONSchema
+	if err := json.Unmarshal(data, &schemas); err != nil {
+		return nil, fmt.Errorf("解析请求 Schema 文件失败: %w", err)
+	}
+	for route, schema := range schemas {
+		if schema == nil {
+			return nil, fmt.Errorf("%s: Schema 为空", route)
+		}
+		if err := schema.Compile(); err != nil {
+			return nil, fmt.Errorf("%s: %w", route, err)
+		}
+	}
+	return schemas, nil
+}
+
+func rejectInvalidRequest(c *gin.Context, route, reason string, errs []SchemaError) {
+	requestValidationFailures.Inc(route, reason)
+	c.JSON(http.StatusBadRequest, gin.H{
+		"code":    400,
+		"message": "请求参数校验失败",
+		"data":    gin.H{"errors": errs},
+	})
+	c.Abort()
+}
+
+// decodeJSONBody 解码请求体（数字保留为 json.Number），请求体须为单个 JSON 值
+func decodeJSONBody(body []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("请求体包含多个 JSON 值")
+	}
+	return value, nil
+}
+
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
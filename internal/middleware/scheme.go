@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// schemeContextKey 请求协议在 Context 中的 key
+const schemeContextKey = "scheme"
+
+// SchemeConfig 协议识别配置
+type SchemeConfig struct {
+	// 受信任的代理（IP 或 CIDR），只有来自这些地址的 X-Forwarded-Proto 才会被采信
+	TrustedProxies []string
+	// 规范 Base URL（如 https://api.example.com），HTTPS 重定向的目标主机
+	BaseURL string
+	// 是否将 HTTP 请求重定向到 HTTPS
+	HTTPSRedirect bool
+}
+
+// ForwardedProto 识别请求的真实协议（支持 TLS 终止代理）
+func ForwardedProto(config SchemeConfig) gin.HandlerFunc {
+	trusted := parseNets(config.TrustedProxies)
+	baseURL := strings.TrimRight(config.BaseURL, "/")
+
+	return func(c *gin.Context) {
+		scheme := "http"
+		if c.Request.TLS != nil {
+			scheme = "https"
+		} else if isTrustedProxy(c.Request.RemoteAddr, trusted) {
+			if proto := forwardedProto(c.Request); proto == "https" || proto == "http" {
+				scheme = proto
+			}
+		}
+
+		c.Set(schemeContextKey, scheme)
+
+		if config.HTTPSRedirect && scheme != "https" {
+			target := "https://" + c.Request.Host + c.Request.URL.RequestURI()
+			if baseURL != "" {
+				target = "https://" + strings.TrimPrefix(strings.TrimPrefix(baseURL, "https://"), "http://") + c.Request.URL.RequestURI()
+			}
+			c.Redirect(http.StatusMovedPermanently, target)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// forwardedProto 从代理头中读取协议
+func forwardedProto(r *http.Request) string {
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		// 多级代理时取第一个（最初的客户端协议）
+		return strings.ToLower(strings.TrimSpace(strings.Split(proto, ",")[0]))
+	}
+	// RFC 7239 Forwarded: for=...;proto=https
+	if fwd := r.Header.Get("Forwarded"); fwd != "" {
+		first := strings.Split(fwd, ",")[0]
+		for _, pair := range strings.Split(first, ";") {
+			kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(kv) == 2 && strings.EqualFold(kv[0], "proto") {
+				return strings.ToLower(strings.Trim(kv[1], `"`))
+			}
+		}
+	}
+	return ""
+}
+
+// parseNets 解析 IP/CIDR 列表
+func parseNets(items []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			if ip := net.ParseIP(item); ip != nil && ip.To4() != nil {
+				item += "/32"
+			} else {
+				item += "/128"
+			}
+		}
+		if _, ipNet, err := net.ParseCIDR(item); err == nil {
+			nets = append(nets, ipNet)
+		}
+	}
+	return nets
+}
+
+// isTrustedProxy 检查直连地址是否为受信任的代理
+func isTrustedProxy(remoteAddr string, trusted []*net.IPNet) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestScheme 获取请求协议（http/https）
+func RequestScheme(c *gin.Context) string {
+	if scheme := c.GetString(schemeContextKey); scheme != "" {
+		return scheme
+	}
+	if c.Request.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// IsHTTPS 检查请求是否通过 HTTPS 访问
+func IsHTTPS(c *gin.Context) bool {
+	return RequestScheme(c) == "https"
+}
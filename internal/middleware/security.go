@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -40,11 +41,12 @@ type SecurityMiddleware struct {
 // NewSecurityMiddleware 创建安全中间件
 func NewSecurityMiddleware(config SecurityConfig) *SecurityMiddleware {
 	auditLogger, _ := NewAuditLogger(config.Audit)
+	rateLimiter, _ := NewRateLimiter(config.RateLimit)
 
 	return &SecurityMiddleware{
 		config:      config,
 		ipFilter:    NewDynamicIPFilter(config.IPFilter),
-		rateLimiter: NewRateLimiter(config.RateLimit),
+		rateLimiter: rateLimiter,
 		auditLogger: auditLogger,
 	}
 }
@@ -63,15 +65,16 @@ func (sm *SecurityMiddleware) Apply(r *gin.RouterGroup) {
 
 // ApplyJWT 应用 JWT 认证
 func (sm *SecurityMiddleware) ApplyJWT(r *gin.RouterGroup) {
-	r.Use(JWTAuthWithConfig(sm.config.JWT))
+	r.Use(NewJWTAuth(sm.config.JWT))
 }
 
 // ApplySignature 应用 API 签名验证
 func (sm *SecurityMiddleware) ApplySignature(r *gin.RouterGroup) {
-	r.Use(APISignatureWithConfig(sm.config.Signature))
+	r.Use(NewSignature(sm.config.Signature))
 }
 
-// GetIPFilter 获取 IP 过滤器（用于动态管理）
+// GetIPFilter 获取 IP 过滤器（用于动态管理）。Apply 挂载的就是该实例，
+// 需将它传给 iprules.Bind 等，管理接口添加的规则才会作用于这些路由
 func (sm *SecurityMiddleware) GetIPFilter() *DynamicIPFilter {
 	return sm.ipFilter
 }
@@ -96,8 +99,10 @@ func SecureHeaders() gin.HandlerFunc {
 		c.Header("Referrer-Policy", "strict-origin-when-cross-origin")
 		// 内容安全策略
 		c.Header("Content-Security-Policy", "default-src 'self'")
-		// HSTS（仅 HTTPS）
-		c.Header("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+		// HSTS（仅 HTTPS，明文 HTTP 下浏览器会忽略且可能误导代理）
+		if IsHTTPS(c) {
+			c.Header("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+		}
 		// 权限策略
 		c.Header("Permissions-Policy", "geolocation=(), microphone=(), camera=()")
 
@@ -147,14 +152,25 @@ func Recovery() gin.HandlerFunc {
 	}
 }
 
-// APIKeyAuth API Key 认证中间件
-func APIKeyAuth(validKeys map[string]string) gin.HandlerFunc {
+// APIKeyIdentity 通过认证的 API Key
+type APIKeyIdentity struct {
+	ID uint
+	// Key 名称（写入 app_name，供日志、审计识别调用方）
+	Name string
+	// Key 的展示前缀（不含完整 Key）
+	Prefix string
+	Scopes []string
+}
+
+// APIKeyResolver 校验 API Key（由 apikey 模块提供：查库并缓存，记录最近使用时间），
+// 返回的错误信息直接作为 401 响应的 message
+var APIKeyResolver func(ctx context.Context, key, clientIP string) (*APIKeyIdentity, error)
+
+// APIKeyAuth API Key 认证中间件：校验 X-API-Key 请求头，并写入 Key 的权限范围（可配合 RequireScope 使用）。
+// 不接受查询参数中的 Key，避免完整 Key 进入访问日志与代理日志
+func APIKeyAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		apiKey := c.GetHeader("X-API-Key")
-		if apiKey == "" {
-			apiKey = c.Query("api_key")
-		}
-
 		if apiKey == "" {
 			c.JSON(401, gin.H{
 				"code":    401,
@@ -163,22 +179,43 @@ func APIKeyAuth(validKeys map[string]string) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		if APIKeyResolver == nil {
+			c.JSON(401, gin.H{
+				"code":    401,
+				"message": "API Key 认证未启用",
+			})
+			c.Abort()
+			return
+		}
 
-		appName, valid := validKeys[apiKey]
-		if !valid {
+		identity, err := APIKeyResolver(c.Request.Context(), apiKey, AbuseIP(c))
+		if err != nil {
+			ReportAuthFailure(c, AuthFailureAPIKey, apiKeyDisplay(apiKey), err.Error())
+			RecordAbuse(c.Request.Context(), AbuseIP(c), AbuseAPIKeyFailure)
 			c.JSON(401, gin.H{
 				"code":    401,
-				"message": "无效的 API Key",
+				"message": err.Error(),
 			})
 			c.Abort()
 			return
 		}
 
-		c.Set("app_name", appName)
+		c.Set("app_name", identity.Name)
+		c.Set("api_key", identity.Prefix)
+		c.Set("api_key_id", identity.ID)
+		c.Set("scopes", identity.Scopes)
 		c.Next()
 	}
 }
 
+// apiKeyDisplay 认证失败事件中记录的 Key（只保留前几位）
+func apiKeyDisplay(key string) string {
+	if len(key) > 11 {
+		return key[:11] + "..."
+	}
+	return key
+}
+
 // BasicAuth 基本认证中间件
 func BasicAuth(accounts gin.Accounts) gin.HandlerFunc {
 	return gin.BasicAuth(accounts)
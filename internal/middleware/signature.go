@@ -2,18 +2,21 @@ package middleware
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
-	"crypto/md5"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"new-openclaw/internal/store"
+	"new-openclaw/pkg/signclient"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -23,7 +26,7 @@ type SignatureConfig struct {
 	SecretKey string
 	// 签名有效期（防止重放攻击）
 	Expiry time.Duration
-	// 签名算法：hmac-sha256, md5
+	// 签名算法：hmac-sha256, md5, v2（规范请求 + 请求头签名，见 signature_v2.go）
 	Algorithm string
 	// 时间戳容差（允许的时间偏差）
 	TimeTolerance time.Duration
@@ -37,9 +40,19 @@ type SignatureConfig struct {
 	AppKeyParam string
 	// 是否验证 Body
 	ValidateBody bool
+	// v2 必须签名的请求头（小写，如 content-type、host）
+	SignedHeaders []string
+	// Nonce 存储（为空时使用 nonce 组件配置的存储）
+	NonceStore store.Store
+	// 按 AppKey 查找签名密钥（可返回多个，如轮换宽限期内的新旧密钥）；
+	// 为空或返回空列表时使用 SecretKey，返回错误时拒绝请求
+	SecretResolver func(ctx context.Context, appKey, clientIP string) ([]string, error)
 }
 
-// DefaultSignatureConfig 默认签名配置
+// DefaultSignatureConfig 默认签名配置（未调用 ConfigureSignature 时使用）
+//
+// Deprecated: 启动后直接修改该变量与 Webhook 重放等后台任务的读取存在数据竞争，
+// 使用 ConfigureSignature 设置全局配置，或以 NewSignature 为路由单独指定配置
 var DefaultSignatureConfig = SignatureConfig{
 	SecretKey:      "your-api-secret-key",
 	Expiry:         time.Minute * 5,
@@ -50,39 +63,53 @@ var DefaultSignatureConfig = SignatureConfig{
 	NonceParam:     "nonce",
 	AppKeyParam:    "app_key",
 	ValidateBody:   true,
+	SignedHeaders:  []string{"content-type"},
+}
+
+// signatureConfig ConfigureSignature 设置的配置快照
+var signatureConfig atomic.Pointer[SignatureConfig]
+
+// ConfigureSignature 设置全局签名配置（保存副本，已创建的签名中间件不受影响）
+func ConfigureSignature(config SignatureConfig) {
+	config = config.clone()
+	signatureConfig.Store(&config)
+}
+
+// CurrentSignatureConfig 当前生效的全局签名配置（返回副本，修改不影响全局配置）
+func CurrentSignatureConfig() SignatureConfig {
+	if config := signatureConfig.Load(); config != nil {
+		return config.clone()
+	}
+	return DefaultSignatureConfig.clone()
 }
 
-// nonceStore 用于存储已使用的 nonce（防重放）
-var nonceStore = make(map[string]time.Time)
+// clone 复制配置（SignedHeaders 为切片，单独复制）
+func (config SignatureConfig) clone() SignatureConfig {
+	config.SignedHeaders = append([]string(nil), config.SignedHeaders...)
+	return config
+}
 
-// APISignature API 签名验证中间件
+// APISignature API 签名验证中间件（使用创建时的全局配置）
 func APISignature() gin.HandlerFunc {
-	return APISignatureWithConfig(DefaultSignatureConfig)
+	return NewSignature(CurrentSignatureConfig())
+}
+
+// NewSignature 创建 API 签名验证中间件，绑定传入配置的快照（调用方之后修改 SignedHeaders 也不影响）
+func NewSignature(config SignatureConfig) gin.HandlerFunc {
+	return APISignatureWithConfig(config.clone())
 }
 
 // APISignatureWithConfig 带配置的 API 签名验证中间件
 func APISignatureWithConfig(config SignatureConfig) gin.HandlerFunc {
+	nonceStore := config.NonceStore
+	if nonceStore == nil {
+		nonceStore = store.For(store.ComponentNonce)
+	}
+
 	return func(c *gin.Context) {
 		// 获取签名参数
-		signature := c.GetHeader("X-Signature")
-		if signature == "" {
-			signature = c.Query(config.SignatureParam)
-		}
-
-		timestamp := c.GetHeader("X-Timestamp")
-		if timestamp == "" {
-			timestamp = c.Query(config.TimestampParam)
-		}
-
-		nonce := c.GetHeader("X-Nonce")
-		if nonce == "" {
-			nonce = c.Query(config.NonceParam)
-		}
-
-		appKey := c.GetHeader("X-App-Key")
-		if appKey == "" {
-			appKey = c.Query(config.AppKeyParam)
-		}
+		params := readSignatureParams(c, config)
+		signature, timestamp, nonce, appKey := params.signature, params.timestamp, params.nonce, params.appKey
 
 		// 验证必要参数
 		if signature == "" || timestamp == "" {
@@ -94,25 +121,24 @@ func APISignatureWithConfig(config SignatureConfig) gin.HandlerFunc {
 			return
 		}
 
-		// 验证时间戳
-		ts, err := strconv.ParseInt(timestamp, 10, 64)
-		if err != nil {
+		// 检查时间戳是否在有效范围内
+		if message := params.checkTimestamp(config, time.Now()); message != "" {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"code":    400,
-				"message": "无效的时间戳",
+				"message": message,
 			})
 			c.Abort()
 			return
 		}
 
-		requestTime := time.Unix(ts, 0)
-		now := time.Now()
-
-		// 检查时间戳是否在有效范围内
-		if now.Sub(requestTime) > config.Expiry || requestTime.Sub(now) > config.TimeTolerance {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"code":    400,
-				"message": "请求已过期",
+		// 查找 AppKey 的签名密钥（在消耗 nonce 之前，无效的 AppKey 不占用 nonce）
+		secretKeys, err := resolveSecretKeys(c, config, appKey)
+		if err != nil {
+			RecordAbuse(c.Request.Context(), AbuseIP(c), AbuseSignatureFailure)
+			ReportAuthFailure(c, AuthFailureSignature, appKey, err.Error())
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": err.Error(),
 			})
 			c.Abort()
 			return
@@ -120,7 +146,17 @@ func APISignatureWithConfig(config SignatureConfig) gin.HandlerFunc {
 
 		// 检查 nonce 是否已使用（防重放攻击）
 		if nonce != "" {
-			if _, exists := nonceStore[nonce]; exists {
+			// nonce 在签名有效期内保留，过期后由存储自动清理
+			ok, err := nonceStore.SetNX(c.Request.Context(), nonce, timestamp, config.Expiry+config.TimeTolerance)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"code":    500,
+					"message": "签名校验服务不可用",
+				})
+				c.Abort()
+				return
+			}
+			if !ok {
 				c.JSON(http.StatusBadRequest, gin.H{
 					"code":    400,
 					"message": "重复的请求",
@@ -128,19 +164,34 @@ func APISignatureWithConfig(config SignatureConfig) gin.HandlerFunc {
 				c.Abort()
 				return
 			}
-			nonceStore[nonce] = now
-			// 清理过期的 nonce
-			go cleanupNonce(config.Expiry)
 		}
 
 		// 构建签名字符串
-		signString := buildSignString(c, config, timestamp, nonce, appKey)
-
-		// 计算签名
-		expectedSign := calculateSignature(signString, config.SecretKey, config.Algorithm)
+		sign, missing, err := requestSigner(c, config, params)
+		if missing != "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "签名未包含必须签名的请求头: " + missing,
+			})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			if AbortIfBodyTooLarge(c, err) {
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "读取请求体失败",
+			})
+			c.Abort()
+			return
+		}
 
-		// 验证签名
-		if !hmac.Equal([]byte(signature), []byte(expectedSign)) {
+		// 计算并验证签名（任一密钥匹配即通过）
+		if !signatureMatches(signature, sign, secretKeys) {
+			RecordAbuse(c.Request.Context(), AbuseIP(c), AbuseSignatureFailure)
+			ReportAuthFailure(c, AuthFailureSignature, appKey, "签名验证失败")
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
 				"message": "签名验证失败",
@@ -149,98 +200,150 @@ func APISignatureWithConfig(config SignatureConfig) gin.HandlerFunc {
 			return
 		}
 
+		// 签名已覆盖 appKey，可供后续中间件（配额、限流豁免）信任
+		if appKey != "" {
+			c.Set("app_key", appKey)
+		}
+
 		c.Next()
 	}
 }
 
-// buildSignString 构建签名字符串
-func buildSignString(c *gin.Context, config SignatureConfig, timestamp, nonce, appKey string) string {
-	var parts []string
+// signatureParams 请求携带的签名参数（请求头优先，其次为查询参数）
+type signatureParams struct {
+	signature string
+	timestamp string
+	nonce     string
+	appKey    string
+}
 
-	// 添加请求方法
-	parts = append(parts, c.Request.Method)
+// readSignatureParams 读取请求的签名参数
+func readSignatureParams(c *gin.Context, config SignatureConfig) signatureParams {
+	param := func(header, query string) string {
+		if value := c.GetHeader(header); value != "" {
+			return value
+		}
+		return c.Query(query)
+	}
+	return signatureParams{
+		signature: param("X-Signature", config.SignatureParam),
+		timestamp: param("X-Timestamp", config.TimestampParam),
+		nonce:     param("X-Nonce", config.NonceParam),
+		appKey:    param("X-App-Key", config.AppKeyParam),
+	}
+}
 
-	// 添加请求路径
-	parts = append(parts, c.Request.URL.Path)
+// checkTimestamp 检查时间戳是否在有效范围内，无效时返回错误提示
+func (p signatureParams) checkTimestamp(config SignatureConfig, now time.Time) string {
+	ts, err := strconv.ParseInt(p.timestamp, 10, 64)
+	if err != nil {
+		return "无效的时间戳"
+	}
+	requestTime := time.Unix(ts, 0)
+	if now.Sub(requestTime) > config.Expiry || requestTime.Sub(now) > config.TimeTolerance {
+		return "请求已过期"
+	}
+	return ""
+}
 
-	// 添加排序后的查询参数
-	queryParams := c.Request.URL.Query()
-	var queryKeys []string
-	for key := range queryParams {
-		// 排除签名相关参数
-		if key != config.SignatureParam && key != config.TimestampParam && 
-		   key != config.NonceParam && key != config.AppKeyParam {
-			queryKeys = append(queryKeys, key)
+// resolveSecretKeys 查找 AppKey 的签名密钥（未配置 SecretResolver 或返回空列表时使用 SecretKey）
+func resolveSecretKeys(c *gin.Context, config SignatureConfig, appKey string) ([]string, error) {
+	if config.SecretResolver != nil {
+		keys, err := config.SecretResolver(c.Request.Context(), appKey, c.ClientIP())
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) > 0 {
+			return keys, nil
 		}
 	}
-	sort.Strings(queryKeys)
+	return []string{config.SecretKey}, nil
+}
 
-	for _, key := range queryKeys {
-		values := queryParams[key]
-		sort.Strings(values)
-		for _, value := range values {
-			parts = append(parts, fmt.Sprintf("%s=%s", key, value))
+// requestSigner 按请求构建签名字符串，返回用密钥计算签名的函数；
+// v2 签名缺少必须签名的请求头时返回该请求头，读取请求体失败时返回错误
+func requestSigner(c *gin.Context, config SignatureConfig, p signatureParams) (func(secretKey string) string, string, error) {
+	if signatureV2Requested(config, c.Request) {
+		signedHeaders, missing := signedHeadersV2(config, c.Request)
+		if missing != "" {
+			return nil, missing, nil
+		}
+		payloadHash, err := bodyHashV2(config, c.Request)
+		if err != nil {
+			return nil, "", err
 		}
+		canonical := canonicalRequestV2(config, c.Request.Method, c.Request.URL.Path, c.Request.URL.Query(),
+			c.Request.Header, c.Request.Host, signedHeaders, payloadHash)
+		stringToSign := stringToSignV2(p.timestamp, p.nonce, p.appKey, canonical)
+		return func(secretKey string) string { return signV2(stringToSign, secretKey) }, "", nil
 	}
 
-	// 添加时间戳
-	parts = append(parts, timestamp)
-
-	// 添加 nonce
-	if nonce != "" {
-		parts = append(parts, nonce)
+	signString, err := buildSignString(c, config, p.timestamp, p.nonce, p.appKey)
+	if err != nil {
+		return nil, "", err
 	}
+	return func(secretKey string) string { return calculateSignature(signString, secretKey, config.Algorithm) }, "", nil
+}
 
-	// 添加 appKey
-	if appKey != "" {
-		parts = append(parts, appKey)
+// signatureMatches 任一密钥计算出的签名与请求的签名一致
+func signatureMatches(signature string, sign func(secretKey string) string, secretKeys []string) bool {
+	for _, secretKey := range secretKeys {
+		if hmac.Equal([]byte(signature), []byte(sign(secretKey))) {
+			return true
+		}
 	}
+	return false
+}
 
+// buildSignString 构建签名字符串
+func buildSignString(c *gin.Context, config SignatureConfig, timestamp, nonce, appKey string) (string, error) {
 	// 添加请求体（如果需要）
+	var body []byte
 	if config.ValidateBody && c.Request.Body != nil {
 		bodyBytes, err := io.ReadAll(c.Request.Body)
-		if err == nil && len(bodyBytes) > 0 {
-			parts = append(parts, string(bodyBytes))
-			// 重新设置 Body，以便后续处理
-			c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+		if err != nil {
+			return "", err
 		}
+		body = bodyBytes
+		// 重新设置 Body，以便后续处理
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 	}
 
-	return strings.Join(parts, "&")
+	return signString(config, c.Request.Method, c.Request.URL.Path, c.Request.URL.Query(), timestamp, nonce, appKey, body), nil
 }
 
-// calculateSignature 计算签名
-func calculateSignature(data, secretKey, algorithm string) string {
-	switch algorithm {
-	case "hmac-sha256":
-		h := hmac.New(sha256.New, []byte(secretKey))
-		h.Write([]byte(data))
-		return hex.EncodeToString(h.Sum(nil))
-	case "md5":
-		h := md5.New()
-		h.Write([]byte(data + secretKey))
-		return hex.EncodeToString(h.Sum(nil))
-	default:
-		h := hmac.New(sha256.New, []byte(secretKey))
-		h.Write([]byte(data))
-		return hex.EncodeToString(h.Sum(nil))
-	}
+// signString 按 方法&路径&排序后的查询参数&时间戳&nonce&appKey&请求体 拼接签名字符串
+func signString(config SignatureConfig, method, path string, queryParams url.Values, timestamp, nonce, appKey string, body []byte) string {
+	return signclient.StringToSign(config.ClientConfig(), method, path, queryParams, timestamp, nonce, appKey, body)
 }
 
-// cleanupNonce 清理过期的 nonce
-func cleanupNonce(expiry time.Duration) {
-	now := time.Now()
-	for key, t := range nonceStore {
-		if now.Sub(t) > expiry {
-			delete(nonceStore, key)
-		}
+// SignRequest 使用指定密钥为请求签名，设置 X-App-Key、X-Timestamp、X-Nonce、X-Signature 请求头（服务端调用其他环境时使用）
+func SignRequest(req *http.Request, body []byte, appKey, secretKey string, config SignatureConfig) {
+	signclient.SignRequest(req, body, appKey, secretKey, config.ClientConfig())
+}
+
+// ClientConfig 转换为签名客户端配置
+func (c SignatureConfig) ClientConfig() signclient.Config {
+	return signclient.Config{
+		Algorithm:      c.Algorithm,
+		SignatureParam: c.SignatureParam,
+		TimestampParam: c.TimestampParam,
+		NonceParam:     c.NonceParam,
+		AppKeyParam:    c.AppKeyParam,
+		ValidateBody:   c.ValidateBody,
+		SignedHeaders:  c.SignedHeaders,
 	}
 }
 
+// calculateSignature 计算签名
+func calculateSignature(data, secretKey, algorithm string) string {
+	return signclient.Sign(data, secretKey, algorithm)
+}
+
 // GenerateSignature 生成签名（供客户端使用）
 func GenerateSignature(method, path string, params map[string]string, body string, secretKey string) (string, string, string) {
 	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
-	nonce := generateNonce()
+	nonce := signclient.NewNonce()
 
 	var parts []string
 	parts = append(parts, method)
@@ -270,13 +373,6 @@ func GenerateSignature(method, path string, params map[string]string, body strin
 	return signature, timestamp, nonce
 }
 
-// generateNonce 生成随机 nonce
-func generateNonce() string {
-	h := md5.New()
-	h.Write([]byte(strconv.FormatInt(time.Now().UnixNano(), 10)))
-	return hex.EncodeToString(h.Sum(nil))[:16]
-}
-
 // SimpleSignature 简单签名验证（仅验证 AppKey + Secret）
 func SimpleSignature(appKeys map[string]string) gin.HandlerFunc {
 	return func(c *gin.Context) {
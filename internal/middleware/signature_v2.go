@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"new-openclaw/pkg/signclient"
+)
+
+// 签名 v2 算法说明见 pkg/signclient/v2.go
+const (
+	// SignatureAlgorithmV2 签名 v2 算法名
+	SignatureAlgorithmV2 = signclient.AlgorithmV2
+
+	unsignedPayload = signclient.UnsignedPayload
+)
+
+// signatureV2Requested 是否按 v2 验签：配置为 v2，或请求携带 X-Signed-Headers（v1 配置下允许调用方逐个升级）
+func signatureV2Requested(config SignatureConfig, r *http.Request) bool {
+	return config.Algorithm == SignatureAlgorithmV2 || r.Header.Get("X-Signed-Headers") != ""
+}
+
+// signedHeadersV2 解析请求声明的签名请求头（未声明时使用配置的必签请求头），返回缺少的必签请求头
+func signedHeadersV2(config SignatureConfig, r *http.Request) ([]string, string) {
+	var headers []string
+	if declared := r.Header.Get("X-Signed-Headers"); declared != "" {
+		for _, name := range strings.Split(declared, ";") {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				headers = append(headers, name)
+			}
+		}
+	} else {
+		for _, name := range config.SignedHeaders {
+			headers = append(headers, strings.ToLower(name))
+		}
+	}
+
+	signed := make(map[string]bool, len(headers))
+	for _, name := range headers {
+		signed[name] = true
+	}
+	for _, name := range config.SignedHeaders {
+		if !signed[strings.ToLower(name)] {
+			return nil, strings.ToLower(name)
+		}
+	}
+	return headers, ""
+}
+
+// bodyHashV2 计算请求体 SHA-256 并重新设置 Body 以便后续处理
+func bodyHashV2(config SignatureConfig, r *http.Request) (string, error) {
+	if !config.ValidateBody {
+		return unsignedPayload, nil
+	}
+	h := sha256.New()
+	if r.Body != nil {
+		var buf bytes.Buffer
+		if _, err := io.Copy(io.MultiWriter(h, &buf), r.Body); err != nil {
+			return "", err
+		}
+		r.Body = io.NopCloser(&buf)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// canonicalRequestV2 构建规范请求
+func canonicalRequestV2(config SignatureConfig, method, path string, query url.Values, header http.Header, host string, signedHeaders []string, payloadHash string) string {
+	return signclient.CanonicalRequest(config.ClientConfig(), method, path, query, header, host, signedHeaders, payloadHash)
+}
+
+// stringToSignV2 构建待签名字符串
+func stringToSignV2(timestamp, nonce, appKey, canonicalRequest string) string {
+	return signclient.StringToSignV2(timestamp, nonce, appKey, canonicalRequest)
+}
+
+// signV2 计算 v2 签名
+func signV2(stringToSign, secretKey string) string {
+	return signclient.SignV2(stringToSign, secretKey)
+}
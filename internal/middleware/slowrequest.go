@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// slowThresholdKey 当前请求的慢请求阈值
+	slowThresholdKey = "slow_threshold"
+	// slowCollectorKey 慢请求详情收集器
+	slowCollectorKey = "slow_collector"
+	// slowRequestKey 请求是否被标记为慢请求
+	slowRequestKey = "slow_request"
+)
+
+var slowRequestsTotal = metrics.NewCounterVec(
+	"http_slow_requests_total", "超过延迟阈值的慢请求数", "method", "route")
+
+// SlowRequestConfig 慢请求检测配置
+type SlowRequestConfig struct {
+	// 默认阈值（<= 0 表示不检测未单独配置的路由）
+	Threshold time.Duration
+	// 按路由配置的阈值，key 为 "METHOD /path" 或 "/path"，路径支持 * 后缀通配
+	RouteThresholds map[string]time.Duration
+	// 是否采集扩展详情（SQL 查询、中间件耗时）
+	CaptureDetail bool
+}
+
+// MiddlewareTiming 中间件耗时（包含其后续处理链）
+type MiddlewareTiming struct {
+	Name     string `json:"name"`
+	OffsetMs int64  `json:"offset_ms"`
+	TotalMs  int64  `json:"total_ms"`
+}
+
+// slowCollector 请求级详情收集器
+type slowCollector struct {
+	start    time.Time
+	recorder *database.QueryRecorder
+	timings  []MiddlewareTiming
+	mu       sync.Mutex
+}
+
+// SlowRequest 慢请求检测中间件（应尽量靠前注册，以便统计后续中间件耗时）
+func SlowRequest(config SlowRequestConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		threshold := slowThreshold(config, c.Request.Method, c.FullPath())
+		if threshold <= 0 {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Set(slowThresholdKey, threshold)
+
+		var collector *slowCollector
+		if config.CaptureDetail {
+			collector = &slowCollector{start: start}
+			ctx, recorder := database.WithQueryRecorder(c.Request.Context())
+			collector.recorder = recorder
+			c.Request = c.Request.WithContext(ctx)
+			c.Set(slowCollectorKey, collector)
+		}
+
+		c.Next()
+
+		latency := time.Since(start)
+		if latency < threshold {
+			return
+		}
+
+		c.Set(slowRequestKey, true)
+		slowRequestsTotal.Inc(c.Request.Method, routeLabel(c))
+		log.Printf("[SLOW REQUEST] %s %s %v (阈值 %v) request_id=%s",
+			c.Request.Method, c.Request.URL.Path, latency, threshold, c.GetString("request_id"))
+	}
+}
+
+// slowThreshold 获取路由对应的慢请求阈值
+func slowThreshold(config SlowRequestConfig, method, route string) time.Duration {
+	if threshold, ok := config.RouteThresholds[method+" "+route]; ok {
+		return threshold
+	}
+	if threshold, ok := config.RouteThresholds[route]; ok {
+		return threshold
+	}
+
+	// 通配规则取最长匹配
+	best, bestLen := config.Threshold, -1
+	for pattern, threshold := range config.RouteThresholds {
+		p := pattern
+		if i := strings.Index(p, " "); i > 0 {
+			if p[:i] != method {
+				continue
+			}
+			p = p[i+1:]
+		}
+		if matchPath(p, route) && len(p) > bestLen {
+			best, bestLen = threshold, len(p)
+		}
+	}
+	return best
+}
+
+// matchPath 路径匹配（支持末尾 * 通配）
+func matchPath(pattern, path string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(path, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == path
+}
+
+// Timed 记录中间件耗时（仅在开启慢请求详情采集时生效）
+func Timed(name string, h gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get(slowCollectorKey)
+		if !exists {
+			h(c)
+			return
+		}
+
+		collector := value.(*slowCollector)
+		start := time.Now()
+
+		collector.mu.Lock()
+		index := len(collector.timings)
+		collector.timings = append(collector.timings, MiddlewareTiming{
+			Name:     name,
+			OffsetMs: start.Sub(collector.start).Milliseconds(),
+		})
+		collector.mu.Unlock()
+
+		h(c)
+		// 中间件未显式调用 c.Next() 时由 gin 继续执行后续处理链
+		if !c.IsAborted() {
+			c.Next()
+		}
+
+		collector.mu.Lock()
+		collector.timings[index].TotalMs = time.Since(start).Milliseconds()
+		collector.mu.Unlock()
+	}
+}
+
+// isSlowRequest 根据阈值判断请求是否为慢请求
+func isSlowRequest(c *gin.Context, latency time.Duration) bool {
+	if c.GetBool(slowRequestKey) {
+		return true
+	}
+	value, exists := c.Get(slowThresholdKey)
+	if !exists {
+		return false
+	}
+	return latency >= value.(time.Duration)
+}
+
+// slowRequestDetail 获取慢请求扩展详情
+func slowRequestDetail(c *gin.Context) map[string]interface{} {
+	value, exists := c.Get(slowCollectorKey)
+	if !exists {
+		return nil
+	}
+
+	collector := value.(*slowCollector)
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+
+	return map[string]interface{}{
+		"middleware_timings": append([]MiddlewareTiming(nil), collector.timings...),
+		"queries":            collector.recorder.Queries(),
+	}
+}
@@ -0,0 +1,454 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SecurityReasonsKey 可疑请求的检测结果在 Context 中的 Key
+const SecurityReasonsKey = "security_reasons"
+
+// SecurityAlert 检测到攻击特征的请求
+type SecurityAlert struct {
+	IP        string
+	Method    string
+	Path      string
+	UserAgent string
+	RequestID string
+	Reasons   []string
+}
+
+// OnSecurityAlert 检测到攻击特征时的处理（如写入安全事件订阅），为空不处理
+var OnSecurityAlert func(ctx context.Context, alert SecurityAlert)
+
+// 内置规则的检测结果
+const (
+	ReasonSQLInjection  = "可能的 SQL 注入"
+	ReasonXSS           = "可能的 XSS 攻击"
+	ReasonPathTraversal = "可能的路径遍历"
+)
+
+// WAF 规则动作
+const (
+	// WAFActionLog 只记录（安全告警、滥用评分、安全事件订阅）
+	WAFActionLog = "log"
+	// WAFActionBlock 记录并返回 403
+	WAFActionBlock = "block"
+	// WAFActionBan 记录、返回 403 并立即封禁来源 IP
+	WAFActionBan = "ban"
+)
+
+// WAF 规则检查的位置；header 检查全部请求头（每行 "Name: value"），header:Name 只检查指定请求头
+const (
+	WAFTargetPath   = "path"
+	WAFTargetQuery  = "query"
+	WAFTargetBody   = "body"
+	WAFTargetHeader = "header"
+)
+
+// WAF 规则来源
+const (
+	WAFSourceBuiltin = "builtin"
+	WAFSourceFile    = "file"
+	WAFSourceDB      = "db"
+)
+
+// WAFRule 一条 WAF 规则
+type WAFRule struct {
+	// 规则名（日志、指标与审计备注中使用）
+	Name string `json:"name"`
+	// 正则表达式（Go RE2 语法，不区分大小写用 (?i)）
+	Pattern string `json:"pattern"`
+	// 检查位置：path、query、body、header、header:Name
+	Targets []string `json:"targets"`
+	// 命中后的动作：log、block、ban
+	Action string `json:"action"`
+	// 告警中的检测结果（为空时使用规则名）
+	Reason string `json:"reason,omitempty"`
+	// 来源：builtin、file、db
+	Source string `json:"source,omitempty"`
+}
+
+// WAFConfig WAF 配置
+type WAFConfig struct {
+	// 请求体最多检查的字节数（之后的内容不检查），0 不检查请求体
+	MaxBodySize int64
+	// ban 动作的封禁处理（如 iprules.AutoBan），为空时 ban 按 block 处理
+	Ban func(ctx context.Context, ip, rule string)
+	// 拦截时的响应（为空时返回 403）
+	BlockHandler gin.HandlerFunc
+}
+
+// DefaultWAFConfig 默认 WAF 配置
+var DefaultWAFConfig = WAFConfig{
+	MaxBodySize: 64 << 10,
+}
+
+// 内置规则的特征（按子串匹配，不区分大小写）
+var (
+	sqlInjectionPatterns = []string{
+		"'--", "' OR ", "' AND ", "UNION SELECT", "DROP TABLE",
+		"INSERT INTO", "DELETE FROM", "UPDATE SET", "1=1", "1'='1",
+	}
+	xssPatterns = []string{
+		"<script", "javascript:", "onerror=", "onload=", "onclick=",
+		"<iframe", "<object", "<embed", "expression(",
+	}
+	pathTraversalPatterns = []string{
+		"../", "..\\", "%2e%2e", "%252e%252e",
+	}
+)
+
+var wafMatchesTotal = metrics.NewCounterVec("waf_matches_total", "命中 WAF 规则的请求数", "rule", "action")
+
+// BuiltinWAFRules 内置规则：查询字符串中的 SQL 注入、XSS 特征，路径中的路径遍历特征
+func BuiltinWAFRules(action string) []WAFRule {
+	return []WAFRule{
+		{Name: "builtin:sql_injection", Pattern: substringPattern(sqlInjectionPatterns), Targets: []string{WAFTargetQuery}, Action: action, Reason: ReasonSQLInjection, Source: WAFSourceBuiltin},
+		{Name: "builtin:xss", Pattern: substringPattern(xssPatterns), Targets: []string{WAFTargetQuery}, Action: action, Reason: ReasonXSS, Source: WAFSourceBuiltin},
+		{Name: "builtin:path_traversal", Pattern: substringPattern(pathTraversalPatterns), Targets: []string{WAFTargetPath}, Action: action, Reason: ReasonPathTraversal, Source: WAFSourceBuiltin},
+	}
+}
+
+func substringPattern(patterns []string) string {
+	quoted := make([]string, len(patterns))
+	for i, p := range patterns {
+		quoted[i] = regexp.QuoteMeta(p)
+	}
+	return "(?i)(?:" + strings.Join(quoted, "|") + ")"
+}
+
+// compiledWAFRule 编译后的规则
+type compiledWAFRule struct {
+	WAFRule
+	re         *regexp.Regexp
+	path       bool
+	query      bool
+	body       bool
+	allHeaders bool
+	headers    []string
+}
+
+// ValidateWAFRule 检查规则的正则表达式、检查位置与动作
+func ValidateWAFRule(rule WAFRule) error {
+	_, err := compileWAFRule(rule)
+	return err
+}
+
+func compileWAFRule(rule WAFRule) (*compiledWAFRule, error) {
+	if strings.TrimSpace(rule.Name) == "" {
+		return nil, fmt.Errorf("规则名不能为空")
+	}
+	switch rule.Action {
+	case WAFActionLog, WAFActionBlock, WAFActionBan:
+	default:
+		return nil, fmt.Errorf("规则 %s: 无效的动作 %q（可选 log、block、ban）", rule.Name, rule.Action)
+	}
+	re, err := regexp.Compile(rule.Pattern)
+	if err != nil || rule.Pattern == "" {
+		return nil, fmt.Errorf("规则 %s: 无效的正则表达式 %q", rule.Name, rule.Pattern)
+	}
+
+	compiled := &compiledWAFRule{WAFRule: rule, re: re}
+	for _, target := range rule.Targets {
+		target = strings.TrimSpace(target)
+		switch {
+		case target == WAFTargetPath:
+			compiled.path = true
+		case target == WAFTargetQuery:
+			compiled.query = true
+		case target == WAFTargetBody:
+			compiled.body = true
+		case target == WAFTargetHeader:
+			compiled.allHeaders = true
+		case strings.HasPrefix(target, WAFTargetHeader+":") && len(target) > len(WAFTargetHeader)+1:
+			compiled.headers = append(compiled.headers, http.CanonicalHeaderKey(target[len(WAFTargetHeader)+1:]))
+		default:
+			return nil, fmt.Errorf("规则 %s: 无效的检查位置 %q（可选 path、query、body、header、header:Name）", rule.Name, target)
+		}
+	}
+	if !compiled.path && !compiled.query && !compiled.body && !compiled.allHeaders && len(compiled.headers) == 0 {
+		return nil, fmt.Errorf("规则 %s: 缺少检查位置", rule.Name)
+	}
+	if compiled.Reason == "" {
+		compiled.Reason = rule.Name
+	}
+	return compiled, nil
+}
+
+// wafInput 待检查的请求内容（URL 编码的内容同时检查解码后的结果，避免编码绕过）
+type wafInput struct {
+	path    string
+	query   string
+	body    string
+	header  http.Header
+	headers string
+}
+
+// allHeaders 全部请求头，每行 "Name: value"（首次使用时拼接）
+func (in *wafInput) allHeaders() string {
+	if in.headers == "" && len(in.header) > 0 {
+		var b strings.Builder
+		for name, values := range in.header {
+			for _, value := range values {
+				b.WriteString(name)
+				b.WriteString(": ")
+				b.WriteString(value)
+				b.WriteByte('\n')
+			}
+		}
+		in.headers = b.String()
+	}
+	return in.headers
+}
+
+// match 返回命中的位置
+func (r *compiledWAFRule) match(in *wafInput) (string, bool) {
+	if r.path && r.re.MatchString(in.path) {
+		return WAFTargetPath, true
+	}
+	if r.query && in.query != "" && r.re.MatchString(in.query) {
+		return WAFTargetQuery, true
+	}
+	if r.body && in.body != "" && r.re.MatchString(in.body) {
+		return WAFTargetBody, true
+	}
+	if r.allHeaders && r.re.MatchString(in.allHeaders()) {
+		return WAFTargetHeader, true
+	}
+	for _, name := range r.headers {
+		for _, value := range in.header.Values(name) {
+			if r.re.MatchString(value) {
+				return WAFTargetHeader + ":" + name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// withDecoded 原文与 URL 解码后的内容（两者不同时以换行连接）
+func withDecoded(raw string) string {
+	if decoded, err := url.QueryUnescape(raw); err == nil && decoded != raw {
+		return raw + "\n" + decoded
+	}
+	return raw
+}
+
+// WAF 按规则检查请求的路径、查询字符串、请求头与请求体，命中后记录、拒绝或封禁来源 IP；规则可在运行中整体替换
+type WAF struct {
+	config WAFConfig
+
+	mu       sync.RWMutex
+	rules    []*compiledWAFRule
+	needBody bool
+}
+
+// NewWAF 创建 WAF
+func NewWAF(config WAFConfig, rules []WAFRule) (*WAF, error) {
+	w := &WAF{config: config}
+	if err := w.Update(rules); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Update 替换全部规则（任一规则无效时返回错误，保持原规则）
+func (w *WAF) Update(rules []WAFRule) error {
+	compiled := make([]*compiledWAFRule, 0, len(rules))
+	needBody := false
+	for _, rule := range rules {
+		r, err := compileWAFRule(rule)
+		if err != nil {
+			return err
+		}
+		compiled = append(compiled, r)
+		needBody = needBody || r.body
+	}
+
+	w.mu.Lock()
+	w.rules, w.needBody = compiled, needBody
+	w.mu.Unlock()
+	return nil
+}
+
+// Rules 当前生效的规则
+func (w *WAF) Rules() []WAFRule {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	rules := make([]WAFRule, len(w.rules))
+	for i, r := range w.rules {
+		rules[i] = r.WAFRule
+	}
+	return rules
+}
+
+// Middleware 返回中间件
+func (w *WAF) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w.mu.RLock()
+		rules, needBody := w.rules, w.needBody
+		w.mu.RUnlock()
+
+		in := &wafInput{
+			path:   c.Request.URL.Path,
+			query:  withDecoded(c.Request.URL.RawQuery),
+			header: c.Request.Header,
+		}
+		if raw := c.Request.URL.RawPath; raw != "" && raw != in.path {
+			in.path += "\n" + raw
+		}
+		if needBody && w.config.MaxBodySize > 0 {
+			in.body = peekBody(c, w.config.MaxBodySize)
+		}
+
+		var matched []*compiledWAFRule
+		action := WAFActionLog
+		for _, rule := range rules {
+			if _, ok := rule.match(in); !ok {
+				continue
+			}
+			matched = append(matched, rule)
+			wafMatchesTotal.Inc(rule.Name, rule.Action)
+			if wafActionRank(rule.Action) > wafActionRank(action) {
+				action = rule.Action
+			}
+		}
+		if len(matched) == 0 {
+			c.Next()
+			return
+		}
+
+		var names, reasons []string
+		for _, rule := range matched {
+			names = append(names, rule.Name)
+			if !containsString(reasons, rule.Reason) {
+				reasons = append(reasons, rule.Reason)
+			}
+		}
+		w.alert(c, action, names, reasons)
+
+		switch action {
+		case WAFActionBan:
+			if w.config.Ban != nil {
+				w.config.Ban(c.Request.Context(), AbuseIP(c), strings.Join(names, ","))
+			}
+			fallthrough
+		case WAFActionBlock:
+			AddAuditNote(c, fmt.Sprintf("WAF %s: %s", action, strings.Join(names, ",")))
+			if w.config.BlockHandler != nil {
+				w.config.BlockHandler(c)
+				c.Abort()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "请求被安全规则拦截",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// alert 记录安全告警，计入滥用评分并写入安全事件订阅
+func (w *WAF) alert(c *gin.Context, action string, names, reasons []string) {
+	securityLog := map[string]interface{}{
+		"timestamp":  time.Now(),
+		"client_ip":  c.ClientIP(),
+		"method":     c.Request.Method,
+		"path":       c.Request.URL.Path,
+		"user_agent": c.Request.UserAgent(),
+		"suspicious": true,
+		"reasons":    reasons,
+		"rules":      names,
+		"action":     action,
+	}
+	log.Printf("[SECURITY ALERT] %v", securityLog)
+	c.Set(SecurityReasonsKey, reasons)
+	RecordAbuse(c.Request.Context(), AbuseIP(c), AbuseSecurityAlert)
+	if OnSecurityAlert != nil {
+		OnSecurityAlert(c.Request.Context(), SecurityAlert{
+			IP:        AbuseIP(c),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			UserAgent: c.Request.UserAgent(),
+			RequestID: c.GetString("request_id"),
+			Reasons:   reasons,
+		})
+	}
+}
+
+func wafActionRank(action string) int {
+	switch action {
+	case WAFActionBan:
+		return 2
+	case WAFActionBlock:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// peekBody 读取请求体的前 max 字节用于检查，请求体保持完整供后续处理（表单同时检查解码后的内容）
+func peekBody(c *gin.Context, max int64) string {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return ""
+	}
+	head, err := io.ReadAll(io.LimitReader(c.Request.Body, max))
+	c.Request.Body = peekedBody{Reader: io.MultiReader(bytes.NewReader(head), c.Request.Body), Closer: c.Request.Body}
+	if err != nil {
+		return ""
+	}
+	if strings.HasPrefix(c.ContentType(), "application/x-www-form-urlencoded") {
+		return withDecoded(string(head))
+	}
+	return string(head)
+}
+
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinWAF 内置规则（只记录），供 SecurityAudit 与 SuspiciousReasons 使用
+var builtinWAF, _ = NewWAF(DefaultWAFConfig, BuiltinWAFRules(WAFActionLog))
+
+// SecurityAudit 安全审计中间件：按内置规则检测攻击特征，只记录不拦截。
+// 需要自定义规则、拦截或热更新时使用 NewWAF
+func SecurityAudit() gin.HandlerFunc {
+	return builtinWAF.Middleware()
+}
+
+// SuspiciousReasons 按内置规则检测请求路径与查询字符串中的攻击特征（查询字符串同时检查解码后的内容，避免 URL 编码绕过）
+func SuspiciousReasons(path, rawQuery string) []string {
+	in := &wafInput{path: path, query: withDecoded(rawQuery)}
+	var reasons []string
+	for _, rule := range builtinWAF.rules {
+		if _, ok := rule.match(in); ok {
+			reasons = append(reasons, rule.Reason)
+		}
+	}
+	return reasons
+}
@@ -3,7 +3,8 @@ package model
 import (
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"new-openclaw/pkg/password"
+
 	"gorm.io/gorm"
 )
 
@@ -21,6 +22,16 @@ type Admin struct {
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// 连续登录失败次数与锁定截止时间（随 LOGIN_STORE 中的计数同步，存储数据丢失后锁定仍然有效）
+	FailedAttempts int        `gorm:"default:0" json:"failed_attempts"`
+	LockedUntil    *time.Time `json:"locked_until"`
+
+	// 数据范围（为空不限制）：在角色权限之外，只能查看和管理范围内的客户（AppKey）
+	Scope *AdminScope `gorm:"type:text;serializer:json" json:"scope,omitempty"`
+
+	// 标签（列表接口填充，不入库）
+	Tags []string `gorm:"-" json:"tags,omitempty"`
 }
 
 // TableName 指定表名
@@ -28,26 +39,47 @@ func (Admin) TableName() string {
 	return "admins"
 }
 
-// SetPassword 设置密码（加密）
-func (a *Admin) SetPassword(password string) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// AdminScope 管理员的数据范围：AppKey 的租户、组织或标签命中任意一项即在范围内
+type AdminScope struct {
+	Tenants       []string `json:"tenants,omitempty"`
+	Organizations []string `json:"organizations,omitempty"`
+	// AppKey 标签（用户分群，如 region:eu、vip）
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Empty 是否未设置任何范围
+func (s *AdminScope) Empty() bool {
+	return s == nil || len(s.Tenants)+len(s.Organizations)+len(s.Tags) == 0
+}
+
+// SetPassword 设置密码（使用当前配置的哈希算法加密）
+func (a *Admin) SetPassword(plain string) error {
+	hashedPassword, err := password.Hash(plain)
 	if err != nil {
 		return err
 	}
-	a.Password = string(hashedPassword)
+	a.Password = hashedPassword
 	return nil
 }
 
 // CheckPassword 验证密码
-func (a *Admin) CheckPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(a.Password), []byte(password))
-	return err == nil
+func (a *Admin) CheckPassword(plain string) bool {
+	return password.Verify(a.Password, plain)
+}
+
+// NeedsRehash 密码哈希算法或参数是否已过时
+func (a *Admin) NeedsRehash() bool {
+	return password.NeedsRehash(a.Password)
 }
 
 // AdminLoginRequest 登录请求
 type AdminLoginRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50"`
 	Password string `json:"password" binding:"required,min=6,max=50"`
+	// 人机验证（账号连续登录失败后需要，验证码通过 GET /api/v1/public/captcha 获取）
+	CaptchaID     string `json:"captcha_id,omitempty"`
+	CaptchaAnswer string `json:"captcha_answer,omitempty"`
+	CaptchaToken  string `json:"captcha_token,omitempty"`
 }
 
 // AdminLoginResponse 登录响应
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// AdminRole 管理员角色及其细粒度权限（super_admin、admin、editor 为内置角色，不可删除）
+type AdminRole struct {
+	ID   uint   `gorm:"primarykey" json:"id"`
+	Name string `gorm:"type:varchar(32);uniqueIndex;not null" json:"name"`
+	// 权限列表，写法同 /api/v1 的权限范围（* 表示全部，pii:* 表示 pii: 开头的全部权限）
+	Permissions []string  `gorm:"type:text;serializer:json" json:"permissions"`
+	Description string    `gorm:"type:varchar(255)" json:"description"`
+	Builtin     bool      `gorm:"default:false" json:"builtin"`
+	Admins      int64     `gorm:"-" json:"admins"` // 使用该角色的管理员数（仅列表、详情返回）
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (AdminRole) TableName() string {
+	return "admin_roles"
+}
+
+// AdminPermission 可分配给角色的权限（内置权限由代码注册，不可删除）
+type AdminPermission struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	Name        string    `gorm:"type:varchar(64);uniqueIndex;not null" json:"name"`
+	Description string    `gorm:"type:varchar(255)" json:"description"`
+	Builtin     bool      `gorm:"default:false" json:"builtin"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (AdminPermission) TableName() string {
+	return "admin_permissions"
+}
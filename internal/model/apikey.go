@@ -0,0 +1,56 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// API Key 状态
+const (
+	APIKeyStatusRevoked = 0
+	APIKeyStatusActive  = 1
+)
+
+// APIKey 服务间调用凭证（X-API-Key 请求头认证）。
+// 只保存 Key 的 SHA-256 摘要，明文只在签发、轮换时返回一次
+type APIKey struct {
+	ID   uint   `gorm:"primarykey" json:"id"`
+	Name string `gorm:"type:varchar(100);not null" json:"name"`
+	// Key 的前几位（如 sk_1a2b3c4d），用于在列表、日志中识别
+	Prefix  string `gorm:"type:varchar(16);index" json:"prefix"`
+	KeyHash string `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	// 轮换前的 Key，在 PreviousExpiresAt 之前仍可使用（平滑切换）
+	PreviousKeyHash   string     `gorm:"type:char(64);index" json:"-"`
+	PreviousExpiresAt *time.Time `json:"previous_expires_at"`
+	// 授予的权限范围（逗号分隔，如 users:read,profile:read）
+	Scopes    string     `gorm:"type:varchar(1024)" json:"scopes"`
+	Status    int        `gorm:"type:tinyint;default:1" json:"status"` // 1: 有效, 0: 已吊销
+	ExpiresAt *time.Time `json:"expires_at"`
+	Remark    string     `gorm:"type:varchar(255)" json:"remark"`
+
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `gorm:"type:varchar(45)" json:"last_used_ip"`
+
+	CreatedBy string     `gorm:"type:varchar(50)" json:"created_by"`
+	RotatedAt *time.Time `json:"rotated_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	RevokedBy string     `gorm:"type:varchar(50)" json:"revoked_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// ScopeList 权限范围列表
+func (k *APIKey) ScopeList() []string {
+	var scopes []string
+	for _, scope := range strings.Split(k.Scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+
+	"new-openclaw/pkg/secrets"
+)
+
+// AppKey 状态
+const (
+	AppKeyStatusDisabled = 0
+	AppKeyStatusActive   = 1
+)
+
+// AppKey 第三方调用方凭证（每个 AppKey 独立的签名密钥）
+type AppKey struct {
+	ID     uint                    `gorm:"primarykey" json:"id"`
+	Key    string                  `gorm:"column:app_key;type:varchar(64);uniqueIndex;not null" json:"app_key"`
+	Name   string                  `gorm:"type:varchar(100)" json:"name"`
+	Secret secrets.EncryptedString `gorm:"type:text;not null" json:"-"`
+	// 轮换前的密钥，在 PreviousExpiresAt 之前仍可验签（平滑切换）
+	PreviousSecret    secrets.EncryptedString `gorm:"type:text" json:"-"`
+	PreviousExpiresAt *time.Time              `json:"previous_expires_at"`
+	Status            int                     `gorm:"type:tinyint;default:1" json:"status"` // 1: 启用, 0: 禁用
+	// 允许调用的 IP/CIDR（逗号分隔，为空不限制）
+	AllowedIPs string     `gorm:"type:varchar(1024)" json:"allowed_ips"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	Remark     string     `gorm:"type:varchar(255)" json:"remark"`
+	RotatedAt  *time.Time `json:"rotated_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// 所属租户、组织（限定管理员数据范围时使用）
+	Tenant       string `gorm:"type:varchar(64);index" json:"tenant"`
+	Organization string `gorm:"type:varchar(100);index" json:"organization"`
+
+	// 标签（来自 taggings 关联表）
+	Tags []string `gorm:"-" json:"tags,omitempty"`
+}
+
+// TableName 指定表名
+func (AppKey) TableName() string {
+	return "app_keys"
+}
+
+func init() {
+	secrets.RegisterColumn("app_keys", "secret")
+	secrets.RegisterColumn("app_keys", "previous_secret")
+}
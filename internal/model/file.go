@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// File 上传的文件（内容保存在存储后端，表中记录元数据）
+type File struct {
+	ID uint `gorm:"primarykey" json:"id"`
+	// 存储后端（local、s3）与对象键（key 为 MySQL 保留字，列名加前缀）
+	Storage string `gorm:"type:varchar(16);not null" json:"storage"`
+	Key     string `gorm:"column:object_key;type:varchar(255);uniqueIndex;not null" json:"key"`
+	// 上传时的文件名
+	Name string `gorm:"type:varchar(255)" json:"name"`
+	Size int64  `json:"size"`
+	// 按文件内容识别的 MIME 类型
+	ContentType string `gorm:"type:varchar(128)" json:"content_type"`
+	SHA256      string `gorm:"column:sha256;type:varchar(64);index" json:"sha256"`
+	// 用途，如 avatar
+	Purpose      string    `gorm:"type:varchar(32);index" json:"purpose"`
+	UploaderID   uint      `gorm:"index" json:"uploader_id"`
+	UploaderName string    `gorm:"type:varchar(64)" json:"uploader_name"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (File) TableName() string {
+	return "files"
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// HealthEvent 依赖健康状态变化记录（MySQL、Redis、MongoDB、审计日志输出）
+type HealthEvent struct {
+	ID        uint   `gorm:"primarykey" json:"id"`
+	Component string `gorm:"type:varchar(32);index;not null" json:"component"`
+	// 变化前后的状态：connected, disconnected（审计日志为 ok, degraded）
+	FromStatus string `gorm:"type:varchar(32)" json:"from_status"`
+	ToStatus   string `gorm:"type:varchar(32)" json:"to_status"`
+	// 检测到变化的实例（主机名-进程号）
+	Instance string `gorm:"type:varchar(128)" json:"instance"`
+	// 最近一小时内该组件在本实例的状态变化次数（含本次）
+	Flaps int `json:"flaps"`
+	// 变化发生时间（数据库不可用期间产生的记录在恢复后补写，以此为准）
+	OccurredAt time.Time `gorm:"index" json:"occurred_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (HealthEvent) TableName() string {
+	return "health_events"
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// 模拟登录的对象类型
+const (
+	ImpersonationRealmAdmin = "admin"
+	ImpersonationRealmUser  = "user"
+)
+
+// Impersonation 超级管理员模拟其他管理员或用户登录的记录
+type Impersonation struct {
+	ID uint `gorm:"primarykey" json:"id"`
+	// 发起模拟的超级管理员
+	AdminID       uint   `gorm:"index;not null" json:"admin_id"`
+	AdminUsername string `gorm:"type:varchar(50)" json:"admin_username"`
+	// 被模拟的账号：admin（管理员）或 user（API 用户）
+	Realm          string `gorm:"type:varchar(10);index:idx_impersonation_target" json:"realm"`
+	TargetID       uint   `gorm:"index:idx_impersonation_target" json:"target_id"`
+	TargetUsername string `gorm:"type:varchar(64)" json:"target_username"`
+	// 模拟原因（如工单号）
+	Reason string `gorm:"type:varchar(255)" json:"reason"`
+	// 签发 Token 的 jti，结束模拟时据此吊销
+	TokenID   string `gorm:"type:varchar(64)" json:"-"`
+	IP        string `gorm:"type:varchar(45)" json:"ip"`
+	UserAgent string `gorm:"type:varchar(512)" json:"user_agent"`
+
+	ExpiresAt time.Time `json:"expires_at"`
+	// 主动结束的时间及操作人（为空表示未结束，到期后自然失效）
+	EndedAt   *time.Time `json:"ended_at"`
+	EndedBy   string     `gorm:"type:varchar(50)" json:"ended_by"`
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (Impersonation) TableName() string {
+	return "admin_impersonations"
+}
+
+// Active 模拟 Token 是否仍有效
+func (i *Impersonation) Active() bool {
+	return i.EndedAt == nil && time.Now().Before(i.ExpiresAt)
+}
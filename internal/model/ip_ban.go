@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// IPBan 自动临时封禁记录（滥用评分达到阈值时写入，供管理员复核）
+type IPBan struct {
+	ID uint   `gorm:"primarykey" json:"id"`
+	IP string `gorm:"type:varchar(64);index;not null" json:"ip"`
+	// 触发封禁的事件类型：not_found, rate_limited, signature_failure, security_alert
+	Trigger string `gorm:"type:varchar(32)" json:"trigger"`
+	// 封禁时的滥用评分及窗口内各类事件次数（JSON）
+	Score  int64  `json:"score"`
+	Events string `gorm:"type:varchar(512)" json:"events"`
+	Reason string `gorm:"type:varchar(255)" json:"reason"`
+	// 执行封禁的实例（主机名-进程号）
+	Instance  string    `gorm:"type:varchar(128)" json:"instance"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+	// 管理员提前解除封禁
+	LiftedAt  *time.Time `json:"lifted_at"`
+	LiftedBy  string     `gorm:"type:varchar(64)" json:"lifted_by"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (IPBan) TableName() string {
+	return "ip_bans"
+}
+
+// Active 封禁是否仍然生效
+func (b IPBan) Active(now time.Time) bool {
+	return b.LiftedAt == nil && now.Before(b.ExpiresAt)
+}
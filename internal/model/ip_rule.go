@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// IP 规则类型
+const (
+	IPRuleWhitelist = "whitelist"
+	IPRuleBlacklist = "blacklist"
+)
+
+// IPRule 持久化的 IP 过滤规则（与 IP_WHITELIST/IP_BLACKLIST 合并生效）
+type IPRule struct {
+	ID   uint   `gorm:"primarykey" json:"id"`
+	Type string `gorm:"type:varchar(16);uniqueIndex:idx_ip_rules_type_cidr;not null" json:"type"`
+	// 单个 IP 或 CIDR 网段（保存前规范化）
+	CIDR   string `gorm:"column:cidr;type:varchar(64);uniqueIndex:idx_ip_rules_type_cidr;not null" json:"cidr"`
+	Remark string `gorm:"type:varchar(255)" json:"remark"`
+	// 添加规则的用户
+	CreatedBy string    `gorm:"type:varchar(64)" json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// 标签（查询时按需填充）
+	Tags []string `gorm:"-" json:"tags,omitempty"`
+}
+
+// TableName 指定表名
+func (IPRule) TableName() string {
+	return "ip_rules"
+}
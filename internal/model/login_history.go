@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// 登录记录的账号类型
+const (
+	LoginRealmAdmin = "admin"
+	LoginRealmUser  = "user"
+)
+
+// 登录结果
+const (
+	LoginResultSuccess = "success"
+	LoginResultFailed  = "failed"
+)
+
+// LoginHistory 管理员与用户的登录记录（成功与失败）
+type LoginHistory struct {
+	ID    uint   `gorm:"primarykey" json:"id"`
+	Realm string `gorm:"type:varchar(16);index:idx_login_history_account,priority:1;not null" json:"realm"`
+	// 账号 ID（账号不存在或无法识别时为 0）
+	AccountID uint   `gorm:"index:idx_login_history_account,priority:2" json:"account_id"`
+	Username  string `gorm:"type:varchar(64);index" json:"username"`
+	// 登录方式：password、webauthn、saml、oauth:{provider}、break_glass
+	Method string `gorm:"type:varchar(32)" json:"method"`
+	Result string `gorm:"type:varchar(16);index" json:"result"`
+	// 失败原因
+	Reason    string `gorm:"type:varchar(255)" json:"reason,omitempty"`
+	IP        string `gorm:"type:varchar(45);index" json:"ip"`
+	UserAgent string `gorm:"type:varchar(512)" json:"user_agent"`
+	Device    string `gorm:"type:varchar(64)" json:"device"`
+	// 国家/地区（需配置 GEOIP_COUNTRY_DB）与 ASN（需配置 GEOIP_ASN_DB）
+	Country     string `gorm:"type:varchar(8)" json:"country,omitempty"`
+	CountryName string `gorm:"type:varchar(64)" json:"country_name,omitempty"`
+	ASN         uint32 `json:"asn,omitempty"`
+	ASNOrg      string `gorm:"type:varchar(255)" json:"asn_org,omitempty"`
+	// 成功登录的设备或位置此前未出现过
+	NewDevice   bool      `json:"new_device"`
+	NewLocation bool      `json:"new_location"`
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (LoginHistory) TableName() string {
+	return "login_histories"
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// Menu 管理后台菜单项（ParentID 为 0 的是一级菜单；Roles、Permission 为空表示不限制）
+type Menu struct {
+	ID       uint   `gorm:"primarykey" json:"id"`
+	ParentID uint   `gorm:"index;default:0" json:"parent_id"`
+	Name     string `gorm:"type:varchar(64);not null" json:"name"`
+	// 前端路由或外部链接，分组菜单可为空
+	Path string `gorm:"type:varchar(255)" json:"path"`
+	Icon string `gorm:"type:varchar(64)" json:"icon"`
+	// 同级菜单按 Sort 升序排列
+	Sort int `gorm:"default:0" json:"sort"`
+	// 可见的角色（为空所有角色可见）与需要的权限（为空不要求）
+	Roles      []string  `gorm:"type:text;serializer:json" json:"roles"`
+	Permission string    `gorm:"type:varchar(64)" json:"permission"`
+	Children   []Menu    `gorm:"-" json:"children,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Menu) TableName() string {
+	return "menus"
+}
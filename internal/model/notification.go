@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// NotificationPreference 管理员通知偏好（每个分类一条，未配置时立即发送、无免打扰）
+type NotificationPreference struct {
+	ID       uint   `gorm:"primarykey" json:"id"`
+	AdminID  uint   `gorm:"uniqueIndex:idx_admin_category;not null" json:"admin_id"`
+	Category string `gorm:"type:varchar(20);uniqueIndex:idx_admin_category;not null" json:"category"` // security, system, reports
+	// 发送频率：immediate, hourly, daily
+	Frequency string `gorm:"type:varchar(20);default:immediate" json:"frequency"`
+	// 免打扰时段（HH:MM，结束早于开始时跨越午夜，均为空表示不启用）
+	QuietStart string `gorm:"type:varchar(5)" json:"quiet_start"`
+	QuietEnd   string `gorm:"type:varchar(5)" json:"quiet_end"`
+	// 时区（IANA 名称，为空使用服务器时区）
+	Timezone     string     `gorm:"type:varchar(64)" json:"timezone"`
+	LastDigestAt *time.Time `json:"last_digest_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (NotificationPreference) TableName() string {
+	return "notification_preferences"
+}
+
+// PendingNotification 等待汇总发送的通知（摘要模式或免打扰时段内产生）
+type PendingNotification struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	AdminID   uint      `gorm:"index;not null" json:"admin_id"`
+	Category  string    `gorm:"type:varchar(20);not null" json:"category"`
+	Title     string    `gorm:"type:varchar(255)" json:"title"`
+	Content   string    `gorm:"type:text" json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (PendingNotification) TableName() string {
+	return "pending_notifications"
+}
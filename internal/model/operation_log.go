@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// OperationLog 管理员操作日志
+type OperationLog struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	AdminID   uint      `gorm:"index" json:"admin_id"`
+	Username  string    `gorm:"type:varchar(50)" json:"username"`
+	Action    string    `gorm:"type:varchar(150);index" json:"action"` // 如 DELETE /admin/admins/:id
+	Method    string    `gorm:"type:varchar(10)" json:"method"`
+	Path      string    `gorm:"type:varchar(255)" json:"path"`
+	Resource  string    `gorm:"type:varchar(64);index" json:"resource"` // 如 admins、appkeys
+	TargetID  string    `gorm:"type:varchar(64)" json:"target_id"`
+	Status    int       `json:"status"`
+	IP        string    `gorm:"type:varchar(64)" json:"ip"`
+	RequestID string    `gorm:"type:varchar(64)" json:"request_id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	// 模拟登录期间的操作：发起模拟的超级管理员（AdminID、Username 为被模拟的管理员）
+	ImpersonatorID   uint   `gorm:"index" json:"impersonator_id,omitempty"`
+	ImpersonatorName string `gorm:"type:varchar(50)" json:"impersonator_name,omitempty"`
+
+	// 字段级变更（键为字段名）：新建时只有 after，删除时只有 before；敏感字段只记录是否变更
+	Changes map[string]FieldChange `gorm:"type:text;serializer:json" json:"changes,omitempty"`
+}
+
+// FieldChange 单个字段变更前后的值
+type FieldChange struct {
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// TableName 指定表名
+func (OperationLog) TableName() string {
+	return "admin_operation_logs"
+}
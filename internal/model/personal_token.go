@@ -0,0 +1,44 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// PersonalAccessToken 用户个人访问令牌（供脚本以用户身份调用 API，Authorization: Bearer pat_...）。
+// 只保存令牌的 SHA-256 摘要，明文只在创建时返回一次；权限范围不超过创建时用户拥有的权限
+type PersonalAccessToken struct {
+	ID     uint   `gorm:"primarykey" json:"id"`
+	UserID uint   `gorm:"index;not null" json:"user_id"`
+	Name   string `gorm:"type:varchar(100);not null" json:"name"`
+	// 令牌的前几位（如 pat_1a2b3c4d），用于在列表、日志中识别
+	Prefix    string `gorm:"type:varchar(16);index" json:"prefix"`
+	TokenHash string `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	// 授予的权限范围（逗号分隔）
+	Scopes    string     `gorm:"type:varchar(1024)" json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `gorm:"type:varchar(45)" json:"last_used_ip"`
+
+	// 吊销时间（为空表示有效，吊销后记录保留）
+	RevokedAt *time.Time `gorm:"index" json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (PersonalAccessToken) TableName() string {
+	return "personal_access_tokens"
+}
+
+// ScopeList 权限范围列表
+func (t *PersonalAccessToken) ScopeList() []string {
+	var scopes []string
+	for _, scope := range strings.Split(t.Scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
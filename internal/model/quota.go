@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// AppKeyQuota AppKey 配额（覆盖全局默认配额，0 表示不限制）
+type AppKeyQuota struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	AppKey       string    `gorm:"type:varchar(64);uniqueIndex;not null" json:"app_key"`
+	DailyLimit   int64     `json:"daily_limit"`
+	MonthlyLimit int64     `json:"monthly_limit"`
+	Remark       string    `gorm:"type:varchar(255)" json:"remark"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (AppKeyQuota) TableName() string {
+	return "app_key_quotas"
+}
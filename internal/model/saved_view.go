@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// SavedView 管理员保存的列表视图（筛选条件与排序的命名组合）
+type SavedView struct {
+	ID      uint `gorm:"primarykey" json:"id"`
+	AdminID uint `gorm:"uniqueIndex:idx_saved_views_admin_list_name;not null" json:"admin_id"`
+	// 适用的列表：admins, operation_logs
+	List string `gorm:"type:varchar(32);uniqueIndex:idx_saved_views_admin_list_name;not null" json:"list"`
+	Name string `gorm:"type:varchar(100);uniqueIndex:idx_saved_views_admin_list_name;not null" json:"name"`
+	// 筛选条件（字段名 → 值，字段由列表定义）
+	Filters map[string]string `gorm:"type:text;serializer:json" json:"filters"`
+	// 排序字段，- 前缀表示降序，如 -created_at
+	Sort      string    `gorm:"type:varchar(64)" json:"sort"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (SavedView) TableName() string {
+	return "saved_views"
+}
@@ -0,0 +1,60 @@
+package model
+
+import "time"
+
+// 安全事件类型
+const (
+	// SecurityEventAttack 检测到攻击特征（WAF 规则命中）
+	SecurityEventAttack = "attack_detected"
+	// SecurityEventBan 自动封禁 IP
+	SecurityEventBan = "ip_banned"
+	// SecurityEventBanLifted 管理员提前解除封禁
+	SecurityEventBanLifted = "ban_lifted"
+	// SecurityEventAuthFailure 认证失败（管理后台登录、紧急访问、无效 Token、签名校验失败）
+	SecurityEventAuthFailure = "auth_failed"
+	// SecurityEventAccountLocked 登录失败次数过多，账号被临时锁定
+	SecurityEventAccountLocked = "account_locked"
+	// SecurityEventHoneypot 访问诱饵路径（扫描器探测）
+	SecurityEventHoneypot = "honeypot_hit"
+)
+
+// 安全事件严重级别（由低到高）
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// Severities 所有严重级别（由低到高）
+var Severities = []string{SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// SecurityEvent 安全事件（事件查询、告警及威胁情报订阅的数据源，按 ID 递增游标读取）。
+// 存储在 MySQL 或 MongoDB（SECURITY_EVENTS_STORE），MongoDB 中的 ID 由计数器分配，同样递增
+type SecurityEvent struct {
+	ID       uint   `gorm:"primarykey" json:"id" bson:"_id"`
+	Type     string `gorm:"type:varchar(32);index;not null" json:"type" bson:"type"`
+	Severity string `gorm:"type:varchar(16);index" json:"severity" bson:"severity"`
+	IP       string `gorm:"type:varchar(64);index" json:"ip" bson:"ip"`
+	// 攻击检测、认证失败：触发请求
+	Method    string `gorm:"type:varchar(10)" json:"method,omitempty" bson:"method,omitempty"`
+	Path      string `gorm:"type:varchar(255)" json:"path,omitempty" bson:"path,omitempty"`
+	UserAgent string `gorm:"type:varchar(255)" json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	RequestID string `gorm:"type:varchar(64)" json:"request_id,omitempty" bson:"request_id,omitempty"`
+	// 认证失败：尝试的用户名或 AppKey
+	Subject string `gorm:"type:varchar(64)" json:"subject,omitempty" bson:"subject,omitempty"`
+	// 逗号分隔的分类，如 sql-injection,xss；封禁为触发的滥用事件类型，认证失败为失败类型
+	Labels string `gorm:"type:varchar(255)" json:"labels" bson:"labels"`
+	// 封禁：记录 ID、评分、原因及生效区间
+	BanID      uint       `gorm:"index" json:"ban_id,omitempty" bson:"ban_id,omitempty"`
+	Score      int64      `json:"score,omitempty" bson:"score,omitempty"`
+	Detail     string     `gorm:"type:varchar(255)" json:"detail,omitempty" bson:"detail,omitempty"`
+	ValidFrom  *time.Time `json:"valid_from,omitempty" bson:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty" bson:"valid_until,omitempty"`
+	CreatedAt  time.Time  `gorm:"index" json:"created_at" bson:"created_at"`
+}
+
+// TableName 指定表名
+func (SecurityEvent) TableName() string {
+	return "security_events"
+}
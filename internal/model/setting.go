@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// 系统设置的值类型
+const (
+	SettingTypeString   = "string"
+	SettingTypeInt      = "int"
+	SettingTypeFloat    = "float"
+	SettingTypeBool     = "bool"
+	SettingTypeDuration = "duration"
+	SettingTypeJSON     = "json"
+)
+
+// Setting 系统设置（类型化的键值，管理后台修改后各实例热更新，优先于对应的环境变量）
+type Setting struct {
+	ID uint `gorm:"primarykey" json:"id"`
+	// 键，如 rate_limit.max_requests（key、group 为 MySQL 保留字，列名加前缀）
+	Key string `gorm:"column:setting_key;type:varchar(128);uniqueIndex;not null" json:"key"`
+	// 值的文本形式，按 Type 解析
+	Value string `gorm:"type:text" json:"value"`
+	// 值类型：string、int、float、bool、duration、json
+	Type string `gorm:"type:varchar(16);not null" json:"type"`
+	// 分组（为空时取键中第一个 . 之前的部分）
+	Group       string `gorm:"column:setting_group;type:varchar(64);index" json:"group"`
+	Description string `gorm:"type:varchar(255)" json:"description"`
+	// 最后修改的管理员
+	UpdatedBy string    `gorm:"type:varchar(64)" json:"updated_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Setting) TableName() string {
+	return "settings"
+}
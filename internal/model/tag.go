@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// Tag 资源标签（如 beta、high-risk、partner-x），可跨模块给管理员、AppKey、IP 规则分组
+type Tag struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Name      string    `gorm:"type:varchar(32);uniqueIndex;not null" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (Tag) TableName() string {
+	return "tags"
+}
+
+// Tagging 标签与资源的关联（多态：资源类型 + 资源 ID）
+type Tagging struct {
+	ID    uint `gorm:"primarykey" json:"id"`
+	TagID uint `gorm:"uniqueIndex:idx_taggings_resource_tag;index;not null" json:"tag_id"`
+	// 资源类型：admin, app_key, ip_rule
+	ResourceType string `gorm:"type:varchar(16);uniqueIndex:idx_taggings_resource_tag;not null" json:"resource_type"`
+	ResourceID   uint   `gorm:"uniqueIndex:idx_taggings_resource_tag;not null" json:"resource_id"`
+	// 添加标签的管理员
+	CreatedBy string    `gorm:"type:varchar(64)" json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (Tagging) TableName() string {
+	return "taggings"
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// PartnerTransform 合作方回调载荷转换模板（Go text/template，输出须为 JSON）
+type PartnerTransform struct {
+	ID     uint   `gorm:"primarykey" json:"id"`
+	AppKey string `gorm:"type:varchar(64);uniqueIndex:idx_transform_app_direction;not null" json:"app_key"`
+	// 方向：outbound（发出的 Webhook 投递前）, inbound（收到的回调解析后）
+	Direction string    `gorm:"type:varchar(16);uniqueIndex:idx_transform_app_direction;not null" json:"direction"`
+	Template  string    `gorm:"type:text;not null" json:"template"`
+	Enabled   bool      `json:"enabled"`
+	Remark    string    `gorm:"type:varchar(255)" json:"remark"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (PartnerTransform) TableName() string {
+	return "partner_transforms"
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"time"
+
+	"new-openclaw/pkg/password"
+)
+
+// 用户状态
+const (
+	UserStatusDisabled = 0
+	UserStatusActive   = 1
+)
+
+// User API 用户（通过注册接口或第三方登录创建）
+type User struct {
+	ID       uint   `gorm:"primarykey" json:"id"`
+	Username string `gorm:"type:varchar(64);uniqueIndex;not null" json:"username"`
+	Email    string `gorm:"type:varchar(255);index" json:"email"`
+	Nickname string `gorm:"type:varchar(100)" json:"nickname"`
+	Avatar   string `gorm:"type:varchar(512)" json:"avatar"`
+	// 角色（决定 Token 的默认权限范围，见 middleware.RoleScopes）
+	Role        string     `gorm:"type:varchar(32);default:user" json:"role"`
+	Status      int        `gorm:"type:tinyint;default:1" json:"status"` // 1: 启用, 0: 禁用
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// 密码哈希（仅第三方登录创建的用户为空，不能用密码登录）
+	Password string `gorm:"type:varchar(255)" json:"-"`
+	// 邮箱验证时间（为空表示未验证，登录后只有受限的权限范围）
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+}
+
+// TableName 指定表名
+func (User) TableName() string {
+	return "users"
+}
+
+// SetPassword 设置密码（哈希后保存）
+func (u *User) SetPassword(plain string) error {
+	hashed, err := password.Hash(plain)
+	if err != nil {
+		return err
+	}
+	u.Password = hashed
+	return nil
+}
+
+// CheckPassword 校验密码；未设置密码的用户始终失败
+func (u *User) CheckPassword(plain string) bool {
+	return u.Password != "" && password.Verify(u.Password, plain)
+}
+
+// NeedsRehash 密码哈希是否需要按当前配置的算法与参数重新生成
+func (u *User) NeedsRehash() bool {
+	return u.Password != "" && password.NeedsRehash(u.Password)
+}
+
+// EmailVerified 邮箱是否已验证
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// UserIdentity 用户绑定的第三方身份（提供方 + 提供方内的用户标识唯一）
+type UserIdentity struct {
+	ID       uint   `gorm:"primarykey" json:"id"`
+	UserID   uint   `gorm:"index;not null" json:"user_id"`
+	Provider string `gorm:"type:varchar(32);uniqueIndex:idx_provider_subject;not null" json:"provider"`
+	// 提供方内的用户标识（OIDC 的 sub、GitHub 的用户 ID）
+	Subject     string     `gorm:"type:varchar(255);uniqueIndex:idx_provider_subject;not null" json:"subject"`
+	Email       string     `gorm:"type:varchar(255)" json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
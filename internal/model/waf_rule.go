@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// WAFRule 持久化的 WAF 规则（与内置规则、WAF_RULES_FILE 中的规则合并生效）
+type WAFRule struct {
+	ID   uint   `gorm:"primarykey" json:"id"`
+	Name string `gorm:"type:varchar(64);uniqueIndex;not null" json:"name"`
+	// 正则表达式（Go RE2 语法）
+	Pattern string `gorm:"type:text;not null" json:"pattern"`
+	// 检查位置，逗号分隔：path、query、body、header、header:Name
+	Targets string `gorm:"type:varchar(255);not null" json:"targets"`
+	// 命中后的动作：log、block、ban
+	Action string `gorm:"type:varchar(16);not null" json:"action"`
+	// 告警中的检测结果（为空时使用规则名）
+	Reason  string `gorm:"type:varchar(128)" json:"reason"`
+	Enabled bool   `json:"enabled"`
+	// 添加规则的管理员
+	CreatedBy string    `gorm:"type:varchar(64)" json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (WAFRule) TableName() string {
+	return "waf_rules"
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// WebAuthnCredential 管理员注册的安全密钥或通行密钥
+type WebAuthnCredential struct {
+	ID      uint `gorm:"primarykey" json:"id"`
+	AdminID uint `gorm:"index;not null" json:"admin_id"`
+	// 名称（如 "YubiKey 5"、"MacBook 触控 ID"）
+	Name string `gorm:"type:varchar(100)" json:"name"`
+	// 凭证 ID（base64url）；最长可达 1023 字节，按其 SHA-256 摘要建唯一索引
+	CredentialID   string `gorm:"type:varchar(1400);not null" json:"credential_id"`
+	CredentialHash string `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	// COSE_Key 编码的公钥及签名算法（-7 ES256、-8 EdDSA、-257 RS256）
+	PublicKey []byte `gorm:"not null" json:"-"`
+	Algorithm int64  `json:"algorithm"`
+	// 认证器签名计数（不支持计数的认证器始终为 0）
+	SignCount uint32 `json:"sign_count"`
+	// 认证器型号标识
+	AAGUID string `gorm:"column:aaguid;type:varchar(36)" json:"aaguid"`
+	// 传输方式（逗号分隔：usb、nfc、ble、internal、hybrid）
+	Transports string `gorm:"type:varchar(100)" json:"transports"`
+	// 可在设备间同步的通行密钥
+	BackupEligible bool `json:"backup_eligible"`
+
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `gorm:"type:varchar(45)" json:"last_used_ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
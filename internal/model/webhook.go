@@ -0,0 +1,91 @@
+package model
+
+import (
+	"time"
+
+	"new-openclaw/pkg/secrets"
+)
+
+// Webhook 投递状态
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookEndpoint Webhook 投递目标（按事件类型订阅）
+type WebhookEndpoint struct {
+	ID uint `gorm:"primarykey" json:"id"`
+	// 订阅的事件类型，* 表示全部
+	EventType string `gorm:"type:varchar(64);index;not null" json:"event_type"`
+	URL       string `gorm:"type:varchar(1024);not null" json:"url"`
+	// 载荷签名密钥（HMAC-SHA256）
+	Secret secrets.EncryptedString `gorm:"type:text;not null" json:"-"`
+	// 所属合作方 AppKey（非空时投递前按 outbound 转换模板转换载荷）
+	AppKey string `gorm:"type:varchar(64)" json:"app_key"`
+	// 固定接收的载荷版本（见事件目录；升级前已存在的目标为 1，弃用期间继续收到旧结构）
+	SchemaVersion int       `gorm:"not null;default:1" json:"schema_version"`
+	Enabled       bool      `json:"enabled"`
+	Remark        string    `gorm:"type:varchar(255)" json:"remark"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (WebhookEndpoint) TableName() string {
+	return "webhook_endpoints"
+}
+
+// WebhookDelivery 一个事件向一个目标的投递（失败后按指数退避重试）
+type WebhookDelivery struct {
+	ID         uint   `gorm:"primarykey" json:"id"`
+	EndpointID uint   `gorm:"index;not null" json:"endpoint_id"`
+	EventID    string `gorm:"type:varchar(64);index;not null" json:"event_id"`
+	EventType  string `gorm:"type:varchar(64);index;not null" json:"event_type"`
+	// 载荷版本
+	SchemaVersion int `json:"schema_version"`
+	// 实际发送的请求体（已按合作方模板转换）
+	Payload string `gorm:"type:mediumtext" json:"payload"`
+	// 状态：pending, succeeded, failed
+	Status string `gorm:"type:varchar(16);index;not null" json:"status"`
+	// 本轮已尝试次数（手动重放后从 0 开始）及重放次数
+	Attempts      int        `json:"attempts"`
+	Replays       int        `json:"replays"`
+	NextAttemptAt *time.Time `gorm:"index" json:"next_attempt_at"`
+	LastAttemptAt *time.Time `json:"last_attempt_at"`
+	LastStatus    int        `json:"last_status"`
+	LastError     string     `gorm:"type:varchar(1024)" json:"last_error"`
+	CompletedAt   *time.Time `json:"completed_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookAttempt 单次投递尝试记录
+type WebhookAttempt struct {
+	ID         uint `gorm:"primarykey" json:"id"`
+	DeliveryID uint `gorm:"index;not null" json:"delivery_id"`
+	// 第几轮（重放次数）中的第几次尝试
+	Round   int `json:"round"`
+	Attempt int `json:"attempt"`
+	// 响应状态码（请求失败时为 0）
+	StatusCode int    `json:"status_code"`
+	Error      string `gorm:"type:varchar(1024)" json:"error"`
+	// 响应体前 1KB
+	Response   string    `gorm:"type:text" json:"response"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (WebhookAttempt) TableName() string {
+	return "webhook_attempts"
+}
+
+func init() {
+	secrets.RegisterColumn("webhook_endpoints", "secret")
+}
@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/jobs"
+	"new-openclaw/internal/mail"
+	"new-openclaw/internal/model"
+)
+
+// categoryNames 分类显示名称
+var categoryNames = map[string]string{
+	CategorySecurity: "安全",
+	CategorySystem:   "系统",
+	CategoryReports:  "报表",
+}
+
+// RegisterDigestJob 注册摘要发送定时任务（多副本部署时仅由 Leader 发送）
+func RegisterDigestJob() {
+	if cfg.DigestInterval <= 0 {
+		return
+	}
+
+	jobs.Register(jobs.Job{
+		Name:        "notification_digest",
+		Description: "汇总发送管理员通知摘要",
+		Interval:    cfg.DigestInterval,
+		LeaderOnly:  true,
+		Run: func(ctx context.Context) error {
+			return flush(time.Now())
+		},
+	})
+}
+
+// flush 汇总到期的待发送通知，每个管理员合并为一封邮件
+func flush(now time.Time) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil
+	}
+
+	var adminIDs []uint
+	if err := db.Model(&model.PendingNotification{}).Distinct("admin_id").Pluck("admin_id", &adminIDs).Error; err != nil {
+		return err
+	}
+
+	for _, adminID := range adminIDs {
+		if err := flushAdmin(adminID, now); err != nil {
+			log.Printf("发送通知摘要失败: admin=%d err=%v", adminID, err)
+		}
+	}
+	return nil
+}
+
+// flushAdmin 发送单个管理员已到期分类的摘要
+func flushAdmin(adminID uint, now time.Time) error {
+	db := database.GetMySQL()
+
+	var admin model.Admin
+	if err := db.First(&admin, adminID).Error; err != nil || admin.Status != 1 || admin.Email == "" {
+		// 管理员已删除、禁用或没有邮箱，丢弃其待发送通知
+		return db.Where("admin_id = ?", adminID).Delete(&model.PendingNotification{}).Error
+	}
+
+	var prefs []model.NotificationPreference
+	if err := db.Where("admin_id = ?", adminID).Find(&prefs).Error; err != nil {
+		return err
+	}
+	byCategory := make(map[string]model.NotificationPreference, len(prefs))
+	for _, p := range prefs {
+		byCategory[p.Category] = p
+	}
+
+	var due []string
+	for _, category := range Categories {
+		p, ok := byCategory[category]
+		if !ok {
+			p = DefaultPreference(adminID, category)
+		}
+		if digestDue(p, now) {
+			due = append(due, category)
+		}
+	}
+	if len(due) == 0 {
+		return nil
+	}
+
+	var items []model.PendingNotification
+	if err := db.Where("admin_id = ? AND category IN ?", adminID, due).Order("id ASC").Find(&items).Error; err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	subject := fmt.Sprintf("通知摘要（%d 条）", len(items))
+	if err := mail.Send(admin.Email, subject, renderDigest(items)); err != nil {
+		return err
+	}
+
+	ids := make([]uint, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	if err := db.Delete(&model.PendingNotification{}, ids).Error; err != nil {
+		return err
+	}
+	return db.Model(&model.NotificationPreference{}).
+		Where("admin_id = ? AND category IN ?", adminID, due).
+		Update("last_digest_at", now).Error
+}
+
+// digestDue 分类是否到了发送摘要的时间（免打扰时段内一律推迟）
+func digestDue(p model.NotificationPreference, now time.Time) bool {
+	if InQuietHours(p, now) {
+		return false
+	}
+
+	var period time.Duration
+	switch p.Frequency {
+	case FrequencyHourly:
+		period = time.Hour
+	case FrequencyDaily:
+		period = 24 * time.Hour
+	default:
+		// 立即发送的通知只会因免打扰或发送失败进入队列，时段结束后即发送
+		return true
+	}
+	return p.LastDigestAt == nil || now.Sub(*p.LastDigestAt) >= period
+}
+
+// renderDigest 按分类渲染摘要正文
+func renderDigest(items []model.PendingNotification) string {
+	var b strings.Builder
+	for _, category := range Categories {
+		first := true
+		for _, item := range items {
+			if item.Category != category {
+				continue
+			}
+			if first {
+				fmt.Fprintf(&b, "【%s】\n", categoryNames[category])
+				first = false
+			}
+			fmt.Fprintf(&b, "- [%s] %s\n  %s\n", item.CreatedAt.Local().Format("2006-01-02 15:04"), item.Title, item.Content)
+		}
+		if !first {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,211 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/mail"
+	"new-openclaw/internal/model"
+	"new-openclaw/pkg/config"
+)
+
+// 通知分类
+const (
+	CategorySecurity = "security"
+	CategorySystem   = "system"
+	CategoryReports  = "reports"
+)
+
+// 发送频率
+const (
+	FrequencyImmediate = "immediate"
+	FrequencyHourly    = "hourly"
+	FrequencyDaily     = "daily"
+)
+
+// Categories 所有通知分类
+var Categories = []string{CategorySecurity, CategorySystem, CategoryReports}
+
+// cfg 通知配置
+var cfg = config.NotifyConfig{
+	DigestInterval: time.Minute,
+}
+
+// Configure 设置通知配置
+func Configure(c config.NotifyConfig) {
+	cfg = c
+}
+
+// Notify 向所有启用且配置了邮箱的管理员发送通知：
+// 偏好为立即发送且不在免打扰时段时直接发送，否则进入摘要队列由摘要任务汇总发送
+func Notify(category, title, content string) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return fmt.Errorf("数据库未连接")
+	}
+
+	var admins []model.Admin
+	if err := db.Where("status = 1 AND email <> ''").Find(&admins).Error; err != nil {
+		return err
+	}
+	if len(admins) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(admins))
+	for i, a := range admins {
+		ids[i] = a.ID
+	}
+	var prefs []model.NotificationPreference
+	if err := db.Where("admin_id IN ? AND category = ?", ids, category).Find(&prefs).Error; err != nil {
+		return err
+	}
+	byAdmin := make(map[uint]model.NotificationPreference, len(prefs))
+	for _, p := range prefs {
+		byAdmin[p.AdminID] = p
+	}
+
+	now := time.Now()
+	for _, a := range admins {
+		p, ok := byAdmin[a.ID]
+		if !ok {
+			p = DefaultPreference(a.ID, category)
+		}
+
+		if p.Frequency == FrequencyImmediate && !InQuietHours(p, now) {
+			err := mail.Send(a.Email, title, content)
+			if err == nil {
+				continue
+			}
+			log.Printf("发送通知失败（转入摘要队列）: admin=%d err=%v", a.ID, err)
+		}
+
+		db.Create(&model.PendingNotification{
+			AdminID:  a.ID,
+			Category: category,
+			Title:    title,
+			Content:  content,
+		})
+	}
+	return nil
+}
+
+// Alert 立即向所有启用且配置了邮箱的超级管理员及额外邮箱发送告警，忽略摘要频率与免打扰设置。
+// 数据库不可用时仍向额外邮箱发送，返回查询超级管理员的错误
+func Alert(title, content string, extra []string) error {
+	recipients := make(map[string]bool)
+	var queryErr error
+	if db := database.GetMySQL(); db == nil {
+		queryErr = fmt.Errorf("数据库未连接")
+	} else {
+		var admins []model.Admin
+		if err := db.Where("status = 1 AND role = ? AND email <> ''", "super_admin").Find(&admins).Error; err != nil {
+			queryErr = err
+		}
+		for _, a := range admins {
+			recipients[a.Email] = true
+		}
+	}
+	for _, email := range extra {
+		if email = strings.TrimSpace(email); email != "" {
+			recipients[email] = true
+		}
+	}
+
+	for email := range recipients {
+		if err := mail.Send(email, title, content); err != nil {
+			log.Printf("发送告警失败: to=%s err=%v", email, err)
+		}
+	}
+	return queryErr
+}
+
+// DefaultPreference 未配置时的默认偏好（立即发送，无免打扰）
+func DefaultPreference(adminID uint, category string) model.NotificationPreference {
+	return model.NotificationPreference{
+		AdminID:   adminID,
+		Category:  category,
+		Frequency: FrequencyImmediate,
+	}
+}
+
+// Validate 校验通知偏好
+func Validate(p model.NotificationPreference) error {
+	if !validCategory(p.Category) {
+		return fmt.Errorf("无效的通知分类: %s", p.Category)
+	}
+	switch p.Frequency {
+	case FrequencyImmediate, FrequencyHourly, FrequencyDaily:
+	default:
+		return fmt.Errorf("无效的发送频率: %s", p.Frequency)
+	}
+	if (p.QuietStart == "") != (p.QuietEnd == "") {
+		return fmt.Errorf("免打扰开始与结束时间需同时设置")
+	}
+	if p.QuietStart != "" {
+		if _, err := parseClock(p.QuietStart); err != nil {
+			return err
+		}
+		if _, err := parseClock(p.QuietEnd); err != nil {
+			return err
+		}
+	}
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			return fmt.Errorf("无效的时区: %s", p.Timezone)
+		}
+	}
+	return nil
+}
+
+// InQuietHours 指定时间是否处于免打扰时段
+func InQuietHours(p model.NotificationPreference, t time.Time) bool {
+	start, err1 := parseClock(p.QuietStart)
+	end, err2 := parseClock(p.QuietEnd)
+	if err1 != nil || err2 != nil || start == end {
+		return false
+	}
+
+	if p.Timezone != "" {
+		if loc, err := time.LoadLocation(p.Timezone); err == nil {
+			t = t.In(loc)
+		}
+	} else {
+		t = t.Local()
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	// 跨越午夜，如 22:00-08:00
+	return minute >= start || minute < end
+}
+
+// validCategory 检查分类是否有效
+func validCategory(category string) bool {
+	for _, c := range Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock 解析 HH:MM，返回当天的分钟数
+func parseClock(s string) (int, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("无效的时间: %q", s)
+	}
+	hour, err1 := strconv.Atoi(parts[0])
+	minute, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("无效的时间: %q", s)
+	}
+	return hour*60 + minute, nil
+}
@@ -0,0 +1,401 @@
+// Package oauth 第三方登录（Google、GitHub 与通用 OIDC）：授权码模式 + PKCE，
+// state 与 verifier 保存在状态存储中（一次性），回调时按提供方与用户标识查找或自动创建用户
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/metrics"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/store"
+	"new-openclaw/pkg/config"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUnavailable 数据库未连接
+	ErrUnavailable = errors.New("数据库未连接")
+	// ErrUnknownProvider 提供方不存在或未配置
+	ErrUnknownProvider = errors.New("不支持的登录方式")
+	// ErrInvalidRedirect 登录完成后的跳转地址不在允许列表中
+	ErrInvalidRedirect = errors.New("不允许的跳转地址")
+	// ErrInvalidState state 不存在、已过期或已使用
+	ErrInvalidState = errors.New("登录请求已失效，请重新登录")
+	// ErrRegistrationDisabled 第三方身份未绑定用户且未开启自动注册
+	ErrRegistrationDisabled = errors.New("该账号未注册")
+	// ErrUserDisabled 用户已被禁用
+	ErrUserDisabled = errors.New("用户已被禁用")
+)
+
+// CallbackPath 回调地址路径（:provider 为提供方名称）
+const CallbackPath = "/api/v1/public/oauth/:provider/callback"
+
+var (
+	cfg       config.OAuthConfig
+	providers = make(map[string]*Provider)
+	client    = &http.Client{Timeout: 10 * time.Second}
+	mu        sync.RWMutex
+
+	loginsTotal = metrics.NewCounterVec("oauth_logins_total", "第三方登录次数", "provider", "result")
+
+	usernameInvalid = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+)
+
+// Configure 设置回调地址与提供方（配置了 ClientID 的提供方启用），baseURL 为未配置 OAUTH_CALLBACK_BASE_URL 时的回调地址前缀
+func Configure(c config.OAuthConfig, baseURL string) error {
+	enabled := make(map[string]*Provider)
+	if c.Google.ClientID != "" {
+		enabled[ProviderGoogle] = newGoogle(c.Google.ClientID, c.Google.ClientSecret, c.Google.Scopes)
+	}
+	if c.GitHub.ClientID != "" {
+		enabled[ProviderGitHub] = newGitHub(c.GitHub.ClientID, c.GitHub.ClientSecret, c.GitHub.Scopes)
+	}
+	if c.OIDC.ClientID != "" {
+		if c.OIDC.Issuer == "" {
+			return errors.New("通用 OIDC 未配置 Issuer")
+		}
+		if _, ok := enabled[c.OIDC.Name]; ok || c.OIDC.Name == "" {
+			return fmt.Errorf("通用 OIDC 的名称 %q 无效或与内置提供方重复", c.OIDC.Name)
+		}
+		enabled[c.OIDC.Name] = newOIDC(c.OIDC.Name, c.OIDC.Issuer, c.OIDC.ClientID, c.OIDC.ClientSecret, c.OIDC.Scopes)
+	}
+
+	if c.CallbackBaseURL == "" {
+		c.CallbackBaseURL = baseURL
+	}
+	if len(enabled) > 0 && c.CallbackBaseURL == "" {
+		return errors.New("启用第三方登录需要配置 OAUTH_CALLBACK_BASE_URL 或 APP_BASE_URL")
+	}
+	c.CallbackBaseURL = strings.TrimSuffix(c.CallbackBaseURL, "/")
+
+	mu.Lock()
+	defer mu.Unlock()
+	cfg, providers = c, enabled
+	if c.Timeout > 0 {
+		client = &http.Client{Timeout: c.Timeout}
+	}
+	return nil
+}
+
+// Providers 已启用的提供方名称
+func Providers() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// pending 授权中的登录请求（以 state 为 Key 保存）
+type pending struct {
+	Provider string `json:"provider"`
+	Verifier string `json:"verifier"`
+	Redirect string `json:"redirect,omitempty"`
+}
+
+// Begin 开始登录：生成 state 与 PKCE verifier 并保存，返回第三方授权页地址与 state（调用方用 StateCookie 绑定到浏览器）。
+// redirect 为登录完成后跳转的前端地址（须在 OAUTH_ALLOWED_REDIRECTS 中，为空时回调返回 JSON）
+func Begin(ctx context.Context, name, redirect string) (string, string, error) {
+	p, c, err := provider(name)
+	if err != nil {
+		return "", "", err
+	}
+	if redirect != "" && !AllowedRedirect(c.AllowedRedirects, redirect) {
+		return "", "", ErrInvalidRedirect
+	}
+	if err := p.endpoints(ctx, client); err != nil {
+		return "", "", err
+	}
+
+	state, err := randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	data, _ := json.Marshal(pending{Provider: name, Verifier: verifier, Redirect: redirect})
+	if err := store.For(store.ComponentNonce).Set(ctx, stateKey(state), string(data), c.StateTTL); err != nil {
+		return "", "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	return p.authCodeURL(callbackURL(c, name), state, base64.RawURLEncoding.EncodeToString(challenge[:])), state, nil
+}
+
+// StateCookieName 保存 state 摘要的 Cookie：回调时要求与 state 一致，
+// 防止他人发起的登录（将回调地址发给受害者）在受害者的浏览器中完成（登录 CSRF）
+const StateCookieName = "oauth_state"
+
+// StateCookie 绑定 state 的 HttpOnly Cookie（仅发送到该提供方的回调地址，state 为空时清除）
+func StateCookie(name, state string) *http.Cookie {
+	_, c, _ := provider(name)
+	cookie := &http.Cookie{
+		Name:     StateCookieName,
+		Path:     strings.Replace(CallbackPath, ":provider", name, 1),
+		MaxAge:   int(c.StateTTL / time.Second),
+		Secure:   strings.HasPrefix(c.CallbackBaseURL, "https://"),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if state == "" {
+		cookie.MaxAge = -1
+		return cookie
+	}
+	cookie.Value = stateDigest(state)
+	return cookie
+}
+
+// CheckStateCookie 回调的 state 是否由当前浏览器发起（cookie 为请求携带的 StateCookieName）
+func CheckStateCookie(cookie, state string) bool {
+	return state != "" && subtle.ConstantTimeCompare([]byte(cookie), []byte(stateDigest(state))) == 1
+}
+
+func stateDigest(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+// Result 登录结果
+type Result struct {
+	User *model.User
+	// 本次登录自动创建了用户
+	Created bool
+	// 登录开始时指定的跳转地址
+	Redirect string
+}
+
+// Redirect 读取并作废 state，返回登录开始时指定的跳转地址（用户在第三方页面拒绝授权时使用）
+func Redirect(ctx context.Context, name, state string) (string, error) {
+	login, err := consume(ctx, name, state)
+	if err != nil {
+		return "", err
+	}
+	return login.Redirect, nil
+}
+
+// Complete 完成登录：校验 state，用授权码与 verifier 换取 Access Token，读取用户信息并查找或创建用户
+func Complete(ctx context.Context, name, code, state string) (*Result, error) {
+	p, c, err := provider(name)
+	if err != nil {
+		return nil, err
+	}
+	login, err := consume(ctx, name, state)
+	if err != nil {
+		loginsTotal.Inc(name, "invalid_state")
+		return nil, err
+	}
+	result := &Result{Redirect: login.Redirect}
+
+	accessToken, err := p.exchange(ctx, client, code, callbackURL(c, name), login.Verifier)
+	if err != nil {
+		loginsTotal.Inc(name, "failed")
+		return result, fmt.Errorf("换取 Access Token 失败: %w", err)
+	}
+	profile, err := p.fetchProfile(ctx, client, accessToken)
+	if err != nil {
+		loginsTotal.Inc(name, "failed")
+		return result, err
+	}
+
+	result.User, result.Created, err = provision(ctx, name, profile, c.AutoRegister)
+	if err != nil {
+		loginsTotal.Inc(name, "rejected")
+		return result, err
+	}
+	loginsTotal.Inc(name, "success")
+	return result, nil
+}
+
+// consume 读取 state 对应的登录请求并作废（同一 state 只能使用一次）
+func consume(ctx context.Context, name, state string) (*pending, error) {
+	if state == "" {
+		return nil, ErrInvalidState
+	}
+	s := store.For(store.ComponentNonce)
+	value, err := s.Get(ctx, stateKey(state))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrInvalidState
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 并发的两次回调只有一次能通过
+	ok, err := s.SetNX(ctx, stateKey(state)+":used", "1", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidState
+	}
+	s.Del(ctx, stateKey(state))
+
+	var login pending
+	if err := json.Unmarshal([]byte(value), &login); err != nil || login.Provider != name {
+		return nil, ErrInvalidState
+	}
+	return &login, nil
+}
+
+// provision 按提供方与用户标识查找绑定的用户，未绑定时（允许自动注册）创建用户并绑定
+func provision(ctx context.Context, name string, profile *Profile, autoRegister bool) (*model.User, bool, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, false, ErrUnavailable
+	}
+	db = db.WithContext(ctx)
+	now := time.Now()
+
+	var identity model.UserIdentity
+	err := db.Where("provider = ? AND subject = ?", name, profile.Subject).First(&identity).Error
+	if err == nil {
+		var user model.User
+		if err := db.First(&user, identity.UserID).Error; err != nil {
+			return nil, false, err
+		}
+		if user.Status != model.UserStatusActive {
+			return nil, false, ErrUserDisabled
+		}
+		db.Model(&identity).Updates(map[string]interface{}{"last_login_at": now, "email": profile.Email})
+		db.Model(&user).Update("last_login_at", now)
+		user.LastLoginAt = &now
+		return &user, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+	if !autoRegister {
+		return nil, false, ErrRegistrationDisabled
+	}
+
+	user := model.User{
+		Email:       profile.Email,
+		Nickname:    profile.Name,
+		Avatar:      profile.Avatar,
+		Role:        "user",
+		Status:      model.UserStatusActive,
+		LastLoginAt: &now,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		username, err := uniqueUsername(tx, name, profile)
+		if err != nil {
+			return err
+		}
+		user.Username = username
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.UserIdentity{
+			UserID:      user.ID,
+			Provider:    name,
+			Subject:     profile.Subject,
+			Email:       profile.Email,
+			LastLoginAt: &now,
+		}).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &user, true, nil
+}
+
+// uniqueUsername 由第三方用户名（或邮箱前缀）生成未被占用的用户名，重名时追加随机后缀
+func uniqueUsername(tx *gorm.DB, name string, profile *Profile) (string, error) {
+	base := profile.Username
+	if base == "" {
+		base, _, _ = strings.Cut(profile.Email, "@")
+	}
+	base = strings.Trim(usernameInvalid.ReplaceAllString(base, "_"), "_.-")
+	if base == "" {
+		base = name + "_user"
+	}
+	if len(base) > 48 {
+		base = base[:48]
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		var count int64
+		if err := tx.Model(&model.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		suffix, err := randomHex(3)
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "_" + suffix
+	}
+	return "", errors.New("生成用户名失败")
+}
+
+func provider(name string) (*Provider, config.OAuthConfig, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := providers[name]
+	if !ok {
+		return nil, cfg, ErrUnknownProvider
+	}
+	return p, cfg, nil
+}
+
+func callbackURL(c config.OAuthConfig, name string) string {
+	return c.CallbackBaseURL + strings.Replace(CallbackPath, ":provider", name, 1)
+}
+
+// AllowedRedirect 跳转地址是否以允许的前缀开头（前缀不以 / 结尾时，其后须为路径、查询或片段的分隔符，
+// 避免 https://app.example.com 匹配 https://app.example.com.evil.com）
+func AllowedRedirect(prefixes []string, redirect string) bool {
+	for _, prefix := range prefixes {
+		if !strings.HasPrefix(redirect, prefix) {
+			continue
+		}
+		rest := redirect[len(prefix):]
+		if rest == "" || strings.HasSuffix(prefix, "/") || strings.ContainsAny(rest[:1], "/?#") {
+			return true
+		}
+	}
+	return false
+}
+
+func stateKey(state string) string {
+	return "oauth:" + state
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
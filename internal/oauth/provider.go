@@ -0,0 +1,281 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 内置的提供方名称
+const (
+	ProviderGoogle = "google"
+	ProviderGitHub = "github"
+)
+
+// maxResponseSize 第三方接口响应的读取上限
+const maxResponseSize = 1 << 20
+
+// Profile 第三方返回的用户信息
+type Profile struct {
+	// 提供方内的用户标识（OIDC 的 sub、GitHub 的用户 ID）
+	Subject  string
+	Username string
+	Email    string
+	Name     string
+	Avatar   string
+}
+
+// Provider 第三方登录提供方（授权码模式 + PKCE）
+type Provider struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+
+	// 通用 OIDC：首次使用时从 discovery 文档获取端点
+	issuer     string
+	discovered bool
+	mu         sync.Mutex
+
+	// 读取用户信息（为空按 OIDC userinfo 的标准字段解析）
+	profile func(ctx context.Context, client *http.Client, p *Provider, accessToken string) (*Profile, error)
+}
+
+// newGoogle Google（OIDC，端点固定）
+func newGoogle(clientID, clientSecret string, scopes []string) *Provider {
+	return &Provider{
+		Name:         ProviderGoogle,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       scopes,
+		AuthURL:      "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL:     "https://oauth2.googleapis.com/token",
+		UserInfoURL:  "https://openidconnect.googleapis.com/v1/userinfo",
+		discovered:   true,
+	}
+}
+
+// newGitHub GitHub（OAuth2，不支持 OIDC，用户信息来自 REST API）
+func newGitHub(clientID, clientSecret string, scopes []string) *Provider {
+	return &Provider{
+		Name:         ProviderGitHub,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       scopes,
+		AuthURL:      "https://github.com/login/oauth/authorize",
+		TokenURL:     "https://github.com/login/oauth/access_token",
+		UserInfoURL:  "https://api.github.com/user",
+		discovered:   true,
+		profile:      gitHubProfile,
+	}
+}
+
+// newOIDC 通用 OIDC 提供方
+func newOIDC(name, issuer, clientID, clientSecret string, scopes []string) *Provider {
+	return &Provider{
+		Name:         name,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       scopes,
+		issuer:       strings.TrimSuffix(issuer, "/"),
+	}
+}
+
+// endpoints 确保端点可用（通用 OIDC 首次使用时读取 discovery 文档，失败后下次重试）
+func (p *Provider) endpoints(ctx context.Context, client *http.Client) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovered {
+		return nil
+	}
+
+	var doc struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	}
+	if err := getJSON(ctx, client, p.issuer+"/.well-known/openid-configuration", nil, &doc); err != nil {
+		return fmt.Errorf("读取 OIDC discovery 文档失败: %w", err)
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != p.issuer {
+		return fmt.Errorf("OIDC discovery 文档的 issuer %q 与配置不一致", doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.UserInfoEndpoint == "" {
+		return errors.New("OIDC discovery 文档缺少授权、Token 或 userinfo 端点")
+	}
+	p.AuthURL, p.TokenURL, p.UserInfoURL = doc.AuthorizationEndpoint, doc.TokenEndpoint, doc.UserInfoEndpoint
+	p.discovered = true
+	return nil
+}
+
+// authCodeURL 第三方授权页地址
+func (p *Provider) authCodeURL(redirectURI, state, challenge string) string {
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {strings.Join(p.Scopes, " ")},
+		"state":                 {state},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(p.AuthURL, "?") {
+		sep = "&"
+	}
+	return p.AuthURL + sep + query.Encode()
+}
+
+// exchange 用授权码换取 Access Token
+func (p *Provider) exchange(ctx context.Context, client *http.Client, code, redirectURI, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// GitHub 默认返回表单格式
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&result); err != nil {
+		return "", fmt.Errorf("解析 Token 响应失败（HTTP %d）: %w", resp.StatusCode, err)
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("%s: %s", result.Error, result.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || result.AccessToken == "" {
+		return "", fmt.Errorf("Token 端点返回 HTTP %d", resp.StatusCode)
+	}
+	return result.AccessToken, nil
+}
+
+// fetchProfile 读取用户信息
+func (p *Provider) fetchProfile(ctx context.Context, client *http.Client, accessToken string) (*Profile, error) {
+	if p.profile != nil {
+		return p.profile(ctx, client, p, accessToken)
+	}
+
+	var info struct {
+		Subject           string `json:"sub"`
+		PreferredUsername string `json:"preferred_username"`
+		Email             string `json:"email"`
+		Name              string `json:"name"`
+		Picture           string `json:"picture"`
+	}
+	if err := getJSON(ctx, client, p.UserInfoURL, bearer(accessToken), &info); err != nil {
+		return nil, fmt.Errorf("读取用户信息失败: %w", err)
+	}
+	if info.Subject == "" {
+		return nil, errors.New("用户信息缺少 sub")
+	}
+	return &Profile{
+		Subject:  info.Subject,
+		Username: info.PreferredUsername,
+		Email:    info.Email,
+		Name:     info.Name,
+		Avatar:   info.Picture,
+	}, nil
+}
+
+// gitHubProfile GitHub 用户信息；邮箱未公开时从 /user/emails 取已验证的主邮箱
+func gitHubProfile(ctx context.Context, client *http.Client, p *Provider, accessToken string) (*Profile, error) {
+	header := bearer(accessToken)
+	header.Set("Accept", "application/vnd.github+json")
+
+	var user struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		Email     string `json:"email"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := getJSON(ctx, client, p.UserInfoURL, header, &user); err != nil {
+		return nil, fmt.Errorf("读取用户信息失败: %w", err)
+	}
+	if user.ID == 0 {
+		return nil, errors.New("用户信息缺少 id")
+	}
+
+	if user.Email == "" {
+		var emails []struct {
+			Email    string `json:"email"`
+			Primary  bool   `json:"primary"`
+			Verified bool   `json:"verified"`
+		}
+		// 未授权 user:email 时读取失败，邮箱留空
+		if err := getJSON(ctx, client, strings.TrimSuffix(p.UserInfoURL, "/user")+"/user/emails", header, &emails); err == nil {
+			for _, e := range emails {
+				if e.Primary && e.Verified {
+					user.Email = e.Email
+				}
+			}
+		}
+	}
+
+	return &Profile{
+		Subject:  strconv.FormatInt(user.ID, 10),
+		Username: user.Login,
+		Email:    user.Email,
+		Name:     user.Name,
+		Avatar:   user.AvatarURL,
+	}, nil
+}
+
+func bearer(accessToken string) http.Header {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+accessToken)
+	header.Set("Accept", "application/json")
+	return header
+}
+
+func getJSON(ctx context.Context, client *http.Client, target string, header http.Header, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	// GitHub API 要求 User-Agent
+	req.Header.Set("User-Agent", "new-openclaw")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v)
+}
@@ -0,0 +1,164 @@
+// Package passwordreset 忘记密码：向注册邮箱发送一次性、限时的重置令牌，凭令牌设置新密码。
+// 令牌只以摘要形式保存在 password_reset 存储中，每个用户同时只有最近签发的一个令牌有效
+package passwordreset
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/mail"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/store"
+	"new-openclaw/pkg/config"
+	"new-openclaw/pkg/password"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidToken 令牌不存在、已过期或已使用
+	ErrInvalidToken = errors.New("重置链接无效或已过期，请重新申请")
+	// ErrTooFrequent 发送过于频繁
+	ErrTooFrequent = errors.New("重置邮件发送过于频繁，请稍后再试")
+	// ErrUnavailable 存储或数据库不可用
+	ErrUnavailable = errors.New("重置密码服务暂不可用")
+)
+
+var cfg = config.PasswordResetConfig{
+	TTL:            30 * time.Minute,
+	ResendInterval: time.Minute,
+}
+
+// Configure 设置令牌有效期、发送间隔与前端重置页面地址
+func Configure(c config.PasswordResetConfig) {
+	if c.TTL <= 0 {
+		c.TTL = 30 * time.Minute
+	}
+	cfg = c
+}
+
+// Request 为用户签发重置令牌并发送邮件，之前签发的令牌随即失效
+func Request(ctx context.Context, u *model.User) error {
+	s := store.For(store.ComponentPasswordReset)
+	id := strconv.FormatUint(uint64(u.ID), 10)
+
+	if cfg.ResendInterval > 0 {
+		first, err := s.SetNX(ctx, "sent:"+id, "1", cfg.ResendInterval)
+		if err != nil {
+			return err
+		}
+		if !first {
+			return ErrTooFrequent
+		}
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	hash := digest(token)
+
+	if previous, err := s.Get(ctx, "user:"+id); err == nil {
+		s.Del(ctx, "token:"+previous)
+	}
+	if err := s.Set(ctx, "token:"+hash, id, cfg.TTL); err != nil {
+		return err
+	}
+	if err := s.Set(ctx, "user:"+id, hash, cfg.TTL); err != nil {
+		return err
+	}
+
+	return mail.Send(u.Email, "重置密码", body(u, token))
+}
+
+// body 重置邮件正文：配置了前端页面时发送链接，否则发送令牌与接口说明
+func body(u *model.User, token string) string {
+	action := fmt.Sprintf("请在 %s 内使用以下令牌调用 POST /api/v1/public/reset-password 设置新密码：\n\n%s", cfg.TTL, token)
+	if cfg.URL != "" {
+		sep := "?"
+		if strings.Contains(cfg.URL, "?") {
+			sep = "&"
+		}
+		action = fmt.Sprintf("请在 %s 内打开以下链接设置新密码：\n\n%s", cfg.TTL, cfg.URL+sep+url.Values{"token": {token}}.Encode())
+	}
+	return fmt.Sprintf("%s，您好：\n\n我们收到了重置您账号密码的请求。%s\n\n重置后所有已登录的设备都需要重新登录。如果这不是您本人的操作，请忽略本邮件，您的密码不会改变。",
+		u.Username, action)
+}
+
+// Reset 校验令牌并设置新密码（需符合密码策略）。令牌只能成功使用一次；新密码不符合策略时令牌仍然有效，可以换一个密码重试。
+// 通过邮件完成重置同时证明了邮箱归属，未验证的邮箱一并标记为已验证
+func Reset(ctx context.Context, token, plain string) (*model.User, error) {
+	s := store.For(store.ComponentPasswordReset)
+	hash := digest(token)
+
+	id, err := s.Get(ctx, "token:"+hash)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		log.Printf("读取重置令牌失败: %v", err)
+		return nil, ErrUnavailable
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	var user model.User
+	if err := db.WithContext(ctx).First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		log.Printf("查询重置密码用户失败: %v", err)
+		return nil, ErrUnavailable
+	}
+	if user.Status != model.UserStatusActive {
+		return nil, ErrInvalidToken
+	}
+
+	if err := password.Validate(plain, user.Username, user.Email); err != nil {
+		return nil, err
+	}
+	if err := user.SetPassword(plain); err != nil {
+		return nil, ErrUnavailable
+	}
+
+	// 并发提交同一令牌时只有一个请求能继续
+	first, err := s.SetNX(ctx, "used:"+hash, "1", cfg.TTL)
+	if err != nil {
+		return nil, ErrUnavailable
+	}
+	if !first {
+		return nil, ErrInvalidToken
+	}
+	s.Del(ctx, "token:"+hash, "user:"+id)
+
+	updates := map[string]interface{}{"password": user.Password}
+	if !user.EmailVerified() {
+		now := time.Now()
+		updates["email_verified_at"] = now
+		user.EmailVerifiedAt = &now
+	}
+	if err := db.WithContext(ctx).Model(&user).Updates(updates).Error; err != nil {
+		log.Printf("保存新密码失败: user=%s err=%v", user.Username, err)
+		return nil, ErrUnavailable
+	}
+	return &user, nil
+}
+
+func digest(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
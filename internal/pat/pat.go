@@ -0,0 +1,252 @@
+// Package pat 用户个人访问令牌（Personal Access Token）的签发与校验。
+// 令牌以固定前缀 pat_ 开头，认证中间件按前缀与 JWT 区分；库中只保存摘要，校验结果按摘要缓存
+package pat
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/store"
+	"new-openclaw/pkg/config"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound 令牌不存在
+	ErrNotFound = errors.New("无效的个人访问令牌")
+	// ErrRevoked 令牌已吊销
+	ErrRevoked = errors.New("个人访问令牌已吊销")
+	// ErrExpired 令牌已过期
+	ErrExpired = errors.New("个人访问令牌已过期")
+	// ErrUserDisabled 令牌所属用户已禁用或已删除
+	ErrUserDisabled = errors.New("账号已禁用")
+	// ErrUnavailable 数据库不可用等原因无法完成校验
+	ErrUnavailable = errors.New("个人访问令牌校验暂不可用")
+)
+
+// Prefix 令牌的固定前缀（认证中间件据此区分个人访问令牌与 JWT，也便于扫描泄露的令牌）
+const Prefix = "pat_"
+
+// tokenLength 令牌总长度（前缀 + 20 字节随机数的十六进制）
+const tokenLength = len(Prefix) + 40
+
+// displayLength 列表、日志中展示的令牌前缀长度
+const displayLength = len(Prefix) + 8
+
+// missingTTL 不存在的令牌的缓存时间
+const missingTTL = 30 * time.Second
+
+// lastUsedInterval 最近使用时间的写库间隔
+const lastUsedInterval = time.Minute
+
+// cfg 个人访问令牌配置
+var cfg = config.PersonalTokenConfig{
+	MaxTTL:     365 * 24 * time.Hour,
+	MaxPerUser: 20,
+	CacheTTL:   time.Minute,
+}
+
+// Configure 设置有效期上限、数量上限与缓存时间
+func Configure(c config.PersonalTokenConfig) {
+	cfg = c
+}
+
+// Config 当前配置
+func Config() config.PersonalTokenConfig {
+	return cfg
+}
+
+// Identity 通过认证的个人访问令牌及其所属用户（用户信息为校验时的最新状态）
+type Identity struct {
+	ID            uint
+	Prefix        string
+	UserID        uint
+	Username      string
+	Role          string
+	EmailVerified bool
+	// 令牌授予的权限范围（实际生效的范围还受用户当前角色限制）
+	Scopes []string
+}
+
+// credential 缓存的校验结果（缓存键为令牌摘要，不含明文）
+type credential struct {
+	Missing       bool       `json:"missing,omitempty"`
+	ID            uint       `json:"id,omitempty"`
+	Prefix        string     `json:"prefix,omitempty"`
+	Scopes        []string   `json:"scopes,omitempty"`
+	Revoked       bool       `json:"revoked,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	UserID        uint       `json:"user_id,omitempty"`
+	Username      string     `json:"username,omitempty"`
+	Role          string     `json:"role,omitempty"`
+	UserActive    bool       `json:"user_active,omitempty"`
+	EmailVerified bool       `json:"email_verified,omitempty"`
+}
+
+// Authenticate 校验令牌的状态、有效期及所属用户状态，并按间隔记录最近使用时间与 IP
+func Authenticate(ctx context.Context, token, clientIP string) (*Identity, error) {
+	if !strings.HasPrefix(token, Prefix) || len(token) != tokenLength {
+		return nil, ErrNotFound
+	}
+
+	cred, err := lookup(ctx, Hash(token))
+	if err != nil {
+		log.Printf("个人访问令牌校验失败: prefix=%s err=%v", token[:displayLength], err)
+		return nil, ErrUnavailable
+	}
+
+	now := time.Now()
+	switch {
+	case cred.Missing:
+		return nil, ErrNotFound
+	case cred.Revoked:
+		return nil, ErrRevoked
+	case cred.ExpiresAt != nil && now.After(*cred.ExpiresAt):
+		return nil, ErrExpired
+	case !cred.UserActive:
+		return nil, ErrUserDisabled
+	}
+
+	touch(ctx, cred.ID, clientIP, now)
+	return &Identity{
+		ID:            cred.ID,
+		Prefix:        cred.Prefix,
+		UserID:        cred.UserID,
+		Username:      cred.Username,
+		Role:          cred.Role,
+		EmailVerified: cred.EmailVerified,
+		Scopes:        cred.Scopes,
+	}, nil
+}
+
+// Invalidate 清除令牌摘要对应的缓存（吊销后调用）
+func Invalidate(ctx context.Context, hashes ...string) error {
+	keys := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		if hash != "" {
+			keys = append(keys, cacheKey(hash))
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return store.For(store.ComponentAPIKey).Del(ctx, keys...)
+}
+
+// InvalidateUser 清除用户全部令牌的缓存（用户被禁用、删除或修改角色后调用，立即按新状态校验）
+func InvalidateUser(ctx context.Context, db *gorm.DB, userID uint) error {
+	var hashes []string
+	if err := db.Model(&model.PersonalAccessToken{}).Where("user_id = ?", userID).Pluck("token_hash", &hashes).Error; err != nil {
+		return err
+	}
+	return Invalidate(ctx, hashes...)
+}
+
+// lookup 先查缓存，未命中时查库（令牌及所属用户）并写入缓存
+func lookup(ctx context.Context, hash string) (*credential, error) {
+	s := store.For(store.ComponentAPIKey)
+
+	if raw, err := s.Get(ctx, cacheKey(hash)); err == nil {
+		var cached credential
+		if json.Unmarshal([]byte(raw), &cached) == nil {
+			return &cached, nil
+		}
+	}
+
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, errors.New("数据库未连接")
+	}
+
+	var token model.PersonalAccessToken
+	err := db.Where("token_hash = ?", hash).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		cred := &credential{Missing: true}
+		cache(ctx, s, hash, cred, missingTTL)
+		return cred, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cred := &credential{
+		ID:        token.ID,
+		Prefix:    token.Prefix,
+		Scopes:    token.ScopeList(),
+		Revoked:   token.RevokedAt != nil,
+		ExpiresAt: token.ExpiresAt,
+		UserID:    token.UserID,
+	}
+	var user model.User
+	err = db.First(&user, token.UserID).Error
+	switch {
+	case err == nil:
+		cred.Username = user.Username
+		cred.Role = user.Role
+		cred.UserActive = user.Status == model.UserStatusActive
+		cred.EmailVerified = user.EmailVerified()
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+	cache(ctx, s, hash, cred, cfg.CacheTTL)
+	return cred, nil
+}
+
+// cache 写入缓存（失败只影响性能，不影响认证）
+func cache(ctx context.Context, s store.Store, hash string, cred *credential, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return
+	}
+	s.Set(ctx, cacheKey(hash), string(data), ttl)
+}
+
+// touch 更新最近使用时间与 IP；同一令牌在 lastUsedInterval 内只写一次库
+func touch(ctx context.Context, id uint, clientIP string, now time.Time) {
+	acquired, err := store.For(store.ComponentAPIKey).SetNX(ctx, "pat-used:"+strconv.FormatUint(uint64(id), 10), "1", lastUsedInterval)
+	if err != nil || !acquired {
+		return
+	}
+	db := database.GetMySQL()
+	if db == nil {
+		return
+	}
+	db.Model(&model.PersonalAccessToken{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"last_used_at": now,
+		"last_used_ip": clientIP,
+	})
+}
+
+// Generate 生成新的令牌，返回明文、展示用前缀及摘要（只有摘要入库）
+func Generate() (token, prefix, hash string, err error) {
+	b := make([]byte, 20)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	token = Prefix + hex.EncodeToString(b)
+	return token, token[:displayLength], Hash(token), nil
+}
+
+// Hash 令牌的 SHA-256 摘要（令牌为高熵随机数，无需加盐的慢哈希）
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func cacheKey(hash string) string {
+	return "pat:" + hash
+}
@@ -0,0 +1,183 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/store"
+	"new-openclaw/pkg/config"
+)
+
+// ErrCodeQuotaExceeded 配额超限错误码（区别于频率限制的 429）
+const ErrCodeQuotaExceeded = 42901
+
+// 配额周期
+const (
+	PeriodDaily   = "daily"
+	PeriodMonthly = "monthly"
+)
+
+// limitCacheTTL 配额上限缓存时间（管理端修改后最长经过该时间生效）
+const limitCacheTTL = time.Minute
+
+// Limits 配额上限（0 表示不限制）
+type Limits struct {
+	Daily   int64 `json:"daily"`
+	Monthly int64 `json:"monthly"`
+}
+
+// Usage 配额使用情况
+type Usage struct {
+	AppKey       string    `json:"app_key"`
+	Limits       Limits    `json:"limits"`
+	Daily        int64     `json:"daily"`
+	Monthly      int64     `json:"monthly"`
+	DailyReset   time.Time `json:"daily_reset"`
+	MonthlyReset time.Time `json:"monthly_reset"`
+	// 超限的周期（未超限为空）
+	Exceeded string `json:"exceeded,omitempty"`
+}
+
+type cachedLimits struct {
+	limits  Limits
+	expires time.Time
+}
+
+var (
+	defaults = Limits{}
+
+	cacheMu sync.RWMutex
+	cache   = make(map[string]cachedLimits)
+)
+
+// Configure 设置全局默认配额
+func Configure(cfg config.QuotaConfig) {
+	defaults = Limits{Daily: cfg.DailyLimit, Monthly: cfg.MonthlyLimit}
+}
+
+// Consume 消耗一次配额，超限时返回的 Usage.Exceeded 不为空且不计入用量
+// 先检查后计数，并发请求下可能略微超出上限
+func Consume(ctx context.Context, appKey string) (Usage, error) {
+	usage, err := GetUsage(ctx, appKey)
+	if err != nil {
+		return usage, err
+	}
+
+	switch {
+	case usage.Limits.Daily > 0 && usage.Daily >= usage.Limits.Daily:
+		usage.Exceeded = PeriodDaily
+		return usage, nil
+	case usage.Limits.Monthly > 0 && usage.Monthly >= usage.Limits.Monthly:
+		usage.Exceeded = PeriodMonthly
+		return usage, nil
+	}
+
+	s := store.For(store.ComponentQuota)
+	dailyKey, monthlyKey := usageKeys(appKey, time.Now())
+
+	if usage.Daily, err = incr(ctx, s, dailyKey, usage.DailyReset); err != nil {
+		return usage, err
+	}
+	if usage.Monthly, err = incr(ctx, s, monthlyKey, usage.MonthlyReset); err != nil {
+		return usage, err
+	}
+	return usage, nil
+}
+
+// GetUsage 查询配额使用情况（不消耗配额）
+func GetUsage(ctx context.Context, appKey string) (Usage, error) {
+	now := time.Now()
+	usage := newUsage(appKey, now)
+	s := store.For(store.ComponentQuota)
+
+	dailyKey, monthlyKey := usageKeys(appKey, now)
+
+	var err error
+	if usage.Daily, err = getCount(ctx, s, dailyKey); err != nil {
+		return usage, err
+	}
+	if usage.Monthly, err = getCount(ctx, s, monthlyKey); err != nil {
+		return usage, err
+	}
+	return usage, nil
+}
+
+// Reset 清零当前周期用量
+func Reset(ctx context.Context, appKey string) error {
+	dailyKey, monthlyKey := usageKeys(appKey, time.Now())
+	return store.For(store.ComponentQuota).Del(ctx, dailyKey, monthlyKey)
+}
+
+// Invalidate 清除配额上限缓存（管理端修改后调用）
+func Invalidate(appKey string) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	delete(cache, appKey)
+}
+
+// Defaults 获取全局默认配额
+func Defaults() Limits {
+	return defaults
+}
+
+// LimitsFor 获取 AppKey 的配额上限（优先使用 MySQL 中的单独配置）
+func LimitsFor(appKey string) Limits {
+	cacheMu.RLock()
+	cached, ok := cache[appKey]
+	cacheMu.RUnlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.limits
+	}
+
+	limits := defaults
+	if db := database.GetMySQL(); db != nil {
+		var q model.AppKeyQuota
+		if err := db.Where("app_key = ?", appKey).First(&q).Error; err == nil {
+			limits = Limits{Daily: q.DailyLimit, Monthly: q.MonthlyLimit}
+		}
+	}
+
+	cacheMu.Lock()
+	cache[appKey] = cachedLimits{limits: limits, expires: time.Now().Add(limitCacheTTL)}
+	cacheMu.Unlock()
+
+	return limits
+}
+
+func newUsage(appKey string, now time.Time) Usage {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	return Usage{
+		AppKey:       appKey,
+		Limits:       LimitsFor(appKey),
+		DailyReset:   day.AddDate(0, 0, 1),
+		MonthlyReset: month.AddDate(0, 1, 0),
+	}
+}
+
+// usageKeys 生成当前日/月的计数 Key
+func usageKeys(appKey string, now time.Time) (string, string) {
+	return "daily:" + appKey + ":" + now.Format("20060102"),
+		"monthly:" + appKey + ":" + now.Format("200601")
+}
+
+func getCount(ctx context.Context, s store.Store, key string) (int64, error) {
+	value, err := s.Get(ctx, key)
+	if errors.Is(err, store.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// incr 计数加一，首次计数时设置过期时间（周期结束后多保留一小时便于查询）
+func incr(ctx context.Context, s store.Store, key string, reset time.Time) (int64, error) {
+	return s.IncrWithTTL(ctx, key, time.Until(reset)+time.Hour)
+}
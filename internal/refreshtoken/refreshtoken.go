@@ -0,0 +1,145 @@
+// Package refreshtoken 用户刷新 Token 的服务端登记与轮换。
+// 每次登录开启一个令牌族（family），存储中只记录该族当前有效的刷新 Token（jti）；刷新时签发同族的新 Token，旧 Token 随即失效。
+// 已被轮换的旧 Token 再次出现说明可能已泄露，整个令牌族作废，持有者需要重新登录
+package refreshtoken
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/store"
+)
+
+var (
+	// ErrInvalid 刷新 Token 无效、已过期、已吊销或未登记
+	ErrInvalid = errors.New("刷新令牌无效或已过期，请重新登录")
+	// ErrReused 已轮换的刷新 Token 被再次使用（令牌族已作废）
+	ErrReused = errors.New("刷新令牌已被使用，请重新登录")
+)
+
+// family 令牌族记录
+type family struct {
+	UserID string `json:"user_id"`
+	// 登录时的用户名：刷新时核对，用户改名后需要重新登录
+	Username string `json:"username"`
+	// 当前有效的刷新 Token
+	TokenID string `json:"jti"`
+}
+
+// Rotation 刷新 Token 轮换结果
+type Rotation struct {
+	// 原刷新 Token 的声明（签名有效时返回，失败时也可用于记录日志）
+	Claims *middleware.RefreshClaims
+	// 登录时的用户名
+	Username string
+	// 同族的新刷新 Token
+	RefreshToken string
+}
+
+// Issue 登录时签发刷新 Token：开启新的令牌族并登记
+func Issue(ctx context.Context, userID, username string, config middleware.JWTConfig) (string, error) {
+	tokenString, claims, err := middleware.GenerateRefreshTokenInFamily(userID, "", config)
+	if err != nil {
+		return "", err
+	}
+	if err := save(ctx, claims.Family, family{UserID: userID, Username: username, TokenID: claims.ID}, config.RefreshExpiry); err != nil {
+		return "", err
+	}
+	return tokenString, nil
+}
+
+// Rotate 校验刷新 Token（签名、有效期、黑名单、类型及登记记录）并轮换为同族的新刷新 Token
+func Rotate(ctx context.Context, tokenString string, config middleware.JWTConfig) (*Rotation, error) {
+	claims, err := middleware.ParseRefreshToken(tokenString, config)
+	if err != nil || claims.Family == "" {
+		// 早期签发、没有令牌族的刷新 Token 同样需要重新登录
+		return nil, ErrInvalid
+	}
+
+	r := &Rotation{Claims: claims}
+	current, err := load(ctx, claims.Family)
+	if errors.Is(err, store.ErrNotFound) {
+		return r, ErrInvalid
+	}
+	if err != nil {
+		return r, err
+	}
+	if current.UserID != claims.Subject {
+		return r, ErrInvalid
+	}
+	if current.TokenID != claims.ID {
+		Revoke(ctx, claims.Family)
+		return r, ErrReused
+	}
+
+	// 并发请求使用同一个刷新 Token 时只有一个能完成轮换
+	ttl := time.Minute
+	if claims.ExpiresAt != nil && time.Until(claims.ExpiresAt.Time) > ttl {
+		ttl = time.Until(claims.ExpiresAt.Time)
+	}
+	first, err := store.For(store.ComponentSession).SetNX(ctx, usedKey(claims.ID), "1", ttl)
+	if err != nil {
+		return r, err
+	}
+	if !first {
+		Revoke(ctx, claims.Family)
+		return r, ErrReused
+	}
+
+	next, nextClaims, err := middleware.GenerateRefreshTokenInFamily(claims.Subject, claims.Family, config)
+	if err != nil {
+		return r, err
+	}
+	current.TokenID = nextClaims.ID
+	if err := save(ctx, claims.Family, *current, config.RefreshExpiry); err != nil {
+		return r, err
+	}
+	r.Username = current.Username
+	r.RefreshToken = next
+	return r, nil
+}
+
+// Revoke 作废令牌族（该族所有刷新 Token 都不能再使用）
+func Revoke(ctx context.Context, familyID string) error {
+	return store.For(store.ComponentSession).Del(ctx, familyKey(familyID))
+}
+
+// Active 刷新 Token 是否仍可使用（声明已通过签名校验）
+func Active(ctx context.Context, claims *middleware.RefreshClaims) bool {
+	if claims.Family == "" {
+		return false
+	}
+	current, err := load(ctx, claims.Family)
+	return err == nil && current.TokenID == claims.ID
+}
+
+func load(ctx context.Context, familyID string) (*family, error) {
+	value, err := store.For(store.ComponentSession).Get(ctx, familyKey(familyID))
+	if err != nil {
+		return nil, err
+	}
+	var f family
+	if err := json.Unmarshal([]byte(value), &f); err != nil {
+		return nil, store.ErrNotFound
+	}
+	return &f, nil
+}
+
+func save(ctx context.Context, familyID string, f family, ttl time.Duration) error {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return store.For(store.ComponentSession).Set(ctx, familyKey(familyID), string(data), ttl)
+}
+
+func familyKey(id string) string {
+	return "refresh:" + id
+}
+
+func usedKey(jti string) string {
+	return "refresh-used:" + jti
+}
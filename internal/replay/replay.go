@@ -0,0 +1,313 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/middleware"
+	"new-openclaw/pkg/config"
+)
+
+var (
+	// ErrNotFound 审计日志中没有该请求
+	ErrNotFound = errors.New("审计日志中未找到该请求")
+	// ErrUnknownTarget 未配置的目标环境
+	ErrUnknownTarget = errors.New("未配置的目标环境")
+)
+
+// maskedValue 审计日志脱敏后的占位值
+const maskedValue = "***MASKED***"
+
+// truncatedSuffix 审计日志截断标记
+const truncatedSuffix = "...(truncated)"
+
+// cfg 重放配置
+var cfg = config.ReplayConfig{
+	DefaultTarget: "staging",
+	Timeout:       10 * time.Second,
+}
+
+// auditFiles 审计日志文件（主文件、备用文件）
+var auditFiles []string
+
+// Configure 设置重放配置及审计日志文件
+func Configure(c config.ReplayConfig, files ...string) {
+	cfg = c
+	auditFiles = nil
+	for _, f := range files {
+		if f != "" {
+			auditFiles = append(auditFiles, f)
+		}
+	}
+}
+
+// Targets 已配置的目标环境名称
+func Targets() []string {
+	names := make([]string, 0, len(cfg.Targets))
+	for name := range cfg.Targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Options 重放选项
+type Options struct {
+	// 目标环境（为空使用默认目标）
+	Target string
+	// 替换的 Authorization 头（审计日志中已脱敏，需要认证的接口由调用方提供目标环境的 Token）
+	Authorization string
+}
+
+// Response 响应摘要
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Body       interface{} `json:"body,omitempty"`
+	LatencyMs  int64       `json:"latency_ms"`
+}
+
+// Diff 响应差异
+type Diff struct {
+	Path     string      `json:"path"`
+	Recorded interface{} `json:"recorded"`
+	Replayed interface{} `json:"replayed"`
+}
+
+// Result 重放结果
+type Result struct {
+	RequestID string    `json:"request_id"`
+	Target    string    `json:"target"`
+	Method    string    `json:"method"`
+	URL       string    `json:"url"`
+	Recorded  Response  `json:"recorded"`
+	Replayed  Response  `json:"replayed"`
+	Diffs     []Diff    `json:"diffs"`
+	Warnings  []string  `json:"warnings,omitempty"`
+	ReplayAt  time.Time `json:"replay_at"`
+}
+
+// Find 按 request_id 在审计日志中查找请求（多次出现时取最后一条）
+func Find(requestID string) (*middleware.AuditLog, error) {
+	var found *middleware.AuditLog
+	for _, path := range auditFiles {
+		entry, err := findInFile(path, requestID)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			found = entry
+		}
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+// findInFile 逐行扫描审计日志文件
+func findInFile(path, requestID string) (*middleware.AuditLog, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var found *middleware.AuditLog
+	needle := []byte(`"request_id":"` + requestID + `"`)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if !bytes.Contains(line, needle) {
+			continue
+		}
+		var entry middleware.AuditLog
+		if err := json.Unmarshal(line, &entry); err == nil && entry.RequestID == requestID {
+			found = &entry
+		}
+	}
+	return found, scanner.Err()
+}
+
+// Replay 将审计日志中的请求重放到目标环境，签名接口使用目标环境密钥重新签名，并对比响应差异
+func Replay(ctx context.Context, entry *middleware.AuditLog, opts Options) (*Result, error) {
+	target := opts.Target
+	if target == "" {
+		target = cfg.DefaultTarget
+	}
+	baseURL, ok := cfg.Targets[target]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTarget, target)
+	}
+
+	result := &Result{
+		RequestID: entry.RequestID,
+		Target:    target,
+		Method:    entry.Method,
+		URL:       strings.TrimRight(baseURL, "/") + entry.Path,
+		Recorded: Response{
+			StatusCode: entry.StatusCode,
+			Body:       decodeBody(entry.ResponseBody),
+			LatencyMs:  entry.Latency,
+		},
+		ReplayAt: time.Now(),
+	}
+	if entry.Query != "" {
+		result.URL += "?" + entry.Query
+	}
+
+	body := entry.RequestBody
+	if strings.HasSuffix(body, truncatedSuffix) {
+		result.Warnings = append(result.Warnings, "请求体在审计日志中被截断，重放的请求体不完整")
+		body = strings.TrimSuffix(body, truncatedSuffix)
+	}
+	if strings.Contains(body, maskedValue) {
+		result.Warnings = append(result.Warnings, "请求体包含脱敏字段，重放时使用占位值")
+	}
+	if strings.HasSuffix(entry.ResponseBody, truncatedSuffix) {
+		result.Warnings = append(result.Warnings, "记录的响应体被截断，仅对比状态码")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, entry.Method, result.URL, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType := entry.Headers["Content-Type"]; contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("User-Agent", "openclaw-replay")
+	req.Header.Set("X-Replay-Of", entry.RequestID)
+	if opts.Authorization != "" {
+		req.Header.Set("Authorization", opts.Authorization)
+	} else if entry.Headers["Authorization"] != "" {
+		result.Warnings = append(result.Warnings, "原请求携带 Authorization（已脱敏），未提供目标环境的 Token")
+	}
+
+	// 原请求经过 API 签名：使用目标环境密钥重新签名（时间戳、nonce 重新生成）
+	if appKey := entry.Headers["X-App-Key"]; appKey != "" {
+		if cfg.SignatureSecret == "" {
+			result.Warnings = append(result.Warnings, "未配置目标环境签名密钥，签名接口将校验失败")
+		} else {
+			middleware.SignRequest(req, []byte(body), appKey, cfg.SignatureSecret, middleware.CurrentSignatureConfig())
+		}
+	}
+
+	client := &http.Client{Timeout: cfg.Timeout}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("重放请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return nil, fmt.Errorf("读取重放响应失败: %v", err)
+	}
+	result.Replayed = Response{
+		StatusCode: resp.StatusCode,
+		Body:       decodeBody(string(respBody)),
+		LatencyMs:  time.Since(start).Milliseconds(),
+	}
+
+	result.Diffs = []Diff{}
+	if result.Recorded.StatusCode != result.Replayed.StatusCode {
+		result.Diffs = append(result.Diffs, Diff{
+			Path:     "status_code",
+			Recorded: result.Recorded.StatusCode,
+			Replayed: result.Replayed.StatusCode,
+		})
+	}
+	if !strings.HasSuffix(entry.ResponseBody, truncatedSuffix) {
+		result.Diffs = append(result.Diffs, compare("body", result.Recorded.Body, result.Replayed.Body)...)
+	}
+	return result, nil
+}
+
+// decodeBody 响应体为 JSON 时解析，否则保留原文
+func decodeBody(body string) interface{} {
+	if body == "" {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(body), &v); err == nil {
+		return v
+	}
+	return body
+}
+
+// compare 递归对比 JSON 值，忽略配置的易变字段及审计日志中的脱敏字段
+func compare(path string, recorded, replayed interface{}) []Diff {
+	if recorded == maskedValue {
+		return nil
+	}
+
+	switch r := recorded.(type) {
+	case map[string]interface{}:
+		p, ok := replayed.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make(map[string]struct{}, len(r)+len(p))
+		for k := range r {
+			keys[k] = struct{}{}
+		}
+		for k := range p {
+			keys[k] = struct{}{}
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			if !ignored(k) {
+				sorted = append(sorted, k)
+			}
+		}
+		sort.Strings(sorted)
+
+		var diffs []Diff
+		for _, k := range sorted {
+			diffs = append(diffs, compare(path+"."+k, r[k], p[k])...)
+		}
+		return diffs
+	case []interface{}:
+		p, ok := replayed.([]interface{})
+		if !ok || len(p) != len(r) {
+			break
+		}
+		var diffs []Diff
+		for i := range r {
+			diffs = append(diffs, compare(fmt.Sprintf("%s[%d]", path, i), r[i], p[i])...)
+		}
+		return diffs
+	}
+
+	a, _ := json.Marshal(recorded)
+	b, _ := json.Marshal(replayed)
+	if bytes.Equal(a, b) {
+		return nil
+	}
+	return []Diff{{Path: path, Recorded: recorded, Replayed: replayed}}
+}
+
+// ignored 是否为对比时忽略的字段
+func ignored(field string) bool {
+	for _, f := range cfg.IgnoreFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
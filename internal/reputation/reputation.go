@@ -0,0 +1,328 @@
+// Package reputation 定时下载公开的 IP 威胁情报黑名单（abuse.ch、FireHOL 等），
+// 解析后整体替换 IP 过滤器中的信誉名单，无需手动维护即可拦截已知的恶意来源
+package reputation
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/jobs"
+	"new-openclaw/internal/metrics"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/pkg/config"
+)
+
+// maxFeedSize 单个名单的大小上限
+const maxFeedSize = 32 << 20
+
+// 网段前缀长度下限：过宽的条目（如误发布的 0.0.0.0/1）会拦截大量正常流量，直接丢弃
+const (
+	minPrefixV4 = 8
+	minPrefixV6 = 19
+)
+
+// FeedStatus 名单的下载状态
+type FeedStatus struct {
+	URL     string `json:"url"`
+	Entries int    `json:"entries"`
+	// 最近一次成功下载（或确认未变化）的时间
+	UpdatedAt *time.Time `json:"updated_at"`
+	CheckedAt *time.Time `json:"checked_at"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// feed 名单及最近一次成功下载的内容（下载失败时沿用）
+type feed struct {
+	FeedStatus
+	entries      []string
+	etag         string
+	lastModified string
+}
+
+var (
+	cfg    = config.ReputationConfig{Interval: time.Hour, Timeout: 30 * time.Second, MaxEntries: 200000}
+	feeds  []*feed
+	filter *middleware.DynamicIPFilter
+	active int
+	// 同一时间只执行一次刷新（启动时的首次刷新与定时任务可能重叠）
+	refreshMu sync.Mutex
+	mu        sync.RWMutex
+
+	client = &http.Client{}
+
+	fetchTotal = metrics.NewCounterVec("ip_reputation_fetch_total", "IP 信誉名单下载次数", "feed", "result")
+)
+
+func init() {
+	metrics.NewGaugeFunc("ip_reputation_entries", "生效中的 IP 信誉名单条目数", func() float64 {
+		return float64(Active())
+	})
+}
+
+// Configure 设置名单地址及刷新参数（未启用或地址为空时不下载）
+func Configure(c config.ReputationConfig) {
+	mu.Lock()
+	defer mu.Unlock()
+	cfg = c
+	client = &http.Client{Timeout: c.Timeout}
+	feeds = nil
+	if filter != nil {
+		filter.SetReputationAdvisory(advisory())
+	}
+	if !c.Enabled {
+		return
+	}
+	for _, raw := range c.Feeds {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			log.Printf("⚠️  忽略无效的 IP 信誉名单地址: %s", raw)
+			continue
+		}
+		feeds = append(feeds, &feed{FeedStatus: FeedStatus{URL: raw}})
+	}
+}
+
+// Bind 绑定生效的动态 IP 过滤器，并按配置决定名单是拦截还是只标记
+func Bind(f *middleware.DynamicIPFilter) {
+	mu.Lock()
+	defer mu.Unlock()
+	filter = f
+	f.SetReputationAdvisory(advisory())
+}
+
+// advisory 名单是否只作为信誉标记（IP_REPUTATION_ACTION=policy），调用方持有锁
+func advisory() bool {
+	return cfg.Action == "policy"
+}
+
+// Start 后台执行首次刷新（不阻塞启动；之后由定时任务刷新）
+func Start() {
+	mu.RLock()
+	enabled := len(feeds) > 0
+	mu.RUnlock()
+	if !enabled {
+		return
+	}
+	go func() {
+		if err := Refresh(context.Background()); err != nil {
+			log.Printf("⚠️  IP 信誉名单首次下载未全部成功: %v", err)
+		}
+	}()
+}
+
+// Refresh 下载所有名单并整体替换过滤器中的信誉名单；部分名单失败时沿用其上一次的内容并返回错误
+func Refresh(ctx context.Context) error {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+
+	mu.RLock()
+	list, f, limit := feeds, filter, cfg.MaxEntries
+	mu.RUnlock()
+	if len(list) == 0 {
+		return nil
+	}
+
+	var errs []string
+	for _, fd := range list {
+		if err := fd.fetch(ctx); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", fd.URL, err))
+		}
+	}
+
+	seen := make(map[string]bool)
+	var merged []string
+	mu.RLock()
+	for _, fd := range list {
+		for _, entry := range fd.entries {
+			if !seen[entry] {
+				seen[entry] = true
+				merged = append(merged, entry)
+			}
+		}
+	}
+	mu.RUnlock()
+	if limit > 0 && len(merged) > limit {
+		log.Printf("⚠️  IP 信誉名单共 %d 条，超出上限 %d，多余部分已丢弃", len(merged), limit)
+		merged = merged[:limit]
+	}
+
+	if f != nil {
+		n := f.SetReputation(merged)
+		mu.Lock()
+		active = n
+		mu.Unlock()
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Status 各名单的下载状态
+func Status() []FeedStatus {
+	mu.RLock()
+	defer mu.RUnlock()
+	result := make([]FeedStatus, len(feeds))
+	for i, fd := range feeds {
+		result[i] = fd.FeedStatus
+	}
+	return result
+}
+
+// Active 生效中的条目数
+func Active() int {
+	mu.RLock()
+	defer mu.RUnlock()
+	return active
+}
+
+// RegisterRefreshJob 注册定时刷新名单的任务（每个实例各自下载，不依赖共享存储）
+func RegisterRefreshJob() {
+	mu.RLock()
+	enabled, interval := len(feeds) > 0, cfg.Interval
+	mu.RUnlock()
+	if !enabled || interval <= 0 {
+		return
+	}
+
+	jobs.Register(jobs.Job{
+		Name:        "ip_reputation_refresh",
+		Description: "下载 IP 威胁情报黑名单并更新 IP 过滤",
+		Interval:    interval,
+		Run:         Refresh,
+	})
+}
+
+// fetch 下载并解析名单（名单未变化时服务端返回 304，沿用已解析的内容）
+func (fd *feed) fetch(ctx context.Context) error {
+	now := time.Now()
+	label := feedLabel(fd.URL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fd.URL, nil)
+	if err != nil {
+		return err
+	}
+	mu.RLock()
+	if fd.etag != "" {
+		req.Header.Set("If-None-Match", fd.etag)
+	}
+	if fd.lastModified != "" {
+		req.Header.Set("If-Modified-Since", fd.lastModified)
+	}
+	c := client
+	mu.RUnlock()
+
+	entries, header, err := download(c, req)
+
+	mu.Lock()
+	defer mu.Unlock()
+	fd.CheckedAt = &now
+	switch {
+	case err != nil:
+		fd.LastError = err.Error()
+		fetchTotal.Inc(label, "error")
+		return err
+	case entries == nil:
+		fetchTotal.Inc(label, "not_modified")
+	default:
+		fd.entries = entries
+		fd.Entries = len(entries)
+		fd.etag = header.Get("ETag")
+		fd.lastModified = header.Get("Last-Modified")
+		fetchTotal.Inc(label, "ok")
+	}
+	fd.LastError = ""
+	fd.UpdatedAt = &now
+	return nil
+}
+
+// download 执行请求并解析响应，名单未变化（304）时返回 nil 条目
+func download(c *http.Client, req *http.Request) ([]string, http.Header, error) {
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, resp.Header, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	entries, err := Parse(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(entries) == 0 {
+		// 空名单多半是上游异常（如返回了错误页），沿用上一次的内容
+		return nil, nil, errors.New("名单中没有有效条目")
+	}
+	return entries, resp.Header, nil
+}
+
+// Parse 解析纯文本名单：每行一个 IP 或 CIDR，# 或 ; 之后为注释，行内其余字段（如 Spamhaus DROP 的编号）忽略；
+// 无效条目与过宽的网段跳过
+func Parse(r io.Reader) ([]string, error) {
+	var entries []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexAny(line, "#;"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ','
+		})
+		if len(fields) == 0 {
+			continue
+		}
+		if entry, ok := normalizeEntry(fields[0]); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// normalizeEntry 规范化条目：IP 转为标准格式，CIDR 取网络地址（/32、/128 视为单个 IP）
+func normalizeEntry(s string) (string, bool) {
+	if !strings.Contains(s, "/") {
+		ip := middleware.NormalizeIP(s)
+		return ip, ip != ""
+	}
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		return "", false
+	}
+	ones, bits := ipNet.Mask.Size()
+	if ones == bits {
+		return ipNet.IP.String(), true
+	}
+	if (bits == 32 && ones < minPrefixV4) || (bits == 128 && ones < minPrefixV6) {
+		return "", false
+	}
+	return ipNet.String(), true
+}
+
+// feedLabel 指标中的名单标识（不含查询参数，避免地址中的令牌写入指标）
+func feedLabel(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return u.Host + u.Path
+}
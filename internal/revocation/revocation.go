@@ -0,0 +1,105 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"new-openclaw/internal/store"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ReasonSuperseded 账号在其他设备登录，旧会话被新登录顶替（只允许一个活跃会话）
+const ReasonSuperseded = "superseded"
+
+// Revoke 吊销单个 Token（按 jti），记录保留到 Token 过期
+func Revoke(ctx context.Context, claims jwt.RegisteredClaims) error {
+	return RevokeWithReason(ctx, claims, "")
+}
+
+// RevokeWithReason 吊销单个 Token 并记录原因，旧客户端请求时可以返回更明确的错误
+func RevokeWithReason(ctx context.Context, claims jwt.RegisteredClaims, reason string) error {
+	if claims.ID == "" {
+		return errors.New("token 缺少 jti，无法单独吊销")
+	}
+
+	ttl := time.Minute
+	if claims.ExpiresAt != nil {
+		ttl = time.Until(claims.ExpiresAt.Time)
+		if ttl <= 0 {
+			return nil
+		}
+	}
+	value := reason
+	if value == "" {
+		value = "1"
+	}
+	return store.For(store.ComponentRevocation).Set(ctx, jtiKey(claims.ID), value, ttl)
+}
+
+// Reason 查询 Token 的吊销原因（未吊销、未记录原因或存储不可用时返回空）
+func Reason(ctx context.Context, jti string) string {
+	if jti == "" {
+		return ""
+	}
+	value, err := store.For(store.ComponentRevocation).Get(ctx, jtiKey(jti))
+	if err != nil || value == "1" {
+		return ""
+	}
+	return value
+}
+
+// RevokeAll 吊销某签发者下某主体在此之前签发的全部 Token（强制下线），
+// ttl 应不小于该签发者 Token（含刷新 Token）的最长有效期
+func RevokeAll(ctx context.Context, issuer, subject string, ttl time.Duration) error {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	return store.For(store.ComponentRevocation).Set(ctx, subjectKey(issuer, subject), now, ttl)
+}
+
+// Blacklist 基于状态存储的 Token 黑名单，实现 pkg/auth/token.Blacklist
+type Blacklist struct{}
+
+// IsRevoked 检查 Token 是否已被吊销；存储不可用时放行，避免 Redis 故障导致全部请求被拒
+func (Blacklist) IsRevoked(claims jwt.RegisteredClaims) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	s := store.For(store.ComponentRevocation)
+
+	if claims.ID != "" {
+		_, err := s.Get(ctx, jtiKey(claims.ID))
+		if err == nil {
+			return true
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			log.Printf("查询 Token 黑名单失败: %v", err)
+		}
+	}
+
+	if claims.Subject == "" || claims.IssuedAt == nil {
+		return false
+	}
+	value, err := s.Get(ctx, subjectKey(claims.Issuer, claims.Subject))
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			log.Printf("查询 Token 黑名单失败: %v", err)
+		}
+		return false
+	}
+	revokedAt, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false
+	}
+	return claims.IssuedAt.Unix() <= revokedAt
+}
+
+func jtiKey(jti string) string {
+	return "jti:" + jti
+}
+
+func subjectKey(issuer, subject string) string {
+	return "sub:" + issuer + ":" + subject
+}
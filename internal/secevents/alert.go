@@ -0,0 +1,291 @@
+package secevents
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/metrics"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/notify"
+	"new-openclaw/internal/store"
+)
+
+// 告警渠道
+const (
+	ChannelWebhook  = "webhook"
+	ChannelDingTalk = "dingtalk"
+	ChannelSlack    = "slack"
+	ChannelEmail    = "email"
+)
+
+// alertQueueSize 待推送告警队列长度（推送缓慢时队列满则丢弃）
+const alertQueueSize = 100
+
+// typeNames 告警中显示的事件类型
+var typeNames = map[string]string{
+	model.SecurityEventAttack:        "攻击检测",
+	model.SecurityEventBan:           "IP 自动封禁",
+	model.SecurityEventBanLifted:     "解除封禁",
+	model.SecurityEventAuthFailure:   "认证失败",
+	model.SecurityEventAccountLocked: "账号锁定",
+	model.SecurityEventHoneypot:      "诱饵路径命中",
+}
+
+// Alert 推送的告警
+type Alert struct {
+	Event model.SecurityEvent `json:"event"`
+	// 上次推送后被合并（未推送）的相同事件数
+	Suppressed int64 `json:"suppressed"`
+}
+
+var (
+	alerts     chan Alert
+	alertsDone chan struct{}
+
+	throttleMu     sync.Mutex
+	throttleMinute int64
+	throttleCount  int
+
+	alertsTotal = metrics.NewCounterVec("security_alerts_total", "安全事件告警推送数", "channel", "result")
+)
+
+// Channels 已配置的告警渠道
+func Channels() []string {
+	c := current()
+	var channels []string
+	if c.AlertWebhook != "" {
+		channels = append(channels, ChannelWebhook)
+	}
+	if c.DingTalkWebhook != "" {
+		channels = append(channels, ChannelDingTalk)
+	}
+	if c.SlackWebhook != "" {
+		channels = append(channels, ChannelSlack)
+	}
+	if c.AlertEmail {
+		channels = append(channels, ChannelEmail)
+	}
+	return channels
+}
+
+// startAlerts 启动告警推送协程（推送较慢，不阻塞事件写入）
+func startAlerts() {
+	alerts = make(chan Alert, alertQueueSize)
+	alertsDone = make(chan struct{})
+	queue := alerts
+	go func() {
+		defer close(alertsDone)
+		for a := range queue {
+			Send(context.Background(), a)
+		}
+	}()
+}
+
+// stopAlerts 推送完队列中的告警后停止
+func stopAlerts() {
+	if alerts == nil {
+		return
+	}
+	close(alerts)
+	<-alertsDone
+	alerts = nil
+}
+
+// maybeAlert 事件达到告警级别时去重、限流后加入推送队列
+func maybeAlert(e model.SecurityEvent) {
+	c := current()
+	if alerts == nil || severityRank(e.Severity) < severityRank(c.AlertMinSeverity) || len(Channels()) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	suppressed, ok := dedupe(ctx, e, c.AlertDedupeWindow)
+	if !ok {
+		alertsTotal.Inc("all", "deduplicated")
+		return
+	}
+	if !allow(time.Now(), c.AlertMaxPerMinute) {
+		alertsTotal.Inc("all", "throttled")
+		return
+	}
+	select {
+	case alerts <- Alert{Event: e, Suppressed: suppressed}:
+	default:
+		alertsTotal.Inc("all", "dropped")
+	}
+}
+
+// dedupe 相同事件（类型、IP、分类）在窗口内只推送一次，之后的事件只计数，下次推送时附带。
+// 计数存储在滥用评分存储中（ABUSE_STORE 为 redis 时跨实例去重），存储不可用时不去重
+func dedupe(ctx context.Context, e model.SecurityEvent, window time.Duration) (int64, bool) {
+	if window <= 0 {
+		return 0, true
+	}
+	sum := sha1.Sum([]byte(e.Type + "|" + e.IP + "|" + e.Labels))
+	key := hex.EncodeToString(sum[:])
+	s := store.For(store.ComponentAbuse)
+
+	first, err := s.SetNX(ctx, "security_alert:"+key, "1", window)
+	if err != nil {
+		return 0, true
+	}
+	countKey := "security_alert_suppressed:" + key
+	if !first {
+		s.IncrWithTTL(ctx, countKey, 2*window)
+		return 0, false
+	}
+
+	var suppressed int64
+	if v, err := s.Get(ctx, countKey); err == nil {
+		suppressed, _ = strconv.ParseInt(v, 10, 64)
+		s.Del(ctx, countKey)
+	}
+	return suppressed, true
+}
+
+// allow 本实例每分钟的推送上限
+func allow(now time.Time, max int) bool {
+	if max <= 0 {
+		return true
+	}
+	throttleMu.Lock()
+	defer throttleMu.Unlock()
+	minute := now.Unix() / 60
+	if minute != throttleMinute {
+		throttleMinute, throttleCount = minute, 0
+	}
+	if throttleCount >= max {
+		return false
+	}
+	throttleCount++
+	return true
+}
+
+// Send 立即推送到所有已配置的渠道，返回各渠道的结果（nil 为成功）
+func Send(ctx context.Context, a Alert) map[string]error {
+	c := current()
+	title, text := render(a)
+	client := &http.Client{Timeout: c.AlertTimeout}
+	results := make(map[string]error)
+
+	if c.AlertWebhook != "" {
+		results[ChannelWebhook] = postJSON(ctx, client, c.AlertWebhook, map[string]interface{}{
+			"event":      "security_event",
+			"timestamp":  time.Now(),
+			"title":      title,
+			"data":       a.Event,
+			"suppressed": a.Suppressed,
+		})
+	}
+	if c.DingTalkWebhook != "" {
+		results[ChannelDingTalk] = postJSON(ctx, client, dingTalkURL(c.DingTalkWebhook, c.DingTalkSecret, time.Now()), map[string]interface{}{
+			"msgtype": "text",
+			"text":    map[string]string{"content": title + "\n" + text},
+		})
+	}
+	if c.SlackWebhook != "" {
+		results[ChannelSlack] = postJSON(ctx, client, c.SlackWebhook, map[string]string{
+			"text": "*" + title + "*\n" + text,
+		})
+	}
+	if c.AlertEmail {
+		results[ChannelEmail] = notify.Notify(notify.CategorySecurity, title, text)
+	}
+
+	for channel, err := range results {
+		if err != nil {
+			alertsTotal.Inc(channel, "error")
+			log.Printf("推送安全告警失败: channel=%s err=%v", channel, err)
+			continue
+		}
+		alertsTotal.Inc(channel, "ok")
+	}
+	return results
+}
+
+// render 告警标题与正文
+func render(a Alert) (string, string) {
+	e := a.Event
+	name := typeNames[e.Type]
+	if name == "" {
+		name = e.Type
+	}
+	title := fmt.Sprintf("[安全告警][%s] %s: %s", e.Severity, name, e.IP)
+
+	lines := []string{"时间: " + e.CreatedAt.Format(time.RFC3339)}
+	if e.Path != "" {
+		lines = append(lines, "请求: "+e.Method+" "+e.Path)
+	}
+	if e.Subject != "" {
+		lines = append(lines, "账号: "+e.Subject)
+	}
+	if e.Labels != "" {
+		lines = append(lines, "分类: "+e.Labels)
+	}
+	if e.Detail != "" {
+		lines = append(lines, "详情: "+e.Detail)
+	}
+	if e.ValidUntil != nil && e.Type == model.SecurityEventBan {
+		lines = append(lines, "封禁至: "+e.ValidUntil.Format(time.RFC3339))
+	}
+	if e.RequestID != "" {
+		lines = append(lines, "请求 ID: "+e.RequestID)
+	}
+	if a.Suppressed > 0 {
+		lines = append(lines, fmt.Sprintf("上次告警后另有 %d 次相同事件（已合并）", a.Suppressed))
+	}
+	return title, strings.Join(lines, "\n")
+}
+
+// dingTalkURL 钉钉机器人加签：timestamp 与 Base64(HmacSHA256(timestamp + "\n" + secret))
+func dingTalkURL(webhook, secret string, now time.Time) string {
+	if secret == "" {
+		return webhook
+	}
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+	sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	sep := "?"
+	if strings.Contains(webhook, "?") {
+		sep = "&"
+	}
+	return webhook + sep + "timestamp=" + timestamp + "&sign=" + url.QueryEscape(sign)
+}
+
+func postJSON(ctx context.Context, client *http.Client, target string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("响应状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package secevents
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoDB 错误码：索引已存在但选项不同
+const codeIndexOptionsConflict = 85
+
+// countersCollection 分配递增 ID 的计数器集合（订阅按 ID 游标读取，MongoDB 中同样需要递增的 ID）
+const countersCollection = "counters"
+
+var (
+	// 索引只需成功创建一次，失败时下次写入或查询再试
+	ensured  bool
+	ensureMu sync.Mutex
+)
+
+// collection 事件集合（首次使用时创建索引）
+func collection(ctx context.Context) (*mongo.Collection, error) {
+	db := database.GetMongoDB()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	c := current()
+	coll := db.Collection(c.Collection)
+
+	ensureMu.Lock()
+	defer ensureMu.Unlock()
+	if !ensured {
+		if err := ensure(ctx, db, coll, c.Collection, c.Retention); err != nil {
+			return nil, err
+		}
+		ensured = true
+	}
+	return coll, nil
+}
+
+// ensure 创建按类型、IP 查询的索引及按保留时长过期的 TTL 索引
+func ensure(ctx context.Context, db *mongo.Database, coll *mongo.Collection, name string, retention time.Duration) error {
+	if _, err := coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "type", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "ip", Value: 1}, {Key: "_id", Value: -1}}},
+	}); err != nil {
+		return err
+	}
+
+	createdAtIndex := options.Index().SetName("created_at")
+	if retention > 0 {
+		createdAtIndex.SetExpireAfterSeconds(int32(retention / time.Second))
+	}
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "created_at", Value: 1}},
+		Options: createdAtIndex,
+	})
+	var se mongo.ServerError
+	if err != nil && retention > 0 && errors.As(err, &se) && se.HasErrorCode(codeIndexOptionsConflict) {
+		err = db.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: name},
+			{Key: "index", Value: bson.M{"name": "created_at", "expireAfterSeconds": int32(retention / time.Second)}},
+		}).Err()
+	}
+	if err != nil {
+		log.Printf("⚠️  安全事件集合 %s 的保留时长未按配置更新: %v", name, err)
+	}
+	return nil
+}
+
+// nextID 从计数器分配下一个 ID
+func nextID(ctx context.Context, db *mongo.Database, name string) (uint, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := db.Collection(countersCollection).FindOneAndUpdate(ctx,
+		bson.M{"_id": name},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return 0, err
+	}
+	return uint(counter.Seq), nil
+}
+
+func insertMongo(ctx context.Context, e *model.SecurityEvent) error {
+	coll, err := collection(ctx)
+	if err != nil {
+		return err
+	}
+	id, err := nextID(ctx, coll.Database(), coll.Name())
+	if err != nil {
+		return err
+	}
+	e.ID = id
+	_, err = coll.InsertOne(ctx, e)
+	return err
+}
+
+func mongoFilter(f Filter) bson.M {
+	filter := bson.M{}
+	if len(f.Types) > 0 {
+		filter["type"] = bson.M{"$in": f.Types}
+	}
+	if f.MinSeverity != "" {
+		filter["severity"] = bson.M{"$in": SeveritiesFrom(f.MinSeverity)}
+	}
+	if f.IP != "" {
+		filter["ip"] = f.IP
+	}
+	createdAt := bson.M{}
+	if !f.From.IsZero() {
+		createdAt["$gte"] = f.From
+	}
+	if !f.To.IsZero() {
+		createdAt["$lte"] = f.To
+	}
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+	return filter
+}
+
+func listMongo(ctx context.Context, f Filter, page, pageSize int) ([]model.SecurityEvent, int64, error) {
+	coll, err := collection(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	filter := mongoFilter(f)
+	total, err := coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	cursor, err := coll.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetSkip(int64((page-1)*pageSize)).
+		SetLimit(int64(pageSize)))
+	if err != nil {
+		return nil, 0, err
+	}
+	events := []model.SecurityEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+func afterMongo(ctx context.Context, after uint, f Filter, limit int) ([]model.SecurityEvent, error) {
+	coll, err := collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter := mongoFilter(f)
+	filter["_id"] = bson.M{"$gt": after}
+	cursor, err := coll.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	events := []model.SecurityEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
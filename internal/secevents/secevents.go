@@ -0,0 +1,413 @@
+// Package secevents 安全事件存储：攻击检测、认证失败、IP 封禁与解除统一写入 MySQL 或 MongoDB，
+// 供管理后台查询、威胁情报订阅读取，并按严重级别推送告警（见 alert.go）
+package secevents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/jobs"
+	"new-openclaw/internal/metrics"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/pkg/config"
+
+	"gorm.io/gorm"
+)
+
+// 存储后端
+const (
+	StoreMySQL   = "mysql"
+	StoreMongoDB = "mongodb"
+)
+
+// queueSize 待写入事件队列长度（攻击洪峰时队列满则丢弃，避免拖慢请求）
+const queueSize = 1000
+
+var (
+	// ErrUnavailable 存储未连接
+	ErrUnavailable = errors.New("安全事件存储不可用（数据库未连接）")
+)
+
+// reasonLabels 内置 WAF 规则的检测结果对应的分类标签（自定义规则统一为 waf-rule）
+var reasonLabels = map[string]string{
+	middleware.ReasonSQLInjection:  "sql-injection",
+	middleware.ReasonXSS:           "xss",
+	middleware.ReasonPathTraversal: "path-traversal",
+}
+
+var (
+	cfg = config.SecurityEventsConfig{
+		Store:              StoreMySQL,
+		Collection:         "security_events",
+		Retention:          30 * 24 * time.Hour,
+		RecordAuthFailures: true,
+		AlertMinSeverity:   model.SeverityHigh,
+		AlertDedupeWindow:  10 * time.Minute,
+		AlertMaxPerMinute:  30,
+		AlertTimeout:       5 * time.Second,
+	}
+	mu sync.RWMutex
+
+	queue   chan model.SecurityEvent
+	stopped chan struct{}
+	wg      sync.WaitGroup
+
+	eventsTotal = metrics.NewCounterVec("security_events_total", "写入的安全事件数", "type", "result")
+)
+
+// Configure 设置存储与告警配置
+func Configure(c config.SecurityEventsConfig) error {
+	if c.Store != StoreMySQL && c.Store != StoreMongoDB {
+		return fmt.Errorf("无效的安全事件存储: %s（可选 %s, %s）", c.Store, StoreMySQL, StoreMongoDB)
+	}
+	if severityRank(c.AlertMinSeverity) < 0 {
+		return fmt.Errorf("无效的告警级别: %s（可选 %s）", c.AlertMinSeverity, strings.Join(model.Severities, ", "))
+	}
+	if c.Collection == "" {
+		c.Collection = "security_events"
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	cfg = c
+	return nil
+}
+
+func current() config.SecurityEventsConfig {
+	mu.RLock()
+	defer mu.RUnlock()
+	return cfg
+}
+
+// Start 启动事件写入与告警推送协程
+func Start() {
+	if queue != nil {
+		return
+	}
+	queue = make(chan model.SecurityEvent, queueSize)
+	stopped = make(chan struct{})
+	startAlerts()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stopped:
+				// 写完已入队的事件再退出
+				for {
+					select {
+					case e := <-queue:
+						write(e)
+					default:
+						return
+					}
+				}
+			case e := <-queue:
+				write(e)
+			}
+		}
+	}()
+}
+
+// Stop 写完队列中的事件、推送完待发送的告警后停止
+func Stop() {
+	if stopped == nil {
+		return
+	}
+	close(stopped)
+	wg.Wait()
+	queue, stopped = nil, nil
+	stopAlerts()
+}
+
+// Record 记录安全事件（未设置严重级别时按类型确定）
+func Record(e model.SecurityEvent) {
+	if e.Severity == "" {
+		e.Severity = defaultSeverity(e)
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	enqueue(e)
+}
+
+// RecordAttack 记录攻击检测（middleware.OnSecurityAlert）
+func RecordAttack(_ context.Context, alert middleware.SecurityAlert) {
+	labels := make([]string, 0, len(alert.Reasons))
+	for _, reason := range alert.Reasons {
+		if label, ok := reasonLabels[reason]; ok {
+			labels = append(labels, label)
+		} else if !containsLabel(labels, "waf-rule") {
+			// 自定义 WAF 规则
+			labels = append(labels, "waf-rule")
+		}
+	}
+	Record(model.SecurityEvent{
+		Type:      model.SecurityEventAttack,
+		IP:        alert.IP,
+		Method:    alert.Method,
+		Path:      truncate(alert.Path, 255),
+		UserAgent: truncate(alert.UserAgent, 255),
+		RequestID: alert.RequestID,
+		Labels:    strings.Join(labels, ","),
+		Detail:    truncate(strings.Join(alert.Reasons, "; "), 255),
+	})
+}
+
+// RecordAuthFailure 记录认证失败（middleware.OnAuthFailure），SECURITY_EVENTS_AUTH_FAILURES 关闭时不记录
+func RecordAuthFailure(_ context.Context, f middleware.AuthFailure) {
+	if !current().RecordAuthFailures {
+		return
+	}
+	Record(model.SecurityEvent{
+		Type:      model.SecurityEventAuthFailure,
+		IP:        f.IP,
+		Method:    f.Method,
+		Path:      truncate(f.Path, 255),
+		UserAgent: truncate(f.UserAgent, 255),
+		RequestID: f.RequestID,
+		Subject:   truncate(f.Subject, 64),
+		Labels:    f.Kind,
+		Detail:    truncate(f.Detail, 255),
+	})
+}
+
+// RecordHoneypot 记录诱饵路径命中（middleware.HoneypotConfig.Record）
+func RecordHoneypot(_ context.Context, hit middleware.HoneypotHit) {
+	Record(model.SecurityEvent{
+		Type:      model.SecurityEventHoneypot,
+		IP:        hit.IP,
+		Method:    hit.Method,
+		Path:      truncate(hit.Path, 255),
+		UserAgent: truncate(hit.UserAgent, 255),
+		RequestID: hit.RequestID,
+		Labels:    "honeypot",
+		Score:     hit.Score,
+		Detail:    truncate("诱饵路径 "+hit.Pattern, 255),
+	})
+}
+
+// RecordBan 记录自动封禁
+func RecordBan(ban model.IPBan) {
+	from, until := ban.CreatedAt, ban.ExpiresAt
+	Record(model.SecurityEvent{
+		Type:       model.SecurityEventBan,
+		IP:         ban.IP,
+		Labels:     ban.Trigger,
+		BanID:      ban.ID,
+		Score:      ban.Score,
+		Detail:     truncate(ban.Reason, 255),
+		ValidFrom:  &from,
+		ValidUntil: &until,
+	})
+}
+
+// RecordBanLifted 记录提前解除封禁（订阅中对应指标标记为已撤销）
+func RecordBanLifted(ban model.IPBan) {
+	from, until := ban.CreatedAt, ban.ExpiresAt
+	Record(model.SecurityEvent{
+		Type:       model.SecurityEventBanLifted,
+		IP:         ban.IP,
+		Labels:     ban.Trigger,
+		BanID:      ban.ID,
+		Score:      ban.Score,
+		Detail:     "解除人: " + ban.LiftedBy,
+		ValidFrom:  &from,
+		ValidUntil: &until,
+	})
+}
+
+// defaultSeverity 按类型确定严重级别：封禁、账号锁定与紧急访问凭证错误为 high，攻击检测、诱饵路径与管理后台登录失败为 medium，其余为 low
+func defaultSeverity(e model.SecurityEvent) string {
+	switch e.Type {
+	case model.SecurityEventBan, model.SecurityEventAccountLocked:
+		return model.SeverityHigh
+	case model.SecurityEventAttack, model.SecurityEventHoneypot:
+		return model.SeverityMedium
+	case model.SecurityEventAuthFailure:
+		switch e.Labels {
+		case middleware.AuthFailureBreakGlass:
+			return model.SeverityHigh
+		case middleware.AuthFailureLogin:
+			return model.SeverityMedium
+		}
+	}
+	return model.SeverityLow
+}
+
+// severityRank 严重级别的顺序，无效时返回 -1
+func severityRank(severity string) int {
+	for i, s := range model.Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// SeveritiesFrom 不低于指定级别的所有严重级别
+func SeveritiesFrom(min string) []string {
+	rank := severityRank(min)
+	if rank < 0 {
+		return nil
+	}
+	return model.Severities[rank:]
+}
+
+// ValidSeverity 是否为有效的严重级别
+func ValidSeverity(severity string) bool {
+	return severityRank(severity) >= 0
+}
+
+// enqueue 加入写入队列（未启动或队列满时丢弃）
+func enqueue(e model.SecurityEvent) {
+	if queue == nil {
+		return
+	}
+	select {
+	case queue <- e:
+	default:
+		eventsTotal.Inc(e.Type, "dropped")
+	}
+}
+
+// write 写入存储并按严重级别推送告警（存储不可用时同样推送）
+func write(e model.SecurityEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var err error
+	if current().Store == StoreMongoDB {
+		err = insertMongo(ctx, &e)
+	} else {
+		err = insertMySQL(ctx, &e)
+	}
+	switch {
+	case errors.Is(err, ErrUnavailable):
+		eventsTotal.Inc(e.Type, "dropped")
+	case err != nil:
+		eventsTotal.Inc(e.Type, "error")
+		log.Printf("写入安全事件失败: type=%s ip=%s err=%v", e.Type, e.IP, err)
+	default:
+		eventsTotal.Inc(e.Type, "ok")
+	}
+	maybeAlert(e)
+}
+
+func insertMySQL(ctx context.Context, e *model.SecurityEvent) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+	return db.WithContext(ctx).Create(e).Error
+}
+
+// Filter 查询条件（零值不限制）
+type Filter struct {
+	Types []string
+	// 最低严重级别
+	MinSeverity string
+	IP          string
+	From        time.Time
+	To          time.Time
+}
+
+// List 按时间倒序分页查询
+func List(ctx context.Context, f Filter, page, pageSize int) ([]model.SecurityEvent, int64, error) {
+	if current().Store == StoreMongoDB {
+		return listMongo(ctx, f, page, pageSize)
+	}
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, 0, ErrUnavailable
+	}
+	query := mysqlFilter(db.WithContext(ctx).Model(&model.SecurityEvent{}), f)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	events := []model.SecurityEvent{}
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// After 按 ID 升序读取 ID 大于 after 的事件（订阅按游标读取）
+func After(ctx context.Context, after uint, f Filter, limit int) ([]model.SecurityEvent, error) {
+	if current().Store == StoreMongoDB {
+		return afterMongo(ctx, after, f, limit)
+	}
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	events := []model.SecurityEvent{}
+	err := mysqlFilter(db.WithContext(ctx).Where("id > ?", after), f).Order("id ASC").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+func mysqlFilter(query *gorm.DB, f Filter) *gorm.DB {
+	if len(f.Types) > 0 {
+		query = query.Where("type IN ?", f.Types)
+	}
+	if f.MinSeverity != "" {
+		query = query.Where("severity IN ?", SeveritiesFrom(f.MinSeverity))
+	}
+	if f.IP != "" {
+		query = query.Where("ip = ?", f.IP)
+	}
+	if !f.From.IsZero() {
+		query = query.Where("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		query = query.Where("created_at <= ?", f.To)
+	}
+	return query
+}
+
+// RegisterCleanupJob 注册清理过期安全事件的任务（MongoDB 由 TTL 索引清理）
+func RegisterCleanupJob() {
+	c := current()
+	if c.Retention <= 0 || c.Store != StoreMySQL {
+		return
+	}
+
+	jobs.Register(jobs.Job{
+		Name:        "security_events_cleanup",
+		Description: "清理超过保留时长的安全事件",
+		Interval:    time.Hour,
+		LeaderOnly:  true,
+		Run: func(ctx context.Context) error {
+			db := database.GetMySQL()
+			if db == nil {
+				return ErrUnavailable
+			}
+			return db.WithContext(ctx).Where("created_at < ?", time.Now().Add(-current().Retention)).
+				Delete(&model.SecurityEvent{}).Error
+		},
+	})
+}
+
+// truncate 截断超出列宽的字符串（不保留被截断的半个字符）
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
+
+func containsLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
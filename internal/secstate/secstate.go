@@ -0,0 +1,429 @@
+// Package secstate 安全状态快照：汇总本实例生效中的封禁、限流热点 Key、过滤与处置规则、功能开关，
+// 定时写入 MongoDB。排查“昨天 14:02 这个请求为什么被拦截”时查看的是当时的状态，而不是现在的状态
+package secstate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/configcenter"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/jobs"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/pkg/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrUnavailable MongoDB 未连接
+	ErrUnavailable = errors.New("MongoDB 未连接，安全状态快照不可用")
+	// ErrNotFound 快照不存在
+	ErrNotFound = errors.New("快照不存在")
+)
+
+// 快照来源
+const (
+	TriggerScheduled = "scheduled"
+	TriggerManual    = "manual"
+)
+
+// 内置的状态组成部分
+const (
+	StateIPFilter     = "ip_filter"
+	StateHotKeys      = "rate_limit_hot_keys"
+	StateAbuse        = "abuse"
+	StateFeatureFlags = "feature_flags"
+)
+
+// lookback 不指定实例按时间查询时，只取该时间之前这段时间内有快照的实例（已下线的实例不再返回）
+const lookback = 24 * time.Hour
+
+// MongoDB 错误码：索引已存在但选项不同
+const codeIndexOptionsConflict = 85
+
+// Snapshot 一个实例在某一时刻的安全状态
+type Snapshot struct {
+	ID       string    `json:"id,omitempty"`
+	Instance string    `json:"instance"`
+	TakenAt  time.Time `json:"taken_at"`
+	Trigger  string    `json:"trigger,omitempty"`
+	// 各组成部分的状态（列表中不返回）
+	State map[string]json.RawMessage `json:"state,omitempty"`
+}
+
+// provider 状态组成部分
+type provider struct {
+	name string
+	fn   func() interface{}
+}
+
+// document 存储的快照
+type document struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty"`
+	Instance string             `bson:"instance"`
+	TakenAt  time.Time          `bson:"taken_at"`
+	Trigger  string             `bson:"trigger"`
+	State    bson.Raw           `bson:"state,omitempty"`
+}
+
+var (
+	cfg = config.SecurityStateConfig{
+		SnapshotInterval: 5 * time.Minute,
+		Collection:       "security_snapshots",
+		Retention:        30 * 24 * time.Hour,
+		HotKeys:          50,
+	}
+
+	instance = func() string {
+		hostname, _ := os.Hostname()
+		return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}()
+
+	mu        sync.RWMutex
+	filter    *middleware.DynamicIPFilter
+	providers []provider
+
+	// 索引只需成功创建一次
+	ensured  bool
+	ensureMu sync.Mutex
+)
+
+// Configure 设置快照间隔、集合与保留时长
+func Configure(c config.SecurityStateConfig) {
+	mu.Lock()
+	defer mu.Unlock()
+	cfg = c
+	if cfg.Collection == "" {
+		cfg.Collection = "security_snapshots"
+	}
+	ensureMu.Lock()
+	ensured = false
+	ensureMu.Unlock()
+}
+
+// Bind 绑定进程内的 IP 过滤器（名单、临时封禁、威胁情报名单）
+func Bind(f *middleware.DynamicIPFilter) {
+	mu.Lock()
+	defer mu.Unlock()
+	filter = f
+}
+
+// Provide 登记一项状态（如频率限制、处置规则），快照时调用 fn 获取当前值；同名登记会替换
+func Provide(name string, fn func() interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+	for i := range providers {
+		if providers[i].name == name {
+			providers[i].fn = fn
+			return
+		}
+	}
+	providers = append(providers, provider{name: name, fn: fn})
+}
+
+// Capture 获取本实例当前的安全状态（不保存）
+func Capture() Snapshot {
+	mu.RLock()
+	f, hotKeys := filter, cfg.HotKeys
+	extra := append([]provider(nil), providers...)
+	mu.RUnlock()
+
+	components := map[string]interface{}{
+		StateHotKeys: middleware.RateLimitHotKeys(hotKeys),
+		StateAbuse: map[string]interface{}{
+			"window":        middleware.DefaultAbuseConfig.Window.String(),
+			"ban_threshold": middleware.DefaultAbuseConfig.Threshold,
+		},
+		StateFeatureFlags: configcenter.Features(),
+	}
+	if f != nil {
+		components[StateIPFilter] = f.State()
+	}
+	for _, p := range extra {
+		components[p.name] = p.fn()
+	}
+
+	snapshot := Snapshot{
+		Instance: instance,
+		TakenAt:  time.Now(),
+		State:    make(map[string]json.RawMessage, len(components)),
+	}
+	for name, value := range components {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			log.Printf("安全状态 %s 序列化失败: %v", name, err)
+			continue
+		}
+		snapshot.State[name] = raw
+	}
+	return snapshot
+}
+
+// Take 获取当前状态并保存
+func Take(ctx context.Context, trigger string) (*Snapshot, error) {
+	coll, err := collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := Capture()
+	snapshot.Trigger = trigger
+	state := bson.M{}
+	for name, raw := range snapshot.State {
+		value, err := decodeJSON(raw)
+		if err != nil {
+			return nil, err
+		}
+		state[name] = value
+	}
+	result, err := coll.InsertOne(ctx, bson.M{
+		"instance": snapshot.Instance,
+		"taken_at": snapshot.TakenAt,
+		"trigger":  snapshot.Trigger,
+		"state":    state,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		snapshot.ID = id.Hex()
+	}
+	return &snapshot, nil
+}
+
+// List 按时间倒序分页列出快照（不含状态内容）
+func List(ctx context.Context, from, to time.Time, inst string, page, pageSize int) ([]Snapshot, int64, error) {
+	coll, err := collection(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := bson.M{}
+	if inst != "" {
+		query["instance"] = inst
+	}
+	if !from.IsZero() || !to.IsZero() {
+		timeRange := bson.M{}
+		if !from.IsZero() {
+			timeRange["$gte"] = from
+		}
+		if !to.IsZero() {
+			timeRange["$lte"] = to
+		}
+		query["taken_at"] = timeRange
+	}
+	total, err := coll.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "taken_at", Value: -1}}).
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize)).
+		SetProjection(bson.M{"state": 0})
+	cursor, err := coll.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	var docs []document
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, 0, err
+	}
+	snapshots, err := fromDocuments(docs)
+	return snapshots, total, err
+}
+
+// Get 获取快照
+func Get(ctx context.Context, id string) (*Snapshot, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	coll, err := collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc document
+	if err := coll.FindOne(ctx, bson.M{"_id": objectID}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	snapshots, err := fromDocuments([]document{doc})
+	if err != nil {
+		return nil, err
+	}
+	return &snapshots[0], nil
+}
+
+// At 查询某一时刻的状态：指定实例时返回该实例在此之前的最后一个快照，
+// 否则返回此前 24 小时内每个实例的最后一个快照（按实例排序）
+func At(ctx context.Context, at time.Time, inst string) ([]Snapshot, error) {
+	coll, err := collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	match := bson.M{"taken_at": bson.M{"$lte": at, "$gte": at.Add(-lookback)}}
+	if inst != "" {
+		match = bson.M{"instance": inst, "taken_at": bson.M{"$lte": at}}
+	}
+	cursor, err := coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "taken_at", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$instance", "doc": bson.M{"$first": "$$ROOT"}}}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$doc"}}},
+		{{Key: "$sort", Value: bson.D{{Key: "instance", Value: 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var docs []document
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, ErrNotFound
+	}
+	return fromDocuments(docs)
+}
+
+// RegisterJob 注册定时快照任务（每个实例的热点 Key 与封禁可能不同，各实例分别快照）
+func RegisterJob() {
+	mu.RLock()
+	interval := cfg.SnapshotInterval
+	mu.RUnlock()
+	if interval <= 0 {
+		return
+	}
+
+	jobs.Register(jobs.Job{
+		Name:        "security_snapshot",
+		Description: "保存本实例的安全状态快照（封禁、限流热点、过滤规则、功能开关）",
+		Interval:    interval,
+		Run: func(ctx context.Context) error {
+			_, err := Take(ctx, TriggerScheduled)
+			return err
+		},
+	})
+}
+
+// collection 快照集合（首次使用时创建索引）
+func collection(ctx context.Context) (*mongo.Collection, error) {
+	db := database.GetMongoDB()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	mu.RLock()
+	c := cfg
+	mu.RUnlock()
+
+	coll := db.Collection(c.Collection)
+	ensureMu.Lock()
+	defer ensureMu.Unlock()
+	if !ensured {
+		if err := ensure(ctx, db, coll, c); err != nil {
+			return nil, err
+		}
+		ensured = true
+	}
+	return coll, nil
+}
+
+// ensure 创建按实例、时间查询的索引及按保留时长过期的 TTL 索引
+func ensure(ctx context.Context, db *mongo.Database, coll *mongo.Collection, c config.SecurityStateConfig) error {
+	if _, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "instance", Value: 1}, {Key: "taken_at", Value: -1}},
+	}); err != nil {
+		return err
+	}
+
+	takenAtIndex := options.Index().SetName("taken_at")
+	if c.Retention > 0 {
+		takenAtIndex.SetExpireAfterSeconds(int32(c.Retention / time.Second))
+	}
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "taken_at", Value: 1}},
+		Options: takenAtIndex,
+	})
+	var se mongo.ServerError
+	if err != nil && c.Retention > 0 && errors.As(err, &se) && se.HasErrorCode(codeIndexOptionsConflict) {
+		err = db.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: c.Collection},
+			{Key: "index", Value: bson.M{"name": "taken_at", "expireAfterSeconds": int32(c.Retention / time.Second)}},
+		}).Err()
+	}
+	if err != nil {
+		log.Printf("⚠️  安全状态快照集合 %s 的保留时长未按配置更新: %v", c.Collection, err)
+	}
+	return nil
+}
+
+// fromDocuments 转换存储的快照，状态按组成部分还原为 JSON
+func fromDocuments(docs []document) ([]Snapshot, error) {
+	snapshots := make([]Snapshot, len(docs))
+	for i, doc := range docs {
+		snapshots[i] = Snapshot{
+			ID:       doc.ID.Hex(),
+			Instance: doc.Instance,
+			TakenAt:  doc.TakenAt,
+			Trigger:  doc.Trigger,
+		}
+		if len(doc.State) == 0 {
+			continue
+		}
+		raw, err := bson.MarshalExtJSON(doc.State, false, false)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &snapshots[i].State); err != nil {
+			return nil, err
+		}
+	}
+	return snapshots, nil
+}
+
+// decodeJSON 把 JSON 还原为可写入 BSON 的值（整数保持为整数）
+func decodeJSON(raw []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return normalize(value), nil
+}
+
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = normalize(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalize(item)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return value
+}
@@ -0,0 +1,379 @@
+package securitytest
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"new-openclaw/internal/admin"
+	adminmiddleware "new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/appkey"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/handler"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/store"
+	"new-openclaw/pkg/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm/logger"
+)
+
+//go:embed corpus/*.txt
+var corpus embed.FS
+
+// 模拟环境中的管理员账号
+const (
+	simAdminUsername = "sim-admin"
+	simAdminPassword = "sim-admin-password"
+)
+
+var (
+	engine *gin.Engine
+	// reasons 最近一次请求被安全审计识别的原因（用例顺序执行）
+	reasons []string
+)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if err := setup(); err != nil {
+		fmt.Fprintf(os.Stderr, "启动模拟环境失败: %v\n", err)
+		os.Exit(2)
+	}
+	os.Exit(m.Run())
+}
+
+// setup 初始化单机模式依赖并注册全部路由
+func setup() error {
+	os.Setenv("APP_MODE", config.AppModeStandalone)
+	os.Setenv("SQLITE_PATH", ":memory:")
+	cfg := config.LoadConfig()
+
+	// 安全审计告警、SQL 日志在用例结果中体现；-v 时保留
+	verbose := testing.Verbose()
+	if !verbose {
+		log.SetOutput(io.Discard)
+	}
+	if err := database.InitSQLite(cfg.Server.SQLitePath); err != nil {
+		return err
+	}
+	if !verbose {
+		database.MySQL.Logger = logger.Default.LogMode(logger.Silent)
+	}
+	if err := database.AutoMigrate(); err != nil {
+		return err
+	}
+	store.Init(&cfg.Store)
+	appkey.Configure(cfg.AppKey)
+
+	simAdmin := model.Admin{Username: simAdminUsername, Role: "admin", Status: 1}
+	if err := simAdmin.SetPassword(simAdminPassword); err != nil {
+		return err
+	}
+	if err := database.MySQL.Create(&simAdmin).Error; err != nil {
+		return err
+	}
+
+	// 与 cmd/server 一致：路由注册前设置 JWT 与签名配置
+	jwtConfig := middleware.CurrentJWTConfig()
+	jwtConfig.SecretKey = cfg.Security.JWTSecretKey
+	jwtConfig.Issuer = cfg.Security.JWTIssuer
+	middleware.ConfigureJWT(jwtConfig)
+	adminmiddleware.DefaultConfig.SecretKey = cfg.Security.AdminJWTSecretKey
+	signatureConfig := middleware.CurrentSignatureConfig()
+	signatureConfig.SecretKey = cfg.Security.APISignatureKey
+	signatureConfig.SecretResolver = appkey.Resolve
+	middleware.ConfigureSignature(signatureConfig)
+
+	gin.SetMode(gin.ReleaseMode)
+	engine = gin.New()
+	engine.Use(gin.Recovery())
+	engine.Use(middleware.RequestID())
+	engine.Use(middleware.SecureHeaders())
+	engine.Use(middleware.SecurityAudit())
+	engine.Use(func(c *gin.Context) {
+		reasons = c.GetStringSlice(middleware.SecurityReasonsKey)
+		c.Next()
+	})
+	handler.RegisterRoutes(engine)
+	admin.RegisterRoutes(engine)
+	return nil
+}
+
+// TestAttackCorpus SQL 注入、XSS、路径遍历载荷：查询参数中的载荷须被安全审计识别，登录请求体中的载荷不能通过认证
+func TestAttackCorpus(t *testing.T) {
+	t.Run("SQL注入", func(t *testing.T) {
+		for _, payload := range loadCorpus(t, "sqli.txt") {
+			rec := do(http.MethodGet, "/ping?q="+url.QueryEscape(payload), nil, nil)
+			if rec.Code != http.StatusOK || !detected(middleware.ReasonSQLInjection) {
+				t.Errorf("查询参数 %s: 状态 %d，识别结果 %v", payload, rec.Code, reasons)
+			}
+
+			body, _ := json.Marshal(map[string]string{"username": payload, "password": payload})
+			header := http.Header{"Content-Type": {"application/json"}}
+			// 参数校验失败（400）或认证失败（401）均视为拒绝
+			for _, path := range []string{"/admin/login", "/api/v1/public/login"} {
+				rec = do(http.MethodPost, path, body, header)
+				if rec.Code != http.StatusUnauthorized && rec.Code != http.StatusBadRequest {
+					t.Errorf("%s %s: 状态 %d", path, payload, rec.Code)
+				}
+			}
+		}
+	})
+
+	t.Run("XSS", func(t *testing.T) {
+		for _, payload := range loadCorpus(t, "xss.txt") {
+			rec := do(http.MethodGet, "/ping?q="+url.QueryEscape(payload), nil, nil)
+			if rec.Code != http.StatusOK || !detected(middleware.ReasonXSS) {
+				t.Errorf("查询参数 %s: 状态 %d，识别结果 %v", payload, rec.Code, reasons)
+			}
+			if rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+				t.Errorf("查询参数 %s: 缺少 X-Content-Type-Options", payload)
+			}
+		}
+	})
+
+	// 路径遍历须被安全审计识别，且不能返回成功或服务端错误
+	t.Run("路径遍历", func(t *testing.T) {
+		for _, path := range loadCorpus(t, "traversal.txt") {
+			rec := do(http.MethodGet, path, nil, nil)
+			if rec.Code < 300 || rec.Code >= 500 || !detected(middleware.ReasonPathTraversal) {
+				t.Errorf("%s: 状态 %d，识别结果 %v", path, rec.Code, reasons)
+			}
+		}
+	})
+}
+
+// TestJWTTampering JWT 篡改：算法置空、改写声明、去除签名、错误密钥、过期、签发者不符、跨系统使用均须返回 401
+func TestJWTTampering(t *testing.T) {
+	userCfg := middleware.CurrentJWTConfig()
+	userToken, err := middleware.GenerateTokenWithScopes("1", "sim", "user", nil, userCfg)
+	if err != nil {
+		t.Fatalf("签发用户 Token: %v", err)
+	}
+	adminToken, _, err := adminmiddleware.GenerateToken(1, simAdminUsername, "admin")
+	if err != nil {
+		t.Fatalf("签发管理员 Token: %v", err)
+	}
+
+	expectStatus(t, "用户 Token 基线", "/api/v1/profile", userToken, http.StatusOK)
+	expectStatus(t, "管理员 Token 基线", "/admin/profile", adminToken, http.StatusOK)
+	expectStatus(t, "管理员越权基线", "/admin/admins", adminToken, http.StatusForbidden)
+
+	wrongKey := userCfg
+	wrongKey.SecretKey = "attacker-chosen-secret"
+	wrongKeyToken, _ := middleware.GenerateTokenWithScopes("1", "sim", "admin", []string{"*"}, wrongKey)
+
+	now := time.Now()
+	expired, _ := userCfg.Sign(&middleware.Claims{UserID: "1", Role: "user", RegisteredClaims: jwt.RegisteredClaims{
+		Issuer: userCfg.Issuer, Subject: "1", IssuedAt: jwt.NewNumericDate(now.Add(-2 * time.Hour)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(-time.Hour)),
+	}})
+	notYetValid, _ := userCfg.Sign(&middleware.Claims{UserID: "1", Role: "user", RegisteredClaims: jwt.RegisteredClaims{
+		Issuer: userCfg.Issuer, Subject: "1", NotBefore: jwt.NewNumericDate(now.Add(time.Hour)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(2 * time.Hour)),
+	}})
+	wrongIssuer, _ := userCfg.Sign(&middleware.Claims{UserID: "1", Role: "user", RegisteredClaims: jwt.RegisteredClaims{
+		Issuer: "attacker", Subject: "1", ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+	}})
+
+	cases := []struct {
+		name  string
+		path  string
+		token string
+	}{
+		{"alg=none", "/api/v1/profile", algNone(userToken)},
+		{"提升角色（保留原签名）", "/api/v1/admin/users", rewriteClaims(userToken, map[string]interface{}{"role": "admin", "scopes": []string{"*"}})},
+		{"去除签名", "/api/v1/profile", stripSignature(userToken)},
+		{"错误密钥签名", "/api/v1/admin/users", wrongKeyToken},
+		{"已过期", "/api/v1/profile", expired},
+		{"尚未生效", "/api/v1/profile", notYetValid},
+		{"签发者不符", "/api/v1/profile", wrongIssuer},
+		{"管理员 Token 访问用户接口", "/api/v1/profile", adminToken},
+		{"用户 Token 访问管理后台", "/admin/profile", userToken},
+		{"管理员提升为超级管理员（保留原签名）", "/admin/admins", rewriteClaims(adminToken, map[string]interface{}{"role": "super_admin"})},
+		{"管理员 alg=none", "/admin/admins", algNone(rewriteClaims(adminToken, map[string]interface{}{"role": "super_admin"}))},
+		{"格式错误", "/api/v1/profile", "not.a.jwt"},
+	}
+	for _, tc := range cases {
+		expectStatus(t, tc.name, tc.path, tc.token, http.StatusUnauthorized)
+	}
+}
+
+// TestSignatureTampering API 签名篡改：改写请求体、请求头，重放，过期时间戳均须被拒绝
+func TestSignatureTampering(t *testing.T) {
+	const path = "/api/v1/signed/webhook"
+	secret := middleware.CurrentSignatureConfig().SecretKey
+	body := []byte(`{"event":"sim"}`)
+
+	signed := func(cfg middleware.SignatureConfig) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "http://sim.local"+path+"?tag=a&tag=b", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		middleware.SignRequest(req, body, "", secret, cfg)
+		return req
+	}
+	expect := func(name string, req *http.Request, want int) {
+		t.Helper()
+		if rec := serve(req); rec.Code != want {
+			t.Errorf("%s: 期望 %d，实际 %d: %s", name, want, rec.Code, rec.Body.String())
+		}
+	}
+	v1 := middleware.CurrentSignatureConfig()
+	v2 := middleware.CurrentSignatureConfig()
+	v2.Algorithm = middleware.SignatureAlgorithmV2
+
+	for _, variant := range []struct {
+		name string
+		cfg  middleware.SignatureConfig
+	}{{"v1", v1}, {"v2", v2}} {
+		req := signed(variant.cfg)
+		expect(variant.name+" 签名基线", req, http.StatusOK)
+
+		replay := signed(variant.cfg)
+		replay.Header = req.Header.Clone()
+		expect(variant.name+" 重放", replay, http.StatusBadRequest)
+
+		tampered := signed(variant.cfg)
+		tampered.Body = io.NopCloser(strings.NewReader(`{"event":"evil"}`))
+		expect(variant.name+" 改写请求体", tampered, http.StatusUnauthorized)
+
+		query := signed(variant.cfg)
+		query.URL.RawQuery = "tag=a&tag=c"
+		expect(variant.name+" 改写重复查询参数", query, http.StatusUnauthorized)
+
+		stale := signed(variant.cfg)
+		stale.Header.Set("X-Timestamp", strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+		expect(variant.name+" 过期时间戳", stale, http.StatusBadRequest)
+	}
+
+	header := signed(v2)
+	header.Header.Set("Content-Type", "text/plain")
+	expect("v2 改写已签名请求头", header, http.StatusUnauthorized)
+
+	unsigned := signed(v2)
+	unsigned.Header.Set("X-Signed-Headers", "host")
+	expect("v2 未签名必签请求头", unsigned, http.StatusBadRequest)
+
+	missing := signed(v1)
+	missing.Header.Del("X-Signature")
+	expect("缺少签名", missing, http.StatusBadRequest)
+}
+
+// do 构造并发送请求
+func do(method, target string, body []byte, header http.Header) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, "http://sim.local"+target, bytes.NewReader(body))
+	if err != nil {
+		// 无法构造的请求视为被客户端拒绝（不会到达服务端）
+		reasons = nil
+		return &httptest.ResponseRecorder{Code: http.StatusBadRequest}
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	return serve(req)
+}
+
+// serve 在进程内处理请求
+func serve(req *http.Request) *httptest.ResponseRecorder {
+	reasons = nil
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+// expectStatus 携带 Token 发送 GET 请求并检查状态码
+func expectStatus(t *testing.T, name, path, token string, want int) {
+	t.Helper()
+	rec := do(http.MethodGet, path, nil, http.Header{"Authorization": {"Bearer " + token}})
+	if rec.Code != want {
+		t.Errorf("%s: 期望 %d，实际 %d: %s", name, want, rec.Code, rec.Body.String())
+	}
+}
+
+// detected 最近一次请求是否被识别为指定类型
+func detected(reason string) bool {
+	for _, r := range reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// loadCorpus 读取载荷文件（每行一个载荷，# 开头为注释）
+func loadCorpus(t *testing.T, name string) []string {
+	t.Helper()
+	f, err := corpus.Open("corpus/" + name)
+	if err != nil {
+		t.Fatalf("读取载荷失败: %v", err)
+	}
+	defer f.Close()
+
+	var payloads []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		payloads = append(payloads, line)
+	}
+	if len(payloads) == 0 {
+		t.Fatalf("载荷文件 %s 为空", name)
+	}
+	return payloads
+}
+
+// algNone 将 Token 改为 alg=none 且不带签名
+func algNone(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return token
+	}
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	return header + "." + parts[1] + "."
+}
+
+// rewriteClaims 改写 Token 载荷中的声明，保留原签名
+func rewriteClaims(token string, changes map[string]interface{}) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return token
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return token
+	}
+	claims := make(map[string]interface{})
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return token
+	}
+	for k, v := range changes {
+		claims[k] = v
+	}
+	payload, _ = json.Marshal(claims)
+	return parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+}
+
+// stripSignature 去除 Token 签名
+func stripSignature(token string) string {
+	if i := strings.LastIndex(token, "."); i >= 0 {
+		return token[:i+1]
+	}
+	return token
+}
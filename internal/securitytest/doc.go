@@ -0,0 +1,4 @@
+// Package securitytest 攻击模拟测试：在进程内以单机模式（SQLite 内存库、内存存储）启动服务，回放 SQL 注入、XSS、
+// 路径遍历、JWT 篡改及 API 签名篡改载荷，检查安全审计与认证层的响应是否符合预期（go test ./internal/securitytest -v）。
+// 载荷见 corpus/*.txt，每行一个，# 开头为注释
+package securitytest
@@ -0,0 +1,179 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/store"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 服务端会话：凭证为 "{会话 ID}.{随机密钥}"，存储中只保存密钥的 SHA-256。
+// 会话 ID 即声明中的 jti，出现在会话列表中，单独不能作为凭证使用。
+// 顺延空闲超时只修改记录的过期时间（记录已被删除时不会重新写入），最近请求时间单独保存
+
+// serverRecord 服务端会话记录
+type serverRecord struct {
+	SecretHash string          `json:"secret_hash"`
+	Data       json.RawMessage `json:"data"`
+	// 空闲超时（0 表示只有绝对超时）
+	Idle      time.Duration `json:"idle"`
+	CreatedAt time.Time     `json:"created_at"`
+	ExpiresAt time.Time     `json:"expires_at"`
+}
+
+// touchInterval 顺延空闲超时的最小间隔（避免每个请求都写存储）
+func (r *serverRecord) touchInterval() time.Duration {
+	if interval := r.Idle / 10; r.Idle > 0 && interval < time.Minute {
+		return interval
+	}
+	return time.Minute
+}
+
+// ttl 记录在存储中的有效期：空闲超时与绝对超时中较早的一个
+func (r *serverRecord) ttl(now time.Time) time.Duration {
+	ttl := r.ExpiresAt.Sub(now)
+	if r.Idle > 0 && r.Idle < ttl {
+		ttl = r.Idle
+	}
+	return ttl
+}
+
+// Create 创建服务端会话（会话 ID 取 claims.ID，绝对超时取 claims.ExpiresAt），保存 data，返回会话凭证
+func Create(ctx context.Context, claims jwt.RegisteredClaims, data interface{}, idle time.Duration) (string, error) {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return "", errors.New("会话缺少 ID 或过期时间")
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	r := &serverRecord{
+		SecretHash: secretHash(hex.EncodeToString(secret)),
+		Data:       payload,
+		Idle:       idle,
+		CreatedAt:  now,
+		ExpiresAt:  claims.ExpiresAt.Time,
+	}
+	ttl := r.ttl(now)
+	if ttl <= 0 {
+		return "", errors.New("会话已过期")
+	}
+	value, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	if err := store.For(store.ComponentSession).Set(ctx, serverKey(claims.ID), string(value), ttl); err != nil {
+		return "", err
+	}
+	return claims.ID + "." + hex.EncodeToString(secret), nil
+}
+
+// Lookup 校验会话凭证并将保存的数据解码到 data；会话不存在、已超时或密钥不匹配时返回 ErrNotFound。
+// 有请求时顺延空闲超时
+func Lookup(ctx context.Context, credential string, data interface{}) error {
+	id, secret, ok := strings.Cut(credential, ".")
+	if !ok || id == "" || secret == "" {
+		return ErrNotFound
+	}
+	r, err := loadRecord(ctx, id)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(secretHash(secret)), []byte(r.SecretHash)) != 1 {
+		return ErrNotFound
+	}
+
+	now := time.Now()
+	if !now.Before(r.ExpiresAt) {
+		return ErrNotFound
+	}
+	touch(ctx, id, r, now)
+	return json.Unmarshal(r.Data, data)
+}
+
+// touch 记录最近请求时间并顺延空闲超时（距上次顺延不足 touchInterval 时跳过；失败不影响本次请求）
+func touch(ctx context.Context, id string, r *serverRecord, now time.Time) {
+	s := store.For(store.ComponentSession)
+	seen, err := lastSeen(ctx, id, r)
+	if err == nil && now.Sub(seen) < r.touchInterval() {
+		return
+	}
+	ttl := r.ttl(now)
+	s.Expire(ctx, serverKey(id), ttl)
+	s.Set(ctx, seenKey(id), now.Format(time.RFC3339Nano), ttl)
+}
+
+// Destroy 删除服务端会话，会话立即失效
+func Destroy(ctx context.Context, id string) error {
+	return store.For(store.ComponentSession).Del(ctx, serverKey(id), seenKey(id))
+}
+
+// lastSeen 服务端会话最近一次请求的时间（还没有请求时取创建时间）
+func lastSeen(ctx context.Context, id string, r *serverRecord) (time.Time, error) {
+	value, err := store.For(store.ComponentSession).Get(ctx, seenKey(id))
+	if errors.Is(err, store.ErrNotFound) {
+		return r.CreatedAt, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// lastActive 服务端会话最近一次请求的时间；会话已失效时返回 ErrNotFound
+func lastActive(ctx context.Context, id string) (time.Time, error) {
+	r, err := loadRecord(ctx, id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return lastSeen(ctx, id, r)
+}
+
+// serverSide 会话 ID 是否为服务端会话
+func serverSide(ctx context.Context, id string) bool {
+	_, err := store.For(store.ComponentSession).Get(ctx, serverKey(id))
+	return err == nil
+}
+
+func loadRecord(ctx context.Context, id string) (*serverRecord, error) {
+	value, err := store.For(store.ComponentSession).Get(ctx, serverKey(id))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r serverRecord
+	if err := json.Unmarshal([]byte(value), &r); err != nil {
+		return nil, ErrNotFound
+	}
+	return &r, nil
+}
+
+func secretHash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func serverKey(id string) string {
+	return "sid:" + id
+}
+
+func seenKey(id string) string {
+	return "sid-seen:" + id
+}
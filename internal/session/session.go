@@ -0,0 +1,400 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/revocation"
+	"new-openclaw/internal/store"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrNotFound 会话不存在
+var ErrNotFound = errors.New("会话不存在")
+
+// ErrLimitExceeded 活跃会话数已达上限（拒绝新登录策略）
+var ErrLimitExceeded = errors.New("活跃会话数已达上限")
+
+// 超出并发会话上限时的处理策略
+const (
+	// PolicyReject 拒绝新登录
+	PolicyReject = "reject"
+	// PolicyRevokeOldest 吊销最早签发的会话
+	PolicyRevokeOldest = "revoke_oldest"
+)
+
+// 会话事件类型
+const (
+	EventLimitRejected = "limit_rejected"
+	EventLimitRevoked  = "limit_revoked"
+)
+
+// 会话事件保留数量与时间
+const (
+	maxEvents = 50
+	eventTTL  = 30 * 24 * time.Hour
+)
+
+// Limit 每个账号的并发会话上限（Max 为 0 不限制）
+type Limit struct {
+	Max    int
+	Policy string
+}
+
+// Event 会话事件（超出上限被拒绝、被吊销等）
+type Event struct {
+	Type      string    `json:"type"`
+	SessionID string    `json:"session_id,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	Device    string    `json:"device,omitempty"`
+	Reason    string    `json:"reason"`
+	At        time.Time `json:"at"`
+}
+
+// Session 已签发的 Token（以 jti 标识）
+type Session struct {
+	ID        string    `json:"id"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Device    string    `json:"device"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// 是否为发起请求的当前会话
+	Current bool `json:"current"`
+
+	// 服务端会话（吊销时直接删除，不使用 Token 黑名单）及其最近一次请求的时间
+	ServerSide   bool       `json:"server_side,omitempty"`
+	LastActiveAt *time.Time `json:"last_active_at,omitempty"`
+}
+
+// Track 记录新签发的 Token，按签发者 + 主体（账号）分组保存
+func Track(ctx context.Context, claims jwt.RegisteredClaims, ip, userAgent string) error {
+	if claims.ID == "" || claims.Subject == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+
+	sessions, err := load(ctx, claims.Issuer, claims.Subject)
+	if err != nil {
+		return err
+	}
+
+	issuedAt := time.Now()
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	sessions = append(sessions, Session{
+		ID:        claims.ID,
+		IP:        ip,
+		UserAgent: userAgent,
+		Device:    Device(userAgent),
+		IssuedAt:  issuedAt,
+		ExpiresAt: claims.ExpiresAt.Time,
+
+		ServerSide: serverSide(ctx, claims.ID),
+	})
+	return save(ctx, claims.Issuer, claims.Subject, sessions)
+}
+
+// List 获取账号的有效会话（按签发时间倒序）
+func List(ctx context.Context, issuer, subject string) ([]Session, error) {
+	sessions, err := load(ctx, issuer, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(sessions)-1; i < j; i, j = i+1, j-1 {
+		sessions[i], sessions[j] = sessions[j], sessions[i]
+	}
+	return sessions, nil
+}
+
+// Count 统计签发者下有效会话的账号数与会话数（遍历会话存储，开销与账号数成正比，调用方应缓存结果）
+func Count(ctx context.Context, issuer string) (accounts, sessions int, err error) {
+	prefix := key(issuer, "")
+	err = store.Scan(ctx, store.For(store.ComponentSession), prefix, func(k string) error {
+		active, err := load(ctx, issuer, strings.TrimPrefix(k, prefix))
+		if err != nil {
+			return err
+		}
+		if len(active) > 0 {
+			accounts++
+			sessions += len(active)
+		}
+		return nil
+	})
+	return accounts, sessions, err
+}
+
+// Revoke 吊销账号的单个会话（服务端会话直接删除，Token 加入黑名单）并移出会话列表
+func Revoke(ctx context.Context, issuer, subject, id string) error {
+	return revoke(ctx, issuer, subject, id, "")
+}
+
+// revoke 吊销单个会话并记录原因（见 revocation.RevokeWithReason）
+func revoke(ctx context.Context, issuer, subject, id, reason string) error {
+	sessions, err := load(ctx, issuer, subject)
+	if err != nil {
+		return err
+	}
+
+	for i, s := range sessions {
+		if s.ID != id {
+			continue
+		}
+		var err error
+		if s.ServerSide {
+			err = Destroy(ctx, s.ID)
+		}
+		// 服务端会话删除即失效；记录了原因时仍写入黑名单，供旧客户端得到明确的错误
+		if err == nil && (!s.ServerSide || reason != "") {
+			err = revocation.RevokeWithReason(ctx, jwt.RegisteredClaims{
+				ID:        s.ID,
+				ExpiresAt: jwt.NewNumericDate(s.ExpiresAt),
+			}, reason)
+		}
+		if err != nil {
+			return err
+		}
+		return save(ctx, issuer, subject, append(sessions[:i], sessions[i+1:]...))
+	}
+	return ErrNotFound
+}
+
+// Remove 从会话列表中移除（Token 已由调用方吊销，如登出）
+func Remove(ctx context.Context, issuer, subject, id string) error {
+	sessions, err := load(ctx, issuer, subject)
+	if err != nil {
+		return err
+	}
+
+	for i, s := range sessions {
+		if s.ID == id {
+			return save(ctx, issuer, subject, append(sessions[:i], sessions[i+1:]...))
+		}
+	}
+	return nil
+}
+
+// RevokeAll 吊销账号的全部会话，ttl 应不小于该签发者 Token 的最长有效期
+func RevokeAll(ctx context.Context, issuer, subject string, ttl time.Duration) error {
+	sessions, err := load(ctx, issuer, subject)
+	if err != nil {
+		return err
+	}
+	for _, s := range sessions {
+		if s.ServerSide {
+			if err := Destroy(ctx, s.ID); err != nil {
+				return err
+			}
+		}
+	}
+	if err := revocation.RevokeAll(ctx, issuer, subject, ttl); err != nil {
+		return err
+	}
+	return store.For(store.ComponentSession).Del(ctx, key(issuer, subject))
+}
+
+// Admit 拒绝新登录策略下检查是否还能创建会话，超出上限时记录事件并返回 ErrLimitExceeded
+func Admit(ctx context.Context, issuer, subject string, limit Limit, ip, userAgent string) error {
+	if limit.Max <= 0 || limit.Policy != PolicyReject {
+		return nil
+	}
+
+	sessions, err := load(ctx, issuer, subject)
+	if err != nil {
+		return err
+	}
+	if len(sessions) < limit.Max {
+		return nil
+	}
+
+	RecordEvent(ctx, issuer, subject, Event{
+		Type:   EventLimitRejected,
+		IP:     ip,
+		Device: Device(userAgent),
+		Reason: fmt.Sprintf("活跃会话数已达上限 %d，拒绝新登录", limit.Max),
+	})
+	return ErrLimitExceeded
+}
+
+// Enforce 吊销最早签发的会话直至不超过上限（keepID 为新签发的会话，不会被吊销），返回被吊销的会话。
+// 被吊销的会话记录为被新登录顶替（revocation.ReasonSuperseded）
+func Enforce(ctx context.Context, issuer, subject string, limit Limit, keepID string) ([]Session, error) {
+	if limit.Max <= 0 || limit.Policy == PolicyReject {
+		return nil, nil
+	}
+
+	sessions, err := load(ctx, issuer, subject)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].IssuedAt.Before(sessions[j].IssuedAt)
+	})
+
+	var revoked []Session
+	for _, s := range sessions {
+		if len(sessions)-len(revoked) <= limit.Max {
+			break
+		}
+		if s.ID == keepID {
+			continue
+		}
+		if err := revoke(ctx, issuer, subject, s.ID, revocation.ReasonSuperseded); err != nil {
+			return revoked, err
+		}
+		revoked = append(revoked, s)
+		RecordEvent(ctx, issuer, subject, Event{
+			Type:      EventLimitRevoked,
+			SessionID: s.ID,
+			IP:        s.IP,
+			Device:    s.Device,
+			Reason:    fmt.Sprintf("活跃会话数超过上限 %d，吊销最早的会话", limit.Max),
+		})
+	}
+	return revoked, nil
+}
+
+// RecordEvent 记录会话事件（只保留最近的事件，写入失败不影响主流程）
+func RecordEvent(ctx context.Context, issuer, subject string, event Event) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	events, _ := Events(ctx, issuer, subject)
+	events = append([]Event{event}, events...)
+	if len(events) > maxEvents {
+		events = events[:maxEvents]
+	}
+
+	data, err := json.Marshal(events)
+	if err != nil {
+		return
+	}
+	store.For(store.ComponentSession).Set(ctx, eventsKey(issuer, subject), string(data), eventTTL)
+}
+
+// Events 获取账号的会话事件（按时间倒序）
+func Events(ctx context.Context, issuer, subject string) ([]Event, error) {
+	value, err := store.For(store.ComponentSession).Get(ctx, eventsKey(issuer, subject))
+	if errors.Is(err, store.ErrNotFound) {
+		return []Event{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	if err := json.Unmarshal([]byte(value), &events); err != nil {
+		return []Event{}, nil
+	}
+	return events, nil
+}
+
+// Device 从 User-Agent 中识别浏览器与操作系统
+func Device(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	browser := "未知客户端"
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"):
+		browser = "curl"
+	}
+
+	os := ""
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"):
+		os = "iOS"
+	case strings.Contains(ua, "android"):
+		os = "Android"
+	case strings.Contains(ua, "windows"):
+		os = "Windows"
+	case strings.Contains(ua, "mac os"):
+		os = "macOS"
+	case strings.Contains(ua, "linux"):
+		os = "Linux"
+	}
+
+	if os == "" {
+		return browser
+	}
+	return browser + " / " + os
+}
+
+// load 读取会话列表并剔除已过期的会话（服务端会话以存储中的记录为准，空闲超时后即剔除）
+func load(ctx context.Context, issuer, subject string) ([]Session, error) {
+	value, err := store.For(store.ComponentSession).Get(ctx, key(issuer, subject))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []Session
+	if err := json.Unmarshal([]byte(value), &sessions); err != nil {
+		return nil, nil
+	}
+
+	now := time.Now()
+	active := sessions[:0]
+	for _, s := range sessions {
+		if !s.ExpiresAt.After(now) {
+			continue
+		}
+		if s.ServerSide {
+			seen, err := lastActive(ctx, s.ID)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err == nil {
+				s.LastActiveAt = &seen
+			}
+		}
+		active = append(active, s)
+	}
+	return active, nil
+}
+
+// save 保存会话列表，过期时间取最晚过期的会话
+func save(ctx context.Context, issuer, subject string, sessions []Session) error {
+	s := store.For(store.ComponentSession)
+	if len(sessions) == 0 {
+		return s.Del(ctx, key(issuer, subject))
+	}
+
+	var latest time.Time
+	for _, session := range sessions {
+		if session.ExpiresAt.After(latest) {
+			latest = session.ExpiresAt
+		}
+	}
+
+	data, err := json.Marshal(sessions)
+	if err != nil {
+		return err
+	}
+	return s.Set(ctx, key(issuer, subject), string(data), time.Until(latest))
+}
+
+func key(issuer, subject string) string {
+	return "tokens:" + issuer + ":" + subject
+}
+
+func eventsKey(issuer, subject string) string {
+	return "events:" + issuer + ":" + subject
+}
@@ -0,0 +1,64 @@
+package settings
+
+import (
+	"log"
+
+	"new-openclaw/internal/configcenter"
+	"new-openclaw/internal/middleware"
+)
+
+// 已接入的设置键
+const (
+	KeyRateLimitMaxRequests   = "rate_limit.max_requests"
+	KeyRateLimitWindow        = "rate_limit.window"
+	KeyRateLimitBurst         = "rate_limit.burst"
+	KeyRateLimitWarnThreshold = "rate_limit.warn_threshold"
+
+	KeyMaintenanceMessage    = "maintenance.message"
+	KeyMaintenanceRetryAfter = "maintenance.retry_after"
+	KeyMaintenanceAllowPaths = "maintenance.allow_paths"
+
+	// 功能开关的键前缀，如 feature.new_dashboard
+	FeaturePrefix = "feature."
+)
+
+// BindRateLimiter 将 rate_limit.* 设置绑定到动态频率限制器：设置变化时覆盖对应的字段，
+// 未设置（或已删除）的字段保持当前值（来自环境变量或配置中心）
+func BindRateLimiter(limiter *middleware.DynamicRateLimiter) {
+	apply := func() {
+		cfg := limiter.Config()
+		cfg.MaxRequests = GetInt(KeyRateLimitMaxRequests, cfg.MaxRequests)
+		cfg.Burst = GetInt(KeyRateLimitBurst, cfg.Burst)
+		cfg.WarnThreshold = GetFloat(KeyRateLimitWarnThreshold, cfg.WarnThreshold)
+		if window := GetDuration(KeyRateLimitWindow, cfg.Window); window > 0 {
+			cfg.Window = window
+		}
+		if cfg.MaxRequests <= 0 {
+			log.Printf("⚠️  忽略无效的频率限制设置 %s=%d", KeyRateLimitMaxRequests, cfg.MaxRequests)
+			return
+		}
+		if err := limiter.Update(cfg); err != nil {
+			log.Printf("⚠️  忽略无效的频率限制设置: %v", err)
+		}
+	}
+	Watch("rate_limit.", apply)
+	apply()
+}
+
+// BindMaintenance 维护模式的默认提示信息、Retry-After 与开放路径按 maintenance.* 设置覆盖
+func BindMaintenance() {
+	middleware.MaintenanceOverride = func(cfg middleware.MaintenanceConfig) middleware.MaintenanceConfig {
+		cfg.Message = GetString(KeyMaintenanceMessage, cfg.Message)
+		cfg.RetryAfter = GetDuration(KeyMaintenanceRetryAfter, cfg.RetryAfter)
+		var paths []string
+		if GetJSON(KeyMaintenanceAllowPaths, &paths) {
+			cfg.AllowPaths = paths
+		}
+		return cfg
+	}
+}
+
+// Feature 查询功能开关：设置 feature.<name> 优先，未设置时取配置中心的开关
+func Feature(name string) bool {
+	return GetBool(FeaturePrefix+name, configcenter.Feature(name))
+}
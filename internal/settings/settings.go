@@ -0,0 +1,390 @@
+// Package settings 系统设置：settings 表中的类型化键值，管理后台修改后无需重启即可生效。
+// 各实例在内存中缓存全部设置，变更后通过 Redis 广播让其他实例重新加载；读取接口按类型解析，未设置时返回调用方给出的默认值
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/jobs"
+	"new-openclaw/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// channel 设置变更广播频道（各实例收到后重新加载）
+const channel = "openclaw:settings:reload"
+
+var (
+	// ErrUnavailable 数据库未连接
+	ErrUnavailable = errors.New("数据库未连接")
+	// ErrNotFound 设置不存在
+	ErrNotFound = errors.New("设置不存在")
+	// ErrExists 键已存在
+	ErrExists = errors.New("设置已存在")
+	// ErrInvalid 键、类型或值不合法
+	ErrInvalid = errors.New("无效的设置")
+)
+
+// keyPattern 键只允许小写字母、数字、下划线、中划线，以 . 分段
+var keyPattern = regexp.MustCompile(`^[a-z0-9_-]+(\.[a-z0-9_-]+)*$`)
+
+// watcher 按键前缀订阅的变更回调
+type watcher struct {
+	prefix string
+	fn     func()
+}
+
+var (
+	mu       sync.RWMutex
+	values   = make(map[string]model.Setting)
+	watchers []watcher
+
+	cancel context.CancelFunc
+)
+
+// Validate 检查键、类型与值，并规范化（去除空白、布尔值统一为 true/false、补全分组）
+func Validate(s *model.Setting) error {
+	s.Key = strings.TrimSpace(s.Key)
+	if len(s.Key) > 128 || !keyPattern.MatchString(s.Key) {
+		return fmt.Errorf("%w: 键只能包含小写字母、数字、_、-，以 . 分段", ErrInvalid)
+	}
+	if s.Type == "" {
+		s.Type = model.SettingTypeString
+	}
+
+	value := strings.TrimSpace(s.Value)
+	switch s.Type {
+	case model.SettingTypeString:
+		// 字符串保留原样
+		value = s.Value
+	case model.SettingTypeInt:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("%w: %s 不是整数", ErrInvalid, value)
+		}
+	case model.SettingTypeFloat:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%w: %s 不是数字", ErrInvalid, value)
+		}
+	case model.SettingTypeBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%w: %s 不是布尔值", ErrInvalid, value)
+		}
+		value = strconv.FormatBool(b)
+	case model.SettingTypeDuration:
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("%w: %s 不是时长（如 30s、5m）", ErrInvalid, value)
+		}
+	case model.SettingTypeJSON:
+		if !json.Valid([]byte(value)) {
+			return fmt.Errorf("%w: 值不是合法的 JSON", ErrInvalid)
+		}
+	default:
+		return fmt.Errorf("%w: 不支持的类型 %s", ErrInvalid, s.Type)
+	}
+	s.Value = value
+
+	s.Group = strings.TrimSpace(s.Group)
+	if s.Group == "" {
+		s.Group, _, _ = strings.Cut(s.Key, ".")
+	}
+	return nil
+}
+
+// List 获取数据库中的设置（group 不为空时只返回该分组）
+func List(ctx context.Context, group string) ([]model.Setting, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	query := db.WithContext(ctx).Order("setting_key ASC")
+	if group != "" {
+		query = query.Where("setting_group = ?", group)
+	}
+	list := []model.Setting{}
+	if err := query.Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Get 从数据库获取设置
+func Get(ctx context.Context, key string) (*model.Setting, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	var s model.Setting
+	err := db.WithContext(ctx).Where("setting_key = ?", key).First(&s).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Create 添加设置并通知所有实例重新加载
+func Create(ctx context.Context, s *model.Setting) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+	if err := Validate(s); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := db.WithContext(ctx).Model(&model.Setting{}).Where("setting_key = ?", s.Key).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %s", ErrExists, s.Key)
+	}
+	if err := db.WithContext(ctx).Create(s).Error; err != nil {
+		return err
+	}
+
+	changed(ctx)
+	return nil
+}
+
+// Update 保存修改后的设置（键不可修改）并通知所有实例重新加载
+func Update(ctx context.Context, s *model.Setting) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+	if err := Validate(s); err != nil {
+		return err
+	}
+	if err := db.WithContext(ctx).Save(s).Error; err != nil {
+		return err
+	}
+
+	changed(ctx)
+	return nil
+}
+
+// Delete 删除设置（之后读取返回默认值）并通知所有实例重新加载
+func Delete(ctx context.Context, key string) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+	result := db.WithContext(ctx).Where("setting_key = ?", key).Delete(&model.Setting{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	changed(ctx)
+	return nil
+}
+
+// Load 重新读取全部设置替换本实例的缓存，并通知发生变化的键的订阅者；数据库未连接时保持当前缓存
+func Load(ctx context.Context) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil
+	}
+	var list []model.Setting
+	if err := db.WithContext(ctx).Find(&list).Error; err != nil {
+		return err
+	}
+	next := make(map[string]model.Setting, len(list))
+	for _, s := range list {
+		next[s.Key] = s
+	}
+
+	mu.Lock()
+	var keys []string
+	for key, s := range next {
+		if old, ok := values[key]; !ok || old.Type != s.Type || old.Value != s.Value {
+			keys = append(keys, key)
+		}
+	}
+	for key := range values {
+		if _, ok := next[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	values = next
+	var notify []func()
+	for _, w := range watchers {
+		for _, key := range keys {
+			if strings.HasPrefix(key, w.prefix) {
+				notify = append(notify, w.fn)
+				break
+			}
+		}
+	}
+	mu.Unlock()
+
+	for _, fn := range notify {
+		fn()
+	}
+	return nil
+}
+
+// Watch 订阅键前缀（如 rate_limit.）下设置的新增、修改与删除，加载后在调用 Load 的协程中回调
+func Watch(prefix string, fn func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	watchers = append(watchers, watcher{prefix: prefix, fn: fn})
+}
+
+// changed 设置变更后立即在本实例生效，并广播给其他实例
+func changed(ctx context.Context) {
+	if err := Load(ctx); err != nil {
+		log.Printf("重新加载系统设置失败: %v", err)
+	}
+	if rdb := database.GetRedis(); rdb != nil {
+		if err := rdb.Publish(ctx, channel, time.Now().Format(time.RFC3339Nano)).Err(); err != nil {
+			log.Printf("广播系统设置变更失败（其他实例将在定时任务中重新加载）: %v", err)
+		}
+	}
+}
+
+// Start 启动时加载设置，并订阅变更广播（未连接 Redis 时只依赖定时重新加载）
+func Start() {
+	if err := Load(context.Background()); err != nil {
+		log.Printf("⚠️  加载系统设置失败，使用环境变量配置: %v", err)
+	}
+
+	rdb := database.GetRedis()
+	if rdb == nil {
+		return
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	mu.Lock()
+	cancel = stop
+	mu.Unlock()
+
+	sub := rdb.Subscribe(ctx, channel)
+	messages := sub.Channel()
+	go func() {
+		defer sub.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-messages:
+				if !ok {
+					return
+				}
+				if err := Load(ctx); err != nil {
+					log.Printf("重新加载系统设置失败: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop 停止订阅变更广播
+func Stop() {
+	mu.Lock()
+	defer mu.Unlock()
+	if cancel != nil {
+		cancel()
+		cancel = nil
+	}
+}
+
+// RegisterReloadJob 注册定时重新加载设置的任务（每个实例都执行，补偿遗漏的广播）
+func RegisterReloadJob(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	jobs.Register(jobs.Job{
+		Name:        "settings_reload",
+		Description: "重新加载系统设置",
+		Interval:    interval,
+		Run:         Load,
+	})
+}
+
+// Lookup 读取本实例缓存的设置
+func Lookup(key string) (model.Setting, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	s, ok := values[key]
+	return s, ok
+}
+
+// lookupType 读取指定类型的设置（类型不符时视为未设置）
+func lookupType(key, typ string) (string, bool) {
+	s, ok := Lookup(key)
+	if !ok || s.Type != typ {
+		return "", false
+	}
+	return s.Value, true
+}
+
+// GetString 字符串设置，未设置时返回 def
+func GetString(key, def string) string {
+	if v, ok := lookupType(key, model.SettingTypeString); ok {
+		return v
+	}
+	return def
+}
+
+// GetInt 整数设置，未设置时返回 def
+func GetInt(key string, def int) int {
+	if v, ok := lookupType(key, model.SettingTypeInt); ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// GetFloat 数字设置，未设置时返回 def
+func GetFloat(key string, def float64) float64 {
+	if v, ok := lookupType(key, model.SettingTypeFloat); ok {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return def
+}
+
+// GetBool 布尔设置，未设置时返回 def
+func GetBool(key string, def bool) bool {
+	if v, ok := lookupType(key, model.SettingTypeBool); ok {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+// GetDuration 时长设置，未设置时返回 def
+func GetDuration(key string, def time.Duration) time.Duration {
+	if v, ok := lookupType(key, model.SettingTypeDuration); ok {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return def
+}
+
+// GetJSON 将 JSON 设置解析到 v，未设置或解析失败时返回 false（v 不变）
+func GetJSON(key string, v interface{}) bool {
+	raw, ok := lookupType(key, model.SettingTypeJSON)
+	return ok && json.Unmarshal([]byte(raw), v) == nil
+}
@@ -0,0 +1,252 @@
+package store
+
+import (
+	"context"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryItem 内存存储条目
+type memoryItem struct {
+	value    string
+	expireAt time.Time
+}
+
+// expired 检查是否已过期
+func (i *memoryItem) expired(now time.Time) bool {
+	return !i.expireAt.IsZero() && now.After(i.expireAt)
+}
+
+// memoryShards 分片数（按 Key 哈希分片，降低高并发下的锁竞争）
+const memoryShards = 64
+
+// memoryShard 存储分片
+type memoryShard struct {
+	items map[string]*memoryItem
+	mu    sync.Mutex
+}
+
+// MemoryStore 内存存储（仅单实例有效）
+type MemoryStore struct {
+	shards [memoryShards]*memoryShard
+}
+
+// NewMemoryStore 创建内存存储
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{}
+	for i := range s.shards {
+		s.shards[i] = &memoryShard{items: make(map[string]*memoryItem)}
+	}
+
+	// 启动清理协程
+	go s.cleanup()
+
+	return s
+}
+
+// shard 获取 Key 所在分片
+func (s *MemoryStore) shard(key string) *memoryShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return s.shards[h.Sum32()%memoryShards]
+}
+
+// cleanup 定期清理过期条目（逐个分片加锁，不会长时间阻塞全部请求）
+func (s *MemoryStore) cleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, shard := range s.shards {
+			shard.mu.Lock()
+			now := time.Now()
+			for key, item := range shard.items {
+				if item.expired(now) {
+					delete(shard.items, key)
+				}
+			}
+			shard.mu.Unlock()
+		}
+	}
+}
+
+// get 获取未过期的条目（调用方需持有分片锁）
+func (s *memoryShard) get(key string, now time.Time) (*memoryItem, bool) {
+	item, exists := s.items[key]
+	if !exists {
+		return nil, false
+	}
+	if item.expired(now) {
+		delete(s.items, key)
+		return nil, false
+	}
+	return item, true
+}
+
+// Get 获取值
+func (s *MemoryStore) Get(ctx context.Context, key string) (string, error) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	item, exists := shard.get(key, time.Now())
+	if !exists {
+		return "", ErrNotFound
+	}
+	return item.value, nil
+}
+
+// Set 设置值
+func (s *MemoryStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	item := &memoryItem{value: value}
+	if ttl > 0 {
+		item.expireAt = time.Now().Add(ttl)
+	}
+	shard.items[key] = item
+	return nil
+}
+
+// SetNX 仅在 Key 不存在时设置值
+func (s *MemoryStore) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := time.Now()
+	if _, exists := shard.get(key, now); exists {
+		return false, nil
+	}
+
+	item := &memoryItem{value: value}
+	if ttl > 0 {
+		item.expireAt = now.Add(ttl)
+	}
+	shard.items[key] = item
+	return true, nil
+}
+
+// Incr 自增
+func (s *MemoryStore) Incr(ctx context.Context, key string) (int64, error) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	item, exists := shard.get(key, time.Now())
+	if !exists {
+		shard.items[key] = &memoryItem{value: "1"}
+		return 1, nil
+	}
+
+	n, err := strconv.ParseInt(item.value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	n++
+	item.value = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+// IncrWithTTL 自增，Key 新建时设置过期时间
+func (s *MemoryStore) IncrWithTTL(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := time.Now()
+	item, exists := shard.get(key, now)
+	if !exists {
+		item = &memoryItem{value: "1"}
+		if ttl > 0 {
+			item.expireAt = now.Add(ttl)
+		}
+		shard.items[key] = item
+		return 1, nil
+	}
+
+	n, err := strconv.ParseInt(item.value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	n++
+	item.value = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+// Expire 设置过期时间
+func (s *MemoryStore) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := time.Now()
+	item, exists := shard.get(key, now)
+	if !exists {
+		return nil
+	}
+	if ttl > 0 {
+		item.expireAt = now.Add(ttl)
+	} else {
+		delete(shard.items, key)
+	}
+	return nil
+}
+
+// TTL 获取剩余过期时间
+func (s *MemoryStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := time.Now()
+	item, exists := shard.get(key, now)
+	if !exists {
+		return 0, ErrNotFound
+	}
+	if item.expireAt.IsZero() {
+		return -1, nil
+	}
+	return item.expireAt.Sub(now), nil
+}
+
+// Del 删除 Key
+func (s *MemoryStore) Del(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		shard := s.shard(key)
+		shard.mu.Lock()
+		delete(shard.items, key)
+		shard.mu.Unlock()
+	}
+	return nil
+}
+
+// Scan 按前缀遍历未过期的 Key（逐个分片复制 Key 后再回调，回调中可以访问存储）
+func (s *MemoryStore) Scan(ctx context.Context, prefix string, fn func(key string) error) error {
+	now := time.Now()
+	for _, shard := range s.shards {
+		var keys []string
+		shard.mu.Lock()
+		for key, item := range shard.items {
+			if strings.HasPrefix(key, prefix) && !item.expired(now) {
+				keys = append(keys, key)
+			}
+		}
+		shard.mu.Unlock()
+
+		for _, key := range keys {
+			if err := fn(key); err != nil {
+				return err
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newSingleLockStore 对照组：所有分片指向同一个 map 与锁，等同分片前全部 Key 共用一把锁的实现
+// （读写路径与分片版本完全相同，差异只在锁竞争）
+func newSingleLockStore() *MemoryStore {
+	s := &MemoryStore{}
+	shared := &memoryShard{items: make(map[string]*memoryItem)}
+	for i := range s.shards {
+		s.shards[i] = shared
+	}
+	return s
+}
+
+// storeVariants 参与对比的存储实现
+var storeVariants = []struct {
+	name string
+	new  func() *MemoryStore
+}{
+	{"sharded", NewMemoryStore},
+	{"single-lock", newSingleLockStore},
+}
+
+// benchKeys 模拟按 IP 限流的计数器 Key
+var benchKeys = func() []string {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = "ratelimit:10.0." + strconv.Itoa(i/256) + "." + strconv.Itoa(i%256)
+	}
+	return keys
+}()
+
+// BenchmarkStoreIncr 固定窗口限流的读写路径：不同 Key 上的 IncrWithTTL
+func BenchmarkStoreIncr(b *testing.B) {
+	for _, v := range storeVariants {
+		b.Run(v.name, func(b *testing.B) {
+			s, ctx := v.new(), context.Background()
+			var seq uint32
+			b.RunParallel(func(pb *testing.PB) {
+				i := int(atomic.AddUint32(&seq, 1)) * 7919
+				for pb.Next() {
+					key := benchKeys[i%len(benchKeys)]
+					if n, _ := s.Incr(ctx, key); n == 1 {
+						s.Expire(ctx, key, time.Minute)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkStoreIncrHotKey 所有请求落在同一个 Key 上（分片无法分散竞争）
+func BenchmarkStoreIncrHotKey(b *testing.B) {
+	for _, v := range storeVariants {
+		b.Run(v.name, func(b *testing.B) {
+			s, ctx := v.new(), context.Background()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					s.Incr(ctx, "ratelimit:hot")
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkStoreGetSet 读多写少（9 次 Get、1 次 Set），如 Token 黑名单、AppKey 缓存
+func BenchmarkStoreGetSet(b *testing.B) {
+	for _, v := range storeVariants {
+		b.Run(v.name, func(b *testing.B) {
+			s, ctx := v.new(), context.Background()
+			for _, key := range benchKeys {
+				s.Set(ctx, key, "1", time.Minute)
+			}
+			var seq uint32
+			b.RunParallel(func(pb *testing.PB) {
+				i := int(atomic.AddUint32(&seq, 1)) * 7919
+				for pb.Next() {
+					key := benchKeys[i%len(benchKeys)]
+					if i%10 == 0 {
+						s.Set(ctx, key, "1", time.Minute)
+					} else {
+						s.Get(ctx, key)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+// TestMemoryStoreConcurrentIncr 并发自增不丢失计数，新建的计数带过期时间
+func TestMemoryStoreConcurrentIncr(t *testing.T) {
+	s, ctx := NewMemoryStore(), context.Background()
+
+	const workers, perWorker = 8, 1000
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				key := benchKeys[(w*perWorker+i)%16]
+				if n, err := s.Incr(ctx, key); err != nil {
+					t.Error(err)
+					return
+				} else if n == 1 {
+					s.Expire(ctx, key, time.Minute)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	var total int64
+	for _, key := range benchKeys[:16] {
+		value, err := s.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+		n, _ := strconv.ParseInt(value, 10, 64)
+		total += n
+		if ttl, err := s.TTL(ctx, key); err != nil || ttl <= 0 {
+			t.Errorf("%s: TTL = %v, %v", key, ttl, err)
+		}
+	}
+	if total != workers*perWorker {
+		t.Fatalf("计数 = %d，期望 %d", total, workers*perWorker)
+	}
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// incrWithTTLScript 自增，Key 新建（或此前遗留了没有过期时间的计数）时设置过期时间
+var incrWithTTLScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 or redis.call("PTTL", KEYS[1]) == -1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// RedisStore Redis 存储（多实例共享）
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore 创建 Redis 存储
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Get 获取值
+func (s *RedisStore) Get(ctx context.Context, key string) (string, error) {
+	value, err := s.client.Get(ctx, s.prefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+// Set 设置值
+func (s *RedisStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+key, value, ttl).Err()
+}
+
+// SetNX 仅在 Key 不存在时设置值
+func (s *RedisStore) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, s.prefix+key, value, ttl).Result()
+}
+
+// Incr 自增
+func (s *RedisStore) Incr(ctx context.Context, key string) (int64, error) {
+	return s.client.Incr(ctx, s.prefix+key).Result()
+}
+
+// IncrWithTTL 自增，Key 新建时设置过期时间（Lua 脚本，INCR 与 PEXPIRE 在一次往返中原子执行）
+func (s *RedisStore) IncrWithTTL(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	if ttl <= 0 {
+		return s.Incr(ctx, key)
+	}
+	return incrWithTTLScript.Run(ctx, s.client, []string{s.prefix + key}, ttl.Milliseconds()).Int64()
+}
+
+// Expire 设置过期时间
+func (s *RedisStore) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return s.client.Del(ctx, s.prefix+key).Err()
+	}
+	return s.client.Expire(ctx, s.prefix+key, ttl).Err()
+}
+
+// TTL 获取剩余过期时间
+func (s *RedisStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := s.client.TTL(ctx, s.prefix+key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// Redis 约定：-2 表示 Key 不存在，-1 表示未设置过期时间
+	if ttl == -2 {
+		return 0, ErrNotFound
+	}
+	return ttl, nil
+}
+
+// Del 删除 Key
+func (s *RedisStore) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = s.prefix + key
+	}
+	return s.client.Del(ctx, prefixed...).Err()
+}
+
+// scanBatch 每次 SCAN 的建议数量
+const scanBatch = 500
+
+// globEscaper 转义 MATCH 模式中的通配字符（前缀按字面匹配）
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// Scan 按前缀遍历 Key（SCAN 游标分批读取，遍历期间新增、删除的 Key 可能被遗漏或重复）
+func (s *RedisStore) Scan(ctx context.Context, prefix string, fn func(key string) error) error {
+	iter := s.client.Scan(ctx, 0, globEscaper.Replace(s.prefix+prefix)+"*", scanBatch).Iterator()
+	for iter.Next(ctx) {
+		if err := fn(strings.TrimPrefix(iter.Val(), s.prefix)); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/pkg/config"
+)
+
+// ErrNotFound Key 不存在或已过期
+var ErrNotFound = errors.New("key 不存在")
+
+// 存储后端类型
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// 使用存储的组件名称
+const (
+	ComponentNonce       = "nonce"
+	ComponentSession     = "session"
+	ComponentRateLimit   = "ratelimit"
+	ComponentQuota       = "quota"
+	ComponentRevocation  = "revocation"
+	ComponentAbuse       = "abuse"
+	ComponentJobs        = "jobs"
+	ComponentAppKey      = "appkey"
+	ComponentLogin       = "login"
+	ComponentMaintenance = "maintenance"
+	ComponentAPIKey      = "apikey"
+	ComponentCaptcha     = "captcha"
+	// 重置密码令牌
+	ComponentPasswordReset = "password_reset"
+	// WebAuthn 注册、登录挑战
+	ComponentWebAuthn = "webauthn"
+)
+
+// Store 统一的 KV/状态存储接口
+type Store interface {
+	// Get 获取值，不存在时返回 ErrNotFound
+	Get(ctx context.Context, key string) (string, error)
+	// Set 设置值，ttl 为 0 表示永不过期
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	// SetNX 仅在 Key 不存在时设置值，返回是否设置成功
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	// Incr 自增并返回自增后的值（Key 不存在时从 0 开始）
+	Incr(ctx context.Context, key string) (int64, error)
+	// IncrWithTTL 自增并返回自增后的值，Key 新建时在同一原子操作中设置过期时间（固定窗口计数器使用，
+	// 避免 Incr 成功、Expire 失败时留下永不过期的计数）
+	IncrWithTTL(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// Expire 设置过期时间
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+	// TTL 获取剩余过期时间，Key 不存在时返回 ErrNotFound，未设置过期时间返回 -1
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	// Del 删除 Key
+	Del(ctx context.Context, keys ...string) error
+}
+
+// Scanner 可以按前缀遍历 Key 的存储（用于统计，Redis 使用 SCAN 分批遍历，不阻塞其他命令）
+type Scanner interface {
+	// Scan 按前缀遍历未过期的 Key（不含存储自身的前缀），fn 返回错误时停止
+	Scan(ctx context.Context, prefix string, fn func(key string) error) error
+}
+
+// ErrScanUnsupported 存储不支持遍历 Key
+var ErrScanUnsupported = errors.New("存储不支持遍历 Key")
+
+// Scan 按前缀遍历存储中的 Key，存储未实现 Scanner 时返回 ErrScanUnsupported
+func Scan(ctx context.Context, s Store, prefix string, fn func(key string) error) error {
+	scanner, ok := s.(Scanner)
+	if !ok {
+		return ErrScanUnsupported
+	}
+	return scanner.Scan(ctx, prefix, fn)
+}
+
+var (
+	stores   = make(map[string]Store)
+	backends = make(map[string]string)
+	storesMu sync.RWMutex
+)
+
+// Init 根据配置初始化各组件的存储后端
+func Init(cfg *config.StoreConfig) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	backends[ComponentNonce] = cfg.NonceBackend
+	backends[ComponentSession] = cfg.SessionBackend
+	backends[ComponentRateLimit] = cfg.RateLimitBackend
+	backends[ComponentQuota] = cfg.QuotaBackend
+	backends[ComponentRevocation] = cfg.RevocationBackend
+	backends[ComponentAbuse] = cfg.AbuseBackend
+	backends[ComponentJobs] = cfg.JobsBackend
+	backends[ComponentAppKey] = cfg.AppKeyBackend
+	backends[ComponentLogin] = cfg.LoginBackend
+	backends[ComponentMaintenance] = cfg.MaintenanceBackend
+	backends[ComponentAPIKey] = cfg.APIKeyBackend
+	backends[ComponentCaptcha] = cfg.CaptchaBackend
+	backends[ComponentPasswordReset] = cfg.PasswordResetBackend
+	backends[ComponentWebAuthn] = cfg.WebAuthnBackend
+
+	for component, backend := range backends {
+		stores[component] = newStore(component, backend)
+	}
+}
+
+// For 获取指定组件的存储（未初始化时使用内存存储）
+func For(component string) Store {
+	storesMu.RLock()
+	s, ok := stores[component]
+	storesMu.RUnlock()
+	if ok {
+		return s
+	}
+
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	if s, ok := stores[component]; ok {
+		return s
+	}
+	s = NewMemoryStore()
+	stores[component] = s
+	backends[component] = BackendMemory
+	return s
+}
+
+// Backends 获取各组件当前使用的存储后端
+func Backends() map[string]string {
+	storesMu.RLock()
+	defer storesMu.RUnlock()
+
+	result := make(map[string]string, len(backends))
+	for component, backend := range backends {
+		result[component] = backend
+	}
+	return result
+}
+
+// newStore 按后端类型创建存储
+func newStore(component, backend string) Store {
+	switch backend {
+	case BackendRedis:
+		if rdb := database.GetRedis(); rdb != nil {
+			return NewRedisStore(rdb, "openclaw:"+component+":")
+		}
+		log.Printf("⚠️  %s 存储配置为 Redis，但 Redis 未初始化，回退到内存存储", component)
+	case BackendMemory, "":
+	default:
+		log.Printf("⚠️  未知的存储后端 %q（%s），使用内存存储", backend, component)
+	}
+	backends[component] = BackendMemory
+	return NewMemoryStore()
+}
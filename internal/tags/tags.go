@@ -0,0 +1,332 @@
+package tags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 可打标签的资源类型
+const (
+	ResourceAdmin  = "admin"
+	ResourceAppKey = "app_key"
+	ResourceIPRule = "ip_rule"
+)
+
+// ResourceTypes 资源类型（按名称排序）
+var ResourceTypes = []string{ResourceAdmin, ResourceAppKey, ResourceIPRule}
+
+// resources 资源类型对应的模型（用于校验资源是否存在，软删除的记录视为不存在）
+var resources = map[string]func() interface{}{
+	ResourceAdmin:  func() interface{} { return &model.Admin{} },
+	ResourceAppKey: func() interface{} { return &model.AppKey{} },
+	ResourceIPRule: func() interface{} { return &model.IPRule{} },
+}
+
+// MaxPerResource 每个资源的标签数量上限
+const MaxPerResource = 20
+
+var (
+	// ErrUnavailable 数据库未连接
+	ErrUnavailable = errors.New("数据库未连接")
+	// ErrNotFound 标签不存在
+	ErrNotFound = errors.New("标签不存在")
+	// ErrResourceNotFound 资源不存在
+	ErrResourceNotFound = errors.New("资源不存在")
+	// ErrInvalid 资源类型、标签名或数量不合法
+	ErrInvalid = errors.New("参数错误")
+)
+
+// namePattern 标签名：小写字母、数字开头，可包含 . _ : -，最长 32 个字符
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._:-]{0,31}$`)
+
+// Usage 标签及各资源类型的使用次数
+type Usage struct {
+	model.Tag
+	Counts map[string]int `json:"counts"`
+}
+
+// ValidResource 检查资源类型
+func ValidResource(resourceType string) error {
+	if _, ok := resources[resourceType]; !ok {
+		return fmt.Errorf("%w: 无效的资源类型 %s（可选 %s）", ErrInvalid, resourceType, strings.Join(ResourceTypes, ", "))
+	}
+	return nil
+}
+
+// Normalize 校验并规范化标签名（去除首尾空白、转小写、去重）
+func Normalize(names []string) ([]string, error) {
+	seen := make(map[string]bool, len(names))
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !namePattern.MatchString(name) {
+			return nil, fmt.Errorf("%w: 无效的标签名 %q（小写字母、数字开头，可包含 . _ : -，最长 32 个字符）", ErrInvalid, name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
+
+// List 获取所有标签及使用次数
+func List(ctx context.Context) ([]Usage, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+
+	var list []model.Tag
+	if err := db.WithContext(ctx).Order("name ASC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	var counts []struct {
+		TagID        uint
+		ResourceType string
+		Count        int
+	}
+	if err := db.WithContext(ctx).Model(&model.Tagging{}).
+		Select("tag_id, resource_type, COUNT(*) AS count").
+		Group("tag_id, resource_type").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	byTag := make(map[uint]map[string]int, len(list))
+	for _, c := range counts {
+		if byTag[c.TagID] == nil {
+			byTag[c.TagID] = make(map[string]int)
+		}
+		byTag[c.TagID][c.ResourceType] = c.Count
+	}
+	usages := make([]Usage, len(list))
+	for i, tag := range list {
+		usages[i] = Usage{Tag: tag, Counts: byTag[tag.ID]}
+		if usages[i].Counts == nil {
+			usages[i].Counts = map[string]int{}
+		}
+	}
+	return usages, nil
+}
+
+// Of 获取资源的标签
+func Of(ctx context.Context, resourceType string, id uint) ([]string, error) {
+	m, err := Map(ctx, resourceType, []uint{id})
+	if err != nil {
+		return nil, err
+	}
+	if m[id] == nil {
+		return []string{}, nil
+	}
+	return m[id], nil
+}
+
+// Map 批量获取资源的标签（列表接口填充 Tags 字段）
+func Map(ctx context.Context, resourceType string, ids []uint) (map[uint][]string, error) {
+	result := make(map[uint][]string, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+
+	var rows []struct {
+		ResourceID uint
+		Name       string
+	}
+	err := db.WithContext(ctx).Table("taggings").
+		Select("taggings.resource_id, tags.name").
+		Joins("JOIN tags ON tags.id = taggings.tag_id").
+		Where("taggings.resource_type = ? AND taggings.resource_id IN ?", resourceType, ids).
+		Order("tags.name ASC").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.ResourceID] = append(result[row.ResourceID], row.Name)
+	}
+	return result, nil
+}
+
+// Assign 给资源添加标签（标签不存在时创建），返回资源当前的标签
+func Assign(ctx context.Context, resourceType string, id uint, names []string, createdBy string) ([]string, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	names, err := Normalize(names)
+	if err != nil {
+		return nil, err
+	}
+	if err := exists(ctx, db, resourceType, id); err != nil {
+		return nil, err
+	}
+
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.Tagging{}).
+			Where("resource_type = ? AND resource_id = ?", resourceType, id).Count(&count).Error; err != nil {
+			return err
+		}
+		for _, name := range names {
+			tag := model.Tag{Name: name}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tag).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("name = ?", name).First(&tag).Error; err != nil {
+				return err
+			}
+			tagging := model.Tagging{TagID: tag.ID, ResourceType: resourceType, ResourceID: id, CreatedBy: createdBy}
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tagging)
+			if result.Error != nil {
+				return result.Error
+			}
+			count += result.RowsAffected
+		}
+		if count > MaxPerResource {
+			return fmt.Errorf("%w: 每个资源最多 %d 个标签", ErrInvalid, MaxPerResource)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return Of(ctx, resourceType, id)
+}
+
+// Unassign 移除资源的标签（标签本身保留），返回资源当前的标签
+func Unassign(ctx context.Context, resourceType string, id uint, names []string) ([]string, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	names, err := Normalize(names)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(names) > 0 {
+		err = db.WithContext(ctx).
+			Where("resource_type = ? AND resource_id = ?", resourceType, id).
+			Where("tag_id IN (?)", db.Model(&model.Tag{}).Select("id").Where("name IN ?", names)).
+			Delete(&model.Tagging{}).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return Of(ctx, resourceType, id)
+}
+
+// Clear 删除资源的所有标签关联（删除资源时调用）
+func Clear(ctx context.Context, resourceType string, id uint) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+	return db.WithContext(ctx).Where("resource_type = ? AND resource_id = ?", resourceType, id).Delete(&model.Tagging{}).Error
+}
+
+// Delete 删除标签及其所有关联
+func Delete(ctx context.Context, name string) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var tag model.Tag
+		if err := tx.Where("name = ?", strings.ToLower(name)).First(&tag).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if err := tx.Where("tag_id = ?", tag.ID).Delete(&model.Tagging{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&tag).Error
+	})
+}
+
+// Resources 获取带有标签的各类资源 ID
+func Resources(ctx context.Context, name string) (map[string][]uint, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+
+	var tag model.Tag
+	if err := db.WithContext(ctx).Where("name = ?", strings.ToLower(name)).First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	var taggings []model.Tagging
+	if err := db.WithContext(ctx).Where("tag_id = ?", tag.ID).Find(&taggings).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]uint, len(ResourceTypes))
+	for _, resourceType := range ResourceTypes {
+		result[resourceType] = []uint{}
+	}
+	for _, t := range taggings {
+		result[t.ResourceType] = append(result[t.ResourceType], t.ResourceID)
+	}
+	for _, ids := range result {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	}
+	return result, nil
+}
+
+// Filter 列表查询条件：只保留同时带有所有指定标签的资源（names 为逗号分隔的标签名，为空不过滤）
+func Filter(resourceType, names string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		var list []string
+		for _, name := range strings.Split(names, ",") {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				list = append(list, name)
+			}
+		}
+		if len(list) == 0 {
+			return db
+		}
+
+		sub := db.Session(&gorm.Session{NewDB: true}).Table("taggings").
+			Select("taggings.resource_id").
+			Joins("JOIN tags ON tags.id = taggings.tag_id").
+			Where("taggings.resource_type = ? AND tags.name IN ?", resourceType, list).
+			Group("taggings.resource_id").
+			Having("COUNT(DISTINCT tags.name) = ?", len(list))
+		return db.Where("id IN (?)", sub)
+	}
+}
+
+// exists 检查资源是否存在
+func exists(ctx context.Context, db *gorm.DB, resourceType string, id uint) error {
+	newModel, ok := resources[resourceType]
+	if !ok {
+		return ValidResource(resourceType)
+	}
+	var count int64
+	if err := db.WithContext(ctx).Model(newModel()).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrResourceNotFound
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package threatfeed
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/model"
+)
+
+// Bundle STIX 2.1 风格的 bundle，附带 TAXII 风格的分页字段
+type Bundle struct {
+	Type    string   `json:"type"`
+	ID      string   `json:"id"`
+	Objects []Object `json:"objects"`
+	// 是否还有下一页（为 true 时立即用 next 继续拉取）
+	More bool `json:"more"`
+	// 下一次拉取的游标（没有新事件时与请求的游标相同）
+	Next string `json:"next,omitempty"`
+}
+
+// Object STIX 对象（仅包含用到的属性，自定义属性以 x_ 开头）
+type Object struct {
+	Type         string    `json:"type"`
+	SpecVersion  string    `json:"spec_version"`
+	ID           string    `json:"id"`
+	Created      time.Time `json:"created"`
+	Modified     time.Time `json:"modified"`
+	CreatedByRef string    `json:"created_by_ref,omitempty"`
+	Revoked      bool      `json:"revoked,omitempty"`
+	Labels       []string  `json:"labels,omitempty"`
+	Name         string    `json:"name,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	// identity
+	IdentityClass string `json:"identity_class,omitempty"`
+	// indicator：被封禁的 IP
+	IndicatorTypes []string   `json:"indicator_types,omitempty"`
+	Pattern        string     `json:"pattern,omitempty"`
+	PatternType    string     `json:"pattern_type,omitempty"`
+	ValidFrom      *time.Time `json:"valid_from,omitempty"`
+	ValidUntil     *time.Time `json:"valid_until,omitempty"`
+	// observed-data：检测到的攻击请求
+	FirstObserved  *time.Time `json:"first_observed,omitempty"`
+	LastObserved   *time.Time `json:"last_observed,omitempty"`
+	NumberObserved int        `json:"number_observed,omitempty"`
+
+	XEventID    uint   `json:"x_event_id,omitempty"`
+	XSourceIP   string `json:"x_source_ip,omitempty"`
+	XHTTPMethod string `json:"x_http_method,omitempty"`
+	XHTTPPath   string `json:"x_http_path,omitempty"`
+	XUserAgent  string `json:"x_user_agent,omitempty"`
+	XRequestID  string `json:"x_request_id,omitempty"`
+	XAbuseScore int64  `json:"x_abuse_score,omitempty"`
+}
+
+// identity 数据来源
+func identity() Object {
+	// 固定的创建时间，保证各次拉取的 identity 对象完全一致
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return Object{
+		Type:          "identity",
+		SpecVersion:   "2.1",
+		ID:            stableID("identity", "source"),
+		Created:       created,
+		Modified:      created,
+		Name:          cfg.Source,
+		IdentityClass: "system",
+	}
+}
+
+// toObject 安全事件转换为 STIX 对象：攻击检测为 observed-data，封禁为 indicator（解除封禁为同一 indicator 的撤销版本）
+func toObject(e model.SecurityEvent, createdBy string) Object {
+	at := e.CreatedAt.UTC()
+	var labels []string
+	if e.Labels != "" {
+		labels = strings.Split(e.Labels, ",")
+	}
+
+	if e.Type == model.SecurityEventAttack {
+		return Object{
+			Type:           "observed-data",
+			SpecVersion:    "2.1",
+			ID:             stableID("observed-data", strconv.FormatUint(uint64(e.ID), 10)),
+			Created:        at,
+			Modified:       at,
+			CreatedByRef:   createdBy,
+			Labels:         labels,
+			FirstObserved:  &at,
+			LastObserved:   &at,
+			NumberObserved: 1,
+			XEventID:       e.ID,
+			XSourceIP:      e.IP,
+			XHTTPMethod:    e.Method,
+			XHTTPPath:      e.Path,
+			XUserAgent:     e.UserAgent,
+			XRequestID:     e.RequestID,
+		}
+	}
+
+	created := at
+	if e.ValidFrom != nil {
+		created = e.ValidFrom.UTC()
+	}
+	obj := Object{
+		Type:           "indicator",
+		SpecVersion:    "2.1",
+		ID:             stableID("indicator", "ban:"+strconv.FormatUint(uint64(e.BanID), 10)),
+		Created:        created,
+		Modified:       at,
+		CreatedByRef:   createdBy,
+		Revoked:        e.Type == model.SecurityEventBanLifted,
+		Labels:         labels,
+		Name:           "自动封禁 IP " + e.IP,
+		Description:    e.Detail,
+		IndicatorTypes: []string{"malicious-activity"},
+		Pattern:        ipPattern(e.IP),
+		PatternType:    "stix",
+		ValidFrom:      &created,
+		XEventID:       e.ID,
+		XSourceIP:      e.IP,
+		XAbuseScore:    e.Score,
+	}
+	if e.ValidUntil != nil {
+		until := e.ValidUntil.UTC()
+		obj.ValidUntil = &until
+	}
+	return obj
+}
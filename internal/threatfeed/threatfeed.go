@@ -0,0 +1,139 @@
+package threatfeed
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/secevents"
+	"new-openclaw/pkg/config"
+)
+
+// 每页数量
+const (
+	DefaultLimit = 100
+	MaxLimit     = 500
+)
+
+var (
+	// ErrUnavailable 安全事件存储未连接
+	ErrUnavailable = secevents.ErrUnavailable
+	// ErrInvalidCursor 游标格式错误
+	ErrInvalidCursor = errors.New("无效的游标")
+)
+
+// Types 订阅中的事件类型
+var Types = []string{model.SecurityEventAttack, model.SecurityEventBan, model.SecurityEventBanLifted}
+
+var cfg = config.ThreatFeedConfig{Source: "new-openclaw"}
+
+// Configure 设置订阅配置
+func Configure(c config.ThreatFeedConfig) {
+	cfg = c
+	if cfg.Source == "" {
+		cfg.Source = "new-openclaw"
+	}
+}
+
+// Allowed AppKey 是否可以拉取订阅
+func Allowed(appKey string) bool {
+	for _, k := range cfg.AppKeys {
+		if k == appKey {
+			return true
+		}
+	}
+	return false
+}
+
+// Query 订阅查询条件
+type Query struct {
+	// 上一页返回的 next，为空从头读取
+	Cursor string
+	Limit  int
+	// 事件类型，为空返回全部
+	Types []string
+	// 只返回该时间之后的事件（首次拉取时使用）
+	Since time.Time
+}
+
+// Feed 按游标读取安全事件，转换为 STIX 2.1 风格的 bundle
+func Feed(ctx context.Context, q Query) (*Bundle, error) {
+	var after uint64
+	if q.Cursor != "" {
+		n, err := strconv.ParseUint(q.Cursor, 10, 64)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		after = n
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	// 认证失败等其他事件不进入订阅
+	if len(q.Types) == 0 {
+		q.Types = Types
+	}
+
+	// 多取一条判断是否还有下一页
+	events, err := secevents.After(ctx, uint(after), secevents.Filter{Types: q.Types, From: q.Since}, q.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	more := len(events) > q.Limit
+	if more {
+		events = events[:q.Limit]
+	}
+	next := q.Cursor
+	if len(events) > 0 {
+		next = strconv.FormatUint(uint64(events[len(events)-1].ID), 10)
+	}
+
+	identity := identity()
+	objects := make([]Object, 0, len(events)+1)
+	objects = append(objects, identity)
+	for _, e := range events {
+		objects = append(objects, toObject(e, identity.ID))
+	}
+	return &Bundle{
+		Type:    "bundle",
+		ID:      "bundle--" + randomUUID(),
+		Objects: objects,
+		More:    more,
+		Next:    next,
+	}, nil
+}
+
+// ipPattern STIX 模式：IPv4 或 IPv6 地址
+func ipPattern(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		return fmt.Sprintf("[ipv6-addr:value = '%s']", ip)
+	}
+	return fmt.Sprintf("[ipv4-addr:value = '%s']", ip)
+}
+
+// stableID 按来源与键生成稳定的 STIX ID（同一封禁的各版本 ID 相同，消费方据此更新或撤销）
+func stableID(objectType, key string) string {
+	sum := sha256.Sum256([]byte(cfg.Source + "|" + objectType + "|" + key))
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%s--%x-%x-%x-%x-%x", objectType, sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// randomUUID 随机 UUID（bundle ID）
+func randomUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
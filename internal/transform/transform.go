@@ -0,0 +1,138 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+)
+
+// 转换方向
+const (
+	// Outbound 发出的 Webhook 投递前
+	Outbound = "outbound"
+	// Inbound 收到的回调解析后
+	Inbound = "inbound"
+)
+
+// cacheTTL 模板缓存时间（管理端修改后最长经过该时间在其他实例生效）
+const cacheTTL = time.Minute
+
+type cachedTemplate struct {
+	tmpl    *template.Template
+	expires time.Time
+}
+
+var (
+	cacheMu sync.RWMutex
+	cache   = make(map[string]cachedTemplate)
+)
+
+// funcs 模板可用函数
+var funcs = template.FuncMap{
+	// json 将值编码为 JSON（字符串自动加引号与转义）
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	// default 值为空时使用默认值：{{ default "x" .field }}
+	"default": func(def, v interface{}) interface{} {
+		if v == nil || v == "" {
+			return def
+		}
+		return v
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	// now 当前时间（RFC3339）
+	"now": func() string {
+		return time.Now().Format(time.RFC3339)
+	},
+	// unix 当前 Unix 时间戳
+	"unix": func() int64 {
+		return time.Now().Unix()
+	},
+}
+
+// ValidDirection 检查转换方向是否有效
+func ValidDirection(direction string) bool {
+	return direction == Outbound || direction == Inbound
+}
+
+// Compile 编译模板（保存前校验语法）
+func Compile(text string) (*template.Template, error) {
+	return template.New("transform").Funcs(funcs).Option("missingkey=zero").Parse(text)
+}
+
+// Render 使用模板转换 JSON 载荷，输出须为合法 JSON
+func Render(tmpl *template.Template, payload []byte) ([]byte, error) {
+	var data interface{}
+	if len(bytes.TrimSpace(payload)) > 0 {
+		if err := json.Unmarshal(payload, &data); err != nil {
+			return nil, fmt.Errorf("载荷不是合法的 JSON: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("执行转换模板失败: %v", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("转换结果不是合法的 JSON: %s", buf.String())
+	}
+	return buf.Bytes(), nil
+}
+
+// Apply 按合作方（AppKey）与方向转换载荷，未配置或已禁用时原样返回
+func Apply(appKey, direction string, payload []byte) ([]byte, error) {
+	tmpl := lookup(appKey, direction)
+	if tmpl == nil {
+		return payload, nil
+	}
+	return Render(tmpl, payload)
+}
+
+// Invalidate 清除模板缓存（管理端修改后调用）
+func Invalidate(appKey, direction string) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	delete(cache, cacheKey(appKey, direction))
+}
+
+// lookup 获取合作方的转换模板（优先读取缓存）
+func lookup(appKey, direction string) *template.Template {
+	key := cacheKey(appKey, direction)
+
+	cacheMu.RLock()
+	cached, ok := cache[key]
+	cacheMu.RUnlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.tmpl
+	}
+
+	var tmpl *template.Template
+	if db := database.GetMySQL(); db != nil {
+		var t model.PartnerTransform
+		err := db.Where("app_key = ? AND direction = ? AND enabled = ?", appKey, direction, true).First(&t).Error
+		if err == nil {
+			// 保存时已校验，编译失败说明数据被直接修改，按未配置处理
+			tmpl, _ = Compile(t.Template)
+		}
+	}
+
+	cacheMu.Lock()
+	cache[key] = cachedTemplate{tmpl: tmpl, expires: time.Now().Add(cacheTTL)}
+	cacheMu.Unlock()
+
+	return tmpl
+}
+
+func cacheKey(appKey, direction string) string {
+	return direction + ":" + appKey
+}
@@ -0,0 +1,28 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotExist 存储后端中没有该对象
+var ErrNotExist = errors.New("文件不存在")
+
+// Storage 文件存储后端（对象键由上传模块生成，只包含小写字母、数字、/ 与 .）
+type Storage interface {
+	// Name 后端名称，记录在 files 表中
+	Name() string
+	// Put 写入对象，sha256 为内容摘要（十六进制）
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType, sha256 string) error
+	// Open 读取对象
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象（对象不存在时不返回错误）
+	Delete(ctx context.Context, key string) error
+}
+
+// Presigner 可以签发直接下载链接的存储后端（下载接口校验签名后重定向到该链接，不经本服务转发文件内容）
+type Presigner interface {
+	PresignGet(key, disposition string, expiry time.Duration) (string, error)
+}
@@ -0,0 +1,331 @@
+// Package upload 文件上传：校验大小与类型（按文件内容识别）后写入存储后端（本地磁盘或 S3 兼容的对象存储），
+// files 表记录文件元数据。下载链接带 HMAC 签名与过期时间，无需登录即可访问，过期后需重新获取
+package upload
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+	"new-openclaw/pkg/config"
+	"new-openclaw/pkg/secrets"
+
+	"gorm.io/gorm"
+)
+
+// 存储后端名称（UPLOAD_STORAGE 的取值）
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+	// StorageOSS 阿里云 OSS，使用其 S3 兼容接口
+	StorageOSS = "oss"
+)
+
+// Route 上传接口（请求体上限按 UPLOAD_MAX_SIZE 单独设置）
+const Route = "POST /admin/upload"
+
+// MultipartOverhead 上传请求中文件之外的 multipart 边界与字段所占的余量
+const MultipartOverhead = 1 << 20
+
+var (
+	// ErrUnavailable 数据库未连接或存储未配置
+	ErrUnavailable = errors.New("文件存储不可用")
+	// ErrTooLarge 文件超过大小上限
+	ErrTooLarge = errors.New("文件过大")
+	// ErrType 文件类型不允许
+	ErrType = errors.New("不允许的文件类型")
+	// ErrInvalid 用途等参数不合法
+	ErrInvalid = errors.New("参数错误")
+	// ErrNotFound 文件记录不存在
+	ErrNotFound = errors.New("文件不存在")
+	// ErrSignature 下载链接签名不匹配
+	ErrSignature = errors.New("下载链接无效")
+	// ErrExpired 下载链接已过期
+	ErrExpired = errors.New("下载链接已过期")
+)
+
+// purposePattern 用途只允许小写字母、数字、_、-
+var purposePattern = regexp.MustCompile(`^[a-z0-9_-]{0,32}$`)
+
+var (
+	cfg = config.UploadConfig{
+		MaxSize:   10 * 1024 * 1024,
+		URLExpiry: time.Hour,
+	}
+	storage Storage
+	secret  []byte
+)
+
+// keyPurpose 由 JWT 密钥派生下载链接签名密钥时使用的用途标识
+const keyPurpose = "upload-url-v1"
+
+// Configure 按配置创建存储后端；未配置 UPLOAD_URL_SECRET 时下载链接使用由 masterSecret（JWT 密钥）派生的专用密钥签名
+func Configure(c config.UploadConfig, masterSecret string) error {
+	var (
+		s   Storage
+		err error
+	)
+	switch strings.ToLower(strings.TrimSpace(c.Storage)) {
+	case "", StorageLocal:
+		s, err = NewLocal(c.LocalDir)
+	case StorageS3, StorageOSS:
+		s, err = NewS3(S3Config{
+			Endpoint:  c.S3Endpoint,
+			Region:    c.S3Region,
+			Bucket:    c.S3Bucket,
+			AccessKey: c.S3AccessKey,
+			SecretKey: c.S3SecretKey,
+			PathStyle: c.S3PathStyle,
+			Client:    &http.Client{Timeout: c.S3Timeout},
+		})
+	default:
+		err = fmt.Errorf("未知的存储后端 %q（可选 local、s3、oss）", c.Storage)
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.URLExpiry <= 0 {
+		c.URLExpiry = time.Hour
+	}
+	for i, t := range c.AllowedTypes {
+		c.AllowedTypes[i] = strings.ToLower(strings.TrimSpace(t))
+	}
+	cfg, storage, secret = c, s, []byte(c.URLSecret)
+	if c.URLSecret == "" {
+		secret = secrets.DeriveKey(masterSecret, keyPurpose)
+	}
+	return nil
+}
+
+// Uploader 上传文件的管理员
+type Uploader struct {
+	ID       uint
+	Username string
+}
+
+// Save 校验并保存上传的文件，返回文件记录
+func Save(ctx context.Context, fh *multipart.FileHeader, purpose string, uploader Uploader) (*model.File, error) {
+	db := database.GetMySQL()
+	if db == nil || storage == nil {
+		return nil, ErrUnavailable
+	}
+	purpose = strings.TrimSpace(purpose)
+	if !purposePattern.MatchString(purpose) {
+		return nil, fmt.Errorf("%w: 用途只能包含小写字母、数字、_、-，最长 32 个字符", ErrInvalid)
+	}
+	if cfg.MaxSize > 0 && fh.Size > cfg.MaxSize {
+		return nil, fmt.Errorf("%w: 最大 %d 字节", ErrTooLarge, cfg.MaxSize)
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// 按内容识别类型（不信任客户端声明的 Content-Type 与扩展名）
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if !allowed(contentType) {
+		return nil, fmt.Errorf("%w: %s", ErrType, contentType)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	digest := sha256.New()
+	if _, err := io.Copy(digest, f); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	file := model.File{
+		Storage:      storage.Name(),
+		Key:          objectKey(time.Now(), fh.Filename, contentType),
+		Name:         displayName(fh.Filename),
+		Size:         fh.Size,
+		ContentType:  contentType,
+		SHA256:       hex.EncodeToString(digest.Sum(nil)),
+		Purpose:      purpose,
+		UploaderID:   uploader.ID,
+		UploaderName: uploader.Username,
+	}
+	if err := storage.Put(ctx, file.Key, f, file.Size, file.ContentType, file.SHA256); err != nil {
+		return nil, fmt.Errorf("写入存储失败: %w", err)
+	}
+	if err := db.WithContext(ctx).Create(&file).Error; err != nil {
+		// 记录写入失败时删除已上传的内容，避免留下无记录的对象
+		storage.Delete(context.Background(), file.Key)
+		return nil, err
+	}
+	return &file, nil
+}
+
+// allowed 类型是否在允许列表中（支持 image/* 通配）
+func allowed(contentType string) bool {
+	for _, t := range cfg.AllowedTypes {
+		if t == contentType || t == "*/*" {
+			return true
+		}
+		if strings.HasSuffix(t, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(t, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// objectKey 生成对象键：{年}/{月}/{日}/{随机串}{扩展名}，扩展名按识别出的类型确定
+func objectKey(now time.Time, filename, contentType string) string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+
+	ext := strings.ToLower(path.Ext(filename))
+	exts, _ := mime.ExtensionsByType(contentType)
+	matched := false
+	for _, e := range exts {
+		if e == ext {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		ext = ""
+		if len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	return now.Format("2006/01/02/") + hex.EncodeToString(buf) + ext
+}
+
+// displayName 上传时的文件名（去掉客户端附带的路径）
+func displayName(filename string) string {
+	filename = path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	if filename == "." || filename == "/" {
+		return ""
+	}
+	if len(filename) > 255 {
+		filename = filename[len(filename)-255:]
+	}
+	return filename
+}
+
+// Get 获取文件记录
+func Get(ctx context.Context, id string) (*model.File, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	fileID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	var f model.File
+	err = db.WithContext(ctx).First(&f, fileID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Delete 删除存储中的内容与文件记录
+func Delete(ctx context.Context, f *model.File) error {
+	db := database.GetMySQL()
+	if db == nil || storage == nil {
+		return ErrUnavailable
+	}
+	if f.Storage != storage.Name() {
+		return fmt.Errorf("%w: 文件保存在 %s，当前存储后端为 %s", ErrUnavailable, f.Storage, storage.Name())
+	}
+	if err := storage.Delete(ctx, f.Key); err != nil {
+		return err
+	}
+	return db.WithContext(ctx).Delete(f).Error
+}
+
+// URL 签发下载链接（相对路径）及其过期时间
+func URL(f *model.File, now time.Time) (string, time.Time) {
+	expires := now.Add(cfg.URLExpiry).Truncate(time.Second)
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", exp)
+	query.Set("signature", sign(f, exp))
+	return fmt.Sprintf("/admin/files/%d/download?%s", f.ID, query.Encode()), expires
+}
+
+// sign 签名覆盖文件 ID、对象键与过期时间（删除后重新上传的同 ID 文件不会匹配旧链接）
+func sign(f *model.File, exp string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatUint(uint64(f.ID), 10) + "\n" + f.Key + "\n" + exp))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验下载链接的签名与过期时间，返回链接的过期时间
+func Verify(f *model.File, exp, signature string, now time.Time) (time.Time, error) {
+	if !hmac.Equal([]byte(signature), []byte(sign(f, exp))) {
+		return time.Time{}, ErrSignature
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrSignature
+	}
+	expires := time.Unix(unix, 0)
+	if now.After(expires) {
+		return time.Time{}, ErrExpired
+	}
+	return expires, nil
+}
+
+// Open 读取文件内容；存储后端支持预签名时返回直接下载链接（有效期与剩余的签名有效期一致）
+func Open(ctx context.Context, f *model.File, expires time.Time) (io.ReadCloser, string, error) {
+	if storage == nil {
+		return nil, "", ErrUnavailable
+	}
+	if f.Storage != storage.Name() {
+		return nil, "", fmt.Errorf("%w: 文件保存在 %s，当前存储后端为 %s", ErrUnavailable, f.Storage, storage.Name())
+	}
+	if p, ok := storage.(Presigner); ok {
+		link, err := p.PresignGet(f.Key, Disposition(f), time.Until(expires))
+		return nil, link, err
+	}
+	r, err := storage.Open(ctx, f.Key)
+	return r, "", err
+}
+
+// Disposition 下载时的 Content-Disposition：图片直接显示，其他类型作为附件下载
+func Disposition(f *model.File) string {
+	kind := "attachment"
+	if strings.HasPrefix(f.ContentType, "image/") {
+		kind = "inline"
+	}
+	if f.Name == "" {
+		return kind
+	}
+	return mime.FormatMediaType(kind, map[string]string{"filename": f.Name})
+}
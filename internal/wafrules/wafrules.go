@@ -0,0 +1,330 @@
+// Package wafrules 维护 WAF 规则：合并内置规则、规则文件与数据库中的规则，
+// 变更后热更新到生效的 WAF，并通过 Redis 广播让其他实例重新加载
+package wafrules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/jobs"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/pkg/config"
+
+	"gorm.io/gorm"
+)
+
+// channel 规则变更广播频道（各实例收到后重新加载）
+const channel = "openclaw:waf_rules:reload"
+
+var (
+	// ErrUnavailable 数据库未连接
+	ErrUnavailable = errors.New("数据库未连接")
+	// ErrNotFound 规则不存在
+	ErrNotFound = errors.New("WAF 规则不存在")
+	// ErrInvalid 规则无效（正则表达式、检查位置或动作错误，或规则名重复）
+	ErrInvalid = errors.New("无效的 WAF 规则")
+)
+
+var (
+	cfg config.WAFConfig
+	waf *middleware.WAF
+	mu  sync.RWMutex
+
+	cancel context.CancelFunc
+)
+
+// Configure 设置规则来源
+func Configure(c config.WAFConfig) {
+	mu.Lock()
+	defer mu.Unlock()
+	cfg = c
+}
+
+// Bind 绑定生效的 WAF
+func Bind(w *middleware.WAF) {
+	mu.Lock()
+	defer mu.Unlock()
+	waf = w
+}
+
+// Builtin 启用时返回内置规则（动作为 WAF_BUILTIN_ACTION）
+func Builtin() []middleware.WAFRule {
+	mu.RLock()
+	defer mu.RUnlock()
+	if !cfg.BuiltinRules {
+		return nil
+	}
+	return middleware.BuiltinWAFRules(cfg.BuiltinAction)
+}
+
+// ToRule 数据库中的规则转换为 WAF 规则
+func ToRule(r model.WAFRule) middleware.WAFRule {
+	var targets []string
+	for _, target := range strings.Split(r.Targets, ",") {
+		if target = strings.TrimSpace(target); target != "" {
+			targets = append(targets, target)
+		}
+	}
+	return middleware.WAFRule{
+		Name:    r.Name,
+		Pattern: r.Pattern,
+		Targets: targets,
+		Action:  r.Action,
+		Reason:  r.Reason,
+		Source:  middleware.WAFSourceDB,
+	}
+}
+
+// Validate 检查规则，并规范化检查位置（去除空白）
+func Validate(r *model.WAFRule) error {
+	rule := ToRule(*r)
+	if err := middleware.ValidateWAFRule(rule); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	r.Targets = strings.Join(rule.Targets, ",")
+	return nil
+}
+
+// List 获取数据库中的规则
+func List(ctx context.Context) ([]model.WAFRule, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	rules := []model.WAFRule{}
+	if err := db.WithContext(ctx).Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Get 获取规则
+func Get(ctx context.Context, id uint) (*model.WAFRule, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	var rule model.WAFRule
+	err := db.WithContext(ctx).First(&rule, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// Create 添加规则并通知所有实例重新加载
+func Create(ctx context.Context, rule *model.WAFRule) error {
+	return save(ctx, rule)
+}
+
+// Update 保存修改后的规则并通知所有实例重新加载
+func Update(ctx context.Context, rule *model.WAFRule) error {
+	return save(ctx, rule)
+}
+
+func save(ctx context.Context, rule *model.WAFRule) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+	if err := Validate(rule); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := db.WithContext(ctx).Model(&model.WAFRule{}).Where("name = ? AND id <> ?", rule.Name, rule.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: 规则名 %s 已存在", ErrInvalid, rule.Name)
+	}
+	if err := db.WithContext(ctx).Save(rule).Error; err != nil {
+		return err
+	}
+
+	changed(ctx)
+	return nil
+}
+
+// Delete 删除规则并通知所有实例重新加载
+func Delete(ctx context.Context, id uint) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return ErrUnavailable
+	}
+	result := db.WithContext(ctx).Delete(&model.WAFRule{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	changed(ctx)
+	return nil
+}
+
+// Active 当前生效的规则（内置、规则文件与数据库中的规则）
+func Active() []middleware.WAFRule {
+	mu.RLock()
+	w := waf
+	mu.RUnlock()
+	if w == nil {
+		return []middleware.WAFRule{}
+	}
+	return w.Rules()
+}
+
+// Load 重新读取规则文件与数据库中启用的规则，与内置规则合并后替换生效的规则。
+// 无效的规则跳过（记录日志）；规则文件或数据库读取失败时保持当前规则，数据库未连接时只使用内置与文件中的规则
+func Load(ctx context.Context) error {
+	mu.RLock()
+	w, c := waf, cfg
+	mu.RUnlock()
+	if w == nil {
+		return nil
+	}
+
+	rules := Builtin()
+	if c.RulesFile != "" {
+		fileRules, err := readFile(c.RulesFile)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, fileRules...)
+	}
+	if db := database.GetMySQL(); db != nil {
+		var stored []model.WAFRule
+		if err := db.WithContext(ctx).Where("enabled = ?", true).Order("id ASC").Find(&stored).Error; err != nil {
+			return err
+		}
+		for _, r := range stored {
+			rule := ToRule(r)
+			if err := middleware.ValidateWAFRule(rule); err != nil {
+				log.Printf("⚠️  跳过无效的 WAF 规则 (id=%d): %v", r.ID, err)
+				continue
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return w.Update(rules)
+}
+
+// readFile 读取规则文件（JSON 数组），跳过无效的规则
+func readFile(path string) ([]middleware.WAFRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 WAF 规则文件失败: %w", err)
+	}
+	var rules []middleware.WAFRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("解析 WAF 规则文件失败: %w", err)
+	}
+
+	valid := rules[:0]
+	for _, rule := range rules {
+		rule.Source = middleware.WAFSourceFile
+		if err := middleware.ValidateWAFRule(rule); err != nil {
+			log.Printf("⚠️  跳过 WAF 规则文件中的无效规则: %v", err)
+			continue
+		}
+		valid = append(valid, rule)
+	}
+	return valid, nil
+}
+
+// changed 规则变更后立即在本实例生效，并广播给其他实例
+func changed(ctx context.Context) {
+	if err := Load(ctx); err != nil {
+		log.Printf("重新加载 WAF 规则失败: %v", err)
+	}
+	publish(ctx)
+}
+
+// Reload 立即重新加载本实例的规则（如修改规则文件后），并通知其他实例
+func Reload(ctx context.Context) error {
+	if err := Load(ctx); err != nil {
+		return err
+	}
+	publish(ctx)
+	return nil
+}
+
+func publish(ctx context.Context) {
+	if rdb := database.GetRedis(); rdb != nil {
+		if err := rdb.Publish(ctx, channel, time.Now().Format(time.RFC3339Nano)).Err(); err != nil {
+			log.Printf("广播 WAF 规则变更失败（其他实例将在定时任务中重新加载）: %v", err)
+		}
+	}
+}
+
+// Start 启动时加载规则，并订阅变更广播（未连接 Redis 时只依赖定时重新加载）
+func Start() {
+	if err := Load(context.Background()); err != nil {
+		log.Printf("⚠️  加载 WAF 规则失败，仅使用内置规则: %v", err)
+	}
+
+	rdb := database.GetRedis()
+	if rdb == nil {
+		return
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	mu.Lock()
+	cancel = stop
+	mu.Unlock()
+
+	sub := rdb.Subscribe(ctx, channel)
+	messages := sub.Channel()
+	go func() {
+		defer sub.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-messages:
+				if !ok {
+					return
+				}
+				if err := Load(ctx); err != nil {
+					log.Printf("重新加载 WAF 规则失败: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop 停止订阅变更广播
+func Stop() {
+	mu.Lock()
+	defer mu.Unlock()
+	if cancel != nil {
+		cancel()
+		cancel = nil
+	}
+}
+
+// RegisterReloadJob 注册定时重新加载规则的任务（每个实例都执行，补偿遗漏的广播并读取规则文件的修改）
+func RegisterReloadJob(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	jobs.Register(jobs.Job{
+		Name:        "waf_rules_reload",
+		Description: "重新加载 WAF 规则（规则文件与数据库）",
+		Interval:    interval,
+		Run:         Load,
+	})
+}
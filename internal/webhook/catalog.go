@@ -0,0 +1,210 @@
+package webhook
+
+import (
+	"fmt"
+	"time"
+)
+
+// EventDefinition 事件目录中的一种事件类型及其各版本的载荷结构
+type EventDefinition struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	// 当前（最新）版本
+	LatestVersion int `json:"latest_version"`
+	// 按版本号升序
+	Versions []EventVersion `json:"versions"`
+}
+
+// EventVersion 事件载荷（data 字段）的一个版本
+type EventVersion struct {
+	Version int     `json:"version"`
+	Schema  *Schema `json:"schema"`
+	// 弃用时间（弃用后仍向固定在该版本的目标投递，直到停止投递时间）
+	DeprecatedAt *time.Time `json:"deprecated_at,omitempty"`
+	// 停止投递时间，之后固定在该版本的目标改收仍在投递的下一个版本
+	Sunset *time.Time `json:"sunset,omitempty"`
+	// 由最新版本的载荷转换为该版本（最新版本为空）
+	downgrade func(latest interface{}) (interface{}, error)
+}
+
+// Deprecated 是否已弃用
+func (v EventVersion) Deprecated(now time.Time) bool {
+	return v.DeprecatedAt != nil && !now.Before(*v.DeprecatedAt)
+}
+
+// Retired 是否已停止投递
+func (v EventVersion) Retired(now time.Time) bool {
+	return v.Sunset != nil && !now.Before(*v.Sunset)
+}
+
+// Envelope 投递请求体（事件信封）的结构，data 按事件类型与版本见事件目录
+var Envelope = &Schema{
+	Type:     "object",
+	Required: []string{"id", "type", "schema_version", "created_at", "data"},
+	Properties: map[string]*Schema{
+		"id":             {Type: "string", Description: "事件 ID，重试、重放及同一事件的不同版本之间不变"},
+		"type":           {Type: "string", Description: "事件类型"},
+		"schema_version": {Type: "integer", Description: "data 的载荷版本"},
+		"created_at":     {Type: "string", Format: "date-time"},
+		"data":           {Description: "事件载荷，结构见对应版本的 schema"},
+	},
+}
+
+// anomalySchema admin.anomaly_detected 中的单条异常
+var anomalySchema = &Schema{
+	Type:     "object",
+	Required: []string{"type", "admin_id", "username", "count", "start", "end", "detail"},
+	Properties: map[string]*Schema{
+		"type":     {Type: "string", Enum: []string{"bulk_delete", "off_hours", "permission_spike"}},
+		"admin_id": {Type: "integer"},
+		"username": {Type: "string"},
+		"count":    {Type: "integer", Description: "时间窗口内的操作次数"},
+		"start":    {Type: "string", Format: "date-time"},
+		"end":      {Type: "string", Format: "date-time"},
+		"detail":   {Type: "string"},
+	},
+}
+
+// catalog 事件目录：新增或修改载荷字段时增加版本，旧版本标记弃用并给出停止投递时间，
+// 弃用期间固定在旧版本的合作方继续收到旧结构
+var catalog = []EventDefinition{
+	{
+		Type:        EventPing,
+		Description: "管理端发起的测试投递",
+		Versions: []EventVersion{
+			{
+				Version: 1,
+				Schema: &Schema{
+					Type:     "object",
+					Required: []string{"endpoint_id"},
+					Properties: map[string]*Schema{
+						"endpoint_id": {Type: "integer"},
+					},
+				},
+			},
+		},
+	},
+	{
+		Type:        EventDependencyStatusChanged,
+		Description: "依赖（MySQL、Redis、MongoDB 等）健康状态变化",
+		Versions: []EventVersion{
+			{
+				Version: 1,
+				Schema: &Schema{
+					Type:     "object",
+					Required: []string{"component", "from", "to", "instance", "at", "flaps"},
+					Properties: map[string]*Schema{
+						"component": {Type: "string"},
+						"from":      {Type: "string"},
+						"to":        {Type: "string"},
+						"instance":  {Type: "string", Description: "检测到变化的实例"},
+						"at":        {Type: "string", Format: "date-time"},
+						"flaps":     {Type: "integer", Description: "最近一小时内该组件的状态变化次数（含本次）"},
+					},
+				},
+			},
+		},
+	},
+	{
+		Type:        EventAdminAnomaly,
+		Description: "检测到管理员异常行为（批量删除、非工作时间操作、权限变更激增）",
+		Versions: []EventVersion{
+			{
+				Version:      1,
+				Schema:       &Schema{Type: "array", Items: anomalySchema},
+				DeprecatedAt: date(2026, 10, 15),
+				Sunset:       date(2027, 4, 15),
+				downgrade: func(latest interface{}) (interface{}, error) {
+					obj, ok := latest.(map[string]interface{})
+					if !ok {
+						return nil, fmt.Errorf("载荷应为对象")
+					}
+					return obj["anomalies"], nil
+				},
+			},
+			{
+				// v2 改为对象，便于之后追加字段而不破坏结构
+				Version: 2,
+				Schema: &Schema{
+					Type:     "object",
+					Required: []string{"anomalies", "count"},
+					Properties: map[string]*Schema{
+						"anomalies": {Type: "array", Items: anomalySchema},
+						"count":     {Type: "integer"},
+					},
+				},
+			},
+		},
+	},
+}
+
+func init() {
+	for i := range catalog {
+		catalog[i].LatestVersion = catalog[i].Versions[len(catalog[i].Versions)-1].Version
+	}
+}
+
+// Catalog 事件目录
+func Catalog() []EventDefinition {
+	return catalog
+}
+
+// Lookup 查找事件类型
+func Lookup(eventType string) (*EventDefinition, bool) {
+	for i := range catalog {
+		if catalog[i].Type == eventType {
+			return &catalog[i], true
+		}
+	}
+	return nil, false
+}
+
+// LatestVersion 事件目录中各事件类型的最高版本（订阅全部事件的目标默认固定在此版本）
+func LatestVersion() int {
+	latest := 0
+	for _, def := range catalog {
+		if def.LatestVersion > latest {
+			latest = def.LatestVersion
+		}
+	}
+	return latest
+}
+
+// Version 查找版本
+func (d *EventDefinition) Version(version int) (EventVersion, bool) {
+	for _, v := range d.Versions {
+		if v.Version == version {
+			return v, true
+		}
+	}
+	return EventVersion{}, false
+}
+
+// Resolve 固定在 pinned 版本的目标实际收到的版本：取不超过 pinned 的最高版本，
+// 该版本已停止投递时改用之后仍在投递的最旧版本（最新版本不会停止投递）
+func (d *EventDefinition) Resolve(pinned int, now time.Time) EventVersion {
+	chosen := 0
+	for i, v := range d.Versions {
+		if v.Version <= pinned {
+			chosen = i
+		}
+	}
+	for chosen < len(d.Versions)-1 && d.Versions[chosen].Retired(now) {
+		chosen++
+	}
+	return d.Versions[chosen]
+}
+
+// eventTypes 事件目录中的全部事件类型
+func eventTypes() []string {
+	types := make([]string, len(catalog))
+	for i, def := range catalog {
+		types[i] = def.Type
+	}
+	return types
+}
+
+func date(year int, month time.Month, day int) *time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
@@ -0,0 +1,195 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/model"
+)
+
+// 投递请求头：
+//
+//	X-Webhook-Id         事件 ID（同一事件重试、重放时不变，接收方据此去重）
+//	X-Webhook-Event      事件类型
+//	X-Webhook-Delivery   投递 ID
+//	X-Webhook-Timestamp  发送时的 Unix 时间戳
+//	X-Webhook-Signature  sha256=hex(HMAC-SHA256(secret, 时间戳 + "." + 请求体))
+//	X-Webhook-Schema-Version  载荷版本；版本已弃用时另附 Deprecation（RFC 9745）与 Sunset（RFC 8594）
+const signaturePrefix = "sha256="
+
+// responseLimit 记录的响应体长度
+const responseLimit = 1024
+
+// Sign 计算载荷签名（接收方以同样方式计算并用常量时间比较，同时校验时间戳防重放）
+func Sign(secret, timestamp string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return signaturePrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// deliver 抢占并投递一条记录，记录尝试结果并安排下次重试
+func deliver(id uint) {
+	db := database.GetMySQL()
+	if db == nil {
+		return
+	}
+
+	// 抢占：将下次尝试时间推后，避免即时投递、重试任务及其他实例重复投递；
+	// 投递中进程退出时租约到期后由重试任务补投
+	now := time.Now()
+	lease := now.Add(2*cfg.Timeout + time.Second)
+	claim := db.Model(&model.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", id, model.WebhookDeliveryPending, now).
+		Update("next_attempt_at", lease)
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return
+	}
+
+	var d model.WebhookDelivery
+	if err := db.First(&d, id).Error; err != nil {
+		return
+	}
+
+	var ep model.WebhookEndpoint
+	if err := db.First(&ep, d.EndpointID).Error; err != nil {
+		finish(&d, model.WebhookDeliveryFailed, "投递目标不存在")
+		return
+	}
+	if !ep.Enabled {
+		finish(&d, model.WebhookDeliveryFailed, "投递目标已禁用")
+		return
+	}
+
+	attempt := model.WebhookAttempt{
+		DeliveryID: d.ID,
+		Round:      d.Replays,
+		Attempt:    d.Attempts + 1,
+	}
+	start := time.Now()
+	attempt.StatusCode, attempt.Response, attempt.Error = send(ep, d)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	db.Create(&attempt)
+
+	d.Attempts = attempt.Attempt
+	d.LastAttemptAt = &start
+	d.LastStatus = attempt.StatusCode
+	d.LastError = attempt.Error
+
+	switch {
+	case attempt.Error == "":
+		deliveriesTotal.Inc(d.EventType, "succeeded")
+		finish(&d, model.WebhookDeliverySucceeded, "")
+	case d.Attempts >= cfg.MaxAttempts:
+		deliveriesTotal.Inc(d.EventType, "failed")
+		log.Printf("Webhook 投递失败（已尝试 %d 次）: delivery=%d url=%s err=%s", d.Attempts, d.ID, ep.URL, attempt.Error)
+		finish(&d, model.WebhookDeliveryFailed, attempt.Error)
+	default:
+		deliveriesTotal.Inc(d.EventType, "retry")
+		next := time.Now().Add(backoff(d.Attempts))
+		d.NextAttemptAt = &next
+		db.Save(&d)
+	}
+}
+
+// send 发送请求，返回状态码、响应体摘要及错误（非 2xx 视为失败）
+func send(ep model.WebhookEndpoint, d model.WebhookDelivery) (int, string, string) {
+	body := []byte(d.Payload)
+	req, err := http.NewRequest(http.MethodPost, ep.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", truncate(err.Error(), 1024)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "openclaw-webhook")
+	req.Header.Set("X-Webhook-Id", d.EventID)
+	req.Header.Set("X-Webhook-Event", d.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(d.ID), 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", Sign(ep.Secret.String(), timestamp, body))
+	setSchemaHeaders(req.Header, d)
+
+	client := &http.Client{
+		Timeout: cfg.Timeout,
+		// 不跟随重定向（签名只对注册的 URL 有效）
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", truncate(err.Error(), 1024)
+	}
+	defer resp.Body.Close()
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, responseLimit))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(snippet), "响应状态码 " + strconv.Itoa(resp.StatusCode)
+	}
+	return resp.StatusCode, string(snippet), ""
+}
+
+// setSchemaHeaders 设置载荷版本请求头，提醒仍在使用弃用版本的接收方迁移
+func setSchemaHeaders(h http.Header, d model.WebhookDelivery) {
+	if d.SchemaVersion == 0 {
+		return
+	}
+	h.Set("X-Webhook-Schema-Version", strconv.Itoa(d.SchemaVersion))
+	def, ok := Lookup(d.EventType)
+	if !ok {
+		return
+	}
+	v, ok := def.Version(d.SchemaVersion)
+	if !ok || !v.Deprecated(time.Now()) {
+		return
+	}
+	h.Set("Deprecation", "@"+strconv.FormatInt(v.DeprecatedAt.Unix(), 10))
+	if v.Sunset != nil {
+		h.Set("Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+	}
+}
+
+// finish 结束投递（成功或失败）
+func finish(d *model.WebhookDelivery, status, lastError string) {
+	now := time.Now()
+	d.Status = status
+	d.NextAttemptAt = nil
+	d.CompletedAt = &now
+	if lastError != "" {
+		d.LastError = truncate(lastError, 1024)
+	}
+	database.GetMySQL().Save(d)
+}
+
+// backoff 第 n 次尝试失败后的重试间隔：InitialBackoff × 2^(n-1)，不超过 MaxBackoff，附加至多 10% 的随机抖动
+func backoff(n int) time.Duration {
+	d := cfg.InitialBackoff
+	for i := 1; i < n && d < cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > cfg.MaxBackoff {
+		d = cfg.MaxBackoff
+	}
+	if jitter := int64(d / 10); jitter > 0 {
+		d += time.Duration(rand.Int63n(jitter))
+	}
+	return d
+}
+
+// ValidURL 检查投递地址（仅支持 http/https）
+func ValidURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "" && len(raw) <= 1024
+}
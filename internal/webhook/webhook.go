@@ -0,0 +1,410 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/jobs"
+	"new-openclaw/internal/metrics"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/transform"
+	"new-openclaw/pkg/config"
+
+	"gorm.io/gorm"
+)
+
+// 事件类型
+const (
+	// EventAll 订阅全部事件
+	EventAll = "*"
+	// EventPing 管理端测试投递
+	EventPing = "webhook.ping"
+	// EventDependencyStatusChanged 依赖健康状态变化
+	EventDependencyStatusChanged = "dependency.status_changed"
+	// EventAdminAnomaly 检测到管理员异常行为
+	EventAdminAnomaly = "admin.anomaly_detected"
+)
+
+// EventTypes 可订阅的事件类型（与事件目录一致）
+var EventTypes = eventTypes()
+
+var (
+	// ErrNotFound 投递记录不存在
+	ErrNotFound = errors.New("投递记录不存在")
+	// ErrNotFailed 只能重放失败的投递
+	ErrNotFailed = errors.New("只能重放失败的投递")
+)
+
+// queueSize 即时投递队列长度（队列满时由重试任务补投）
+const queueSize = 1000
+
+var (
+	deliveriesTotal = metrics.NewCounterVec(
+		"webhook_deliveries_total", "Webhook 投递尝试次数", "event_type", "result")
+	schemaViolationsTotal = metrics.NewCounterVec(
+		"webhook_schema_violations_total", "载荷不符合事件目录中的结构而未投递的次数", "event_type", "version")
+)
+
+// Event 投递的事件（请求体）
+type Event struct {
+	ID            string      `json:"id"`
+	Type          string      `json:"type"`
+	SchemaVersion int         `json:"schema_version"`
+	CreatedAt     time.Time   `json:"created_at"`
+	Data          interface{} `json:"data"`
+}
+
+var (
+	cfg = config.WebhookConfig{
+		MaxAttempts:    8,
+		InitialBackoff: 30 * time.Second,
+		MaxBackoff:     time.Hour,
+		Timeout:        10 * time.Second,
+		Workers:        4,
+		RetryInterval:  30 * time.Second,
+	}
+
+	queue   chan uint
+	stopped chan struct{}
+	wg      sync.WaitGroup
+)
+
+// Configure 设置投递配置
+func Configure(c config.WebhookConfig) {
+	cfg = c
+}
+
+// ValidEventType 检查订阅的事件类型是否有效
+func ValidEventType(eventType string) bool {
+	if eventType == EventAll {
+		return true
+	}
+	_, ok := Lookup(eventType)
+	return ok
+}
+
+// DefaultSchemaVersion 新建目标未指定载荷版本时固定的版本（事件类型的最新版本）
+func DefaultSchemaVersion(eventType string) int {
+	if def, ok := Lookup(eventType); ok {
+		return def.LatestVersion
+	}
+	return LatestVersion()
+}
+
+// ValidSchemaVersion 检查目标可以固定的载荷版本：须为该事件类型仍在投递的版本，订阅全部事件时不超过目录中的最高版本
+func ValidSchemaVersion(eventType string, version int) bool {
+	if version < 1 {
+		return false
+	}
+	def, ok := Lookup(eventType)
+	if !ok {
+		return eventType == EventAll && version <= LatestVersion()
+	}
+	v, ok := def.Version(version)
+	return ok && !v.Retired(time.Now())
+}
+
+// Start 启动即时投递的工作协程
+func Start() {
+	if queue != nil || cfg.Workers <= 0 {
+		return
+	}
+	queue = make(chan uint, queueSize)
+	stopped = make(chan struct{})
+	for i := 0; i < cfg.Workers; i++ {
+		wg.Add(1)
+		go work()
+	}
+}
+
+// Stop 停止工作协程并等待投递中的请求结束（队列中未投递的记录由重试任务补投）
+func Stop() {
+	if stopped == nil {
+		return
+	}
+	close(stopped)
+	wg.Wait()
+	queue, stopped = nil, nil
+}
+
+// RegisterRetryJob 注册重试任务：扫描到期的待投递记录并投递（多副本部署时仅由 Leader 扫描）
+func RegisterRetryJob() {
+	if cfg.RetryInterval <= 0 {
+		return
+	}
+
+	jobs.Register(jobs.Job{
+		Name:        "webhook_retries",
+		Description: "重试到期的 Webhook 投递",
+		Interval:    cfg.RetryInterval,
+		LeaderOnly:  true,
+		Run:         retryDue,
+	})
+}
+
+// Emit 向订阅了该事件类型的所有启用目标投递事件（异步，失败按指数退避重试），返回事件 ID
+func Emit(eventType string, data interface{}) (string, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return "", fmt.Errorf("数据库未连接")
+	}
+
+	var endpoints []model.WebhookEndpoint
+	if err := db.Where("enabled = ? AND event_type IN ?", true, []string{eventType, EventAll}).
+		Find(&endpoints).Error; err != nil {
+		return "", err
+	}
+	return emit(db, endpoints, eventType, data)
+}
+
+// Ping 向指定目标投递测试事件
+func Ping(endpoint model.WebhookEndpoint) (string, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return "", fmt.Errorf("数据库未连接")
+	}
+	return emit(db, []model.WebhookEndpoint{endpoint}, EventPing, map[string]interface{}{
+		"endpoint_id": endpoint.ID,
+	})
+}
+
+// emit 按目标固定的载荷版本生成事件并校验结构，为每个目标创建投递记录并加入即时投递队列。
+// 不符合结构的版本不投递（返回错误，其余版本照常投递）
+func emit(db *gorm.DB, endpoints []model.WebhookEndpoint, eventType string, data interface{}) (string, error) {
+	def, ok := Lookup(eventType)
+	if !ok {
+		return "", fmt.Errorf("事件类型 %s 未登记到事件目录", eventType)
+	}
+	latest, err := toJSONValue(data)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	b := payloadBuilder{
+		event:    Event{ID: newEventID(), Type: eventType, CreatedAt: now},
+		latest:   latest,
+		payloads: make(map[int]versionPayload),
+	}
+	// 没有订阅方时也校验最新版本，尽早发现载荷与目录不一致
+	if _, err := b.build(def.Versions[len(def.Versions)-1]); err != nil {
+		return "", err
+	}
+
+	var violations []string
+	deliveries := make([]model.WebhookDelivery, 0, len(endpoints))
+	for _, ep := range endpoints {
+		version := def.Resolve(ep.SchemaVersion, now)
+		payload, err := b.build(version)
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("目标 %d: %v", ep.ID, err))
+			continue
+		}
+		d := model.WebhookDelivery{
+			EndpointID:    ep.ID,
+			EventID:       b.event.ID,
+			EventType:     eventType,
+			SchemaVersion: version.Version,
+			Payload:       string(payload),
+			Status:        model.WebhookDeliveryPending,
+			NextAttemptAt: &now,
+		}
+		// 合作方的 outbound 转换模板（转换失败时直接标记为失败，修正模板后可重放）
+		if ep.AppKey != "" {
+			body, err := transform.Apply(ep.AppKey, transform.Outbound, payload)
+			if err != nil {
+				d.Status = model.WebhookDeliveryFailed
+				d.LastError = truncate(err.Error(), 1024)
+				d.NextAttemptAt = nil
+				d.CompletedAt = &now
+			} else {
+				d.Payload = string(body)
+			}
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if len(deliveries) > 0 {
+		if err := db.Create(&deliveries).Error; err != nil {
+			return "", fmt.Errorf("创建投递记录失败: %v", err)
+		}
+	}
+	for _, d := range deliveries {
+		if d.Status == model.WebhookDeliveryPending {
+			enqueue(d.ID)
+		}
+	}
+	if len(violations) > 0 {
+		return b.event.ID, fmt.Errorf("%d 个目标的载荷不符合事件目录，未投递: %s", len(violations), strings.Join(violations, "; "))
+	}
+	return b.event.ID, nil
+}
+
+// payloadBuilder 同一事件按版本生成的请求体（各目标共用）
+type payloadBuilder struct {
+	event    Event
+	latest   interface{}
+	payloads map[int]versionPayload
+}
+
+type versionPayload struct {
+	body []byte
+	err  error
+}
+
+// build 生成指定版本的请求体：由最新版本的载荷转换并按该版本的结构校验
+func (b *payloadBuilder) build(v EventVersion) ([]byte, error) {
+	if p, ok := b.payloads[v.Version]; ok {
+		return p.body, p.err
+	}
+
+	data, err := b.latest, error(nil)
+	if v.downgrade != nil {
+		data, err = v.downgrade(b.latest)
+	}
+	if err == nil {
+		err = v.Schema.Validate(data)
+	}
+	var body []byte
+	if err != nil {
+		schemaViolationsTotal.Inc(b.event.Type, strconv.Itoa(v.Version))
+		err = fmt.Errorf("%s v%d: %w", b.event.Type, v.Version, err)
+		log.Printf("⚠️  Webhook 载荷不符合事件目录，未投递: %v", err)
+	} else {
+		event := b.event
+		event.SchemaVersion, event.Data = v.Version, data
+		body, err = json.Marshal(event)
+	}
+	b.payloads[v.Version] = versionPayload{body: body, err: err}
+	return body, err
+}
+
+// Replay 重放失败的投递：重置为待投递并从第 1 次尝试开始
+func Replay(ctx context.Context, id uint) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return fmt.Errorf("数据库未连接")
+	}
+
+	var d model.WebhookDelivery
+	err := db.WithContext(ctx).First(&d, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if d.Status != model.WebhookDeliveryFailed {
+		return ErrNotFailed
+	}
+
+	if err := db.WithContext(ctx).Model(&model.WebhookDelivery{}).
+		Where("id = ? AND status = ?", id, model.WebhookDeliveryFailed).
+		Updates(replayUpdates()).Error; err != nil {
+		return err
+	}
+	enqueue(id)
+	return nil
+}
+
+// ReplayFailed 批量重放失败的投递（endpointID 为 0 表示全部目标），由重试任务投递，返回重放数量
+func ReplayFailed(ctx context.Context, endpointID uint, since time.Time) (int64, error) {
+	db := database.GetMySQL()
+	if db == nil {
+		return 0, fmt.Errorf("数据库未连接")
+	}
+
+	query := db.WithContext(ctx).Model(&model.WebhookDelivery{}).
+		Where("status = ? AND created_at >= ?", model.WebhookDeliveryFailed, since)
+	if endpointID > 0 {
+		query = query.Where("endpoint_id = ?", endpointID)
+	}
+	result := query.Updates(replayUpdates())
+	return result.RowsAffected, result.Error
+}
+
+// replayUpdates 重放时重置的字段
+func replayUpdates() map[string]interface{} {
+	return map[string]interface{}{
+		"status":          model.WebhookDeliveryPending,
+		"attempts":        0,
+		"replays":         gorm.Expr("replays + 1"),
+		"next_attempt_at": time.Now(),
+		"completed_at":    nil,
+	}
+}
+
+// enqueue 加入即时投递队列（未启动或队列已满时等待重试任务投递）
+func enqueue(id uint) {
+	if queue == nil {
+		return
+	}
+	select {
+	case queue <- id:
+	default:
+		log.Printf("Webhook 投递队列已满，投递 %d 将由重试任务处理", id)
+	}
+}
+
+// work 工作协程：逐个投递队列中的记录
+func work() {
+	defer wg.Done()
+	for {
+		select {
+		case <-stopped:
+			return
+		case id := <-queue:
+			deliver(id)
+		}
+	}
+}
+
+// retryDue 投递到期的待投递记录
+func retryDue(ctx context.Context) error {
+	db := database.GetMySQL()
+	if db == nil {
+		return fmt.Errorf("数据库未连接")
+	}
+
+	var ids []uint
+	if err := db.WithContext(ctx).Model(&model.WebhookDelivery{}).
+		Where("status = ? AND next_attempt_at <= ?", model.WebhookDeliveryPending, time.Now()).
+		Order("next_attempt_at ASC").Limit(queueSize).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if queue != nil {
+			enqueue(id)
+		} else {
+			deliver(id)
+		}
+	}
+	return nil
+}
+
+// newEventID 生成事件 ID
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// truncate 截断字符串（按字节，用于写入定长列）
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
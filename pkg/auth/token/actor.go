@@ -0,0 +1,20 @@
+package token
+
+import "fmt"
+
+// ActorContextKey 模拟登录时发起模拟的管理员在 gin.Context 中的 key（审计日志、操作日志据此标记代为执行的请求）
+const ActorContextKey = "impersonator"
+
+// Actor 代表账号执行操作的主体（RFC 8693 的 act 声明）：超级管理员模拟其他管理员或用户时，
+// 签发的 Token 以被模拟账号为主体，并携带发起模拟的管理员
+type Actor struct {
+	AdminID  uint   `json:"admin_id" bson:"admin_id"`
+	Username string `json:"username" bson:"username"`
+	// 模拟记录 ID
+	ImpersonationID uint `json:"impersonation_id" bson:"impersonation_id"`
+}
+
+// String 用于日志显示，如 root(#1)
+func (a *Actor) String() string {
+	return fmt.Sprintf("%s(#%d)", a.Username, a.AdminID)
+}
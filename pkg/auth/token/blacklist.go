@@ -0,0 +1,23 @@
+package token
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Blacklist Token 黑名单（登出、强制下线），存储实现由调用方注入
+type Blacklist interface {
+	// IsRevoked 检查 Token 是否已被吊销（按 jti，或按签发者 + 主体的整体吊销时间）
+	IsRevoked(claims jwt.RegisteredClaims) bool
+}
+
+// NewTokenID 生成随机 jti
+func NewTokenID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,69 @@
+package token
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CookieConfig 以 Cookie 下发 Token 的配置（浏览器客户端），Name 为空表示不使用 Cookie
+type CookieConfig struct {
+	Name     string
+	Domain   string
+	Path     string
+	Secure   bool
+	SameSite http.SameSite
+}
+
+// Enabled 是否启用 Cookie 下发
+func (c CookieConfig) Enabled() bool {
+	return c.Name != ""
+}
+
+// New 生成携带 Token 的 HttpOnly Cookie
+func (c CookieConfig) New(token string, expiresAt time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     c.Name,
+		Value:    token,
+		Domain:   c.Domain,
+		Path:     c.path(),
+		Expires:  expiresAt,
+		MaxAge:   int(time.Until(expiresAt).Seconds()),
+		Secure:   c.Secure,
+		HttpOnly: true,
+		SameSite: c.SameSite,
+	}
+}
+
+// Expired 生成清除 Token 的 Cookie
+func (c CookieConfig) Expired() *http.Cookie {
+	return &http.Cookie{
+		Name:     c.Name,
+		Value:    "",
+		Domain:   c.Domain,
+		Path:     c.path(),
+		MaxAge:   -1,
+		Secure:   c.Secure,
+		HttpOnly: true,
+		SameSite: c.SameSite,
+	}
+}
+
+func (c CookieConfig) path() string {
+	if c.Path == "" {
+		return "/"
+	}
+	return c.Path
+}
+
+// ParseSameSite 解析 SameSite 配置：strict, lax, none
+func ParseSameSite(s string) http.SameSite {
+	switch strings.ToLower(s) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
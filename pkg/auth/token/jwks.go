@@ -0,0 +1,185 @@
+package token
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWK JSON Web Key（仅包含公钥参数）
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet JWKS 文档
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 导出当前密钥及历史密钥的公钥（HS256 共享密钥不会导出）
+func (k *Keys) JWKS() []JWK {
+	var result []JWK
+	if jwk, ok := toJWK(k.KeyID, k.VerifyKey); ok {
+		result = append(result, jwk)
+	}
+	for kid, retired := range k.retired {
+		if jwk, ok := toJWK(kid, retired.key); ok {
+			result = append(result, jwk)
+		}
+	}
+	return result
+}
+
+// MergeJWKS 合并多组密钥的 JWKS（按 kid 去重）
+func MergeJWKS(keys ...*Keys) JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		if k == nil {
+			continue
+		}
+		for _, jwk := range k.JWKS() {
+			if !seen[jwk.Kid] {
+				seen[jwk.Kid] = true
+				set.Keys = append(set.Keys, jwk)
+			}
+		}
+	}
+	return set
+}
+
+// toJWK 公钥转换为 JWK
+func toJWK(kid string, key interface{}) (JWK, bool) {
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA", Kid: kid, Use: "sig", Alg: SigningMethodRS256,
+			N: b64(pub.N.Bytes()),
+			E: b64(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		return JWK{
+			Kty: "EC", Kid: kid, Use: "sig", Alg: SigningMethodES256,
+			Crv: pub.Curve.Params().Name,
+			X:   b64(pub.X.FillBytes(make([]byte, size))),
+			Y:   b64(pub.Y.FillBytes(make([]byte, size))),
+		}, true
+	}
+	return JWK{}, false
+}
+
+// thumbprint RFC 7638 公钥指纹（作为默认 kid）
+func thumbprint(key interface{}) (string, error) {
+	jwk, ok := toJWK("", key)
+	if !ok {
+		return "", errors.New("不支持的公钥类型")
+	}
+
+	var canonical string
+	switch jwk.Kty {
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, jwk.E, jwk.N)
+	case "EC":
+		canonical = fmt.Sprintf(`{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`, jwk.Crv, jwk.X, jwk.Y)
+	}
+
+	sum := sha256.Sum256([]byte(canonical))
+	return b64(sum[:]), nil
+}
+
+// loadVerifyKeys 加载目录下的历史密钥（文件名去掉 .pem / .pub.pem 后缀即为 kid）
+func loadVerifyKeys(dir string) (map[string]verifyKey, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]verifyKey)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取密钥 %s 失败: %w", file, err)
+		}
+		key, err := parsePublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("解析密钥 %s 失败: %w", file, err)
+		}
+
+		kid := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(file), ".pem"), ".pub")
+		switch key.(type) {
+		case *rsa.PublicKey:
+			result[kid] = verifyKey{method: jwt.SigningMethodRS256, key: key}
+		case *ecdsa.PublicKey:
+			result[kid] = verifyKey{method: jwt.SigningMethodES256, key: key}
+		default:
+			return nil, fmt.Errorf("密钥 %s 类型不受支持", file)
+		}
+	}
+	return result, nil
+}
+
+// parsePublicKey 解析 PEM 公钥（私钥则导出其公钥）
+func parsePublicKey(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("无效的 PEM 数据")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return &key.PublicKey, nil
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return &key.PublicKey, nil
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch k := key.(type) {
+		case *rsa.PrivateKey:
+			return &k.PublicKey, nil
+		case *ecdsa.PrivateKey:
+			return &k.PublicKey, nil
+		}
+		return nil, errors.New("不支持的私钥类型")
+	}
+	return nil, fmt.Errorf("不支持的 PEM 类型: %s", block.Type)
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,201 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 签名算法
+const (
+	SigningMethodHS256 = "HS256"
+	SigningMethodRS256 = "RS256"
+	SigningMethodES256 = "ES256"
+)
+
+// ErrNoSigningKey 仅配置了公钥，无法签发 Token
+var ErrNoSigningKey = errors.New("未配置签名私钥，仅支持验证 Token")
+
+// KeyConfig 签名密钥配置
+type KeyConfig struct {
+	// 签名算法：HS256（默认）, RS256, ES256
+	SigningMethod string
+	// HS256 共享密钥
+	SecretKey string
+	// RS256/ES256 私钥 PEM 文件（签发 Token 时需要）
+	PrivateKeyFile string
+	// RS256/ES256 公钥 PEM 文件（为空时从私钥导出；只验证 Token 的服务只需配置公钥）
+	PublicKeyFile string
+	// 当前签名密钥的 kid（为空时 RS256/ES256 使用 RFC 7638 公钥指纹）
+	KeyID string
+	// 仅用于验证的历史密钥目录（<kid>.pem，公钥或私钥均可），轮换后旧 Token 在过期前仍可验证
+	VerifyKeysDir string
+}
+
+// Keys 已加载的签名/验证密钥
+type Keys struct {
+	Method    jwt.SigningMethod
+	KeyID     string
+	SignKey   interface{}
+	VerifyKey interface{}
+	// 历史密钥（kid => 验证密钥）
+	retired map[string]verifyKey
+}
+
+// verifyKey 仅用于验证的密钥
+type verifyKey struct {
+	method jwt.SigningMethod
+	key    interface{}
+}
+
+var (
+	keysCache = make(map[KeyConfig]*Keys)
+	keysMu    sync.Mutex
+)
+
+// LoadKeys 按配置加载密钥（相同配置只解析一次 PEM 文件）
+func LoadKeys(cfg KeyConfig) (*Keys, error) {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+
+	if keys, ok := keysCache[cfg]; ok {
+		return keys, nil
+	}
+
+	keys, err := loadKeys(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	keys.KeyID = cfg.KeyID
+	if keys.KeyID == "" && keys.Method != jwt.SigningMethodHS256 {
+		if keys.KeyID, err = thumbprint(keys.VerifyKey); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.VerifyKeysDir != "" {
+		if keys.retired, err = loadVerifyKeys(cfg.VerifyKeysDir); err != nil {
+			return nil, err
+		}
+		delete(keys.retired, keys.KeyID)
+	}
+
+	keysCache[cfg] = keys
+	return keys, nil
+}
+
+// ReloadKeys 清空密钥缓存（轮换密钥文件后调用，下次使用时重新加载）
+func ReloadKeys() {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	keysCache = make(map[KeyConfig]*Keys)
+}
+
+func loadKeys(cfg KeyConfig) (*Keys, error) {
+	switch cfg.SigningMethod {
+	case SigningMethodHS256, "":
+		return &Keys{
+			Method:    jwt.SigningMethodHS256,
+			SignKey:   []byte(cfg.SecretKey),
+			VerifyKey: []byte(cfg.SecretKey),
+		}, nil
+
+	case SigningMethodRS256:
+		keys := &Keys{Method: jwt.SigningMethodRS256}
+		if cfg.PrivateKeyFile != "" {
+			data, err := os.ReadFile(cfg.PrivateKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("读取私钥失败: %w", err)
+			}
+			privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+			if err != nil {
+				return nil, fmt.Errorf("解析 RSA 私钥失败: %w", err)
+			}
+			keys.SignKey, keys.VerifyKey = privateKey, &privateKey.PublicKey
+		}
+		if cfg.PublicKeyFile != "" {
+			data, err := os.ReadFile(cfg.PublicKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("读取公钥失败: %w", err)
+			}
+			publicKey, err := jwt.ParseRSAPublicKeyFromPEM(data)
+			if err != nil {
+				return nil, fmt.Errorf("解析 RSA 公钥失败: %w", err)
+			}
+			keys.VerifyKey = publicKey
+		}
+		if keys.VerifyKey == nil {
+			return nil, errors.New("RS256 需要配置私钥或公钥文件")
+		}
+		return keys, nil
+
+	case SigningMethodES256:
+		keys := &Keys{Method: jwt.SigningMethodES256}
+		if cfg.PrivateKeyFile != "" {
+			data, err := os.ReadFile(cfg.PrivateKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("读取私钥失败: %w", err)
+			}
+			privateKey, err := jwt.ParseECPrivateKeyFromPEM(data)
+			if err != nil {
+				return nil, fmt.Errorf("解析 EC 私钥失败: %w", err)
+			}
+			keys.SignKey, keys.VerifyKey = privateKey, &privateKey.PublicKey
+		}
+		if cfg.PublicKeyFile != "" {
+			data, err := os.ReadFile(cfg.PublicKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("读取公钥失败: %w", err)
+			}
+			publicKey, err := jwt.ParseECPublicKeyFromPEM(data)
+			if err != nil {
+				return nil, fmt.Errorf("解析 EC 公钥失败: %w", err)
+			}
+			keys.VerifyKey = publicKey
+		}
+		if keys.VerifyKey == nil {
+			return nil, errors.New("ES256 需要配置私钥或公钥文件")
+		}
+		return keys, nil
+
+	default:
+		return nil, fmt.Errorf("不支持的签名算法: %s", cfg.SigningMethod)
+	}
+}
+
+// Sign 签发 Token（Header 中携带 kid）
+func (k *Keys) Sign(claims jwt.Claims) (string, error) {
+	if k.SignKey == nil {
+		return "", ErrNoSigningKey
+	}
+	token := jwt.NewWithClaims(k.Method, claims)
+	if k.KeyID != "" {
+		token.Header["kid"] = k.KeyID
+	}
+	return token.SignedString(k.SignKey)
+}
+
+// Keyfunc 验证 Token 时按 kid 选择验证密钥（拒绝与密钥不一致的算法，防止算法混淆攻击）
+// 未携带 kid 的历史 Token 使用当前密钥验证
+func (k *Keys) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid != "" && kid != k.KeyID {
+		retired, ok := k.retired[kid]
+		if !ok {
+			return nil, fmt.Errorf("未知的密钥 ID: %s", kid)
+		}
+		if token.Method.Alg() != retired.method.Alg() {
+			return nil, fmt.Errorf("无效的签名方法: %s", token.Method.Alg())
+		}
+		return retired.key, nil
+	}
+
+	if token.Method.Alg() != k.Method.Alg() {
+		return nil, fmt.Errorf("无效的签名方法: %s", token.Method.Alg())
+	}
+	return k.VerifyKey, nil
+}
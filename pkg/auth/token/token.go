@@ -0,0 +1,141 @@
+package token
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrTokenExpired     = errors.New("token已过期")
+	ErrTokenNotValidYet = errors.New("token尚未生效")
+	ErrTokenMalformed   = errors.New("token格式错误")
+	ErrTokenInvalid     = errors.New("无效的token")
+	ErrTokenRevoked     = errors.New("token已被吊销")
+	// ErrTokenMissing 请求未携带 Token
+	ErrTokenMissing = errors.New("缺少认证令牌")
+	// ErrTokenFormat Authorization 头不是 Bearer 格式
+	ErrTokenFormat = errors.New("认证令牌格式错误")
+)
+
+// Config Token 签发与校验配置（用户 Token、管理后台 Token 共用）
+type Config struct {
+	SecretKey     string
+	TokenExpiry   time.Duration
+	RefreshExpiry time.Duration
+	Issuer        string
+	// 签名算法：HS256（默认）, RS256, ES256
+	SigningMethod string
+	// RS256/ES256 私钥 PEM 文件（只验证 Token 的服务可不配置）
+	PrivateKeyFile string
+	// RS256/ES256 公钥 PEM 文件（为空时从私钥导出）
+	PublicKeyFile string
+	// 当前密钥 kid（为空时使用公钥指纹）
+	KeyID string
+	// 历史密钥目录（<kid>.pem），轮换后旧 Token 在过期前仍可验证
+	VerifyKeysDir string
+	// Token 黑名单（为空不检查）
+	Blacklist Blacklist
+	// 以 HttpOnly Cookie 下发 Token（浏览器客户端），未携带 Authorization 头时从 Cookie 读取
+	Cookie CookieConfig
+	// 校验 exp/nbf/iat 时允许的时钟偏差
+	Leeway time.Duration
+}
+
+// Claims 可签发、校验的声明（自定义声明嵌入 jwt.RegisteredClaims 并实现 Registered）
+type Claims interface {
+	jwt.Claims
+	// Registered 返回标准声明（黑名单按 jti、签发者 + 主体检查）
+	Registered() jwt.RegisteredClaims
+}
+
+// Keys 加载签名密钥
+func (cfg Config) Keys() (*Keys, error) {
+	return LoadKeys(KeyConfig{
+		SigningMethod:  cfg.SigningMethod,
+		SecretKey:      cfg.SecretKey,
+		PrivateKeyFile: cfg.PrivateKeyFile,
+		PublicKeyFile:  cfg.PublicKeyFile,
+		KeyID:          cfg.KeyID,
+		VerifyKeysDir:  cfg.VerifyKeysDir,
+	})
+}
+
+// NewRegisteredClaims 生成标准声明（签发者、主体、有效期、随机 jti）
+func (cfg Config) NewRegisteredClaims(subject string, ttl time.Duration) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		Issuer:    cfg.Issuer,
+		Subject:   subject,
+		ID:        NewTokenID(),
+	}
+}
+
+// Sign 使用当前签名密钥签发 Token
+func (cfg Config) Sign(claims jwt.Claims) (string, error) {
+	keys, err := cfg.Keys()
+	if err != nil {
+		return "", err
+	}
+	return keys.Sign(claims)
+}
+
+// Parse 校验 Token 并解析到 claims：签名（含历史密钥）、签发者、有效期（允许 Leeway 偏差）、黑名单
+func (cfg Config) Parse(tokenString string, claims Claims) error {
+	keys, err := cfg.Keys()
+	if err != nil {
+		return err
+	}
+
+	options := []jwt.ParserOption{jwt.WithLeeway(cfg.Leeway)}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, keys.Keyfunc, options...)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return ErrTokenExpired
+		}
+		if errors.Is(err, jwt.ErrTokenNotValidYet) {
+			return ErrTokenNotValidYet
+		}
+		if errors.Is(err, jwt.ErrTokenMalformed) {
+			return ErrTokenMalformed
+		}
+		return ErrTokenInvalid
+	}
+
+	if !token.Valid {
+		return ErrTokenInvalid
+	}
+	if cfg.Blacklist != nil && cfg.Blacklist.IsRevoked(claims.Registered()) {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// FromRequest 从 Authorization 头（Bearer）或 Token Cookie 中获取 Token
+func (cfg Config) FromRequest(r *http.Request) (string, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		if cfg.Cookie.Enabled() {
+			if cookie, err := r.Cookie(cfg.Cookie.Name); err == nil && cookie.Value != "" {
+				return cookie.Value, nil
+			}
+		}
+		return "", ErrTokenMissing
+	}
+
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", ErrTokenFormat
+	}
+	return parts[1], nil
+}
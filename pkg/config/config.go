@@ -9,19 +9,84 @@ import (
 
 // Config 应用配置
 type Config struct {
-	Server   ServerConfig
-	MySQL    MySQLConfig
-	Redis    RedisConfig
-	MongoDB  MongoDBConfig
-	Security SecurityConfig
+	Server         ServerConfig
+	MySQL          MySQLConfig
+	Redis          RedisConfig
+	MongoDB        MongoDBConfig
+	Security       SecurityConfig
+	Store          StoreConfig
+	Analytics      AnalyticsConfig
+	Observability  ObservabilityConfig
+	Protection     ProtectionConfig
+	Discovery      DiscoveryConfig
+	ConfigCenter   ConfigCenterConfig
+	Leader         LeaderConfig
+	Quota          QuotaConfig
+	Password       PasswordConfig
+	Secrets        SecretsConfig
+	Mail           MailConfig
+	Notify         NotifyConfig
+	Replay         ReplayConfig
+	AppKey         AppKeyConfig
+	BreakGlass     BreakGlassConfig
+	Health         HealthConfig
+	Webhook        WebhookConfig
+	ThreatFeed     ThreatFeedConfig
+	Reputation     ReputationConfig
+	AuditSinks     AuditSinksConfig
+	Policy         PolicyConfig
+	SecurityState  SecurityStateConfig
+	WAF            WAFConfig
+	Settings       SettingsConfig
+	Dashboard      DashboardConfig
+	Roles          RolesConfig
+	Upload         UploadConfig
+	SecurityEvents SecurityEventsConfig
+	LoginGuard     LoginGuardConfig
+	Validation     ValidationConfig
+	Maintenance    MaintenanceConfig
+	OAuth          OAuthConfig
+	APIKey         APIKeyConfig
+	PersonalToken  PersonalTokenConfig
+	Captcha        CaptchaConfig
+	EmailVerify    EmailVerifyConfig
+	PasswordReset  PasswordResetConfig
+	LoginHistory   LoginHistoryConfig
+	WebAuthn       WebAuthnConfig
+	Impersonation  ImpersonationConfig
+	SAML           SAMLConfig
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
 	Port string
 	Mode string
+	// 服务版本（注册到服务发现的元数据中）
+	Version string
+	// 规范 Base URL（用于邮件、Webhook 中的绝对链接）
+	BaseURL string
+	// 受信任的代理（IP/CIDR，用于解析客户端 IP 及识别 X-Forwarded-Proto）
+	TrustedProxies []string
+	// 是否将 HTTP 重定向到 HTTPS
+	HTTPSRedirect bool
+	// 运行模式：为空为常规部署，standalone 为单机模式（SQLite + 内存存储，不依赖外部服务）
+	AppMode string
+	// 单机模式的 SQLite 数据库文件
+	SQLitePath string
+	// GET 请求的 JSON 响应计算 ETag 并支持 If-None-Match（304）
+	ETagEnabled bool
+	// 计算 ETag 的最大响应体（字节）
+	ETagMaxSize int
+	// 不计算 ETag 的路径（支持末尾 * 通配）
+	ETagExcludePaths []string
 }
 
+// AppModeStandalone 单机模式（演示、本地开发）
+const AppModeStandalone = "standalone"
+
+// DefaultJWTSecretKey 未配置 JWT_SECRET_KEY 时的默认密钥（公开值，仅供本地开发）
+const DefaultJWTSecretKey = "your-secret-key-change-in-production"
+
 // SecurityConfig 安全配置
 type SecurityConfig struct {
 	// JWT 配置
@@ -29,24 +94,131 @@ type SecurityConfig struct {
 	JWTExpiry        time.Duration
 	JWTRefreshExpiry time.Duration
 	JWTIssuer        string
+	// 签名算法（HS256/RS256/ES256）及 RS256/ES256 的 PEM 密钥文件
+	JWTSigningMethod  string
+	JWTPrivateKeyFile string
+	JWTPublicKeyFile  string
+	// 当前密钥 kid 及历史密钥目录（密钥轮换）
+	JWTKeyID         string
+	JWTVerifyKeysDir string
+
+	// 管理后台 JWT 配置
+	AdminJWTSecretKey      string
+	AdminJWTSigningMethod  string
+	AdminJWTPrivateKeyFile string
+	AdminJWTPublicKeyFile  string
+	AdminJWTKeyID          string
+	AdminJWTVerifyKeysDir  string
+
+	// JWT 校验允许的时钟偏差（用户与管理后台共用）
+	JWTLeeway time.Duration
+
+	// 每个管理员的并发会话上限（0 不限制）及超出时的策略（reject / revoke_oldest）
+	AdminMaxSessions        int
+	AdminSessionLimitPolicy string
+	// 每个管理员只允许一个活跃会话：新登录吊销之前的会话，旧客户端收到"已在其他设备登录"（优先于 AdminMaxSessions）
+	AdminSingleSession bool
+
+	// 管理后台认证方式：jwt（默认）或 session（服务端会话，保存在会话存储中），以及服务端会话的空闲、绝对超时
+	AdminAuthMode               string
+	AdminSessionIdleTimeout     time.Duration
+	AdminSessionAbsoluteTimeout time.Duration
+
+	// Cookie 下发 Token（浏览器客户端，名称为空不启用）及 CSRF 防护
+	JWTCookieName      string
+	AdminJWTCookieName string
+	JWTCookieDomain    string
+	JWTCookieSecure    bool
+	JWTCookieSameSite  string
+	CSRFCookieName     string
+	CSRFHeaderName     string
 
 	// 频率限制配置
 	RateLimitWindow      time.Duration
 	RateLimitMaxRequests int
+	RateLimitAlgorithm   string
+	RateLimitBurst       int
+	// 按路由的频率限制规则（按顺序匹配，命中第一条）
+	RateLimitRules []RateLimitRule
+	// 频率限制豁免（IP/CIDR、AppKey、JWT 角色）
+	RateLimitExemptCIDRs   []string
+	RateLimitExemptAppKeys []string
+	RateLimitExemptRoles   []string
+	// 预警阈值（已用比例，0 不预警）与全局限额的观察期截止时间
+	RateLimitWarnThreshold float64
+	RateLimitEnforceAfter  time.Time
 
 	// API 签名配置
 	APISignatureKey    string
 	APISignatureExpiry time.Duration
+	// 签名算法（hmac-sha256 / md5 / v2）及 v2 必须签名的请求头
+	APISignatureAlgorithm     string
+	APISignatureSignedHeaders []string
 
 	// IP 过滤配置
 	IPWhitelistMode bool
 	IPWhitelist     []string
 	IPBlacklist     []string
+	// 持久化 IP 规则（ip_rules 表）的定时重新加载间隔，补偿遗漏的变更广播（0 不启用）
+	IPRuleReloadInterval time.Duration
+	// ASN 数据库（MaxMind GeoLite2-ASN 等 .mmdb 文件），名单中 AS 开头的条目依赖该数据库
+	GeoIPASNDatabase string
+	// 国家/地区数据库（GeoLite2-Country 或 GeoLite2-City），用于登录记录中的位置
+	GeoIPCountryDatabase string
+	// 检查 ASN、国家/地区数据库文件更新的间隔（0 不检查）
+	GeoIPReloadInterval time.Duration
 
 	// 审计配置
 	AuditEnabled  bool
 	AuditOutput   string
 	AuditFilePath string
+	// 审计日志备用文件（主文件写入失败时使用，为空输出到标准错误）
+	AuditFallbackPath string
+	// 审计日志轮转：超过大小（MB）或跨过周期时轮转（均为 0 不轮转），轮转文件的保留时长、数量与是否压缩
+	AuditRotateMaxSizeMB  int
+	AuditRotateInterval   time.Duration
+	AuditRotateMaxAge     time.Duration
+	AuditRotateMaxBackups int
+	AuditRotateCompress   bool
+	// 审计采样：记录请求的比例、记录的请求中采集请求/响应体的比例（0~1），按路径的规则优先；
+	// 未被采样的 5xx 与慢请求是否仍记录（不含请求/响应体）
+	AuditSampleRate       float64
+	AuditBodySampleRate   float64
+	AuditPathRules        []AuditPathRule
+	AuditSampleKeepErrors bool
+	// 审计脱敏规则（在 password、token 等字段名之外）：正则表达式、JSONPath、查询参数名、请求头名
+	AuditMaskPatterns    []string
+	AuditMaskJSONPaths   []string
+	AuditMaskQueryParams []string
+	AuditMaskHeaders     []string
+
+	// 滥用评分统计窗口（未知路径探测等可疑事件）
+	AbuseWindow time.Duration
+	// 窗口内滥用评分达到该值时自动临时封禁 IP（0 不封禁）及封禁时长
+	AbuseBanThreshold int64
+	AbuseBanTTL       time.Duration
+	// 诱饵路径（为空不启用）：命中即记录安全事件，HoneypotBan 时立即临时封禁来源 IP
+	HoneypotPaths []string
+	HoneypotBan   bool
+
+	// 响应脱敏字段（字段名=mask/hide），调用方缺少 pii:read 权限时生效
+	PIIRedactFields map[string]string
+	// 拥有 pii:read 的管理后台角色（/api/v1 按 Token 的权限范围判断）
+	PIIReadAdminRoles []string
+}
+
+// RateLimitRule 路由频率限制规则
+type RateLimitRule struct {
+	// 请求方法（为空匹配所有方法）
+	Method string
+	// 路径模式（支持末尾 * 通配）
+	Path string
+	// 时间窗口
+	Window time.Duration
+	// 窗口内最大请求数
+	MaxRequests int
+	// 在此时间之前只记录超限、不拦截（零值表示立即生效）
+	EnforceAfter time.Time
 }
 
 // MySQLConfig MySQL 配置
@@ -72,12 +244,682 @@ type MongoDBConfig struct {
 	Database string
 }
 
+// StoreConfig 状态存储配置（按组件选择后端：memory, redis）
+type StoreConfig struct {
+	NonceBackend     string
+	SessionBackend   string
+	RateLimitBackend string
+	QuotaBackend     string
+	// Token 黑名单（多副本部署需使用 redis 才能全局生效）
+	RevocationBackend string
+	// IP 滥用评分
+	AbuseBackend string
+	// 定时任务暂停状态（多副本部署需使用 redis 才能全局生效）
+	JobsBackend string
+	// AppKey 签名密钥缓存
+	AppKeyBackend string
+	// 登录失败计数与账号锁定（多副本部署需使用 redis 才能全局生效）
+	LoginBackend string
+	// 维护模式开关（多副本部署需使用 redis 才能全局生效）
+	MaintenanceBackend string
+	// API Key 校验结果缓存及最近使用时间的写库节流
+	APIKeyBackend string
+	// 图片验证码答案（多副本部署需使用 redis，获取与提交可能落在不同实例）
+	CaptchaBackend string
+	// 重置密码令牌（多副本部署需使用 redis，申请与重置可能落在不同实例）
+	PasswordResetBackend string
+	// WebAuthn 注册、登录挑战（多副本部署需使用 redis，获取选项与提交可能落在不同实例）
+	WebAuthnBackend string
+}
+
+// AnalyticsConfig 管理员行为分析配置
+type AnalyticsConfig struct {
+	// 工作时间（小时，[Start, End)）
+	WorkHourStart int
+	WorkHourEnd   int
+	// 批量删除阈值（窗口内删除次数）
+	BulkDeleteThreshold int
+	BulkDeleteWindow    time.Duration
+	// 权限修改激增阈值（窗口内修改次数）
+	PermissionEditThreshold int
+	PermissionEditWindow    time.Duration
+	// 告警 Webhook（为空则不推送）
+	AlertWebhook  string
+	AlertInterval time.Duration
+}
+
+// ObservabilityConfig 可观测性配置
+type ObservabilityConfig struct {
+	// 慢请求默认阈值（0 表示仅检测单独配置的路由）
+	SlowRequestThreshold time.Duration
+	// 按路由配置的慢请求阈值（"GET /api/v1/users" 或 "/admin/*" => 阈值）
+	SlowRequestRoutes map[string]time.Duration
+	// 是否采集慢请求扩展详情（SQL、中间件耗时）
+	SlowRequestDetail bool
+}
+
+// ProtectionConfig 过载保护配置
+type ProtectionConfig struct {
+	// 是否启用过载保护（负载过高时丢弃低优先级请求）
+	OverloadEnabled bool
+	// 最大 goroutine 数
+	MaxGoroutines int
+	// 最大堆内存（MB）
+	MaxHeapMB int
+	// 最大调度延迟
+	MaxSchedLatency time.Duration
+
+	// 关键/批量请求路由（"[METHOD ]/path"，路径支持末尾 * 通配）
+	CriticalRoutes []string
+	BulkRoutes     []string
+	// 各优先级并发预算（0 表示不限制）
+	CriticalConcurrency int
+	NormalConcurrency   int
+	BulkConcurrency     int
+	// 并发预算耗尽时的排队超时
+	PriorityQueueTimeout time.Duration
+
+	// 请求体大小上限（字节，0 表示不限制）
+	MaxBodySize int64
+	// 按路由配置的请求体上限（"POST /api/v1/signed/callback" 或 "/admin/*" => 字节数）
+	RouteBodyLimits map[string]int64
+
+	// 单实例最大并发请求数（0 表示不限制）
+	MaxInFlight int
+	// 单 IP 最大并发请求数（0 表示不限制）
+	MaxInFlightPerIP int
+	// 并发饱和时的排队超时
+	ConcurrencyQueueTimeout time.Duration
+}
+
+// DiscoveryConfig 服务发现配置
+type DiscoveryConfig struct {
+	// 注册中心类型：consul, etcd, nacos（为空则不注册）
+	Provider string
+	// 注册中心地址（如 http://127.0.0.1:8500）
+	Endpoint string
+	// 服务名
+	ServiceName string
+	// 注册的服务地址（为空时自动探测本机 IP）
+	ServiceAddress string
+	// 健康检查路径
+	HealthCheckPath string
+	// 注册 TTL（超过 TTL 未续约则实例被摘除）
+	TTL time.Duration
+	// 命名空间（Nacos 为 namespaceId，etcd 为 key 前缀）
+	Namespace string
+	// 附加元数据
+	Metadata map[string]string
+}
+
+// ConfigCenterConfig 配置中心配置（监听远程配置并热更新频率限制、IP 规则、功能开关）
+type ConfigCenterConfig struct {
+	// 配置中心类型：etcd, nacos（为空则不启用）
+	Provider string
+	// 配置中心地址（如 http://127.0.0.1:2379）
+	Endpoint string
+	// 配置 Key（etcd 为 key，Nacos 为 dataId）
+	Key string
+	// Nacos 分组
+	Group string
+	// Nacos 命名空间
+	Namespace string
+}
+
+// LeaderConfig 选主配置（单例后台任务只在 Leader 上运行）
+type LeaderConfig struct {
+	// 选主后端：redis, etcd（为空则视为单实例，始终为 Leader）
+	Backend string
+	// etcd 地址（仅 etcd 后端）
+	Endpoint string
+	// 锁 Key
+	Key string
+	// 锁 TTL（Leader 宕机后最长经过 TTL 完成切换）
+	TTL time.Duration
+}
+
+// QuotaConfig AppKey 配额配置（单独配置存储在 app_key_quotas 表中）
+type QuotaConfig struct {
+	// 默认每日配额（0 表示不限制）
+	DailyLimit int64
+	// 默认每月配额（0 表示不限制）
+	MonthlyLimit int64
+}
+
+// PasswordConfig 密码哈希配置
+type PasswordConfig struct {
+	// 新密码使用的算法：bcrypt, argon2id, scrypt（旧哈希在登录成功后自动升级）
+	Algorithm  string
+	BcryptCost int
+
+	Argon2Memory      int // KiB
+	Argon2Iterations  int
+	Argon2Parallelism int
+
+	ScryptN int
+	ScryptR int
+	ScryptP int
+
+	// 密码强度策略（注册、重置密码时校验）：最小/最大长度与至少包含的字符类别数
+	MinLength  int
+	MaxLength  int
+	MinClasses int
+}
+
+// SecretsConfig 静态密钥加密配置（合作方 Secret、Webhook 签名密钥等敏感列）
+type SecretsConfig struct {
+	// 当前 KEK（base64 编码的 32 字节密钥）
+	KEK string
+	// KEK 文件（由 KMS / Vault Agent 解密后挂载，优先于 KEK）
+	KEKFile string
+	// 当前 KEK 版本（写入密文前缀）
+	KEKVersion string
+	// 历史 KEK（版本 => base64 密钥），轮换后用于解密旧数据
+	RetiredKEKs map[string]string
+}
+
+// MailConfig 邮件发送配置（管理员通知与注册邮箱验证共用）
+type MailConfig struct {
+	// SMTP 服务器（为空时邮件只写日志）
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	// 发件人（为空使用 SMTPUsername）
+	From string
+}
+
+// NotifyConfig 管理员邮件通知配置（经 Mail 配置的 SMTP 发送）
+type NotifyConfig struct {
+	// 摘要队列检查间隔
+	DigestInterval time.Duration
+}
+
+// ReplayConfig 请求重放配置（按 request_id 将审计日志中的请求重放到其他环境排查问题）
+type ReplayConfig struct {
+	// 目标环境（名称 => 基础 URL）
+	Targets map[string]string
+	// 默认目标环境
+	DefaultTarget string
+	// 目标环境的 API 签名密钥（重放签名接口时重新签名）
+	SignatureSecret string
+	// 对比响应时忽略的易变字段
+	IgnoreFields []string
+	// 重放请求超时
+	Timeout time.Duration
+}
+
+// AppKeyConfig AppKey 签名密钥配置
+type AppKeyConfig struct {
+	// 密钥缓存时间（管理端修改后立即失效）
+	CacheTTL time.Duration
+	// 未登记的 AppKey 是否回退到全局签名密钥（迁移期间开启，全部调用方登记后关闭）
+	GlobalFallback bool
+}
+
+// APIKeyConfig API Key 认证配置
+type APIKeyConfig struct {
+	// 校验结果缓存时间（管理端吊销、轮换后立即失效）
+	CacheTTL time.Duration
+	// 最近使用时间与 IP 的写库间隔（同一 Key 在间隔内只写一次）
+	LastUsedInterval time.Duration
+}
+
+// PersonalTokenConfig 用户个人访问令牌配置（校验结果与 API Key 共用 APIKEY_STORE 缓存）
+type PersonalTokenConfig struct {
+	// 最长有效期（0 表示允许永不过期）
+	MaxTTL time.Duration
+	// 每个用户最多持有的有效令牌数
+	MaxPerUser int
+	// 校验结果缓存时间（吊销后立即失效；用户被禁用、改角色最迟在缓存过期后生效）
+	CacheTTL time.Duration
+}
+
+// CaptchaConfig 登录与注册的人机验证
+type CaptchaConfig struct {
+	Enabled bool
+	// 验证方式：image（服务端生成图片验证码）、hcaptcha、recaptcha、turnstile
+	Provider string
+	// 第三方服务的 siteverify 地址（为空使用该服务的默认地址）、密钥与前端 site key
+	VerifyURL string
+	Secret    string
+	SiteKey   string
+	Timeout   time.Duration
+	// 图片验证码的位数与有效期
+	Length int
+	TTL    time.Duration
+	// 管理后台账号连续失败该次数后登录需要验证（0 表示每次登录都需要）
+	LoginAfter int
+	// 注册是否需要验证
+	Register bool
+}
+
+// EmailVerifyConfig 注册邮箱验证配置
+type EmailVerifyConfig struct {
+	// 验证链接的签名密钥（为空时由 JWT_SECRET_KEY 派生，见 secrets.DeriveKey）
+	Secret string
+	// 验证链接有效期
+	TTL time.Duration
+	// 同一用户重新发送验证邮件的最小间隔
+	ResendInterval time.Duration
+	// 验证完成后跳转的前端地址（为空时验证接口返回 JSON）
+	RedirectURL string
+}
+
+// LoginHistoryConfig 登录记录配置
+type LoginHistoryConfig struct {
+	Enabled bool
+	// 保留时长（0 不清理）
+	Retention time.Duration
+	// 成功登录来自新设备或新位置时向账号邮箱发送提醒
+	NotifyNewDevice bool
+}
+
+// WebAuthnConfig 管理员使用安全密钥、通行密钥（WebAuthn）登录的配置
+type WebAuthnConfig struct {
+	// 依赖方 ID（管理后台的域名，为空时取 APP_BASE_URL 的主机名；两者都为空时不启用）
+	RPID string
+	// 依赖方名称（注册时浏览器向管理员展示）
+	RPName string
+	// 允许的来源（管理后台前端地址，如 https://admin.example.com；为空时取 APP_BASE_URL）
+	Origins []string
+	// 注册、登录挑战的有效期
+	Timeout time.Duration
+	// 要求认证器完成用户验证（PIN、指纹、面容），安全密钥单独即可完成登录
+	RequireUserVerification bool
+	// 必须使用安全密钥登录的管理员角色（如 super_admin）
+	RequiredRoles []string
+}
+
+// SAMLConfig 管理后台 SAML 2.0 单点登录（Okta、Azure AD 等），配置了 IdP 单点登录地址与证书时启用
+type SAMLConfig struct {
+	// SP 实体 ID（为空取 {APP_BASE_URL}/admin/saml/metadata）
+	EntityID string
+	// 断言消费服务地址（为空取 {APP_BASE_URL}/admin/saml/acs）
+	ACSURL string
+	// IdP 实体 ID（为空不校验颁发者）
+	IdPEntityID string
+	// IdP 单点登录地址（HTTP-Redirect 绑定）
+	IdPSSOURL string
+	// IdP 签名证书：PEM 文件（可包含多个证书，用于轮换），或直接配置 PEM / Base64 内容
+	IdPCertFile string
+	IdPCert     string
+	// 用户名取自的属性（为空使用 NameID）、邮箱属性、分组属性
+	UsernameAttribute string
+	EmailAttribute    string
+	GroupsAttribute   string
+	// 角色对应的 IdP 分组（同时属于多个分组时取权限最高的角色：super_admin > admin > editor）
+	RoleGroups map[string][]string
+	// 不属于任何已映射分组时的角色（为空拒绝登录）
+	DefaultRole string
+	// 管理员不存在时自动创建
+	AutoCreate bool
+	// 允许 IdP 发起的登录（没有对应的认证请求）
+	AllowIdPInitiated bool
+	// 登录完成后允许跳转的前端地址前缀（登录请求未指定 redirect 时直接返回 JSON）
+	AllowedRedirects []string
+	// 认证请求的有效期（管理员在 IdP 页面停留的最长时间）
+	RequestTTL time.Duration
+	// 校验断言有效期时允许的时钟偏差
+	ClockSkew time.Duration
+}
+
+// ImpersonationConfig 超级管理员模拟其他管理员、用户登录的配置
+type ImpersonationConfig struct {
+	// 模拟登录 Token 的有效期（不能刷新，到期后需重新发起）
+	TTL time.Duration
+}
+
+// PasswordResetConfig 忘记密码（邮件发送一次性重置令牌）配置
+type PasswordResetConfig struct {
+	// 重置令牌有效期
+	TTL time.Duration
+	// 同一用户两次发送重置邮件的最小间隔
+	ResendInterval time.Duration
+	// 前端重置密码页面地址（邮件中的链接为 {URL}?token=...；为空时邮件中只包含令牌）
+	URL string
+}
+
+// BreakGlassConfig 紧急访问配置（Redis/数据库故障导致常规登录不可用时使用）
+type BreakGlassConfig struct {
+	// 紧急账号用户名
+	Username string
+	// 密码哈希（pkg/password 格式，可由 cmd/breakglass 生成；为空不启用）
+	PasswordHash string
+	// 紧急会话有效期（不可刷新）
+	TTL time.Duration
+	// 审计轨迹文件（只追加，哈希链防篡改）
+	TrailFile string
+	// 额外接收告警的邮箱（数据库不可用、无法查询超级管理员时仍可送达）
+	AlertEmails []string
+}
+
+// HealthConfig 依赖健康监测配置（状态变化时通知并记录）
+type HealthConfig struct {
+	// 检测间隔（0 不检测）
+	CheckInterval time.Duration
+	// 额外接收告警的邮箱（管理员通知因数据库不可用发送失败时使用）
+	AlertEmails []string
+}
+
+// WebhookConfig Webhook 投递配置
+type WebhookConfig struct {
+	// 每轮最多尝试次数（含首次投递），用尽后标记为失败，可在管理端重放
+	MaxAttempts int
+	// 重试退避：首次重试间隔，之后逐次翻倍，不超过 MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// 单次投递超时
+	Timeout time.Duration
+	// 即时投递的并发数
+	Workers int
+	// 扫描待重试投递的间隔
+	RetryInterval time.Duration
+}
+
+// ThreatFeedConfig 安全事件订阅配置（供内部威胁情报汇聚系统拉取攻击检测与封禁）
+type ThreatFeedConfig struct {
+	// 允许拉取的 AppKey（需 API 签名；为空时订阅不可用）
+	AppKeys []string
+	// 订阅中标识数据来源的名称（生成 identity 及各对象的稳定 ID）
+	Source string
+}
+
+// SecurityEventsConfig 安全事件存储（攻击检测、认证失败、封禁）与告警推送
+type SecurityEventsConfig struct {
+	// 存储：mysql 或 mongodb
+	Store string
+	// MongoDB 集合
+	Collection string
+	// 保留时长（0 不清理）
+	Retention time.Duration
+	// 是否记录认证失败（无效 Token 与签名失败的量可能很大）
+	RecordAuthFailures bool
+
+	// 达到该严重级别（low、medium、high、critical）的事件推送告警
+	AlertMinSeverity string
+	// 通用 Webhook（POST JSON）
+	AlertWebhook string
+	// 钉钉机器人 Webhook 及加签密钥（为空不加签）
+	DingTalkWebhook string
+	DingTalkSecret  string
+	// Slack Incoming Webhook
+	SlackWebhook string
+	// 是否通过邮件发送（按管理员的安全通知偏好发送）
+	AlertEmail bool
+	// 相同事件（类型、IP、分类）在此时长内只推送一次，之后的推送附带期间被合并的次数
+	AlertDedupeWindow time.Duration
+	// 每个实例每分钟最多推送的告警数（超出的丢弃，0 不限制）
+	AlertMaxPerMinute int
+	// 推送超时
+	AlertTimeout time.Duration
+}
+
+// LoginGuardConfig 登录暴力破解防护（管理后台与用户登录，按账号与 IP 分别计数）
+type LoginGuardConfig struct {
+	Enabled bool
+	// 失败计数窗口（从第一次失败开始计时）
+	Window time.Duration
+	// 账号失败超过该次数后，每次失败都要求等待 BaseDelay × 2^n 后才能再次尝试（0 不限制）
+	DelayAfter int
+	// 同一 IP 失败超过该次数后同样要求等待（不区分账号，0 不限制）
+	IPDelayAfter int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	// 账号失败达到该次数后锁定（0 不锁定）
+	LockThreshold int
+	// 首次锁定时长，24 小时内再次锁定时逐次加倍，不超过 MaxLockDuration
+	LockDuration    time.Duration
+	MaxLockDuration time.Duration
+}
+
+// ValidationConfig 请求格式校验（Content-Type 与请求体 JSON Schema）
+type ValidationConfig struct {
+	Enabled bool
+	// 带请求体的请求允许的 Content-Type
+	ContentTypes []string
+	// 按路由允许的 Content-Type（"POST /api/v1/signed/callback" => 媒体类型列表）
+	RouteContentTypes map[string][]string
+	// 按路由的 JSON Schema 文件（为空不校验请求体）
+	SchemaFile string
+	// 最多返回的校验错误数
+	MaxErrors int
+}
+
+// MaintenanceConfig 维护模式（开关由管理接口设置，以下为默认值）
+type MaintenanceConfig struct {
+	// 默认提示信息
+	Message string
+	// 默认的 Retry-After
+	RetryAfter time.Duration
+	// 维护期间仍然开放的路径（支持末尾 * 通配，/admin 下的接口始终开放）
+	AllowPaths []string
+	// 各实例从存储刷新开关状态的间隔
+	RefreshInterval time.Duration
+}
+
+// OAuthConfig 第三方登录（OAuth2 / OIDC），配置了 ClientID 的提供方启用
+type OAuthConfig struct {
+	// 回调地址的 Base URL（为空使用 APP_BASE_URL），回调地址为 {base}/api/v1/public/oauth/{provider}/callback
+	CallbackBaseURL string
+	// 登录完成后允许跳转的前端地址前缀（登录请求未指定 redirect 时回调直接返回 JSON）
+	AllowedRedirects []string
+	// state 与 PKCE verifier 的有效期（用户在第三方页面停留的最长时间）
+	StateTTL time.Duration
+	// 第三方身份未绑定用户时自动创建用户（关闭时只有已绑定的身份可以登录）
+	AutoRegister bool
+	// 请求第三方接口的超时
+	Timeout time.Duration
+
+	Google OAuthProviderConfig
+	GitHub OAuthProviderConfig
+	// 通用 OIDC 提供方（Keycloak、Auth0、Azure AD 等），端点从 Issuer 的 discovery 文档获取
+	OIDC OAuthProviderConfig
+}
+
+// OAuthProviderConfig 第三方登录提供方
+type OAuthProviderConfig struct {
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// 仅通用 OIDC：路径中的提供方名称与 Issuer
+	Name   string
+	Issuer string
+}
+
+// ReputationConfig IP 信誉名单配置（定时下载公开的威胁情报黑名单并合并到 IP 过滤）
+type ReputationConfig struct {
+	Enabled bool
+	// 名单地址（每行一个 IP 或 CIDR，# 或 ; 开头为注释，如 abuse.ch、FireHOL 的纯文本名单）
+	Feeds []string
+	// 刷新间隔与单个名单的下载超时
+	Interval time.Duration
+	Timeout  time.Duration
+	// 合并后的条目数上限（超出部分丢弃）
+	MaxEntries int
+	// 命中名单的处理：block 直接拦截，policy 只标记为 listed，交给反滥用处置策略决定
+	Action string
+}
+
+// AuditPathRule 按路径的审计记录规则（比例为 -1 时沿用全局比例）
+type AuditPathRule struct {
+	// 路由："[METHOD ]/path"，末尾 * 按前缀匹配
+	Route string
+	// 记录请求的比例（0 不记录）
+	SampleRate float64
+	// 记录的请求中采集请求/响应体的比例（0 只记录请求头等元数据）
+	BodySampleRate float64
+}
+
+// AuditSinksConfig 审计日志外部输出配置（可同时启用多个，接入已有的 SIEM 管道）
+type AuditSinksConfig struct {
+	// 启用的输出：elasticsearch, kafka, syslog, mongodb
+	Enabled []string
+	// 批量写入参数
+	BatchSize     int
+	FlushInterval time.Duration
+	BufferSize    int
+	Timeout       time.Duration
+
+	// Elasticsearch 地址与索引（索引名中的 {date} 按日志日期替换为 2006.01.02）
+	ElasticsearchURL      string
+	ElasticsearchIndex    string
+	ElasticsearchUsername string
+	ElasticsearchPassword string
+	ElasticsearchAPIKey   string
+
+	// Kafka REST Proxy 地址与主题
+	KafkaRESTURL string
+	KafkaTopic   string
+
+	// syslog 地址（udp://host:514、tcp://host:601 或 tls://host:6514）、标签与设施
+	SyslogAddr     string
+	SyslogTag      string
+	SyslogFacility string
+
+	// MongoDB 集合（写入 MONGO_DATABASE，同时供 GET /admin/audit-logs 查询）及保留时长；
+	// 设置固定集合大小（MB）后改为固定集合，写满时覆盖最早的日志，不再按时长过期
+	MongoCollection   string
+	MongoRetention    time.Duration
+	MongoCappedSizeMB int
+}
+
+// PolicyConfig 反滥用处置策略：按滥用评分、路由敏感级别与客户端信誉，
+// 由一个中间件统一决定放行、限速、人机验证、重新认证或拒绝
+type PolicyConfig struct {
+	// 按顺序匹配，命中第一条规则（没有规则时不做处置）
+	Rules []PolicyRule
+	// 路由敏感级别（按顺序匹配，未列出的路由为 low）
+	Routes []PolicyRoute
+
+	// 人机验证服务的校验地址与密钥（hCaptcha、reCAPTCHA、Turnstile 的 siteverify 接口）
+	CaptchaVerifyURL string
+	CaptchaSecret    string
+	// 返回给前端渲染验证组件的 site key
+	CaptchaSiteKey string
+	CaptchaTimeout time.Duration
+	// 验证通过后该 IP 在此时长内无需再次验证
+	CaptchaPassTTL time.Duration
+
+	// 重新认证：Token 的认证时间早于此时长时要求重新登录
+	StepUpMaxAge time.Duration
+}
+
+// SecurityStateConfig 安全状态快照：定时把生效中的封禁、限流热点 Key、过滤规则与功能开关写入 MongoDB，
+// 事后按时间查询当时的状态
+type SecurityStateConfig struct {
+	// 快照间隔（0 不定时快照，仍可手动快照）
+	SnapshotInterval time.Duration
+	// MongoDB 集合及快照保留时长（TTL 索引，0 不过期）
+	Collection string
+	Retention  time.Duration
+	// 快照中记录的限流热点 Key 数
+	HotKeys int
+}
+
+// WAFConfig WAF 规则引擎：规则来自内置特征、规则文件与数据库（管理后台维护），命中后记录、拦截或封禁
+type WAFConfig struct {
+	// 是否启用内置的 SQL 注入、XSS、路径遍历规则，及其动作（log、block、ban）
+	BuiltinRules  bool
+	BuiltinAction string
+	// 规则文件（JSON 数组：name、pattern、targets、action、reason），为空不加载
+	RulesFile string
+	// 请求体最多检查的字节数（0 不检查请求体）
+	MaxBodySize int64
+	// 定时重新加载规则的间隔（补偿遗漏的变更广播，并重新读取规则文件；0 不定时加载）
+	ReloadInterval time.Duration
+}
+
+// SettingsConfig 系统设置（settings 表中的键值，管理后台修改后热更新）
+type SettingsConfig struct {
+	// 定时重新加载的间隔（补偿遗漏的变更广播；0 不定时加载）
+	ReloadInterval time.Duration
+}
+
+// RolesConfig 管理员角色与权限（admin_roles 表，管理后台修改后热更新）
+type RolesConfig struct {
+	// 定时重新加载角色权限的间隔（补偿遗漏的变更广播；0 不定时加载）
+	ReloadInterval time.Duration
+}
+
+// UploadConfig 文件上传
+type UploadConfig struct {
+	// 存储后端：local（本地磁盘）或 s3（S3 兼容的对象存储，如 AWS S3、MinIO、阿里云 OSS）
+	Storage string
+	// 单个文件大小上限（字节）
+	MaxSize int64
+	// 允许的文件类型（按文件内容识别的 MIME 类型，支持 image/* 通配）
+	AllowedTypes []string
+	// 下载链接的签名密钥（为空时由 JWT_SECRET_KEY 派生，见 secrets.DeriveKey）与有效期
+	URLSecret string
+	URLExpiry time.Duration
+	// 本地存储目录
+	LocalDir string
+	// S3 兼容存储：服务地址（如 https://s3.us-east-1.amazonaws.com、https://oss-cn-hangzhou.aliyuncs.com）、区域、存储桶与访问密钥
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	// 使用路径形式（{endpoint}/{bucket}/{key}，MinIO 等自建服务常用），否则使用虚拟主机形式（{bucket}.{endpoint}/{key}）
+	S3PathStyle bool
+	// 请求 S3 的超时
+	S3Timeout time.Duration
+}
+
+// DashboardConfig 管理后台首页统计
+type DashboardConfig struct {
+	// 统计结果在本实例的缓存时间（账号数、今日请求、有效会话、最近的安全事件）
+	StatsCacheTTL time.Duration
+}
+
+// PolicyRule 处置规则（所有条件同时满足时命中，未设置的条件不限制）
+type PolicyRule struct {
+	// 原始规则文本（用于日志与指标）
+	Name string
+	// 滥用评分下限（0 不限制）
+	MinScore int64
+	// 路由敏感级别下限：low < medium < high < critical（为空不限制）
+	MinSensitivity string
+	// 客户端信誉标记，如 listed（命中 IP 信誉名单）、clean（未命中）
+	Reputation string
+	// 处置动作：allow, throttle, captcha, step_up, block
+	Action string
+	// throttle 的限额
+	MaxRequests int
+	Window      time.Duration
+}
+
+// PolicyRoute 路由敏感级别
+type PolicyRoute struct {
+	// 路由（"[METHOD ]/path"，路径支持末尾 * 通配）
+	Route string
+	// 敏感级别：low, medium, high, critical
+	Level string
+}
+
+// PolicySensitivityLevels 路由敏感级别（由低到高）
+var PolicySensitivityLevels = []string{"low", "medium", "high", "critical"}
+
 // LoadConfig 加载配置（从环境变量）
 func LoadConfig() *Config {
-	return &Config{
+	cfg := &Config{
 		Server: ServerConfig{
 			Port: getEnv("PORT", "8080"),
 			Mode: getEnv("GIN_MODE", "debug"),
+
+			Version: getEnv("APP_VERSION", "1.0.0"),
+
+			BaseURL:        getEnv("APP_BASE_URL", ""),
+			TrustedProxies: getSliceEnv("TRUSTED_PROXIES", []string{"127.0.0.1", "::1"}),
+			HTTPSRedirect:  getBoolEnv("HTTPS_REDIRECT", false),
+
+			AppMode:    getEnv("APP_MODE", ""),
+			SQLitePath: getEnv("SQLITE_PATH", "data/openclaw.db"),
+
+			ETagEnabled:      getBoolEnv("ETAG_ENABLED", true),
+			ETagMaxSize:      getIntEnv("ETAG_MAX_SIZE", 1<<20),
+			ETagExcludePaths: getSliceEnv("ETAG_EXCLUDE_PATHS", []string{}),
 		},
 		MySQL: MySQLConfig{
 			Host:     getEnv("MYSQL_HOST", "localhost"),
@@ -98,30 +940,511 @@ func LoadConfig() *Config {
 		},
 		Security: SecurityConfig{
 			// JWT 配置
-			JWTSecretKey:     getEnv("JWT_SECRET_KEY", "your-secret-key-change-in-production"),
+			JWTSecretKey:     getEnv("JWT_SECRET_KEY", DefaultJWTSecretKey),
 			JWTExpiry:        getDurationEnv("JWT_EXPIRY", time.Hour*24),
 			JWTRefreshExpiry: getDurationEnv("JWT_REFRESH_EXPIRY", time.Hour*24*7),
 			JWTIssuer:        getEnv("JWT_ISSUER", "new-openclaw"),
 
+			JWTSigningMethod:  getEnv("JWT_SIGNING_METHOD", "HS256"),
+			JWTPrivateKeyFile: getEnv("JWT_PRIVATE_KEY_FILE", ""),
+			JWTPublicKeyFile:  getEnv("JWT_PUBLIC_KEY_FILE", ""),
+			JWTKeyID:          getEnv("JWT_KEY_ID", ""),
+			JWTVerifyKeysDir:  getEnv("JWT_VERIFY_KEYS_DIR", ""),
+
+			AdminJWTSecretKey:      getEnv("ADMIN_JWT_SECRET_KEY", "openclaw-admin-secret-key-2024"),
+			AdminJWTSigningMethod:  getEnv("ADMIN_JWT_SIGNING_METHOD", "HS256"),
+			AdminJWTPrivateKeyFile: getEnv("ADMIN_JWT_PRIVATE_KEY_FILE", ""),
+			AdminJWTPublicKeyFile:  getEnv("ADMIN_JWT_PUBLIC_KEY_FILE", ""),
+			AdminJWTKeyID:          getEnv("ADMIN_JWT_KEY_ID", ""),
+			AdminJWTVerifyKeysDir:  getEnv("ADMIN_JWT_VERIFY_KEYS_DIR", ""),
+
+			JWTLeeway: getDurationEnv("JWT_LEEWAY", 5*time.Second),
+
+			AdminMaxSessions:        getIntEnv("ADMIN_MAX_SESSIONS", 0),
+			AdminSessionLimitPolicy: getEnv("ADMIN_SESSION_LIMIT_POLICY", "revoke_oldest"),
+			AdminSingleSession:      getBoolEnv("ADMIN_SINGLE_SESSION", false),
+
+			AdminAuthMode:               getEnv("ADMIN_AUTH_MODE", "jwt"),
+			AdminSessionIdleTimeout:     getDurationEnv("ADMIN_SESSION_IDLE_TIMEOUT", 30*time.Minute),
+			AdminSessionAbsoluteTimeout: getDurationEnv("ADMIN_SESSION_ABSOLUTE_TIMEOUT", 12*time.Hour),
+
+			JWTCookieName:      getEnv("JWT_COOKIE_NAME", ""),
+			AdminJWTCookieName: getEnv("ADMIN_JWT_COOKIE_NAME", ""),
+			JWTCookieDomain:    getEnv("JWT_COOKIE_DOMAIN", ""),
+			JWTCookieSecure:    getBoolEnv("JWT_COOKIE_SECURE", true),
+			JWTCookieSameSite:  getEnv("JWT_COOKIE_SAMESITE", "lax"),
+			CSRFCookieName:     getEnv("CSRF_COOKIE_NAME", "csrf_token"),
+			CSRFHeaderName:     getEnv("CSRF_HEADER_NAME", "X-CSRF-Token"),
+
 			// 频率限制配置
 			RateLimitWindow:      getDurationEnv("RATE_LIMIT_WINDOW", time.Minute),
 			RateLimitMaxRequests: getIntEnv("RATE_LIMIT_MAX_REQUESTS", 60),
+			RateLimitAlgorithm:   getEnv("RATE_LIMIT_ALGORITHM", "fixed_window"),
+			RateLimitBurst:       getIntEnv("RATE_LIMIT_BURST", 0),
+			RateLimitRules:       getRateLimitRulesEnv("RATE_LIMIT_RULES", []RateLimitRule{}),
+
+			RateLimitExemptCIDRs:   getSliceEnv("RATE_LIMIT_EXEMPT_CIDRS", []string{}),
+			RateLimitExemptAppKeys: getSliceEnv("RATE_LIMIT_EXEMPT_APP_KEYS", []string{}),
+			RateLimitExemptRoles:   getSliceEnv("RATE_LIMIT_EXEMPT_ROLES", []string{}),
+			RateLimitWarnThreshold: getFloatEnv("RATE_LIMIT_WARN_THRESHOLD", 0.8),
+			RateLimitEnforceAfter:  getTimeEnv("RATE_LIMIT_ENFORCE_AFTER", time.Time{}),
 
 			// API 签名配置
 			APISignatureKey:    getEnv("API_SIGNATURE_KEY", "your-api-secret-key"),
 			APISignatureExpiry: getDurationEnv("API_SIGNATURE_EXPIRY", time.Minute*5),
 
+			APISignatureAlgorithm:     getEnv("API_SIGNATURE_ALGORITHM", "hmac-sha256"),
+			APISignatureSignedHeaders: getSliceEnv("API_SIGNATURE_SIGNED_HEADERS", []string{"content-type"}),
+
 			// IP 过滤配置
 			IPWhitelistMode: getBoolEnv("IP_WHITELIST_MODE", false),
 			IPWhitelist:     getSliceEnv("IP_WHITELIST", []string{}),
 			IPBlacklist:     getSliceEnv("IP_BLACKLIST", []string{}),
 
+			IPRuleReloadInterval: getDurationEnv("IP_RULES_RELOAD_INTERVAL", 5*time.Minute),
+			GeoIPASNDatabase:     getEnv("GEOIP_ASN_DB", ""),
+			GeoIPCountryDatabase: getEnv("GEOIP_COUNTRY_DB", ""),
+			GeoIPReloadInterval:  getDurationEnv("GEOIP_RELOAD_INTERVAL", time.Hour),
+
 			// 审计配置
-			AuditEnabled:  getBoolEnv("AUDIT_ENABLED", true),
-			AuditOutput:   getEnv("AUDIT_OUTPUT", "both"),
-			AuditFilePath: getEnv("AUDIT_FILE_PATH", "logs/audit.log"),
+			AuditEnabled:      getBoolEnv("AUDIT_ENABLED", true),
+			AuditOutput:       getEnv("AUDIT_OUTPUT", "both"),
+			AuditFilePath:     getEnv("AUDIT_FILE_PATH", "logs/audit.log"),
+			AuditFallbackPath: getEnv("AUDIT_FALLBACK_PATH", ""),
+
+			AuditRotateMaxSizeMB:  getIntEnv("AUDIT_ROTATE_MAX_SIZE_MB", 100),
+			AuditRotateInterval:   getDurationEnv("AUDIT_ROTATE_INTERVAL", 24*time.Hour),
+			AuditRotateMaxAge:     getDurationEnv("AUDIT_ROTATE_MAX_AGE", 30*24*time.Hour),
+			AuditRotateMaxBackups: getIntEnv("AUDIT_ROTATE_MAX_BACKUPS", 0),
+			AuditRotateCompress:   getBoolEnv("AUDIT_ROTATE_COMPRESS", true),
+
+			AuditSampleRate:       getRateEnv("AUDIT_SAMPLE_RATE", 1),
+			AuditBodySampleRate:   getRateEnv("AUDIT_BODY_SAMPLE_RATE", 1),
+			AuditPathRules:        getAuditPathRulesEnv("AUDIT_PATH_RULES", nil),
+			AuditSampleKeepErrors: getBoolEnv("AUDIT_SAMPLE_KEEP_ERRORS", true),
+
+			AuditMaskPatterns:    getPatternsEnv("AUDIT_MASK_PATTERNS", nil),
+			AuditMaskJSONPaths:   getSliceEnv("AUDIT_MASK_JSON_PATHS", nil),
+			AuditMaskQueryParams: getSliceEnv("AUDIT_MASK_QUERY_PARAMS", nil),
+			AuditMaskHeaders:     getSliceEnv("AUDIT_MASK_HEADERS", nil),
+
+			AbuseWindow:       getDurationEnv("ABUSE_SCORE_WINDOW", 10*time.Minute),
+			AbuseBanThreshold: int64(getIntEnv("ABUSE_BAN_THRESHOLD", 50)),
+			AbuseBanTTL:       getDurationEnv("ABUSE_BAN_TTL", time.Hour),
+			HoneypotPaths: getSliceEnv("HONEYPOT_PATHS", []string{
+				"/wp-login.php", "/wp-admin*", "/xmlrpc.php", "/.env*", "/.git/*", "/phpmyadmin*", "/pma/*", "/cgi-bin/*",
+			}),
+			HoneypotBan: getBoolEnv("HONEYPOT_BAN", true),
+
+			PIIRedactFields: getStringMapEnv("PII_REDACT_FIELDS", map[string]string{
+				"email": "mask", "phone": "mask", "last_login_ip": "mask",
+			}),
+			PIIReadAdminRoles: getSliceEnv("ADMIN_PII_READ_ROLES", []string{"super_admin"}),
+		},
+		Store: StoreConfig{
+			NonceBackend:       getEnv("NONCE_STORE", "redis"),
+			SessionBackend:     getEnv("SESSION_STORE", "redis"),
+			RateLimitBackend:   getEnv("RATE_LIMIT_STORE", "memory"),
+			QuotaBackend:       getEnv("QUOTA_STORE", "redis"),
+			RevocationBackend:  getEnv("REVOCATION_STORE", "redis"),
+			AbuseBackend:       getEnv("ABUSE_STORE", "memory"),
+			JobsBackend:        getEnv("JOBS_STORE", "memory"),
+			AppKeyBackend:      getEnv("APPKEY_CACHE_STORE", "redis"),
+			LoginBackend:       getEnv("LOGIN_STORE", "redis"),
+			MaintenanceBackend: getEnv("MAINTENANCE_STORE", "redis"),
+			APIKeyBackend:      getEnv("APIKEY_STORE", "redis"),
+			CaptchaBackend:     getEnv("CAPTCHA_STORE", "redis"),
+
+			PasswordResetBackend: getEnv("PASSWORD_RESET_STORE", "redis"),
+			WebAuthnBackend:      getEnv("WEBAUTHN_STORE", "redis"),
+		},
+		Analytics: AnalyticsConfig{
+			WorkHourStart:           getIntEnv("ANOMALY_WORK_HOUR_START", 9),
+			WorkHourEnd:             getIntEnv("ANOMALY_WORK_HOUR_END", 19),
+			BulkDeleteThreshold:     getIntEnv("ANOMALY_BULK_DELETE_THRESHOLD", 10),
+			BulkDeleteWindow:        getDurationEnv("ANOMALY_BULK_DELETE_WINDOW", 10*time.Minute),
+			PermissionEditThreshold: getIntEnv("ANOMALY_PERMISSION_EDIT_THRESHOLD", 5),
+			PermissionEditWindow:    getDurationEnv("ANOMALY_PERMISSION_EDIT_WINDOW", time.Hour),
+			AlertWebhook:            getEnv("ANOMALY_ALERT_WEBHOOK", ""),
+			AlertInterval:           getDurationEnv("ANOMALY_ALERT_INTERVAL", 10*time.Minute),
+		},
+		Observability: ObservabilityConfig{
+			SlowRequestThreshold: getDurationEnv("SLOW_REQUEST_THRESHOLD", time.Second),
+			SlowRequestRoutes:    getDurationMapEnv("SLOW_REQUEST_ROUTES", map[string]time.Duration{}),
+			SlowRequestDetail:    getBoolEnv("SLOW_REQUEST_DETAIL", false),
+		},
+		Protection: ProtectionConfig{
+			OverloadEnabled: getBoolEnv("OVERLOAD_PROTECTION", true),
+			MaxGoroutines:   getIntEnv("OVERLOAD_MAX_GOROUTINES", 10000),
+			MaxHeapMB:       getIntEnv("OVERLOAD_MAX_HEAP_MB", 1024),
+			MaxSchedLatency: getDurationEnv("OVERLOAD_MAX_SCHED_LATENCY", 100*time.Millisecond),
+
+			CriticalRoutes:       getSliceEnv("PRIORITY_CRITICAL_ROUTES", []string{"POST /api/v1/public/login", "POST /admin/login"}),
+			BulkRoutes:           getSliceEnv("PRIORITY_BULK_ROUTES", []string{"/admin/analytics/*"}),
+			CriticalConcurrency:  getIntEnv("PRIORITY_CRITICAL_CONCURRENCY", 0),
+			NormalConcurrency:    getIntEnv("PRIORITY_NORMAL_CONCURRENCY", 500),
+			BulkConcurrency:      getIntEnv("PRIORITY_BULK_CONCURRENCY", 10),
+			PriorityQueueTimeout: getDurationEnv("PRIORITY_QUEUE_TIMEOUT", 2*time.Second),
+
+			MaxBodySize:     int64(getIntEnv("MAX_BODY_SIZE", 10*1024*1024)),
+			RouteBodyLimits: getSizeMapEnv("ROUTE_BODY_LIMITS", map[string]int64{}),
+
+			MaxInFlight:             getIntEnv("CONCURRENCY_MAX_IN_FLIGHT", 0),
+			MaxInFlightPerIP:        getIntEnv("CONCURRENCY_MAX_PER_IP", 0),
+			ConcurrencyQueueTimeout: getDurationEnv("CONCURRENCY_QUEUE_TIMEOUT", 500*time.Millisecond),
+		},
+		Discovery: DiscoveryConfig{
+			Provider:        getEnv("DISCOVERY_PROVIDER", ""),
+			Endpoint:        getEnv("DISCOVERY_ENDPOINT", ""),
+			ServiceName:     getEnv("DISCOVERY_SERVICE_NAME", "new-openclaw"),
+			ServiceAddress:  getEnv("DISCOVERY_SERVICE_ADDRESS", ""),
+			HealthCheckPath: getEnv("DISCOVERY_HEALTH_PATH", "/health"),
+			TTL:             getDurationEnv("DISCOVERY_TTL", 15*time.Second),
+			Namespace:       getEnv("DISCOVERY_NAMESPACE", ""),
+			Metadata:        getStringMapEnv("DISCOVERY_METADATA", map[string]string{}),
+		},
+		ConfigCenter: ConfigCenterConfig{
+			Provider:  getEnv("CONFIG_CENTER_PROVIDER", ""),
+			Endpoint:  getEnv("CONFIG_CENTER_ENDPOINT", ""),
+			Key:       getEnv("CONFIG_CENTER_KEY", "/config/new-openclaw"),
+			Group:     getEnv("CONFIG_CENTER_GROUP", "DEFAULT_GROUP"),
+			Namespace: getEnv("CONFIG_CENTER_NAMESPACE", ""),
+		},
+		Leader: LeaderConfig{
+			Backend:  getEnv("LEADER_ELECTION", ""),
+			Endpoint: getEnv("LEADER_ELECTION_ENDPOINT", ""),
+			Key:      getEnv("LEADER_ELECTION_KEY", "openclaw:leader"),
+			TTL:      getDurationEnv("LEADER_ELECTION_TTL", 15*time.Second),
+		},
+		Quota: QuotaConfig{
+			DailyLimit:   int64(getIntEnv("QUOTA_DAILY_LIMIT", 0)),
+			MonthlyLimit: int64(getIntEnv("QUOTA_MONTHLY_LIMIT", 0)),
+		},
+		Password: PasswordConfig{
+			Algorithm:  getEnv("PASSWORD_HASH_ALGORITHM", "bcrypt"),
+			BcryptCost: getIntEnv("PASSWORD_BCRYPT_COST", 10),
+
+			Argon2Memory:      getIntEnv("PASSWORD_ARGON2_MEMORY_KB", 64*1024),
+			Argon2Iterations:  getIntEnv("PASSWORD_ARGON2_ITERATIONS", 3),
+			Argon2Parallelism: getIntEnv("PASSWORD_ARGON2_PARALLELISM", 2),
+
+			ScryptN: getIntEnv("PASSWORD_SCRYPT_N", 32768),
+			ScryptR: getIntEnv("PASSWORD_SCRYPT_R", 8),
+			ScryptP: getIntEnv("PASSWORD_SCRYPT_P", 1),
+
+			MinLength:  getIntEnv("PASSWORD_MIN_LENGTH", 8),
+			MaxLength:  getIntEnv("PASSWORD_MAX_LENGTH", 72),
+			MinClasses: getIntEnv("PASSWORD_MIN_CLASSES", 2),
+		},
+		Secrets: SecretsConfig{
+			KEK:         getEnv("SECRETS_KEK", ""),
+			KEKFile:     getEnv("SECRETS_KEK_FILE", ""),
+			KEKVersion:  getEnv("SECRETS_KEK_VERSION", "v1"),
+			RetiredKEKs: getStringMapEnv("SECRETS_KEK_RETIRED", map[string]string{}),
+		},
+		// 兼容旧的 NOTIFY_SMTP_* 配置
+		Mail: MailConfig{
+			SMTPHost:     getEnv("MAIL_SMTP_HOST", getEnv("NOTIFY_SMTP_HOST", "")),
+			SMTPPort:     getIntEnv("MAIL_SMTP_PORT", getIntEnv("NOTIFY_SMTP_PORT", 587)),
+			SMTPUsername: getEnv("MAIL_SMTP_USERNAME", getEnv("NOTIFY_SMTP_USERNAME", "")),
+			SMTPPassword: getEnv("MAIL_SMTP_PASSWORD", getEnv("NOTIFY_SMTP_PASSWORD", "")),
+			From:         getEnv("MAIL_FROM", getEnv("NOTIFY_FROM", "")),
+		},
+		Notify: NotifyConfig{
+			DigestInterval: getDurationEnv("NOTIFY_DIGEST_INTERVAL", time.Minute),
+		},
+		Replay: ReplayConfig{
+			Targets:         getStringMapEnv("REPLAY_TARGETS", map[string]string{}),
+			DefaultTarget:   getEnv("REPLAY_DEFAULT_TARGET", "staging"),
+			SignatureSecret: getEnv("REPLAY_SIGNATURE_SECRET", ""),
+			IgnoreFields:    getSliceEnv("REPLAY_IGNORE_FIELDS", []string{"timestamp", "request_id", "trace_id"}),
+			Timeout:         getDurationEnv("REPLAY_TIMEOUT", 10*time.Second),
+		},
+		AppKey: AppKeyConfig{
+			CacheTTL:       getDurationEnv("APPKEY_CACHE_TTL", 5*time.Minute),
+			GlobalFallback: getBoolEnv("API_SIGNATURE_GLOBAL_FALLBACK", true),
+		},
+		APIKey: APIKeyConfig{
+			CacheTTL:         getDurationEnv("APIKEY_CACHE_TTL", 5*time.Minute),
+			LastUsedInterval: getDurationEnv("APIKEY_LAST_USED_INTERVAL", time.Minute),
+		},
+		PersonalToken: PersonalTokenConfig{
+			MaxTTL:     getDurationEnv("PAT_MAX_TTL", 365*24*time.Hour),
+			MaxPerUser: getIntEnv("PAT_MAX_PER_USER", 20),
+			CacheTTL:   getDurationEnv("PAT_CACHE_TTL", time.Minute),
+		},
+		Captcha: CaptchaConfig{
+			Enabled:    getBoolEnv("CAPTCHA_ENABLED", true),
+			Provider:   getEnv("CAPTCHA_PROVIDER", "image"),
+			VerifyURL:  getEnv("CAPTCHA_VERIFY_URL", ""),
+			Secret:     getEnv("CAPTCHA_SECRET", ""),
+			SiteKey:    getEnv("CAPTCHA_SITE_KEY", ""),
+			Timeout:    getDurationEnv("CAPTCHA_TIMEOUT", 5*time.Second),
+			Length:     getIntEnv("CAPTCHA_LENGTH", 5),
+			TTL:        getDurationEnv("CAPTCHA_TTL", 2*time.Minute),
+			LoginAfter: getIntEnv("CAPTCHA_LOGIN_AFTER", 3),
+			Register:   getBoolEnv("CAPTCHA_REGISTER", true),
+		},
+		EmailVerify: EmailVerifyConfig{
+			Secret:         getEnv("EMAIL_VERIFY_SECRET", ""),
+			TTL:            getDurationEnv("EMAIL_VERIFY_TTL", 24*time.Hour),
+			ResendInterval: getDurationEnv("EMAIL_VERIFY_RESEND_INTERVAL", time.Minute),
+			RedirectURL:    getEnv("EMAIL_VERIFY_REDIRECT_URL", ""),
+		},
+		LoginHistory: LoginHistoryConfig{
+			Enabled:         getBoolEnv("LOGIN_HISTORY_ENABLED", true),
+			Retention:       getDurationEnv("LOGIN_HISTORY_RETENTION", 90*24*time.Hour),
+			NotifyNewDevice: getBoolEnv("LOGIN_HISTORY_NOTIFY_NEW_DEVICE", true),
+		},
+		WebAuthn: WebAuthnConfig{
+			RPID:                    getEnv("WEBAUTHN_RP_ID", ""),
+			RPName:                  getEnv("WEBAUTHN_RP_NAME", "OpenClaw 管理后台"),
+			Origins:                 getSliceEnv("WEBAUTHN_ORIGINS", nil),
+			Timeout:                 getDurationEnv("WEBAUTHN_TIMEOUT", 2*time.Minute),
+			RequireUserVerification: getBoolEnv("WEBAUTHN_REQUIRE_USER_VERIFICATION", true),
+			RequiredRoles:           getSliceEnv("WEBAUTHN_REQUIRED_ROLES", nil),
+		},
+		SAML: SAMLConfig{
+			EntityID:          getEnv("SAML_SP_ENTITY_ID", ""),
+			ACSURL:            getEnv("SAML_ACS_URL", ""),
+			IdPEntityID:       getEnv("SAML_IDP_ENTITY_ID", ""),
+			IdPSSOURL:         getEnv("SAML_IDP_SSO_URL", ""),
+			IdPCertFile:       getEnv("SAML_IDP_CERT_FILE", ""),
+			IdPCert:           getEnv("SAML_IDP_CERT", ""),
+			UsernameAttribute: getEnv("SAML_USERNAME_ATTRIBUTE", ""),
+			EmailAttribute:    getEnv("SAML_EMAIL_ATTRIBUTE", "email"),
+			GroupsAttribute:   getEnv("SAML_GROUPS_ATTRIBUTE", "groups"),
+			RoleGroups:        getListMapEnv("SAML_ROLE_GROUPS", map[string][]string{}),
+			DefaultRole:       getEnv("SAML_DEFAULT_ROLE", ""),
+			AutoCreate:        getBoolEnv("SAML_AUTO_CREATE", true),
+			AllowIdPInitiated: getBoolEnv("SAML_ALLOW_IDP_INITIATED", false),
+			AllowedRedirects:  getSliceEnv("SAML_ALLOWED_REDIRECTS", nil),
+			RequestTTL:        getDurationEnv("SAML_REQUEST_TTL", 10*time.Minute),
+			ClockSkew:         getDurationEnv("SAML_CLOCK_SKEW", 2*time.Minute),
+		},
+		Impersonation: ImpersonationConfig{
+			TTL: getDurationEnv("IMPERSONATION_TTL", 30*time.Minute),
+		},
+		PasswordReset: PasswordResetConfig{
+			TTL:            getDurationEnv("PASSWORD_RESET_TTL", 30*time.Minute),
+			ResendInterval: getDurationEnv("PASSWORD_RESET_RESEND_INTERVAL", time.Minute),
+			URL:            getEnv("PASSWORD_RESET_URL", ""),
+		},
+		BreakGlass: BreakGlassConfig{
+			Username:     getEnv("BREAK_GLASS_USERNAME", "break-glass"),
+			PasswordHash: getEnv("BREAK_GLASS_PASSWORD_HASH", ""),
+			TTL:          getDurationEnv("BREAK_GLASS_TTL", time.Hour),
+			TrailFile:    getEnv("BREAK_GLASS_TRAIL_FILE", "logs/break-glass.log"),
+			AlertEmails:  getSliceEnv("BREAK_GLASS_ALERT_EMAILS", nil),
+		},
+		Health: HealthConfig{
+			CheckInterval: getDurationEnv("HEALTH_CHECK_INTERVAL", 30*time.Second),
+			AlertEmails:   getSliceEnv("HEALTH_ALERT_EMAILS", nil),
+		},
+		Webhook: WebhookConfig{
+			MaxAttempts:    getIntEnv("WEBHOOK_MAX_ATTEMPTS", 8),
+			InitialBackoff: getDurationEnv("WEBHOOK_INITIAL_BACKOFF", 30*time.Second),
+			MaxBackoff:     getDurationEnv("WEBHOOK_MAX_BACKOFF", time.Hour),
+			Timeout:        getDurationEnv("WEBHOOK_TIMEOUT", 10*time.Second),
+			Workers:        getIntEnv("WEBHOOK_WORKERS", 4),
+			RetryInterval:  getDurationEnv("WEBHOOK_RETRY_INTERVAL", 30*time.Second),
+		},
+		ThreatFeed: ThreatFeedConfig{
+			AppKeys: getSliceEnv("THREAT_FEED_APP_KEYS", nil),
+			Source:  getEnv("THREAT_FEED_SOURCE", "new-openclaw"),
+		},
+		Reputation: ReputationConfig{
+			Enabled: getBoolEnv("IP_REPUTATION_ENABLED", false),
+			Feeds: getSliceEnv("IP_REPUTATION_FEEDS", []string{
+				"https://feodotracker.abuse.ch/downloads/ipblocklist.txt",
+				"https://iplists.firehol.org/files/firehol_level1.netset",
+			}),
+			Interval:   getDurationEnv("IP_REPUTATION_INTERVAL", time.Hour),
+			Timeout:    getDurationEnv("IP_REPUTATION_TIMEOUT", 30*time.Second),
+			MaxEntries: getIntEnv("IP_REPUTATION_MAX_ENTRIES", 200000),
+			Action:     getEnv("IP_REPUTATION_ACTION", "block"),
+		},
+		AuditSinks: AuditSinksConfig{
+			Enabled:       getSliceEnv("AUDIT_SINKS", nil),
+			BatchSize:     getIntEnv("AUDIT_SINK_BATCH_SIZE", 100),
+			FlushInterval: getDurationEnv("AUDIT_SINK_FLUSH_INTERVAL", 2*time.Second),
+			BufferSize:    getIntEnv("AUDIT_SINK_BUFFER_SIZE", 10000),
+			Timeout:       getDurationEnv("AUDIT_SINK_TIMEOUT", 10*time.Second),
+
+			ElasticsearchURL:      getEnv("AUDIT_ES_URL", "http://localhost:9200"),
+			ElasticsearchIndex:    getEnv("AUDIT_ES_INDEX", "openclaw-audit-{date}"),
+			ElasticsearchUsername: getEnv("AUDIT_ES_USERNAME", ""),
+			ElasticsearchPassword: getEnv("AUDIT_ES_PASSWORD", ""),
+			ElasticsearchAPIKey:   getEnv("AUDIT_ES_API_KEY", ""),
+
+			KafkaRESTURL: getEnv("AUDIT_KAFKA_REST_URL", "http://localhost:8082"),
+			KafkaTopic:   getEnv("AUDIT_KAFKA_TOPIC", "openclaw-audit"),
+
+			SyslogAddr:     getEnv("AUDIT_SYSLOG_ADDR", "udp://localhost:514"),
+			SyslogTag:      getEnv("AUDIT_SYSLOG_TAG", "openclaw-audit"),
+			SyslogFacility: getEnv("AUDIT_SYSLOG_FACILITY", "local0"),
+
+			MongoCollection:   getEnv("AUDIT_MONGO_COLLECTION", "audit_logs"),
+			MongoRetention:    getDurationEnv("AUDIT_MONGO_RETENTION", 30*24*time.Hour),
+			MongoCappedSizeMB: getIntEnv("AUDIT_MONGO_CAPPED_SIZE_MB", 0),
+		},
+		Policy: PolicyConfig{
+			Rules:  getPolicyRulesEnv("POLICY_RULES", nil),
+			Routes: getPolicyRoutesEnv("POLICY_SENSITIVE_ROUTES", nil),
+
+			CaptchaVerifyURL: getEnv("POLICY_CAPTCHA_VERIFY_URL", "https://hcaptcha.com/siteverify"),
+			CaptchaSecret:    getEnv("POLICY_CAPTCHA_SECRET", ""),
+			CaptchaSiteKey:   getEnv("POLICY_CAPTCHA_SITE_KEY", ""),
+			CaptchaTimeout:   getDurationEnv("POLICY_CAPTCHA_TIMEOUT", 5*time.Second),
+			CaptchaPassTTL:   getDurationEnv("POLICY_CAPTCHA_PASS_TTL", 30*time.Minute),
+
+			StepUpMaxAge: getDurationEnv("POLICY_STEP_UP_MAX_AGE", 5*time.Minute),
+		},
+		SecurityState: SecurityStateConfig{
+			SnapshotInterval: getDurationEnv("SECURITY_SNAPSHOT_INTERVAL", 5*time.Minute),
+			Collection:       getEnv("SECURITY_SNAPSHOT_COLLECTION", "security_snapshots"),
+			Retention:        getDurationEnv("SECURITY_SNAPSHOT_RETENTION", 30*24*time.Hour),
+			HotKeys:          getIntEnv("SECURITY_SNAPSHOT_HOT_KEYS", 50),
+		},
+		WAF: WAFConfig{
+			BuiltinRules:   getBoolEnv("WAF_BUILTIN_RULES", true),
+			BuiltinAction:  getEnv("WAF_BUILTIN_ACTION", "log"),
+			RulesFile:      getEnv("WAF_RULES_FILE", ""),
+			MaxBodySize:    int64(getIntEnv("WAF_MAX_BODY_SIZE", 64*1024)),
+			ReloadInterval: getDurationEnv("WAF_RELOAD_INTERVAL", time.Minute),
+		},
+		Settings: SettingsConfig{
+			ReloadInterval: getDurationEnv("SETTINGS_RELOAD_INTERVAL", time.Minute),
+		},
+		Dashboard: DashboardConfig{
+			StatsCacheTTL: getDurationEnv("DASHBOARD_STATS_CACHE_TTL", time.Minute),
+		},
+		Roles: RolesConfig{
+			ReloadInterval: getDurationEnv("ADMIN_ROLES_RELOAD_INTERVAL", time.Minute),
+		},
+		Upload: UploadConfig{
+			Storage:      getEnv("UPLOAD_STORAGE", "local"),
+			MaxSize:      int64(getIntEnv("UPLOAD_MAX_SIZE", 10*1024*1024)),
+			AllowedTypes: getSliceEnv("UPLOAD_ALLOWED_TYPES", []string{"image/jpeg", "image/png", "image/gif", "image/webp", "application/pdf"}),
+			URLSecret:    getEnv("UPLOAD_URL_SECRET", ""),
+			URLExpiry:    getDurationEnv("UPLOAD_URL_EXPIRY", time.Hour),
+			LocalDir:     getEnv("UPLOAD_LOCAL_DIR", "data/uploads"),
+			S3Endpoint:   getEnv("UPLOAD_S3_ENDPOINT", ""),
+			S3Region:     getEnv("UPLOAD_S3_REGION", "us-east-1"),
+			S3Bucket:     getEnv("UPLOAD_S3_BUCKET", ""),
+			S3AccessKey:  getEnv("UPLOAD_S3_ACCESS_KEY", ""),
+			S3SecretKey:  getEnv("UPLOAD_S3_SECRET_KEY", ""),
+			S3PathStyle:  getBoolEnv("UPLOAD_S3_PATH_STYLE", false),
+			S3Timeout:    getDurationEnv("UPLOAD_S3_TIMEOUT", 30*time.Second),
+		},
+		SecurityEvents: SecurityEventsConfig{
+			Store:      getEnv("SECURITY_EVENTS_STORE", "mysql"),
+			Collection: getEnv("SECURITY_EVENTS_COLLECTION", "security_events"),
+			// 兼容原 THREAT_FEED_RETENTION
+			Retention:          getDurationEnv("SECURITY_EVENTS_RETENTION", getDurationEnv("THREAT_FEED_RETENTION", 30*24*time.Hour)),
+			RecordAuthFailures: getBoolEnv("SECURITY_EVENTS_AUTH_FAILURES", true),
+
+			AlertMinSeverity:  getEnv("SECURITY_ALERT_MIN_SEVERITY", "high"),
+			AlertWebhook:      getEnv("SECURITY_ALERT_WEBHOOK", ""),
+			DingTalkWebhook:   getEnv("SECURITY_ALERT_DINGTALK_WEBHOOK", ""),
+			DingTalkSecret:    getEnv("SECURITY_ALERT_DINGTALK_SECRET", ""),
+			SlackWebhook:      getEnv("SECURITY_ALERT_SLACK_WEBHOOK", ""),
+			AlertEmail:        getBoolEnv("SECURITY_ALERT_EMAIL", false),
+			AlertDedupeWindow: getDurationEnv("SECURITY_ALERT_DEDUPE_WINDOW", 10*time.Minute),
+			AlertMaxPerMinute: getIntEnv("SECURITY_ALERT_MAX_PER_MINUTE", 30),
+			AlertTimeout:      getDurationEnv("SECURITY_ALERT_TIMEOUT", 5*time.Second),
+		},
+		Validation: ValidationConfig{
+			Enabled:           getBoolEnv("REQUEST_VALIDATION_ENABLED", true),
+			ContentTypes:      getSliceEnv("REQUEST_CONTENT_TYPES", []string{"application/json", "application/x-www-form-urlencoded", "multipart/form-data"}),
+			RouteContentTypes: getListMapEnv("ROUTE_CONTENT_TYPES", map[string][]string{}),
+			SchemaFile:        getEnv("REQUEST_SCHEMA_FILE", ""),
+			MaxErrors:         getIntEnv("REQUEST_SCHEMA_MAX_ERRORS", 20),
+		},
+		OAuth: OAuthConfig{
+			CallbackBaseURL:  getEnv("OAUTH_CALLBACK_BASE_URL", ""),
+			AllowedRedirects: getSliceEnv("OAUTH_ALLOWED_REDIRECTS", nil),
+			StateTTL:         getDurationEnv("OAUTH_STATE_TTL", 10*time.Minute),
+			AutoRegister:     getBoolEnv("OAUTH_AUTO_REGISTER", true),
+			Timeout:          getDurationEnv("OAUTH_TIMEOUT", 10*time.Second),
+			Google: OAuthProviderConfig{
+				ClientID:     getEnv("OAUTH_GOOGLE_CLIENT_ID", ""),
+				ClientSecret: getEnv("OAUTH_GOOGLE_CLIENT_SECRET", ""),
+				Scopes:       getSliceEnv("OAUTH_GOOGLE_SCOPES", []string{"openid", "email", "profile"}),
+			},
+			GitHub: OAuthProviderConfig{
+				ClientID:     getEnv("OAUTH_GITHUB_CLIENT_ID", ""),
+				ClientSecret: getEnv("OAUTH_GITHUB_CLIENT_SECRET", ""),
+				Scopes:       getSliceEnv("OAUTH_GITHUB_SCOPES", []string{"read:user", "user:email"}),
+			},
+			OIDC: OAuthProviderConfig{
+				Name:         getEnv("OAUTH_OIDC_NAME", "oidc"),
+				Issuer:       getEnv("OAUTH_OIDC_ISSUER", ""),
+				ClientID:     getEnv("OAUTH_OIDC_CLIENT_ID", ""),
+				ClientSecret: getEnv("OAUTH_OIDC_CLIENT_SECRET", ""),
+				Scopes:       getSliceEnv("OAUTH_OIDC_SCOPES", []string{"openid", "email", "profile"}),
+			},
+		},
+		Maintenance: MaintenanceConfig{
+			Message:         getEnv("MAINTENANCE_MESSAGE", "系统维护中，请稍后再试"),
+			RetryAfter:      getDurationEnv("MAINTENANCE_RETRY_AFTER", 5*time.Minute),
+			AllowPaths:      getSliceEnv("MAINTENANCE_ALLOW_PATHS", []string{"/ping", "/health", "/metrics"}),
+			RefreshInterval: getDurationEnv("MAINTENANCE_REFRESH_INTERVAL", 5*time.Second),
+		},
+		LoginGuard: LoginGuardConfig{
+			Enabled:         getBoolEnv("LOGIN_GUARD_ENABLED", true),
+			Window:          getDurationEnv("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+			DelayAfter:      getIntEnv("LOGIN_DELAY_AFTER", 3),
+			IPDelayAfter:    getIntEnv("LOGIN_IP_DELAY_AFTER", 20),
+			BaseDelay:       getDurationEnv("LOGIN_DELAY_BASE", time.Second),
+			MaxDelay:        getDurationEnv("LOGIN_DELAY_MAX", time.Minute),
+			LockThreshold:   getIntEnv("LOGIN_LOCK_THRESHOLD", 10),
+			LockDuration:    getDurationEnv("LOGIN_LOCK_DURATION", 15*time.Minute),
+			MaxLockDuration: getDurationEnv("LOGIN_LOCK_MAX_DURATION", 24*time.Hour),
 		},
 	}
+
+	if cfg.Standalone() {
+		cfg.applyStandalone()
+	}
+	return cfg
+}
+
+// Standalone 是否为单机模式
+func (c *Config) Standalone() bool {
+	return c.Server.AppMode == AppModeStandalone
+}
+
+// applyStandalone 单机模式：状态存储全部使用内存，关闭服务发现、配置中心、选主及外发通知
+func (c *Config) applyStandalone() {
+	c.Store = StoreConfig{
+		NonceBackend:       "memory",
+		SessionBackend:     "memory",
+		RateLimitBackend:   "memory",
+		QuotaBackend:       "memory",
+		RevocationBackend:  "memory",
+		AbuseBackend:       "memory",
+		JobsBackend:        "memory",
+		AppKeyBackend:      "memory",
+		LoginBackend:       "memory",
+		MaintenanceBackend: "memory",
+		APIKeyBackend:      "memory",
+		CaptchaBackend:     "memory",
+
+		PasswordResetBackend: "memory",
+		WebAuthnBackend:      "memory",
+	}
+	c.Discovery.Provider = ""
+	c.ConfigCenter.Provider = ""
+	c.Leader.Backend = ""
+	c.Mail.SMTPHost = ""
+	c.Analytics.AlertWebhook = ""
+	c.SecurityEvents.Store = "mysql"
+	c.SecurityEvents.AlertWebhook = ""
+	c.SecurityEvents.DingTalkWebhook = ""
+	c.SecurityEvents.SlackWebhook = ""
+	// 快照存储在 MongoDB，单机模式只提供实时状态
+	c.SecurityState.SnapshotInterval = 0
 }
 
 func getEnv(key, defaultValue string) string {
@@ -140,6 +1463,36 @@ func getIntEnv(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return defaultValue
+}
+
+// getTimeEnv 解析时间（RFC3339 或 2006-01-02，后者按本地时区零点）
+func getTimeEnv(key string, defaultValue time.Time) time.Time {
+	if value := os.Getenv(key); value != "" {
+		if t, ok := parseTime(value); ok {
+			return t
+		}
+	}
+	return defaultValue
+}
+
+func parseTime(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
 func getBoolEnv(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if boolVal, err := strconv.ParseBool(value); err == nil {
@@ -164,3 +1517,350 @@ func getSliceEnv(key string, defaultValue []string) []string {
 	}
 	return defaultValue
 }
+
+// getDurationMapEnv 解析 "key=duration,key=duration" 格式的环境变量
+func getDurationMapEnv(key string, defaultValue map[string]time.Duration) map[string]time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result := make(map[string]time.Duration)
+	for _, item := range strings.Split(value, ",") {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		if duration, err := time.ParseDuration(strings.TrimSpace(kv[1])); err == nil {
+			result[strings.TrimSpace(kv[0])] = duration
+		}
+	}
+	return result
+}
+
+// getSizeMapEnv 解析 "key=字节数,key=字节数" 格式的环境变量
+func getSizeMapEnv(key string, defaultValue map[string]int64) map[string]int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result := make(map[string]int64)
+	for _, item := range strings.Split(value, ",") {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		if size, err := strconv.ParseInt(strings.TrimSpace(kv[1]), 10, 64); err == nil {
+			result[strings.TrimSpace(kv[0])] = size
+		}
+	}
+	return result
+}
+
+// getListMapEnv 解析 "key=a|b,key=c" 格式的环境变量（值以 | 分隔）
+func getListMapEnv(key string, defaultValue map[string][]string) map[string][]string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result := make(map[string][]string)
+	for _, item := range strings.Split(value, ",") {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		var values []string
+		for _, v := range strings.Split(kv[1], "|") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		result[strings.TrimSpace(kv[0])] = values
+	}
+	return result
+}
+
+// getStringMapEnv 解析 "key=value,key=value" 格式的环境变量
+func getStringMapEnv(key string, defaultValue map[string]string) map[string]string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		result[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return result
+}
+
+// getRateLimitRulesEnv 解析路由频率限制规则
+// 格式："[METHOD ]/path=max/window[@观察期截止时间]"，多条以逗号分隔，
+// 如 "POST /api/v1/public/login=5/1m,/api/v1/users*=100/1m@2026-03-01"（截止前只记录超限、不拦截）
+func getRateLimitRulesEnv(key string, defaultValue []RateLimitRule) []RateLimitRule {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var rules []RateLimitRule
+	for _, item := range strings.Split(value, ",") {
+		kv := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		spec, enforceAfter := kv[1], time.Time{}
+		if i := strings.Index(spec, "@"); i >= 0 {
+			t, ok := parseTime(spec[i+1:])
+			if !ok {
+				continue
+			}
+			spec, enforceAfter = spec[:i], t
+		}
+
+		limit := strings.SplitN(spec, "/", 2)
+		if len(limit) != 2 {
+			continue
+		}
+		maxRequests, err := strconv.Atoi(strings.TrimSpace(limit[0]))
+		if err != nil {
+			continue
+		}
+		// 时间窗口必须大于 0，且每个请求的平均间隔（window/max）不能为 0
+		window, err := time.ParseDuration(strings.TrimSpace(limit[1]))
+		if err != nil || window <= 0 || (maxRequests > 0 && window < time.Duration(maxRequests)) {
+			continue
+		}
+
+		rule := RateLimitRule{Path: strings.TrimSpace(kv[0]), Window: window, MaxRequests: maxRequests, EnforceAfter: enforceAfter}
+		if parts := strings.Fields(rule.Path); len(parts) == 2 {
+			rule.Method, rule.Path = strings.ToUpper(parts[0]), parts[1]
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// getPolicyRulesEnv 解析反滥用处置规则
+// 格式："条件[&条件...]:动作"，多条以逗号分隔，按顺序匹配第一条；
+// 条件为 score>=N、sensitivity>=级别、reputation=标记，* 表示无条件；
+// 动作为 allow、captcha、step_up、block 或 throttle(max/window)，
+// 如 "score>=30:block,reputation=listed:captcha,sensitivity>=high&score>=5:step_up,score>=10:throttle(10/1m)"
+func getPolicyRulesEnv(key string, defaultValue []PolicyRule) []PolicyRule {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var rules []PolicyRule
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		i := strings.LastIndex(item, ":")
+		if i < 0 {
+			continue
+		}
+		rule := PolicyRule{Name: item}
+		if !parsePolicyAction(&rule, strings.TrimSpace(item[i+1:])) {
+			continue
+		}
+		if !parsePolicyConditions(&rule, strings.TrimSpace(item[:i])) {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// parsePolicyAction 解析处置动作
+func parsePolicyAction(rule *PolicyRule, action string) bool {
+	switch action {
+	case "allow", "captcha", "step_up", "block":
+		rule.Action = action
+		return true
+	}
+
+	spec, ok := strings.CutPrefix(action, "throttle(")
+	if !ok || !strings.HasSuffix(spec, ")") {
+		return false
+	}
+	limit := strings.SplitN(strings.TrimSuffix(spec, ")"), "/", 2)
+	if len(limit) != 2 {
+		return false
+	}
+	maxRequests, err := strconv.Atoi(strings.TrimSpace(limit[0]))
+	if err != nil || maxRequests <= 0 {
+		return false
+	}
+	window, err := time.ParseDuration(strings.TrimSpace(limit[1]))
+	if err != nil || window < time.Duration(maxRequests) {
+		return false
+	}
+	rule.Action, rule.MaxRequests, rule.Window = "throttle", maxRequests, window
+	return true
+}
+
+// parsePolicyConditions 解析以 & 连接的条件
+func parsePolicyConditions(rule *PolicyRule, conditions string) bool {
+	if conditions == "*" {
+		return true
+	}
+	for _, cond := range strings.Split(conditions, "&") {
+		cond = strings.TrimSpace(cond)
+		switch {
+		case strings.HasPrefix(cond, "score>="):
+			score, err := strconv.ParseInt(strings.TrimPrefix(cond, "score>="), 10, 64)
+			if err != nil || score <= 0 {
+				return false
+			}
+			rule.MinScore = score
+		case strings.HasPrefix(cond, "sensitivity>="):
+			level := strings.TrimPrefix(cond, "sensitivity>=")
+			if !validSensitivity(level) {
+				return false
+			}
+			rule.MinSensitivity = level
+		case strings.HasPrefix(cond, "reputation="):
+			rule.Reputation = strings.TrimPrefix(cond, "reputation=")
+			if rule.Reputation == "" {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// getPolicyRoutesEnv 解析路由敏感级别，格式 "[METHOD ]/path=级别"，多条以逗号分隔，
+// 如 "POST /api/v1/public/login=high,/admin/*=critical"
+func getPolicyRoutesEnv(key string, defaultValue []PolicyRoute) []PolicyRoute {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var routes []PolicyRoute
+	for _, item := range strings.Split(value, ",") {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		route := PolicyRoute{Route: strings.TrimSpace(kv[0]), Level: strings.TrimSpace(kv[1])}
+		if route.Route == "" || !validSensitivity(route.Level) {
+			continue
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+// getAuditPathRulesEnv 解析按路径的审计记录规则
+// 格式："[METHOD ]/path=选项[&选项]"，多条以逗号分隔，按顺序匹配第一条；
+// 选项为 sample:比例（记录请求的比例）、body:比例（采集请求/响应体的比例）、headers_only（不采集请求/响应体）、off（不记录），
+// 比例可写作 1% 或 0.01，如 "/admin/*=sample:100%&body:100%,/api/v1/users*=body:1%,POST /api/v1/upload*=headers_only"
+func getAuditPathRulesEnv(key string, defaultValue []AuditPathRule) []AuditPathRule {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var rules []AuditPathRule
+	for _, item := range strings.Split(value, ",") {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		rule := AuditPathRule{Route: strings.TrimSpace(kv[0]), SampleRate: -1, BodySampleRate: -1}
+		valid := rule.Route != ""
+		for _, option := range strings.Split(kv[1], "&") {
+			option = strings.TrimSpace(option)
+			var ok bool
+			switch {
+			case option == "off":
+				rule.SampleRate, ok = 0, true
+			case option == "headers_only":
+				rule.BodySampleRate, ok = 0, true
+			case strings.HasPrefix(option, "sample:"):
+				rule.SampleRate, ok = parseRate(strings.TrimPrefix(option, "sample:"))
+			case strings.HasPrefix(option, "body:"):
+				rule.BodySampleRate, ok = parseRate(strings.TrimPrefix(option, "body:"))
+			}
+			valid = valid && ok
+		}
+		if valid {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// getPatternsEnv 解析逗号分隔的正则表达式：括号内（如 \d{3,4}、[a,b]）及转义的逗号（\,）不作为分隔符
+func getPatternsEnv(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var patterns []string
+	depth, start := 0, 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				patterns = append(patterns, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(patterns, value[start:])
+}
+
+// getRateEnv 获取 0~1 的比例（可写作 1% 或 0.01），格式错误时使用默认值
+func getRateEnv(key string, defaultValue float64) float64 {
+	if rate, ok := parseRate(os.Getenv(key)); ok {
+		return rate
+	}
+	return defaultValue
+}
+
+// parseRate 解析比例：百分数或 0~1 的小数
+func parseRate(value string) (float64, bool) {
+	value = strings.TrimSpace(value)
+	scale := 1.0
+	if v, ok := strings.CutSuffix(value, "%"); ok {
+		value, scale = strings.TrimSpace(v), 100
+	}
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	rate /= scale
+	if rate < 0 || rate > 1 {
+		return 0, false
+	}
+	return rate, true
+}
+
+func validSensitivity(level string) bool {
+	for _, l := range PolicySensitivityLevels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
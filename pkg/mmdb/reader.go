@@ -0,0 +1,381 @@
+// Package mmdb 读取 MaxMind DB（.mmdb）格式的数据库，如 GeoLite2-ASN、GeoLite2-Country。
+// 只实现查询所需的部分：元数据、二叉搜索树与数据段解码（格式见 https://maxmind.github.io/MaxMind-DB/）
+package mmdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+// metadataMarker 元数据段的起始标记（文件末尾附近最后一次出现的位置）
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// ErrInvalidDatabase 文件不是有效的 MaxMind DB
+var ErrInvalidDatabase = errors.New("无效的 MaxMind DB 文件")
+
+// Metadata 数据库元数据
+type Metadata struct {
+	DatabaseType string
+	IPVersion    int
+	RecordSize   int
+	NodeCount    uint
+	BuildEpoch   uint64
+}
+
+// Reader 数据库读取器（整个文件加载到内存，可并发查询）
+type Reader struct {
+	buf      []byte
+	data     []byte
+	metadata Metadata
+	// IPv6 数据库中 IPv4 地址（::/96）对应的起始节点
+	ipv4Start uint
+}
+
+// Open 打开数据库文件
+func Open(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return FromBytes(buf)
+}
+
+// FromBytes 从内存中的数据库内容创建读取器
+func FromBytes(buf []byte) (*Reader, error) {
+	start := bytes.LastIndex(buf, metadataMarker)
+	if start < 0 {
+		return nil, ErrInvalidDatabase
+	}
+	start += len(metadataMarker)
+
+	d := decoder{buf: buf[start:]}
+	raw, _, err := d.decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 元数据: %v", ErrInvalidDatabase, err)
+	}
+	fields, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, ErrInvalidDatabase
+	}
+
+	md := Metadata{
+		DatabaseType: stringField(fields, "database_type"),
+		IPVersion:    int(uintField(fields, "ip_version")),
+		RecordSize:   int(uintField(fields, "record_size")),
+		NodeCount:    uint(uintField(fields, "node_count")),
+		BuildEpoch:   uintField(fields, "build_epoch"),
+	}
+	switch md.RecordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("%w: 不支持的记录长度 %d", ErrInvalidDatabase, md.RecordSize)
+	}
+
+	treeSize := md.NodeCount * uint(md.RecordSize) / 4
+	// 搜索树与数据段之间有 16 字节的分隔
+	if treeSize+16 > uint(len(buf)) {
+		return nil, fmt.Errorf("%w: 搜索树超出文件长度", ErrInvalidDatabase)
+	}
+	r := &Reader{
+		buf:      buf[:treeSize],
+		data:     buf[treeSize+16 : start-len(metadataMarker)],
+		metadata: md,
+	}
+
+	if md.IPVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < md.NodeCount; i++ {
+			node = r.readNode(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// Metadata 获取数据库元数据
+func (r *Reader) Metadata() Metadata {
+	return r.metadata
+}
+
+// Lookup 查询 IP 对应的记录（通常为 map[string]interface{}），不在库中时 found 为 false
+func (r *Reader) Lookup(ip net.IP) (record interface{}, found bool, err error) {
+	node, bits, err := r.start(ip)
+	if err != nil {
+		return nil, false, err
+	}
+	addr := ip.To4()
+	if addr == nil {
+		addr = ip.To16()
+	}
+
+	nodeCount := r.metadata.NodeCount
+	for i := 0; i < bits && node < nodeCount; i++ {
+		bit := uint(addr[i>>3]>>(7-uint(i%8))) & 1
+		node = r.readNode(node, bit)
+	}
+	if node == nodeCount {
+		return nil, false, nil
+	}
+	if node < nodeCount {
+		return nil, false, fmt.Errorf("%w: 搜索树未指向数据", ErrInvalidDatabase)
+	}
+
+	offset := node - nodeCount - 16
+	if offset >= uint(len(r.data)) {
+		return nil, false, fmt.Errorf("%w: 数据偏移越界", ErrInvalidDatabase)
+	}
+	d := decoder{buf: r.data}
+	record, _, err = d.decode(offset)
+	if err != nil {
+		return nil, false, err
+	}
+	return record, true, nil
+}
+
+// start 查询起始节点及需要遍历的位数
+func (r *Reader) start(ip net.IP) (uint, int, error) {
+	if v4 := ip.To4(); v4 != nil {
+		if r.metadata.IPVersion == 6 {
+			return r.ipv4Start, 32, nil
+		}
+		return 0, 32, nil
+	}
+	if ip.To16() == nil {
+		return 0, 0, fmt.Errorf("无效的 IP 地址: %v", ip)
+	}
+	if r.metadata.IPVersion == 4 {
+		return 0, 0, fmt.Errorf("IPv4 数据库不支持查询 IPv6 地址: %v", ip)
+	}
+	return 0, 128, nil
+}
+
+// readNode 读取节点的左（bit=0）或右（bit=1）记录
+func (r *Reader) readNode(node, bit uint) uint {
+	switch r.metadata.RecordSize {
+	case 24:
+		b := r.buf[node*6+bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		b := r.buf[node*7:]
+		if bit == 0 {
+			return (uint(b[3])&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return (uint(b[3])&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		b := r.buf[node*8+bit*4:]
+		return uint(b[0])<<24 | uint(b[1])<<16 | uint(b[2])<<8 | uint(b[3])
+	}
+}
+
+// 数据段字段类型
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// maxDepth 嵌套深度上限（防止损坏的文件导致无限递归）
+const maxDepth = 32
+
+// decoder 数据段解码器
+type decoder struct {
+	buf   []byte
+	depth int
+}
+
+// decode 解码 offset 处的字段，返回值及下一个字段的偏移
+func (d *decoder) decode(offset uint) (interface{}, uint, error) {
+	if d.depth > maxDepth {
+		return nil, 0, fmt.Errorf("%w: 嵌套过深", ErrInvalidDatabase)
+	}
+	if offset >= uint(len(d.buf)) {
+		return nil, 0, fmt.Errorf("%w: 偏移越界", ErrInvalidDatabase)
+	}
+
+	ctrl := d.buf[offset]
+	offset++
+	kind := int(ctrl >> 5)
+
+	if kind == typePointer {
+		pointer, next, err := d.pointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		d.depth++
+		value, _, err := d.decode(pointer)
+		d.depth--
+		return value, next, err
+	}
+
+	if kind == typeExtended {
+		if offset >= uint(len(d.buf)) {
+			return nil, 0, fmt.Errorf("%w: 偏移越界", ErrInvalidDatabase)
+		}
+		kind = 7 + int(d.buf[offset])
+		offset++
+	}
+
+	size, offset, err := d.size(ctrl, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch kind {
+	case typeMap:
+		m := make(map[string]interface{}, size)
+		d.depth++
+		defer func() { d.depth-- }()
+		for i := uint(0); i < size; i++ {
+			key, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("%w: map 的键不是字符串", ErrInvalidDatabase)
+			}
+			value, next, err := d.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[k] = value
+			offset = next
+		}
+		return m, offset, nil
+	case typeArray:
+		list := make([]interface{}, 0, size)
+		d.depth++
+		defer func() { d.depth-- }()
+		for i := uint(0); i < size; i++ {
+			value, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			list = append(list, value)
+			offset = next
+		}
+		return list, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(d.buf)) {
+		return nil, 0, fmt.Errorf("%w: 字段越界", ErrInvalidDatabase)
+	}
+	b := d.buf[offset : offset+size]
+	next := offset + size
+
+	switch kind {
+	case typeString:
+		return string(b), next, nil
+	case typeBytes:
+		return append([]byte(nil), b...), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("%w: double 长度 %d", ErrInvalidDatabase, size)
+		}
+		return math.Float64frombits(uint64(beUint(b))), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("%w: float 长度 %d", ErrInvalidDatabase, size)
+		}
+		return math.Float32frombits(uint32(beUint(b))), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, fmt.Errorf("%w: 整数长度 %d", ErrInvalidDatabase, size)
+		}
+		return uint64(beUint(b)), next, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, fmt.Errorf("%w: int32 长度 %d", ErrInvalidDatabase, size)
+		}
+		return int32(uint32(beUint(b))), next, nil
+	case typeUint128:
+		// 128 位整数按字节返回
+		return append([]byte(nil), b...), next, nil
+	default:
+		return nil, 0, fmt.Errorf("%w: 未知的字段类型 %d", ErrInvalidDatabase, kind)
+	}
+}
+
+// size 解析字段长度
+func (d *decoder) size(ctrl byte, offset uint) (uint, uint, error) {
+	size := uint(ctrl & 0x1f)
+	if size < 29 {
+		return size, offset, nil
+	}
+	n := size - 28
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, fmt.Errorf("%w: 长度越界", ErrInvalidDatabase)
+	}
+	b := d.buf[offset : offset+n]
+	switch size {
+	case 29:
+		size = 29 + uint(b[0])
+	case 30:
+		size = 285 + uint(beUint(b))
+	default:
+		size = 65821 + uint(beUint(b))
+	}
+	return size, offset + n, nil
+}
+
+// pointer 解析指针，返回指向的偏移及下一个字段的偏移
+func (d *decoder) pointer(ctrl byte, offset uint) (uint, uint, error) {
+	n := uint((ctrl>>3)&0x3) + 1
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, fmt.Errorf("%w: 指针越界", ErrInvalidDatabase)
+	}
+	b := d.buf[offset : offset+n]
+	v := uint(ctrl & 0x7)
+	var pointer uint
+	switch n {
+	case 1:
+		pointer = v<<8 | uint(b[0])
+	case 2:
+		pointer = (v<<16 | uint(beUint(b))) + 2048
+	case 3:
+		pointer = (v<<24 | uint(beUint(b))) + 526336
+	default:
+		pointer = uint(beUint(b))
+	}
+	return pointer, offset + n, nil
+}
+
+// beUint 大端无符号整数
+func beUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// stringField 读取元数据中的字符串字段
+func stringField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// uintField 读取元数据中的整数字段
+func uintField(m map[string]interface{}, key string) uint64 {
+	v, _ := m[key].(uint64)
+	return v
+}
@@ -0,0 +1,209 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+// 哈希算法
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmScrypt   = "scrypt"
+)
+
+// Config 密码哈希配置
+type Config struct {
+	// 新密码使用的算法：bcrypt（默认）, argon2id, scrypt
+	Algorithm string
+
+	// bcrypt 参数
+	BcryptCost int
+
+	// Argon2id 参数
+	Argon2Memory      uint32 // KiB
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+
+	// scrypt 参数（N 需为 2 的幂）
+	ScryptN int
+	ScryptR int
+	ScryptP int
+}
+
+// DefaultConfig 默认配置
+var DefaultConfig = Config{
+	Algorithm:         AlgorithmBcrypt,
+	BcryptCost:        bcrypt.DefaultCost,
+	Argon2Memory:      64 * 1024,
+	Argon2Iterations:  3,
+	Argon2Parallelism: 2,
+	ScryptN:           32768,
+	ScryptR:           8,
+	ScryptP:           1,
+}
+
+const (
+	saltLength = 16
+	keyLength  = 32
+)
+
+// Configure 设置全局配置
+func Configure(cfg Config) {
+	DefaultConfig = cfg
+}
+
+// Hash 使用当前算法生成哈希，算法与参数编码在结果中：
+//
+//	bcrypt:   $2a$10$...
+//	argon2id: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+//	scrypt:   $scrypt$n=32768,r=8,p=1$<salt>$<hash>
+func Hash(password string) (string, error) {
+	cfg := DefaultConfig
+
+	switch cfg.Algorithm {
+	case AlgorithmBcrypt, "":
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), cfg.BcryptCost)
+		return string(hashed), err
+
+	case AlgorithmArgon2id:
+		salt, err := newSalt()
+		if err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, cfg.Argon2Iterations, cfg.Argon2Memory, cfg.Argon2Parallelism, keyLength)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+			cfg.Argon2Memory, cfg.Argon2Iterations, cfg.Argon2Parallelism, encode(salt), encode(key)), nil
+
+	case AlgorithmScrypt:
+		salt, err := newSalt()
+		if err != nil {
+			return "", err
+		}
+		key, err := scrypt.Key([]byte(password), salt, cfg.ScryptN, cfg.ScryptR, cfg.ScryptP, keyLength)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("$scrypt$n=%d,r=%d,p=%d$%s$%s",
+			cfg.ScryptN, cfg.ScryptR, cfg.ScryptP, encode(salt), encode(key)), nil
+
+	default:
+		return "", fmt.Errorf("不支持的密码哈希算法: %s", cfg.Algorithm)
+	}
+}
+
+// Verify 验证密码（根据哈希前缀识别算法，兼容历史哈希）
+func Verify(encoded, password string) bool {
+	switch algorithmOf(encoded) {
+	case AlgorithmBcrypt:
+		return bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) == nil
+
+	case AlgorithmArgon2id:
+		var version int
+		var memory, iterations uint32
+		var parallelism uint8
+		parts := strings.Split(encoded, "$")
+		if len(parts) != 6 {
+			return false
+		}
+		if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+			return false
+		}
+		if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism); err != nil {
+			return false
+		}
+		salt, key, err := decodeSaltKey(parts[4], parts[5])
+		if err != nil {
+			return false
+		}
+		actual := argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, uint32(len(key)))
+		return subtle.ConstantTimeCompare(actual, key) == 1
+
+	case AlgorithmScrypt:
+		var n, r, p int
+		parts := strings.Split(encoded, "$")
+		if len(parts) != 5 {
+			return false
+		}
+		if _, err := fmt.Sscanf(parts[2], "n=%d,r=%d,p=%d", &n, &r, &p); err != nil {
+			return false
+		}
+		salt, key, err := decodeSaltKey(parts[3], parts[4])
+		if err != nil {
+			return false
+		}
+		actual, err := scrypt.Key([]byte(password), salt, n, r, p, len(key))
+		if err != nil {
+			return false
+		}
+		return subtle.ConstantTimeCompare(actual, key) == 1
+	}
+
+	return false
+}
+
+// NeedsRehash 哈希的算法或参数与当前配置不一致时返回 true（登录成功后可透明升级）
+func NeedsRehash(encoded string) bool {
+	cfg := DefaultConfig
+	algorithm := cfg.Algorithm
+	if algorithm == "" {
+		algorithm = AlgorithmBcrypt
+	}
+
+	if algorithmOf(encoded) != algorithm {
+		return true
+	}
+
+	switch algorithm {
+	case AlgorithmBcrypt:
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return err != nil || cost != cfg.BcryptCost
+	case AlgorithmArgon2id:
+		return !strings.Contains(encoded, fmt.Sprintf("$m=%d,t=%d,p=%d$", cfg.Argon2Memory, cfg.Argon2Iterations, cfg.Argon2Parallelism))
+	case AlgorithmScrypt:
+		return !strings.Contains(encoded, fmt.Sprintf("$n=%d,r=%d,p=%d$", cfg.ScryptN, cfg.ScryptR, cfg.ScryptP))
+	}
+	return false
+}
+
+// algorithmOf 根据前缀识别哈希算法
+func algorithmOf(encoded string) string {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return AlgorithmArgon2id
+	case strings.HasPrefix(encoded, "$scrypt$"):
+		return AlgorithmScrypt
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return AlgorithmBcrypt
+	}
+	return ""
+}
+
+func newSalt() ([]byte, error) {
+	salt := make([]byte, saltLength)
+	_, err := rand.Read(salt)
+	return salt, err
+}
+
+func encode(b []byte) string {
+	return base64.RawStdEncoding.EncodeToString(b)
+}
+
+func decodeSaltKey(saltPart, keyPart string) ([]byte, []byte, error) {
+	salt, err := base64.RawStdEncoding.DecodeString(saltPart)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(keyPart)
+	if err != nil {
+		return nil, nil, err
+	}
+	return salt, key, nil
+}
@@ -0,0 +1,81 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Policy 密码强度策略（用户注册、重置密码时校验）
+type Policy struct {
+	MinLength int
+	// 最大长度（bcrypt 只使用前 72 字节）
+	MaxLength int
+	// 至少包含的字符类别数（大写字母、小写字母、数字、符号）
+	MinClasses int
+}
+
+// DefaultPolicy 默认策略
+var DefaultPolicy = Policy{
+	MinLength:  8,
+	MaxLength:  72,
+	MinClasses: 2,
+}
+
+// ErrContainsIdentity 密码包含用户名或邮箱
+var ErrContainsIdentity = errors.New("密码不能包含用户名或邮箱")
+
+// ConfigurePolicy 设置全局密码策略
+func ConfigurePolicy(p Policy) {
+	DefaultPolicy = p
+}
+
+// Validate 按全局策略校验密码；identities 为用户名、邮箱等不允许出现在密码中的内容（邮箱只比较 @ 之前的部分）
+func Validate(plain string, identities ...string) error {
+	return DefaultPolicy.Validate(plain, identities...)
+}
+
+// Validate 按策略校验密码
+func (p Policy) Validate(plain string, identities ...string) error {
+	if n := utf8.RuneCountInString(plain); n < p.MinLength {
+		return fmt.Errorf("密码长度不能少于 %d 位", p.MinLength)
+	}
+	if p.MaxLength > 0 && len(plain) > p.MaxLength {
+		return fmt.Errorf("密码长度不能超过 %d 字节", p.MaxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range plain {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, ok := range []bool{upper, lower, digit, symbol} {
+		if ok {
+			classes++
+		}
+	}
+	if classes < p.MinClasses {
+		return fmt.Errorf("密码至少需要包含大写字母、小写字母、数字、符号中的 %d 类", p.MinClasses)
+	}
+
+	lowered := strings.ToLower(plain)
+	for _, identity := range identities {
+		identity, _, _ = strings.Cut(strings.ToLower(identity), "@")
+		// 过短的内容容易误判，不参与比较
+		if len(identity) >= 3 && strings.Contains(lowered, identity) {
+			return ErrContainsIdentity
+		}
+	}
+	return nil
+}
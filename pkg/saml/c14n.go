@@ -0,0 +1,158 @@
+package saml
+
+import (
+	"sort"
+	"strings"
+)
+
+// canonicalize 按 Exclusive XML Canonicalization（不含注释，https://www.w3.org/TR/xml-exc-c14n/）输出以 e 为根的子树。
+// skip 为不输出的元素（enveloped-signature 变换移除的签名元素）；inclusive 为 InclusiveNamespaces PrefixList 中的前缀（#default 表示默认命名空间）
+func canonicalize(e, skip *element, inclusive []string) []byte {
+	var b strings.Builder
+	incl := make(map[string]bool, len(inclusive))
+	for _, p := range inclusive {
+		if p == "#default" {
+			p = ""
+		}
+		incl[p] = true
+	}
+	c14nElement(&b, e, skip, incl, map[string]string{})
+	return []byte(b.String())
+}
+
+// c14nElement 输出元素；rendered 为输出中祖先元素已声明的命名空间
+func c14nElement(b *strings.Builder, e, skip *element, incl map[string]bool, rendered map[string]string) {
+	// 需要输出的命名空间：元素名、属性名实际使用的前缀，以及 PrefixList 中在作用域内的前缀
+	used := map[string]bool{e.prefix: true}
+	for _, a := range e.attrs {
+		if a.prefix != "" {
+			used[a.prefix] = true
+		}
+	}
+	for p := range incl {
+		if _, ok := e.lookup(p); ok {
+			used[p] = true
+		}
+	}
+
+	var decls []attr
+	scope := rendered
+	for p := range used {
+		if p == "xml" {
+			continue
+		}
+		uri, _ := e.lookup(p)
+		prev, ok := rendered[p]
+		if p == "" && uri == "" {
+			// 默认命名空间为空：只有输出中的祖先声明了非空默认命名空间时才需要 xmlns=""
+			if !ok || prev == "" {
+				continue
+			}
+		} else if ok && prev == uri {
+			continue
+		}
+		if len(decls) == 0 {
+			scope = make(map[string]string, len(rendered)+len(used))
+			for k, v := range rendered {
+				scope[k] = v
+			}
+		}
+		scope[p] = uri
+		decls = append(decls, attr{local: p, value: uri})
+	}
+	sort.Slice(decls, func(i, j int) bool { return decls[i].local < decls[j].local })
+
+	attrs := make([]attr, len(e.attrs))
+	copy(attrs, e.attrs)
+	sort.Slice(attrs, func(i, j int) bool {
+		si, sj := attrSpace(e, attrs[i]), attrSpace(e, attrs[j])
+		if si != sj {
+			return si < sj
+		}
+		return attrs[i].local < attrs[j].local
+	})
+
+	name := qname(e.prefix, e.local)
+	b.WriteByte('<')
+	b.WriteString(name)
+	for _, d := range decls {
+		if d.local == "" {
+			b.WriteString(` xmlns="`)
+		} else {
+			b.WriteString(" xmlns:" + d.local + `="`)
+		}
+		escapeAttr(b, d.value)
+		b.WriteByte('"')
+	}
+	for _, a := range attrs {
+		b.WriteString(" " + qname(a.prefix, a.local) + `="`)
+		escapeAttr(b, a.value)
+		b.WriteByte('"')
+	}
+	b.WriteByte('>')
+
+	for _, c := range e.children {
+		switch n := c.(type) {
+		case *element:
+			if n != skip {
+				c14nElement(b, n, skip, incl, scope)
+			}
+		case string:
+			escapeText(b, n)
+		}
+	}
+	b.WriteString("</" + name + ">")
+}
+
+func attrSpace(e *element, a attr) string {
+	if a.prefix == "" {
+		return ""
+	}
+	uri, _ := e.lookup(a.prefix)
+	return uri
+}
+
+func qname(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
+
+func escapeText(b *strings.Builder, s string) {
+	for _, r := range s {
+		switch r {
+		case '&':
+			b.WriteString("&amp;")
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		case '\r':
+			b.WriteString("&#xD;")
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+func escapeAttr(b *strings.Builder, s string) {
+	for _, r := range s {
+		switch r {
+		case '&':
+			b.WriteString("&amp;")
+		case '<':
+			b.WriteString("&lt;")
+		case '"':
+			b.WriteString("&quot;")
+		case '\t':
+			b.WriteString("&#x9;")
+		case '\n':
+			b.WriteString("&#xA;")
+		case '\r':
+			b.WriteString("&#xD;")
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
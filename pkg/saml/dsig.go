@@ -0,0 +1,147 @@
+package saml
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"strings"
+)
+
+// XML 签名算法（只支持 SHA-256 及以上，拒绝 SHA-1）
+const (
+	algExcC14N     = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	algEnveloped   = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+	algSHA256      = "http://www.w3.org/2001/04/xmlenc#sha256"
+	algSHA512      = "http://www.w3.org/2001/04/xmlenc#sha512"
+	algRSASHA256   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	algRSASHA512   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha512"
+	nsExcC14NNames = "http://www.w3.org/2001/10/xml-exc-c14n#"
+)
+
+// verifySignature 校验 e 的直接子元素 ds:Signature（enveloped 签名，引用 e 自身的 ID）。
+// 返回 false, nil 表示 e 没有签名；签名存在但无效时返回错误
+func verifySignature(e *element, certs []*x509.Certificate) (bool, error) {
+	sigs := e.childrenNamed(nsDSig, "Signature")
+	if len(sigs) == 0 {
+		return false, nil
+	}
+	if len(sigs) > 1 {
+		return false, ErrSignature
+	}
+	sig := sigs[0]
+
+	signedInfos := sig.childrenNamed(nsDSig, "SignedInfo")
+	if len(signedInfos) != 1 {
+		return false, ErrSignature
+	}
+	signedInfo := signedInfos[0]
+	c14nMethod := signedInfo.child(nsDSig, "CanonicalizationMethod")
+	if c14nMethod == nil || c14nMethod.attr("Algorithm") != algExcC14N {
+		return false, ErrUnsupportedAlgorithm
+	}
+	sigMethod := signedInfo.child(nsDSig, "SignatureMethod")
+	if sigMethod == nil {
+		return false, ErrSignature
+	}
+	var hash crypto.Hash
+	switch sigMethod.attr("Algorithm") {
+	case algRSASHA256:
+		hash = crypto.SHA256
+	case algRSASHA512:
+		hash = crypto.SHA512
+	default:
+		return false, ErrUnsupportedAlgorithm
+	}
+
+	// 只接受一个引用，且必须指向签名所在的元素（防止签名包装攻击：签名覆盖的内容与实际使用的内容不一致）
+	refs := signedInfo.childrenNamed(nsDSig, "Reference")
+	id := e.attr("ID")
+	if len(refs) != 1 || id == "" || refs[0].attr("URI") != "#"+id {
+		return false, ErrSignature
+	}
+	ref := refs[0]
+
+	var inclusive []string
+	enveloped := false
+	if transforms := ref.child(nsDSig, "Transforms"); transforms != nil {
+		for _, t := range transforms.childrenNamed(nsDSig, "Transform") {
+			switch t.attr("Algorithm") {
+			case algEnveloped:
+				enveloped = true
+			case algExcC14N:
+				inclusive = prefixList(t)
+			default:
+				return false, ErrUnsupportedAlgorithm
+			}
+		}
+	}
+	if !enveloped {
+		return false, ErrSignature
+	}
+
+	digestMethod := ref.child(nsDSig, "DigestMethod")
+	digestValue := ref.child(nsDSig, "DigestValue")
+	if digestMethod == nil || digestValue == nil {
+		return false, ErrSignature
+	}
+	expected, err := decodeBase64(digestValue.text())
+	if err != nil {
+		return false, ErrSignature
+	}
+	content := canonicalize(e, sig, inclusive)
+	var digest []byte
+	switch digestMethod.attr("Algorithm") {
+	case algSHA256:
+		sum := sha256.Sum256(content)
+		digest = sum[:]
+	case algSHA512:
+		sum := sha512.Sum512(content)
+		digest = sum[:]
+	default:
+		return false, ErrUnsupportedAlgorithm
+	}
+	if subtle.ConstantTimeCompare(digest, expected) != 1 {
+		return false, ErrSignature
+	}
+
+	sigValue := sig.child(nsDSig, "SignatureValue")
+	if sigValue == nil {
+		return false, ErrSignature
+	}
+	signature, err := decodeBase64(sigValue.text())
+	if err != nil {
+		return false, ErrSignature
+	}
+	h := hash.New()
+	h.Write(canonicalize(signedInfo, nil, prefixList(c14nMethod)))
+	hashed := h.Sum(nil)
+
+	// 依次尝试配置的证书（IdP 轮换证书期间可同时配置新旧证书），不信任响应中携带的 KeyInfo
+	for _, cert := range certs {
+		pub, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			continue
+		}
+		if rsa.VerifyPKCS1v15(pub, hash, hashed, signature) == nil {
+			return true, nil
+		}
+	}
+	return false, ErrSignature
+}
+
+// prefixList 读取规范化方法中 InclusiveNamespaces 的 PrefixList
+func prefixList(method *element) []string {
+	if in := method.child(nsExcC14NNames, "InclusiveNamespaces"); in != nil {
+		return strings.Fields(in.attr("PrefixList"))
+	}
+	return nil
+}
+
+// decodeBase64 解码 Base64（忽略其中的换行与空白）
+func decodeBase64(s string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+}
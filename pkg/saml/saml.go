@@ -0,0 +1,363 @@
+// Package saml SAML 2.0 服务提供方（SP）：生成元数据与 HTTP-Redirect 绑定的 AuthnRequest，
+// 校验 HTTP-POST 绑定提交的响应（XML 签名、颁发者、受众、有效期、接收地址）。
+// 只实现 Web 浏览器 SSO 所需的子集，不依赖第三方库：签名只支持 Exclusive C14N + RSA-SHA256/SHA512，不支持加密断言
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidResponse 响应格式错误或缺少必需的元素
+	ErrInvalidResponse = errors.New("SAML 响应格式错误")
+	// ErrSignature 签名缺失或无效
+	ErrSignature = errors.New("SAML 响应签名无效")
+	// ErrUnsupportedAlgorithm 不支持的规范化、摘要或签名算法
+	ErrUnsupportedAlgorithm = errors.New("SAML 响应使用了不支持的签名算法")
+	// ErrEncryptedAssertion 断言已加密（需在 IdP 关闭断言加密）
+	ErrEncryptedAssertion = errors.New("不支持加密的 SAML 断言")
+	// ErrStatus IdP 返回认证失败
+	ErrStatus = errors.New("IdP 认证未通过")
+	// ErrIssuer 颁发者与配置的 IdP 不一致
+	ErrIssuer = errors.New("SAML 响应的颁发者不匹配")
+	// ErrAudience 受众不包含本服务
+	ErrAudience = errors.New("SAML 断言的受众不匹配")
+	// ErrDestination 接收地址与本服务的 ACS 地址不一致
+	ErrDestination = errors.New("SAML 响应的接收地址不匹配")
+	// ErrExpired 断言不在有效期内
+	ErrExpired = errors.New("SAML 断言已过期或尚未生效")
+)
+
+// 绑定与名称标识格式
+const (
+	BindingHTTPPost     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	BindingHTTPRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+
+	NameIDFormatUnspecified = "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"
+	NameIDFormatEmail       = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+
+	statusSuccess = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	methodBearer  = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+)
+
+// ServiceProvider 服务提供方配置
+type ServiceProvider struct {
+	// SP 实体 ID（IdP 中配置的 Audience / Identifier）
+	EntityID string
+	// 断言消费服务（ACS）地址
+	ACSURL string
+	// IdP 实体 ID（为空不校验颁发者）与单点登录地址（HTTP-Redirect 绑定）
+	IdPEntityID string
+	IdPSSOURL   string
+	// IdP 签名证书
+	IdPCertificates []*x509.Certificate
+	// 允许的时钟偏差
+	ClockSkew time.Duration
+}
+
+// ParseCertificates 解析 PEM 格式的证书（可包含多个，用于证书轮换）；也接受不带 PEM 头的 Base64（IdP 元数据中的格式）
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := bytes.TrimSpace(data)
+	if !bytes.HasPrefix(rest, []byte("-----")) {
+		der, err := decodeBase64(string(rest))
+		if err != nil {
+			return nil, errors.New("IdP 证书格式错误")
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("IdP 证书格式错误: %w", err)
+		}
+		return []*x509.Certificate{cert}, nil
+	}
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("IdP 证书格式错误: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("未找到 IdP 证书")
+	}
+	return certs, nil
+}
+
+// Metadata 生成 SP 元数据（导入 IdP 即可完成配置）
+func (sp *ServiceProvider) Metadata() []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	fmt.Fprintf(&b, `<md:EntityDescriptor xmlns:md="%s" entityID="%s">`, nsMetadata, escape(sp.EntityID))
+	fmt.Fprintf(&b, `<md:SPSSODescriptor AuthnRequestsSigned="false" WantAssertionsSigned="true" protocolSupportEnumeration="%s">`, nsProtocol)
+	fmt.Fprintf(&b, `<md:NameIDFormat>%s</md:NameIDFormat>`, NameIDFormatEmail)
+	fmt.Fprintf(&b, `<md:NameIDFormat>%s</md:NameIDFormat>`, NameIDFormatUnspecified)
+	fmt.Fprintf(&b, `<md:AssertionConsumerService Binding="%s" Location="%s" index="0" isDefault="true"/>`, BindingHTTPPost, escape(sp.ACSURL))
+	b.WriteString(`</md:SPSSODescriptor></md:EntityDescriptor>`)
+	return b.Bytes()
+}
+
+// AuthnRequest 生成认证请求，返回跳转到 IdP 的地址（HTTP-Redirect 绑定）及请求 ID（校验响应的 InResponseTo）
+func (sp *ServiceProvider) AuthnRequest(relayState string) (string, string, error) {
+	id, err := newID()
+	if err != nil {
+		return "", "", err
+	}
+	var req bytes.Buffer
+	fmt.Fprintf(&req, `<samlp:AuthnRequest xmlns:samlp="%s" xmlns:saml="%s" ID="%s" Version="2.0" IssueInstant="%s" Destination="%s" ProtocolBinding="%s" AssertionConsumerServiceURL="%s">`,
+		nsProtocol, nsAssertion, id, time.Now().UTC().Format(time.RFC3339), escape(sp.IdPSSOURL), BindingHTTPPost, escape(sp.ACSURL))
+	fmt.Fprintf(&req, `<saml:Issuer>%s</saml:Issuer>`, escape(sp.EntityID))
+	fmt.Fprintf(&req, `<samlp:NameIDPolicy Format="%s" AllowCreate="true"/>`, NameIDFormatUnspecified)
+	req.WriteString(`</samlp:AuthnRequest>`)
+
+	var deflated bytes.Buffer
+	w, _ := flate.NewWriter(&deflated, flate.DefaultCompression)
+	w.Write(req.Bytes())
+	w.Close()
+
+	target, err := url.Parse(sp.IdPSSOURL)
+	if err != nil {
+		return "", "", err
+	}
+	query := target.Query()
+	query.Set("SAMLRequest", base64.StdEncoding.EncodeToString(deflated.Bytes()))
+	if relayState != "" {
+		query.Set("RelayState", relayState)
+	}
+	target.RawQuery = query.Encode()
+	return target.String(), id, nil
+}
+
+// Assertion 校验通过的断言
+type Assertion struct {
+	// 断言 ID（防重放）
+	ID string
+	// 对应的认证请求 ID（IdP 发起的登录为空）
+	InResponseTo string
+	Issuer       string
+	NameID       string
+	NameIDFormat string
+	SessionIndex string
+	// 断言的失效时间（防重放记录保留到此时）
+	NotOnOrAfter time.Time
+	// 属性（按 Name 及 FriendlyName 索引）
+	Attributes map[string][]string
+}
+
+// Attribute 获取属性的第一个值
+func (a *Assertion) Attribute(name string) string {
+	if values := a.Attributes[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// ParseResponse 解码并校验 HTTP-POST 绑定提交的 SAMLResponse（Base64）。
+// 响应或断言至少有一个由 IdP 证书签名；只使用签名覆盖范围内的断言
+func (sp *ServiceProvider) ParseResponse(encoded string) (*Assertion, error) {
+	data, err := decodeBase64(encoded)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+	root, err := parseXML(data)
+	if err != nil {
+		return nil, err
+	}
+	if !root.is(nsProtocol, "Response") || root.attr("Version") != "2.0" {
+		return nil, ErrInvalidResponse
+	}
+
+	responseSigned, err := verifySignature(root, sp.IdPCertificates)
+	if err != nil {
+		return nil, err
+	}
+	if dest := root.attr("Destination"); dest != "" && dest != sp.ACSURL {
+		return nil, ErrDestination
+	}
+	if issuer := root.child(nsAssertion, "Issuer"); issuer != nil && sp.IdPEntityID != "" && issuer.text() != sp.IdPEntityID {
+		return nil, ErrIssuer
+	}
+
+	status := root.child(nsProtocol, "Status")
+	if status == nil {
+		return nil, ErrInvalidResponse
+	}
+	if code := status.child(nsProtocol, "StatusCode"); code == nil || code.attr("Value") != statusSuccess {
+		if msg := status.child(nsProtocol, "StatusMessage"); msg != nil && msg.text() != "" {
+			return nil, fmt.Errorf("%w: %s", ErrStatus, msg.text())
+		}
+		return nil, ErrStatus
+	}
+
+	if len(root.childrenNamed(nsAssertion, "EncryptedAssertion")) > 0 {
+		return nil, ErrEncryptedAssertion
+	}
+	assertions := root.childrenNamed(nsAssertion, "Assertion")
+	if len(assertions) != 1 {
+		return nil, ErrInvalidResponse
+	}
+	assertion := assertions[0]
+	assertionSigned, err := verifySignature(assertion, sp.IdPCertificates)
+	if err != nil {
+		return nil, err
+	}
+	if !responseSigned && !assertionSigned {
+		return nil, ErrSignature
+	}
+
+	// 只有断言签名时响应外层可被替换：InResponseTo 只取签名覆盖的响应或断言中的 SubjectConfirmationData
+	inResponseTo := ""
+	if responseSigned {
+		inResponseTo = root.attr("InResponseTo")
+	}
+	return sp.validateAssertion(assertion, inResponseTo, time.Now())
+}
+
+// validateAssertion 校验断言的颁发者、主体确认、有效期与受众，提取名称标识与属性
+func (sp *ServiceProvider) validateAssertion(a *element, inResponseTo string, now time.Time) (*Assertion, error) {
+	if a.attr("Version") != "2.0" || a.attr("ID") == "" {
+		return nil, ErrInvalidResponse
+	}
+	result := &Assertion{ID: a.attr("ID"), InResponseTo: inResponseTo, Attributes: make(map[string][]string)}
+
+	issuer := a.child(nsAssertion, "Issuer")
+	if issuer == nil {
+		return nil, ErrInvalidResponse
+	}
+	result.Issuer = issuer.text()
+	if sp.IdPEntityID != "" && result.Issuer != sp.IdPEntityID {
+		return nil, ErrIssuer
+	}
+
+	subject := a.child(nsAssertion, "Subject")
+	if subject == nil {
+		return nil, ErrInvalidResponse
+	}
+	nameID := subject.child(nsAssertion, "NameID")
+	if nameID == nil || nameID.text() == "" {
+		return nil, ErrInvalidResponse
+	}
+	result.NameID, result.NameIDFormat = nameID.text(), nameID.attr("Format")
+
+	// 至少一个 bearer 主体确认：接收地址为本服务的 ACS，且仍在有效期内
+	confirmed := false
+	for _, sc := range subject.childrenNamed(nsAssertion, "SubjectConfirmation") {
+		if sc.attr("Method") != methodBearer {
+			continue
+		}
+		data := sc.child(nsAssertion, "SubjectConfirmationData")
+		if data == nil || data.attr("Recipient") != sp.ACSURL {
+			continue
+		}
+		if irt := data.attr("InResponseTo"); irt != "" {
+			if inResponseTo != "" && irt != inResponseTo {
+				continue
+			}
+			result.InResponseTo = irt
+		}
+		notOnOrAfter, err := parseTime(data.attr("NotOnOrAfter"))
+		if err != nil || !now.Before(notOnOrAfter.Add(sp.ClockSkew)) {
+			continue
+		}
+		confirmed = true
+		result.NotOnOrAfter = notOnOrAfter
+		break
+	}
+	if !confirmed {
+		return nil, ErrExpired
+	}
+
+	conditions := a.child(nsAssertion, "Conditions")
+	if conditions == nil {
+		return nil, ErrInvalidResponse
+	}
+	if v := conditions.attr("NotBefore"); v != "" {
+		notBefore, err := parseTime(v)
+		if err != nil || now.Add(sp.ClockSkew).Before(notBefore) {
+			return nil, ErrExpired
+		}
+	}
+	if v := conditions.attr("NotOnOrAfter"); v != "" {
+		notOnOrAfter, err := parseTime(v)
+		if err != nil || !now.Before(notOnOrAfter.Add(sp.ClockSkew)) {
+			return nil, ErrExpired
+		}
+		if notOnOrAfter.Before(result.NotOnOrAfter) {
+			result.NotOnOrAfter = notOnOrAfter
+		}
+	}
+	// 每个受众限制都必须包含本服务
+	restrictions := conditions.childrenNamed(nsAssertion, "AudienceRestriction")
+	if len(restrictions) == 0 {
+		return nil, ErrAudience
+	}
+	for _, r := range restrictions {
+		found := false
+		for _, audience := range r.childrenNamed(nsAssertion, "Audience") {
+			if audience.text() == sp.EntityID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, ErrAudience
+		}
+	}
+
+	if authn := a.child(nsAssertion, "AuthnStatement"); authn != nil {
+		result.SessionIndex = authn.attr("SessionIndex")
+	}
+	for _, statement := range a.childrenNamed(nsAssertion, "AttributeStatement") {
+		for _, attribute := range statement.childrenNamed(nsAssertion, "Attribute") {
+			var values []string
+			for _, v := range attribute.childrenNamed(nsAssertion, "AttributeValue") {
+				values = append(values, v.text())
+			}
+			for _, name := range []string{attribute.attr("Name"), attribute.attr("FriendlyName")} {
+				if name != "" {
+					result.Attributes[name] = append(result.Attributes[name], values...)
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// parseTime 解析 xs:dateTime
+func parseTime(v string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, v)
+}
+
+// newID 生成请求 ID（须以字母或下划线开头）
+func newID() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "_" + hex.EncodeToString(b), nil
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,192 @@
+package saml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// errXML XML 格式错误或使用了不支持的特性
+var errXML = errors.New("SAML 响应不是有效的 XML")
+
+// maxXMLDepth 元素的最大嵌套深度
+const maxXMLDepth = 64
+
+// 命名空间
+const (
+	nsXML       = "http://www.w3.org/XML/1998/namespace"
+	nsDSig      = "http://www.w3.org/2000/09/xmldsig#"
+	nsProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	nsAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	nsMetadata  = "urn:oasis:names:tc:SAML:2.0:metadata"
+)
+
+// element XML 元素：保留命名空间前缀与声明，供规范化（C14N）还原签名时的字节
+type element struct {
+	parent *element
+	prefix string
+	local  string
+	// 本元素上的命名空间声明（前缀为空表示默认命名空间）
+	ns    []attr
+	attrs []attr
+	// 子节点：*element 或文本（string）
+	children []interface{}
+}
+
+type attr struct {
+	prefix string
+	local  string
+	value  string
+}
+
+// parseXML 解析 XML 文档，返回根元素。拒绝 DTD（避免实体扩展）与元素内的处理指令，忽略注释
+func parseXML(data []byte) (*element, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var root, cur *element
+	depth := 0
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errXML
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if root != nil && cur == nil {
+				// 根元素之后出现第二个元素
+				return nil, errXML
+			}
+			depth++
+			if depth > maxXMLDepth {
+				return nil, errXML
+			}
+			e := &element{parent: cur, prefix: t.Name.Space, local: t.Name.Local}
+			seen := make(map[string]bool, len(t.Attr))
+			for _, a := range t.Attr {
+				key := a.Name.Space + ":" + a.Name.Local
+				if seen[key] {
+					return nil, errXML
+				}
+				seen[key] = true
+				switch {
+				case a.Name.Space == "xmlns":
+					e.ns = append(e.ns, attr{local: a.Name.Local, value: a.Value})
+				case a.Name.Space == "" && a.Name.Local == "xmlns":
+					e.ns = append(e.ns, attr{value: a.Value})
+				default:
+					e.attrs = append(e.attrs, attr{prefix: a.Name.Space, local: a.Name.Local, value: a.Value})
+				}
+			}
+			// 未声明的前缀
+			if _, ok := e.lookup(e.prefix); !ok {
+				return nil, errXML
+			}
+			for _, a := range e.attrs {
+				if _, ok := e.lookup(a.prefix); !ok {
+					return nil, errXML
+				}
+			}
+			if cur != nil {
+				cur.children = append(cur.children, e)
+			} else {
+				root = e
+			}
+			cur = e
+		case xml.EndElement:
+			if cur == nil || t.Name.Space != cur.prefix || t.Name.Local != cur.local {
+				return nil, errXML
+			}
+			depth--
+			cur = cur.parent
+		case xml.CharData:
+			if cur != nil {
+				cur.children = append(cur.children, string(t))
+			} else if len(bytes.TrimSpace(t)) > 0 {
+				return nil, errXML
+			}
+		case xml.ProcInst:
+			// 只允许文档开头的 XML 声明
+			if root != nil || t.Target != "xml" {
+				return nil, errXML
+			}
+		case xml.Directive:
+			return nil, errXML
+		}
+	}
+	if root == nil || cur != nil {
+		return nil, errXML
+	}
+	return root, nil
+}
+
+// lookup 按前缀查找在作用域内的命名空间
+func (e *element) lookup(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return nsXML, true
+	}
+	for n := e; n != nil; n = n.parent {
+		for _, ns := range n.ns {
+			if ns.local == prefix {
+				return ns.value, true
+			}
+		}
+	}
+	return "", prefix == ""
+}
+
+// space 元素的命名空间
+func (e *element) space() string {
+	uri, _ := e.lookup(e.prefix)
+	return uri
+}
+
+// is 元素是否为指定命名空间下的指定名称
+func (e *element) is(space, local string) bool {
+	return e.local == local && e.space() == space
+}
+
+// attr 获取不带前缀的属性
+func (e *element) attr(local string) string {
+	for _, a := range e.attrs {
+		if a.prefix == "" && a.local == local {
+			return a.value
+		}
+	}
+	return ""
+}
+
+// child 第一个指定名称的子元素
+func (e *element) child(space, local string) *element {
+	for _, c := range e.children {
+		if ce, ok := c.(*element); ok && ce.is(space, local) {
+			return ce
+		}
+	}
+	return nil
+}
+
+// childrenNamed 所有指定名称的子元素
+func (e *element) childrenNamed(space, local string) []*element {
+	var result []*element
+	for _, c := range e.children {
+		if ce, ok := c.(*element); ok && ce.is(space, local) {
+			result = append(result, ce)
+		}
+	}
+	return result
+}
+
+// text 元素的文本内容（不含子元素中的文本），去除首尾空白
+func (e *element) text() string {
+	var b strings.Builder
+	for _, c := range e.children {
+		if s, ok := c.(string); ok {
+			b.WriteString(s)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,79 @@
+package secrets
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"sync"
+)
+
+// EncryptedString 加密存储的字符串字段：写库时用当前 KEK 加密，读库时自动解密
+//
+// 用法：
+//
+//	type AppKey struct {
+//		Secret secrets.EncryptedString `gorm:"type:text"`
+//	}
+//
+//	func init() { secrets.RegisterColumn("app_keys", "secret") }
+type EncryptedString string
+
+// Value 实现 driver.Valuer
+func (s EncryptedString) Value() (driver.Value, error) {
+	if s == "" {
+		return "", nil
+	}
+	return Encrypt(string(s))
+}
+
+// Scan 实现 sql.Scanner
+func (s *EncryptedString) Scan(src interface{}) error {
+	var raw string
+	switch v := src.(type) {
+	case nil:
+		*s = ""
+		return nil
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("secrets: 不支持的类型 %T", src)
+	}
+
+	plaintext, err := Decrypt(raw)
+	if err != nil {
+		return err
+	}
+	*s = EncryptedString(plaintext)
+	return nil
+}
+
+// String 返回明文
+func (s EncryptedString) String() string {
+	return string(s)
+}
+
+// Column 加密存储的列（主键列固定为 id）
+type Column struct {
+	Table  string
+	Column string
+}
+
+var (
+	columnsMu sync.Mutex
+	columns   []Column
+)
+
+// RegisterColumn 登记加密列，供密钥轮换后的重新加密命令遍历
+func RegisterColumn(table, column string) {
+	columnsMu.Lock()
+	defer columnsMu.Unlock()
+	columns = append(columns, Column{Table: table, Column: column})
+}
+
+// Columns 已登记的加密列
+func Columns() []Column {
+	columnsMu.Lock()
+	defer columnsMu.Unlock()
+	return append([]Column(nil), columns...)
+}
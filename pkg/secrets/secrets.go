@@ -0,0 +1,210 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// prefix 密文前缀，完整格式：enc:<版本>:<base64(nonce || 密文)>
+const prefix = "enc:"
+
+var (
+	// ErrNoKEK 未配置 KEK
+	ErrNoKEK = errors.New("secrets: 未配置 KEK")
+	// ErrUnknownVersion 密文使用的 KEK 版本不存在
+	ErrUnknownVersion = errors.New("secrets: 未知的 KEK 版本")
+	// ErrMalformed 密文格式错误
+	ErrMalformed = errors.New("secrets: 密文格式错误")
+)
+
+// Config 静态密钥加密配置
+type Config struct {
+	// 当前 KEK（base64 编码的 32 字节 AES-256 密钥）
+	KEK string
+	// 当前 KEK 文件（由 KMS / Vault Agent 解密后挂载，优先于 KEK）
+	KEKFile string
+	// 当前 KEK 版本，写入密文前缀
+	Version string
+	// 历史 KEK：版本 => base64 密钥，仅用于解密轮换前写入的数据
+	Retired map[string]string
+}
+
+// Keyring 已加载的 KEK
+type Keyring struct {
+	version string
+	aeads   map[string]cipher.AEAD
+}
+
+var (
+	mu      sync.RWMutex
+	current *Keyring
+)
+
+// Configure 加载 KEK；未配置 KEK 时新数据以明文写入（仅适用于开发环境）
+func Configure(cfg Config) error {
+	kr, err := Load(cfg)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	current = kr
+	mu.Unlock()
+	return nil
+}
+
+// Load 根据配置构建 Keyring
+func Load(cfg Config) (*Keyring, error) {
+	kr := &Keyring{aeads: make(map[string]cipher.AEAD)}
+
+	for version, encoded := range cfg.Retired {
+		aead, err := newAEAD(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("历史 KEK %s: %w", version, err)
+		}
+		kr.aeads[version] = aead
+	}
+
+	encoded := cfg.KEK
+	if cfg.KEKFile != "" {
+		data, err := os.ReadFile(cfg.KEKFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 KEK 文件失败: %w", err)
+		}
+		encoded = strings.TrimSpace(string(data))
+	}
+	if encoded == "" {
+		return kr, nil
+	}
+
+	if cfg.Version == "" || strings.Contains(cfg.Version, ":") {
+		return nil, fmt.Errorf("无效的 KEK 版本: %q", cfg.Version)
+	}
+	aead, err := newAEAD(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("KEK: %w", err)
+	}
+	kr.version = cfg.Version
+	kr.aeads[cfg.Version] = aead
+	return kr, nil
+}
+
+// Default 当前全局 Keyring
+func Default() *Keyring {
+	mu.RLock()
+	defer mu.RUnlock()
+	if current == nil {
+		return &Keyring{aeads: map[string]cipher.AEAD{}}
+	}
+	return current
+}
+
+// Enabled 是否配置了当前 KEK
+func (k *Keyring) Enabled() bool {
+	return k.version != ""
+}
+
+// Version 当前 KEK 版本
+func (k *Keyring) Version() string {
+	return k.version
+}
+
+// Encrypt 使用当前 KEK 加密；未配置 KEK 时原样返回
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if !k.Enabled() {
+		return plaintext, nil
+	}
+	aead := k.aeads[k.version]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(k.version))
+	return prefix + k.version + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 按密文中的版本选择 KEK 解密；不带前缀的值视为历史明文
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, prefix) {
+		return value, nil
+	}
+	parts := strings.SplitN(strings.TrimPrefix(value, prefix), ":", 2)
+	if len(parts) != 2 {
+		return "", ErrMalformed
+	}
+
+	aead, ok := k.aeads[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownVersion, parts[0])
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrMalformed
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(parts[0]))
+	if err != nil {
+		return "", fmt.Errorf("secrets: 解密失败: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsReencrypt 值是否需要用当前 KEK 重新加密（明文或旧版本密文）
+func (k *Keyring) NeedsReencrypt(value string) bool {
+	if !k.Enabled() || value == "" {
+		return false
+	}
+	return VersionOf(value) != k.version
+}
+
+// VersionOf 返回密文的 KEK 版本，明文返回空字符串
+func VersionOf(value string) string {
+	if !strings.HasPrefix(value, prefix) {
+		return ""
+	}
+	version, _, _ := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	return version
+}
+
+// Encrypt 使用全局 Keyring 加密
+func Encrypt(plaintext string) (string, error) {
+	return Default().Encrypt(plaintext)
+}
+
+// Decrypt 使用全局 Keyring 解密
+func Decrypt(value string) (string, error) {
+	return Default().Decrypt(value)
+}
+
+// DeriveKey 由主密钥派生指定用途的子密钥：HMAC-SHA256(master, purpose)。
+// 未单独配置签名密钥的功能使用派生密钥，避免与 JWT 等共用同一把密钥（purpose 带版本号，如 "upload-url-v1"）
+func DeriveKey(master, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(master))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+func newAEAD(encoded string) (cipher.AEAD, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("base64 解码失败: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("密钥长度必须为 32 字节，实际 %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,452 @@
+package server_test
+
+// 接口契约测试：以单机模式（SQLite、内存存储）通过 NewServer 组装完整的中间件与路由，
+// 检查 @Router 注释与注册的路由一致，并按注释中的参数（path/query 的 example，缺省时取占位值）
+// 逐个重放接口，断言状态码在注释声明的范围内、响应体符合统一格式与声明的类型
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	adminmiddleware "new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/admin/rbac"
+	"new-openclaw/internal/breakglass"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/jobs"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/model"
+	"new-openclaw/internal/replay"
+	"new-openclaw/internal/session"
+	"new-openclaw/internal/threatfeed"
+	"new-openclaw/pkg/auth/token"
+	"new-openclaw/pkg/config"
+	"new-openclaw/pkg/server"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/logger"
+)
+
+// sourceDir 扫描 @Router 注释的源码目录（相对于本包）
+const sourceDir = "../../internal"
+
+// responseTypes 注释中 {object}/{array} 声明的响应类型（成功响应的 data，或不使用统一格式的整个响应体）
+var responseTypes = map[string]reflect.Type{
+	"breakglass.Status":        reflect.TypeOf(breakglass.Status{}),
+	"jobs.Status":              reflect.TypeOf(jobs.Status{}),
+	"model.Admin":              reflect.TypeOf(model.Admin{}),
+	"model.AdminLoginResponse": reflect.TypeOf(model.AdminLoginResponse{}),
+	"model.PartnerTransform":   reflect.TypeOf(model.PartnerTransform{}),
+	"model.WebAuthnCredential": reflect.TypeOf(model.WebAuthnCredential{}),
+	"replay.Result":            reflect.TypeOf(replay.Result{}),
+	"session.Session":          reflect.TypeOf(session.Session{}),
+	"threatfeed.Bundle":        reflect.TypeOf(threatfeed.Bundle{}),
+	"token.JWKSet":             reflect.TypeOf(token.JWKSet{}),
+}
+
+// commonStatuses 未在注释中声明、但任何接口都可能返回的状态码（参数错误、未认证、无权限、资源不存在、冲突、限流、依赖不可用）
+var commonStatuses = []int{
+	http.StatusBadRequest,
+	http.StatusUnauthorized,
+	http.StatusForbidden,
+	http.StatusNotFound,
+	http.StatusConflict,
+	http.StatusTooManyRequests,
+	http.StatusServiceUnavailable,
+}
+
+// contractAdmin 契约测试使用的超级管理员
+const contractAdmin = "contract-admin"
+
+var (
+	engine  *gin.Engine
+	adminID uint
+	// users 已创建的契约测试用户数
+	users int
+)
+
+var (
+	// routerPattern // @Router /admin/admins/{id} [put]
+	routerPattern = regexp.MustCompile(`@Router\s+(\S+)\s+\[(\w+)\]`)
+	// paramPattern // @Param id path int true "管理员 ID" example(1)
+	paramPattern = regexp.MustCompile(`@Param\s+(\S+)\s+(\w+)\s+(\S+)\s+(true|false)(?:\s+"[^"]*")?(?:.*example\(([^)]*)\))?`)
+	// responsePattern // @Success 200 {object} model.Admin
+	responsePattern = regexp.MustCompile(`@(Success|Failure)\s+(\d{3})(?:\s+\{(\w+)\}\s+(\S+))?`)
+	// producePattern // @Produce json
+	producePattern = regexp.MustCompile(`@Produce\s+(\S+)`)
+	// pathParamPattern OpenAPI 路径参数 {id}
+	pathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+)
+
+// param 注释中声明的参数
+type param struct {
+	name     string
+	in       string
+	typ      string
+	required bool
+	example  string
+}
+
+// response 注释中声明的响应
+type response struct {
+	kind string // object、array、string、file
+	typ  string
+}
+
+// endpoint 注释中声明的接口
+type endpoint struct {
+	method    string
+	path      string
+	pos       string
+	produce   string
+	params    []param
+	responses map[int]response
+}
+
+// route gin 格式的路由（{id} 转换为 :id）
+func (e endpoint) route() string {
+	return e.method + " " + pathParamPattern.ReplaceAllString(e.path, ":$1")
+}
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	shutdown, err := setup()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "启动契约测试环境失败: %v\n", err)
+		os.Exit(2)
+	}
+	code := m.Run()
+	shutdown()
+	os.Exit(code)
+}
+
+// setup 以单机模式组装服务，并创建契约测试使用的超级管理员
+func setup() (func(), error) {
+	dir, err := os.MkdirTemp("", "contract")
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range map[string]string{
+		"APP_MODE":                config.AppModeStandalone,
+		"SQLITE_PATH":             filepath.Join(dir, "contract.db"),
+		"AUDIT_FILE_PATH":         filepath.Join(dir, "audit.log"),
+		"UPLOAD_LOCAL_DIR":        filepath.Join(dir, "uploads"),
+		"CAPTCHA_ENABLED":         "false",
+		"ABUSE_BAN_THRESHOLD":     "0",
+		"LOGIN_DELAY_AFTER":       "0",
+		"RATE_LIMIT_MAX_REQUESTS": "1000000",
+	} {
+		os.Setenv(key, value)
+	}
+
+	// 初始化日志与 SQL 日志只在 -v 时输出
+	if !testing.Verbose() {
+		log.SetOutput(io.Discard)
+		logger.Default = logger.New(log.New(io.Discard, "", 0), logger.Config{})
+	}
+	gin.SetMode(gin.TestMode)
+	r, shutdown, err := server.NewServer(config.LoadConfig())
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	cleanup := func() {
+		shutdown()
+		os.RemoveAll(dir)
+	}
+
+	admin := model.Admin{Username: contractAdmin, Role: rbac.SuperAdmin, Status: 1}
+	if err = admin.SetPassword("contract-admin-password"); err == nil {
+		err = database.MySQL.Create(&admin).Error
+	}
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	engine, adminID = r, admin.ID
+	return cleanup, nil
+}
+
+// TestRoutesDocumented 注释声明的接口必须已注册，已注册的接口必须有注释
+func TestRoutesDocumented(t *testing.T) {
+	endpoints := scan(t)
+
+	registered := make(map[string]bool)
+	for _, r := range engine.Routes() {
+		registered[r.Method+" "+r.Path] = true
+	}
+	documented := make(map[string]bool)
+	for _, e := range endpoints {
+		documented[e.route()] = true
+		if !registered[e.route()] {
+			t.Errorf("%s 注释声明的接口未注册: %s", e.pos, e.route())
+		}
+	}
+	var undocumented []string
+	for route := range registered {
+		if !documented[route] {
+			undocumented = append(undocumented, route)
+		}
+	}
+	sort.Strings(undocumented)
+	for _, route := range undocumented {
+		t.Errorf("接口缺少 @Router 注释: %s", route)
+	}
+	t.Logf("已注册 %d 个接口，注释 %d 个", len(registered), len(documented))
+}
+
+// TestDocumentedExamples 按注释重放每个接口（先执行查询，再执行修改），检查状态码与响应体
+func TestDocumentedExamples(t *testing.T) {
+	endpoints := scan(t)
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		return (endpoints[i].method == http.MethodGet) && (endpoints[j].method != http.MethodGet)
+	})
+
+	for _, e := range endpoints {
+		e := e
+		t.Run(e.method+" "+e.path, func(t *testing.T) {
+			w := serve(t, e)
+			check(t, e, w)
+		})
+	}
+}
+
+// serve 按注释构造请求并发送到进程内的服务
+func serve(t *testing.T, e endpoint) *httptest.ResponseRecorder {
+	t.Helper()
+
+	path, query := e.path, url.Values{}
+	var body io.Reader
+	contentType := ""
+	for _, p := range e.params {
+		value := p.example
+		switch p.in {
+		case "path":
+			if value == "" {
+				value = placeholder(e.method, p.typ)
+			}
+			path = strings.ReplaceAll(path, "{"+p.name+"}", url.PathEscape(value))
+		case "query":
+			if value == "" && p.required {
+				value = placeholder(e.method, p.typ)
+			}
+			if value != "" {
+				query.Set(p.name, value)
+			}
+		case "body":
+			// 请求体不携带示例，只检查参数校验是否返回统一格式的错误
+			body, contentType = strings.NewReader("{}"), "application/json"
+		case "formData":
+			body, contentType = strings.NewReader(""), "application/x-www-form-urlencoded"
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	req := httptest.NewRequest(e.method, path, body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	if token := credential(t, e.path); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+// check 状态码须为注释声明的或通用的错误码；JSON 响应的错误须为统一格式，成功响应须符合声明的类型
+func check(t *testing.T, e endpoint, w *httptest.ResponseRecorder) {
+	t.Helper()
+
+	status := w.Code
+	t.Logf("%d %s", status, truncate(w.Body.String()))
+	declared, ok := e.responses[status]
+	if !ok && !containsStatus(commonStatuses, status) {
+		t.Fatalf("%s 返回未声明的状态码 %d: %s", e.pos, status, truncate(w.Body.String()))
+	}
+	if status == http.StatusFound || status == http.StatusNoContent || status == http.StatusNotModified {
+		return
+	}
+	if status < 400 && e.produce != "" && e.produce != "json" {
+		return
+	}
+
+	var payload map[string]json.RawMessage
+	if _, typed := responseTypes[declared.typ]; status >= 400 || declared.kind == "object" && !typed {
+		if err := json.Unmarshal(w.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("%s 状态码 %d 的响应不是 JSON 对象: %v: %s", e.pos, status, err, truncate(w.Body.String()))
+		}
+	}
+	if status >= 400 {
+		var envelope struct {
+			Code    *int    `json:"code"`
+			Message *string `json:"message"`
+			Path    string  `json:"path"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &envelope)
+		if envelope.Code == nil || envelope.Message == nil {
+			t.Fatalf("%s 错误响应缺少 code 或 message: %s", e.pos, truncate(w.Body.String()))
+		}
+		if status == http.StatusNotFound && envelope.Path != "" {
+			t.Fatalf("%s 请求未匹配到已注册的路由: %s", e.pos, envelope.Path)
+		}
+		return
+	}
+
+	typ, ok := responseTypes[declared.typ]
+	if !ok {
+		if declared.kind == "object" || declared.kind == "array" {
+			if !strings.HasPrefix(declared.typ, "map[") && declared.typ != "object" {
+				t.Fatalf("%s 响应类型 %s 未在 responseTypes 中登记", e.pos, declared.typ)
+			}
+		}
+		return
+	}
+	if declared.kind == "array" {
+		typ = reflect.SliceOf(typ)
+	}
+	data := w.Body.Bytes()
+	var envelope struct {
+		Code *int            `json:"code"`
+		Data json.RawMessage `json:"data"`
+	}
+	if json.Unmarshal(data, &envelope) == nil && envelope.Code != nil {
+		data = envelope.Data
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(reflect.New(typ).Interface()); err != nil {
+		t.Fatalf("%s 响应不符合声明的类型 %s: %v: %s", e.pos, declared.typ, err, truncate(string(data)))
+	}
+}
+
+// credential 按路径选择凭证：管理后台使用新签发的超级管理员 Token，需要登录的用户接口使用新建用户的 Token
+// （吊销全部会话、删除用户等用例不影响后续用例）
+func credential(t *testing.T, path string) string {
+	t.Helper()
+
+	switch {
+	case strings.HasPrefix(path, "/admin/"):
+		token, _, err := adminmiddleware.GenerateToken(adminID, contractAdmin, rbac.SuperAdmin)
+		if err != nil {
+			t.Fatalf("生成管理员 Token 失败: %v", err)
+		}
+		return token
+	case strings.HasPrefix(path, "/api/v1/") && !strings.HasPrefix(path, "/api/v1/public/"):
+		users++
+		user := model.User{Username: fmt.Sprintf("contract-user-%d", users), Role: "admin", Status: model.UserStatusActive}
+		user.Email = user.Username + "@example.com"
+		if err := database.MySQL.Create(&user).Error; err != nil {
+			t.Fatalf("创建用户失败: %v", err)
+		}
+		id := strconv.FormatUint(uint64(user.ID), 10)
+		token, err := middleware.GenerateTokenWithScopes(id, user.Username, user.Role, middleware.RoleScopes[user.Role], middleware.CurrentJWTConfig())
+		if err != nil {
+			t.Fatalf("生成用户 Token 失败: %v", err)
+		}
+		return token
+	}
+	return ""
+}
+
+// placeholder 没有示例的参数取占位值：查询使用已存在的 ID，修改使用不存在的 ID（避免删除契约测试账号）
+func placeholder(method, typ string) string {
+	if typ == "int" || typ == "integer" {
+		if method == http.MethodGet {
+			return "1"
+		}
+		return "999999"
+	}
+	return "contract"
+}
+
+// scan 扫描源码中的接口注释：同一个函数注释中的 @Param、@Success、@Failure、@Produce 适用于其中所有的 @Router
+func scan(t *testing.T) []endpoint {
+	t.Helper()
+
+	var endpoints []endpoint
+	err := filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		var block endpoint
+		var routers []endpoint
+		flush := func() {
+			for _, r := range routers {
+				r.produce, r.params, r.responses = block.produce, block.params, block.responses
+				endpoints = append(endpoints, r)
+			}
+			block, routers = endpoint{}, nil
+		}
+
+		scanner := bufio.NewScanner(file)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if !strings.HasPrefix(text, "//") {
+				flush()
+				continue
+			}
+			if block.responses == nil {
+				block.responses = make(map[int]response)
+			}
+			if m := routerPattern.FindStringSubmatch(text); m != nil {
+				routers = append(routers, endpoint{method: strings.ToUpper(m[2]), path: m[1], pos: fmt.Sprintf("%s:%d", path, line)})
+			} else if m := paramPattern.FindStringSubmatch(text); m != nil {
+				block.params = append(block.params, param{name: m[1], in: m[2], typ: m[3], required: m[4] == "true", example: m[5]})
+			} else if m := responsePattern.FindStringSubmatch(text); m != nil {
+				code, _ := strconv.Atoi(m[2])
+				block.responses[code] = response{kind: m[3], typ: m[4]}
+			} else if m := producePattern.FindStringSubmatch(text); m != nil {
+				block.produce = m[1]
+			}
+		}
+		flush()
+		return scanner.Err()
+	})
+	if err != nil {
+		t.Fatalf("扫描注释失败: %v", err)
+	}
+	return endpoints
+}
+
+func containsStatus(statuses []int, status int) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate 截断过长的响应体
+func truncate(s string) string {
+	if len(s) > 300 {
+		return s[:300] + "..."
+	}
+	return s
+}
@@ -0,0 +1,771 @@
+// Package server 组装完整的服务：初始化依赖、后台任务与全部中间件，注册业务与管理后台路由。
+// cmd/server 与嵌入本 API 的其他程序、端到端测试使用同一个构造函数，得到与生产环境一致的路由
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"new-openclaw/internal/admin"
+	"new-openclaw/internal/admin/analytics"
+	"new-openclaw/internal/admin/dashboard"
+	adminhandler "new-openclaw/internal/admin/handler"
+	"new-openclaw/internal/admin/menu"
+	adminmiddleware "new-openclaw/internal/admin/middleware"
+	"new-openclaw/internal/admin/rbac"
+	"new-openclaw/internal/apikey"
+	"new-openclaw/internal/appkey"
+	"new-openclaw/internal/auditsink"
+	"new-openclaw/internal/auditstore"
+	"new-openclaw/internal/breakglass"
+	"new-openclaw/internal/captcha"
+	"new-openclaw/internal/configcenter"
+	"new-openclaw/internal/database"
+	"new-openclaw/internal/emailverify"
+	"new-openclaw/internal/geoip"
+	"new-openclaw/internal/handler"
+	"new-openclaw/internal/health"
+	"new-openclaw/internal/iprules"
+	"new-openclaw/internal/jobs"
+	"new-openclaw/internal/leader"
+	"new-openclaw/internal/loginguard"
+	"new-openclaw/internal/loginhistory"
+	"new-openclaw/internal/mail"
+	"new-openclaw/internal/middleware"
+	"new-openclaw/internal/notify"
+	"new-openclaw/internal/oauth"
+	"new-openclaw/internal/passwordreset"
+	"new-openclaw/internal/pat"
+	"new-openclaw/internal/quota"
+	"new-openclaw/internal/replay"
+	"new-openclaw/internal/reputation"
+	"new-openclaw/internal/revocation"
+	"new-openclaw/internal/secevents"
+	"new-openclaw/internal/secstate"
+	"new-openclaw/internal/session"
+	"new-openclaw/internal/settings"
+	"new-openclaw/internal/store"
+	"new-openclaw/internal/threatfeed"
+	"new-openclaw/internal/upload"
+	"new-openclaw/internal/wafrules"
+	"new-openclaw/internal/webhook"
+	"new-openclaw/pkg/auth/token"
+	"new-openclaw/pkg/config"
+	"new-openclaw/pkg/password"
+	"new-openclaw/pkg/secrets"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewServer 按配置初始化数据库、状态存储与后台任务，返回挂载了完整中间件与路由的 gin.Engine
+// 及关闭函数（停止后台任务、发送剩余审计日志、关闭数据库连接）。
+//
+// 监听端口、服务发现注册、信号处理（SIGHUP 重新加载 JWT 密钥、退出时调用关闭函数）由调用方负责；
+// 各组件的状态为进程级，每个进程只应调用一次。密钥、审计输出或脱敏规则等配置错误时停止已启动的后台任务并返回错误，
+// 是否退出进程由调用方决定
+func NewServer(cfg *config.Config) (*gin.Engine, func() error, error) {
+	var auditLogger *middleware.AuditLogger
+	shutdown := func() error {
+		configcenter.Stop()
+		jobs.Stop()
+		webhook.Stop()
+		iprules.Stop()
+		wafrules.Stop()
+		settings.Stop()
+		rbac.Stop()
+		secevents.Stop()
+		if auditLogger != nil {
+			// 发送外部输出中尚未发送的审计日志
+			auditLogger.Close()
+		}
+		leader.Stop()
+		return database.CloseAll()
+	}
+	// fail 配置错误：停止已启动的组件并返回错误
+	fail := func(err error) (*gin.Engine, func() error, error) {
+		shutdown()
+		return nil, nil, err
+	}
+
+	// 初始化数据库连接
+	if err := database.InitAll(cfg); err != nil {
+		log.Printf("数据库初始化警告: %v", err)
+	}
+
+	// 初始化状态存储（nonce、会话、频率限制）
+	store.Init(&cfg.Store)
+
+	// 密码哈希算法
+	password.Configure(password.Config{
+		Algorithm:         cfg.Password.Algorithm,
+		BcryptCost:        cfg.Password.BcryptCost,
+		Argon2Memory:      uint32(cfg.Password.Argon2Memory),
+		Argon2Iterations:  uint32(cfg.Password.Argon2Iterations),
+		Argon2Parallelism: uint8(cfg.Password.Argon2Parallelism),
+		ScryptN:           cfg.Password.ScryptN,
+		ScryptR:           cfg.Password.ScryptR,
+		ScryptP:           cfg.Password.ScryptP,
+	})
+	password.ConfigurePolicy(password.Policy{
+		MinLength:  cfg.Password.MinLength,
+		MaxLength:  cfg.Password.MaxLength,
+		MinClasses: cfg.Password.MinClasses,
+	})
+
+	// 敏感列加密 KEK
+	if err := secrets.Configure(secrets.Config{
+		KEK:     cfg.Secrets.KEK,
+		KEKFile: cfg.Secrets.KEKFile,
+		Version: cfg.Secrets.KEKVersion,
+		Retired: cfg.Secrets.RetiredKEKs,
+	}); err != nil {
+		return fail(fmt.Errorf("加载 KEK 失败: %w", err))
+	}
+	if !secrets.Default().Enabled() {
+		log.Println("⚠️  未配置 SECRETS_KEK，敏感列将以明文存储（仅限开发环境）")
+	}
+
+	// AppKey 默认配额
+	quota.Configure(cfg.Quota)
+
+	// AppKey 签名密钥（按 AppKey 查库，结果缓存）
+	appkey.Configure(cfg.AppKey)
+
+	// API Key 认证（按 Key 摘要查库，结果缓存，定期记录最近使用时间）
+	apikey.Configure(cfg.APIKey)
+	middleware.APIKeyResolver = func(ctx context.Context, key, clientIP string) (*middleware.APIKeyIdentity, error) {
+		identity, err := apikey.Authenticate(ctx, key, clientIP)
+		if err != nil {
+			return nil, err
+		}
+		return &middleware.APIKeyIdentity{
+			ID:     identity.ID,
+			Name:   identity.Name,
+			Prefix: identity.Prefix,
+			Scopes: identity.Scopes,
+		}, nil
+	}
+
+	// 用户个人访问令牌（Bearer pat_...，按摘要查库，结果缓存）
+	pat.Configure(cfg.PersonalToken)
+	middleware.PersonalTokenResolver = func(ctx context.Context, token, clientIP string) (*middleware.PersonalTokenIdentity, error) {
+		identity, err := pat.Authenticate(ctx, token, clientIP)
+		if err != nil {
+			return nil, err
+		}
+		return &middleware.PersonalTokenIdentity{
+			ID:            identity.ID,
+			Prefix:        identity.Prefix,
+			UserID:        identity.UserID,
+			Username:      identity.Username,
+			Role:          identity.Role,
+			EmailVerified: identity.EmailVerified,
+			Scopes:        identity.Scopes,
+		}, nil
+	}
+
+	// 选主（单例后台任务只在 Leader 上运行）
+	if err := leader.Start(cfg.Leader); err != nil {
+		log.Printf("选主启动警告: %v", err)
+	}
+
+	// 管理员行为分析与异常告警
+	analytics.Configure(cfg.Analytics)
+	analytics.RegisterAlertJob()
+
+	// Webhook 投递（异步投递，失败按指数退避重试）
+	webhook.Configure(cfg.Webhook)
+	webhook.Start()
+	webhook.RegisterRetryJob()
+
+	// 依赖健康监测（状态变化时通知、记录并投递 Webhook）
+	health.Configure(cfg.Health)
+	health.OnChange(func(e health.Event) {
+		if _, err := webhook.Emit(webhook.EventDependencyStatusChanged, e); err != nil {
+			log.Printf("投递依赖状态变化 Webhook 失败: %v", err)
+		}
+	})
+	health.RegisterJob()
+
+	// 持久化 IP 规则定时重新加载（补偿遗漏的变更广播）
+	iprules.RegisterReloadJob(cfg.Security.IPRuleReloadInterval)
+
+	// WAF 规则（内置、规则文件与 waf_rules 表）定时重新加载
+	wafrules.Configure(cfg.WAF)
+	wafrules.RegisterReloadJob(cfg.WAF.ReloadInterval)
+
+	// 系统设置（settings 表）定时重新加载
+	settings.RegisterReloadJob(cfg.Settings.ReloadInterval)
+
+	// 管理员角色权限（admin_roles 表）定时重新加载
+	rbac.RegisterReloadJob(cfg.Roles.ReloadInterval)
+
+	// 管理后台首页统计的缓存时间
+	dashboard.Configure(cfg.Dashboard)
+
+	// ASN 数据库（IP 名单中按自治系统放行/封禁）
+	if err := geoip.Configure(cfg.Security.GeoIPASNDatabase); err != nil {
+		log.Printf("⚠️  %v，名单中的 ASN 条目暂不生效", err)
+	}
+	// 国家/地区数据库（登录记录中的位置）
+	if err := geoip.ConfigureCountry(cfg.Security.GeoIPCountryDatabase); err != nil {
+		log.Printf("⚠️  %v，登录记录暂不记录国家/地区", err)
+	}
+	geoip.RegisterReloadJob(cfg.Security.GeoIPReloadInterval)
+
+	// 公开的 IP 威胁情报黑名单（abuse.ch、FireHOL 等）定时下载
+	reputation.Configure(cfg.Reputation)
+	reputation.RegisterRefreshJob()
+
+	// 安全事件（攻击检测、认证失败、封禁）存储与告警推送
+	if err := secevents.Configure(cfg.SecurityEvents); err != nil {
+		return fail(fmt.Errorf("安全事件配置错误: %w", err))
+	}
+	secevents.Start()
+	secevents.RegisterCleanupJob()
+
+	// 登录暴力破解防护（账号锁定记录为安全事件）
+	loginguard.Configure(cfg.LoginGuard)
+
+	// 登录与注册的人机验证（图片验证码或第三方服务）
+	if err := captcha.Configure(cfg.Captcha); err != nil {
+		return fail(fmt.Errorf("人机验证配置错误: %w", err))
+	}
+
+	// 第三方登录（配置了 ClientID 的提供方启用）
+	if err := oauth.Configure(cfg.OAuth, cfg.Server.BaseURL); err != nil {
+		return fail(fmt.Errorf("第三方登录配置错误: %w", err))
+	}
+
+	// 安全事件订阅（攻击检测与自动封禁，供威胁情报汇聚系统拉取）
+	threatfeed.Configure(cfg.ThreatFeed)
+
+	// 邮件发送（管理员通知与注册邮箱验证共用 SMTP 配置）
+	mail.Configure(cfg.Mail)
+	// 未单独配置签名密钥时由 JWT 密钥派生，JWT 密钥仍为公开的默认值时派生出的密钥同样是公开的
+	if cfg.Security.JWTSecretKey == config.DefaultJWTSecretKey {
+		var derived []string
+		if cfg.EmailVerify.Secret == "" && mail.Enabled() {
+			derived = append(derived, "EMAIL_VERIFY_SECRET")
+		}
+		if cfg.Upload.URLSecret == "" {
+			derived = append(derived, "UPLOAD_URL_SECRET")
+		}
+		if len(derived) > 0 {
+			if !cfg.Standalone() {
+				return fail(fmt.Errorf("JWT_SECRET_KEY 为默认值，请修改 JWT_SECRET_KEY 或配置 %s", strings.Join(derived, "、")))
+			}
+			log.Printf("⚠️  JWT_SECRET_KEY 为默认值，%s 未配置时派生的签名密钥可被推算（仅限单机模式）", strings.Join(derived, "、"))
+		}
+	}
+	if err := emailverify.Configure(cfg.EmailVerify, cfg.Security.JWTSecretKey, cfg.Server.BaseURL); err != nil {
+		return fail(fmt.Errorf("邮箱验证配置错误: %w", err))
+	}
+	if err := upload.Configure(cfg.Upload, cfg.Security.JWTSecretKey); err != nil {
+		return fail(fmt.Errorf("文件上传配置错误: %w", err))
+	}
+	passwordreset.Configure(cfg.PasswordReset)
+
+	// 登录记录（新设备、新位置登录提醒）
+	loginhistory.Configure(cfg.LoginHistory)
+	loginhistory.RegisterCleanupJob()
+
+	// 管理员邮件通知（摘要与免打扰）
+	notify.Configure(cfg.Notify)
+	replay.Configure(cfg.Replay, cfg.Security.AuditFilePath, cfg.Security.AuditFallbackPath)
+	notify.RegisterDigestJob()
+
+	// 管理员并发会话上限；只允许一个活跃会话时新登录顶替之前的会话
+	sessionLimit := session.Limit{
+		Max:    cfg.Security.AdminMaxSessions,
+		Policy: cfg.Security.AdminSessionLimitPolicy,
+	}
+	if cfg.Security.AdminSingleSession {
+		sessionLimit = session.Limit{Max: 1, Policy: session.PolicyRevokeOldest}
+	}
+	adminhandler.ConfigureSessionLimit(sessionLimit)
+
+	// 超级管理员模拟登录
+	adminhandler.ConfigureImpersonation(cfg.Impersonation)
+
+	// 管理员安全密钥（WebAuthn）登录及必须使用安全密钥的角色
+	if err := adminhandler.ConfigureWebAuthn(cfg.WebAuthn, cfg.Server.BaseURL); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+
+	// 管理员 SAML 单点登录（IdP 分组映射为管理员角色）
+	if err := adminhandler.ConfigureSAML(cfg.SAML, cfg.Server.BaseURL); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+
+	// 紧急访问（break-glass）
+	if err := breakglass.Configure(cfg.BreakGlass); err != nil {
+		log.Printf("⚠️  读取紧急访问轨迹失败: %v", err)
+	}
+
+	// 运行模式及功能可用性（/admin/system/info）
+	adminhandler.ConfigureSystem(cfg)
+
+	// 安全状态快照（封禁、限流热点、过滤规则、功能开关），定时保存到 MongoDB
+	secstate.Configure(cfg.SecurityState)
+	secstate.RegisterJob()
+
+	// 启动定时任务（可在 /admin/jobs 查看、手动触发、暂停）
+	jobs.Start()
+
+	// 创建路由
+	r := gin.New()
+	// c.ClientIP()（频率限制、日志、审计）与 IP 过滤使用同一组受信任的代理
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Printf("⚠️  TRUSTED_PROXIES 配置无效，不采信任何代理头: %v", err)
+		r.SetTrustedProxies(nil)
+	}
+
+	// ========== 安全中间件配置 ==========
+
+	// 1. 基础中间件
+	r.Use(gin.Recovery())
+	r.Use(middleware.RequestID()) // 请求 ID
+	r.Use(middleware.ForwardedProto(middleware.SchemeConfig{
+		TrustedProxies: cfg.Server.TrustedProxies,
+		BaseURL:        cfg.Server.BaseURL,
+		HTTPSRedirect:  cfg.Server.HTTPSRedirect,
+	})) // 识别代理后的真实协议
+	r.Use(middleware.SecureHeaders()) // 安全响应头
+	r.Use(middleware.Metrics())       // 请求指标
+	r.Use(middleware.SlowRequest(middleware.SlowRequestConfig{
+		Threshold:       cfg.Observability.SlowRequestThreshold,
+		RouteThresholds: cfg.Observability.SlowRequestRoutes,
+		CaptureDetail:   cfg.Observability.SlowRequestDetail,
+	})) // 慢请求检测
+
+	// 请求体大小限制（在审计、签名验证等读取请求体的中间件之前）；
+	// 上传接口未在 ROUTE_BODY_LIMITS 中单独设置时按 UPLOAD_MAX_SIZE 放宽
+	routeBodyLimits := make(map[string]int64, len(cfg.Protection.RouteBodyLimits)+1)
+	for route, limit := range cfg.Protection.RouteBodyLimits {
+		routeBodyLimits[route] = limit
+	}
+	if _, ok := routeBodyLimits[upload.Route]; !ok && cfg.Upload.MaxSize > 0 {
+		routeBodyLimits[upload.Route] = cfg.Upload.MaxSize + upload.MultipartOverhead
+	}
+	r.Use(middleware.BodyLimitWithConfig(middleware.BodyLimitConfig{
+		MaxBytes:    cfg.Protection.MaxBodySize,
+		RouteLimits: routeBodyLimits,
+	}))
+
+	// 并发限制（防止慢请求堆积）
+	if cfg.Protection.MaxInFlight > 0 || cfg.Protection.MaxInFlightPerIP > 0 {
+		r.Use(middleware.ConcurrencyLimitWithConfig(middleware.ConcurrencyConfig{
+			MaxInFlight:       cfg.Protection.MaxInFlight,
+			MaxInFlightPerKey: cfg.Protection.MaxInFlightPerIP,
+			QueueTimeout:      cfg.Protection.ConcurrencyQueueTimeout,
+		}))
+	}
+
+	// 优先级调度（关键请求与批量请求分别占用独立的并发预算）
+	scheduler := middleware.NewPriorityScheduler(middleware.PriorityConfig{
+		CriticalRoutes: cfg.Protection.CriticalRoutes,
+		BulkRoutes:     cfg.Protection.BulkRoutes,
+		Budgets: map[string]int{
+			middleware.PriorityCritical: cfg.Protection.CriticalConcurrency,
+			middleware.PriorityNormal:   cfg.Protection.NormalConcurrency,
+			middleware.PriorityBulk:     cfg.Protection.BulkConcurrency,
+		},
+		QueueTimeout: cfg.Protection.PriorityQueueTimeout,
+	})
+	r.Use(scheduler.Middleware())
+
+	// 过载保护（负载过高时优先丢弃批量请求和未认证的公开请求）
+	if cfg.Protection.OverloadEnabled {
+		overloadConfig := middleware.DefaultOverloadConfig
+		overloadConfig.MaxGoroutines = cfg.Protection.MaxGoroutines
+		overloadConfig.MaxHeapBytes = uint64(cfg.Protection.MaxHeapMB) << 20
+		overloadConfig.MaxSchedLatency = cfg.Protection.MaxSchedLatency
+		r.Use(middleware.LoadShedding(middleware.NewOverloadMonitor(overloadConfig)))
+	}
+
+	// 2. CORS 跨域
+	r.Use(middleware.Cors())
+
+	// 维护模式（开关由管理接口设置，管理后台与开放路径不受影响）
+	middleware.DefaultMaintenanceConfig.Message = cfg.Maintenance.Message
+	middleware.DefaultMaintenanceConfig.RetryAfter = cfg.Maintenance.RetryAfter
+	middleware.DefaultMaintenanceConfig.AllowPaths = cfg.Maintenance.AllowPaths
+	middleware.DefaultMaintenanceConfig.RefreshInterval = cfg.Maintenance.RefreshInterval
+	r.Use(middleware.Timed("maintenance", middleware.Maintenance()))
+
+	// 3. IP 过滤（黑名单/白名单）
+	ipFilterConfig := middleware.IPFilterConfig{
+		WhitelistMode:  cfg.Security.IPWhitelistMode,
+		Whitelist:      cfg.Security.IPWhitelist,
+		Blacklist:      cfg.Security.IPBlacklist,
+		AllowPrivate:   true,
+		TrustProxy:     true,
+		TrustedProxies: cfg.Server.TrustedProxies,
+		ProxyHeader:    "X-Real-IP",
+		ASNLookup:      geoip.LookupNumber,
+		BlockHandler:   middleware.DefaultIPFilterConfig.BlockHandler,
+	}
+	// 进程内唯一的 IP 过滤器：管理接口（经 iprules）、自动封禁、信誉名单与配置中心修改的都是这一个实例
+	ipFilter := middleware.NewDynamicIPFilter(ipFilterConfig)
+	r.Use(middleware.Timed("ip_filter", ipFilter.Middleware()))
+	configcenter.BindIPFilter(ipFilter)
+
+	// 合并 ip_rules 表中的规则及生效中的自动封禁，订阅其他实例的变更广播
+	iprules.Bind(ipFilter)
+	iprules.ConfigureBans(cfg.Security.AbuseBanTTL)
+	iprules.Start()
+	reputation.Bind(ipFilter)
+	reputation.Start()
+	secstate.Bind(ipFilter)
+
+	// 滥用评分（未知路径、超限、签名失败、攻击特征）达到阈值时自动临时封禁
+	middleware.DefaultAbuseConfig.Threshold = cfg.Security.AbuseBanThreshold
+	middleware.DefaultAbuseConfig.OnThreshold = iprules.AutoBan
+	middleware.OnSecurityAlert = secevents.RecordAttack
+	middleware.OnAuthFailure = secevents.RecordAuthFailure
+	adminmiddleware.OnTokenFailure = func(c *gin.Context, err error) {
+		middleware.ReportAuthFailure(c, middleware.AuthFailureToken, "", err.Error())
+	}
+
+	// 诱饵路径（命中即记录安全事件并临时封禁，不等滥用评分达到阈值）
+	if len(cfg.Security.HoneypotPaths) > 0 {
+		honeypot := middleware.HoneypotConfig{
+			Paths:  cfg.Security.HoneypotPaths,
+			Record: secevents.RecordHoneypot,
+		}
+		if cfg.Security.HoneypotBan {
+			honeypot.Ban = func(ctx context.Context, ip string, score int64) {
+				iprules.AutoBan(ctx, ip, score, middleware.AbuseHoneypot)
+			}
+		}
+		r.Use(middleware.Timed("honeypot", middleware.Honeypot(honeypot)))
+	}
+
+	// 4. 全局频率限制
+	rateLimitConfig := middleware.RateLimitConfig{
+		Window:       cfg.Security.RateLimitWindow,
+		MaxRequests:  cfg.Security.RateLimitMaxRequests,
+		KeyFunc:      middleware.DefaultRateLimitConfig.KeyFunc,
+		LimitHandler: middleware.DefaultRateLimitConfig.LimitHandler,
+		Prefix:       "global",
+		Algorithm:    cfg.Security.RateLimitAlgorithm,
+		Burst:        cfg.Security.RateLimitBurst,
+
+		ExemptCIDRs:   cfg.Security.RateLimitExemptCIDRs,
+		ExemptAppKeys: cfg.Security.RateLimitExemptAppKeys,
+		ExemptRoles:   cfg.Security.RateLimitExemptRoles,
+
+		WarnThreshold: cfg.Security.RateLimitWarnThreshold,
+		EnforceAfter:  cfg.Security.RateLimitEnforceAfter,
+	}
+	rateLimiter, err := middleware.NewDynamicRateLimiter(rateLimitConfig)
+	if err != nil {
+		return fail(fmt.Errorf("频率限制配置错误: %w", err))
+	}
+	r.Use(middleware.Timed("rate_limit", rateLimiter.Middleware()))
+	secstate.Provide("rate_limit", func() interface{} {
+		c := rateLimiter.Config()
+		return gin.H{
+			"window":          c.Window.String(),
+			"max_requests":    c.MaxRequests,
+			"algorithm":       c.Algorithm,
+			"burst":           c.Burst,
+			"exempt_cidrs":    c.ExemptCIDRs,
+			"exempt_app_keys": c.ExemptAppKeys,
+			"exempt_roles":    c.ExemptRoles,
+			"warn_threshold":  c.WarnThreshold,
+			"enforce_after":   c.EnforceAfter,
+		}
+	})
+
+	// 按路由的频率限制（如登录接口更严格）
+	if len(cfg.Security.RateLimitRules) > 0 {
+		routeRateLimit, err := middleware.RouteRateLimit(cfg.Security.RateLimitRules, rateLimitConfig)
+		if err != nil {
+			return fail(fmt.Errorf("路由频率限制配置错误: %w", err))
+		}
+		r.Use(middleware.Timed("route_rate_limit", routeRateLimit))
+		secstate.Provide("route_rate_limits", func() interface{} {
+			rules := make([]gin.H, len(cfg.Security.RateLimitRules))
+			for i, rule := range cfg.Security.RateLimitRules {
+				rules[i] = gin.H{
+					"method":        rule.Method,
+					"path":          rule.Path,
+					"window":        rule.Window.String(),
+					"max_requests":  rule.MaxRequests,
+					"enforce_after": rule.EnforceAfter,
+				}
+			}
+			return rules
+		})
+	}
+
+	// 反滥用处置策略：按滥用评分、路由敏感级别与客户端信誉统一决定限速、人机验证、重新认证或拒绝
+	if len(cfg.Policy.Rules) > 0 {
+		policyConfig := middleware.PolicyConfig{
+			Rules:  cfg.Policy.Rules,
+			Routes: cfg.Policy.Routes,
+			Reputation: func(c *gin.Context) string {
+				if ipFilter.ReputationListed(middleware.AbuseIP(c)) {
+					return middleware.ReputationListed
+				}
+				return ""
+			},
+			CaptchaSiteKey: cfg.Policy.CaptchaSiteKey,
+			CaptchaPassTTL: cfg.Policy.CaptchaPassTTL,
+			StepUpMaxAge:   cfg.Policy.StepUpMaxAge,
+		}
+		if cfg.Policy.CaptchaSecret != "" {
+			policyConfig.Captcha = middleware.NewSiteVerifyCaptcha(cfg.Policy.CaptchaVerifyURL, cfg.Policy.CaptchaSecret, cfg.Policy.CaptchaTimeout)
+		}
+		policy, err := middleware.NewPolicyEngine(policyConfig)
+		if err != nil {
+			return fail(fmt.Errorf("处置策略配置错误: %w", err))
+		}
+		r.Use(middleware.Timed("policy", policy.Middleware()))
+		secstate.Provide("policy", func() interface{} {
+			rules := make([]string, len(cfg.Policy.Rules))
+			for i, rule := range cfg.Policy.Rules {
+				rules[i] = rule.Name
+			}
+			routes := make([]gin.H, len(cfg.Policy.Routes))
+			for i, route := range cfg.Policy.Routes {
+				routes[i] = gin.H{"route": route.Route, "level": route.Level}
+			}
+			return gin.H{"rules": rules, "routes": routes}
+		})
+	}
+
+	// 5. 请求日志审计（可同时输出到 Elasticsearch、Kafka、syslog）
+	auditstore.Configure(cfg.AuditSinks)
+	auditSinks, err := auditsink.FromConfig(cfg.AuditSinks)
+	if err != nil {
+		return fail(fmt.Errorf("审计日志输出配置错误: %w", err))
+	}
+	auditConfig := middleware.AuditConfig{
+		Enabled:             cfg.Security.AuditEnabled,
+		Output:              cfg.Security.AuditOutput,
+		FilePath:            cfg.Security.AuditFilePath,
+		FallbackPath:        cfg.Security.AuditFallbackPath,
+		LogRequestBody:      true,
+		LogResponseBody:     true,
+		MaxRequestBodySize:  4096,
+		MaxResponseBodySize: 4096,
+		SensitiveFields:     []string{"password", "token", "secret", "key", "authorization"},
+		ExcludePaths:        []string{"/ping", "/health", "/metrics"},
+		SampleRate:          cfg.Security.AuditSampleRate,
+		BodySampleRate:      cfg.Security.AuditBodySampleRate,
+		PathRules:           cfg.Security.AuditPathRules,
+		KeepErrors:          cfg.Security.AuditSampleKeepErrors,
+		Async:               true,
+		BufferSize:          1000,
+		Sinks:               auditSinks,
+		SinkOptions:         auditsink.Options(cfg.AuditSinks),
+		MaskRules: middleware.AuditMaskRules{
+			Patterns:    cfg.Security.AuditMaskPatterns,
+			JSONPaths:   cfg.Security.AuditMaskJSONPaths,
+			QueryParams: cfg.Security.AuditMaskQueryParams,
+			Headers:     cfg.Security.AuditMaskHeaders,
+		},
+		Rotation: middleware.AuditRotation{
+			MaxSize:    int64(cfg.Security.AuditRotateMaxSizeMB) << 20,
+			Interval:   cfg.Security.AuditRotateInterval,
+			MaxAge:     cfg.Security.AuditRotateMaxAge,
+			MaxBackups: cfg.Security.AuditRotateMaxBackups,
+			Compress:   cfg.Security.AuditRotateCompress,
+		},
+	}
+	// 脱敏规则有误时不能带着未脱敏的数据继续写日志
+	if err := auditConfig.MaskRules.Validate(); err != nil {
+		return fail(fmt.Errorf("审计日志脱敏规则配置错误: %w", err))
+	}
+	auditLogger, err = middleware.NewAuditLogger(auditConfig)
+	if err != nil {
+		log.Printf("创建审计日志记录器失败: %v", err)
+	} else {
+		r.Use(middleware.Timed("audit", middleware.AuditWithLogger(auditLogger)))
+	}
+
+	// 6. 安全审计（WAF：按规则检测攻击特征，记录、拦截或封禁）
+	waf, err := middleware.NewWAF(middleware.WAFConfig{
+		MaxBodySize: cfg.WAF.MaxBodySize,
+		Ban: func(ctx context.Context, ip, rule string) {
+			iprules.AutoBan(ctx, ip, middleware.AbuseScore(ctx, ip), "waf")
+		},
+	}, wafrules.Builtin())
+	if err != nil {
+		return fail(fmt.Errorf("WAF 内置规则配置错误（WAF_BUILTIN_ACTION）: %w", err))
+	}
+	r.Use(middleware.Timed("security_audit", waf.Middleware()))
+	wafrules.Bind(waf)
+	wafrules.Start()
+	secstate.Provide("waf_rules", func() interface{} {
+		return waf.Rules()
+	})
+
+	// 7. 日志中间件
+	r.Use(middleware.Logger())
+
+	// 请求格式校验（Content-Type 与按路由的 JSON Schema）
+	if cfg.Validation.Enabled {
+		validation := middleware.RequestValidationConfig{
+			ContentTypes:      cfg.Validation.ContentTypes,
+			RouteContentTypes: cfg.Validation.RouteContentTypes,
+			MaxErrors:         cfg.Validation.MaxErrors,
+		}
+		if cfg.Validation.SchemaFile != "" {
+			schemas, err := middleware.LoadRequestSchemas(cfg.Validation.SchemaFile)
+			if err != nil {
+				return fail(fmt.Errorf("请求 Schema 配置错误（REQUEST_SCHEMA_FILE）: %w", err))
+			}
+			validation.Schemas = schemas
+		}
+		r.Use(middleware.Timed("request_validation", middleware.RequestValidation(validation)))
+	}
+
+	// 8. 条件请求（在路由组的脱敏中间件外层，ETag 按脱敏后的响应计算）
+	if cfg.Server.ETagEnabled {
+		r.Use(middleware.ETagWithConfig(middleware.ETagConfig{
+			MaxSize:      cfg.Server.ETagMaxSize,
+			ExcludePaths: cfg.Server.ETagExcludePaths,
+		}))
+	}
+
+	// JWT 配置（保存快照，之后注册的认证中间件与签发接口使用该配置）
+	middleware.ConfigureJWT(middleware.JWTConfig{
+		SecretKey:     cfg.Security.JWTSecretKey,
+		TokenExpiry:   cfg.Security.JWTExpiry,
+		RefreshExpiry: cfg.Security.JWTRefreshExpiry,
+		Issuer:        cfg.Security.JWTIssuer,
+
+		SigningMethod:  cfg.Security.JWTSigningMethod,
+		PrivateKeyFile: cfg.Security.JWTPrivateKeyFile,
+		PublicKeyFile:  cfg.Security.JWTPublicKeyFile,
+		KeyID:          cfg.Security.JWTKeyID,
+		VerifyKeysDir:  cfg.Security.JWTVerifyKeysDir,
+
+		Leeway:    cfg.Security.JWTLeeway,
+		Blacklist: revocation.Blacklist{},
+		Cookie: token.CookieConfig{
+			Name:     cfg.Security.JWTCookieName,
+			Domain:   cfg.Security.JWTCookieDomain,
+			Secure:   cfg.Security.JWTCookieSecure,
+			SameSite: token.ParseSameSite(cfg.Security.JWTCookieSameSite),
+		},
+	})
+
+	// 更新管理后台 JWT 配置
+	adminmiddleware.DefaultConfig.SecretKey = cfg.Security.AdminJWTSecretKey
+	adminmiddleware.DefaultConfig.SigningMethod = cfg.Security.AdminJWTSigningMethod
+	adminmiddleware.DefaultConfig.PrivateKeyFile = cfg.Security.AdminJWTPrivateKeyFile
+	adminmiddleware.DefaultConfig.PublicKeyFile = cfg.Security.AdminJWTPublicKeyFile
+	adminmiddleware.DefaultConfig.KeyID = cfg.Security.AdminJWTKeyID
+	adminmiddleware.DefaultConfig.VerifyKeysDir = cfg.Security.AdminJWTVerifyKeysDir
+	adminmiddleware.DefaultConfig.Leeway = cfg.Security.JWTLeeway
+	adminmiddleware.DefaultConfig.Blacklist = revocation.Blacklist{}
+	// 服务端会话：凭证只保存在会话存储中，登出、吊销立即生效；浏览器客户端默认以 admin_session Cookie 下发
+	adminCookieName := cfg.Security.AdminJWTCookieName
+	if cfg.Security.AdminAuthMode == "session" {
+		adminmiddleware.UseServerSessions(cfg.Security.AdminSessionIdleTimeout, cfg.Security.AdminSessionAbsoluteTimeout)
+		if adminCookieName == "" {
+			adminCookieName = "admin_session"
+		}
+		log.Printf("管理后台使用服务端会话（空闲超时 %s，绝对超时 %s）", cfg.Security.AdminSessionIdleTimeout, cfg.Security.AdminSessionAbsoluteTimeout)
+	}
+	adminmiddleware.DefaultConfig.Cookie = token.CookieConfig{
+		Name:     adminCookieName,
+		Domain:   cfg.Security.JWTCookieDomain,
+		Path:     "/admin",
+		Secure:   cfg.Security.JWTCookieSecure,
+		SameSite: token.ParseSameSite(cfg.Security.JWTCookieSameSite),
+	}
+
+	// Token 以 Cookie 下发时启用双重提交 CSRF 防护
+	var authCookies []string
+	for _, name := range []string{cfg.Security.JWTCookieName, adminCookieName} {
+		if name != "" {
+			authCookies = append(authCookies, name)
+		}
+	}
+	if len(authCookies) > 0 {
+		r.Use(middleware.CSRFWithConfig(middleware.CSRFConfig{
+			CookieName:  cfg.Security.CSRFCookieName,
+			HeaderName:  cfg.Security.CSRFHeaderName,
+			AuthCookies: authCookies,
+			Domain:      cfg.Security.JWTCookieDomain,
+			Path:        "/",
+			Secure:      cfg.Security.JWTCookieSecure,
+			SameSite:    token.ParseSameSite(cfg.Security.JWTCookieSameSite),
+		}))
+	}
+
+	// 启动时加载密钥文件，配置错误直接退出
+	if _, err := middleware.CurrentJWTConfig().Keys(); err != nil {
+		return fail(fmt.Errorf("JWT 密钥加载失败: %w", err))
+	}
+	if _, err := adminmiddleware.DefaultConfig.Keys(); err != nil {
+		return fail(fmt.Errorf("管理后台 JWT 密钥加载失败: %w", err))
+	}
+
+	// API 签名配置
+	middleware.ConfigureSignature(middleware.SignatureConfig{
+		SecretKey:      cfg.Security.APISignatureKey,
+		Expiry:         cfg.Security.APISignatureExpiry,
+		Algorithm:      cfg.Security.APISignatureAlgorithm,
+		TimeTolerance:  time.Minute * 2,
+		SignatureParam: "sign",
+		TimestampParam: "timestamp",
+		NonceParam:     "nonce",
+		AppKeyParam:    "app_key",
+		ValidateBody:   true,
+		SignedHeaders:  cfg.Security.APISignatureSignedHeaders,
+		SecretResolver: appkey.Resolve,
+	})
+
+	// 配置中心（频率限制、IP 规则、功能开关热更新）
+	configcenter.BindRateLimiter(rateLimiter)
+	if err := configcenter.Start(cfg.ConfigCenter); err != nil {
+		log.Printf("配置中心启动警告: %v", err)
+	}
+
+	// 系统设置（管理后台修改后热更新，已设置的项覆盖环境变量与配置中心的值）
+	settings.Start()
+	settings.BindRateLimiter(rateLimiter)
+	settings.BindMaintenance()
+
+	// 管理员角色与权限（角色管理中维护的权限与下面按配置授予的权限合并生效）
+	rbac.Start()
+
+	// 管理后台菜单（菜单表为空时写入默认菜单）
+	if err := menu.Seed(context.Background()); err != nil && !errors.Is(err, menu.ErrUnavailable) {
+		log.Printf("⚠️  写入默认菜单失败: %v", err)
+	}
+
+	// 响应脱敏（缺少 pii:read 的调用方看到遮盖后的敏感字段）
+	middleware.DefaultRedactionConfig.Fields = cfg.Security.PIIRedactFields
+	for _, role := range cfg.Security.PIIReadAdminRoles {
+		adminmiddleware.Grant(role, middleware.PIIReadScope)
+	}
+
+	// ========== 注册路由 ==========
+	handler.RegisterRoutes(r)
+
+	// 注册管理后台路由
+	admin.RegisterRoutes(r)
+
+	// 未知路由与不支持的请求方法（统一响应格式，未知路径计入滥用评分）
+	middleware.DefaultAbuseConfig.Window = cfg.Security.AbuseWindow
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(middleware.NotFound())
+	r.NoMethod(middleware.MethodNotAllowed(r))
+	if conflicts := middleware.HoneypotConflicts(cfg.Security.HoneypotPaths, r.Routes()); len(conflicts) > 0 {
+		return fail(fmt.Errorf("诱饵路径与已注册的路由冲突（HONEYPOT_PATHS）: %s", strings.Join(conflicts, ", ")))
+	}
+
+	return r, shutdown, nil
+}
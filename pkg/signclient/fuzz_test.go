@@ -0,0 +1,54 @@
+package signclient
+
+// 模糊测试：go test 时只运行种子语料；持续模糊测试示例：
+//
+//	go test -fuzz=FuzzSignatureV2 -fuzztime=30s ./pkg/signclient
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// FuzzSignatureV2 签名 v2 规范化：任意 URL 不能 panic；查询参数值的顺序不影响规范请求；编码可逆且只含非保留字符与 %XX
+func FuzzSignatureV2(f *testing.F) {
+	f.Add("https://api.example.com/api/v1/users?page=1&size=20")
+	f.Add("http://localhost:8080/a%20b/c?x=2&x=1&y=")
+	f.Add("/中文/路径?q=a+b&q=%26")
+	f.Add("/~user/file.tar.gz?k=v=w")
+	f.Fuzz(func(t *testing.T, data string) {
+		u, err := url.Parse(data)
+		if err != nil {
+			t.Skip()
+		}
+
+		config := DefaultConfig
+		header := http.Header{"Content-Type": {data}}
+		signed := []string{"content-type", "host"}
+
+		query := u.Query()
+		canonical := CanonicalRequest(config, http.MethodPost, u.Path, query, header, u.Host, signed, UnsignedPayload)
+
+		reversed := url.Values{}
+		for key, values := range query {
+			for i := len(values) - 1; i >= 0; i-- {
+				reversed.Add(key, values[i])
+			}
+		}
+		if CanonicalRequest(config, http.MethodPost, u.Path, reversed, header, u.Host, signed, UnsignedPayload) != canonical {
+			t.Fatalf("规范请求依赖查询参数顺序: %q", data)
+		}
+
+		encoded := uriEncode(u.Path)
+		if decoded, err := url.PathUnescape(encoded); err != nil || decoded != u.Path {
+			t.Fatalf("uriEncode 不可逆: %q -> %q", u.Path, encoded)
+		}
+		for i := 0; i < len(encoded); i++ {
+			c := encoded[i]
+			if c >= 0x80 || c <= ' ' || strings.IndexByte("/?#&=+", c) >= 0 {
+				t.Fatalf("uriEncode 输出包含保留字符: %q", encoded)
+			}
+		}
+	})
+}
@@ -0,0 +1,170 @@
+// Package signclient API 签名客户端：与服务端签名中间件共用同一套签名算法（v1 拼接、v2 规范请求），
+// 第三方 Go 调用方通过 Transport 自动为请求签名，无需手工拼接签名字符串
+package signclient
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 签名算法
+const (
+	AlgorithmHMACSHA256 = "hmac-sha256"
+	AlgorithmMD5        = "md5"
+	// AlgorithmV2 规范请求 + 请求头签名（见 v2.go）
+	AlgorithmV2 = "v2"
+)
+
+// Config 签名配置，须与服务端 API_SIGNATURE_* 配置一致
+type Config struct {
+	// 签名算法：hmac-sha256, md5, v2
+	Algorithm string
+	// 签名相关查询参数名（不参与签名）
+	SignatureParam string
+	TimestampParam string
+	NonceParam     string
+	AppKeyParam    string
+	// 是否签名请求体
+	ValidateBody bool
+	// v2 签名的请求头（小写，如 content-type、host）
+	SignedHeaders []string
+}
+
+// DefaultConfig 默认签名配置（与服务端默认配置一致）
+var DefaultConfig = Config{
+	Algorithm:      AlgorithmHMACSHA256,
+	SignatureParam: "sign",
+	TimestampParam: "timestamp",
+	NonceParam:     "nonce",
+	AppKeyParam:    "app_key",
+	ValidateBody:   true,
+	SignedHeaders:  []string{"content-type"},
+}
+
+// SignRequest 为请求签名，设置 X-App-Key、X-Timestamp、X-Nonce、X-Signature（v2 另设置 X-Signed-Headers）请求头；
+// body 为请求体原文，调用方负责保证与实际发送的内容一致
+func SignRequest(req *http.Request, body []byte, appKey, secretKey string, config Config) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := NewNonce()
+
+	if appKey != "" {
+		req.Header.Set("X-App-Key", appKey)
+	}
+	req.Header.Set("X-Timestamp", timestamp)
+	req.Header.Set("X-Nonce", nonce)
+
+	if config.Algorithm == AlgorithmV2 {
+		signedHeaders := make([]string, 0, len(config.SignedHeaders))
+		for _, name := range config.SignedHeaders {
+			signedHeaders = append(signedHeaders, strings.ToLower(name))
+		}
+		if len(signedHeaders) == 0 {
+			signedHeaders = []string{"content-type"}
+		}
+		sort.Strings(signedHeaders)
+		req.Header.Set("X-Signed-Headers", strings.Join(signedHeaders, ";"))
+
+		payloadHash := UnsignedPayload
+		if config.ValidateBody {
+			payloadHash = PayloadHash(body)
+		}
+		host := req.Host
+		if host == "" {
+			host = req.URL.Host
+		}
+		canonical := CanonicalRequest(config, req.Method, req.URL.Path, req.URL.Query(), req.Header, host, signedHeaders, payloadHash)
+		req.Header.Set("X-Signature", SignV2(StringToSignV2(timestamp, nonce, appKey, canonical), secretKey))
+		return
+	}
+
+	data := StringToSign(config, req.Method, req.URL.Path, req.URL.Query(), timestamp, nonce, appKey, body)
+	req.Header.Set("X-Signature", Sign(data, secretKey, config.Algorithm))
+}
+
+// StringToSign v1 签名字符串：方法&路径&排序后的查询参数&时间戳&nonce&appKey&请求体
+func StringToSign(config Config, method, path string, queryParams url.Values, timestamp, nonce, appKey string, body []byte) string {
+	var parts []string
+
+	// 添加请求方法
+	parts = append(parts, method)
+
+	// 添加请求路径
+	parts = append(parts, path)
+
+	// 添加排序后的查询参数
+	var queryKeys []string
+	for key := range queryParams {
+		// 排除签名相关参数
+		if !config.isSignatureParam(key) {
+			queryKeys = append(queryKeys, key)
+		}
+	}
+	sort.Strings(queryKeys)
+
+	for _, key := range queryKeys {
+		values := append([]string{}, queryParams[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, fmt.Sprintf("%s=%s", key, value))
+		}
+	}
+
+	// 添加时间戳
+	parts = append(parts, timestamp)
+
+	// 添加 nonce
+	if nonce != "" {
+		parts = append(parts, nonce)
+	}
+
+	// 添加 appKey
+	if appKey != "" {
+		parts = append(parts, appKey)
+	}
+
+	// 添加请求体
+	if config.ValidateBody && len(body) > 0 {
+		parts = append(parts, string(body))
+	}
+
+	return strings.Join(parts, "&")
+}
+
+// Sign 计算 v1 签名（未知算法按 hmac-sha256 处理）
+func Sign(data, secretKey, algorithm string) string {
+	switch algorithm {
+	case AlgorithmMD5:
+		h := md5.New()
+		h.Write([]byte(data + secretKey))
+		return hex.EncodeToString(h.Sum(nil))
+	default:
+		h := hmac.New(sha256.New, []byte(secretKey))
+		h.Write([]byte(data))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+}
+
+// NewNonce 生成随机 nonce（16 位十六进制）
+func NewNonce() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// isSignatureParam 是否为签名相关参数
+func (c Config) isSignatureParam(key string) bool {
+	return key == c.SignatureParam || key == c.TimestampParam ||
+		key == c.NonceParam || key == c.AppKeyParam
+}
@@ -0,0 +1,66 @@
+package signclient
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// Transport 自动签名的 http.RoundTripper：发送前读取请求体并设置 X-App-Key、X-Timestamp、X-Nonce、X-Signature。
+// 每次发送（包括重定向、重试）都会重新生成时间戳与 nonce
+//
+//	client := &http.Client{Transport: &signclient.Transport{AppKey: "ak", SecretKey: "sk", Config: signclient.DefaultConfig}}
+type Transport struct {
+	// 签名使用的 AppKey 与密钥
+	AppKey    string
+	SecretKey string
+	// 签名配置，须与服务端一致
+	Config Config
+	// 实际发送请求的 RoundTripper，为空时使用 http.DefaultTransport
+	Base http.RoundTripper
+}
+
+// NewClient 创建自动签名的 HTTP 客户端
+func NewClient(appKey, secretKey string, config Config) *http.Client {
+	return &http.Client{Transport: &Transport{AppKey: appKey, SecretKey: secretKey, Config: config}}
+}
+
+// RoundTrip 签名并发送请求（不修改调用方的请求）
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.SecretKey == "" {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, errors.New("signclient: 未配置签名密钥")
+	}
+
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	signed := req.Clone(req.Context())
+	if body != nil {
+		signed.Body = io.NopCloser(bytes.NewReader(body))
+		signed.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		signed.ContentLength = int64(len(body))
+	}
+	SignRequest(signed, body, t.AppKey, t.SecretKey, t.Config)
+
+	return t.base().RoundTrip(signed)
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
@@ -0,0 +1,157 @@
+package signclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// 签名 v2（参考 AWS SigV4）：
+//
+//	CanonicalRequest = 方法 \n 规范路径 \n 规范查询参数 \n 规范请求头 \n 签名请求头列表 \n 请求体 SHA-256
+//	StringToSign     = OPENCLAW-HMAC-SHA256 \n 时间戳 \n nonce \n appKey \n hex(SHA-256(CanonicalRequest))
+//	Signature        = hex(HMAC-SHA256(secret, StringToSign))
+//
+//   - 规范路径：按 / 分段，每段按 RFC 3986 编码（仅 A-Z a-z 0-9 - _ . ~ 不编码）
+//   - 规范查询参数：排除签名相关参数，键和值分别编码后按键、值排序，重复的键逐个保留，以 & 连接
+//   - 规范请求头：名称转小写，值去除首尾空白并将连续空白合并为一个空格，按名称排序，每行 "名称:值\n"；
+//     同名请求头的多个值以逗号连接；未携带的请求头值为空
+//   - 签名请求头列表：小写名称按字典序以 ; 连接，通过 X-Signed-Headers 请求头传递
+//   - 请求体 SHA-256：小写十六进制；不校验请求体时为 UNSIGNED-PAYLOAD
+
+const (
+	scheme = "OPENCLAW-HMAC-SHA256"
+
+	// UnsignedPayload 不签名请求体时的请求体哈希
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// PayloadHash 请求体 SHA-256（小写十六进制）
+func PayloadHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// CanonicalRequest 构建 v2 规范请求
+func CanonicalRequest(config Config, method, path string, query url.Values, header http.Header, host string, signedHeaders []string, payloadHash string) string {
+	names := make([]string, 0, len(signedHeaders))
+	seen := make(map[string]bool, len(signedHeaders))
+	for _, name := range signedHeaders {
+		name = strings.ToLower(name)
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var headers strings.Builder
+	for _, name := range names {
+		headers.WriteString(name)
+		headers.WriteByte(':')
+		headers.WriteString(canonicalHeaderValue(name, header, host))
+		headers.WriteByte('\n')
+	}
+
+	return strings.Join([]string{
+		method,
+		canonicalPath(path),
+		canonicalQuery(config, query),
+		headers.String(),
+		strings.Join(names, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+// StringToSignV2 构建 v2 待签名字符串
+func StringToSignV2(timestamp, nonce, appKey, canonicalRequest string) string {
+	sum := sha256.Sum256([]byte(canonicalRequest))
+	return strings.Join([]string{
+		scheme,
+		timestamp,
+		nonce,
+		appKey,
+		hex.EncodeToString(sum[:]),
+	}, "\n")
+}
+
+// SignV2 计算 v2 签名
+func SignV2(stringToSign, secretKey string) string {
+	h := hmac.New(sha256.New, []byte(secretKey))
+	h.Write([]byte(stringToSign))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// canonicalPath 按段编码路径
+func canonicalPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = uriEncode(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery 编码并按键、值排序查询参数（排除签名相关参数）
+func canonicalQuery(config Config, query url.Values) string {
+	type pair struct{ key, value string }
+	var pairs []pair
+	for key, values := range query {
+		if config.isSignatureParam(key) {
+			continue
+		}
+		encodedKey := uriEncode(key)
+		for _, value := range values {
+			pairs = append(pairs, pair{encodedKey, uriEncode(value)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].key != pairs[j].key {
+			return pairs[i].key < pairs[j].key
+		}
+		return pairs[i].value < pairs[j].value
+	})
+
+	parts := make([]string, len(pairs))
+	for i, p := range pairs {
+		parts[i] = p.key + "=" + p.value
+	}
+	return strings.Join(parts, "&")
+}
+
+// canonicalHeaderValue 规范化请求头的值（net/http 将 Host 从请求头移到 Request.Host，单独传入）
+func canonicalHeaderValue(name string, header http.Header, host string) string {
+	if name == "host" {
+		return strings.ToLower(host)
+	}
+	values := header.Values(name)
+	normalized := make([]string, len(values))
+	for i, v := range values {
+		normalized[i] = strings.Join(strings.Fields(v), " ")
+	}
+	return strings.Join(normalized, ",")
+}
+
+// uriEncode 按 RFC 3986 编码（仅保留非保留字符）
+func uriEncode(s string) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hexDigits[c>>4])
+		b.WriteByte(hexDigits[c&15])
+	}
+	return b.String()
+}
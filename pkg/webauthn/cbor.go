@@ -0,0 +1,144 @@
+package webauthn
+
+import (
+	"errors"
+	"math"
+)
+
+// errCBOR CBOR 编码无效或使用了不支持的特性
+var errCBOR = errors.New("CBOR 格式错误")
+
+// maxCBORDepth 数组、映射的最大嵌套深度
+const maxCBORDepth = 16
+
+// decodeCBOR 解码 buf 开头的一个数据项，返回解码结果及占用的字节数。
+// 只实现 WebAuthn 用到的子集（RFC 8949）：确定长度的整数、字节串、文本串、数组、映射、标签及 true/false/null；
+// 整数解码为 int64，字节串为 []byte，文本串为 string，映射为 map[interface{}]interface{}（键只能是整数或文本串）
+func decodeCBOR(buf []byte) (interface{}, int, error) {
+	d := cborDecoder{buf: buf}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, 0, err
+	}
+	return v, d.pos, nil
+}
+
+type cborDecoder struct {
+	buf []byte
+	pos int
+}
+
+// head 读取数据项的首字节及其后的长度/数值
+func (d *cborDecoder) head() (byte, uint64, error) {
+	if d.pos >= len(d.buf) {
+		return 0, 0, errCBOR
+	}
+	b := d.buf[d.pos]
+	d.pos++
+
+	major, info := b>>5, b&0x1f
+	if info < 24 {
+		return major, uint64(info), nil
+	}
+	// 不定长编码（31）及保留值（28-30）不支持
+	if info > 27 {
+		return 0, 0, errCBOR
+	}
+	n := 1 << (info - 24)
+	if len(d.buf)-d.pos < n {
+		return 0, 0, errCBOR
+	}
+	var arg uint64
+	for _, c := range d.buf[d.pos : d.pos+n] {
+		arg = arg<<8 | uint64(c)
+	}
+	d.pos += n
+	return major, arg, nil
+}
+
+func (d *cborDecoder) value(depth int) (interface{}, error) {
+	if depth > maxCBORDepth {
+		return nil, errCBOR
+	}
+	major, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	remaining := uint64(len(d.buf) - d.pos)
+
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, errCBOR
+		}
+		return int64(arg), nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, errCBOR
+		}
+		return -1 - int64(arg), nil
+	case 2, 3:
+		if arg > remaining {
+			return nil, errCBOR
+		}
+		b := d.buf[d.pos : d.pos+int(arg)]
+		d.pos += int(arg)
+		if major == 3 {
+			return string(b), nil
+		}
+		return b, nil
+	case 4:
+		// 每个元素至少占一个字节，超出剩余长度的声明直接拒绝，避免按声明长度预分配
+		if arg > remaining {
+			return nil, errCBOR
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	case 5:
+		if arg > remaining/2 {
+			return nil, errCBOR
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			k, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, errCBOR
+			}
+			if _, dup := m[k]; dup {
+				return nil, errCBOR
+			}
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+		}
+		return m, nil
+	case 6:
+		// 标签：忽略标签号，只取内容
+		return d.value(depth + 1)
+	default:
+		switch arg {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		}
+		// 浮点数及其他简单值 WebAuthn 不使用
+		return nil, errCBOR
+	}
+}
@@ -0,0 +1,130 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"math/big"
+)
+
+// COSE 签名算法（https://www.iana.org/assignments/cose/cose.xhtml#algorithms）
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+// SupportedAlgorithms 支持的签名算法（按优先顺序，注册时提供给浏览器）
+var SupportedAlgorithms = []int64{AlgES256, AlgEdDSA, AlgRS256}
+
+// COSE_Key 参数
+const (
+	coseKty = 1
+	coseAlg = 3
+	// EC2、OKP 的曲线；RSA 的模数 n
+	coseCrv = -1
+	// EC2、OKP 的 x 坐标；RSA 的公共指数 e
+	coseX = -2
+	// EC2 的 y 坐标
+	coseY = -3
+
+	ktyOKP = 1
+	ktyEC2 = 2
+	ktyRSA = 3
+
+	crvP256    = 1
+	crvEd25519 = 6
+)
+
+// minRSABits RSA 公钥的最小长度
+const minRSABits = 2048
+
+// PublicKey 凭证公钥
+type PublicKey struct {
+	Algorithm int64
+	key       crypto.PublicKey
+}
+
+// ParsePublicKey 解析 COSE_Key 编码的公钥（ES256 的 P-256 曲线、EdDSA 的 Ed25519、RS256）
+func ParsePublicKey(raw []byte) (*PublicKey, error) {
+	v, n, err := decodeCBOR(raw)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(raw) {
+		return nil, errCBOR
+	}
+	return publicKeyFromCOSE(v)
+}
+
+func publicKeyFromCOSE(v interface{}) (*PublicKey, error) {
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrInvalidResponse
+	}
+	kty, _ := m[int64(coseKty)].(int64)
+	alg, _ := m[int64(coseAlg)].(int64)
+	crv, _ := m[int64(coseCrv)].(int64)
+
+	switch {
+	case kty == ktyEC2 && alg == AlgES256:
+		x, _ := m[int64(coseX)].([]byte)
+		y, _ := m[int64(coseY)].([]byte)
+		if crv != crvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, ErrInvalidResponse
+		}
+		// 借助 crypto/ecdh 校验点在曲线上
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, ErrInvalidResponse
+		}
+		return &PublicKey{Algorithm: alg, key: &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}}, nil
+
+	case kty == ktyOKP && alg == AlgEdDSA:
+		x, _ := m[int64(coseX)].([]byte)
+		if crv != crvEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, ErrInvalidResponse
+		}
+		return &PublicKey{Algorithm: alg, key: ed25519.PublicKey(x)}, nil
+
+	case kty == ktyRSA && alg == AlgRS256:
+		nBytes, _ := m[int64(coseCrv)].([]byte)
+		eBytes, _ := m[int64(coseX)].([]byte)
+		if len(eBytes) == 0 || len(eBytes) > 4 {
+			return nil, ErrInvalidResponse
+		}
+		e := 0
+		for _, b := range eBytes {
+			e = e<<8 | int(b)
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(nBytes), E: e}
+		if pub.N.BitLen() < minRSABits || e < 3 || e%2 == 0 {
+			return nil, ErrInvalidResponse
+		}
+		return &PublicKey{Algorithm: alg, key: pub}, nil
+	}
+	return nil, ErrUnsupportedAlgorithm
+}
+
+// Verify 校验 message 的签名
+func (k *PublicKey) Verify(message, sig []byte) bool {
+	switch pub := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(message)
+		return ecdsa.VerifyASN1(pub, digest[:], sig)
+	case ed25519.PublicKey:
+		return ed25519.Verify(pub, message, sig)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(message)
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+	}
+	return false
+}
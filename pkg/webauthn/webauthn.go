@@ -0,0 +1,420 @@
+// Package webauthn WebAuthn（安全密钥、通行密钥）服务端校验：生成注册与登录选项，校验浏览器返回的
+// clientDataJSON、认证器数据、COSE 公钥及登录签名（规范见 https://www.w3.org/TR/webauthn-2/）。
+// 只实现管理后台登录所需的部分：注册时请求 attestation=none，不校验认证器的型号证明；签名算法支持 ES256、EdDSA、RS256
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidResponse 浏览器返回的数据格式无效
+	ErrInvalidResponse = errors.New("无效的 WebAuthn 响应")
+	// ErrChallengeMismatch 挑战不匹配（不是本次仪式签发的挑战）
+	ErrChallengeMismatch = errors.New("WebAuthn 挑战不匹配")
+	// ErrOriginMismatch 来源不在允许列表中
+	ErrOriginMismatch = errors.New("WebAuthn 来源不允许")
+	// ErrRPIDMismatch 凭证不属于本站点
+	ErrRPIDMismatch = errors.New("WebAuthn 依赖方 ID 不匹配")
+	// ErrUserNotPresent 认证器未确认用户在场
+	ErrUserNotPresent = errors.New("认证器未确认用户在场")
+	// ErrUserNotVerified 认证器未完成用户验证（PIN、指纹等）
+	ErrUserNotVerified = errors.New("认证器未完成用户验证")
+	// ErrUnsupportedAlgorithm 公钥算法不支持
+	ErrUnsupportedAlgorithm = errors.New("不支持的凭证公钥算法")
+	// ErrSignature 签名校验失败
+	ErrSignature = errors.New("WebAuthn 签名无效")
+	// ErrSignCount 签名计数器没有增加，认证器可能被复制
+	ErrSignCount = errors.New("认证器签名计数异常，可能已被复制")
+)
+
+// 认证器数据标志位
+const (
+	flagUserPresent    = 0x01
+	flagUserVerified   = 0x04
+	flagBackupEligible = 0x08
+	flagAttested       = 0x40
+	flagExtensions     = 0x80
+)
+
+// maxCredentialIDLength 凭证 ID 的最大长度
+const maxCredentialIDLength = 1023
+
+// RelyingParty 依赖方（站点）
+type RelyingParty struct {
+	// 依赖方 ID（站点域名，如 admin.example.com 或 example.com）
+	ID   string
+	Name string
+	// 允许的来源（如 https://admin.example.com），与浏览器报告的来源完全一致才通过
+	Origins []string
+	// 要求用户验证（PIN、指纹、面容），不满足时注册与登录都失败
+	RequireUserVerification bool
+}
+
+// CredentialDescriptor 注册时排除、登录时允许的凭证
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// NewDescriptor 凭证 ID 与传输方式（usb、nfc、ble、internal、hybrid）
+func NewDescriptor(id []byte, transports []string) CredentialDescriptor {
+	return CredentialDescriptor{Type: "public-key", ID: Encode(id), Transports: transports}
+}
+
+// CreationOptions navigator.credentials.create() 的 publicKey 参数（二进制字段为 base64url，由前端解码）
+type CreationOptions struct {
+	RP struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	Challenge        string `json:"challenge"`
+	PubKeyCredParams []struct {
+		Type string `json:"type"`
+		Alg  int64  `json:"alg"`
+	} `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection struct {
+		ResidentKey      string `json:"residentKey"`
+		UserVerification string `json:"userVerification"`
+	} `json:"authenticatorSelection"`
+	Attestation string `json:"attestation"`
+}
+
+// RequestOptions navigator.credentials.get() 的 publicKey 参数
+type RequestOptions struct {
+	Challenge string `json:"challenge"`
+	Timeout   int64  `json:"timeout"`
+	RPID      string `json:"rpId"`
+	// 为空时由浏览器列出本站点的可发现凭证（通行密钥），无需先输入用户名
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// NewChallenge 生成随机挑战
+func NewChallenge() ([]byte, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// CreationOptions 注册选项：userID 为账号的不透明标识（不应包含个人信息），exclude 为账号已注册的凭证
+func (rp *RelyingParty) CreationOptions(challenge, userID []byte, name, displayName string, exclude []CredentialDescriptor, timeout time.Duration) *CreationOptions {
+	o := &CreationOptions{
+		Challenge:          Encode(challenge),
+		Timeout:            timeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		Attestation:        "none",
+	}
+	if o.ExcludeCredentials == nil {
+		o.ExcludeCredentials = []CredentialDescriptor{}
+	}
+	o.RP.ID, o.RP.Name = rp.ID, rp.Name
+	o.User.ID, o.User.Name, o.User.DisplayName = Encode(userID), name, displayName
+	for _, alg := range SupportedAlgorithms {
+		o.PubKeyCredParams = append(o.PubKeyCredParams, struct {
+			Type string `json:"type"`
+			Alg  int64  `json:"alg"`
+		}{"public-key", alg})
+	}
+	o.AuthenticatorSelection.ResidentKey = "preferred"
+	o.AuthenticatorSelection.UserVerification = rp.userVerification()
+	return o
+}
+
+// RequestOptions 登录选项：allow 为空时使用可发现凭证
+func (rp *RelyingParty) RequestOptions(challenge []byte, allow []CredentialDescriptor, timeout time.Duration) *RequestOptions {
+	if allow == nil {
+		allow = []CredentialDescriptor{}
+	}
+	return &RequestOptions{
+		Challenge:        Encode(challenge),
+		Timeout:          timeout.Milliseconds(),
+		RPID:             rp.ID,
+		AllowCredentials: allow,
+		UserVerification: rp.userVerification(),
+	}
+}
+
+func (rp *RelyingParty) userVerification() string {
+	if rp.RequireUserVerification {
+		return "required"
+	}
+	return "preferred"
+}
+
+// RegistrationCredential navigator.credentials.create() 返回的 PublicKeyCredential（二进制字段为 base64url）
+type RegistrationCredential struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AttestationObject string `json:"attestationObject"`
+		// response.getTransports() 的结果
+		Transports []string `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionCredential navigator.credentials.get() 返回的 PublicKeyCredential
+type AssertionCredential struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// CredentialID 断言使用的凭证 ID
+func (a *AssertionCredential) CredentialID() ([]byte, error) {
+	raw := a.RawID
+	if raw == "" {
+		raw = a.ID
+	}
+	id, err := Decode(raw)
+	if err != nil || len(id) == 0 || len(id) > maxCredentialIDLength {
+		return nil, ErrInvalidResponse
+	}
+	return id, nil
+}
+
+// UserHandle 可发现凭证登录时认证器返回的账号标识（注册时的 userID），非可发现凭证为空
+func (a *AssertionCredential) UserHandle() []byte {
+	b, _ := Decode(a.Response.UserHandle)
+	return b
+}
+
+// Credential 注册成功的凭证
+type Credential struct {
+	ID []byte
+	// COSE_Key 编码的公钥
+	PublicKey []byte
+	Algorithm int64
+	SignCount uint32
+	// 认证器型号（AAGUID，attestation=none 时部分认证器返回全零）
+	AAGUID []byte
+	// 可备份的凭证（跨设备同步的通行密钥）
+	BackupEligible bool
+	Transports     []string
+}
+
+// VerifyRegistration 校验注册响应，返回新凭证
+func (rp *RelyingParty) VerifyRegistration(challenge []byte, cred *RegistrationCredential) (*Credential, error) {
+	if cred.Type != "public-key" {
+		return nil, ErrInvalidResponse
+	}
+	if _, err := rp.verifyClientData(cred.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	raw, err := Decode(cred.Response.AttestationObject)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+	v, _, err := decodeCBOR(raw)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+	attestation, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrInvalidResponse
+	}
+	// fmt 与 attStmt 不做校验：请求的是 attestation=none，只信任注册时已登录账号的身份
+	authData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, ErrInvalidResponse
+	}
+
+	data, err := rp.parseAuthenticatorData(authData)
+	if err != nil {
+		return nil, err
+	}
+	if data.flags&flagAttested == 0 || len(data.credentialID) == 0 {
+		return nil, ErrInvalidResponse
+	}
+	key, err := ParsePublicKey(data.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:             data.credentialID,
+		PublicKey:      data.publicKey,
+		Algorithm:      key.Algorithm,
+		SignCount:      data.signCount,
+		AAGUID:         data.aaguid,
+		BackupEligible: data.flags&flagBackupEligible != 0,
+		Transports:     cred.Response.Transports,
+	}, nil
+}
+
+// VerifyAssertion 用已注册凭证的公钥校验登录断言，返回认证器新的签名计数。
+// 两次计数都为 0 表示认证器不支持计数（如同步的通行密钥），否则新计数必须大于已保存的计数
+func (rp *RelyingParty) VerifyAssertion(challenge []byte, cred *AssertionCredential, publicKey []byte, signCount uint32) (uint32, error) {
+	if cred.Type != "public-key" {
+		return 0, ErrInvalidResponse
+	}
+	clientDataJSON, err := rp.verifyClientData(cred.Response.ClientDataJSON, "webauthn.get", challenge)
+	if err != nil {
+		return 0, err
+	}
+
+	authData, err := Decode(cred.Response.AuthenticatorData)
+	if err != nil {
+		return 0, ErrInvalidResponse
+	}
+	data, err := rp.parseAuthenticatorData(authData)
+	if err != nil {
+		return 0, err
+	}
+
+	sig, err := Decode(cred.Response.Signature)
+	if err != nil {
+		return 0, ErrInvalidResponse
+	}
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	if !key.Verify(append(authData[:len(authData):len(authData)], clientDataHash[:]...), sig) {
+		return 0, ErrSignature
+	}
+
+	if (data.signCount != 0 || signCount != 0) && data.signCount <= signCount {
+		return 0, ErrSignCount
+	}
+	return data.signCount, nil
+}
+
+// verifyClientData 校验 clientDataJSON 的类型、挑战与来源，返回解码后的原文（用于计算签名消息）
+func (rp *RelyingParty) verifyClientData(encoded, typ string, challenge []byte) ([]byte, error) {
+	raw, err := Decode(encoded)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+	var clientData struct {
+		Type        string `json:"type"`
+		Challenge   string `json:"challenge"`
+		Origin      string `json:"origin"`
+		CrossOrigin bool   `json:"crossOrigin"`
+	}
+	if err := json.Unmarshal(raw, &clientData); err != nil || clientData.Type != typ {
+		return nil, ErrInvalidResponse
+	}
+
+	got, err := Decode(clientData.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return nil, ErrChallengeMismatch
+	}
+	// 不接受嵌入在其他站点 iframe 中发起的仪式
+	if clientData.CrossOrigin {
+		return nil, ErrOriginMismatch
+	}
+	for _, origin := range rp.Origins {
+		if clientData.Origin == origin {
+			return raw, nil
+		}
+	}
+	return nil, ErrOriginMismatch
+}
+
+// authenticatorData 认证器数据
+type authenticatorData struct {
+	flags     byte
+	signCount uint32
+	// 以下仅注册时存在
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+// parseAuthenticatorData 解析认证器数据并校验依赖方 ID 摘要、用户在场与用户验证标志
+func (rp *RelyingParty) parseAuthenticatorData(b []byte) (*authenticatorData, error) {
+	// rpIdHash(32) + flags(1) + signCount(4)
+	if len(b) < 37 {
+		return nil, ErrInvalidResponse
+	}
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(b[:32], rpIDHash[:]) {
+		return nil, ErrRPIDMismatch
+	}
+	data := &authenticatorData{
+		flags:     b[32],
+		signCount: binary.BigEndian.Uint32(b[33:37]),
+	}
+	if data.flags&flagUserPresent == 0 {
+		return nil, ErrUserNotPresent
+	}
+	if rp.RequireUserVerification && data.flags&flagUserVerified == 0 {
+		return nil, ErrUserNotVerified
+	}
+
+	rest := b[37:]
+	if data.flags&flagAttested != 0 {
+		// aaguid(16) + credentialIdLength(2) + credentialId + credentialPublicKey
+		if len(rest) < 18 {
+			return nil, ErrInvalidResponse
+		}
+		data.aaguid = rest[:16]
+		n := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if n == 0 || n > maxCredentialIDLength || len(rest) < n {
+			return nil, ErrInvalidResponse
+		}
+		data.credentialID, rest = rest[:n], rest[n:]
+
+		_, size, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, ErrInvalidResponse
+		}
+		data.publicKey, rest = rest[:size], rest[size:]
+	}
+	if data.flags&flagExtensions != 0 {
+		_, size, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, ErrInvalidResponse
+		}
+		rest = rest[size:]
+	}
+	if len(rest) != 0 {
+		return nil, ErrInvalidResponse
+	}
+	return data, nil
+}
+
+// Encode base64url 编码（无填充）
+func Encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Decode 解码 base64url（兼容带填充及标准 base64 的写法）
+func Decode(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	if b, err := base64.RawURLEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}